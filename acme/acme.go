@@ -0,0 +1,100 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package acme issues and renews certificates for the cnames of apps using
+// an ACME certificate authority, installing them in the app routers with TLS
+// support. Certificates uploaded manually are kept while they are valid and
+// replaced by ACME issued certificates before they expire.
+package acme
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/storage"
+)
+
+type Status string
+
+const (
+	// StatusIssued is the status of cnames whose certificate was issued by
+	// the ACME certificate authority.
+	StatusIssued = Status("issued")
+	// StatusManual is the status of cnames whose certificate was uploaded
+	// by users and is still far from its expiration.
+	StatusManual = Status("manual")
+	// StatusError is the status of cnames whose last issuance failed, it
+	// will be retried after NextAttempt.
+	StatusError = Status("error")
+)
+
+// Certificate is the state of the certificate of an app cname. The issued
+// certificate and key are stored so they can be installed in routers added
+// to the app later.
+type Certificate struct {
+	CName       string    `json:"cname" bson:"_id"`
+	App         string    `json:"app"`
+	Status      Status    `json:"status"`
+	Routers     []string  `json:"routers"`
+	NotAfter    time.Time `json:"notAfter"`
+	IssuedAt    time.Time `json:"issuedAt"`
+	LastError   string    `json:"lastError,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	Certificate string    `json:"-"`
+	Key         string    `json:"-"`
+}
+
+func collection() (*storage.Collection, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Collection("acme_certificates"), nil
+}
+
+// AppCertificates returns the state of the certificates of the cnames of an
+// app.
+func AppCertificates(appName string) ([]Certificate, error) {
+	return listCertificates(bson.M{"app": appName})
+}
+
+func listCertificates(query bson.M) ([]Certificate, error) {
+	coll, err := collection()
+	if err != nil {
+		return nil, err
+	}
+	defer coll.Close()
+	var certs []Certificate
+	err = coll.Find(query).Sort("_id").All(&certs)
+	if err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+func saveCertificate(cert *Certificate) error {
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	_, err = coll.UpsertId(cert.CName, cert)
+	return err
+}
+
+func removeCertificate(cname string) error {
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	err = coll.RemoveId(cname)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	tsuruNet "github.com/tsuru/tsuru/net"
+)
+
+// DNSProvider creates and removes the TXT records used to answer DNS-01
+// challenges. The fqdn is the fully qualified record name, with a trailing
+// dot, and value is the content of the TXT record.
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// DNSProviderFactory creates a DNS provider reading its settings from the
+// config entries under configPrefix.
+type DNSProviderFactory func(configPrefix string) (DNSProvider, error)
+
+var (
+	dnsProvidersMu sync.Mutex
+	dnsProviders   = map[string]DNSProviderFactory{}
+)
+
+// RegisterDNSProvider registers a DNS provider that can be selected in the
+// acme:dns:type config entry.
+func RegisterDNSProvider(name string, factory DNSProviderFactory) {
+	dnsProvidersMu.Lock()
+	defer dnsProvidersMu.Unlock()
+	dnsProviders[name] = factory
+}
+
+func getDNSProvider(name, configPrefix string) (DNSProvider, error) {
+	dnsProvidersMu.Lock()
+	factory, ok := dnsProviders[name]
+	dnsProvidersMu.Unlock()
+	if !ok {
+		return nil, errors.Errorf("unknown acme dns provider: %q", name)
+	}
+	return factory(configPrefix)
+}
+
+func init() {
+	RegisterDNSProvider("webhook", newWebhookDNSProvider)
+}
+
+// webhookDNSProvider delegates the changes in TXT records to an external
+// service, sending a POST request to create and a DELETE request to remove
+// a record.
+type webhookDNSProvider struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+type dnsRecord struct {
+	FQDN  string `json:"fqdn"`
+	Value string `json:"value"`
+}
+
+func newWebhookDNSProvider(configPrefix string) (DNSProvider, error) {
+	url, err := config.GetString(configPrefix + ":url")
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook dns provider requires an url")
+	}
+	headers := map[string]string{}
+	rawHeaders, _ := config.Get(configPrefix + ":headers")
+	if rawHeaders != nil {
+		h, ok := rawHeaders.(map[interface{}]interface{})
+		if !ok {
+			return nil, errors.Errorf("invalid header configuration: %v", rawHeaders)
+		}
+		for k := range h {
+			name, ok := k.(string)
+			if !ok {
+				return nil, errors.Errorf("invalid header configuration: %v", rawHeaders)
+			}
+			headers[name], _ = config.GetString(configPrefix + ":headers:" + name)
+		}
+	}
+	return &webhookDNSProvider{
+		url:     url,
+		headers: headers,
+		client:  tsuruNet.Dial15Full60ClientNoKeepAlive,
+	}, nil
+}
+
+func (p *webhookDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	return p.do(ctx, http.MethodPost, fqdn, value)
+}
+
+func (p *webhookDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.do(ctx, http.MethodDelete, fqdn, value)
+}
+
+func (p *webhookDNSProvider) do(ctx context.Context, method, fqdn, value string) error {
+	body, err := json.Marshal(dnsRecord{FQDN: fqdn, Value: value})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	rsp, err := p.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(rsp.Body)
+		return errors.Errorf("invalid status code %d from dns webhook: %q", rsp.StatusCode, string(data))
+	}
+	return nil
+}
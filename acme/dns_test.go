@@ -0,0 +1,84 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/tsuru/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestWebhookDNSProvider(c *check.C) {
+	var requests []string
+	var records []dnsRecord
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.Header.Get("Authorization"))
+		var record dnsRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		records = append(records, record)
+	}))
+	defer srv.Close()
+	config.Set("acme:dns:url", srv.URL)
+	config.Set("acme:dns:headers", map[interface{}]interface{}{"Authorization": "Bearer abc"})
+	defer config.Unset("acme:dns")
+	provider, err := getDNSProvider("webhook", "acme:dns")
+	c.Assert(err, check.IsNil)
+	err = provider.Present(context.Background(), "_acme-challenge.app.io.", "value1")
+	c.Assert(err, check.IsNil)
+	err = provider.CleanUp(context.Background(), "_acme-challenge.app.io.", "value1")
+	c.Assert(err, check.IsNil)
+	c.Assert(requests, check.DeepEquals, []string{"POST Bearer abc", "DELETE Bearer abc"})
+	expected := dnsRecord{FQDN: "_acme-challenge.app.io.", Value: "value1"}
+	c.Assert(records, check.DeepEquals, []dnsRecord{expected, expected})
+}
+
+func (s *S) TestWebhookDNSProviderError(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unknown zone"))
+	}))
+	defer srv.Close()
+	config.Set("acme:dns:url", srv.URL)
+	defer config.Unset("acme:dns")
+	provider, err := getDNSProvider("webhook", "acme:dns")
+	c.Assert(err, check.IsNil)
+	err = provider.Present(context.Background(), "_acme-challenge.app.io.", "value1")
+	c.Assert(err, check.ErrorMatches, `invalid status code 400 from dns webhook: "unknown zone"`)
+}
+
+func (s *S) TestGetDNSProviderUnknown(c *check.C) {
+	_, err := getDNSProvider("unknown", "acme:dns")
+	c.Assert(err, check.ErrorMatches, `unknown acme dns provider: "unknown"`)
+}
+
+func (s *S) TestDNSSolver(c *check.C) {
+	provider := &fakeDNSProvider{records: map[string]string{}}
+	solver := &dnsSolver{provider: provider}
+	c.Assert(solver.challengeType(), check.Equals, ChallengeDNS01)
+	err := solver.present(context.Background(), nil, "app.io", "token", "value1")
+	c.Assert(err, check.IsNil)
+	c.Assert(provider.records, check.DeepEquals, map[string]string{"_acme-challenge.app.io.": "value1"})
+	err = solver.cleanUp(context.Background(), nil, "app.io", "token", "value1")
+	c.Assert(err, check.IsNil)
+	c.Assert(provider.records, check.HasLen, 0)
+}
+
+type fakeDNSProvider struct {
+	records map[string]string
+}
+
+func (p *fakeDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	p.records[fqdn] = value
+	return nil
+}
+
+func (p *fakeDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	delete(p.records, fqdn)
+	return nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/router"
+	xacme "golang.org/x/crypto/acme"
+)
+
+const (
+	ChallengeHTTP01 = "http-01"
+	ChallengeDNS01  = "dns-01"
+)
+
+// issuedCertificate is a PEM encoded certificate chain and its private key.
+type issuedCertificate struct {
+	Certificate string
+	Key         string
+	NotAfter    time.Time
+}
+
+type issuer interface {
+	issue(ctx context.Context, a *app.App, cname string, w io.Writer) (*issuedCertificate, error)
+}
+
+// challengeSolver publishes the response to a challenge of the ACME
+// certificate authority, proving control over the cname.
+type challengeSolver interface {
+	challengeType() string
+	present(ctx context.Context, a *app.App, cname, token, value string) error
+	cleanUp(ctx context.Context, a *app.App, cname, token, value string) error
+}
+
+// httpSolver answers HTTP-01 challenges using the app routers.
+type httpSolver struct{}
+
+func (httpSolver) challengeType() string {
+	return ChallengeHTTP01
+}
+
+func (httpSolver) present(ctx context.Context, a *app.App, cname, token, value string) error {
+	routers, err := acmeRouters(a)
+	if err != nil {
+		return err
+	}
+	for _, r := range routers {
+		err = r.AddACMEChallenge(a, cname, token, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (httpSolver) cleanUp(ctx context.Context, a *app.App, cname, token, value string) error {
+	routers, err := acmeRouters(a)
+	if err != nil {
+		return err
+	}
+	for _, r := range routers {
+		err = r.RemoveACMEChallenge(a, cname, token)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func acmeRouters(a *app.App) ([]router.ACMERouter, error) {
+	var routers []router.ACMERouter
+	for _, appRouter := range a.GetRouters() {
+		r, err := router.Get(appRouter.Name)
+		if err != nil {
+			return nil, err
+		}
+		if acmeRouter, ok := r.(router.ACMERouter); ok {
+			routers = append(routers, acmeRouter)
+		}
+	}
+	if len(routers) == 0 {
+		return nil, errors.New("no router with acme challenge support")
+	}
+	return routers, nil
+}
+
+// dnsSolver answers DNS-01 challenges using a DNS provider.
+type dnsSolver struct {
+	provider DNSProvider
+}
+
+func (s *dnsSolver) challengeType() string {
+	return ChallengeDNS01
+}
+
+func (s *dnsSolver) present(ctx context.Context, a *app.App, cname, token, value string) error {
+	return s.provider.Present(ctx, dnsChallengeFQDN(cname), value)
+}
+
+func (s *dnsSolver) cleanUp(ctx context.Context, a *app.App, cname, token, value string) error {
+	return s.provider.CleanUp(ctx, dnsChallengeFQDN(cname), value)
+}
+
+func dnsChallengeFQDN(cname string) string {
+	return "_acme-challenge." + cname + "."
+}
+
+// acmeIssuer issues certificates using an ACME certificate authority. The
+// account key is created and registered on the first issuance and stored in
+// the database, shared by every tsuru api instance.
+type acmeIssuer struct {
+	client *xacme.Client
+	email  string
+	solver challengeSolver
+	mu     sync.Mutex
+}
+
+type account struct {
+	DirectoryURL string `bson:"_id"`
+	URI          string
+	Key          string
+}
+
+func (i *acmeIssuer) ensureAccount(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.client.Key != nil {
+		return nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	accounts := conn.Collection("acme_accounts")
+	var acc account
+	err = accounts.FindId(i.client.DirectoryURL).One(&acc)
+	if err == nil {
+		block, _ := pem.Decode([]byte(acc.Key))
+		if block == nil {
+			return errors.New("invalid acme account key")
+		}
+		var key crypto.Signer
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return errors.Wrap(err, "invalid acme account key")
+		}
+		i.client.Key = key
+		return nil
+	}
+	if err != mgo.ErrNotFound {
+		return err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	i.client.Key = key
+	var contact []string
+	if i.email != "" {
+		contact = []string{"mailto:" + i.email}
+	}
+	registered, err := i.client.Register(ctx, &xacme.Account{Contact: contact}, xacme.AcceptTOS)
+	if err != nil {
+		i.client.Key = nil
+		return errors.Wrap(err, "unable to register acme account")
+	}
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return err
+	}
+	return accounts.Insert(account{DirectoryURL: i.client.DirectoryURL, URI: registered.URI, Key: keyPEM})
+}
+
+func (i *acmeIssuer) issue(ctx context.Context, a *app.App, cname string, w io.Writer) (*issuedCertificate, error) {
+	err := i.ensureAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := i.client.AuthorizeOrder(ctx, xacme.DomainIDs(cname))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create order for %q", cname)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		var authz *xacme.Authorization
+		authz, err = i.client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get authorization for %q", cname)
+		}
+		if authz.Status == xacme.StatusValid {
+			continue
+		}
+		err = i.authorize(ctx, a, cname, authz, w)
+		if err != nil {
+			return nil, err
+		}
+	}
+	order, err = i.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, errors.Wrapf(err, "order for %q failed", cname)
+	}
+	fmt.Fprintf(w, "Requesting certificate for %q\n", cname)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: cname},
+		DNSNames: []string{cname},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	der, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create certificate for %q", cname)
+	}
+	if len(der) == 0 {
+		return nil, errors.Errorf("empty certificate chain for %q", cname)
+	}
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var certPEM []byte
+	for _, b := range der {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+	return &issuedCertificate{
+		Certificate: string(certPEM),
+		Key:         keyPEM,
+		NotAfter:    leaf.NotAfter,
+	}, nil
+}
+
+func (i *acmeIssuer) authorize(ctx context.Context, a *app.App, cname string, authz *xacme.Authorization, w io.Writer) error {
+	var chal *xacme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == i.solver.challengeType() {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return errors.Errorf("challenge %q not offered for %q", i.solver.challengeType(), cname)
+	}
+	var value string
+	var err error
+	if chal.Type == ChallengeDNS01 {
+		value, err = i.client.DNS01ChallengeRecord(chal.Token)
+	} else {
+		value, err = i.client.HTTP01ChallengeResponse(chal.Token)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Answering %s challenge for %q\n", chal.Type, cname)
+	err = i.solver.present(ctx, a, cname, chal.Token, value)
+	if err != nil {
+		return errors.Wrapf(err, "unable to answer %s challenge", chal.Type)
+	}
+	defer func() {
+		if cleanErr := i.solver.cleanUp(ctx, a, cname, chal.Token, value); cleanErr != nil {
+			log.Errorf("[acme] unable to clean up %s challenge for %q: %s", chal.Type, cname, cleanErr)
+		}
+	}()
+	_, err = i.client.Accept(ctx, chal)
+	if err != nil {
+		return errors.Wrapf(err, "unable to accept %s challenge", chal.Type)
+	}
+	_, err = i.client.WaitAuthorization(ctx, authz.URI)
+	if err != nil {
+		return errors.Wrapf(err, "authorization for %q failed", cname)
+	}
+	return nil
+}
+
+func encodeECKey(key *ecdsa.PrivateKey) (string, error) {
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})), nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"context"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/router/routertest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestHTTPSolver(c *check.C) {
+	a := &app.App{Name: "myapp", Routers: []appTypes.AppRouter{{Name: "fake"}, {Name: "fake-tls"}}}
+	solver := httpSolver{}
+	c.Assert(solver.challengeType(), check.Equals, ChallengeHTTP01)
+	err := solver.present(context.Background(), a, "app.io", "token1", "token1.thumb")
+	c.Assert(err, check.IsNil)
+	c.Assert(routertest.TLSRouter.Challenges, check.DeepEquals, map[string]string{"app.io/token1": "token1.thumb"})
+	err = solver.cleanUp(context.Background(), a, "app.io", "token1", "token1.thumb")
+	c.Assert(err, check.IsNil)
+	c.Assert(routertest.TLSRouter.Challenges, check.HasLen, 0)
+}
+
+func (s *S) TestHTTPSolverNoACMERouter(c *check.C) {
+	a := &app.App{Name: "myapp", Routers: []appTypes.AppRouter{{Name: "fake"}}}
+	err := httpSolver{}.present(context.Background(), a, "app.io", "token1", "token1.thumb")
+	c.Assert(err, check.ErrorMatches, "no router with acme challenge support")
+}
@@ -0,0 +1,405 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db/lease"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/router"
+	"github.com/tsuru/tsuru/servicemanager"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+	xacme "golang.org/x/crypto/acme"
+)
+
+const (
+	leaseName = "acme"
+	ownerName = "acme"
+
+	issueTimeout = 5 * time.Minute
+	minBackoff   = 5 * time.Minute
+	maxBackoff   = 24 * time.Hour
+)
+
+// Manager periodically issues certificates for app cnames without a valid
+// certificate and renews the ones close to their expiration. Only one tsuru
+// api instance talks to the certificate authority at a time, the leader is
+// elected using a lease stored in the database which is renewed before each
+// cname is handled.
+type Manager struct {
+	RunInterval time.Duration
+	RenewBefore time.Duration
+	issuer      issuer
+	instance    string
+	done        chan bool
+	running     bool
+}
+
+// Initialize starts the certificates manager if it's enabled in the acme
+// config.
+func Initialize() error {
+	enabled, _ := config.GetBool("acme:enabled")
+	if !enabled {
+		return nil
+	}
+	manager, err := newManager()
+	if err != nil {
+		return err
+	}
+	shutdown.Register(manager)
+	manager.running = true
+	go manager.run()
+	return nil
+}
+
+func newManager() (*Manager, error) {
+	instance, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return nil, err
+	}
+	iss, err := newIssuer()
+	if err != nil {
+		return nil, err
+	}
+	runInterval, _ := config.GetInt("acme:run-interval")
+	renewBefore, _ := config.GetInt("acme:renew-before")
+	manager := &Manager{
+		RunInterval: time.Duration(runInterval) * time.Second,
+		RenewBefore: time.Duration(renewBefore) * 24 * time.Hour,
+		issuer:      iss,
+		instance:    instance.Name,
+		done:        make(chan bool),
+	}
+	if manager.RunInterval == 0 {
+		manager.RunInterval = time.Minute
+	}
+	if manager.RenewBefore == 0 {
+		manager.RenewBefore = 30 * 24 * time.Hour
+	}
+	return manager, nil
+}
+
+func newIssuer() (*acmeIssuer, error) {
+	directoryURL, _ := config.GetString("acme:directory-url")
+	if directoryURL == "" {
+		directoryURL = xacme.LetsEncryptURL
+	}
+	email, _ := config.GetString("acme:email")
+	challenge, _ := config.GetString("acme:challenge")
+	var solver challengeSolver
+	switch challenge {
+	case "", ChallengeHTTP01:
+		solver = httpSolver{}
+	case ChallengeDNS01:
+		providerName, _ := config.GetString("acme:dns:type")
+		provider, err := getDNSProvider(providerName, "acme:dns")
+		if err != nil {
+			return nil, err
+		}
+		solver = &dnsSolver{provider: provider}
+	default:
+		return nil, errors.Errorf("invalid acme challenge %q, must be %q or %q", challenge, ChallengeHTTP01, ChallengeDNS01)
+	}
+	return &acmeIssuer{
+		client: &xacme.Client{DirectoryURL: directoryURL},
+		email:  email,
+		solver: solver,
+	}, nil
+}
+
+func (m *Manager) run() {
+	for {
+		err := m.runOnce()
+		if err != nil {
+			log.Errorf("[acme] %s", err)
+		}
+		select {
+		case <-m.done:
+			return
+		case <-time.After(m.RunInterval):
+		}
+	}
+}
+
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if !m.running {
+		return nil
+	}
+	m.done <- true
+	m.running = false
+	return m.lease().Release()
+}
+
+func (m *Manager) String() string {
+	return "acme certificates manager"
+}
+
+// lease returns the manager lease for the current instance. Issuing a
+// certificate may take up to issueTimeout, the lease must outlive it.
+func (m *Manager) lease() *lease.Lease {
+	return &lease.Lease{Name: leaseName, Owner: m.instance, Duration: 3*m.RunInterval + issueTimeout}
+}
+
+func (m *Manager) runOnce() (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = errors.Errorf("recovered panic, we can never stop! panic: %v", r)
+		}
+	}()
+	leader, err := m.lease().Acquire()
+	if err != nil {
+		return errors.Wrap(err, "unable to acquire leadership")
+	}
+	if !leader {
+		return nil
+	}
+	return m.reconcile(time.Now().UTC())
+}
+
+// reconcile ensures every app cname has a certificate valid for longer than
+// RenewBefore and removes the state of cnames no longer in use.
+func (m *Manager) reconcile(now time.Time) error {
+	apps, err := app.List(&app.Filter{WithCNames: true})
+	if err != nil {
+		return errors.Wrap(err, "unable to list apps with cnames")
+	}
+	certs, err := listCertificates(nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list certificates")
+	}
+	existing := make(map[string]*Certificate, len(certs))
+	for i := range certs {
+		existing[certs[i].CName] = &certs[i]
+	}
+	inUse := map[string]bool{}
+	for i := range apps {
+		a := &apps[i]
+		for _, cname := range a.CName {
+			inUse[cname] = true
+			leader, err := m.lease().Acquire()
+			if err != nil {
+				return errors.Wrap(err, "unable to renew leadership")
+			}
+			if !leader {
+				return errors.New("leadership lost, stopping run")
+			}
+			cert := existing[cname]
+			if cert == nil {
+				cert = &Certificate{CName: cname, App: a.Name}
+			}
+			err = m.ensureCertificate(a, cert, now)
+			if err != nil {
+				log.Errorf("[acme] unable to ensure certificate for %q in app %q: %s", cname, a.Name, err)
+			}
+		}
+	}
+	for cname := range existing {
+		if inUse[cname] {
+			continue
+		}
+		err = removeCertificate(cname)
+		if err != nil {
+			log.Errorf("[acme] unable to remove certificate state for %q: %s", cname, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) ensureCertificate(a *app.App, cert *Certificate, now time.Time) error {
+	routers := appRouterNames(a)
+	if cert.App != a.Name {
+		cert.App = a.Name
+		cert.Routers = nil
+	}
+	if cert.Status == StatusError && now.Before(cert.NextAttempt) {
+		return nil
+	}
+	renewAt := now.Add(m.RenewBefore)
+	if cert.Status != StatusError && cert.NotAfter.After(renewAt) && sameRouters(cert.Routers, routers) {
+		return nil
+	}
+	notAfter, current, err := installedCertificate(a, cert.CName)
+	if err != nil {
+		return m.failed(cert, err, now)
+	}
+	if notAfter.After(renewAt) {
+		cert.Status = StatusManual
+		if cert.Certificate != "" && current == cert.Certificate {
+			cert.Status = StatusIssued
+		}
+		cert.NotAfter = notAfter
+		cert.Routers = routers
+		cert.LastError = ""
+		cert.Failures = 0
+		return saveCertificate(cert)
+	}
+	if cert.Certificate != "" && cert.NotAfter.After(renewAt) {
+		// The stored certificate is still valid, a router added to the app
+		// is missing it.
+		err = a.SetCertificate(cert.CName, cert.Certificate, cert.Key)
+		if err != nil {
+			return m.failed(cert, err, now)
+		}
+		cert.Status = StatusIssued
+		cert.Routers = routers
+		cert.LastError = ""
+		cert.Failures = 0
+		return saveCertificate(cert)
+	}
+	return m.issue(a, cert, routers, now)
+}
+
+func (m *Manager) issue(a *app.App, cert *Certificate, routers []string, now time.Time) (err error) {
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:       permission.PermAppUpdateCertificateSet,
+		RawOwner:   event.Owner{Type: event.OwnerTypeInternal, Name: ownerName},
+		CustomData: event.FormToCustomData(url.Values{"cname": []string{cert.CName}}),
+		Allowed: event.Allowed(permission.PermAppReadEvents, append(permission.Contexts(permTypes.CtxTeam, a.Teams),
+			permission.Context(permTypes.CtxApp, a.Name),
+			permission.Context(permTypes.CtxPool, a.Pool),
+		)...),
+	})
+	if err != nil {
+		if _, ok := err.(event.ErrEventLocked); ok {
+			log.Debugf("[acme] skipping certificate for %q, app %q locked", cert.CName, a.Name)
+			return nil
+		}
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	ctx, cancel := context.WithTimeout(context.Background(), issueTimeout)
+	defer cancel()
+	issued, err := m.issuer.issue(ctx, a, cert.CName, evt)
+	if err == nil {
+		err = a.SetCertificate(cert.CName, issued.Certificate, issued.Key)
+	}
+	if err != nil {
+		return m.failed(cert, err, now)
+	}
+	evt.Logf("Certificate for %q installed, valid until %s", cert.CName, issued.NotAfter.UTC().Format(time.RFC3339))
+	cert.Status = StatusIssued
+	cert.Certificate = issued.Certificate
+	cert.Key = issued.Key
+	cert.NotAfter = issued.NotAfter
+	cert.IssuedAt = now
+	cert.Routers = routers
+	cert.LastError = ""
+	cert.Failures = 0
+	cert.NextAttempt = time.Time{}
+	return saveCertificate(cert)
+}
+
+// failed records an error with the certificate of a cname, the next attempt
+// is delayed exponentially to respect the certificate authority rate limits.
+func (m *Manager) failed(cert *Certificate, cause error, now time.Time) error {
+	cert.Status = StatusError
+	cert.LastError = cause.Error()
+	cert.Failures++
+	backoff := minBackoff << uint(cert.Failures-1)
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	}
+	if retryAfter, ok := xacme.RateLimit(errors.Cause(cause)); ok && retryAfter > backoff {
+		backoff = retryAfter
+	}
+	cert.NextAttempt = now.Add(backoff)
+	err := saveCertificate(cert)
+	if err != nil {
+		return err
+	}
+	return cause
+}
+
+// installedCertificate returns the earliest expiration among the
+// certificates for cname in the app routers with TLS support, and the
+// certificate found in them if it's the same in every router. A zero time is
+// returned if any router has no valid certificate for cname.
+func installedCertificate(a *app.App, cname string) (time.Time, string, error) {
+	var notAfter time.Time
+	var current string
+	found := false
+	for _, appRouter := range a.GetRouters() {
+		r, err := router.Get(appRouter.Name)
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		tlsRouter, ok := r.(router.TLSRouter)
+		if !ok {
+			continue
+		}
+		data, err := tlsRouter.GetCertificate(a, cname)
+		if err == router.ErrCertificateNotFound {
+			return time.Time{}, "", nil
+		}
+		if err != nil {
+			return time.Time{}, "", errors.Wrapf(err, "error in router %q", appRouter.Name)
+		}
+		expiration := certificateExpiration(data, cname)
+		if expiration.IsZero() {
+			return time.Time{}, "", nil
+		}
+		if !found || expiration.Before(notAfter) {
+			notAfter = expiration
+		}
+		if found && data != current {
+			current = ""
+		} else if !found {
+			current = data
+		}
+		found = true
+	}
+	if !found {
+		return time.Time{}, "", errors.New("no router with tls support")
+	}
+	return notAfter, current, nil
+}
+
+func certificateExpiration(data, cname string) time.Time {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return time.Time{}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || cert.VerifyHostname(cname) != nil {
+		return time.Time{}
+	}
+	return cert.NotAfter
+}
+
+func appRouterNames(a *app.App) []string {
+	var names []string
+	for _, r := range a.GetRouters() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func sameRouters(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, name := range a {
+		set[name] = true
+	}
+	for _, name := range b {
+		if !set[name] {
+			return false
+		}
+	}
+	return true
+}
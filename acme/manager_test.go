@@ -0,0 +1,185 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/router/routertest"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) newManager(iss issuer) *Manager {
+	return &Manager{
+		RunInterval: time.Minute,
+		RenewBefore: 30 * 24 * time.Hour,
+		issuer:      iss,
+		instance:    "instance1",
+	}
+}
+
+func (s *S) TestReconcileIssuesMissingCertificate(c *check.C) {
+	s.newApp(c, "myapp", "app.io")
+	now := time.Now().UTC().Truncate(time.Second)
+	iss := &fakeIssuer{c: c, notAfter: now.Add(90 * 24 * time.Hour)}
+	err := s.newManager(iss).reconcile(now)
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.DeepEquals, []string{"myapp/app.io"})
+	certs, err := AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs, check.HasLen, 1)
+	c.Assert(certs[0].Status, check.Equals, StatusIssued)
+	c.Assert(certs[0].NotAfter.Equal(iss.notAfter), check.Equals, true)
+	c.Assert(certs[0].Routers, check.DeepEquals, []string{"fake-tls"})
+	c.Assert(routertest.TLSRouter.Certs["app.io"], check.Equals, certs[0].Certificate)
+	c.Assert(routertest.TLSRouter.Keys["app.io"], check.Equals, certs[0].Key)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Kind:   "app.update.certificate.set",
+		Owner:  ownerName,
+	}, eventtest.HasEvent)
+	err = s.newManager(iss).reconcile(now.Add(time.Hour))
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.HasLen, 1)
+}
+
+func (s *S) TestReconcileKeepsValidManualCertificate(c *check.C) {
+	a := s.newApp(c, "myapp", "app.io")
+	now := time.Now().UTC().Truncate(time.Second)
+	manualNotAfter := now.Add(60 * 24 * time.Hour)
+	cert, key := newCertificate(c, "app.io", manualNotAfter)
+	err := a.SetCertificate("app.io", cert, key)
+	c.Assert(err, check.IsNil)
+	iss := &fakeIssuer{c: c, notAfter: now.Add(90 * 24 * time.Hour)}
+	err = s.newManager(iss).reconcile(now)
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.HasLen, 0)
+	certs, err := AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs, check.HasLen, 1)
+	c.Assert(certs[0].Status, check.Equals, StatusManual)
+	c.Assert(certs[0].NotAfter.Equal(manualNotAfter), check.Equals, true)
+	c.Assert(routertest.TLSRouter.Certs["app.io"], check.Equals, cert)
+}
+
+func (s *S) TestReconcileRenewsExpiringManualCertificate(c *check.C) {
+	a := s.newApp(c, "myapp", "app.io")
+	now := time.Now().UTC().Truncate(time.Second)
+	cert, key := newCertificate(c, "app.io", now.Add(60*24*time.Hour))
+	err := a.SetCertificate("app.io", cert, key)
+	c.Assert(err, check.IsNil)
+	iss := &fakeIssuer{c: c, notAfter: now.Add(120 * 24 * time.Hour)}
+	manager := s.newManager(iss)
+	err = manager.reconcile(now)
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.HasLen, 0)
+	err = manager.reconcile(now.Add(40 * 24 * time.Hour))
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.DeepEquals, []string{"myapp/app.io"})
+	certs, err := AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs, check.HasLen, 1)
+	c.Assert(certs[0].Status, check.Equals, StatusIssued)
+	c.Assert(certs[0].NotAfter.Equal(iss.notAfter), check.Equals, true)
+	c.Assert(routertest.TLSRouter.Certs["app.io"], check.Equals, certs[0].Certificate)
+}
+
+func (s *S) TestReconcileFailureBackoff(c *check.C) {
+	s.newApp(c, "myapp", "app.io")
+	now := time.Now().UTC().Truncate(time.Second)
+	iss := &fakeIssuer{c: c, err: errors.New("challenge failed")}
+	manager := s.newManager(iss)
+	err := manager.reconcile(now)
+	c.Assert(err, check.IsNil)
+	certs, err := AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs, check.HasLen, 1)
+	c.Assert(certs[0].Status, check.Equals, StatusError)
+	c.Assert(certs[0].LastError, check.Equals, "challenge failed")
+	c.Assert(certs[0].Failures, check.Equals, 1)
+	c.Assert(certs[0].NextAttempt.Equal(now.Add(minBackoff)), check.Equals, true)
+	err = manager.reconcile(now.Add(time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.HasLen, 1)
+	err = manager.reconcile(now.Add(minBackoff))
+	c.Assert(err, check.IsNil)
+	c.Assert(iss.calls, check.HasLen, 2)
+	certs, err = AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs[0].Failures, check.Equals, 2)
+	c.Assert(certs[0].NextAttempt.Equal(now.Add(3*minBackoff)), check.Equals, true)
+	iss.err = nil
+	iss.notAfter = now.Add(90 * 24 * time.Hour)
+	err = manager.reconcile(now.Add(3 * minBackoff))
+	c.Assert(err, check.IsNil)
+	certs, err = AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs[0].Status, check.Equals, StatusIssued)
+	c.Assert(certs[0].LastError, check.Equals, "")
+	c.Assert(certs[0].Failures, check.Equals, 0)
+}
+
+func (s *S) TestReconcileRemovesUnusedCNames(c *check.C) {
+	s.newApp(c, "myapp", "app.io")
+	err := saveCertificate(&Certificate{CName: "old.io", App: "myapp", Status: StatusIssued})
+	c.Assert(err, check.IsNil)
+	now := time.Now().UTC().Truncate(time.Second)
+	iss := &fakeIssuer{c: c, notAfter: now.Add(90 * 24 * time.Hour)}
+	err = s.newManager(iss).reconcile(now)
+	c.Assert(err, check.IsNil)
+	certs, err := AppCertificates("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(certs, check.HasLen, 1)
+	c.Assert(certs[0].CName, check.Equals, "app.io")
+}
+
+func (s *S) TestManagerLeadership(c *check.C) {
+	m1 := s.newManager(nil)
+	m2 := s.newManager(nil)
+	m2.instance = "instance2"
+	leader, err := m1.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	leader, err = m2.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, false)
+	err = m1.lease().Release()
+	c.Assert(err, check.IsNil)
+	leader, err = m2.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+}
+
+func (s *S) TestReconcileStopsWithoutLeadership(c *check.C) {
+	s.newApp(c, "myapp", "app.io")
+	other := s.newManager(nil)
+	other.instance = "instance2"
+	leader, err := other.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	now := time.Now().UTC().Truncate(time.Second)
+	iss := &fakeIssuer{c: c, notAfter: now.Add(90 * 24 * time.Hour)}
+	err = s.newManager(iss).reconcile(now)
+	c.Assert(err, check.ErrorMatches, "leadership lost, stopping run")
+	c.Assert(iss.calls, check.HasLen, 0)
+}
+
+func (s *S) TestCertificateExpiration(c *check.C) {
+	notAfter := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	cert, _ := newCertificate(c, "app.io", notAfter)
+	c.Assert(certificateExpiration(cert, "app.io").Equal(notAfter), check.Equals, true)
+	c.Assert(certificateExpiration(cert, "other.io").IsZero(), check.Equals, true)
+	c.Assert(certificateExpiration("invalid", "app.io").IsZero(), check.Equals, true)
+}
+
+func (s *S) TestSameRouters(c *check.C) {
+	c.Assert(sameRouters(nil, nil), check.Equals, true)
+	c.Assert(sameRouters([]string{"a", "b"}, []string{"b", "a"}), check.Equals, true)
+	c.Assert(sameRouters([]string{"a"}, []string{"a", "b"}), check.Equals, false)
+	c.Assert(sameRouters([]string{"a", "c"}, []string{"a", "b"}), check.Equals, false)
+}
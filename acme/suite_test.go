@@ -0,0 +1,114 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/dbtest"
+	"github.com/tsuru/tsuru/router/routertest"
+	servicemock "github.com/tsuru/tsuru/servicemanager/mock"
+	_ "github.com/tsuru/tsuru/storage/mongodb"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+var _ = check.Suite(&S{})
+
+type S struct {
+	conn        *db.Storage
+	mockService servicemock.MockService
+}
+
+func (s *S) SetUpSuite(c *check.C) {
+	config.Set("log:disable-syslog", true)
+	config.Set("database:url", "127.0.0.1:27017?maxPoolSize=100")
+	config.Set("database:name", "acme_tests_s")
+	config.Set("routers:fake-tls:type", "fake-tls")
+	config.Set("routers:fake:type", "fake")
+}
+
+func (s *S) SetUpTest(c *check.C) {
+	routertest.FakeRouter.Reset()
+	routertest.TLSRouter.Reset()
+	var err error
+	s.conn, err = db.Conn()
+	c.Assert(err, check.IsNil)
+	dbtest.ClearAllCollections(s.conn.Apps().Database)
+	servicemock.SetMockService(&s.mockService)
+}
+
+func (s *S) TearDownTest(c *check.C) {
+	app.GetAppRouterUpdater().Shutdown(context.Background())
+	s.conn.Close()
+}
+
+func (s *S) TearDownSuite(c *check.C) {
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	dbtest.ClearAllCollections(conn.Apps().Database)
+}
+
+func (s *S) newApp(c *check.C, name string, cnames ...string) *app.App {
+	a := &app.App{
+		Name:    name,
+		CName:   cnames,
+		Routers: []appTypes.AppRouter{{Name: "fake-tls"}},
+	}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	err = routertest.TLSRouter.AddBackend(a)
+	c.Assert(err, check.IsNil)
+	return a
+}
+
+func newCertificate(c *check.C, cname string, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cname},
+		DNSNames:     []string{cname},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	c.Assert(err, check.IsNil)
+	keyPEM, err := encodeECKey(key)
+	c.Assert(err, check.IsNil)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), keyPEM
+}
+
+type fakeIssuer struct {
+	c        *check.C
+	notAfter time.Time
+	err      error
+	calls    []string
+}
+
+func (i *fakeIssuer) issue(ctx context.Context, a *app.App, cname string, w io.Writer) (*issuedCertificate, error) {
+	i.calls = append(i.calls, a.Name+"/"+cname)
+	if i.err != nil {
+		return nil, i.err
+	}
+	cert, key := newCertificate(i.c, cname, i.notAfter)
+	return &issuedCertificate{Certificate: cert, Key: key, NotAfter: i.notAfter}, nil
+}
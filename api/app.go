@@ -11,12 +11,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/globalsign/mgo/bson"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tsuru/tsuru/acme"
 	"github.com/tsuru/tsuru/api/context"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/bind"
@@ -300,6 +302,7 @@ type inputApp struct {
 	Router      string
 	RouterOpts  map[string]string
 	Tags        []string
+	Metadata    appTypes.Metadata
 }
 
 func autoTeamOwner(t auth.Token, perm *permission.PermissionScheme) (string, error) {
@@ -469,6 +472,7 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 		Platform:       InputValue(r, "platform"),
 		UpdatePlatform: imageReset,
 		RouterOpts:     ia.RouterOpts,
+		Metadata:       ia.Metadata,
 	}
 	tags, _ := InputValues(r, "tag")
 	updateData.Tags = append(updateData.Tags, tags...) // for compatibility
@@ -515,8 +519,11 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if updateData.UpdatePlatform {
 		wantedPerms = append(wantedPerms, permission.PermAppUpdateImageReset)
 	}
+	if !updateData.Metadata.Empty() {
+		wantedPerms = append(wantedPerms, permission.PermAppUpdateMetadata)
+	}
 	if len(wantedPerms) == 0 {
-		msg := "Neither the description, tags, plan, pool, team owner or platform were set. You must define at least one."
+		msg := "Neither the description, tags, plan, pool, team owner, platform or metadata were set. You must define at least one."
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
 	}
 	for _, perm := range wantedPerms {
@@ -663,6 +670,200 @@ func removeUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err erro
 	return a.RemoveUnits(n, processName, evt)
 }
 
+// title: units autoscale info
+// path: /apps/{app}/units/autoscale
+// method: GET
+// produce: application/json
+// responses:
+//   200: Ok
+//   204: No content
+//   401: Unauthorized
+//   404: App not found
+func autoScaleUnitsInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	canRead := permission.Check(t, permission.PermAppRead,
+		contextsForApp(&a)...,
+	)
+	if !canRead {
+		return permission.ErrUnauthorized
+	}
+	info := a.AutoScaleInfo()
+	if len(info) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(info)
+}
+
+// title: add unit auto scale
+// path: /apps/{app}/units/autoscale
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func addAutoScaleUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateUnitAutoscaleAdd,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	var spec appTypes.AutoScaleSpec
+	err = ParseInput(r, &spec)
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateUnitAutoscaleAdd,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.SetAutoScale(spec)
+	if _, ok := err.(provision.InvalidProcessError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	switch err {
+	case appTypes.ErrAutoScaleNoTarget, appTypes.ErrAutoScaleInvalidUnits,
+		appTypes.ErrAutoScaleInvalidPct, appTypes.ErrAutoScaleNegativeReqs:
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return err
+}
+
+// title: remove unit auto scale
+// path: /apps/{app}/units/autoscale
+// method: DELETE
+// responses:
+//   200: Ok
+//   401: Unauthorized
+//   404: App or autoscale not found
+func removeAutoScaleUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateUnitAutoscaleRemove,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateUnitAutoscaleRemove,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.RemoveAutoScale(InputValue(r, "process"))
+	if err == appTypes.ErrAutoScaleNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+type inputProcess struct {
+	Plan          string
+	Memory        int64
+	MemoryRequest int64
+	Swap          int64
+	CpuShare      int
+	CPURequest    int
+	CPULimit      int
+	NoRestart     bool
+}
+
+// title: update app process
+// path: /apps/{app}/processes/{process}
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// produce: application/x-json-stream
+// responses:
+//   200: Process updated
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func updateAppProcess(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	var input inputProcess
+	err = ParseInput(r, &input)
+	if err != nil {
+		return err
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdatePlanProcess,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdatePlanProcess,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	evt.SetLogWriter(writer)
+	err = a.UpdateProcess(app.UpdateProcessArgs{
+		Process: r.URL.Query().Get(":process"),
+		Plan:    input.Plan,
+		Resources: appTypes.ProcessResources{
+			Memory:        input.Memory,
+			MemoryRequest: input.MemoryRequest,
+			Swap:          input.Swap,
+			CpuShare:      input.CpuShare,
+			CPURequest:    input.CPURequest,
+			CPULimit:      input.CPULimit,
+		},
+		NoRestart: input.NoRestart,
+		Writer:    evt,
+	})
+	if _, ok := err.(provision.InvalidProcessError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	switch err {
+	case appTypes.ErrPlanNotFound, appTypes.ErrProcessNegativeResources,
+		appTypes.ErrProcessMemoryRequest, appTypes.ErrProcessCPURequest:
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return err
+}
+
 // title: set unit status
 // path: /apps/{app}/units/{unit}
 // method: POST
@@ -837,6 +1038,7 @@ func revokeAppAccess(w http.ResponseWriter, r *http.Request, t auth.Token) (err
 // method: POST
 // responses:
 //   200: Ok
+//   202: Detached run started
 //   401: Unauthorized
 //   404: App not found
 func runCommand(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
@@ -858,6 +1060,9 @@ func runCommand(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if detach, _ := strconv.ParseBool(InputValue(r, "detach")); detach {
+		return runCommandDetached(w, r, t, &a, command)
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppRun,
@@ -880,6 +1085,122 @@ func runCommand(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	return a.Run(command, evt, args)
 }
 
+// runCommandDetached starts the command in a new unit of the app, returning
+// the run id without waiting for it to finish. The event of the run is done
+// by the app when the command finishes.
+func runCommandDetached(w http.ResponseWriter, r *http.Request, t auth.Token, a *app.App, command string) error {
+	evt, err := event.New(&event.Opts{
+		Target:        appTarget(a.Name),
+		Kind:          permission.PermAppRun,
+		Owner:         t,
+		CustomData:    event.FormToCustomData(InputFields(r)),
+		Allowed:       event.Allowed(permission.PermAppReadEvents, contextsForApp(a)...),
+		AllowedCancel: event.Allowed(permission.PermAppRun, contextsForApp(a)...),
+		Cancelable:    true,
+		// Detached runs may take hours, they must not block other
+		// operations on the app.
+		DisableLock: true,
+	})
+	if err != nil {
+		return err
+	}
+	a.RunDetached(command, evt)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(map[string]string{"id": evt.UniqueID.Hex()})
+}
+
+// title: app run list
+// path: /apps/{app}/runs
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+//   404: App not found
+func appRunList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppReadEvents, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	runs, err := a.ListRuns()
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(runs)
+}
+
+// title: app run info
+// path: /apps/{app}/runs/{id}
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: App or run not found
+func appRunInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppReadEvents, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	run, err := a.GetRun(r.URL.Query().Get(":id"))
+	if err != nil {
+		if err == appTypes.ErrRunNotFound {
+			return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+		}
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(run)
+}
+
+// title: app run cancel
+// path: /apps/{app}/runs/{id}/cancel
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   204: Cancel requested
+//   400: Run is not running or canceling is not supported
+//   401: Unauthorized
+//   404: App or run not found
+func appRunCancel(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppRun, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	reason := InputValue(r, "reason")
+	if reason == "" {
+		reason = "canceled by " + t.GetUserName()
+	}
+	err = a.CancelRun(r.URL.Query().Get(":id"), reason, t.GetUserName())
+	switch err {
+	case nil:
+	case appTypes.ErrRunNotFound:
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	case event.ErrNotCancelable, event.ErrCancelAlreadyRequested, appTypes.ErrRunCancelNotSupported:
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	default:
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 // title: get envs
 // path: /apps/{app}/env
 // method: GET
@@ -1178,8 +1499,45 @@ func appLog(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	if invert && follow {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: `Log following with inverted filters are not supported yet.`}
 	}
-
-	filterLog := appTypes.Applog{Source: source, Unit: unit}
+	args := appTypes.ListLogArgs{
+		Source:        source,
+		Unit:          unit,
+		Limit:         lines,
+		InvertFilters: invert,
+		Message:       r.URL.Query().Get("message"),
+		Level:         r.URL.Query().Get("level"),
+		Token:         t,
+	}
+	for _, field := range r.URL.Query()["field"] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || !logFieldNameRegexp.MatchString(parts[0]) {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf(`Parameter "field" must be in the format <name>=<value>, got %q.`, field)}
+		}
+		if args.Fields == nil {
+			args.Fields = map[string]string{}
+		}
+		args.Fields[parts[0]] = parts[1]
+	}
+	if args.Since, err = logTimeParam(r, "since"); err != nil {
+		return err
+	}
+	if args.Until, err = logTimeParam(r, "until"); err != nil {
+		return err
+	}
+	if args.Message != "" {
+		if _, err = regexp.Compile(args.Message); err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf(`Parameter "message" must be a valid regular expression: %v`, err)}
+		}
+	}
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		args.Cursor, err = appTypes.ParseLogCursor(c)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+	}
+	if follow && (args.Message != "" || !args.Until.IsZero() || args.Cursor != nil || len(args.FieldsFilter()) > 0) {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: `Log following with message, until, cursor, level or field filters is not supported.`}
+	}
 	a, err := getAppFromContext(appName, r)
 	if err != nil {
 		return err
@@ -1196,10 +1554,13 @@ func appLog(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 			logService = svcInstance.Instance()
 		}
 	}
-	logs, err := a.LastLogs(logService, lines, filterLog, invert, t)
+	logs, err := a.ListLogs(logService, args)
 	if err != nil {
 		return err
 	}
+	if cursor := appTypes.NextLogCursor(logs, args.Cursor); cursor != nil {
+		w.Header().Set(logCursorHeader, cursor.String())
+	}
 	encoder := json.NewEncoder(w)
 	err = encoder.Encode(logs)
 	if err != nil {
@@ -1215,6 +1576,24 @@ func appLog(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	return followLogs(r.Context(), a.Name, watcher, encoder)
 }
 
+// logCursorHeader is the response header holding the cursor to be sent
+// in the next request to list the entries preceding the returned ones.
+const logCursorHeader = "X-Tsuru-Log-Cursor"
+
+var logFieldNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func logTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf(`Parameter %q must be a date in RFC3339 format.`, name)}
+	}
+	return t, nil
+}
+
 type msgEncoder interface {
 	Encode(interface{}) error
 }
@@ -1637,6 +2016,72 @@ func swap(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	return app.Swap(app1, app2, cnameOnly)
 }
 
+type inputTraffic struct {
+	Weights []app.TrafficWeight `json:"weights"`
+}
+
+// title: app traffic split
+// path: /apps/{app}/traffic
+// method: PUT
+// consume: application/json
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+//   409: App locked
+func setAppTraffic(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	var input inputTraffic
+	err = ParseInput(r, &input)
+	if err != nil {
+		return err
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateTraffic,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	var extraTargets []event.ExtraTarget
+	for _, weight := range input.Weights {
+		if weight.App == appName {
+			continue
+		}
+		target, err := getApp(weight.App)
+		if err != nil {
+			return err
+		}
+		allowed = permission.Check(t, permission.PermAppUpdateTraffic,
+			contextsForApp(target)...,
+		)
+		if !allowed {
+			return permission.ErrUnauthorized
+		}
+		extraTargets = append(extraTargets, event.ExtraTarget{Target: appTarget(target.Name)})
+	}
+	evt, err := event.New(&event.Opts{
+		Target:       appTarget(appName),
+		ExtraTargets: extraTargets,
+		Kind:         permission.PermAppUpdateTraffic,
+		Owner:        t,
+		CustomData:   event.FormToCustomData(InputFields(r)),
+		Allowed:      event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		if _, locked := err.(event.ErrEventLocked); locked {
+			return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+		}
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return a.SetTrafficWeights(input.Weights)
+}
+
 // title: app start
 // path: /apps/{app}/start
 // method: POST
@@ -1945,6 +2390,14 @@ func unsetCertificate(w http.ResponseWriter, r *http.Request, t auth.Token) (err
 	return nil
 }
 
+// certificatesStatus is the response of the certificates list when the
+// status is requested, including the state of the certificates managed by
+// the ACME certificates manager.
+type certificatesStatus struct {
+	Routers map[string]map[string]string `json:"routers"`
+	ACME    []acme.Certificate           `json:"acme"`
+}
+
 // title: list app certificates
 // path: /apps/{app}/certificate
 // method: GET
@@ -1969,6 +2422,13 @@ func listCertificates(w http.ResponseWriter, r *http.Request, t auth.Token) erro
 	if err != nil {
 		return err
 	}
+	if withStatus, _ := strconv.ParseBool(r.URL.Query().Get("status")); withStatus {
+		acmeCerts, err := acme.AppCertificates(a.Name)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(certificatesStatus{Routers: result, ACME: acmeCerts})
+	}
 	return json.NewEncoder(w).Encode(&result)
 }
 
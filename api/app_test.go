@@ -24,8 +24,10 @@ import (
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/globalsign/mgo/bson"
 	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/acme"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/errors"
@@ -39,6 +41,7 @@ import (
 	"github.com/tsuru/tsuru/provision/provisiontest"
 	"github.com/tsuru/tsuru/repository"
 	"github.com/tsuru/tsuru/repository/repositorytest"
+	"github.com/tsuru/tsuru/router"
 	"github.com/tsuru/tsuru/router/rebuild"
 	"github.com/tsuru/tsuru/router/routertest"
 	"github.com/tsuru/tsuru/service"
@@ -1715,6 +1718,49 @@ func (s *S) TestUpdateAppWithTagsOnly(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestUpdateAppWithMetadata(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateMetadata,
+		Context: permission.Context(permTypes.CtxApp, a.Name),
+	})
+	b := strings.NewReader("metadata.labels.0.name=tier&metadata.labels.0.value=backend&metadata.annotations.0.name=tsuru.io/anti-affinity&metadata.annotations.0.value=preferred")
+	request, err := http.NewRequest("PUT", "/apps/myapp", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var gotApp app.App
+	err = s.conn.Apps().Find(bson.M{"name": "myapp"}).One(&gotApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(gotApp.Metadata, check.DeepEquals, appTypes.Metadata{
+		Labels:      []appTypes.MetadataItem{{Name: "tier", Value: "backend"}},
+		Annotations: []appTypes.MetadataItem{{Name: "tsuru.io/anti-affinity", Value: "preferred"}},
+	})
+}
+
+func (s *S) TestUpdateAppWithMetadataWithoutPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateTags,
+		Context: permission.Context(permTypes.CtxApp, a.Name),
+	})
+	b := strings.NewReader("metadata.labels.0.name=tier&metadata.labels.0.value=backend")
+	request, err := http.NewRequest("PUT", "/apps/myapp", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
 func (s *S) TestUpdateAppWithTagsWithoutPermission(c *check.C) {
 	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
@@ -1904,7 +1950,7 @@ func (s *S) TestUpdateAppWithoutFlag(c *check.C) {
 	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	recorder := httptest.NewRecorder()
 	s.testServer.ServeHTTP(recorder, request)
-	errorMessage := "Neither the description, tags, plan, pool, team owner or platform were set. You must define at least one.\n"
+	errorMessage := "Neither the description, tags, plan, pool, team owner, platform or metadata were set. You must define at least one.\n"
 	c.Check(recorder.Code, check.Equals, http.StatusBadRequest)
 	c.Check(recorder.Body.String(), check.Equals, errorMessage)
 }
@@ -2314,6 +2360,213 @@ func (s *S) TestRemoveUnitsReturns400IfNumberIsInvalid(c *check.C) {
 	}
 }
 
+func (s *S) TestAutoScaleUnitsInfo(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/myapp/units/autoscale", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 70}
+	err = s.conn.Apps().Update(bson.M{"name": a.Name}, bson.M{"$set": bson.M{"autoscale": []appTypes.AutoScaleSpec{spec}}})
+	c.Assert(err, check.IsNil)
+	request, err = http.NewRequest("GET", "/apps/myapp/units/autoscale", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var specs []appTypes.AutoScaleSpec
+	err = json.Unmarshal(recorder.Body.Bytes(), &specs)
+	c.Assert(err, check.IsNil)
+	c.Assert(specs, check.DeepEquals, []appTypes.AutoScaleSpec{spec})
+}
+
+func (s *S) TestAddAutoScaleUnits(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "tsuru/app-myapp:v1")
+	c.Assert(err, check.IsNil)
+	imgData := image.ImageMetadata{Name: "tsuru/app-myapp:v1", Processes: map[string][]string{"web": {"python app.py"}}}
+	err = imgData.Save()
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("process=web&minUnits=1&maxUnits=5&targetCPU=70")
+	request, err := http.NewRequest("POST", "/apps/myapp/units/autoscale", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.AutoScaleInfo(), check.DeepEquals, []appTypes.AutoScaleSpec{
+		{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 70},
+	})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.unit.autoscale.add",
+		StartCustomData: []map[string]interface{}{
+			{"name": "process", "value": "web"},
+			{"name": "minUnits", "value": "1"},
+			{"name": "maxUnits", "value": "5"},
+			{"name": "targetCPU", "value": "70"},
+			{"name": ":app", "value": "myapp"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddAutoScaleUnitsInvalidSpec(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("process=web&minUnits=1&maxUnits=5")
+	request, err := http.NewRequest("POST", "/apps/myapp/units/autoscale", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, appTypes.ErrAutoScaleNoTarget.Error()+"\n")
+}
+
+func (s *S) TestAddAutoScaleUnitsNoPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateUnitAutoscaleAdd,
+		Context: permission.Context(permTypes.CtxApp, "-invalid-"),
+	})
+	body := strings.NewReader("process=web&minUnits=1&maxUnits=5&targetCPU=70")
+	request, err := http.NewRequest("POST", "/apps/myapp/units/autoscale", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestRemoveAutoScaleUnits(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 70}
+	err = s.conn.Apps().Update(bson.M{"name": a.Name}, bson.M{"$set": bson.M{"autoscale": []appTypes.AutoScaleSpec{spec}}})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/apps/myapp/units/autoscale?process=web", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.AutoScaleInfo(), check.HasLen, 0)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.unit.autoscale.remove",
+		StartCustomData: []map[string]interface{}{
+			{"name": "process", "value": "web"},
+			{"name": ":app", "value": "myapp"},
+		},
+	}, eventtest.HasEvent)
+	request, err = http.NewRequest("DELETE", "/apps/myapp/units/autoscale?process=web", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestUpdateAppProcess(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	plan := appTypes.Plan{Name: "small", Memory: 67108864, CpuShare: 10}
+	s.mockService.Plan.OnFindByName = func(name string) (*appTypes.Plan, error) {
+		c.Assert(name, check.Equals, plan.Name)
+		return &plan, nil
+	}
+	s.mockService.Plan.OnList = func() ([]appTypes.Plan, error) {
+		return []appTypes.Plan{plan}, nil
+	}
+	err = image.AppendAppImageName(a.Name, "tsuru/app-myapp:v1")
+	c.Assert(err, check.IsNil)
+	imgData := image.ImageMetadata{Name: "tsuru/app-myapp:v1", Processes: map[string][]string{"web": {"python app.py"}, "worker": {"python worker.py"}}}
+	err = imgData.Save()
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("plan=small&memoryRequest=33554432&cpuLimit=500")
+	request, err := http.NewRequest("PUT", "/apps/myapp/processes/worker", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Processes, check.DeepEquals, []appTypes.Process{
+		{Name: "worker", Plan: plan, Resources: appTypes.ProcessResources{MemoryRequest: 33554432, CPULimit: 500}},
+	})
+	c.Assert(s.provisioner.Restarts(dbApp, "worker"), check.Equals, 1)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.plan.process",
+		StartCustomData: []map[string]interface{}{
+			{"name": "plan", "value": "small"},
+			{"name": "memoryRequest", "value": "33554432"},
+			{"name": "cpuLimit", "value": "500"},
+			{"name": ":app", "value": "myapp"},
+			{"name": ":process", "value": "worker"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestUpdateAppProcessInvalidResources(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("cpuRequest=1000&cpuLimit=500")
+	request, err := http.NewRequest("PUT", "/apps/myapp/processes/web", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, appTypes.ErrProcessCPURequest.Error()+"\n")
+}
+
+func (s *S) TestUpdateAppProcessNoPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdatePlanProcess,
+		Context: permission.Context(permTypes.CtxApp, "-invalid-"),
+	})
+	body := strings.NewReader("memory=67108864")
+	request, err := http.NewRequest("PUT", "/apps/myapp/processes/web", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
 func (s *S) TestSetUnitStatus(c *check.C) {
 	a := app.App{Name: "telegram", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
@@ -2915,6 +3168,142 @@ func (s *S) TestRunIsolated(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func waitRunDone(c *check.C, id string) *event.Event {
+	timeout := time.After(5 * time.Second)
+	for {
+		evt, err := event.GetByID(bson.ObjectIdHex(id))
+		c.Assert(err, check.IsNil)
+		if !evt.Running {
+			return evt
+		}
+		select {
+		case <-timeout:
+			c.Fatalf("timeout waiting for run %s to finish", id)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (s *S) TestRunDetached(c *check.C) {
+	s.provisioner.PrepareOutput([]byte("migrated"))
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 1, "web", nil)
+	url := fmt.Sprintf("/apps/%s/run", a.Name)
+	request, err := http.NewRequest("POST", url, strings.NewReader("command=./migrate&detach=true"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusAccepted)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result map[string]string
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	evt := waitRunDone(c, result["id"])
+	c.Assert(evt.Cancelable, check.Equals, true)
+	c.Assert(evt.Error, check.Equals, "")
+	c.Assert(evt.Log, check.Equals, "running './migrate'\n")
+	allExecs := s.provisioner.AllExecs()
+	c.Assert(allExecs["isolated"], check.HasLen, 1)
+	run, err := a.GetRun(result["id"])
+	c.Assert(err, check.IsNil)
+	c.Assert(run.Command, check.Equals, "./migrate")
+	c.Assert(run.Owner, check.Equals, s.token.GetUserName())
+	c.Assert(run.Running, check.Equals, false)
+	c.Assert(*run.ExitCode, check.Equals, 0)
+	c.Assert(run.Output, check.Equals, "migrated")
+}
+
+func (s *S) TestAppRunList(c *check.C) {
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/secrets/runs", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+	evt, err := event.New(&event.Opts{
+		Target:      appTarget(a.Name),
+		Kind:        permission.PermAppRun,
+		Owner:       s.token,
+		CustomData:  []map[string]interface{}{{"name": "command", "value": "./migrate"}},
+		Allowed:     event.Allowed(permission.PermAppReadEvents),
+		Cancelable:  true,
+		DisableLock: true,
+	})
+	c.Assert(err, check.IsNil)
+	request, err = http.NewRequest("GET", "/apps/secrets/runs", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var runs []appTypes.AppRun
+	err = json.Unmarshal(recorder.Body.Bytes(), &runs)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 1)
+	c.Assert(runs[0].ID, check.Equals, evt.UniqueID.Hex())
+	c.Assert(runs[0].Command, check.Equals, "./migrate")
+	c.Assert(runs[0].Running, check.Equals, true)
+	c.Assert(runs[0].ExitCode, check.IsNil)
+}
+
+func (s *S) TestAppRunInfoNotFound(c *check.C) {
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	for _, id := range []string{"invalid", bson.NewObjectId().Hex()} {
+		request, err := http.NewRequest("GET", "/apps/secrets/runs/"+id, nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		s.testServer.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+		c.Assert(recorder.Body.String(), check.Equals, appTypes.ErrRunNotFound.Error()+"\n")
+	}
+}
+
+func (s *S) TestAppRunCancel(c *check.C) {
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	evt, err := event.New(&event.Opts{
+		Target:        appTarget(a.Name),
+		Kind:          permission.PermAppRun,
+		Owner:         s.token,
+		Allowed:       event.Allowed(permission.PermAppReadEvents),
+		AllowedCancel: event.Allowed(permission.PermAppRun),
+		Cancelable:    true,
+		DisableLock:   true,
+	})
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/secrets/runs/%s/cancel", evt.UniqueID.Hex())
+	request, err := http.NewRequest("POST", url, strings.NewReader("reason=wrong+command"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+	dbEvt, err := event.GetByID(evt.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbEvt.CancelInfo.Asked, check.Equals, true)
+	c.Assert(dbEvt.CancelInfo.Reason, check.Equals, "wrong command")
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	request, err = http.NewRequest("POST", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
 func (s *S) TestRunReturnsTheOutputOfTheCommandEvenIfItFails(c *check.C) {
 	s.provisioner.PrepareFailure("ExecuteCommand", &errors.HTTP{Code: 500, Message: "something went wrong"})
 	s.provisioner.PrepareOutput([]byte("failure output"))
@@ -4431,6 +4820,160 @@ func (s *S) TestAppLogSelectByLinesShouldReturnTheLastestEntries(c *check.C) {
 	c.Assert(logs[2].Message, check.Equals, "14")
 }
 
+func (s *S) TestAppLogSelectByTimeRange(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	base := time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC)
+	coll, err := s.logConn.CreateAppLogCollection(a.Name)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 10; i++ {
+		l := appTypes.Applog{
+			Date:    base.Add(time.Duration(i) * time.Minute),
+			Message: strconv.Itoa(i),
+			Source:  "source",
+			AppName: a.Name,
+		}
+		coll.Insert(l)
+	}
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&lines=10&since=2019-05-10T14:02:00Z&until=2019-05-10T14:05:00Z", a.Name, a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = appLog(recorder, request, s.token)
+	c.Assert(err, check.IsNil)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var logs []appTypes.Applog
+	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 3)
+	c.Assert(logs[0].Message, check.Equals, "2")
+	c.Assert(logs[1].Message, check.Equals, "3")
+	c.Assert(logs[2].Message, check.Equals, "4")
+}
+
+func (s *S) TestAppLogSelectByMessage(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	servicemanager.AppLog.Add(a.Name, "starting server", "web", "u1")
+	servicemanager.AppLog.Add(a.Name, "process killed: OOM", "web", "u1")
+	servicemanager.AppLog.Add(a.Name, "request finished", "web", "u1")
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&lines=10&message=OOM", a.Name, a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = appLog(recorder, request, s.token)
+	c.Assert(err, check.IsNil)
+	var logs []appTypes.Applog
+	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "process killed: OOM")
+}
+
+func (s *S) TestAppLogSelectByLevelAndField(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	coll, err := s.logConn.CreateAppLogCollection(a.Name)
+	c.Assert(err, check.IsNil)
+	entries := []appTypes.Applog{
+		{Message: "1", Fields: map[string]string{"level": "info", "path": "/"}},
+		{Message: "2", Fields: map[string]string{"level": "error", "path": "/"}},
+		{Message: "3", Fields: map[string]string{"level": "error", "path": "/health"}},
+		{Message: "4"},
+	}
+	for _, l := range entries {
+		l.AppName = a.Name
+		l.Date = time.Now()
+		coll.Insert(l)
+	}
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&lines=10&level=ERROR&field=path=/", a.Name, a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = appLog(recorder, request, s.token)
+	c.Assert(err, check.IsNil)
+	var logs []appTypes.Applog
+	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "2")
+	c.Assert(logs[0].Fields, check.DeepEquals, map[string]string{"level": "error", "path": "/"})
+}
+
+func (s *S) TestAppLogPaginationWithCursor(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	date := time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC)
+	coll, err := s.logConn.CreateAppLogCollection(a.Name)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 7; i++ {
+		l := appTypes.Applog{
+			Date:    date.Add(time.Duration(i/3) * time.Second),
+			Message: strconv.Itoa(i),
+			Source:  "source",
+			AppName: a.Name,
+		}
+		coll.Insert(l)
+	}
+	var messages [][]string
+	var cursor string
+	for {
+		url := fmt.Sprintf("/apps/%s/log/?:app=%s&lines=2&cursor=%s", a.Name, a.Name, cursor)
+		request, err := http.NewRequest("GET", url, nil)
+		c.Assert(err, check.IsNil)
+		recorder := httptest.NewRecorder()
+		err = appLog(recorder, request, s.token)
+		c.Assert(err, check.IsNil)
+		var logs []appTypes.Applog
+		err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+		c.Assert(err, check.IsNil)
+		if len(logs) == 0 {
+			c.Assert(recorder.Header().Get("X-Tsuru-Log-Cursor"), check.Equals, "")
+			break
+		}
+		var page []string
+		for _, l := range logs {
+			page = append(page, l.Message)
+		}
+		messages = append(messages, page)
+		cursor = recorder.Header().Get("X-Tsuru-Log-Cursor")
+		c.Assert(cursor, check.Not(check.Equals), "")
+	}
+	c.Assert(messages, check.DeepEquals, [][]string{{"5", "6"}, {"3", "4"}, {"1", "2"}, {"0"}})
+}
+
+func (s *S) TestAppLogInvalidFilters(c *check.C) {
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{"since=yesterday", `Parameter "since" must be a date in RFC3339 format.`},
+		{"until=2019-05-10", `Parameter "until" must be a date in RFC3339 format.`},
+		{"message=(OOM", "Parameter \"message\" must be a valid regular expression: .*"},
+		{"cursor=abc", `invalid log cursor "abc"`},
+		{"follow=1&message=OOM", `Log following with message, until, cursor, level or field filters is not supported.`},
+		{"follow=1&level=error", `Log following with message, until, cursor, level or field filters is not supported.`},
+		{"field=status", `Parameter "field" must be in the format <name>=<value>, got "status".`},
+		{"field=$where=1", `Parameter "field" must be in the format <name>=<value>, got "\$where=1".`},
+	}
+	for _, tt := range tests {
+		url := "/apps/something/log/?:app=doesntmatter&lines=10&" + tt.query
+		request, err := http.NewRequest("GET", url, nil)
+		c.Assert(err, check.IsNil)
+		recorder := httptest.NewRecorder()
+		err = appLog(recorder, request, s.token)
+		c.Assert(err, check.NotNil)
+		e, ok := err.(*errors.HTTP)
+		c.Assert(ok, check.Equals, true)
+		c.Assert(e.Code, check.Equals, http.StatusBadRequest)
+		c.Assert(e.Message, check.Matches, tt.message)
+	}
+}
+
 func (s *S) TestAppLogShouldReturnLogByApp(c *check.C) {
 	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&app1, s.user)
@@ -5776,6 +6319,90 @@ func (s *S) TestSwapApp2Locked(c *check.C) {
 	c.Assert(recorder.Body.String(), check.Matches, "event locked: app\\(app2\\) running \"anything\" start by internal.*\n")
 }
 
+func (s *S) TestSetAppTraffic(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := app.App{Name: "app2", Platform: "zend", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err = app.CreateApp(&app2, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader(`{"weights":[{"app":"app1","weight":80},{"app":"app2","weight":20}]}`)
+	request, err := http.NewRequest("PUT", "/apps/app1/traffic", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	weights, err := routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.DeepEquals, []router.BackendWeight{
+		{Backend: "app1", Weight: 80},
+		{Backend: "app2", Weight: 20},
+	})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(app1.Name),
+		ExtraTargets: []event.ExtraTarget{
+			{Target: event.Target{Type: "app", Value: app2.Name}},
+		},
+		Owner: s.token.GetUserName(),
+		Kind:  "app.update.traffic",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestSetAppTrafficInvalidWeights(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader(`{"weights":[{"app":"app1","weight":80}]}`)
+	request, err := http.NewRequest("PUT", "/apps/app1/traffic", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "weights must add up to 100, got 80\n")
+}
+
+func (s *S) TestSetAppTrafficTargetNotFound(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader(`{"weights":[{"app":"app1","weight":50},{"app":"unknown","weight":50}]}`)
+	request, err := http.NewRequest("PUT", "/apps/app1/traffic", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestSetAppTrafficTargetUnauthorized(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := app.App{Name: "app2", Platform: "zend", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err = app.CreateApp(&app2, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateTraffic,
+		Context: permission.Context(permTypes.CtxApp, app1.Name),
+	})
+	body := strings.NewReader(`{"weights":[{"app":"app1","weight":50},{"app":"app2","weight":50}]}`)
+	request, err := http.NewRequest("PUT", "/apps/app1/traffic", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	weights, err := routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.HasLen, 0)
+}
+
 func (s *S) TestSwapIncompatiblePlatforms(c *check.C) {
 	app1 := app.App{Name: "app1", Teams: []string{s.team.Name}, Platform: "x"}
 	err := s.conn.Apps().Insert(&app1)
@@ -6352,6 +6979,42 @@ func (s *S) TestListCertificates(c *check.C) {
 	})
 }
 
+func (s *S) TestListCertificatesWithStatus(c *check.C) {
+	a := app.App{Name: "myapp", TeamOwner: s.team.Name, CName: []string{"app.io"}, Router: "fake-tls"}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetCertificate("app.io", testCert, testKey)
+	c.Assert(err, check.IsNil)
+	notAfter := time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = s.conn.Collection("acme_certificates").Insert(acme.Certificate{
+		CName:    "app.io",
+		App:      "myapp",
+		Status:   acme.StatusManual,
+		Routers:  []string{"fake-tls"},
+		NotAfter: notAfter,
+	})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/myapp/certificate?status=true", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var result certificatesStatus
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.Routers, check.DeepEquals, map[string]map[string]string{
+		"fake-tls": {
+			"app.io":                  testCert,
+			"myapp.faketlsrouter.com": "",
+		},
+	})
+	c.Assert(result.ACME, check.HasLen, 1)
+	c.Assert(result.ACME[0].CName, check.Equals, "app.io")
+	c.Assert(result.ACME[0].Status, check.Equals, acme.StatusManual)
+	c.Assert(result.ACME[0].NotAfter.Equal(notAfter), check.Equals, true)
+}
+
 type fakeEncoder struct {
 	done chan struct{}
 	msg  interface{}
@@ -51,6 +51,14 @@ func (s *S) TestAutoScaleHistoryHandler(c *check.C) {
 		Result: &autoscale.ScalerResult{ToRebalance: true, Reason: "r2"},
 	})
 	c.Assert(err, check.IsNil)
+	appEvt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		InternalKind: autoscale.EventKind,
+		Allowed:      event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	err = appEvt.Done(nil)
+	c.Assert(err, check.IsNil)
 	recorder := httptest.NewRecorder()
 	request, err := http.NewRequest("GET", "/node/autoscale", nil)
 	c.Assert(err, check.IsNil)
@@ -82,6 +82,10 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if origin == "" && commit != "" {
 		origin = "git"
 	}
+	opts.Strategy, err = deployStrategyFromRequest(r, instance)
+	if err != nil {
+		return err
+	}
 	opts.App = instance
 	opts.Commit = commit
 	opts.User = userName
@@ -121,6 +125,17 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	return err
 }
 
+func deployStrategyFromRequest(r *http.Request, a *app.App) (*app.DeployStrategy, error) {
+	strategy, err := app.ParseDeployStrategy(InputValue(r, "strategy"), InputValue(r, "strategy-steps"), InputValue(r, "strategy-interval"))
+	if err == nil {
+		err = a.ValidateDeployStrategy(strategy)
+	}
+	if err != nil {
+		return nil, &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return strategy, nil
+}
+
 func permSchemeForDeploy(opts app.DeployOptions) *permission.PermissionScheme {
 	switch opts.GetKind() {
 	case app.DeployGit:
@@ -206,6 +221,10 @@ func deployRollback(w http.ResponseWriter, r *http.Request, t auth.Token) error
 			}
 		}
 	}
+	strategy, err := deployStrategyFromRequest(r, instance)
+	if err != nil {
+		return err
+	}
 	w.Header().Set("Content-Type", "application/x-json-stream")
 	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
 	defer keepAliveWriter.Stop()
@@ -217,6 +236,7 @@ func deployRollback(w http.ResponseWriter, r *http.Request, t auth.Token) error
 		User:         t.GetUserName(),
 		Origin:       origin,
 		Rollback:     true,
+		Strategy:     strategy,
 	}
 	opts.GetKind()
 	canRollback := permission.Check(t, permSchemeForDeploy(opts), contextsForApp(instance)...)
@@ -253,6 +253,22 @@ func (s *DeploySuite) TestDeployInvalidOrigin(c *check.C) {
 	c.Assert(recorder.Body.String(), check.Equals, "Invalid deployment origin\n")
 }
 
+func (s *DeploySuite) TestDeployInvalidStrategy(c *check.C) {
+	a := app.App{Name: "otherapp", Platform: "python", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/deploy?:appname=%s", a.Name, a.Name)
+	request, err := http.NewRequest("POST", url, strings.NewReader("archive-url=http://something.tar.gz&strategy=canary&strategy-steps=50,10"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, app.ErrInvalidDeployStrategySteps.Error()+"\n")
+}
+
 func (s *DeploySuite) TestDeployOriginImage(c *check.C) {
 	s.builder.OnBuild = func(p provision.BuilderDeploy, app provision.App, evt *event.Event, opts *builder.BuildOpts) (string, error) {
 		return "tsuruteam/app-otherapp:mytag", nil
@@ -0,0 +1,175 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestAppJobList(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/myapp/jobs", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+	err = a.SetJob(appTypes.Job{Name: "cleanup", Schedule: "@hourly", Command: "./cleanup.sh"})
+	c.Assert(err, check.IsNil)
+	request, err = http.NewRequest("GET", "/apps/myapp/jobs", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var jobs []appTypes.Job
+	err = json.Unmarshal(recorder.Body.Bytes(), &jobs)
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 1)
+	c.Assert(jobs[0].Name, check.Equals, "cleanup")
+	c.Assert(jobs[0].ConcurrencyPolicy, check.Equals, appTypes.JobConcurrencyAllow)
+	c.Assert(jobs[0].HistoryLimit, check.Equals, appTypes.DefaultJobHistoryLimit)
+}
+
+func (s *S) TestAppJobSet(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("name=cleanup&schedule=*/5+*+*+*+*&command=./cleanup.sh&concurrencyPolicy=forbid&historyLimit=5")
+	request, err := http.NewRequest("POST", "/apps/myapp/jobs", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 1)
+	job := dbApp.Jobs[0]
+	c.Assert(job.CreatedAt.IsZero(), check.Equals, false)
+	job.CreatedAt = job.CreatedAt.UTC()
+	c.Assert(job, check.DeepEquals, appTypes.Job{
+		Name:              "cleanup",
+		Schedule:          "*/5 * * * *",
+		Command:           "./cleanup.sh",
+		ConcurrencyPolicy: appTypes.JobConcurrencyForbid,
+		HistoryLimit:      5,
+		CreatedAt:         job.CreatedAt,
+	})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.job.add",
+		StartCustomData: []map[string]interface{}{
+			{"name": "name", "value": "cleanup"},
+			{"name": "schedule", "value": "*/5 * * * *"},
+			{"name": "command", "value": "./cleanup.sh"},
+			{"name": "concurrencyPolicy", "value": "forbid"},
+			{"name": "historyLimit", "value": "5"},
+			{"name": ":app", "value": "myapp"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAppJobSetInvalid(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		body string
+		err  string
+	}{
+		{"name=Cleanup&schedule=@hourly&command=ls", appTypes.ErrJobInvalidName.Error()},
+		{"name=cleanup&command=ls", appTypes.ErrJobNoSchedule.Error()},
+		{"name=cleanup&schedule=@hourly", appTypes.ErrJobNoCommand.Error()},
+		{"name=cleanup&schedule=@hourly&command=ls&concurrencyPolicy=queue", appTypes.ErrJobInvalidConcurrency.Error()},
+		{"name=cleanup&schedule=@hourly&command=ls&historyLimit=500", appTypes.ErrJobInvalidHistory.Error()},
+		{"name=cleanup&schedule=every+hour&command=ls", `.*every hour.*`},
+	}
+	for _, tt := range tests {
+		request, err := http.NewRequest("POST", "/apps/myapp/jobs", strings.NewReader(tt.body))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		s.testServer.ServeHTTP(recorder, request)
+		c.Check(recorder.Code, check.Equals, http.StatusBadRequest, check.Commentf("body: %s", tt.body))
+		c.Check(strings.TrimSpace(recorder.Body.String()), check.Matches, tt.err)
+	}
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 0)
+}
+
+func (s *S) TestAppJobSetNoPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateJobAdd,
+		Context: permission.Context(permTypes.CtxApp, "-invalid-"),
+	})
+	body := strings.NewReader("name=cleanup&schedule=@hourly&command=ls")
+	request, err := http.NewRequest("POST", "/apps/myapp/jobs", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestAppJobRemove(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetJob(appTypes.Job{Name: "cleanup", Schedule: "@hourly", Command: "./cleanup.sh"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/apps/myapp/jobs/cleanup", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 0)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.job.remove",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":app", "value": "myapp"},
+			{"name": ":job", "value": "cleanup"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAppJobRemoveNotFound(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/apps/myapp/jobs/cleanup", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+	c.Assert(recorder.Body.String(), check.Equals, appTypes.ErrJobNotFound.Error()+"\n")
+}
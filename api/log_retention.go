@@ -0,0 +1,246 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/applog"
+	"github.com/tsuru/tsuru/applog/retention"
+	"github.com/tsuru/tsuru/auth"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision/pool"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+)
+
+type logRetentionPermissions struct {
+	read, update, readEvents *permission.PermissionScheme
+	contexts                 []permTypes.PermissionContext
+}
+
+// logRetentionTarget returns the app or pool whose log retention is being
+// managed, based on the path of the request, along with its pool.
+func logRetentionTarget(r *http.Request) (event.Target, string, *logRetentionPermissions, error) {
+	if appName := r.URL.Query().Get(":app"); appName != "" {
+		a, err := getAppFromContext(appName, r)
+		if err != nil {
+			return event.Target{}, "", nil, err
+		}
+		return appTarget(appName), a.Pool, &logRetentionPermissions{
+			read:       permission.PermAppReadLog,
+			update:     permission.PermAppUpdateLogRetention,
+			readEvents: permission.PermAppReadEvents,
+			contexts:   contextsForApp(&a),
+		}, nil
+	}
+	poolName := r.URL.Query().Get(":name")
+	_, err := pool.GetPoolByName(poolName)
+	if err != nil {
+		if err == pool.ErrPoolNotFound {
+			return event.Target{}, "", nil, &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+		}
+		return event.Target{}, "", nil, err
+	}
+	return event.Target{Type: event.TargetTypePool, Value: poolName}, poolName, &logRetentionPermissions{
+		read:       permission.PermPoolReadLogRetention,
+		update:     permission.PermPoolUpdateLogRetention,
+		readEvents: permission.PermPoolReadEvents,
+		contexts:   []permTypes.PermissionContext{permission.Context(permTypes.CtxPool, poolName)},
+	}, nil
+}
+
+// parseLogMaxAge parses a duration in the format accepted by
+// time.ParseDuration, also accepting a number of days, like 90d.
+func parseLogMaxAge(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+// title: log retention info
+// path: /apps/{app}/log/retention
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: App not found
+func logRetentionInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	target, poolName, perms, err := logRetentionTarget(r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, perms.read, perms.contexts...) {
+		return permission.ErrUnauthorized
+	}
+	var policy *retention.EffectivePolicy
+	if target.Type == event.TargetTypeApp {
+		policy, err = retention.ForApp(target.Value, poolName)
+	} else {
+		policy, err = retention.ForPool(poolName)
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(policy)
+}
+
+// title: log retention set
+// path: /apps/{app}/log/retention
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: OK
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func logRetentionSet(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	target, _, perms, err := logRetentionTarget(r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, perms.update, perms.contexts...) {
+		return permission.ErrUnauthorized
+	}
+	policy := retention.Policy{Target: target}
+	if maxAge := InputValue(r, "max-age"); maxAge != "" {
+		policy.MaxAge, err = parseLogMaxAge(maxAge)
+		if err != nil {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf(`Parameter "max-age" must be a duration, like 24h or 90d, got %q.`, maxAge)}
+		}
+	}
+	if maxSize := InputValue(r, "max-size"); maxSize != "" {
+		policy.MaxSize, err = strconv.ParseInt(maxSize, 10, 64)
+		if err != nil {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: `Parameter "max-size" must be an integer.`}
+		}
+	}
+	err = policy.Validate()
+	if err != nil {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     target,
+		Kind:       perms.update,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(perms.readEvents, perms.contexts...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return retention.Set(&policy)
+}
+
+// title: log retention remove
+// path: /apps/{app}/log/retention
+// method: DELETE
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: App or retention policy not found
+func logRetentionRemove(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	target, _, perms, err := logRetentionTarget(r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, perms.update, perms.contexts...) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     target,
+		Kind:       perms.update,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(perms.readEvents, perms.contexts...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = retention.Remove(target)
+	if err == retention.ErrPolicyNotFound {
+		return &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+// title: app log export
+// path: /apps/{app}/log/export
+// method: GET
+// produce: application/gzip
+// responses:
+//   200: OK
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func appLogExport(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	since, err := logTimeParam(r, "since")
+	if err != nil {
+		return err
+	}
+	if since.IsZero() {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: `Parameter "since" is mandatory.`}
+	}
+	until, err := logTimeParam(r, "until")
+	if err != nil {
+		return err
+	}
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	if !since.Before(until) {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: `Parameter "since" must be before "until".`}
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppReadLog, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	args := appTypes.ListLogArgs{
+		AppName: a.Name,
+		Source:  r.URL.Query().Get("source"),
+		Unit:    r.URL.Query().Get("unit"),
+		Since:   since,
+		Until:   until,
+		Token:   t,
+	}
+	fileName := fmt.Sprintf("%s-%s-%s.ndjson.gz", a.Name, since.UTC().Format("20060102T150405Z"), until.UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	gzipWriter := gzip.NewWriter(w)
+	count, err := applog.Export(servicemanager.AppLog, args, gzipWriter)
+	if err != nil {
+		if count == 0 {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Disposition")
+			return err
+		}
+		// The response was already partially sent, the error is only
+		// logged and the archive is left truncated.
+		log.Errorf("[log export] unable to export logs for app %q: %s", a.Name, err)
+	}
+	return gzipWriter.Close()
+}
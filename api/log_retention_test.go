@@ -0,0 +1,184 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/applog/retention"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestLogRetentionSetApp(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("max-age=90d&max-size=1048576")
+	request, err := http.NewRequest("PUT", "/1.9/apps/myapp/log/retention", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	policy, err := retention.Get(appTarget("myapp"))
+	c.Assert(err, check.IsNil)
+	c.Assert(policy.LogRetention, check.DeepEquals, appTypes.LogRetention{MaxAge: 90 * 24 * time.Hour, MaxSize: 1048576})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.log-retention",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestLogRetentionSetInvalid(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		body    string
+		message string
+	}{
+		{"max-age=forever", `Parameter "max-age" must be a duration, like 24h or 90d, got "forever".` + "\n"},
+		{"max-size=1GB", `Parameter "max-size" must be an integer.` + "\n"},
+		{"", retention.ErrInvalidPolicy.Error() + "\n"},
+	}
+	for _, tt := range tests {
+		request, err := http.NewRequest("PUT", "/1.9/apps/myapp/log/retention", strings.NewReader(tt.body))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		s.testServer.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+		c.Assert(recorder.Body.String(), check.Equals, tt.message)
+	}
+}
+
+func (s *S) TestLogRetentionSetPoolNoPermission(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermPoolUpdateLogRetention,
+		Context: permission.Context(permTypes.CtxPool, "other-pool"),
+	})
+	request, err := http.NewRequest("PUT", "/1.9/pools/"+s.Pool+"/log/retention", strings.NewReader("max-age=24h"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestLogRetentionInfo(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = retention.Set(&retention.Policy{
+		Target:       event.Target{Type: event.TargetTypePool, Value: a.Pool},
+		LogRetention: appTypes.LogRetention{MaxAge: 24 * time.Hour},
+	})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/1.9/apps/myapp/log/retention", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var policy retention.EffectivePolicy
+	err = json.NewDecoder(recorder.Body).Decode(&policy)
+	c.Assert(err, check.IsNil)
+	c.Assert(policy, check.DeepEquals, retention.EffectivePolicy{
+		LogRetention: appTypes.LogRetention{MaxAge: 24 * time.Hour},
+		Source:       retention.SourcePool,
+	})
+}
+
+func (s *S) TestLogRetentionRemovePool(c *check.C) {
+	target := event.Target{Type: event.TargetTypePool, Value: s.Pool}
+	err := retention.Set(&retention.Policy{Target: target, LogRetention: appTypes.LogRetention{MaxSize: 1024}})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/1.9/pools/"+s.Pool+"/log/retention", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	_, err = retention.Get(target)
+	c.Assert(err, check.Equals, retention.ErrPolicyNotFound)
+	c.Assert(eventtest.EventDesc{
+		Target: target,
+		Owner:  s.token.GetUserName(),
+		Kind:   "pool.update.log-retention",
+	}, eventtest.HasEvent)
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestAppLogExport(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = servicemanager.AppLog.Add("myapp", "first\nsecond", "web", "u1")
+	c.Assert(err, check.IsNil)
+	err = servicemanager.AppLog.Add("myapp", "third", "worker", "u1")
+	c.Assert(err, check.IsNil)
+	since := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	request, err := http.NewRequest("GET", "/1.9/apps/myapp/log/export?source=web&since="+since, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/gzip")
+	c.Assert(recorder.Header().Get("Content-Disposition"), check.Matches, `attachment; filename="myapp-.*\.ndjson\.gz"`)
+	reader, err := gzip.NewReader(recorder.Body)
+	c.Assert(err, check.IsNil)
+	decoder := json.NewDecoder(reader)
+	var messages []string
+	for decoder.More() {
+		var entry appTypes.Applog
+		err = decoder.Decode(&entry)
+		c.Assert(err, check.IsNil)
+		messages = append(messages, entry.Message)
+	}
+	c.Assert(messages, check.DeepEquals, []string{"first", "second"})
+}
+
+func (s *S) TestAppLogExportInvalidTimeRange(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{"", `Parameter "since" is mandatory.` + "\n"},
+		{"since=yesterday", `Parameter "since" must be a date in RFC3339 format.` + "\n"},
+		{"since=2019-05-10T14:00:00Z&until=2019-05-10T13:00:00Z", `Parameter "since" must be before "until".` + "\n"},
+	}
+	for _, tt := range tests {
+		request, err := http.NewRequest("GET", "/1.9/apps/myapp/log/export?"+tt.query, nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		s.testServer.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+		c.Assert(recorder.Body.String(), check.Equals, tt.message)
+	}
+}
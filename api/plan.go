@@ -6,8 +6,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/errors"
@@ -31,12 +34,22 @@ func addPlan(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	isDefault, _ := strconv.ParseBool(InputValue(r, "default"))
 	memory := getSize(InputValue(r, "memory"))
 	swap := getSize(InputValue(r, "swap"))
+	cpuRequest, err := getMillicores(InputValue(r, "cpurequest"))
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	cpuLimit, err := getMillicores(InputValue(r, "cpulimit"))
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
 	plan := appTypes.Plan{
-		Name:     InputValue(r, "name"),
-		Memory:   memory,
-		Swap:     swap,
-		CpuShare: cpuShare,
-		Default:  isDefault,
+		Name:       InputValue(r, "name"),
+		Memory:     memory,
+		Swap:       swap,
+		CpuShare:   cpuShare,
+		CPURequest: cpuRequest,
+		CPULimit:   cpuLimit,
+		Default:    isDefault,
 	}
 	allowed := permission.Check(t, permission.PermPlanCreate)
 	if !allowed {
@@ -60,7 +73,14 @@ func addPlan(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 			Message: err.Error(),
 		}
 	}
-	if err == appTypes.ErrLimitOfMemory || err == appTypes.ErrLimitOfCpuShare {
+	if _, ok := err.(appTypes.PlanValidationError); ok {
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}
+	}
+	if err == appTypes.ErrLimitOfMemory || err == appTypes.ErrLimitOfCpuShare ||
+		err == appTypes.ErrLimitOfCPU || err == appTypes.ErrPlanCPURequest {
 		return &errors.HTTP{
 			Code:    http.StatusBadRequest,
 			Message: err.Error(),
@@ -145,3 +165,23 @@ func getSize(formValue string) int64 {
 	}
 	return value
 }
+
+// getMillicores parses a cpu amount in millicores, like 500m, or in cores,
+// like 0.5 or 2.
+func getMillicores(formValue string) (int, error) {
+	if formValue == "" {
+		return 0, nil
+	}
+	if millicores := strings.TrimSuffix(formValue, "m"); millicores != formValue {
+		value, err := strconv.Atoi(millicores)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cpu value: %q", formValue)
+		}
+		return value, nil
+	}
+	cores, err := strconv.ParseFloat(formValue, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu value: %q", formValue)
+	}
+	return int(math.Round(cores * 1000)), nil
+}
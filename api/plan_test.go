@@ -108,6 +108,59 @@ func (s *S) TestPlanAddWithGigabyteAsMemoryUnit(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
 }
 
+func (s *S) TestPlanAddWithCPU(c *check.C) {
+	s.mockService.Plan.OnCreate = func(plan appTypes.Plan) error {
+		c.Assert(plan, check.DeepEquals, appTypes.Plan{
+			Name:       "xyz",
+			Memory:     1024,
+			Swap:       1024,
+			CPURequest: 250,
+			CPULimit:   1500,
+		})
+		return nil
+	}
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("name=xyz&memory=1024&swap=1024&cpurequest=250m&cpulimit=1.5")
+	request, err := http.NewRequest("POST", "/plans", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+}
+
+func (s *S) TestPlanAddInvalidCPU(c *check.C) {
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("name=xyz&memory=1024&cpushare=100&cpulimit=abc")
+	request, err := http.NewRequest("POST", "/plans", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "invalid cpu value: \"abc\"\n")
+}
+
+func (s *S) TestGetMillicores(c *check.C) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", 0},
+		{"500m", 500},
+		{"0.5", 500},
+		{"2", 2000},
+		{"0.001", 1},
+	}
+	for _, tt := range tests {
+		value, err := getMillicores(tt.value)
+		c.Assert(err, check.IsNil)
+		c.Assert(value, check.Equals, tt.expected, check.Commentf("value %q", tt.value))
+	}
+	_, err := getMillicores("1.5m")
+	c.Assert(err, check.ErrorMatches, `invalid cpu value: "1.5m"`)
+}
+
 func (s *S) TestPlanAddWithNoPermission(c *check.C) {
 	token := userWithPermission(c)
 	recorder := httptest.NewRecorder()
@@ -6,16 +6,22 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/provision/pool"
 	"github.com/tsuru/tsuru/router"
+	"github.com/tsuru/tsuru/router/rebuild"
+	"github.com/tsuru/tsuru/servicemanager"
 	appTypes "github.com/tsuru/tsuru/types/app"
 	permTypes "github.com/tsuru/tsuru/types/permission"
+	routerTypes "github.com/tsuru/tsuru/types/router"
 )
 
 // title: router list
@@ -74,6 +80,165 @@ contexts:
 	return json.NewEncoder(w).Encode(filteredRouters)
 }
 
+// title: router create
+// path: /routers
+// method: POST
+// consume: application/json
+// responses:
+//   201: Router created
+//   400: Invalid router
+//   401: Unauthorized
+//   409: Router already exists
+func addRouter(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	if !permission.Check(t, permission.PermRouterCreate) {
+		return permission.ErrUnauthorized
+	}
+	var dynamicRouter routerTypes.DynamicRouter
+	err = ParseInput(r, &dynamicRouter)
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeRouter, Value: dynamicRouter.Name},
+		Kind:       permission.PermRouterCreate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermRouterReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = servicemanager.DynamicRouter.Create(dynamicRouter)
+	if err == routerTypes.ErrDynamicRouterAlreadyExists {
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// title: router update
+// path: /routers/{name}
+// method: PUT
+// consume: application/json
+// responses:
+//   200: Router updated
+//   400: Invalid router
+//   401: Unauthorized
+//   404: Router not found
+func updateRouter(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	if !permission.Check(t, permission.PermRouterUpdate) {
+		return permission.ErrUnauthorized
+	}
+	var dynamicRouter routerTypes.DynamicRouter
+	err = ParseInput(r, &dynamicRouter)
+	if err != nil {
+		return err
+	}
+	dynamicRouter.Name = r.URL.Query().Get(":name")
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeRouter, Value: dynamicRouter.Name},
+		Kind:       permission.PermRouterUpdate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermRouterReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = servicemanager.DynamicRouter.Update(dynamicRouter)
+	if err == routerTypes.ErrDynamicRouterNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+// title: router delete
+// path: /routers/{name}
+// method: DELETE
+// responses:
+//   200: Router removed
+//   400: Router in use
+//   401: Unauthorized
+//   404: Router not found
+func deleteRouter(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	if !permission.Check(t, permission.PermRouterDelete) {
+		return permission.ErrUnauthorized
+	}
+	routerName := r.URL.Query().Get(":name")
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeRouter, Value: routerName},
+		Kind:       permission.PermRouterDelete,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermRouterReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	filter := &app.Filter{}
+	filter.ExtraIn("routers.name", routerName)
+	apps, err := app.List(filter)
+	if err != nil {
+		return err
+	}
+	if len(apps) > 0 {
+		appNames := make([]string, len(apps))
+		for i, a := range apps {
+			appNames[i] = a.Name
+		}
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("router is in use by apps: %s", strings.Join(appNames, ", ")),
+		}
+	}
+	err = servicemanager.DynamicRouter.Remove(routerName)
+	if err == routerTypes.ErrDynamicRouterNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+// title: router drift report
+// path: /routers/drift
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func routerDriftReport(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	if !permission.Check(t, permission.PermRouterReadDrift) {
+		return permission.ErrUnauthorized
+	}
+	report, err := rebuild.GetDriftReport()
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	routerName := r.URL.Query().Get("router")
+	appName := r.URL.Query().Get("app")
+	if routerName != "" || appName != "" {
+		drifts := []rebuild.Drift{}
+		for _, d := range report.Drifts {
+			if (routerName == "" || d.Router == routerName) && (appName == "" || d.App == appName) {
+				drifts = append(drifts, d)
+			}
+		}
+		report.Drifts = drifts
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(report)
+}
+
 // title: add app router
 // path: /app/{app}/routers
 // method: POST
@@ -12,12 +12,16 @@ import (
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/provision/pool"
 	"github.com/tsuru/tsuru/router"
+	"github.com/tsuru/tsuru/router/rebuild"
 	"github.com/tsuru/tsuru/router/routertest"
 	appTypes "github.com/tsuru/tsuru/types/app"
 	permTypes "github.com/tsuru/tsuru/types/permission"
+	routerTypes "github.com/tsuru/tsuru/types/router"
 	check "gopkg.in/check.v1"
 )
 
@@ -38,8 +42,8 @@ func (s *S) TestRoutersList(c *check.C) {
 	config.Set("routers:router2:type", "bar")
 	defer config.Unset("routers:router1:type")
 	defer config.Unset("routers:router2:type")
-	router.Register("foo", func(_, _ string) (router.Router, error) { return nil, nil })
-	router.Register("bar", func(_, _ string) (router.Router, error) { return nil, nil })
+	router.Register("foo", func(_ string, _ router.ConfigGetter) (router.Router, error) { return nil, nil })
+	router.Register("bar", func(_ string, _ router.ConfigGetter) (router.Router, error) { return nil, nil })
 	defer router.Unregister("foo")
 	defer router.Unregister("bar")
 	recorder := httptest.NewRecorder()
@@ -66,8 +70,8 @@ func (s *S) TestRoutersListAppCreatePermissionTeam(c *check.C) {
 	config.Set("routers:router2:type", "bar")
 	defer config.Unset("routers:router1:type")
 	defer config.Unset("routers:router2:type")
-	router.Register("foo", func(_, _ string) (router.Router, error) { return nil, nil })
-	router.Register("bar", func(_, _ string) (router.Router, error) { return nil, nil })
+	router.Register("foo", func(_ string, _ router.ConfigGetter) (router.Router, error) { return nil, nil })
+	router.Register("bar", func(_ string, _ router.ConfigGetter) (router.Router, error) { return nil, nil })
 	defer router.Unregister("foo")
 	defer router.Unregister("bar")
 	token := userWithPermission(c, permission.Permission{
@@ -361,3 +365,204 @@ func (s *S) TestRemoveAppRouterNotFound(c *check.C) {
 	s.testServer.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
 }
+
+func (s *S) TestAddRouter(c *check.C) {
+	var created routerTypes.DynamicRouter
+	s.mockService.DynamicRouter.OnCreate = func(r routerTypes.DynamicRouter) error {
+		created = r
+		return nil
+	}
+	body := strings.NewReader(`{"name": "dyn", "type": "api", "config": {"api-url": "http://router.io", "headers": {"X-Key": "v"}}, "default": true}`)
+	request, err := http.NewRequest("POST", "/1.9/routers", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated, check.Commentf("body: %q", recorder.Body.String()))
+	c.Assert(created, check.DeepEquals, routerTypes.DynamicRouter{
+		Name: "dyn",
+		Type: "api",
+		Config: map[string]interface{}{
+			"api-url": "http://router.io",
+			"headers": map[string]interface{}{"X-Key": "v"},
+		},
+		Default: true,
+	})
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeRouter, Value: "dyn"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "router.create",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddRouterAlreadyExists(c *check.C) {
+	s.mockService.DynamicRouter.OnCreate = func(r routerTypes.DynamicRouter) error {
+		return routerTypes.ErrDynamicRouterAlreadyExists
+	}
+	body := strings.NewReader(`{"name": "dyn", "type": "api"}`)
+	request, err := http.NewRequest("POST", "/1.9/routers", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestAddRouterUnauthorized(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermClusterCreate,
+		Context: permission.Context(permTypes.CtxGlobal, ""),
+	})
+	s.mockService.DynamicRouter.OnCreate = func(r routerTypes.DynamicRouter) error {
+		c.Fatal("unexpected router creation")
+		return nil
+	}
+	body := strings.NewReader(`{"name": "dyn", "type": "api"}`)
+	request, err := http.NewRequest("POST", "/1.9/routers", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestUpdateRouter(c *check.C) {
+	var updated routerTypes.DynamicRouter
+	s.mockService.DynamicRouter.OnUpdate = func(r routerTypes.DynamicRouter) error {
+		updated = r
+		return nil
+	}
+	body := strings.NewReader(`{"type": "galeb", "config": {"domain": "galeb.io"}}`)
+	request, err := http.NewRequest("PUT", "/1.9/routers/dyn", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %q", recorder.Body.String()))
+	c.Assert(updated, check.DeepEquals, routerTypes.DynamicRouter{
+		Name:   "dyn",
+		Type:   "galeb",
+		Config: map[string]interface{}{"domain": "galeb.io"},
+	})
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeRouter, Value: "dyn"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "router.update",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestUpdateRouterNotFound(c *check.C) {
+	s.mockService.DynamicRouter.OnUpdate = func(r routerTypes.DynamicRouter) error {
+		return routerTypes.ErrDynamicRouterNotFound
+	}
+	body := strings.NewReader(`{"type": "galeb"}`)
+	request, err := http.NewRequest("PUT", "/1.9/routers/dyn", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestDeleteRouter(c *check.C) {
+	var removed []string
+	s.mockService.DynamicRouter.OnRemove = func(name string) error {
+		removed = append(removed, name)
+		return nil
+	}
+	request, err := http.NewRequest("DELETE", "/1.9/routers/dyn", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %q", recorder.Body.String()))
+	c.Assert(removed, check.DeepEquals, []string{"dyn"})
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeRouter, Value: "dyn"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "router.delete",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestDeleteRouterInUse(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "go", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "dyn"}}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	s.mockService.DynamicRouter.OnRemove = func(name string) error {
+		c.Fatal("unexpected router removal")
+		return nil
+	}
+	request, err := http.NewRequest("DELETE", "/1.9/routers/dyn", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "router is in use by apps: myapp\n")
+}
+
+func (s *S) TestDeleteRouterNotFound(c *check.C) {
+	s.mockService.DynamicRouter.OnRemove = func(name string) error {
+		return routerTypes.ErrDynamicRouterNotFound
+	}
+	request, err := http.NewRequest("DELETE", "/1.9/routers/dyn", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestRouterDriftReport(c *check.C) {
+	report := rebuild.DriftReport{
+		ID:          "latest",
+		CheckedApps: 2,
+		Drifts: []rebuild.Drift{
+			{App: "app1", Router: "fake", StaleRoutes: []string{"http://10.0.0.1:1234"}},
+			{App: "app2", Router: "fake-tls", MissingBackend: true},
+		},
+	}
+	err := s.conn.Collection("router_drift").Insert(report)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/1.9/routers/drift?router=fake", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %q", recorder.Body.String()))
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result rebuild.DriftReport
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.CheckedApps, check.Equals, 2)
+	c.Assert(result.Drifts, check.DeepEquals, []rebuild.Drift{
+		{App: "app1", Router: "fake", StaleRoutes: []string{"http://10.0.0.1:1234"}},
+	})
+}
+
+func (s *S) TestRouterDriftReportNoReport(c *check.C) {
+	request, err := http.NewRequest("GET", "/1.9/routers/drift", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestRouterDriftReportUnauthorized(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermRouterRead,
+		Context: permission.Context(permTypes.CtxGlobal, ""),
+	})
+	request, err := http.NewRequest("GET", "/1.9/routers/drift", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
@@ -0,0 +1,241 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/auth"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision/pool"
+	"github.com/tsuru/tsuru/schedule"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+)
+
+const defaultDryRunCount = 5
+
+type schedulePermissions struct {
+	read, add, remove, readEvents *permission.PermissionScheme
+	contexts                      []permTypes.PermissionContext
+}
+
+func permissionsForSchedule(target event.Target) (*schedulePermissions, error) {
+	switch target.Type {
+	case event.TargetTypeApp:
+		a, err := app.GetByName(target.Value)
+		if err != nil {
+			if err == appTypes.ErrAppNotFound {
+				return nil, &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+			}
+			return nil, err
+		}
+		return &schedulePermissions{
+			read:       permission.PermAppReadSchedule,
+			add:        permission.PermAppUpdateScheduleAdd,
+			remove:     permission.PermAppUpdateScheduleRemove,
+			readEvents: permission.PermAppReadEvents,
+			contexts:   contextsForApp(a),
+		}, nil
+	case event.TargetTypePool:
+		_, err := pool.GetPoolByName(target.Value)
+		if err != nil {
+			if err == pool.ErrPoolNotFound {
+				return nil, &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+			}
+			return nil, err
+		}
+		return &schedulePermissions{
+			read:       permission.PermPoolReadSchedule,
+			add:        permission.PermPoolUpdateScheduleAdd,
+			remove:     permission.PermPoolUpdateScheduleRemove,
+			readEvents: permission.PermPoolReadEvents,
+			contexts:   []permTypes.PermissionContext{permission.Context(permTypes.CtxPool, target.Value)},
+		}, nil
+	}
+	return nil, &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: schedule.ErrInvalidTarget.Error()}
+}
+
+func scheduleTargetFromRequest(r *http.Request) *event.Target {
+	if appName := InputValue(r, "app"); appName != "" {
+		return &event.Target{Type: event.TargetTypeApp, Value: appName}
+	}
+	if poolName := InputValue(r, "pool"); poolName != "" {
+		return &event.Target{Type: event.TargetTypePool, Value: poolName}
+	}
+	return nil
+}
+
+func getSchedule(r *http.Request) (*schedule.Schedule, *schedulePermissions, error) {
+	sched, err := schedule.Get(r.URL.Query().Get(":name"))
+	if err != nil {
+		if err == schedule.ErrScheduleNotFound {
+			return nil, nil, &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+		}
+		return nil, nil, err
+	}
+	perms, err := permissionsForSchedule(sched.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sched, perms, nil
+}
+
+// title: schedule list
+// path: /schedules
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func scheduleList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	var targets []event.Target
+	if target := scheduleTargetFromRequest(r); target != nil {
+		targets = []event.Target{*target}
+	}
+	schedules, err := schedule.List(targets)
+	if err != nil {
+		return err
+	}
+	var allowed []schedule.Schedule
+	for _, sched := range schedules {
+		perms, err := permissionsForSchedule(sched.Target)
+		if err != nil {
+			continue
+		}
+		if permission.Check(t, perms.read, perms.contexts...) {
+			allowed = append(allowed, sched)
+		}
+	}
+	if len(allowed) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(allowed)
+}
+
+// title: schedule create
+// path: /schedules
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   201: Schedule created
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App or pool not found
+//   409: Schedule already exists
+func scheduleCreate(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	var sched schedule.Schedule
+	err = ParseInput(r, &sched)
+	if err != nil {
+		return err
+	}
+	if target := scheduleTargetFromRequest(r); target != nil {
+		sched.Target = *target
+	}
+	perms, err := permissionsForSchedule(sched.Target)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, perms.add, perms.contexts...) {
+		return permission.ErrUnauthorized
+	}
+	err = sched.Validate()
+	if err != nil {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     sched.Target,
+		Kind:       perms.add,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(perms.readEvents, perms.contexts...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = schedule.Create(&sched)
+	if err == schedule.ErrScheduleAlreadyExists {
+		return &tsuruErrors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// title: schedule delete
+// path: /schedules/{name}
+// method: DELETE
+// responses:
+//   200: Schedule removed
+//   401: Unauthorized
+//   404: Schedule not found
+func scheduleDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	sched, perms, err := getSchedule(r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, perms.remove, perms.contexts...) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     sched.Target,
+		Kind:       perms.remove,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(perms.readEvents, perms.contexts...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = schedule.Remove(sched.Name)
+	if err == schedule.ErrScheduleNotFound {
+		return &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+// title: schedule dry run
+// path: /schedules/{name}/dry-run
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Schedule not found
+func scheduleDryRun(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	sched, perms, err := getSchedule(r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, perms.read, perms.contexts...) {
+		return permission.ErrUnauthorized
+	}
+	count := defaultDryRunCount
+	if rawCount := r.URL.Query().Get("count"); rawCount != "" {
+		count, err = strconv.Atoi(rawCount)
+		if err != nil || count <= 0 {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "count must be a positive integer"}
+		}
+	}
+	result, err := schedule.DryRun(sched, count)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(result)
+}
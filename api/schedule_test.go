@@ -0,0 +1,190 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/schedule"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestScheduleCreate(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("name=scale-down&app=myapp&cron=0+20+*+*+*&action=scale&process=web&units=1")
+	request, err := http.NewRequest("POST", "/1.9/schedules", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated, check.Commentf("body: %s", recorder.Body.String()))
+	sched, err := schedule.Get("scale-down")
+	c.Assert(err, check.IsNil)
+	c.Assert(sched.Target, check.DeepEquals, event.Target{Type: event.TargetTypeApp, Value: "myapp"})
+	c.Assert(sched.Cron, check.Equals, "0 20 * * *")
+	c.Assert(sched.Action, check.Equals, schedule.ActionScale)
+	c.Assert(sched.Process, check.Equals, "web")
+	c.Assert(sched.Units, check.Equals, uint(1))
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.schedule.add",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestScheduleCreatePool(c *check.C) {
+	body := strings.NewReader("name=stop-weekend&pool=" + s.Pool + "&cron=0+0+*+*+sat&action=stop")
+	request, err := http.NewRequest("POST", "/1.9/schedules", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated, check.Commentf("body: %s", recorder.Body.String()))
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypePool, Value: s.Pool},
+		Owner:  s.token.GetUserName(),
+		Kind:   "pool.update.schedule.add",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestScheduleCreateInvalid(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("name=scale-down&app=myapp&cron=0+20+*+*&action=scale&process=web&units=1")
+	request, err := http.NewRequest("POST", "/1.9/schedules", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Matches, "invalid cron expression.*\n")
+}
+
+func (s *S) TestScheduleCreateNoPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateScheduleAdd,
+		Context: permission.Context(permTypes.CtxApp, "other-app"),
+	})
+	body := strings.NewReader("name=scale-down&app=myapp&cron=0+20+*+*+*&action=stop")
+	request, err := http.NewRequest("POST", "/1.9/schedules", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestScheduleList(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = schedule.Create(&schedule.Schedule{Name: "s1", Target: appTarget("myapp"), Cron: "0 20 * * *", Action: schedule.ActionStop})
+	c.Assert(err, check.IsNil)
+	err = schedule.Create(&schedule.Schedule{Name: "s2", Target: event.Target{Type: event.TargetTypePool, Value: s.Pool}, Cron: "0 7 * * *", Action: schedule.ActionStart})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/1.9/schedules?app=myapp", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var result []schedule.Schedule
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "s1")
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermPoolReadSchedule,
+		Context: permission.Context(permTypes.CtxPool, s.Pool),
+	})
+	request, err = http.NewRequest("GET", "/1.9/schedules", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	result = nil
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "s2")
+}
+
+func (s *S) TestScheduleListEmpty(c *check.C) {
+	request, err := http.NewRequest("GET", "/1.9/schedules", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestScheduleDelete(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = schedule.Create(&schedule.Schedule{Name: "s1", Target: appTarget("myapp"), Cron: "0 20 * * *", Action: schedule.ActionStop})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/1.9/schedules/s1", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	_, err = schedule.Get("s1")
+	c.Assert(err, check.Equals, schedule.ErrScheduleNotFound)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.schedule.remove",
+	}, eventtest.HasEvent)
+	recorder = httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestScheduleDryRun(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	err = schedule.Create(&schedule.Schedule{Name: "s1", Target: appTarget("myapp"), Cron: "0 20 * * *", Action: schedule.ActionScale, Process: "web", Units: 4})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/1.9/schedules/s1/dry-run?count=2", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var result schedule.DryRunResult
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.NextRuns, check.HasLen, 2)
+	c.Assert(result.Actions, check.DeepEquals, []schedule.ScheduledAction{
+		{App: "myapp", Action: schedule.ActionScale, Process: "web", CurrentUnits: 2, Units: 4, Description: `add 2 units to process "web"`},
+	})
+	units, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 2)
+}
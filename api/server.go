@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/acme"
 	apiRouter "github.com/tsuru/tsuru/api/router"
 	"github.com/tsuru/tsuru/api/shutdown"
 	"github.com/tsuru/tsuru/api/tracker"
@@ -30,6 +31,7 @@ import (
 	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/app/image/gc"
 	"github.com/tsuru/tsuru/applog"
+	"github.com/tsuru/tsuru/applog/retention"
 	"github.com/tsuru/tsuru/auth"
 	_ "github.com/tsuru/tsuru/auth/native"
 	_ "github.com/tsuru/tsuru/auth/oauth"
@@ -37,6 +39,7 @@ import (
 	"github.com/tsuru/tsuru/autoscale"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/archive"
 	"github.com/tsuru/tsuru/event/webhook"
 	"github.com/tsuru/tsuru/hc"
 	"github.com/tsuru/tsuru/healer"
@@ -46,6 +49,7 @@ import (
 	"github.com/tsuru/tsuru/provision/nodecontainer"
 	"github.com/tsuru/tsuru/router"
 	"github.com/tsuru/tsuru/router/rebuild"
+	"github.com/tsuru/tsuru/schedule"
 	"github.com/tsuru/tsuru/service"
 	"github.com/tsuru/tsuru/servicemanager"
 	"github.com/tsuru/tsuru/storage"
@@ -152,6 +156,14 @@ func setupServices() error {
 	if err != nil {
 		return err
 	}
+	err = applog.ValidateInstances()
+	if err != nil {
+		return err
+	}
+	servicemanager.DynamicRouter, err = router.DynamicRouterService()
+	if err != nil {
+		return err
+	}
 	return err
 }
 
@@ -222,6 +234,9 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Delete", "/apps/{app}/cname", AuthorizationRequiredHandler(unsetCName))
 	runHandler := AuthorizationRequiredHandler(runCommand)
 	m.Add("1.0", "Post", "/apps/{app}/run", runHandler)
+	m.Add("1.9", "Get", "/apps/{app}/runs", AuthorizationRequiredHandler(appRunList))
+	m.Add("1.9", "Get", "/apps/{app}/runs/{id}", AuthorizationRequiredHandler(appRunInfo))
+	m.Add("1.9", "Post", "/apps/{app}/runs/{id}/cancel", AuthorizationRequiredHandler(appRunCancel))
 	m.Add("1.0", "Post", "/apps/{app}/restart", AuthorizationRequiredHandler(restart))
 	m.Add("1.0", "Post", "/apps/{app}/start", AuthorizationRequiredHandler(start))
 	m.Add("1.0", "Post", "/apps/{app}/stop", AuthorizationRequiredHandler(stop))
@@ -239,6 +254,14 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Delete", "/apps/{app}/units", AuthorizationRequiredHandler(removeUnits))
 	registerUnitHandler := AuthorizationRequiredHandler(registerUnit)
 	m.Add("1.0", "Post", "/apps/{app}/units/register", registerUnitHandler)
+	m.Add("1.9", "Get", "/apps/{app}/units/autoscale", AuthorizationRequiredHandler(autoScaleUnitsInfo))
+	m.Add("1.9", "Post", "/apps/{app}/units/autoscale", AuthorizationRequiredHandler(addAutoScaleUnits))
+	m.Add("1.9", "Delete", "/apps/{app}/units/autoscale", AuthorizationRequiredHandler(removeAutoScaleUnits))
+	m.Add("1.9", "Get", "/apps/{app}/jobs", AuthorizationRequiredHandler(appJobList))
+	m.Add("1.9", "Post", "/apps/{app}/jobs", AuthorizationRequiredHandler(appJobSet))
+	m.Add("1.9", "Delete", "/apps/{app}/jobs/{job}", AuthorizationRequiredHandler(appJobRemove))
+	m.Add("1.9", "Put", "/apps/{app}/processes/{process}", AuthorizationRequiredHandler(updateAppProcess))
+	m.Add("1.9", "Put", "/apps/{app}/traffic", AuthorizationRequiredHandler(setAppTraffic))
 	setUnitStatusHandler := AuthorizationRequiredHandler(setUnitStatus)
 	m.Add("1.0", "Post", "/apps/{app}/units/{unit}", setUnitStatusHandler)
 	m.Add("1.0", "Put", "/apps/{app}/teams/{team}", AuthorizationRequiredHandler(grantAppAccess))
@@ -247,6 +270,10 @@ func RunServer(dry bool) http.Handler {
 	m.AddNamed("log-get-instance", "1.8", "Get", "/apps/{app}/log-instance", AuthorizationRequiredHandler(appLog))
 	logPostHandler := AuthorizationRequiredHandler(addLog)
 	m.Add("1.0", "Post", "/apps/{app}/log", logPostHandler)
+	m.Add("1.9", "Get", "/apps/{app}/log/export", AuthorizationRequiredHandler(appLogExport))
+	m.Add("1.9", "Get", "/apps/{app}/log/retention", AuthorizationRequiredHandler(logRetentionInfo))
+	m.Add("1.9", "Put", "/apps/{app}/log/retention", AuthorizationRequiredHandler(logRetentionSet))
+	m.Add("1.9", "Delete", "/apps/{app}/log/retention", AuthorizationRequiredHandler(logRetentionRemove))
 	m.Add("1.0", "Post", "/apps/{appname}/deploy/rollback", AuthorizationRequiredHandler(deployRollback))
 	m.Add("1.4", "Put", "/apps/{appname}/deploy/rollback/update", AuthorizationRequiredHandler(deployRollbackUpdate))
 	m.Add("1.3", "Post", "/apps/{appname}/deploy/rebuild", AuthorizationRequiredHandler(deployRebuild))
@@ -279,6 +306,13 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.6", "Get", "/events/webhooks/{name}", AuthorizationRequiredHandler(webhookInfo))
 	m.Add("1.6", "Put", "/events/webhooks/{name}", AuthorizationRequiredHandler(webhookUpdate))
 	m.Add("1.6", "Delete", "/events/webhooks/{name}", AuthorizationRequiredHandler(webhookDelete))
+	m.Add("1.9", "Get", "/events/webhooks/{name}/deliveries", AuthorizationRequiredHandler(webhookDeliveries))
+	m.Add("1.9", "Post", "/events/webhooks/{name}/deliveries/{id}/redeliver", AuthorizationRequiredHandler(webhookRedeliver))
+
+	m.Add("1.9", "Get", "/schedules", AuthorizationRequiredHandler(scheduleList))
+	m.Add("1.9", "Post", "/schedules", AuthorizationRequiredHandler(scheduleCreate))
+	m.Add("1.9", "Delete", "/schedules/{name}", AuthorizationRequiredHandler(scheduleDelete))
+	m.Add("1.9", "Get", "/schedules/{name}/dry-run", AuthorizationRequiredHandler(scheduleDryRun))
 
 	m.Add("1.0", "Get", "/platforms", AuthorizationRequiredHandler(platformList))
 	m.Add("1.0", "Post", "/platforms", AuthorizationRequiredHandler(platformAdd))
@@ -353,6 +387,9 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Post", "/pools/{name}/team", AuthorizationRequiredHandler(addTeamToPoolHandler))
 	m.Add("1.0", "Delete", "/pools/{name}/team", AuthorizationRequiredHandler(removeTeamToPoolHandler))
 	m.Add("1.8", "Get", "/pools/{name}", AuthorizationRequiredHandler(getPoolHandler))
+	m.Add("1.9", "Get", "/pools/{name}/log/retention", AuthorizationRequiredHandler(logRetentionInfo))
+	m.Add("1.9", "Put", "/pools/{name}/log/retention", AuthorizationRequiredHandler(logRetentionSet))
+	m.Add("1.9", "Delete", "/pools/{name}/log/retention", AuthorizationRequiredHandler(logRetentionRemove))
 
 	m.Add("1.3", "Get", "/constraints", AuthorizationRequiredHandler(poolConstraintList))
 	m.Add("1.3", "Put", "/constraints", AuthorizationRequiredHandler(poolConstraintSet))
@@ -417,6 +454,10 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.2", "DELETE", "/healing/node", AuthorizationRequiredHandler(nodeHealingDelete))
 	m.Add("1.3", "GET", "/healing", AuthorizationRequiredHandler(healingHistoryHandler))
 	m.Add("1.3", "GET", "/routers", AuthorizationRequiredHandler(listRouters))
+	m.Add("1.9", "GET", "/routers/drift", AuthorizationRequiredHandler(routerDriftReport))
+	m.Add("1.9", "POST", "/routers", AuthorizationRequiredHandler(addRouter))
+	m.Add("1.9", "PUT", "/routers/{name}", AuthorizationRequiredHandler(updateRouter))
+	m.Add("1.9", "DELETE", "/routers/{name}", AuthorizationRequiredHandler(deleteRouter))
 	m.Add("1.2", "GET", "/metrics", promhttp.Handler())
 
 	m.Add("1.7", "GET", "/provisioner", AuthorizationRequiredHandler(provisionerList))
@@ -554,6 +595,18 @@ func bindAppsLister() ([]bind.App, error) {
 	return bindApps, nil
 }
 
+func rebuildAppsLister() ([]rebuild.RebuildApp, error) {
+	apps, err := app.List(nil)
+	if err != nil {
+		return nil, err
+	}
+	rebuildApps := make([]rebuild.RebuildApp, len(apps))
+	for i := range apps {
+		rebuildApps[i] = &apps[i]
+	}
+	return rebuildApps, nil
+}
+
 func startServer(handler http.Handler) error {
 	srvConf, err := createServers(handler)
 	if err != nil {
@@ -630,10 +683,34 @@ func startServer(handler http.Handler) error {
 	if err != nil {
 		return err
 	}
+	err = autoscale.InitializeApps()
+	if err != nil {
+		return err
+	}
+	err = schedule.Initialize()
+	if err != nil {
+		return err
+	}
+	err = app.FailStaleRuns()
+	if err != nil {
+		return errors.Wrap(err, "unable to finish interrupted app runs")
+	}
+	err = acme.Initialize()
+	if err != nil {
+		return errors.Wrap(err, "unable to start acme certificates manager")
+	}
+	err = retention.Initialize()
+	if err != nil {
+		return err
+	}
 	err = event.Initialize()
 	if err != nil {
 		return errors.Wrap(err, "unable to load events throttling config")
 	}
+	err = archive.Initialize()
+	if err != nil {
+		return errors.Wrap(err, "unable to start events archiver")
+	}
 	err = gc.Initialize()
 	if err != nil {
 		return errors.Wrap(err, "unable to initialize old image gc")
@@ -642,6 +719,10 @@ func startServer(handler http.Handler) error {
 	if err != nil {
 		return err
 	}
+	err = rebuild.InitializeDriftChecker(rebuildAppsLister)
+	if err != nil {
+		return errors.Wrap(err, "unable to start router drift checker")
+	}
 	fmt.Println("Checking components status:")
 	results := hc.Check("all")
 	for _, result := range results {
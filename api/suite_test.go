@@ -110,8 +110,10 @@ func (s *S) SetUpSuite(c *check.C) {
 func (s *S) SetUpTest(c *check.C) {
 	config.Set("routers:fake:default", true)
 	config.Set("routers:fake-tls:type", "fake-tls")
+	config.Set("routers:fake-weights:type", "fake-weights")
 	routertest.FakeRouter.Reset()
 	routertest.TLSRouter.Reset()
+	routertest.WeightsRouter.Reset()
 	repositorytest.Reset()
 	var err error
 	s.conn, err = db.Conn()
@@ -7,8 +7,10 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/tsuru/tsuru/auth"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/servicemanager"
@@ -41,6 +43,9 @@ func webhookList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 		w.WriteHeader(http.StatusNoContent)
 		return nil
 	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
 	w.Header().Set("Content-Type", "application/json")
 	return json.NewEncoder(w).Encode(webhooks)
 }
@@ -66,6 +71,7 @@ func webhookInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	if !permission.Check(t, permission.PermWebhookRead, ctx) {
 		return permission.ErrUnauthorized
 	}
+	webhook.Secret = ""
 	w.Header().Set("Content-Type", "application/json")
 	return json.NewEncoder(w).Encode(webhook)
 }
@@ -98,7 +104,7 @@ func webhookCreate(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 		Target:     event.Target{Type: event.TargetTypeWebhook, Value: webhook.Name},
 		Kind:       permission.PermWebhookCreate,
 		Owner:      t,
-		CustomData: event.FormToCustomData(InputFields(r)),
+		CustomData: event.FormToCustomData(InputFields(r, "secret")),
 		Allowed:    event.Allowed(permission.PermWebhookReadEvents, ctx),
 	})
 	if err != nil {
@@ -137,7 +143,7 @@ func webhookUpdate(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 		Target:     event.Target{Type: event.TargetTypeWebhook, Value: webhook.Name},
 		Kind:       permission.PermWebhookUpdate,
 		Owner:      t,
-		CustomData: event.FormToCustomData(InputFields(r)),
+		CustomData: event.FormToCustomData(InputFields(r, "secret")),
 		Allowed:    event.Allowed(permission.PermWebhookReadEvents, ctx),
 	})
 	if err != nil {
@@ -188,3 +194,85 @@ func webhookDelete(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	}()
 	return servicemanager.Webhook.Delete(webhookName)
 }
+
+// title: webhook deliveries
+// path: /events/webhooks/{name}/deliveries
+// method: GET
+// produce: application/json
+// responses:
+//   200: List webhook deliveries
+//   204: No content
+//   400: Invalid limit
+//   401: Unauthorized
+//   404: Webhook not found
+func webhookDeliveries(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	webhookName := r.URL.Query().Get(":name")
+	webhook, err := servicemanager.Webhook.Find(webhookName)
+	if err != nil {
+		if err == eventTypes.ErrWebhookNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return err
+	}
+	ctx := permission.Context(permTypes.CtxTeam, webhook.TeamOwner)
+	if !permission.Check(t, permission.PermWebhookRead, ctx) {
+		return permission.ErrUnauthorized
+	}
+	var limit int
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "limit must be a positive integer"}
+		}
+	}
+	deliveries, err := servicemanager.Webhook.Deliveries(webhookName, limit)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(deliveries)
+}
+
+// title: webhook redeliver
+// path: /events/webhooks/{name}/deliveries/{id}/redeliver
+// method: POST
+// responses:
+//   200: Redelivery scheduled
+//   401: Unauthorized
+//   404: Webhook or delivery not found
+func webhookRedeliver(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	webhookName := r.URL.Query().Get(":name")
+	webhook, err := servicemanager.Webhook.Find(webhookName)
+	if err != nil {
+		if err == eventTypes.ErrWebhookNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return err
+	}
+	ctx := permission.Context(permTypes.CtxTeam, webhook.TeamOwner)
+	if !permission.Check(t, permission.PermWebhookUpdateRedeliver, ctx) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeWebhook, Value: webhook.Name},
+		Kind:       permission.PermWebhookUpdateRedeliver,
+		Owner:      t,
+		CustomData: event.FormToCustomData(InputFields(r)),
+		Allowed:    event.Allowed(permission.PermWebhookReadEvents, ctx),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		evt.Done(err)
+	}()
+	err = servicemanager.Webhook.Redeliver(webhookName, r.URL.Query().Get(":id"))
+	if err == eventTypes.ErrWebhookDeliveryNotFound || err == eventTypes.ErrWebhookNotFound {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	return err
+}
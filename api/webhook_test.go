@@ -9,10 +9,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/ajg/form"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/servicemanager"
+	"github.com/tsuru/tsuru/storage"
 	eventTypes "github.com/tsuru/tsuru/types/event"
 	permTypes "github.com/tsuru/tsuru/types/permission"
 	check "gopkg.in/check.v1"
@@ -385,6 +389,62 @@ func (s *S) TestWebhookInfo(c *check.C) {
 	})
 }
 
+func (s *S) TestWebhookInfoAndListRedactSecret(c *check.C) {
+	err := servicemanager.Webhook.Create(eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       "http://me/xyz",
+		Secret:    "my-secret",
+	})
+	c.Assert(err, check.IsNil)
+	for _, url := range []string{"/1.6/events/webhooks/wh1", "/1.6/events/webhooks"} {
+		request, err := http.NewRequest("GET", url, nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		s.testServer.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusOK)
+		c.Assert(recorder.Body.String(), check.Not(check.Matches), "(?s).*my-secret.*")
+	}
+}
+
+func (s *S) TestWebhookCreateAndUpdateOmitSecretFromEvents(c *check.C) {
+	webhook1 := eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       "http://me",
+		Secret:    "my-secret",
+	}
+	for _, method := range []string{"POST", "PUT"} {
+		url := "/1.6/events/webhooks"
+		if method == "PUT" {
+			url += "/wh1"
+			webhook1.Secret = "my-new-secret"
+		}
+		bodyData, err := form.EncodeToString(webhook1)
+		c.Assert(err, check.IsNil)
+		request, err := http.NewRequest(method, url, strings.NewReader(bodyData))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		s.testServer.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	}
+	evts, err := event.List(&event.Filter{Target: event.Target{Type: event.TargetTypeWebhook, Value: "wh1"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 2)
+	for _, evt := range evts {
+		var data []map[string]interface{}
+		err = evt.StartData(&data)
+		c.Assert(err, check.IsNil)
+		c.Assert(data, check.Not(check.HasLen), 0)
+		rawData, err := json.Marshal(data)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(rawData), check.Not(check.Matches), "(?s).*secret.*")
+	}
+}
+
 func (s *S) TestWebhookInfoNotFound(c *check.C) {
 	request, err := http.NewRequest("GET", "/1.6/events/webhooks/wh1", nil)
 	c.Assert(err, check.IsNil)
@@ -394,3 +454,149 @@ func (s *S) TestWebhookInfoNotFound(c *check.C) {
 	s.testServer.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
 }
+
+func (s *S) TestWebhookDeliveries(c *check.C) {
+	err := servicemanager.Webhook.Create(eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       "http://me/xyz",
+	})
+	c.Assert(err, check.IsNil)
+	driver, err := storage.GetDefaultDbDriver()
+	c.Assert(err, check.IsNil)
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 1; i <= 3; i++ {
+		err = driver.WebhookStorage.InsertDelivery(eventTypes.WebhookDelivery{
+			Webhook:    "wh1",
+			EventID:    "evt1",
+			Attempt:    i,
+			Timestamp:  now.Add(time.Duration(i) * time.Second),
+			StatusCode: http.StatusBadGateway,
+			Response:   "bad gateway",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	request, err := http.NewRequest("GET", "/1.9/events/webhooks/wh1/deliveries?limit=2", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var deliveries []eventTypes.WebhookDelivery
+	err = json.Unmarshal(recorder.Body.Bytes(), &deliveries)
+	c.Assert(err, check.IsNil)
+	c.Assert(deliveries, check.HasLen, 2)
+	c.Assert(deliveries[0].Attempt, check.Equals, 3)
+	c.Assert(deliveries[0].StatusCode, check.Equals, http.StatusBadGateway)
+	c.Assert(deliveries[0].Response, check.Equals, "bad gateway")
+	c.Assert(deliveries[1].Attempt, check.Equals, 2)
+}
+
+func (s *S) TestWebhookDeliveriesEmpty(c *check.C) {
+	err := servicemanager.Webhook.Create(eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       "http://me/xyz",
+	})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/1.9/events/webhooks/wh1/deliveries", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestWebhookDeliveriesNotFound(c *check.C) {
+	request, err := http.NewRequest("GET", "/1.9/events/webhooks/wh1/deliveries", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestWebhookRedeliver(c *check.C) {
+	called := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(called)
+	}))
+	defer srv.Close()
+	err := servicemanager.Webhook.Create(eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       srv.URL,
+	})
+	c.Assert(err, check.IsNil)
+	evt, err := event.New(&event.Opts{
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: event.Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	driver, err := storage.GetDefaultDbDriver()
+	c.Assert(err, check.IsNil)
+	err = driver.WebhookStorage.InsertDelivery(eventTypes.WebhookDelivery{
+		ID:         "d1",
+		Webhook:    "wh1",
+		EventID:    evt.UniqueID.Hex(),
+		Attempt:    1,
+		Timestamp:  time.Now(),
+		StatusCode: http.StatusBadGateway,
+	})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/1.9/events/webhooks/wh1/deliveries/d1/redeliver", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK, check.Commentf("body: %s", recorder.Body.String()))
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for redelivery")
+	}
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeWebhook, Value: "wh1"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "webhook.update.redeliver",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestWebhookRedeliverDeliveryNotFound(c *check.C) {
+	err := servicemanager.Webhook.Create(eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       "http://me/xyz",
+	})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/1.9/events/webhooks/wh1/deliveries/d1/redeliver", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestWebhookRedeliverNoPermission(c *check.C) {
+	err := servicemanager.Webhook.Create(eventTypes.Webhook{
+		TeamOwner: s.team.Name,
+		Name:      "wh1",
+		URL:       "http://me/xyz",
+	})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermWebhookRead,
+		Context: permission.Context(permTypes.CtxTeam, s.team.Name),
+	})
+	request, err := http.NewRequest("POST", "/1.9/events/webhooks/wh1/deliveries/d1/redeliver", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	s.testServer.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
@@ -108,6 +108,11 @@ type App struct {
 	Tags            []string
 	Error           string
 	Routers         []appTypes.AppRouter
+	AutoScale       []appTypes.AutoScaleSpec
+	Jobs            []appTypes.Job
+	Processes       []appTypes.Process
+	Metadata        appTypes.Metadata
+	TrafficWeights  []TrafficWeight
 
 	// UUID is a v4 UUID lazily generated on the first call to GetUUID()
 	UUID string
@@ -216,6 +221,12 @@ func (app *App) MarshalJSON() ([]byte, error) {
 		"swap":     app.Plan.Swap,
 		"cpushare": app.Plan.CpuShare,
 	}
+	if app.Plan.CPURequest != 0 {
+		plan["cpurequest"] = app.Plan.CPURequest
+	}
+	if app.Plan.CPULimit != 0 {
+		plan["cpulimit"] = app.Plan.CPULimit
+	}
 	routers, err := app.GetRoutersWithAddr()
 	if err != nil {
 		errMsgs = append(errMsgs, fmt.Sprintf("unable to get app addresses: %+v", err))
@@ -239,6 +250,21 @@ func (app *App) MarshalJSON() ([]byte, error) {
 	if len(app.InternalAddresses) > 0 {
 		result["internalAddresses"] = app.InternalAddresses
 	}
+	if len(app.AutoScale) > 0 {
+		result["autoscale"] = app.AutoScale
+	}
+	if len(app.Jobs) > 0 {
+		result["jobs"] = app.Jobs
+	}
+	if len(app.Processes) > 0 {
+		result["processes"] = app.Processes
+	}
+	if !app.Metadata.Empty() {
+		result["metadata"] = app.Metadata
+	}
+	if len(app.TrafficWeights) > 0 {
+		result["trafficWeights"] = app.TrafficWeights
+	}
 	if len(errMsgs) > 0 {
 		result["error"] = strings.Join(errMsgs, "\n")
 	}
@@ -402,6 +428,13 @@ func (app *App) Update(updateData App, w io.Writer) (err error) {
 	if tags != nil {
 		app.Tags = tags
 	}
+	if !updateData.Metadata.Empty() {
+		err = updateData.Metadata.Validate()
+		if err != nil {
+			return &tsuruErrors.ValidationError{Message: err.Error()}
+		}
+		app.Metadata.Update(updateData.Metadata)
+	}
 	if platform != "" {
 		var p, v string
 		p, v, err = getPlatformNameAndVersion(platform)
@@ -607,6 +640,10 @@ func Delete(app *App, evt *event.Event, requestID string) error {
 	if err != nil {
 		logErr("Unable to unbind app", err)
 	}
+	err = app.clearTrafficWeights(w)
+	if err != nil {
+		logErr("Unable to clear traffic weights", err)
+	}
 	routers := app.GetRouters()
 	for _, appRouter := range routers {
 		var r router.Router
@@ -1196,6 +1233,10 @@ func cmdsForExec(cmd string) []string {
 }
 
 func (app *App) run(cmd string, w io.Writer, args provision.RunArgs) error {
+	return app.runWithEvent(cmd, w, args, nil)
+}
+
+func (app *App) runWithEvent(cmd string, w io.Writer, args provision.RunArgs, evt *event.Event) error {
 	prov, err := app.getProvisioner()
 	if err != nil {
 		return err
@@ -1209,6 +1250,7 @@ func (app *App) run(cmd string, w io.Writer, args provision.RunArgs) error {
 		Stdout: w,
 		Stderr: w,
 		Cmds:   cmdsForExec(cmd),
+		Event:  evt,
 	}
 	units, err := app.Units()
 	if err != nil {
@@ -1385,6 +1427,27 @@ func (app *App) GetCpuShare() int {
 	return app.Plan.CpuShare
 }
 
+// GetProcessResources returns the resources of each unit of a process,
+// from the process plan and resources, if set, or from the app plan.
+func (app *App) GetProcessResources(process string) appTypes.ProcessResources {
+	for _, p := range app.Processes {
+		if p.Name != process {
+			continue
+		}
+		plan := app.Plan
+		if p.Plan.Name != "" {
+			plan = p.Plan
+		}
+		return plan.Resources().Override(p.Resources)
+	}
+	return app.Plan.Resources()
+}
+
+// GetMetadata returns the labels and annotations of the app.
+func (app *App) GetMetadata() appTypes.Metadata {
+	return app.Metadata
+}
+
 func (app *App) GetAddresses() ([]string, error) {
 	routers, err := app.GetRoutersWithAddr()
 	if err != nil {
@@ -1602,6 +1665,10 @@ func serviceEnvsFromEnvVars(vars []bind.ServiceEnvVar) bind.EnvVar {
 }
 
 func (app *App) AddInstance(addArgs bind.AddInstanceArgs) error {
+	err := updateServiceBinds(addArgs.ServiceName, addArgs.InstanceName)
+	if err != nil {
+		return err
+	}
 	if len(addArgs.Envs) == 0 {
 		return nil
 	}
@@ -1625,6 +1692,10 @@ func (app *App) AddInstance(addArgs bind.AddInstanceArgs) error {
 }
 
 func (app *App) RemoveInstance(removeArgs bind.RemoveInstanceArgs) error {
+	err := updateServiceBinds(removeArgs.ServiceName, removeArgs.InstanceName)
+	if err != nil {
+		return err
+	}
 	lenBefore := len(app.ServiceEnvs)
 	for i := 0; i < len(app.ServiceEnvs); i++ {
 		se := app.ServiceEnvs[i]
@@ -1655,9 +1726,42 @@ func (app *App) RemoveInstance(removeArgs bind.RemoveInstanceArgs) error {
 	return nil
 }
 
+// updateServiceBinds notifies the provisioners handling service binds that
+// the apps bound to the service instance changed.
+func updateServiceBinds(serviceName, instanceName string) error {
+	if serviceName == "" || instanceName == "" {
+		return nil
+	}
+	provisioners, err := provision.Registry()
+	if err != nil {
+		return err
+	}
+	for _, p := range provisioners {
+		if bindProv, ok := p.(provision.ServiceBindProvisioner); ok {
+			err = bindProv.UpdateServiceBinds(serviceName, instanceName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // LastLogs returns a list of the last `lines` log of the app, matching the
 // fields in the log instance received as an example.
 func (app *App) LastLogs(logService appTypes.AppLogService, lines int, filterLog appTypes.Applog, invertFilter bool, t authTypes.Token) ([]appTypes.Applog, error) {
+	return app.ListLogs(logService, appTypes.ListLogArgs{
+		InvertFilters: invertFilter,
+		Limit:         lines,
+		Source:        filterLog.Source,
+		Unit:          filterLog.Unit,
+		Token:         t,
+	})
+}
+
+// ListLogs returns the log entries of the app matching args. The AppName in
+// args is always replaced by the name of the app.
+func (app *App) ListLogs(logService appTypes.AppLogService, args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
 	prov, err := app.getProvisioner()
 	if err != nil {
 		return nil, err
@@ -1674,14 +1778,8 @@ func (app *App) LastLogs(logService appTypes.AppLogService, lines int, filterLog
 			return nil, errors.New(doc)
 		}
 	}
-	return logService.List(appTypes.ListLogArgs{
-		AppName:       app.Name,
-		InvertFilters: invertFilter,
-		Limit:         lines,
-		Source:        filterLog.Source,
-		Unit:          filterLog.Unit,
-		Token:         t,
-	})
+	args.AppName = app.Name
+	return logService.List(args)
 }
 
 type Filter struct {
@@ -1694,6 +1792,9 @@ type Filter struct {
 	Pools       []string
 	Statuses    []string
 	Locked      bool
+	AutoScaled  bool
+	WithJobs    bool
+	WithCNames  bool
 	Tags        []string
 	Extra       map[string][]string
 }
@@ -1754,6 +1855,15 @@ func (f *Filter) Query() bson.M {
 	if f.Locked {
 		query["lock.locked"] = true
 	}
+	if f.AutoScaled {
+		query["autoscale.0"] = bson.M{"$exists": true}
+	}
+	if f.WithJobs {
+		query["jobs.0"] = bson.M{"$exists": true}
+	}
+	if f.WithCNames {
+		query["cname.0"] = bson.M{"$exists": true}
+	}
 	if len(f.Pools) > 0 {
 		query["pool"] = bson.M{"$in": f.Pools}
 	}
@@ -1958,6 +2068,155 @@ func Swap(app1, app2 *App, cnameOnly bool) error {
 	return updateCName(app2, r2)
 }
 
+// TrafficWeight is the percentage of the traffic of an app sent to the units
+// of another app.
+type TrafficWeight struct {
+	App    string `json:"app"`
+	Weight int    `json:"weight"`
+}
+
+// SetTrafficWeights splits the traffic sent to the app between the apps in
+// weights, in every router of the app. The weights must add up to 100 and
+// every app listed must use the same routers as the app. An empty list sends
+// all the traffic back to the app.
+func (app *App) SetTrafficWeights(weights []TrafficWeight) error {
+	backendWeights := make([]router.BackendWeight, len(weights))
+	targets := make([]*App, 0, len(weights))
+	seen := map[string]bool{}
+	total := 0
+	for i, w := range weights {
+		if w.Weight < 0 || w.Weight > 100 {
+			msg := fmt.Sprintf("weight for app %q must be between 0 and 100", w.App)
+			return &tsuruErrors.ValidationError{Message: msg}
+		}
+		if seen[w.App] {
+			msg := fmt.Sprintf("app %q is listed more than once", w.App)
+			return &tsuruErrors.ValidationError{Message: msg}
+		}
+		seen[w.App] = true
+		total += w.Weight
+		backendWeights[i] = router.BackendWeight{Backend: w.App, Weight: w.Weight}
+		if w.App == app.Name {
+			continue
+		}
+		target, err := GetByName(w.App)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+	if len(weights) > 0 && total != 100 {
+		msg := fmt.Sprintf("weights must add up to 100, got %d", total)
+		return &tsuruErrors.ValidationError{Message: msg}
+	}
+	appRouters := app.GetRouters()
+	if len(appRouters) == 0 {
+		return &tsuruErrors.ValidationError{Message: "app has no routers"}
+	}
+	splitRouters := make([]router.TrafficSplitRouter, len(appRouters))
+	for i, appRouter := range appRouters {
+		r, err := router.Get(appRouter.Name)
+		if err != nil {
+			return err
+		}
+		splitRouter, ok := r.(router.TrafficSplitRouter)
+		if !ok {
+			msg := fmt.Sprintf("router %q does not support traffic splitting", appRouter.Name)
+			return &tsuruErrors.ValidationError{Message: msg}
+		}
+		for _, target := range targets {
+			if !target.hasRouter(appRouter.Name) {
+				msg := fmt.Sprintf("app %q is not using router %q", target.Name, appRouter.Name)
+				return &tsuruErrors.ValidationError{Message: msg}
+			}
+		}
+		splitRouters[i] = splitRouter
+	}
+	previous := app.GetTrafficWeights()
+	for i, splitRouter := range splitRouters {
+		err := splitRouter.SetBackendWeights(app.Name, backendWeights)
+		if err == nil {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			rollbackErr := splitRouters[j].SetBackendWeights(app.Name, previous)
+			if rollbackErr != nil {
+				log.Errorf("[traffic-weights] unable to roll back weights of app %q in router %q: %s", app.Name, appRouters[j].Name, rollbackErr)
+			}
+		}
+		return errors.Wrapf(err, "unable to set weights in router %q", appRouters[i].Name)
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	update := bson.M{"$set": bson.M{"trafficweights": weights}}
+	if len(weights) == 0 {
+		weights = nil
+		update = bson.M{"$unset": bson.M{"trafficweights": ""}}
+	}
+	err = conn.Apps().Update(bson.M{"name": app.Name}, update)
+	if err != nil {
+		return err
+	}
+	app.TrafficWeights = weights
+	return nil
+}
+
+// GetTrafficWeights returns the weights set with SetTrafficWeights, reapplied
+// to the routers of the app when its routes are rebuilt.
+func (app *App) GetTrafficWeights() []router.BackendWeight {
+	if len(app.TrafficWeights) == 0 {
+		return nil
+	}
+	weights := make([]router.BackendWeight, len(app.TrafficWeights))
+	for i, w := range app.TrafficWeights {
+		weights[i] = router.BackendWeight{Backend: w.App, Weight: w.Weight}
+	}
+	return weights
+}
+
+// clearTrafficWeights sends all the traffic of the app, and of the apps
+// sending part of their traffic to it, back to their own units. It's called
+// when the app is removed.
+func (app *App) clearTrafficWeights(w io.Writer) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	var splitting []App
+	err = conn.Apps().Find(bson.M{"$or": []bson.M{
+		{"name": app.Name, "trafficweights.0": bson.M{"$exists": true}},
+		{"trafficweights.app": app.Name},
+	}}).All(&splitting)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+	errs := tsuruErrors.NewMultiError()
+	for i := range splitting {
+		other := &splitting[i]
+		if other.Name != app.Name {
+			fmt.Fprintf(w, "Sending all the traffic of app %q back to its units\n", other.Name)
+		}
+		err = other.SetTrafficWeights(nil)
+		if err != nil {
+			errs.Add(errors.Wrapf(err, "unable to clear traffic weights of app %q", other.Name))
+		}
+	}
+	return errs.ToError()
+}
+
+func (app *App) hasRouter(name string) bool {
+	for _, r := range app.GetRouters() {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Start starts the app calling the provisioner.Start method and
 // changing the units state to StatusStarted.
 func (app *App) Start(w io.Writer, process string) error {
@@ -2174,6 +2174,32 @@ func (s *S) TestAddInstanceAndRemoveInstanceMultipleServices(c *check.C) {
 	})
 }
 
+type serviceBindFakeProvisioner struct {
+	provisiontest.FakeProvisioner
+	binds []string
+}
+
+func (p *serviceBindFakeProvisioner) UpdateServiceBinds(serviceName, instanceName string) error {
+	p.binds = append(p.binds, serviceName+"/"+instanceName)
+	return nil
+}
+
+func (s *S) TestAddInstanceAndRemoveInstanceUpdateServiceBinds(c *check.C) {
+	p := &serviceBindFakeProvisioner{}
+	provision.Register("service-bind", func() (provision.Provisioner, error) {
+		return p, nil
+	})
+	defer provision.Unregister("service-bind")
+	a := &App{Name: "dark", TeamOwner: s.team.Name}
+	err := CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddInstance(bind.AddInstanceArgs{ServiceName: "mysql", InstanceName: "db"})
+	c.Assert(err, check.IsNil)
+	err = a.RemoveInstance(bind.RemoveInstanceArgs{ServiceName: "mysql", InstanceName: "db"})
+	c.Assert(err, check.IsNil)
+	c.Assert(p.binds, check.DeepEquals, []string{"mysql/db", "mysql/db"})
+}
+
 func (s *S) TestRemoveInstance(c *check.C) {
 	a := &App{Name: "dark", TeamOwner: s.team.Name}
 	err := CreateApp(a, s.user)
@@ -3879,6 +3905,122 @@ func (s *S) TestSwapCnameOnly(c *check.C) {
 	c.Assert(newAddrs2, check.DeepEquals, oldAddrs2)
 }
 
+func (s *S) TestSetTrafficWeights(c *check.C) {
+	app1 := &App{Name: "app1", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := CreateApp(app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := &App{Name: "app2", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err = CreateApp(app2, s.user)
+	c.Assert(err, check.IsNil)
+	err = app1.SetTrafficWeights([]TrafficWeight{{App: "app1", Weight: 90}, {App: "app2", Weight: 10}})
+	c.Assert(err, check.IsNil)
+	weights, err := routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.DeepEquals, []router.BackendWeight{
+		{Backend: "app1", Weight: 90},
+		{Backend: "app2", Weight: 10},
+	})
+	dbApp, err := GetByName(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.TrafficWeights, check.DeepEquals, []TrafficWeight{{App: "app1", Weight: 90}, {App: "app2", Weight: 10}})
+	err = app1.SetTrafficWeights(nil)
+	c.Assert(err, check.IsNil)
+	weights, err = routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.HasLen, 0)
+	dbApp, err = GetByName(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.TrafficWeights, check.HasLen, 0)
+}
+
+type failingWeightsRouter struct {
+	router.Router
+}
+
+func (r *failingWeightsRouter) SetBackendWeights(name string, weights []router.BackendWeight) error {
+	return stderrors.New("weights unavailable")
+}
+
+func (r *failingWeightsRouter) GetBackendWeights(name string) ([]router.BackendWeight, error) {
+	return nil, nil
+}
+
+func (s *S) TestSetTrafficWeightsRollsBackOnFailure(c *check.C) {
+	router.Register("failing-weights", func(name string, config router.ConfigGetter) (router.Router, error) {
+		return &failingWeightsRouter{Router: &routertest.FakeRouter}, nil
+	})
+	config.Set("routers:failing-weights:type", "failing-weights")
+	defer config.Unset("routers:failing-weights")
+	routers := []appTypes.AppRouter{{Name: "fake-weights"}, {Name: "failing-weights"}}
+	app1 := &App{Name: "app1", TeamOwner: s.team.Name, Routers: routers}
+	err := CreateApp(app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := &App{Name: "app2", TeamOwner: s.team.Name, Routers: routers}
+	err = CreateApp(app2, s.user)
+	c.Assert(err, check.IsNil)
+	err = app1.SetTrafficWeights([]TrafficWeight{{App: "app1", Weight: 90}, {App: "app2", Weight: 10}})
+	c.Assert(err, check.ErrorMatches, `unable to set weights in router "failing-weights": weights unavailable`)
+	weights, err := routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.HasLen, 0)
+	dbApp, err := GetByName(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.TrafficWeights, check.HasLen, 0)
+}
+
+func (s *S) TestDeleteClearsTrafficWeights(c *check.C) {
+	app1 := &App{Name: "app1", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := CreateApp(app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := &App{Name: "app2", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err = CreateApp(app2, s.user)
+	c.Assert(err, check.IsNil)
+	err = app1.SetTrafficWeights([]TrafficWeight{{App: "app1", Weight: 90}, {App: "app2", Weight: 10}})
+	c.Assert(err, check.IsNil)
+	evt, err := event.New(&event.Opts{
+		Target:   event.Target{Type: "app", Value: app2.Name},
+		Kind:     permission.PermAppDelete,
+		RawOwner: event.Owner{Type: event.OwnerTypeUser, Name: s.user.Email},
+		Allowed:  event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	err = Delete(app2, evt, "")
+	c.Assert(err, check.IsNil)
+	weights, err := routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.HasLen, 0)
+	dbApp, err := GetByName(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.TrafficWeights, check.HasLen, 0)
+}
+
+func (s *S) TestSetTrafficWeightsInvalid(c *check.C) {
+	app1 := &App{Name: "app1", TeamOwner: s.team.Name, Routers: []appTypes.AppRouter{{Name: "fake-weights"}}}
+	err := CreateApp(app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := &App{Name: "app2", TeamOwner: s.team.Name}
+	err = CreateApp(app2, s.user)
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		weights []TrafficWeight
+		msg     string
+	}{
+		{[]TrafficWeight{{App: "app1", Weight: 101}}, `weight for app "app1" must be between 0 and 100`},
+		{[]TrafficWeight{{App: "app1", Weight: 50}, {App: "app1", Weight: 50}}, `app "app1" is listed more than once`},
+		{[]TrafficWeight{{App: "app1", Weight: 50}, {App: "app2", Weight: 40}}, `weights must add up to 100, got 90`},
+		{[]TrafficWeight{{App: "app1", Weight: 50}, {App: "app2", Weight: 50}}, `app "app2" is not using router "fake-weights"`},
+	}
+	for _, tt := range tests {
+		err = app1.SetTrafficWeights(tt.weights)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+		c.Assert(err, check.ErrorMatches, tt.msg)
+	}
+	err = app1.SetTrafficWeights([]TrafficWeight{{App: "app1", Weight: 50}, {App: "unknown", Weight: 50}})
+	c.Assert(err, check.Equals, appTypes.ErrAppNotFound)
+	err = app2.SetTrafficWeights([]TrafficWeight{{App: "app2", Weight: 100}})
+	c.Assert(err, check.ErrorMatches, `router "fake" does not support traffic splitting`)
+}
+
 func (s *S) TestStart(c *check.C) {
 	s.provisioner.PrepareOutput([]byte("not yaml")) // loadConf
 	a := App{
@@ -4485,6 +4627,52 @@ func (s *S) TestUpdateDescription(c *check.C) {
 	c.Assert(dbApp.Description, check.Equals, "bleble")
 }
 
+func (s *S) TestUpdateMetadata(c *check.C) {
+	app := App{Name: "example", Platform: "python", TeamOwner: s.team.Name}
+	app.Metadata.Labels = []appTypes.MetadataItem{{Name: "team", Value: "a"}, {Name: "tier", Value: "backend"}}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	updateData := App{Name: "example", Metadata: appTypes.Metadata{
+		Labels: []appTypes.MetadataItem{
+			{Name: "team", Value: "b"},
+			{Name: "tier", Delete: true},
+		},
+		Annotations: []appTypes.MetadataItem{
+			{Name: "tsuru.io/anti-affinity", Value: "required"},
+		},
+	}}
+	err = app.Update(updateData, new(bytes.Buffer))
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(app.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Metadata, check.DeepEquals, appTypes.Metadata{
+		Labels:      []appTypes.MetadataItem{{Name: "team", Value: "b"}},
+		Annotations: []appTypes.MetadataItem{{Name: "tsuru.io/anti-affinity", Value: "required"}},
+	})
+	value, ok := dbApp.Metadata.Annotation("tsuru.io/anti-affinity")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(value, check.Equals, "required")
+}
+
+func (s *S) TestUpdateMetadataInvalid(c *check.C) {
+	app := App{Name: "example", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	updateData := App{Name: "example", Metadata: appTypes.Metadata{
+		Labels: []appTypes.MetadataItem{{Name: "team", Value: "not valid"}},
+	}}
+	err = app.Update(updateData, new(bytes.Buffer))
+	c.Assert(err, check.ErrorMatches, `invalid value "not valid" for label "team"`)
+	updateData = App{Name: "example", Metadata: appTypes.Metadata{
+		Annotations: []appTypes.MetadataItem{{Name: "invalid name", Value: "x"}},
+	}}
+	err = app.Update(updateData, new(bytes.Buffer))
+	c.Assert(err, check.ErrorMatches, `invalid metadata name "invalid name"`)
+	dbApp, err := GetByName(app.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Metadata, check.DeepEquals, appTypes.Metadata{})
+}
+
 func (s *S) TestUpdateAppPlatform(c *check.C) {
 	app := App{Name: "example", Platform: "python", TeamOwner: s.team.Name}
 	err := CreateApp(&app, s.user)
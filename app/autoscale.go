@@ -0,0 +1,115 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/set"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+// AutoScaleInfo returns the units autoscale specs configured for the app.
+func (app *App) AutoScaleInfo() []appTypes.AutoScaleSpec {
+	return app.AutoScale
+}
+
+// SetAutoScale adds or replaces the autoscale spec for a process of the app.
+// The process may be omitted if the app has only one process.
+func (app *App) SetAutoScale(spec appTypes.AutoScaleSpec) error {
+	err := spec.Validate()
+	if err != nil {
+		return err
+	}
+	processes, err := image.AllAppProcesses(app.Name)
+	if err != nil && errors.Cause(err) != image.ErrNoImagesAvailable {
+		return err
+	}
+	if spec.Process == "" {
+		if len(processes) != 1 {
+			return provision.InvalidProcessError{Msg: "process is required when the app has more than one process"}
+		}
+		spec.Process = processes[0]
+	}
+	if len(processes) > 0 && !set.FromSlice(processes).Includes(spec.Process) {
+		return provision.InvalidProcessError{Msg: fmt.Sprintf("process %q not found in app", spec.Process)}
+	}
+	specs := make([]appTypes.AutoScaleSpec, 0, len(app.AutoScale)+1)
+	for _, s := range app.AutoScale {
+		if s.Process != spec.Process {
+			specs = append(specs, s)
+		}
+	}
+	specs = append(specs, spec)
+	err = app.updateAutoScaleDB(specs)
+	if err != nil {
+		return err
+	}
+	return app.updateProvisionerAutoScale()
+}
+
+// RemoveAutoScale removes the autoscale spec for a process of the app.
+func (app *App) RemoveAutoScale(process string) error {
+	var specs []appTypes.AutoScaleSpec
+	for _, s := range app.AutoScale {
+		if s.Process != process {
+			specs = append(specs, s)
+		}
+	}
+	if len(specs) == len(app.AutoScale) {
+		return appTypes.ErrAutoScaleNotFound
+	}
+	err := app.updateAutoScaleDB(specs)
+	if err != nil {
+		return err
+	}
+	return app.updateProvisionerAutoScale()
+}
+
+// HandlesAutoScale returns whether the units of the process in spec are
+// scaled natively by the app provisioner instead of tsuru.
+func (app *App) HandlesAutoScale(spec appTypes.AutoScaleSpec) (bool, error) {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return false, err
+	}
+	autoScaleProv, ok := prov.(provision.AutoScaleProvisioner)
+	if !ok {
+		return false, nil
+	}
+	return autoScaleProv.HandlesAutoScale(app, spec)
+}
+
+func (app *App) updateProvisionerAutoScale() error {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	if autoScaleProv, ok := prov.(provision.AutoScaleProvisioner); ok {
+		return autoScaleProv.UpdateAutoScale(app)
+	}
+	return nil
+}
+
+func (app *App) updateAutoScaleDB(specs []appTypes.AutoScaleSpec) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{
+		"$set": bson.M{"autoscale": specs},
+	})
+	if err != nil {
+		return err
+	}
+	app.AutoScale = specs
+	return nil
+}
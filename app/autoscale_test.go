@@ -0,0 +1,95 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) saveAppProcesses(c *check.C, appName string, processes ...string) {
+	imgName := "tsuru/app-" + appName + ":v1"
+	err := image.AppendAppImageName(appName, imgName)
+	c.Assert(err, check.IsNil)
+	data := image.ImageMetadata{Name: imgName, Processes: map[string][]string{}}
+	for _, p := range processes {
+		data.Processes[p] = []string{"run-" + p}
+	}
+	err = data.Save()
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestSetAutoScale(c *check.C) {
+	a := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.saveAppProcesses(c, a.Name, "web", "worker")
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 70}
+	err = a.SetAutoScale(spec)
+	c.Assert(err, check.IsNil)
+	spec2 := appTypes.AutoScaleSpec{Process: "worker", MinUnits: 2, MaxUnits: 4, TargetMemory: 80}
+	err = a.SetAutoScale(spec2)
+	c.Assert(err, check.IsNil)
+	spec.MaxUnits = 10
+	err = a.SetAutoScale(spec)
+	c.Assert(err, check.IsNil)
+	c.Assert(a.AutoScaleInfo(), check.DeepEquals, []appTypes.AutoScaleSpec{spec2, spec})
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.AutoScaleInfo(), check.DeepEquals, []appTypes.AutoScaleSpec{spec2, spec})
+	apps, err := List(&Filter{AutoScaled: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 1)
+	c.Assert(apps[0].Name, check.Equals, a.Name)
+}
+
+func (s *S) TestSetAutoScaleDefaultProcess(c *check.C) {
+	a := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.saveAppProcesses(c, a.Name, "web")
+	err = a.SetAutoScale(appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 5, TargetCPU: 70})
+	c.Assert(err, check.IsNil)
+	c.Assert(a.AutoScaleInfo(), check.DeepEquals, []appTypes.AutoScaleSpec{
+		{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 70},
+	})
+}
+
+func (s *S) TestSetAutoScaleInvalid(c *check.C) {
+	a := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.saveAppProcesses(c, a.Name, "web", "worker")
+	err = a.SetAutoScale(appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5})
+	c.Assert(err, check.Equals, appTypes.ErrAutoScaleNoTarget)
+	err = a.SetAutoScale(appTypes.AutoScaleSpec{Process: "web", MinUnits: 6, MaxUnits: 5, TargetCPU: 50})
+	c.Assert(err, check.Equals, appTypes.ErrAutoScaleInvalidUnits)
+	err = a.SetAutoScale(appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 150})
+	c.Assert(err, check.Equals, appTypes.ErrAutoScaleInvalidPct)
+	err = a.SetAutoScale(appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 5, TargetCPU: 50})
+	c.Assert(err, check.FitsTypeOf, provision.InvalidProcessError{})
+	err = a.SetAutoScale(appTypes.AutoScaleSpec{Process: "other", MinUnits: 1, MaxUnits: 5, TargetCPU: 50})
+	c.Assert(err, check.ErrorMatches, `process error: process "other" not found in app`)
+	c.Assert(a.AutoScaleInfo(), check.HasLen, 0)
+}
+
+func (s *S) TestRemoveAutoScale(c *check.C) {
+	a := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.saveAppProcesses(c, a.Name, "web", "worker")
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 70}
+	err = a.SetAutoScale(spec)
+	c.Assert(err, check.IsNil)
+	err = a.RemoveAutoScale("worker")
+	c.Assert(err, check.Equals, appTypes.ErrAutoScaleNotFound)
+	err = a.RemoveAutoScale("web")
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.AutoScaleInfo(), check.HasLen, 0)
+}
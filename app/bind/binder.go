@@ -61,6 +61,8 @@ type UnsetEnvArgs struct {
 }
 
 type AddInstanceArgs struct {
+	ServiceName   string
+	InstanceName  string
 	Envs          []ServiceEnvVar
 	Writer        io.Writer
 	ShouldRestart bool
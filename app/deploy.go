@@ -174,6 +174,7 @@ type DeployOptions struct {
 	Kind         DeployKind
 	Message      string
 	Token        auth.Token
+	Strategy     *DeployStrategy `bson:",omitempty"`
 }
 
 func (o *DeployOptions) GetOrigin() string {
@@ -326,6 +327,12 @@ func deployToProvisioner(opts *DeployOptions, evt *event.Event) (string, error)
 	if opts.App.GetPlatform() == "" && opts.Kind != DeployImage && opts.Kind != DeployRollback {
 		return "", errors.Errorf("can't deploy app without platform, if it's not an image or rollback")
 	}
+	if opts.Strategy != nil {
+		err = checkDeployStrategySupport(prov, opts.Strategy)
+		if err != nil {
+			return "", err
+		}
+	}
 
 	if opts.Kind != DeployRollback {
 		if deployer, ok := prov.(provision.BuilderDeploy); ok {
@@ -333,10 +340,16 @@ func deployToProvisioner(opts *DeployOptions, evt *event.Event) (string, error)
 			if err != nil {
 				return "", err
 			}
+			if opts.Strategy != nil {
+				return deployWithStrategy(prov, opts, evt, imageID, deployer.Deploy)
+			}
 			return deployer.Deploy(opts.App, imageID, evt)
 		}
 	} else {
 		if deployer, ok := prov.(provision.RollbackableDeployer); ok {
+			if opts.Strategy != nil {
+				return deployWithStrategy(prov, opts, evt, opts.Image, deployer.Rollback)
+			}
 			return deployer.Rollback(opts.App, opts.Image, evt)
 		}
 	}
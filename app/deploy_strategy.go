@@ -0,0 +1,439 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app/image"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/router"
+)
+
+type DeployStrategyType string
+
+const (
+	DeployStrategyCanary    DeployStrategyType = "canary"
+	DeployStrategyBlueGreen DeployStrategyType = "blue-green"
+)
+
+var (
+	ErrInvalidDeployStrategy      = errors.New("invalid deploy strategy, valid values are: canary, blue-green")
+	ErrInvalidDeployStrategySteps = errors.New("canary steps must be increasing percentages between 1 and 100")
+	ErrDeployStrategyCanceled     = errors.New("deploy canceled by user request")
+
+	defaultCanarySteps = []int{10, 50, 100}
+
+	strategyCheckInterval = 5 * time.Second
+)
+
+// maxSplitDeviation is how far, in percentage points, the share of traffic
+// actually sent to the new units may be from the one requested in a step.
+const maxSplitDeviation = 10
+
+// DeployStrategy describes how the traffic is moved from the units running
+// the current image to the units running the image being deployed. Units for
+// the new image are started side by side with the old ones and the traffic is
+// shifted in Steps (percentages of traffic to the new units), pausing for
+// Interval after each step while the new units are checked for failures.
+type DeployStrategy struct {
+	Type     DeployStrategyType
+	Steps    []int
+	Interval time.Duration
+}
+
+// ParseDeployStrategy builds a DeployStrategy from its string
+// representation, as received by the API. An empty strategyType means no
+// strategy at all.
+func ParseDeployStrategy(strategyType, steps, interval string) (*DeployStrategy, error) {
+	if strategyType == "" {
+		return nil, nil
+	}
+	strategy := DeployStrategy{Type: DeployStrategyType(strategyType)}
+	if steps != "" {
+		for _, s := range strings.Split(steps, ",") {
+			step, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(s, "%")))
+			if err != nil {
+				return nil, ErrInvalidDeployStrategySteps
+			}
+			strategy.Steps = append(strategy.Steps, step)
+		}
+	}
+	if interval != "" {
+		var err error
+		strategy.Interval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid deploy strategy interval")
+		}
+	}
+	err := strategy.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &strategy, nil
+}
+
+func (s *DeployStrategy) Validate() error {
+	switch s.Type {
+	case DeployStrategyCanary:
+	case DeployStrategyBlueGreen:
+		if len(s.Steps) > 0 {
+			return errors.New("blue-green deploy strategy does not accept steps")
+		}
+	default:
+		return ErrInvalidDeployStrategy
+	}
+	if s.Interval < 0 {
+		return errors.New("deploy strategy interval must not be negative")
+	}
+	last := 0
+	for _, step := range s.Steps {
+		if step <= last || step > 100 {
+			return ErrInvalidDeployStrategySteps
+		}
+		last = step
+	}
+	return nil
+}
+
+func (s *DeployStrategy) steps() []int {
+	if s.Type == DeployStrategyBlueGreen {
+		return []int{100}
+	}
+	steps := s.Steps
+	if len(steps) == 0 {
+		steps = defaultCanarySteps
+	}
+	if steps[len(steps)-1] != 100 {
+		steps = append(append([]int{}, steps...), 100)
+	}
+	return steps
+}
+
+// ValidateDeployStrategy checks whether the provisioner of the app is able
+// to deploy it using strategy. It's meant to be called before starting the
+// deploy, so the build is not wasted on a strategy that can't be used.
+func (app *App) ValidateDeployStrategy(strategy *DeployStrategy) error {
+	if strategy == nil {
+		return nil
+	}
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	return checkDeployStrategySupport(prov, strategy)
+}
+
+func checkDeployStrategySupport(prov provision.Provisioner, strategy *DeployStrategy) error {
+	if _, ok := prov.(provision.StrategyDeployer); !ok {
+		msg := fmt.Sprintf("provisioner %q does not support the %s deploy strategy", prov.GetName(), strategy.Type)
+		return &tsuruErrors.ValidationError{Message: msg}
+	}
+	return nil
+}
+
+type unhealthyUnitsError struct {
+	units []string
+}
+
+func (e *unhealthyUnitsError) Error() string {
+	return fmt.Sprintf("new units are not healthy: %s", strings.Join(e.units, ", "))
+}
+
+type deployFunc func(provision.App, string, *event.Event) (string, error)
+
+// deployWithStrategy deploys newImage according to opts.Strategy. If the app
+// has no previously deployed image, fallback is used to replace every unit at
+// once. Any failure while shifting the traffic moves it back to the units
+// running the previous image and removes the new units.
+func deployWithStrategy(prov provision.Provisioner, opts *DeployOptions, evt *event.Event, newImage string, fallback deployFunc) (string, error) {
+	err := checkDeployStrategySupport(prov, opts.Strategy)
+	if err != nil {
+		return "", err
+	}
+	deployer := prov.(provision.StrategyDeployer)
+	prevImage, err := previousAppImage(opts.App.Name, newImage)
+	if err != nil {
+		return "", err
+	}
+	if prevImage == "" {
+		fmt.Fprintf(evt, "\n---- No previous image found, ignoring %s deploy strategy ----\n", opts.Strategy.Type)
+		return fallback(opts.App, newImage, evt)
+	}
+	fmt.Fprintf(evt, "\n---- Starting %s deploy, previous image is %s ----\n", opts.Strategy.Type, prevImage)
+	newImage, candidates, err := deployer.DeployCandidate(opts.App, newImage, evt)
+	if err != nil {
+		return "", abortStrategyDeploy(deployer, nil, opts.App, prevImage, evt, err)
+	}
+	shifter, err := newTrafficShifter(prov, opts.App, candidates)
+	if err != nil {
+		return "", abortStrategyDeploy(deployer, nil, opts.App, prevImage, evt, err)
+	}
+	steps, err := shifter.plan(opts.Strategy, evt)
+	if err != nil {
+		return "", abortStrategyDeploy(deployer, shifter, opts.App, prevImage, evt, err)
+	}
+	checker := &candidateChecker{prov: prov, app: opts.App, units: candidates}
+	for _, step := range steps {
+		err = checker.check()
+		if err == nil {
+			err = shifter.shift(step, evt)
+		}
+		if err == nil {
+			err = checker.watch(opts.Strategy.Interval, evt)
+		}
+		if err != nil {
+			return "", abortStrategyDeploy(deployer, shifter, opts.App, prevImage, evt, err)
+		}
+	}
+	fmt.Fprintf(evt, "\n---- Promoting image %s ----\n", newImage)
+	return deployer.PromoteCandidate(opts.App, newImage, evt)
+}
+
+func abortStrategyDeploy(deployer provision.StrategyDeployer, shifter *trafficShifter, a *App, prevImage string, evt *event.Event, cause error) error {
+	fmt.Fprintf(evt, "\n**** ABORTING DEPLOY AFTER FAILURE ****\n ---> %s <---\n", cause)
+	if shifter != nil {
+		err := shifter.restore(evt)
+		if err != nil {
+			log.Errorf("[deploy strategy] unable to restore routes for app %q: %s", a.Name, err)
+		}
+	}
+	fmt.Fprintf(evt, "\n---- Rolling back to image %s ----\n", prevImage)
+	_, err := deployer.PromoteCandidate(a, prevImage, evt)
+	if err != nil {
+		log.Errorf("[deploy strategy] unable to roll back app %q to image %q: %s", a.Name, prevImage, err)
+	}
+	return cause
+}
+
+// previousAppImage returns the last valid image for the app different from
+// newImage, which is the one currently serving the traffic.
+func previousAppImage(appName, newImage string) (string, error) {
+	images, err := image.ListValidAppImages(appName)
+	if err != nil {
+		return "", err
+	}
+	for i := len(images) - 1; i >= 0; i-- {
+		if images[i] != newImage {
+			return images[i], nil
+		}
+	}
+	return "", nil
+}
+
+type candidateChecker struct {
+	prov  provision.Provisioner
+	app   *App
+	units []provision.Unit
+}
+
+func (c *candidateChecker) check() error {
+	units, err := c.prov.Units(c.app)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]provision.Unit, len(units))
+	for _, u := range units {
+		current[u.ID] = u
+	}
+	var unhealthy []string
+	for _, candidate := range c.units {
+		u, ok := current[candidate.ID]
+		if !ok {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s (removed)", candidate.ID))
+			continue
+		}
+		if u.Status != provision.StatusStarted && u.Status != provision.StatusStarting {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s (%s)", u.ID, u.Status))
+		}
+	}
+	if len(unhealthy) > 0 {
+		return &unhealthyUnitsError{units: unhealthy}
+	}
+	return nil
+}
+
+func (c *candidateChecker) watch(interval time.Duration, evt *event.Event) error {
+	if interval <= 0 {
+		return nil
+	}
+	fmt.Fprintf(evt, " ---> Waiting %v while checking new units\n", interval)
+	deadline := time.Now().Add(interval)
+	for {
+		canceled, err := evt.AckCancel()
+		if err != nil {
+			log.Errorf("[deploy strategy] unable to check if event should be canceled, ignoring: %s", err)
+		}
+		if canceled {
+			return ErrDeployStrategyCanceled
+		}
+		err = c.check()
+		if err != nil {
+			return err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+		if remaining > strategyCheckInterval {
+			remaining = strategyCheckInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// trafficShifter moves the app routes from the old units to the new ones.
+// The share of the traffic sent to the new units is given by the proportion
+// of routes pointing to them in the app routers, so only the percentages
+// allowed by the number of old and new units can be honored.
+type trafficShifter struct {
+	app     *App
+	oldAddr []*url.URL
+	newAddr []*url.URL
+	current map[string]*url.URL
+}
+
+func newTrafficShifter(prov provision.Provisioner, a *App, candidates []provision.Unit) (*trafficShifter, error) {
+	routable, err := prov.RoutableAddresses(a)
+	if err != nil {
+		return nil, err
+	}
+	candidateAddrs := make(map[string]struct{}, len(candidates))
+	for _, u := range candidates {
+		if u.Address != nil {
+			candidateAddrs[u.Address.String()] = struct{}{}
+		}
+	}
+	s := &trafficShifter{app: a, current: map[string]*url.URL{}}
+	for i := range routable {
+		addr := routable[i]
+		if _, ok := candidateAddrs[addr.String()]; ok {
+			s.newAddr = append(s.newAddr, &addr)
+			continue
+		}
+		s.oldAddr = append(s.oldAddr, &addr)
+		s.current[addr.String()] = &addr
+	}
+	sort.Slice(s.newAddr, func(i, j int) bool { return s.newAddr[i].String() < s.newAddr[j].String() })
+	sort.Slice(s.oldAddr, func(i, j int) bool { return s.oldAddr[i].String() < s.oldAddr[j].String() })
+	return s, nil
+}
+
+// split returns how many routes to new and old units are needed to send the
+// share of the traffic closest to percent to the new units, along with the
+// resulting share. Old units keep receiving traffic until the last step.
+func (s *trafficShifter) split(percent int) (newCount, oldCount, share int) {
+	if percent >= 100 || len(s.oldAddr) == 0 {
+		return len(s.newAddr), 0, 100
+	}
+	bestDiff := -1
+	for n := 1; n <= len(s.newAddr); n++ {
+		for o := 1; o <= len(s.oldAddr); o++ {
+			current := n * 100 / (n + o)
+			diff := current - percent
+			if diff < 0 {
+				diff = -diff
+			}
+			if bestDiff == -1 || diff < bestDiff || (diff == bestDiff && n+o > newCount+oldCount) {
+				newCount, oldCount, share, bestDiff = n, o, current, diff
+			}
+		}
+	}
+	return newCount, oldCount, share
+}
+
+// plan returns the steps of strategy that can be honored with the current
+// units. Steps explicitly requested by the user that can't be represented
+// within maxSplitDeviation fail the deploy, while default ones are skipped.
+func (s *trafficShifter) plan(strategy *DeployStrategy, evt *event.Event) ([]int, error) {
+	explicit := len(strategy.Steps) > 0
+	var steps []int
+	for _, step := range strategy.steps() {
+		newCount, oldCount, share := s.split(step)
+		diff := share - step
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= maxSplitDeviation {
+			steps = append(steps, step)
+			continue
+		}
+		if explicit {
+			msg := fmt.Sprintf("unable to route %d%% of traffic to new units, closest split with %d new and %d old units is %d%% (%d new and %d old routes), use larger steps or more units",
+				step, len(s.newAddr), len(s.oldAddr), share, newCount, oldCount)
+			return nil, &tsuruErrors.ValidationError{Message: msg}
+		}
+		fmt.Fprintf(evt, " ---> Skipping %d%% step, closest split with current units is %d%%\n", step, share)
+	}
+	return steps, nil
+}
+
+func (s *trafficShifter) shift(percent int, evt *event.Event) error {
+	newCount, oldCount, share := s.split(percent)
+	if newCount+oldCount == 0 {
+		return errors.New("no routable units available")
+	}
+	desired := append(append([]*url.URL{}, s.newAddr[:newCount]...), s.oldAddr[:oldCount]...)
+	err := s.apply(desired)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(evt, " ---> Routing %d%% of traffic to new units (requested %d%%, %d new and %d old routes)\n", share, percent, newCount, oldCount)
+	return nil
+}
+
+func (s *trafficShifter) restore(evt *event.Event) error {
+	err := s.apply(s.oldAddr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(evt, " ---> Restored routes to %d old units\n", len(s.oldAddr))
+	return nil
+}
+
+func (s *trafficShifter) apply(desired []*url.URL) error {
+	desiredMap := make(map[string]*url.URL, len(desired))
+	var toAdd, toRemove []*url.URL
+	for _, addr := range desired {
+		desiredMap[addr.String()] = addr
+		if _, ok := s.current[addr.String()]; !ok {
+			toAdd = append(toAdd, addr)
+		}
+	}
+	for key, addr := range s.current {
+		if _, ok := desiredMap[key]; !ok {
+			toRemove = append(toRemove, addr)
+		}
+	}
+	for _, appRouter := range s.app.GetRouters() {
+		r, err := router.Get(appRouter.Name)
+		if err != nil {
+			return err
+		}
+		if len(toAdd) > 0 {
+			err = r.AddRoutes(s.app.Name, toAdd)
+			if err != nil {
+				return err
+			}
+		}
+		if len(toRemove) > 0 {
+			err = r.RemoveRoutes(s.app.Name, toRemove)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	s.current = desiredMap
+	return nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/builder"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/router/routertest"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestParseDeployStrategy(c *check.C) {
+	tests := []struct {
+		strategyType, steps, interval string
+		expected                      *DeployStrategy
+		err                           string
+	}{
+		{"", "", "", nil, ""},
+		{"canary", "", "", &DeployStrategy{Type: DeployStrategyCanary}, ""},
+		{"canary", "10,50%, 100", "1m", &DeployStrategy{Type: DeployStrategyCanary, Steps: []int{10, 50, 100}, Interval: time.Minute}, ""},
+		{"blue-green", "", "30s", &DeployStrategy{Type: DeployStrategyBlueGreen, Interval: 30 * time.Second}, ""},
+		{"blue-green", "50", "", nil, "blue-green deploy strategy does not accept steps"},
+		{"canary", "50,10", "", nil, ErrInvalidDeployStrategySteps.Error()},
+		{"canary", "0", "", nil, ErrInvalidDeployStrategySteps.Error()},
+		{"canary", "120", "", nil, ErrInvalidDeployStrategySteps.Error()},
+		{"canary", "a", "", nil, ErrInvalidDeployStrategySteps.Error()},
+		{"canary", "", "xyz", nil, `invalid deploy strategy interval: time: invalid duration "xyz"`},
+		{"rolling", "", "", nil, ErrInvalidDeployStrategy.Error()},
+	}
+	for i, tt := range tests {
+		strategy, err := ParseDeployStrategy(tt.strategyType, tt.steps, tt.interval)
+		if tt.err != "" {
+			c.Assert(err, check.ErrorMatches, tt.err, check.Commentf("test %d", i))
+			continue
+		}
+		c.Assert(err, check.IsNil, check.Commentf("test %d", i))
+		c.Assert(strategy, check.DeepEquals, tt.expected, check.Commentf("test %d", i))
+	}
+}
+
+func (s *S) TestDeployStrategySteps(c *check.C) {
+	c.Assert((&DeployStrategy{Type: DeployStrategyCanary}).steps(), check.DeepEquals, []int{10, 50, 100})
+	c.Assert((&DeployStrategy{Type: DeployStrategyCanary, Steps: []int{20}}).steps(), check.DeepEquals, []int{20, 100})
+	c.Assert((&DeployStrategy{Type: DeployStrategyCanary, Steps: []int{20, 100}}).steps(), check.DeepEquals, []int{20, 100})
+	c.Assert((&DeployStrategy{Type: DeployStrategyBlueGreen}).steps(), check.DeepEquals, []int{100})
+}
+
+func (s *S) TestCheckDeployStrategySupport(c *check.C) {
+	strategy := &DeployStrategy{Type: DeployStrategyCanary}
+	err := checkDeployStrategySupport(s.provisioner, strategy)
+	c.Assert(err, check.IsNil)
+	noStrategyProv := struct{ provision.Provisioner }{s.provisioner}
+	err = checkDeployStrategySupport(noStrategyProv, strategy)
+	c.Assert(err, check.FitsTypeOf, &tsuruErrors.ValidationError{})
+	c.Assert(err, check.ErrorMatches, `provisioner "fake" does not support the canary deploy strategy`)
+}
+
+func (s *S) newStrategyDeployEvent(c *check.C, a *App) *event.Event {
+	s.builder.OnBuild = func(p provision.BuilderDeploy, a provision.App, evt *event.Event, opts *builder.BuildOpts) (string, error) {
+		return opts.ImageID, nil
+	}
+	evt, err := event.New(&event.Opts{
+		Target:   event.Target{Type: "app", Value: a.Name},
+		Kind:     permission.PermAppDeploy,
+		RawOwner: event.Owner{Type: event.OwnerTypeUser, Name: s.user.Email},
+		Allowed:  event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	return evt
+}
+
+func (s *S) TestDeployToProvisionerWithCanaryStrategy(c *check.C) {
+	a := App{Name: "some-app", Platform: "django", TeamOwner: s.team.Name, Router: "fake"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	oldUnits, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "registry.somewhere/tsuru/app-some-app:v1")
+	c.Assert(err, check.IsNil)
+	evt := s.newStrategyDeployEvent(c, &a)
+	opts := DeployOptions{
+		App:      &a,
+		Image:    "registry.somewhere/tsuru/app-some-app:v2",
+		Strategy: &DeployStrategy{Type: DeployStrategyCanary, Steps: []int{50}},
+	}
+	imgID, err := deployToProvisioner(&opts, evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(imgID, check.Equals, "registry.somewhere/tsuru/app-some-app:v2")
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Matches, `(?s).*Starting canary deploy, previous image is registry.somewhere/tsuru/app-some-app:v1.*`)
+	c.Assert(evt.Log, check.Matches, `(?s).*Routing 50% of traffic to new units \(requested 50%, 2 new and 2 old routes\).*`)
+	c.Assert(evt.Log, check.Matches, `(?s).*Routing 100% of traffic to new units \(requested 100%, 2 new and 0 old routes\).*`)
+	units, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 2)
+	for _, u := range units {
+		c.Assert(routertest.FakeRouter.HasRoute(a.Name, u.Address.String()), check.Equals, true)
+	}
+	for _, u := range oldUnits {
+		c.Assert(routertest.FakeRouter.HasRoute(a.Name, u.Address.String()), check.Equals, false)
+	}
+	c.Assert(s.provisioner.Image(&a), check.Equals, "registry.somewhere/tsuru/app-some-app:v2")
+}
+
+func (s *S) TestDeployToProvisionerWithCanaryStrategyUnrepresentableStep(c *check.C) {
+	a := App{Name: "some-app", Platform: "django", TeamOwner: s.team.Name, Router: "fake"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 1, "web", nil)
+	c.Assert(err, check.IsNil)
+	oldUnits, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "registry.somewhere/tsuru/app-some-app:v1")
+	c.Assert(err, check.IsNil)
+	evt := s.newStrategyDeployEvent(c, &a)
+	opts := DeployOptions{
+		App:      &a,
+		Image:    "registry.somewhere/tsuru/app-some-app:v2",
+		Strategy: &DeployStrategy{Type: DeployStrategyCanary, Steps: []int{10}},
+	}
+	_, err = deployToProvisioner(&opts, evt)
+	c.Assert(err, check.ErrorMatches, `unable to route 10% of traffic to new units, closest split with 1 new and 1 old units is 50% \(1 new and 1 old routes\), use larger steps or more units`)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	units, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.DeepEquals, oldUnits)
+	c.Assert(routertest.FakeRouter.HasRoute(a.Name, oldUnits[0].Address.String()), check.Equals, true)
+	c.Assert(s.provisioner.Image(&a), check.Equals, "registry.somewhere/tsuru/app-some-app:v1")
+}
+
+func (s *S) TestTrafficShifterSplit(c *check.C) {
+	addrs := func(n int, prefix string) []*url.URL {
+		var result []*url.URL
+		for i := 0; i < n; i++ {
+			result = append(result, &url.URL{Scheme: "http", Host: fmt.Sprintf("%s%d:80", prefix, i)})
+		}
+		return result
+	}
+	tests := []struct {
+		old, new, percent         int
+		newCount, oldCount, share int
+	}{
+		{old: 1, new: 1, percent: 10, newCount: 1, oldCount: 1, share: 50},
+		{old: 2, new: 2, percent: 50, newCount: 2, oldCount: 2, share: 50},
+		{old: 9, new: 9, percent: 10, newCount: 1, oldCount: 9, share: 10},
+		{old: 4, new: 4, percent: 30, newCount: 2, oldCount: 4, share: 33},
+		{old: 3, new: 3, percent: 100, newCount: 3, oldCount: 0, share: 100},
+		{old: 0, new: 2, percent: 10, newCount: 2, oldCount: 0, share: 100},
+	}
+	for _, tt := range tests {
+		shifter := &trafficShifter{oldAddr: addrs(tt.old, "old"), newAddr: addrs(tt.new, "new")}
+		newCount, oldCount, share := shifter.split(tt.percent)
+		c.Check([]int{newCount, oldCount, share}, check.DeepEquals, []int{tt.newCount, tt.oldCount, tt.share}, check.Commentf("%+v", tt))
+	}
+}
+
+func (s *S) TestTrafficShifterPlanSkipsDefaultSteps(c *check.C) {
+	a := App{Name: "some-app", Platform: "django", TeamOwner: s.team.Name, Router: "fake"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	evt := s.newStrategyDeployEvent(c, &a)
+	shifter := &trafficShifter{
+		app:     &a,
+		oldAddr: []*url.URL{{Scheme: "http", Host: "old0:80"}},
+		newAddr: []*url.URL{{Scheme: "http", Host: "new0:80"}},
+	}
+	steps, err := shifter.plan(&DeployStrategy{Type: DeployStrategyCanary}, evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(steps, check.DeepEquals, []int{50, 100})
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Matches, `(?s).*Skipping 10% step, closest split with current units is 50%.*`)
+}
+
+func (s *S) TestDeployToProvisionerWithStrategyNoPreviousImage(c *check.C) {
+	a := App{Name: "some-app", Platform: "django", TeamOwner: s.team.Name, Router: "fake"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	evt := s.newStrategyDeployEvent(c, &a)
+	opts := DeployOptions{
+		App:      &a,
+		Image:    "myimage",
+		Strategy: &DeployStrategy{Type: DeployStrategyBlueGreen},
+	}
+	_, err = deployToProvisioner(&opts, evt)
+	c.Assert(err, check.IsNil)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Matches, `(?s).*No previous image found, ignoring blue-green deploy strategy.*Builder deploy called`)
+}
+
+func (s *S) TestDeployToProvisionerWithStrategyAbortsOnFailure(c *check.C) {
+	a := App{Name: "some-app", Platform: "django", TeamOwner: s.team.Name, Router: "fake"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	oldUnits, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "registry.somewhere/tsuru/app-some-app:v1")
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareFailure("DeployCandidate", errors.New("unable to start units"))
+	evt := s.newStrategyDeployEvent(c, &a)
+	opts := DeployOptions{
+		App:      &a,
+		Image:    "registry.somewhere/tsuru/app-some-app:v2",
+		Strategy: &DeployStrategy{Type: DeployStrategyBlueGreen},
+	}
+	_, err = deployToProvisioner(&opts, evt)
+	c.Assert(err, check.ErrorMatches, "unable to start units")
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Matches, `(?s).*ABORTING DEPLOY AFTER FAILURE.*Rolling back to image registry.somewhere/tsuru/app-some-app:v1.*`)
+	units, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.DeepEquals, oldUnits)
+	for _, u := range oldUnits {
+		c.Assert(routertest.FakeRouter.HasRoute(a.Name, u.Address.String()), check.Equals, true)
+	}
+	c.Assert(s.provisioner.Image(&a), check.Equals, "registry.somewhere/tsuru/app-some-app:v1")
+}
+
+func (s *S) TestCandidateCheckerUnhealthyUnits(c *check.C) {
+	a := App{Name: "some-app", Platform: "django", TeamOwner: s.team.Name, Router: "fake"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	units, err := s.provisioner.Units(&a)
+	c.Assert(err, check.IsNil)
+	checker := &candidateChecker{prov: s.provisioner, app: &a, units: units}
+	c.Assert(checker.check(), check.IsNil)
+	err = s.provisioner.SetUnitStatus(units[0], provision.StatusError)
+	c.Assert(err, check.IsNil)
+	err = checker.check()
+	c.Assert(err, check.ErrorMatches, `new units are not healthy: some-app-0 \(error\)`)
+	checker.units = append(checker.units, provision.Unit{ID: "gone"})
+	err = checker.check()
+	c.Assert(err, check.ErrorMatches, `new units are not healthy: some-app-0 \(error\), gone \(removed\)`)
+}
@@ -35,9 +35,10 @@ type ImageNotFoundErr struct {
 }
 
 type customData struct {
-	Hooks       *provTypes.TsuruYamlHooks       `bson:",omitempty"`
-	Healthcheck *provTypes.TsuruYamlHealthcheck `bson:",omitempty"`
-	Kubernetes  *tsuruYamlKubernetesConfig      `bson:",omitempty"`
+	Hooks       *provTypes.TsuruYamlHooks                   `bson:",omitempty"`
+	Healthcheck *provTypes.TsuruYamlHealthcheck             `bson:",omitempty"`
+	Kubernetes  *tsuruYamlKubernetesConfig                  `bson:",omitempty"`
+	Processes   map[string]provTypes.TsuruYamlProcessConfig `json:"process_config" bson:"process_config,omitempty"`
 }
 
 type tsuruYamlKubernetesConfig struct {
@@ -295,6 +296,9 @@ func marshalCustomData(data map[string]interface{}) (map[string]interface{}, err
 	}
 	result["hooks"] = yamlData.Hooks
 	result["healthcheck"] = yamlData.Healthcheck
+	if yamlData.Processes != nil {
+		result["process_config"] = yamlData.Processes
+	}
 	if yamlData.Kubernetes == nil {
 		return result, nil
 	}
@@ -343,6 +347,9 @@ func unmarshalCustomData(data map[string]interface{}) (map[string]interface{}, e
 	if yamlData.Kubernetes != nil {
 		result["kubernetes"] = yamlData.Kubernetes
 	}
+	if yamlData.Processes != nil {
+		result["process_config"] = yamlData.Processes
+	}
 	return result, nil
 }
 
@@ -363,6 +370,7 @@ func unmarshalYamlData(data map[string]interface{}) (provTypes.TsuruYamlData, er
 	result := provTypes.TsuruYamlData{
 		Hooks:       custom.Hooks,
 		Healthcheck: custom.Healthcheck,
+		Processes:   custom.Processes,
 	}
 	if custom.Kubernetes == nil {
 		return result, nil
@@ -0,0 +1,181 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"io"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+)
+
+const jobRunOwner = "job"
+
+// GetJobs returns the scheduled jobs of the app.
+func (app *App) GetJobs() []appTypes.Job {
+	return app.Jobs
+}
+
+// GetJob returns the scheduled job of the app with the given name.
+func (app *App) GetJob(name string) (*appTypes.Job, error) {
+	for i := range app.Jobs {
+		if app.Jobs[i].Name == name {
+			return &app.Jobs[i], nil
+		}
+	}
+	return nil, appTypes.ErrJobNotFound
+}
+
+// SetJob adds or replaces a scheduled job of the app. The schedule
+// expression must be validated by the caller.
+func (app *App) SetJob(job appTypes.Job) error {
+	err := job.Validate()
+	if err != nil {
+		return err
+	}
+	job.CreatedAt = time.Now().UTC()
+	job.LastRun = time.Time{}
+	jobs := make([]appTypes.Job, 0, len(app.Jobs)+1)
+	for _, j := range app.Jobs {
+		if j.Name == job.Name {
+			job.CreatedAt = j.CreatedAt
+			job.LastRun = j.LastRun
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	jobs = append(jobs, job)
+	err = app.updateJobsDB(jobs)
+	if err != nil {
+		return err
+	}
+	return app.updateProvisionerJobs()
+}
+
+// RemoveJob removes a scheduled job of the app.
+func (app *App) RemoveJob(name string) error {
+	var jobs []appTypes.Job
+	for _, j := range app.Jobs {
+		if j.Name != name {
+			jobs = append(jobs, j)
+		}
+	}
+	if len(jobs) == len(app.Jobs) {
+		return appTypes.ErrJobNotFound
+	}
+	err := app.updateJobsDB(jobs)
+	if err != nil {
+		return err
+	}
+	return app.updateProvisionerJobs()
+}
+
+// HandlesJobs returns whether the scheduled jobs of the app are run natively
+// by the app provisioner instead of the tsuru scheduler.
+func (app *App) HandlesJobs() (bool, error) {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return false, err
+	}
+	_, ok := prov.(provision.JobProvisioner)
+	return ok, nil
+}
+
+// RunJob runs the command of a job in a new unit of the app, writing its
+// output to evt and to the app logs. Canceling evt stops the command on
+// provisioners supporting it.
+func (app *App) RunJob(job appTypes.Job, evt *event.Event) error {
+	logWriter := LogWriter{AppName: app.Name, Source: appTypes.JobLogSource(job.Name)}
+	logWriter.Async()
+	defer logWriter.Close()
+	return app.runWithEvent(job.Command, io.MultiWriter(evt, &logWriter), provision.RunArgs{Isolated: true}, evt)
+}
+
+// NewJobRunEvent creates the event of a run of a job of the app, which also
+// targets the app. Locked events prevent other runs of the job from starting
+// until the event is done. Canceling the event stops the run on provisioners
+// supporting it.
+func (app *App) NewJobRunEvent(job appTypes.Job, run *appTypes.JobRun, lock bool) (*event.Event, error) {
+	return event.New(&event.Opts{
+		Target: event.Target{Type: event.TargetTypeAppJob, Value: app.Name + "/" + job.Name},
+		ExtraTargets: []event.ExtraTarget{
+			{Target: event.Target{Type: event.TargetTypeApp, Value: app.Name}},
+		},
+		Kind:        permission.PermAppRunJob,
+		RawOwner:    event.Owner{Type: event.OwnerTypeInternal, Name: jobRunOwner},
+		CustomData:  run,
+		DisableLock: !lock,
+		Cancelable:  true,
+		Allowed: event.Allowed(permission.PermAppReadEvents, append(permission.Contexts(permTypes.CtxTeam, app.Teams),
+			permission.Context(permTypes.CtxApp, app.Name),
+			permission.Context(permTypes.CtxPool, app.Pool),
+		)...),
+	})
+}
+
+// CancelJobRuns asks the running runs of a job of the app to be stopped.
+func (app *App) CancelJobRuns(job appTypes.Job, reason string) error {
+	running := true
+	evts, err := event.List(&event.Filter{
+		Target:    event.Target{Type: event.TargetTypeAppJob, Value: app.Name + "/" + job.Name},
+		KindNames: []string{permission.PermAppRunJob.FullName()},
+		Running:   &running,
+	})
+	if err != nil {
+		return err
+	}
+	for i := range evts {
+		err = evts[i].TryCancel(reason, jobRunOwner)
+		if err != nil && err != event.ErrNotCancelable {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateJobLastRun stores the time of the last run of a job of the app.
+func (app *App) UpdateJobLastRun(name string, lastRun time.Time) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Apps().Update(bson.M{"name": app.Name, "jobs.name": name}, bson.M{
+		"$set": bson.M{"jobs.$.lastrun": lastRun},
+	})
+}
+
+func (app *App) updateProvisionerJobs() error {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	if jobProv, ok := prov.(provision.JobProvisioner); ok {
+		return jobProv.UpdateJobs(app)
+	}
+	return nil
+}
+
+func (app *App) updateJobsDB(jobs []appTypes.Job) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{
+		"$set": bson.M{"jobs": jobs},
+	})
+	if err != nil {
+		return err
+	}
+	app.Jobs = jobs
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestSetJob(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetJob(appTypes.Job{Name: "cleanup", Schedule: "@hourly", Command: "./cleanup.sh"})
+	c.Assert(err, check.IsNil)
+	lastRun := time.Now().UTC().Truncate(time.Second)
+	err = a.UpdateJobLastRun("cleanup", lastRun)
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 1)
+	c.Assert(dbApp.Jobs[0].ConcurrencyPolicy, check.Equals, appTypes.JobConcurrencyAllow)
+	c.Assert(dbApp.Jobs[0].HistoryLimit, check.Equals, appTypes.DefaultJobHistoryLimit)
+	createdAt := dbApp.Jobs[0].CreatedAt
+	c.Assert(createdAt.IsZero(), check.Equals, false)
+	err = dbApp.SetJob(appTypes.Job{Name: "cleanup", Schedule: "@daily", Command: "./cleanup.sh --all"})
+	c.Assert(err, check.IsNil)
+	err = dbApp.SetJob(appTypes.Job{Name: "report", Schedule: "@weekly", Command: "./report.sh"})
+	c.Assert(err, check.IsNil)
+	dbApp, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 2)
+	job, err := dbApp.GetJob("cleanup")
+	c.Assert(err, check.IsNil)
+	c.Assert(job.Schedule, check.Equals, "@daily")
+	c.Assert(job.Command, check.Equals, "./cleanup.sh --all")
+	c.Assert(job.CreatedAt.Equal(createdAt), check.Equals, true)
+	c.Assert(job.LastRun.Equal(lastRun), check.Equals, true)
+}
+
+func (s *S) TestSetJobInvalid(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetJob(appTypes.Job{Name: "cleanup", Command: "./cleanup.sh"})
+	c.Assert(err, check.Equals, appTypes.ErrJobNoSchedule)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 0)
+}
+
+func (s *S) TestRemoveJob(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetJob(appTypes.Job{Name: "cleanup", Schedule: "@hourly", Command: "./cleanup.sh"})
+	c.Assert(err, check.IsNil)
+	err = a.RemoveJob("report")
+	c.Assert(err, check.Equals, appTypes.ErrJobNotFound)
+	err = a.RemoveJob("cleanup")
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 0)
+}
+
+func (s *S) TestHandlesJobs(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	native, err := a.HandlesJobs()
+	c.Assert(err, check.IsNil)
+	c.Assert(native, check.Equals, false)
+}
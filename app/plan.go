@@ -37,6 +37,21 @@ func (s *planService) Create(plan appTypes.Plan) error {
 	if plan.Name == "" {
 		return appTypes.PlanValidationError{Field: "name"}
 	}
+	if plan.CPURequest < 0 {
+		return appTypes.PlanValidationError{Field: "cpurequest"}
+	}
+	if plan.CPULimit < 0 {
+		return appTypes.PlanValidationError{Field: "cpulimit"}
+	}
+	if (plan.CPURequest > 0 && plan.CPURequest < minMillicores) || (plan.CPULimit > 0 && plan.CPULimit < minMillicores) {
+		return appTypes.ErrLimitOfCPU
+	}
+	if plan.CPULimit > 0 && plan.CPURequest > plan.CPULimit {
+		return appTypes.ErrPlanCPURequest
+	}
+	if plan.CpuShare == 0 {
+		plan.CpuShare = cpuShareFromMillicores(plan)
+	}
 	if plan.CpuShare < 2 {
 		return appTypes.ErrLimitOfCpuShare
 	}
@@ -46,6 +61,19 @@ func (s *planService) Create(plan appTypes.Plan) error {
 	return s.storage.Insert(plan)
 }
 
+const minMillicores = 10
+
+// cpuShareFromMillicores returns the cpu shares matching the cpu request, or
+// the limit, of plans not setting the cpu shares, in the same way kubernetes
+// converts requests to shares.
+func cpuShareFromMillicores(plan appTypes.Plan) int {
+	millicores := plan.CPURequest
+	if millicores == 0 {
+		millicores = plan.CPULimit
+	}
+	return millicores * 1024 / 1000
+}
+
 // List implements List method of PlanService interface
 func (s *planService) List() ([]appTypes.Plan, error) {
 	return s.storage.FindAll()
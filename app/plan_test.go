@@ -30,6 +30,24 @@ func (s *S) TestPlanAdd(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *S) TestPlanAddWithCPU(c *check.C) {
+	var inserted appTypes.Plan
+	ps := &planService{
+		storage: &appTypes.MockPlanStorage{
+			OnInsert: func(plan appTypes.Plan) error {
+				inserted = plan
+				return nil
+			},
+		},
+	}
+	err := ps.Create(appTypes.Plan{Name: "plan1", Memory: 4194304, CPURequest: 500, CPULimit: 1000})
+	c.Assert(err, check.IsNil)
+	c.Assert(inserted, check.Equals, appTypes.Plan{Name: "plan1", Memory: 4194304, CpuShare: 512, CPURequest: 500, CPULimit: 1000})
+	err = ps.Create(appTypes.Plan{Name: "plan2", CpuShare: 100, CPULimit: 2000})
+	c.Assert(err, check.IsNil)
+	c.Assert(inserted, check.Equals, appTypes.Plan{Name: "plan2", CpuShare: 100, CPULimit: 2000})
+}
+
 func (s *S) TestPlanAddInvalid(c *check.C) {
 	invalidPlans := []appTypes.Plan{
 		{
@@ -49,8 +67,31 @@ func (s *S) TestPlanAddInvalid(c *check.C) {
 			Swap:     1024,
 			CpuShare: 100,
 		},
+		{
+			Name:       "plan1",
+			CpuShare:   100,
+			CPURequest: -1,
+		},
+		{
+			Name:     "plan1",
+			CpuShare: 100,
+			CPULimit: 5,
+		},
+		{
+			Name:       "plan1",
+			CpuShare:   100,
+			CPURequest: 1000,
+			CPULimit:   500,
+		},
+	}
+	expectedError := []error{
+		appTypes.PlanValidationError{Field: "name"},
+		appTypes.ErrLimitOfCpuShare,
+		appTypes.ErrLimitOfMemory,
+		appTypes.PlanValidationError{Field: "cpurequest"},
+		appTypes.ErrLimitOfCPU,
+		appTypes.ErrPlanCPURequest,
 	}
-	expectedError := []error{appTypes.PlanValidationError{Field: "name"}, appTypes.ErrLimitOfCpuShare, appTypes.ErrLimitOfMemory}
 	ps := &planService{
 		storage: &appTypes.MockPlanStorage{
 			OnInsert: func(appTypes.Plan) error {
@@ -61,7 +102,7 @@ func (s *S) TestPlanAddInvalid(c *check.C) {
 	}
 	for i, p := range invalidPlans {
 		err := ps.Create(p)
-		c.Assert(err, check.FitsTypeOf, expectedError[i])
+		c.Assert(err, check.DeepEquals, expectedError[i])
 	}
 }
 
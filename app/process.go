@@ -0,0 +1,110 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/pool"
+	"github.com/tsuru/tsuru/servicemanager"
+	"github.com/tsuru/tsuru/set"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+// UpdateProcessArgs are the settings of a process changed by UpdateProcess.
+// An empty plan name means the process uses the app plan.
+type UpdateProcessArgs struct {
+	Process   string
+	Plan      string
+	Resources appTypes.ProcessResources
+	NoRestart bool
+	Writer    io.Writer
+}
+
+// UpdateProcess sets the plan and the resources of a process of the app,
+// restarting the process when its resources change. Setting an empty plan
+// and no resources makes the process use the app plan again.
+func (app *App) UpdateProcess(args UpdateProcessArgs) error {
+	processes, err := image.AllAppProcesses(app.Name)
+	if err != nil && errors.Cause(err) != image.ErrNoImagesAvailable {
+		return err
+	}
+	if len(processes) > 0 && !set.FromSlice(processes).Includes(args.Process) {
+		return provision.InvalidProcessError{Msg: fmt.Sprintf("process %q not found in app", args.Process)}
+	}
+	process := appTypes.Process{Name: args.Process, Resources: args.Resources}
+	if args.Plan != "" {
+		plan, err := servicemanager.Plan.FindByName(args.Plan)
+		if err != nil {
+			return err
+		}
+		err = app.validateProcessPlan(plan.Name)
+		if err != nil {
+			return err
+		}
+		process.Plan = *plan
+	}
+	oldResources := app.GetProcessResources(args.Process)
+	newProcesses := make([]appTypes.Process, 0, len(app.Processes)+1)
+	for _, p := range app.Processes {
+		if p.Name != args.Process {
+			newProcesses = append(newProcesses, p)
+		}
+	}
+	if process.Plan.Name != "" || process.Resources != (appTypes.ProcessResources{}) {
+		newProcesses = append(newProcesses, process)
+	}
+	oldProcesses := app.Processes
+	app.Processes = newProcesses
+	newResources := app.GetProcessResources(args.Process)
+	err = newResources.Validate()
+	if err != nil {
+		app.Processes = oldProcesses
+		return err
+	}
+	err = app.updateProcessesDB(newProcesses)
+	if err != nil {
+		app.Processes = oldProcesses
+		return err
+	}
+	if args.NoRestart || newResources == oldResources {
+		return nil
+	}
+	return app.Restart(args.Process, args.Writer)
+}
+
+func (app *App) validateProcessPlan(planName string) error {
+	pool, err := pool.GetPoolByName(app.Pool)
+	if err != nil {
+		return err
+	}
+	plans, err := pool.GetPlans()
+	if err != nil {
+		return err
+	}
+	if !set.FromSlice(plans).Includes(planName) {
+		msg := fmt.Sprintf("Process plan %q is not allowed on pool %q", planName, pool.Name)
+		return &tsuruErrors.ValidationError{Message: msg}
+	}
+	return nil
+}
+
+func (app *App) updateProcessesDB(processes []appTypes.Process) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Apps().Update(bson.M{"name": app.Name}, bson.M{
+		"$set": bson.M{"processes": processes},
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/pool"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestGetProcessResources(c *check.C) {
+	a := App{
+		Name: "myapp",
+		Plan: appTypes.Plan{Name: "default", Memory: 512, Swap: 128, CpuShare: 100},
+		Processes: []appTypes.Process{
+			{Name: "worker", Plan: appTypes.Plan{Name: "small", Memory: 64, CpuShare: 10}},
+			{Name: "web", Resources: appTypes.ProcessResources{Memory: 1024, CPULimit: 2000}},
+			{Name: "cron", Plan: appTypes.Plan{Name: "small", Memory: 64}, Resources: appTypes.ProcessResources{MemoryRequest: 32}},
+		},
+	}
+	c.Assert(a.GetProcessResources("other"), check.DeepEquals, appTypes.ProcessResources{Memory: 512, Swap: 128, CpuShare: 100})
+	c.Assert(a.GetProcessResources("worker"), check.DeepEquals, appTypes.ProcessResources{Memory: 64, CpuShare: 10})
+	c.Assert(a.GetProcessResources("web"), check.DeepEquals, appTypes.ProcessResources{Memory: 1024, Swap: 128, CpuShare: 100, CPULimit: 2000})
+	c.Assert(a.GetProcessResources("cron"), check.DeepEquals, appTypes.ProcessResources{Memory: 64, MemoryRequest: 32})
+}
+
+func (s *S) TestUpdateProcess(c *check.C) {
+	plan := appTypes.Plan{Name: "small", Memory: 67108864, CpuShare: 10}
+	s.mockService.Plan.OnFindByName = func(name string) (*appTypes.Plan, error) {
+		c.Assert(name, check.Equals, plan.Name)
+		return &plan, nil
+	}
+	s.mockService.Plan.OnList = func() ([]appTypes.Plan, error) {
+		return []appTypes.Plan{s.defaultPlan, plan}, nil
+	}
+	a := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.saveAppProcesses(c, a.Name, "web", "worker")
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "worker", Plan: "small", Writer: new(bytes.Buffer)})
+	c.Assert(err, check.IsNil)
+	err = a.UpdateProcess(UpdateProcessArgs{
+		Process:   "web",
+		Resources: appTypes.ProcessResources{MemoryRequest: 1024},
+		NoRestart: true,
+	})
+	c.Assert(err, check.IsNil)
+	expected := []appTypes.Process{
+		{Name: "worker", Plan: plan},
+		{Name: "web", Resources: appTypes.ProcessResources{MemoryRequest: 1024}},
+	}
+	c.Assert(a.Processes, check.DeepEquals, expected)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Processes, check.DeepEquals, expected)
+	c.Assert(dbApp.GetProcessResources("worker"), check.DeepEquals, plan.Resources())
+	c.Assert(s.provisioner.Restarts(dbApp, "worker"), check.Equals, 1)
+	c.Assert(s.provisioner.Restarts(dbApp, "web"), check.Equals, 0)
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "worker", Writer: new(bytes.Buffer)})
+	c.Assert(err, check.IsNil)
+	dbApp, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Processes, check.DeepEquals, expected[1:])
+	c.Assert(s.provisioner.Restarts(dbApp, "worker"), check.Equals, 2)
+}
+
+func (s *S) TestUpdateProcessInvalid(c *check.C) {
+	plan := appTypes.Plan{Name: "small", Memory: 67108864}
+	s.mockService.Plan.OnFindByName = func(name string) (*appTypes.Plan, error) {
+		if name != plan.Name {
+			return nil, appTypes.ErrPlanNotFound
+		}
+		return &plan, nil
+	}
+	s.mockService.Plan.OnList = func() ([]appTypes.Plan, error) {
+		return []appTypes.Plan{s.defaultPlan, plan}, nil
+	}
+	a := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.saveAppProcesses(c, a.Name, "web", "worker")
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "other", Plan: "small"})
+	c.Assert(err, check.FitsTypeOf, provision.InvalidProcessError{})
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "web", Plan: "huge"})
+	c.Assert(err, check.Equals, appTypes.ErrPlanNotFound)
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "web", Resources: appTypes.ProcessResources{Memory: -1}})
+	c.Assert(err, check.Equals, appTypes.ErrProcessNegativeResources)
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "web", Plan: "small", Resources: appTypes.ProcessResources{MemoryRequest: 67108865}})
+	c.Assert(err, check.Equals, appTypes.ErrProcessMemoryRequest)
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "web", Resources: appTypes.ProcessResources{CPURequest: 2000, CPULimit: 1000}})
+	c.Assert(err, check.Equals, appTypes.ErrProcessCPURequest)
+	err = pool.SetPoolConstraint(&pool.PoolConstraint{
+		PoolExpr:  "pool1",
+		Field:     pool.ConstraintTypePlan,
+		Values:    []string{plan.Name},
+		Blacklist: true,
+	})
+	c.Assert(err, check.IsNil)
+	err = a.UpdateProcess(UpdateProcessArgs{Process: "web", Plan: "small"})
+	c.Assert(err, check.ErrorMatches, `Process plan "small" is not allowed on pool "pool1"`)
+	c.Assert(a.Processes, check.HasLen, 0)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Processes, check.HasLen, 0)
+}
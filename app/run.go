@@ -0,0 +1,190 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+var (
+	runOutputLines     = 1000
+	runLogFlushTimeout = time.Minute
+)
+
+type runResult struct {
+	ExitCode *int
+}
+
+// RunDetached runs cmd in a new unit of the app in background. The run is
+// recorded in evt, which is done with the exit code of the command when it
+// finishes. The output of the command is only sent to the app log, as it may
+// not fit in the event. Canceling evt stops the command on provisioners
+// supporting it.
+func (app *App) RunDetached(cmd string, evt *event.Event) {
+	go func() {
+		fmt.Fprintf(evt, "running '%s'\n", cmd)
+		logWriter := LogWriter{AppName: app.Name, Source: appTypes.RunLogSource(evt.UniqueID.Hex())}
+		logWriter.Async()
+		err := app.runWithEvent(cmd, &logWriter, provision.RunArgs{Isolated: true}, evt)
+		logWriter.Close()
+		if waitErr := logWriter.Wait(runLogFlushTimeout); waitErr != nil {
+			log.Errorf("[run] unable to flush output of %q in app %q: %v", cmd, app.Name, waitErr)
+		}
+		var result runResult
+		if err == nil {
+			exitCode := 0
+			result.ExitCode = &exitCode
+		} else if exitErr, ok := err.(*provision.ExecExitError); ok {
+			result.ExitCode = &exitErr.Code
+		}
+		if doneErr := evt.DoneCustomData(err, result); doneErr != nil {
+			log.Errorf("[run] unable to finish run of %q in app %q: %v", cmd, app.Name, doneErr)
+		}
+	}()
+}
+
+// ListRuns returns the detached runs of the app, most recent first.
+func (app *App) ListRuns() ([]appTypes.AppRun, error) {
+	evts, err := event.List(&event.Filter{
+		Target:    event.Target{Type: event.TargetTypeApp, Value: app.Name},
+		KindNames: []string{permission.PermAppRun.FullName()},
+		Raw:       bson.M{"cancelable": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]appTypes.AppRun, 0, len(evts))
+	for i := range evts {
+		runs = append(runs, runFromEvent(&evts[i]))
+	}
+	return runs, nil
+}
+
+// GetRun returns a detached run of the app, including the last lines of its
+// output, read from the app log, if it has finished.
+func (app *App) GetRun(id string) (*appTypes.AppRun, error) {
+	evt, err := app.getRunEvent(id)
+	if err != nil {
+		return nil, err
+	}
+	run := runFromEvent(evt)
+	if run.Running {
+		return &run, nil
+	}
+	logs, err := servicemanager.AppLog.List(appTypes.ListLogArgs{
+		AppName: app.Name,
+		Source:  appTypes.RunLogSource(run.ID),
+		Limit:   runOutputLines,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(logs))
+	for i := range logs {
+		lines[i] = logs[i].Message
+	}
+	run.Output = strings.Join(lines, "\n")
+	return &run, nil
+}
+
+// CancelRun asks for a running detached run of the app to be stopped. It
+// fails if the provisioner of the app is unable to stop commands.
+func (app *App) CancelRun(id, reason, owner string) error {
+	evt, err := app.getRunEvent(id)
+	if err != nil {
+		return err
+	}
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	cancelProv, ok := prov.(provision.ExecCancelProvisioner)
+	if !ok || !cancelProv.HandlesExecCancel() {
+		return appTypes.ErrRunCancelNotSupported
+	}
+	return evt.TryCancel(reason, owner)
+}
+
+// FailStaleRuns finishes, with an error, the detached runs that are still
+// running in the current tsuru API instance. It must be called on startup,
+// before any run is started, as runs are interrupted when the instance that
+// started them stops. Runs of other instances that stopped are expired by
+// the event package.
+func FailStaleRuns() error {
+	instance, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return err
+	}
+	running := true
+	evts, err := event.List(&event.Filter{
+		KindNames: []string{permission.PermAppRun.FullName()},
+		Running:   &running,
+		Raw:       bson.M{"cancelable": true, "instance.name": instance.Name},
+	})
+	if err != nil {
+		return err
+	}
+	for i := range evts {
+		err = evts[i].Done(errors.New("run interrupted by a restart of the tsuru api"))
+		if err != nil {
+			log.Errorf("[run] unable to finish stale run %s: %v", evts[i].UniqueID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+func (app *App) getRunEvent(id string) (*event.Event, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, appTypes.ErrRunNotFound
+	}
+	evt, err := event.GetByID(bson.ObjectIdHex(id))
+	if err != nil {
+		if err == event.ErrEventNotFound {
+			return nil, appTypes.ErrRunNotFound
+		}
+		return nil, err
+	}
+	isRun := evt.Kind.Name == permission.PermAppRun.FullName() && evt.Cancelable
+	if !isRun || evt.Target != (event.Target{Type: event.TargetTypeApp, Value: app.Name}) {
+		return nil, appTypes.ErrRunNotFound
+	}
+	return evt, nil
+}
+
+func runFromEvent(evt *event.Event) appTypes.AppRun {
+	run := appTypes.AppRun{
+		ID:        evt.UniqueID.Hex(),
+		Owner:     evt.Owner.Name,
+		Running:   evt.Running,
+		Canceled:  evt.CancelInfo.Canceled,
+		StartTime: evt.StartTime,
+		EndTime:   evt.EndTime,
+		Error:     evt.Error,
+	}
+	var form []map[string]interface{}
+	if evt.StartData(&form) == nil {
+		for _, field := range form {
+			if field["name"] == "command" {
+				run.Command, _ = field["value"].(string)
+			}
+		}
+	}
+	var result runResult
+	if evt.EndData(&result) == nil {
+		run.ExitCode = result.ExitCode
+	}
+	return run
+}
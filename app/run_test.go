@@ -0,0 +1,164 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) newRunEvent(c *check.C, a *App, command string) *event.Event {
+	evt, err := event.New(&event.Opts{
+		Target:        event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:          permission.PermAppRun,
+		RawOwner:      event.Owner{Type: event.OwnerTypeUser, Name: s.user.Email},
+		CustomData:    []map[string]interface{}{{"name": "command", "value": command}},
+		Allowed:       event.Allowed(permission.PermAppReadEvents),
+		AllowedCancel: event.Allowed(permission.PermAppRun),
+		Cancelable:    true,
+		DisableLock:   true,
+	})
+	c.Assert(err, check.IsNil)
+	return evt
+}
+
+func (s *S) waitRun(c *check.C, a *App, id string) *appTypes.AppRun {
+	timeout := time.After(5 * time.Second)
+	for {
+		run, err := a.GetRun(id)
+		c.Assert(err, check.IsNil)
+		if !run.Running {
+			return run
+		}
+		select {
+		case <-timeout:
+			c.Fatalf("timeout waiting for run %s to finish", id)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (s *S) TestRunDetached(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareOutput([]byte("migrated"))
+	evt := s.newRunEvent(c, &a, "./migrate")
+	a.RunDetached("./migrate", evt)
+	run := s.waitRun(c, &a, evt.UniqueID.Hex())
+	c.Assert(run.Command, check.Equals, "./migrate")
+	c.Assert(run.Error, check.Equals, "")
+	c.Assert(run.Output, check.Equals, "migrated")
+	c.Assert(*run.ExitCode, check.Equals, 0)
+	dbEvt, err := event.GetByID(evt.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbEvt.Log, check.Equals, "running './migrate'\n")
+	execs := s.provisioner.Execs("isolated")
+	c.Assert(execs, check.HasLen, 1)
+	c.Assert(execs[0].Event, check.Equals, evt)
+	runs, err := a.ListRuns()
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 1)
+	c.Assert(runs[0].ID, check.Equals, evt.UniqueID.Hex())
+}
+
+func (s *S) TestRunDetachedExitCode(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareFailure("ExecuteCommand", &provision.ExecExitError{Code: 2, Err: errors.New("command failed")})
+	evt := s.newRunEvent(c, &a, "./migrate")
+	a.RunDetached("./migrate", evt)
+	run := s.waitRun(c, &a, evt.UniqueID.Hex())
+	c.Assert(run.Error, check.Equals, "command failed")
+	c.Assert(*run.ExitCode, check.Equals, 2)
+}
+
+func (s *S) TestGetRunNotFound(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	_, err = a.GetRun("invalid")
+	c.Assert(err, check.Equals, appTypes.ErrRunNotFound)
+	other := App{Name: "other-app", TeamOwner: s.team.Name}
+	err = CreateApp(&other, s.user)
+	c.Assert(err, check.IsNil)
+	evt := s.newRunEvent(c, &other, "ls")
+	_, err = a.GetRun(evt.UniqueID.Hex())
+	c.Assert(err, check.Equals, appTypes.ErrRunNotFound)
+	err = a.CancelRun(evt.UniqueID.Hex(), "reason", "me")
+	c.Assert(err, check.Equals, appTypes.ErrRunNotFound)
+}
+
+func (s *S) TestGetRunOutputLimit(c *check.C) {
+	oldLines := runOutputLines
+	runOutputLines = 2
+	defer func() { runOutputLines = oldLines }()
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareOutput([]byte("line1\nline2\nline3\n"))
+	evt := s.newRunEvent(c, &a, "./migrate")
+	a.RunDetached("./migrate", evt)
+	run := s.waitRun(c, &a, evt.UniqueID.Hex())
+	c.Assert(run.Output, check.Equals, "line2\nline3")
+}
+
+type noExecCancelFakeProvisioner struct {
+	provisiontest.FakeProvisioner
+}
+
+func (p *noExecCancelFakeProvisioner) HandlesExecCancel() bool {
+	return false
+}
+
+func (s *S) TestCancelRunNotSupported(c *check.C) {
+	provision.Register("no-exec-cancel", func() (provision.Provisioner, error) {
+		return &noExecCancelFakeProvisioner{}, nil
+	})
+	defer provision.Unregister("no-exec-cancel")
+	oldProvisioner := provision.DefaultProvisioner
+	defer func() { provision.DefaultProvisioner = oldProvisioner }()
+	provision.DefaultProvisioner = "no-exec-cancel"
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	evt := s.newRunEvent(c, &a, "./migrate")
+	err = a.CancelRun(evt.UniqueID.Hex(), "reason", "me")
+	c.Assert(err, check.Equals, appTypes.ErrRunCancelNotSupported)
+	dbEvt, err := event.GetByID(evt.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbEvt.CancelInfo.Asked, check.Equals, false)
+}
+
+func (s *S) TestFailStaleRuns(c *check.C) {
+	a := App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	evt := s.newRunEvent(c, &a, "./migrate")
+	other, err := event.New(&event.Opts{
+		Target:   event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:     permission.PermAppUpdate,
+		RawOwner: event.Owner{Type: event.OwnerTypeUser, Name: s.user.Email},
+		Allowed:  event.Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	err = FailStaleRuns()
+	c.Assert(err, check.IsNil)
+	run, err := a.GetRun(evt.UniqueID.Hex())
+	c.Assert(err, check.IsNil)
+	c.Assert(run.Running, check.Equals, false)
+	c.Assert(run.Error, check.Equals, "run interrupted by a restart of the tsuru api")
+	dbEvt, err := event.GetByID(other.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbEvt.Running, check.Equals, true)
+}
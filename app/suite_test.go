@@ -113,6 +113,7 @@ func (s *S) SetUpSuite(c *check.C) {
 	config.Set("queue:mongo-polling-interval", 0.01)
 	config.Set("docker:registry", "registry.somewhere")
 	config.Set("routers:fake-tls:type", "fake-tls")
+	config.Set("routers:fake-weights:type", "fake-weights")
 	config.Set("auth:hash-cost", bcrypt.MinCost)
 	s.conn, err = db.Conn()
 	c.Assert(err, check.IsNil)
@@ -141,12 +142,14 @@ func (s *S) SetUpTest(c *check.C) {
 	routertest.FakeRouter.Reset()
 	routertest.HCRouter.Reset()
 	routertest.TLSRouter.Reset()
+	routertest.WeightsRouter.Reset()
 	routertest.OptsRouter.Reset()
 	queue.ResetQueue()
 	rebuild.Shutdown(context.Background())
 	routertest.FakeRouter.Reset()
 	routertest.HCRouter.Reset()
 	routertest.TLSRouter.Reset()
+	routertest.WeightsRouter.Reset()
 	routertest.OptsRouter.Reset()
 	pool.ResetCache()
 	err := rebuild.Initialize(func(appName string) (rebuild.RebuildApp, error) {
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tsuru/tsuru/log"
@@ -51,7 +52,24 @@ func (s *aggregatorLogService) Add(appName, message, source, unit string) error
 	return s.base.Add(appName, message, source, unit)
 }
 
+// Prune prunes the logs kept in memory by the current instance, every
+// instance is expected to prune its own logs.
+func (s *aggregatorLogService) Prune(appName string, retention appTypes.LogRetention) error {
+	if pruner, ok := s.base.(appTypes.AppLogPruner); ok {
+		return pruner.Prune(appName, retention)
+	}
+	return nil
+}
+
+func (s *aggregatorLogService) PrunesLocalLogs() bool {
+	return true
+}
+
 func (s *aggregatorLogService) List(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+	return listPage(args, s.list)
+}
+
+func (s *aggregatorLogService) list(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
 	requests, err := buildInstanceRequests(args, false)
 	if err != nil {
 		return nil, errors.Wrapf(err, "[aggregator service]")
@@ -221,6 +239,21 @@ func buildInstanceRequests(args appTypes.ListLogArgs, follow bool) ([]*http.Requ
 		urlValues.Add("source", args.Source)
 		urlValues.Add("unit", args.Unit)
 		urlValues.Add("invert-filters", strconv.FormatBool(args.InvertFilters))
+		if !args.Since.IsZero() {
+			urlValues.Add("since", args.Since.Format(time.RFC3339Nano))
+		}
+		if !args.Until.IsZero() {
+			urlValues.Add("until", args.Until.Format(time.RFC3339Nano))
+		}
+		if args.Message != "" {
+			urlValues.Add("message", args.Message)
+		}
+		if args.Level != "" {
+			urlValues.Add("level", args.Level)
+		}
+		for k, v := range args.Fields {
+			urlValues.Add("field", k+"="+v)
+		}
 		if follow {
 			urlValues.Add("follow", "1")
 		}
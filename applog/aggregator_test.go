@@ -117,6 +117,36 @@ func (s *S) Test_Aggregator_ListFilter(c *check.C) {
 	})
 }
 
+func (s *S) Test_Aggregator_ListTimeRangeAndCursor(c *check.C) {
+	cursorDate := time.Date(2019, 5, 10, 14, 30, 0, 0, time.UTC)
+	rollback := mockServers(2, func(i int, w http.ResponseWriter, r *http.Request) bool {
+		c.Assert(r.URL.Query().Get("lines"), check.Equals, "4")
+		c.Assert(r.URL.Query().Get("since"), check.Equals, "2019-05-10T14:00:00Z")
+		c.Assert(r.URL.Query().Get("until"), check.Equals, "2019-05-10T14:30:00.000000001Z")
+		c.Assert(r.URL.Query().Get("message"), check.Equals, "OOM")
+		c.Assert(r.URL.Query().Get("cursor"), check.Equals, "")
+		json.NewEncoder(w).Encode([]appTypes.Applog{
+			{Message: fmt.Sprintf("msg%d-1", i), Date: cursorDate.Add(-time.Duration(i+1) * time.Minute)},
+			{Message: fmt.Sprintf("msg%d-2", i), Date: cursorDate},
+		})
+		return true
+	})
+	defer rollback()
+	svc := &aggregatorLogService{}
+	logs, err := svc.List(appTypes.ListLogArgs{
+		AppName: "myapp",
+		Since:   time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC),
+		Message: "OOM",
+		Limit:   2,
+		Cursor:  &appTypes.LogCursor{Date: cursorDate, Skip: 2},
+	})
+	c.Assert(err, check.IsNil)
+	compareLogsNoDate(c, logs, []appTypes.Applog{
+		{Message: "msg1-1"},
+		{Message: "msg0-1"},
+	})
+}
+
 func (s *S) Test_Aggregator_ListReorderMessages(c *check.C) {
 	rollback := mockServers(6, func(i int, w http.ResponseWriter, r *http.Request) bool {
 		switch i {
@@ -102,6 +102,7 @@ type logDispatcher struct {
 	shuttingDown   int32
 	doneProcessing chan struct{}
 	storage        appTypes.AppLogStorage
+	parseMessages  bool
 }
 
 type msgWithTS struct {
@@ -115,6 +116,7 @@ func newlogDispatcher(chanSize int, storage appTypes.AppLogStorage) *logDispatch
 		msgCh:          make(chan *msgWithTS, chanSize),
 		doneProcessing: make(chan struct{}),
 		storage:        storage,
+		parseMessages:  structuredParsingEnabled(),
 	}
 	go d.runWriter()
 	logsQueueSize.Set(float64(chanSize))
@@ -156,6 +158,9 @@ func (d *logDispatcher) send(msg *appTypes.Applog) error {
 	if atomic.LoadInt32(&d.shuttingDown) == 1 {
 		return errors.New("log dispatcher is shutting down")
 	}
+	if d.parseMessages {
+		parseStructuredLog(msg)
+	}
 	logsInQueue.Inc()
 	logsEnqueued.WithLabelValues(msg.AppName).Inc()
 	msgExtra := &msgWithTS{msg: msg, arriveTime: time.Now()}
@@ -0,0 +1,422 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/net"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	defaultElasticsearchIndexPrefix = "tsuru-logs"
+	elasticsearchMaxResults         = 10000
+	elasticsearchMaxRawMessage      = 8191
+
+	// elasticsearchMaxScanned is the maximum number of entries matching the
+	// other filters that are searched for the message filter.
+	elasticsearchMaxScanned = 100000
+)
+
+var (
+	elasticsearchPageSize = elasticsearchMaxResults
+
+	// elasticsearchWatchOverlap is how long before the previous poll watchers
+	// search for entries again. It allows entries only visible after an index
+	// refresh, or inserted by tsuru api instances with clocks slightly behind,
+	// to still be sent. Entries already sent are skipped by document ID.
+	elasticsearchWatchOverlap = 30 * time.Second
+)
+
+// elasticsearchLogStorage stores logs using the bulk and search APIs of
+// Elasticsearch, or any server compatible with them. Entries are written to
+// daily indexes named <index-prefix>-YYYY.MM.DD, allowing old logs to be
+// removed by simply dropping old indexes.
+type elasticsearchLogStorage struct {
+	url          string
+	indexPrefix  string
+	pollInterval time.Duration
+	client       *http.Client
+	templateOnce sync.Once
+	lastSeq      int64
+}
+
+var _ appTypes.AppLogStorage = &elasticsearchLogStorage{}
+
+type elasticsearchDoc struct {
+	Date    time.Time         `json:"date"`
+	Seq     int64             `json:"seq"`
+	Message string            `json:"message"`
+	Source  string            `json:"source"`
+	App     string            `json:"app"`
+	Unit    string            `json:"unit"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (d *elasticsearchDoc) toApplog() appTypes.Applog {
+	return appTypes.Applog{
+		Date:    d.Date,
+		Message: d.Message,
+		Source:  d.Source,
+		AppName: d.App,
+		Unit:    d.Unit,
+		Fields:  d.Fields,
+	}
+}
+
+func newElasticsearchLogStorage() (appTypes.AppLogStorage, error) {
+	prefix := "log:elasticsearch"
+	address, err := config.GetString(prefix + ":url")
+	if err != nil {
+		return nil, errors.Errorf("config key %s:url not found", prefix)
+	}
+	indexPrefix, _ := config.GetString(prefix + ":index-prefix")
+	if indexPrefix == "" {
+		indexPrefix = defaultElasticsearchIndexPrefix
+	}
+	pollInterval, _ := config.GetFloat(prefix + ":poll-interval")
+	return &elasticsearchLogStorage{
+		url:          strings.TrimRight(address, "/"),
+		indexPrefix:  indexPrefix,
+		pollInterval: time.Duration(pollInterval * float64(time.Second)),
+		client:       net.Dial15Full60ClientNoKeepAlive,
+	}, nil
+}
+
+// nextSeq returns an always increasing number based on the current time,
+// used to keep the insertion order of entries with the same date.
+func (s *elasticsearchLogStorage) nextSeq() int64 {
+	for {
+		last := atomic.LoadInt64(&s.lastSeq)
+		next := time.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&s.lastSeq, last, next) {
+			return next
+		}
+	}
+}
+
+func (s *elasticsearchLogStorage) do(method, path string, body []byte, contentType string, result interface{}) error {
+	req, err := http.NewRequest(method, s.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	data, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("invalid status code %d from elasticsearch: %s", rsp.StatusCode, string(data))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// ensureTemplate creates an index template so that the fields used as
+// filters are not analyzed. Dates keep nanosecond precision, as the
+// pagination cursors are bounded by the date of the last returned entry.
+func (s *elasticsearchLogStorage) ensureTemplate() {
+	template := map[string]interface{}{
+		"index_patterns": []string{s.indexPrefix + "-*"},
+		"mappings": map[string]interface{}{
+			"dynamic_templates": []map[string]interface{}{
+				{"fields": map[string]interface{}{
+					"path_match": "fields.*",
+					"mapping":    map[string]string{"type": "keyword"},
+				}},
+			},
+			"properties": map[string]interface{}{
+				"date": map[string]string{"type": "date_nanos"},
+				"seq":  map[string]string{"type": "long"},
+				"message": map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"raw": map[string]interface{}{"type": "keyword", "ignore_above": elasticsearchMaxRawMessage},
+					},
+				},
+				"source": map[string]string{"type": "keyword"},
+				"app":    map[string]string{"type": "keyword"},
+				"unit":   map[string]string{"type": "keyword"},
+			},
+		},
+	}
+	data, _ := json.Marshal(template)
+	err := s.do(http.MethodPut, "/_template/"+s.indexPrefix, data, "application/json", nil)
+	if err != nil {
+		log.Errorf("[log elasticsearch] unable to create index template: %v", err)
+	}
+}
+
+func (s *elasticsearchLogStorage) InsertApp(appName string, msgs ...*appTypes.Applog) error {
+	s.templateOnce.Do(s.ensureTemplate)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	now := time.Now().UTC()
+	for _, msg := range msgs {
+		date := msg.Date
+		if date.IsZero() {
+			date = now
+		}
+		index := fmt.Sprintf("%s-%s", s.indexPrefix, date.UTC().Format("2006.01.02"))
+		err := encoder.Encode(map[string]interface{}{"index": map[string]string{"_index": index}})
+		if err != nil {
+			return err
+		}
+		err = encoder.Encode(elasticsearchDoc{
+			Date:    date,
+			Seq:     s.nextSeq(),
+			Message: msg.Message,
+			Source:  msg.Source,
+			App:     appName,
+			Unit:    msg.Unit,
+			Fields:  msg.Fields,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var result struct {
+		Errors bool
+		Items  []map[string]struct {
+			Error json.RawMessage
+		}
+	}
+	err := s.do(http.MethodPost, "/_bulk", buf.Bytes(), "application/x-ndjson", &result)
+	if err != nil {
+		log.Errorf("[log insert] unable to insert logs: %s", err)
+		return err
+	}
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, op := range item {
+				if len(op.Error) > 0 {
+					return errors.Errorf("unable to insert logs: %s", string(op.Error))
+				}
+			}
+		}
+		return errors.New("unable to insert logs")
+	}
+	return nil
+}
+
+type elasticsearchQuery map[string]interface{}
+
+// elasticsearchHit is a search result. Sort values are kept raw to be used
+// in search_after without losing the precision of longs.
+type elasticsearchHit struct {
+	ID     string            `json:"_id"`
+	Source elasticsearchDoc  `json:"_source"`
+	Sort   []json.RawMessage `json:"sort"`
+}
+
+func termQuery(field, value string) elasticsearchQuery {
+	return elasticsearchQuery{"term": map[string]string{field: value}}
+}
+
+func (s *elasticsearchLogStorage) search(query map[string]interface{}) ([]elasticsearchHit, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Hits struct {
+			Hits []elasticsearchHit
+		}
+	}
+	path := fmt.Sprintf("/%s-*/_search?ignore_unavailable=true&allow_no_indices=true", s.indexPrefix)
+	err = s.do(http.MethodPost, path, data, "application/json", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Hits.Hits, nil
+}
+
+func (s *elasticsearchLogStorage) filters(args appTypes.ListLogArgs) (filter, mustNot []elasticsearchQuery) {
+	filter = []elasticsearchQuery{termQuery("app", args.AppName)}
+	for _, f := range []struct{ field, value string }{{"source", args.Source}, {"unit", args.Unit}} {
+		if f.value == "" {
+			continue
+		}
+		if args.InvertFilters {
+			mustNot = append(mustNot, termQuery(f.field, f.value))
+		} else {
+			filter = append(filter, termQuery(f.field, f.value))
+		}
+	}
+	if !args.Since.IsZero() || !args.Until.IsZero() {
+		dateRange := map[string]string{}
+		if !args.Since.IsZero() {
+			dateRange["gte"] = args.Since.UTC().Format(time.RFC3339Nano)
+		}
+		if !args.Until.IsZero() {
+			dateRange["lt"] = args.Until.UTC().Format(time.RFC3339Nano)
+		}
+		filter = append(filter, elasticsearchQuery{"range": map[string]interface{}{"date": dateRange}})
+	}
+	for k, v := range args.FieldsFilter() {
+		filter = append(filter, termQuery("fields."+k, v))
+	}
+	return filter, mustNot
+}
+
+func (s *elasticsearchLogStorage) List(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+	if args.AppName == "" {
+		return nil, errors.New("unable to list logs with empty app name")
+	}
+	size := args.Limit
+	if size <= 0 || size > elasticsearchMaxResults {
+		size = elasticsearchMaxResults
+	}
+	// The message filter is matched by tsuru, using the same regular
+	// expression syntax as the other log services, as Elasticsearch regexp
+	// queries have a different syntax and only work on short keywords.
+	var message *regexp.Regexp
+	if args.Message != "" {
+		var err error
+		message, err = regexp.Compile(args.Message)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid message filter")
+		}
+	}
+	filter, mustNot := s.filters(args)
+	query := map[string]interface{}{
+		"size": size,
+		"sort": []map[string]string{{"date": "desc"}, {"seq": "desc"}},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filter, "must_not": mustNot},
+		},
+	}
+	if message != nil {
+		query["size"] = elasticsearchPageSize
+	}
+	var docs []elasticsearchDoc
+	for scanned := 0; ; {
+		hits, err := s.search(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			if message == nil || message.MatchString(hit.Source.Message) {
+				docs = append(docs, hit.Source)
+			}
+		}
+		scanned += len(hits)
+		if message == nil || len(docs) >= size || len(hits) < elasticsearchPageSize || scanned >= elasticsearchMaxScanned {
+			break
+		}
+		query["search_after"] = hits[len(hits)-1].Sort
+	}
+	if len(docs) > size {
+		docs = docs[:size]
+	}
+	logs := make([]appTypes.Applog, len(docs))
+	for i := range docs {
+		logs[len(docs)-1-i] = docs[i].toApplog()
+	}
+	return logs, nil
+}
+
+// Prune removes the entries of the app older than the retention max age.
+// The max size is not enforced, as sizes are only available per index, and
+// should be controlled by removing old indexes.
+func (s *elasticsearchLogStorage) Prune(appName string, retention appTypes.LogRetention) error {
+	cutoff := retention.Cutoff(time.Now())
+	if cutoff.IsZero() {
+		return nil
+	}
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": []elasticsearchQuery{
+				termQuery("app", appName),
+				{"range": map[string]interface{}{"date": map[string]string{"lt": cutoff.UTC().Format(time.RFC3339Nano)}}},
+			}},
+		},
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/%s-*/_delete_by_query?ignore_unavailable=true&allow_no_indices=true&conflicts=proceed", s.indexPrefix)
+	return s.do(http.MethodPost, path, data, "application/json", nil)
+}
+
+// Watch polls for entries dated after the start of the watch. Each poll
+// searches from elasticsearchWatchOverlap before the previous one, as the
+// date of the entries is set by the instance inserting them and they may only
+// become searchable after a while.
+func (s *elasticsearchLogStorage) Watch(appName, source, unit string) (appTypes.LogWatcher, error) {
+	filter, _ := s.filters(appTypes.ListLogArgs{AppName: appName, Source: source, Unit: unit})
+	start := time.Now().UTC()
+	lastPoll := start
+	sent := map[string]time.Time{}
+	return newPollWatcher(s.pollInterval, func() ([]appTypes.Applog, error) {
+		now := time.Now().UTC()
+		since := lastPoll.Add(-elasticsearchWatchOverlap)
+		if since.Before(start) {
+			since = start
+		}
+		query := map[string]interface{}{
+			"size": elasticsearchPageSize,
+			"sort": []map[string]string{{"date": "asc"}, {"seq": "asc"}},
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": append(filter, elasticsearchQuery{
+						"range": map[string]interface{}{"date": map[string]string{"gte": since.Format(time.RFC3339Nano)}},
+					}),
+				},
+			},
+		}
+		var found []elasticsearchHit
+		for {
+			hits, err := s.search(query)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, hits...)
+			if len(hits) < elasticsearchPageSize {
+				break
+			}
+			query["search_after"] = hits[len(hits)-1].Sort
+		}
+		var logs []appTypes.Applog
+		for i := range found {
+			if _, ok := sent[found[i].ID]; ok {
+				continue
+			}
+			sent[found[i].ID] = found[i].Source.Date
+			logs = append(logs, found[i].Source.toApplog())
+		}
+		for id, date := range sent {
+			if date.Before(since) {
+				delete(sent, id)
+			}
+		}
+		lastPoll = now
+		return logs, nil
+	}), nil
+}
@@ -0,0 +1,396 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/storage/storagetest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+// fakeElasticsearch implements the subset of the Elasticsearch API used by
+// elasticsearchLogStorage, keeping documents in memory.
+type fakeElasticsearch struct {
+	sync.Mutex
+	server    *httptest.Server
+	docs      map[string][]fakeESDoc
+	lastID    int
+	templates []string
+	failBulk  bool
+}
+
+type fakeESDoc struct {
+	elasticsearchDoc
+	id string
+}
+
+func newFakeElasticsearch() *fakeElasticsearch {
+	f := &fakeElasticsearch{docs: map[string][]fakeESDoc{}}
+	f.server = httptest.NewServer(f)
+	return f
+}
+
+func (f *fakeElasticsearch) reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.docs = map[string][]fakeESDoc{}
+	f.templates = nil
+	f.failBulk = false
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_template/"):
+		f.templates = append(f.templates, strings.TrimPrefix(r.URL.Path, "/_template/"))
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		f.bulk(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_search"):
+		f.search(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_delete_by_query"):
+		f.deleteByQuery(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeElasticsearch) bulk(w http.ResponseWriter, r *http.Request) {
+	if f.failBulk {
+		w.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+			}
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		if !scanner.Scan() {
+			break
+		}
+		var doc elasticsearchDoc
+		json.Unmarshal(scanner.Bytes(), &doc)
+		f.add(action.Index.Index, doc)
+	}
+	w.Write([]byte(`{"errors":false}`))
+}
+
+func (f *fakeElasticsearch) add(index string, doc elasticsearchDoc) {
+	f.lastID++
+	f.docs[index] = append(f.docs[index], fakeESDoc{elasticsearchDoc: doc, id: strconv.Itoa(f.lastID)})
+}
+
+type fakeESQuery struct {
+	Size        int
+	Sort        []map[string]string
+	SearchAfter []int64 `json:"search_after"`
+	Query       struct {
+		Bool struct {
+			Filter  []map[string]map[string]json.RawMessage
+			MustNot []map[string]map[string]json.RawMessage `json:"must_not"`
+		}
+	}
+}
+
+func (q *fakeESQuery) matches(doc elasticsearchDoc) bool {
+	for _, clause := range q.Query.Bool.Filter {
+		if !fakeESMatches(doc, clause) {
+			return false
+		}
+	}
+	for _, clause := range q.Query.Bool.MustNot {
+		if fakeESMatches(doc, clause) {
+			return false
+		}
+	}
+	return true
+}
+
+func fakeESField(doc elasticsearchDoc, field string) string {
+	switch field {
+	case "app":
+		return doc.App
+	case "source":
+		return doc.Source
+	case "unit":
+		return doc.Unit
+	}
+	if strings.HasPrefix(field, "fields.") {
+		return doc.Fields[strings.TrimPrefix(field, "fields.")]
+	}
+	return ""
+}
+
+func fakeESMatches(doc elasticsearchDoc, clause map[string]map[string]json.RawMessage) bool {
+	if term, ok := clause["term"]; ok {
+		for field, raw := range term {
+			var value string
+			json.Unmarshal(raw, &value)
+			if fakeESField(doc, field) != value {
+				return false
+			}
+		}
+	}
+	if rng, ok := clause["range"]; ok {
+		if raw, ok := rng["date"]; ok {
+			var cond struct{ Gte, Lt time.Time }
+			json.Unmarshal(raw, &cond)
+			if (!cond.Gte.IsZero() && doc.Date.Before(cond.Gte)) || (!cond.Lt.IsZero() && !doc.Date.Before(cond.Lt)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *fakeElasticsearch) search(w http.ResponseWriter, r *http.Request) {
+	var query fakeESQuery
+	json.NewDecoder(r.Body).Decode(&query)
+	var result []fakeESDoc
+	for _, docs := range f.docs {
+		for _, doc := range docs {
+			if query.matches(doc.elasticsearchDoc) {
+				result = append(result, doc)
+			}
+		}
+	}
+	desc := len(query.Sort) > 0 && query.Sort[0]["date"] == "desc"
+	before := func(a, b [2]int64) bool {
+		if desc {
+			a, b = b, a
+		}
+		return a[0] < b[0] || (a[0] == b[0] && a[1] < b[1])
+	}
+	sortKey := func(doc fakeESDoc) [2]int64 {
+		return [2]int64{doc.Date.UnixNano(), doc.Seq}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return before(sortKey(result[i]), sortKey(result[j]))
+	})
+	if len(query.SearchAfter) == 2 {
+		after := [2]int64{query.SearchAfter[0], query.SearchAfter[1]}
+		for len(result) > 0 && !before(after, sortKey(result[0])) {
+			result = result[1:]
+		}
+	}
+	if query.Size > 0 && len(result) > query.Size {
+		result = result[:query.Size]
+	}
+	var rsp struct {
+		Hits struct {
+			Hits []elasticsearchHit `json:"hits"`
+		} `json:"hits"`
+	}
+	rsp.Hits.Hits = []elasticsearchHit{}
+	for _, doc := range result {
+		rsp.Hits.Hits = append(rsp.Hits.Hits, elasticsearchHit{
+			ID:     doc.id,
+			Source: doc.elasticsearchDoc,
+			Sort: []json.RawMessage{
+				json.RawMessage(strconv.FormatInt(doc.Date.UnixNano(), 10)),
+				json.RawMessage(strconv.FormatInt(doc.Seq, 10)),
+			},
+		})
+	}
+	json.NewEncoder(w).Encode(rsp)
+}
+
+func (f *fakeElasticsearch) deleteByQuery(w http.ResponseWriter, r *http.Request) {
+	var query fakeESQuery
+	json.NewDecoder(r.Body).Decode(&query)
+	var deleted int
+	for index, docs := range f.docs {
+		var kept []fakeESDoc
+		for _, doc := range docs {
+			if query.matches(doc.elasticsearchDoc) {
+				deleted++
+			} else {
+				kept = append(kept, doc)
+			}
+		}
+		f.docs[index] = kept
+	}
+	json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+}
+
+var fakeES = newFakeElasticsearch()
+
+var esStorage = &elasticsearchLogStorage{
+	url:          fakeES.server.URL,
+	indexPrefix:  defaultElasticsearchIndexPrefix,
+	pollInterval: 10 * time.Millisecond,
+	client:       http.DefaultClient,
+}
+
+var _ = check.Suite(&storagetest.AppLogSuite{
+	AppLogStorage: esStorage,
+	SuiteHooks:    &fakeServerHooks{reset: fakeES.reset},
+})
+
+// fakeServerHooks implements storagetest.SuiteHooks resetting the state of a
+// fake server before each test.
+type fakeServerHooks struct {
+	reset func()
+}
+
+func (h *fakeServerHooks) SetUpSuite(c *check.C) {}
+
+func (h *fakeServerHooks) TearDownSuite(c *check.C) {}
+
+func (h *fakeServerHooks) SetUpTest(c *check.C) {
+	h.reset()
+}
+
+func (h *fakeServerHooks) TearDownTest(c *check.C) {}
+
+type ElasticsearchSuite struct {
+	storage *elasticsearchLogStorage
+}
+
+var _ = check.Suite(&ElasticsearchSuite{})
+
+func (s *ElasticsearchSuite) SetUpTest(c *check.C) {
+	fakeES.reset()
+	s.storage = &elasticsearchLogStorage{
+		url:         fakeES.server.URL,
+		indexPrefix: "logs",
+		client:      http.DefaultClient,
+	}
+}
+
+func (s *ElasticsearchSuite) TestNewElasticsearchLogStorage(c *check.C) {
+	_, err := newElasticsearchLogStorage()
+	c.Assert(err, check.ErrorMatches, "config key log:elasticsearch:url not found")
+	config.Set("log:elasticsearch:url", "http://es.example.com:9200/")
+	defer config.Unset("log:elasticsearch")
+	storage, err := newElasticsearchLogStorage()
+	c.Assert(err, check.IsNil)
+	esStorage := storage.(*elasticsearchLogStorage)
+	c.Assert(esStorage.url, check.Equals, "http://es.example.com:9200")
+	c.Assert(esStorage.indexPrefix, check.Equals, "tsuru-logs")
+}
+
+func (s *ElasticsearchSuite) TestInsertAppDailyIndexes(c *check.C) {
+	day1 := time.Date(2019, 3, 10, 23, 59, 0, 0, time.UTC)
+	day2 := day1.Add(time.Minute)
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Date: day1, Message: "1", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Date: day2, Message: "2", Source: "web", Unit: "u1"},
+	)
+	c.Assert(err, check.IsNil)
+	fakeES.Lock()
+	defer fakeES.Unlock()
+	c.Assert(fakeES.templates, check.DeepEquals, []string{"logs"})
+	c.Assert(fakeES.docs["logs-2019.03.10"], check.HasLen, 1)
+	c.Assert(fakeES.docs["logs-2019.03.10"][0].Message, check.Equals, "1")
+	c.Assert(fakeES.docs["logs-2019.03.11"], check.HasLen, 1)
+	c.Assert(fakeES.docs["logs-2019.03.11"][0].App, check.Equals, "myapp")
+}
+
+func (s *ElasticsearchSuite) TestInsertAppBulkErrors(c *check.C) {
+	fakeES.Lock()
+	fakeES.failBulk = true
+	fakeES.Unlock()
+	err := s.storage.InsertApp("myapp", &appTypes.Applog{Message: "1"})
+	c.Assert(err, check.ErrorMatches, `unable to insert logs: {"type":"mapper_parsing_exception"}`)
+}
+
+func (s *ElasticsearchSuite) TestListInvertFilters(c *check.C) {
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Message: "1", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "2", Source: "worker", Unit: "u1"},
+		&appTypes.Applog{Message: "3", Source: "web", Unit: "u2"},
+		&appTypes.Applog{Message: "4", Source: "worker", Unit: "u2"},
+	)
+	c.Assert(err, check.IsNil)
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Source: "web", Unit: "u1", InvertFilters: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "4")
+}
+
+func (s *ElasticsearchSuite) TestListMessageFilterPages(c *check.C) {
+	defer func(pageSize int) { elasticsearchPageSize = pageSize }(elasticsearchPageSize)
+	elasticsearchPageSize = 2
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Message: "error 1", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "ok", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "error 2", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "ok", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "ok", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "error 3", Source: "web", Unit: "u1"},
+	)
+	c.Assert(err, check.IsNil)
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Message: "^error", Limit: 2})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+	c.Assert(logs[0].Message, check.Equals, "error 2")
+	c.Assert(logs[1].Message, check.Equals, "error 3")
+	logs, err = s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Message: "^error"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 3)
+	c.Assert(logs[0].Message, check.Equals, "error 1")
+}
+
+func (s *ElasticsearchSuite) TestListMessageFilterLongMessages(c *check.C) {
+	long := strings.Repeat("a", elasticsearchMaxRawMessage+1) + " timeout"
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Message: long, Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "ok", Source: "web", Unit: "u1"},
+	)
+	c.Assert(err, check.IsNil)
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Message: "timeout$"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, long)
+}
+
+func (s *ElasticsearchSuite) TestListInvalidMessageFilter(c *check.C) {
+	_, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Message: "a(b"})
+	c.Assert(err, check.ErrorMatches, "invalid message filter.*")
+}
+
+func (s *ElasticsearchSuite) TestWatchSendsLateEntriesOnce(c *check.C) {
+	defer func(pageSize int) { elasticsearchPageSize = pageSize }(elasticsearchPageSize)
+	elasticsearchPageSize = 1
+	s.storage.pollInterval = 10 * time.Millisecond
+	err := s.storage.InsertApp("myapp", &appTypes.Applog{Message: "old", Source: "web", Unit: "u1"})
+	c.Assert(err, check.IsNil)
+	w, err := s.storage.Watch("myapp", "", "")
+	c.Assert(err, check.IsNil)
+	defer w.Close()
+	started := time.Now().UTC()
+	time.Sleep(50 * time.Millisecond)
+	err = s.storage.InsertApp("myapp", &appTypes.Applog{Message: "1", Source: "web", Unit: "u1"})
+	c.Assert(err, check.IsNil)
+	c.Assert((<-w.Chan()).Message, check.Equals, "1")
+	time.Sleep(50 * time.Millisecond)
+	// Entry dated before the last poll, inserted by another instance and
+	// only searchable now.
+	fakeES.Lock()
+	fakeES.add("logs-other", elasticsearchDoc{Date: started, Seq: 1, Message: "2", Source: "web", App: "myapp", Unit: "u2"})
+	fakeES.Unlock()
+	c.Assert((<-w.Chan()).Message, check.Equals, "2")
+	select {
+	case l := <-w.Chan():
+		c.Fatalf("unexpected log entry sent twice: %#v", l)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
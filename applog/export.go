@@ -0,0 +1,78 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	exportWindow   = time.Hour
+	exportPageSize = 1000
+)
+
+// Export writes the logs matching args between args.Since and args.Until to
+// w, one JSON encoded entry per line, from the oldest to the newest. Logs are
+// read in windows of one hour, so that only the entries in one window are
+// kept in memory. It returns the number of exported entries.
+func Export(svc appTypes.AppLogService, args appTypes.ListLogArgs, w io.Writer) (int, error) {
+	if args.Since.IsZero() || args.Until.IsZero() {
+		return 0, errors.New("since and until are required to export logs")
+	}
+	if !args.Since.Before(args.Until) {
+		return 0, errors.New("since must be before until")
+	}
+	encoder := json.NewEncoder(w)
+	var count int
+	until := args.Until
+	for start := args.Since; start.Before(until); start = start.Add(exportWindow) {
+		windowArgs := args
+		windowArgs.Since = start
+		windowArgs.Until = start.Add(exportWindow)
+		if windowArgs.Until.After(until) {
+			windowArgs.Until = until
+		}
+		pages, err := listWindow(svc, windowArgs)
+		if err != nil {
+			return count, err
+		}
+		for i := len(pages) - 1; i >= 0; i-- {
+			for _, entry := range pages[i] {
+				err = encoder.Encode(entry)
+				if err != nil {
+					return count, err
+				}
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// listWindow returns every page of logs matching args, from the newest to
+// the oldest page.
+func listWindow(svc appTypes.AppLogService, args appTypes.ListLogArgs) ([][]appTypes.Applog, error) {
+	var pages [][]appTypes.Applog
+	args.Limit = exportPageSize
+	args.Cursor = nil
+	for {
+		logs, err := svc.List(args)
+		if err != nil {
+			return nil, err
+		}
+		if len(logs) > 0 {
+			pages = append(pages, logs)
+		}
+		if len(logs) < args.Limit {
+			return pages, nil
+		}
+		args.Cursor = appTypes.NextLogCursor(logs, args.Cursor)
+	}
+}
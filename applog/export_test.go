@@ -0,0 +1,67 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestExport(c *check.C) {
+	svc := memoryLogService{}
+	base := time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC)
+	for i := 0; i < 2500; i++ {
+		source := "web"
+		if i%5 == 0 {
+			source = "worker"
+		}
+		err := svc.Enqueue(&appTypes.Applog{
+			Date:    base.Add(time.Duration(i/2) * 5 * time.Second),
+			Message: strconv.Itoa(i),
+			Source:  source,
+			AppName: "myapp",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	var buf bytes.Buffer
+	count, err := Export(&svc, appTypes.ListLogArgs{
+		AppName: "myapp",
+		Source:  "web",
+		Since:   base.Add(10 * time.Second),
+		Until:   base.Add(2 * time.Hour),
+	}, &buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 1997)
+	decoder := json.NewDecoder(&buf)
+	var messages []int
+	for decoder.More() {
+		var entry appTypes.Applog
+		err = decoder.Decode(&entry)
+		c.Assert(err, check.IsNil)
+		c.Assert(entry.Source, check.Equals, "web")
+		n, _ := strconv.Atoi(entry.Message)
+		messages = append(messages, n)
+	}
+	c.Assert(messages, check.HasLen, count)
+	c.Assert(messages[0], check.Equals, 4)
+	c.Assert(messages[len(messages)-1], check.Equals, 2499)
+	for i := 1; i < len(messages); i++ {
+		c.Assert(messages[i] > messages[i-1], check.Equals, true)
+	}
+}
+
+func (s *S) TestExportRequiresTimeRange(c *check.C) {
+	svc := memoryLogService{}
+	now := time.Now()
+	_, err := Export(&svc, appTypes.ListLogArgs{AppName: "myapp", Since: now}, &bytes.Buffer{})
+	c.Assert(err, check.ErrorMatches, "since and until are required to export logs")
+	_, err = Export(&svc, appTypes.ListLogArgs{AppName: "myapp", Since: now, Until: now}, &bytes.Buffer{})
+	c.Assert(err, check.ErrorMatches, "since must be before until")
+}
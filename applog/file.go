@@ -0,0 +1,352 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	defaultLogFilePath     = "/var/log/tsuru/apps"
+	defaultLogFileMaxSize  = 100 // MB
+	defaultLogFileMaxFiles = 5
+)
+
+// fileLogStorage stores logs in one file per app in a local directory, one
+// JSON encoded entry per line. Files are rotated once they reach max-size and
+// at most max-files rotated files are kept for each app. Logs are only
+// visible to the tsuru api instance storing them, see ValidateInstances.
+type fileLogStorage struct {
+	path         string
+	maxSize      int64
+	maxFiles     int
+	pollInterval time.Duration
+	mu           sync.Mutex
+}
+
+var _ appTypes.AppLogStorage = &fileLogStorage{}
+
+func newFileLogStorage() (appTypes.AppLogStorage, error) {
+	prefix := "log:local-file"
+	path, _ := config.GetString(prefix + ":path")
+	if path == "" {
+		path = defaultLogFilePath
+	}
+	maxSize, _ := config.GetInt(prefix + ":max-size")
+	if maxSize <= 0 {
+		maxSize = defaultLogFileMaxSize
+	}
+	maxFiles, _ := config.GetInt(prefix + ":max-files")
+	if maxFiles <= 0 {
+		maxFiles = defaultLogFileMaxFiles
+	}
+	pollInterval, _ := config.GetFloat(prefix + ":poll-interval")
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create log directory")
+	}
+	return &fileLogStorage{
+		path:         path,
+		maxSize:      int64(maxSize) * 1024 * 1024,
+		maxFiles:     maxFiles,
+		pollInterval: time.Duration(pollInterval * float64(time.Second)),
+	}, nil
+}
+
+func (s *fileLogStorage) fileName(appName string, rotation int) (string, error) {
+	if appName == "" || strings.HasPrefix(appName, ".") || strings.ContainsAny(appName, `/\`) {
+		return "", errors.Errorf("invalid app name for log file: %q", appName)
+	}
+	name := filepath.Join(s.path, appName+".log")
+	if rotation > 0 {
+		name = fmt.Sprintf("%s.%d", name, rotation)
+	}
+	return name, nil
+}
+
+func (s *fileLogStorage) InsertApp(appName string, msgs ...*appTypes.Applog) error {
+	fileName, err := s.fileName(appName, 0)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	now := time.Now().UTC()
+	for _, msg := range msgs {
+		if msg.Date.IsZero() {
+			msg.Date = now
+		}
+		err = encoder.Encode(msg)
+		if err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, err := os.Stat(fileName)
+	if err == nil && info.Size() >= s.maxSize {
+		err = s.rotate(appName)
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(buf.Bytes())
+	return err
+}
+
+func (s *fileLogStorage) rotate(appName string) error {
+	for i := s.maxFiles; i > 0; i-- {
+		src, _ := s.fileName(appName, i-1)
+		dst, _ := s.fileName(appName, i)
+		err := os.Rename(src, dst)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "unable to rotate log file")
+		}
+	}
+	return nil
+}
+
+// Prune removes the entries older than the retention max age and the oldest
+// entries exceeding the retention max size, considering the size of every
+// file of the app. Files without remaining entries are removed. The current
+// file is rotated before being pruned, so that watchers following it are not
+// affected by the rewrite.
+func (s *fileLogStorage) Prune(appName string, retention appTypes.LogRetention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := retention.Cutoff(time.Now())
+	var total int64
+	for i := 0; i <= s.maxFiles; i++ {
+		fileName, err := s.fileName(appName, i)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		maxSize := int64(-1)
+		if retention.MaxSize > 0 {
+			maxSize = retention.MaxSize - total
+		}
+		start := prunedLogStart(data, cutoff, maxSize)
+		total += int64(len(data) - start)
+		if start == 0 {
+			continue
+		}
+		if i == 0 {
+			err = s.rotate(appName)
+			if err != nil {
+				return err
+			}
+			fileName, _ = s.fileName(appName, 1)
+			i++
+		}
+		if start == len(data) {
+			err = os.Remove(fileName)
+		} else {
+			err = ioutil.WriteFile(fileName+".tmp", data[start:], 0644)
+			if err == nil {
+				err = os.Rename(fileName+".tmp", fileName)
+			}
+		}
+		if err != nil {
+			return errors.Wrap(err, "unable to prune log file")
+		}
+	}
+	return nil
+}
+
+// PrunesLocalLogs returns true, as log files are kept by each instance.
+func (s *fileLogStorage) PrunesLocalLogs() bool {
+	return true
+}
+
+// prunedLogStart returns the offset of the first line in data to be kept,
+// skipping lines with entries older than cutoff and, if maxSize is not
+// negative, the oldest lines exceeding maxSize.
+func prunedLogStart(data []byte, cutoff time.Time, maxSize int64) int {
+	start := 0
+	for start < len(data) {
+		end := bytes.IndexByte(data[start:], '\n') + 1
+		if end == 0 {
+			end = len(data) - start
+		}
+		var entry struct{ Date time.Time }
+		expired := !cutoff.IsZero() && json.Unmarshal(data[start:start+end], &entry) == nil && entry.Date.Before(cutoff)
+		oversized := maxSize >= 0 && int64(len(data)-start) > maxSize
+		if !expired && !oversized {
+			break
+		}
+		start += end
+	}
+	return start
+}
+
+func (s *fileLogStorage) List(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+	if args.AppName == "" {
+		return nil, errors.New("unable to list logs with empty app name")
+	}
+	filter, err := newLogFilter(args)
+	if err != nil {
+		return nil, err
+	}
+	var logs []appTypes.Applog
+	for i := 0; i <= s.maxFiles; i++ {
+		fileName, err := s.fileName(args.AppName, i)
+		if err != nil {
+			return nil, err
+		}
+		fileLogs, err := readLogFile(fileName, filter)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(fileLogs, logs...)
+		if args.Limit > 0 && len(logs) >= args.Limit {
+			break
+		}
+	}
+	if args.Limit > 0 && len(logs) > args.Limit {
+		logs = logs[len(logs)-args.Limit:]
+	}
+	if logs == nil {
+		logs = []appTypes.Applog{}
+	}
+	return logs, nil
+}
+
+func readLogFile(fileName string, filter *logFilter) ([]appTypes.Applog, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	logs, _, err := decodeLogLines(f, filter)
+	return logs, err
+}
+
+// decodeLogLines decodes the complete lines available in r, returning the
+// entries matching filter and the number of bytes consumed.
+func decodeLogLines(r io.Reader, filter *logFilter) ([]appTypes.Applog, int64, error) {
+	var logs []appTypes.Applog
+	var consumed int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return logs, consumed, nil
+		}
+		if err != nil {
+			return nil, consumed, err
+		}
+		consumed += int64(len(line))
+		var entry appTypes.Applog
+		if json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		if filter.match(&entry) {
+			logs = append(logs, entry)
+		}
+	}
+}
+
+func (s *fileLogStorage) Watch(appName, source, unit string) (appTypes.LogWatcher, error) {
+	fileName, err := s.fileName(appName, 0)
+	if err != nil {
+		return nil, err
+	}
+	rotatedName, _ := s.fileName(appName, 1)
+	tail := &fileTail{
+		fileName:    fileName,
+		rotatedName: rotatedName,
+		filter:      &logFilter{source: source, unit: unit},
+	}
+	if info, err := os.Stat(fileName); err == nil {
+		tail.info = info
+		tail.offset = info.Size()
+	}
+	return newPollWatcher(s.pollInterval, tail.poll), nil
+}
+
+// fileTail follows the entries appended to a log file, detecting when the
+// file is rotated.
+type fileTail struct {
+	fileName    string
+	rotatedName string
+	filter      *logFilter
+	info        os.FileInfo
+	offset      int64
+}
+
+func (t *fileTail) poll() ([]appTypes.Applog, error) {
+	info, err := os.Stat(t.fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var logs []appTypes.Applog
+	if t.info != nil && !os.SameFile(t.info, info) {
+		if rotatedInfo, err := os.Stat(t.rotatedName); err == nil && os.SameFile(t.info, rotatedInfo) {
+			logs, _, err = t.readFrom(t.rotatedName, t.offset)
+			if err != nil {
+				return nil, err
+			}
+		}
+		t.offset = 0
+	}
+	t.info = info
+	if info.Size() < t.offset {
+		t.offset = 0
+	}
+	if info.Size() == t.offset {
+		return logs, nil
+	}
+	newLogs, consumed, err := t.readFrom(t.fileName, t.offset)
+	if err != nil {
+		return nil, err
+	}
+	t.offset += consumed
+	return append(logs, newLogs...), nil
+}
+
+func (t *fileTail) readFrom(fileName string, offset int64) ([]appTypes.Applog, int64, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decodeLogLines(f, t.filter)
+}
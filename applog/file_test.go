@@ -0,0 +1,173 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/servicemanager"
+	"github.com/tsuru/tsuru/storage/storagetest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	"github.com/tsuru/tsuru/types/tracker"
+	check "gopkg.in/check.v1"
+)
+
+var fileStorage = &fileLogStorage{maxSize: 1024 * 1024, maxFiles: 2, pollInterval: 10 * time.Millisecond}
+
+var _ = check.Suite(&storagetest.AppLogSuite{
+	AppLogStorage: fileStorage,
+	SuiteHooks:    &fileStorageHooks{storage: fileStorage},
+})
+
+type fileStorageHooks struct {
+	storage *fileLogStorage
+}
+
+func (h *fileStorageHooks) SetUpSuite(c *check.C) {}
+
+func (h *fileStorageHooks) TearDownSuite(c *check.C) {}
+
+func (h *fileStorageHooks) SetUpTest(c *check.C) {
+	h.storage.path = c.MkDir()
+}
+
+func (h *fileStorageHooks) TearDownTest(c *check.C) {}
+
+type FileSuite struct {
+	storage *fileLogStorage
+}
+
+var _ = check.Suite(&FileSuite{})
+
+func (s *FileSuite) SetUpTest(c *check.C) {
+	s.storage = &fileLogStorage{path: c.MkDir(), maxSize: 100, maxFiles: 2, pollInterval: 10 * time.Millisecond}
+}
+
+func (s *FileSuite) TestNewFileLogStorage(c *check.C) {
+	dir := c.MkDir()
+	config.Set("log:local-file:path", dir+"/apps")
+	config.Set("log:local-file:max-size", 10)
+	config.Set("log:local-file:max-files", 3)
+	defer config.Unset("log:local-file")
+	storage, err := newFileLogStorage()
+	c.Assert(err, check.IsNil)
+	c.Assert(storage, check.DeepEquals, &fileLogStorage{path: dir + "/apps", maxSize: 10 * 1024 * 1024, maxFiles: 3})
+	info, err := os.Stat(dir + "/apps")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.IsDir(), check.Equals, true)
+}
+
+func (s *FileSuite) TestInsertAppInvalidName(c *check.C) {
+	err := s.storage.InsertApp("../myapp", &appTypes.Applog{Message: "x"})
+	c.Assert(err, check.ErrorMatches, `invalid app name for log file: "../myapp"`)
+}
+
+func (s *FileSuite) TestInsertAppRotates(c *check.C) {
+	for i := 0; i < 10; i++ {
+		err := s.storage.InsertApp("myapp", &appTypes.Applog{Message: strconv.Itoa(i), Source: "web"})
+		c.Assert(err, check.IsNil)
+	}
+	files, err := ioutil.ReadDir(s.storage.path)
+	c.Assert(err, check.IsNil)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	c.Assert(names, check.DeepEquals, []string{"myapp.log", "myapp.log.1", "myapp.log.2"})
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs) < 10, check.Equals, true)
+	c.Assert(logs[len(logs)-1].Message, check.Equals, "9")
+	for i := 1; i < len(logs); i++ {
+		prev, _ := strconv.Atoi(logs[i-1].Message)
+		c.Assert(logs[i].Message, check.Equals, strconv.Itoa(prev+1))
+	}
+}
+
+func (s *FileSuite) TestListInvertFilters(c *check.C) {
+	s.storage.maxSize = 1024 * 1024
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Message: "1", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "2", Source: "worker", Unit: "u1"},
+		&appTypes.Applog{Message: "3", Source: "web", Unit: "u2"},
+		&appTypes.Applog{Message: "4", Source: "worker", Unit: "u2"},
+	)
+	c.Assert(err, check.IsNil)
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Source: "web", Unit: "u1", InvertFilters: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "4")
+}
+
+func (s *FileSuite) TestWatchFollowsRotation(c *check.C) {
+	w, err := s.storage.Watch("myapp", "", "")
+	c.Assert(err, check.IsNil)
+	defer w.Close()
+	for i := 0; i < 5; i++ {
+		err = s.storage.InsertApp("myapp", &appTypes.Applog{Message: strconv.Itoa(i), Source: "web"})
+		c.Assert(err, check.IsNil)
+		select {
+		case l := <-w.Chan():
+			c.Assert(l.Message, check.Equals, strconv.Itoa(i))
+		case <-time.After(2 * time.Second):
+			c.Fatalf("timed out waiting for log %d", i)
+		}
+	}
+}
+
+func (s *FileSuite) TestPruneBySize(c *check.C) {
+	s.storage.maxSize = 1024 * 1024
+	for i := 0; i < 10; i++ {
+		err := s.storage.InsertApp("myapp", &appTypes.Applog{Message: strconv.Itoa(i), Source: "web"})
+		c.Assert(err, check.IsNil)
+	}
+	fileName, _ := s.storage.fileName("myapp", 0)
+	data, err := ioutil.ReadFile(fileName)
+	c.Assert(err, check.IsNil)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	lastSize := int64(len(lines[9]))
+	err = s.storage.Prune("myapp", appTypes.LogRetention{MaxSize: int64(len(bytes.Join(lines[7:], nil)))})
+	c.Assert(err, check.IsNil)
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 3)
+	c.Assert(logs[0].Message, check.Equals, "7")
+	rotatedName, _ := s.storage.fileName("myapp", 1)
+	_, err = os.Stat(rotatedName)
+	c.Assert(err, check.IsNil)
+	_, err = os.Stat(fileName)
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+	err = s.storage.Prune("myapp", appTypes.LogRetention{MaxSize: lastSize})
+	c.Assert(err, check.IsNil)
+	logs, err = s.storage.List(appTypes.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "9")
+}
+
+func (s *FileSuite) TestValidateInstances(c *check.C) {
+	defer func(t tracker.InstanceService) { servicemanager.InstanceTracker = t }(servicemanager.InstanceTracker)
+	instances := []tracker.TrackedInstance{{Name: "api1"}}
+	servicemanager.InstanceTracker = &tracker.MockInstanceService{
+		OnCurrentInstance: func() (tracker.TrackedInstance, error) {
+			return tracker.TrackedInstance{Name: "api1"}, nil
+		},
+		OnLiveInstances: func() ([]tracker.TrackedInstance, error) {
+			return instances, nil
+		},
+	}
+	config.Set("log:app-log-service", "file")
+	defer config.Unset("log:app-log-service")
+	c.Assert(ValidateInstances(), check.IsNil)
+	instances = append(instances, tracker.TrackedInstance{Name: "api2"})
+	c.Assert(ValidateInstances(), check.ErrorMatches, `the "file" app log service only supports a single tsuru api instance, found live instance "api2"`)
+	config.Set("log:app-log-service", "elasticsearch")
+	c.Assert(ValidateInstances(), check.IsNil)
+}
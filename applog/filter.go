@@ -0,0 +1,93 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+// logFilter matches log entries against the filters in a ListLogArgs, for
+// storages unable to filter them natively.
+type logFilter struct {
+	source  string
+	unit    string
+	invert  bool
+	since   time.Time
+	until   time.Time
+	message *regexp.Regexp
+	fields  map[string]string
+}
+
+func newLogFilter(args appTypes.ListLogArgs) (*logFilter, error) {
+	f := &logFilter{
+		source: args.Source,
+		unit:   args.Unit,
+		invert: args.InvertFilters,
+		since:  args.Since,
+		until:  args.Until,
+		fields: args.FieldsFilter(),
+	}
+	if args.Message != "" {
+		var err error
+		f.message, err = regexp.Compile(args.Message)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid message filter")
+		}
+	}
+	return f, nil
+}
+
+func (f *logFilter) match(l *appTypes.Applog) bool {
+	return (f.source == "" || (f.source == l.Source) != f.invert) &&
+		(f.unit == "" || (f.unit == l.Unit) != f.invert) &&
+		(f.since.IsZero() || !l.Date.Before(f.since)) &&
+		(f.until.IsZero() || l.Date.Before(f.until)) &&
+		(f.message == nil || f.message.MatchString(l.Message)) &&
+		f.matchFields(l)
+}
+
+func (f *logFilter) matchFields(l *appTypes.Applog) bool {
+	for k, v := range f.fields {
+		if value, ok := l.Fields[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// listPage calls list translating args.Cursor into an upper bound for the
+// dates of the entries, then removing the entries already returned in the
+// previous page. Cursors with an ID are only returned by storages handling
+// them natively, and are passed through.
+func listPage(args appTypes.ListLogArgs, list func(appTypes.ListLogArgs) ([]appTypes.Applog, error)) ([]appTypes.Applog, error) {
+	cursor := args.Cursor
+	if cursor == nil || cursor.ID != "" {
+		return list(args)
+	}
+	args.Cursor = nil
+	until := cursor.Date.Add(time.Nanosecond)
+	if args.Until.IsZero() || until.Before(args.Until) {
+		args.Until = until
+	}
+	limit := args.Limit
+	if limit > 0 {
+		args.Limit += cursor.Skip
+	}
+	logs, err := list(args)
+	if err != nil {
+		return nil, err
+	}
+	for skip := cursor.Skip; skip > 0 && len(logs) > 0 && logs[len(logs)-1].Date.Equal(cursor.Date); skip-- {
+		logs = logs[:len(logs)-1]
+	}
+	if limit > 0 && len(logs) > limit {
+		logs = logs[len(logs)-limit:]
+	}
+	return logs, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"strconv"
+	"time"
+
+	appTypes "github.com/tsuru/tsuru/types/app"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestLogFilterMatch(c *check.C) {
+	base := time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC)
+	entry := appTypes.Applog{Date: base, Message: "process killed: OOM", Source: "web", Unit: "u1"}
+	tests := []struct {
+		args     appTypes.ListLogArgs
+		expected bool
+	}{
+		{appTypes.ListLogArgs{}, true},
+		{appTypes.ListLogArgs{Source: "web", Unit: "u1"}, true},
+		{appTypes.ListLogArgs{Source: "web", Unit: "u2"}, false},
+		{appTypes.ListLogArgs{Source: "worker", InvertFilters: true}, true},
+		{appTypes.ListLogArgs{Since: base}, true},
+		{appTypes.ListLogArgs{Since: base.Add(time.Nanosecond)}, false},
+		{appTypes.ListLogArgs{Until: base}, false},
+		{appTypes.ListLogArgs{Until: base.Add(time.Nanosecond)}, true},
+		{appTypes.ListLogArgs{Message: "killed: [A-Z]+$"}, true},
+		{appTypes.ListLogArgs{Message: "oom"}, false},
+		{appTypes.ListLogArgs{Message: "(?i)oom", Source: "worker", InvertFilters: true}, true},
+	}
+	for i, tt := range tests {
+		filter, err := newLogFilter(tt.args)
+		c.Assert(err, check.IsNil)
+		c.Check(filter.match(&entry), check.Equals, tt.expected, check.Commentf("test %d", i))
+	}
+	_, err := newLogFilter(appTypes.ListLogArgs{Message: "(OOM"})
+	c.Assert(err, check.ErrorMatches, "invalid message filter: .*")
+}
+
+func (s *S) TestListPage(c *check.C) {
+	base := time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC)
+	var all []appTypes.Applog
+	for i := 0; i < 7; i++ {
+		all = append(all, appTypes.Applog{Date: base.Add(time.Duration(i/3) * time.Second), Message: strconv.Itoa(i)})
+	}
+	list := func(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+		c.Assert(args.Cursor, check.IsNil)
+		var logs []appTypes.Applog
+		for _, l := range all {
+			if args.Until.IsZero() || l.Date.Before(args.Until) {
+				logs = append(logs, l)
+			}
+		}
+		if args.Limit > 0 && len(logs) > args.Limit {
+			logs = logs[len(logs)-args.Limit:]
+		}
+		return logs, nil
+	}
+	var pages [][]string
+	var cursor *appTypes.LogCursor
+	for {
+		logs, err := listPage(appTypes.ListLogArgs{Limit: 2, Cursor: cursor}, list)
+		c.Assert(err, check.IsNil)
+		if len(logs) == 0 {
+			break
+		}
+		var page []string
+		for _, l := range logs {
+			page = append(page, l.Message)
+		}
+		pages = append(pages, page)
+		cursor = appTypes.NextLogCursor(logs, cursor)
+		parsed, err := appTypes.ParseLogCursor(cursor.String())
+		c.Assert(err, check.IsNil)
+		c.Assert(parsed, check.DeepEquals, cursor)
+	}
+	c.Assert(pages, check.DeepEquals, [][]string{{"5", "6"}, {"3", "4"}, {"1", "2"}, {"0"}})
+}
+
+func (s *S) TestListPageCursorWithID(c *check.C) {
+	cursor := &appTypes.LogCursor{Date: time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC), ID: "5cd5849c1a2f3e0001d3a0b1"}
+	var calledArgs appTypes.ListLogArgs
+	list := func(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+		calledArgs = args
+		return nil, nil
+	}
+	_, err := listPage(appTypes.ListLogArgs{Limit: 2, Cursor: cursor}, list)
+	c.Assert(err, check.IsNil)
+	c.Assert(calledArgs, check.DeepEquals, appTypes.ListLogArgs{Limit: 2, Cursor: cursor})
+	parsed, err := appTypes.ParseLogCursor(cursor.String())
+	c.Assert(err, check.IsNil)
+	c.Assert(parsed, check.DeepEquals, cursor)
+}
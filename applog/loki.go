@@ -0,0 +1,365 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/net"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	defaultLokiLookback   = 30 * 24 * time.Hour
+	lokiMaxResults        = 5000
+	lokiPushPath          = "/loki/api/v1/push"
+	lokiQueryRangePath    = "/loki/api/v1/query_range"
+	lokiDeletePath        = "/loki/api/v1/delete"
+	lokiLabelAppName      = "app"
+	lokiLabelSource       = "source"
+	lokiLabelUnit         = "unit"
+	lokiDirectionBackward = "backward"
+	lokiDirectionForward  = "forward"
+	lokiFieldPrefix       = "field_"
+)
+
+// lokiLogStorage stores logs using the push and query APIs of Loki. Each
+// combination of app, source and unit is stored as a different stream, and
+// structured fields are stored as structured metadata, which requires Loki
+// 3.0 or newer.
+type lokiLogStorage struct {
+	url          string
+	lookback     time.Duration
+	pollInterval time.Duration
+	client       *http.Client
+}
+
+var _ appTypes.AppLogStorage = &lokiLogStorage{}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values []lokiValue       `json:"values"`
+	lastTS int64
+}
+
+// lokiValue is an entry in a stream, encoded as an array with the timestamp,
+// the line and, optionally, the structured metadata of the entry.
+type lokiValue struct {
+	ts       string
+	line     string
+	metadata map[string]string
+}
+
+func (v lokiValue) MarshalJSON() ([]byte, error) {
+	value := []interface{}{v.ts, v.line}
+	if len(v.metadata) > 0 {
+		value = append(value, v.metadata)
+	}
+	return json.Marshal(value)
+}
+
+func (v *lokiValue) UnmarshalJSON(data []byte) error {
+	var value []json.RawMessage
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	if len(value) < 2 {
+		return errors.Errorf("invalid loki entry: %s", string(data))
+	}
+	err = json.Unmarshal(value[0], &v.ts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(value[1], &v.line)
+	if err != nil {
+		return err
+	}
+	if len(value) > 2 {
+		return json.Unmarshal(value[2], &v.metadata)
+	}
+	return nil
+}
+
+// add appends an entry to the stream. Loki keeps the order of entries
+// with the same timestamp unspecified, so timestamps within a stream are
+// made strictly increasing.
+func (s *lokiStream) add(date time.Time, message string, fields map[string]string) {
+	ts := date.UnixNano()
+	if ts <= s.lastTS {
+		ts = s.lastTS + 1
+	}
+	s.lastTS = ts
+	value := lokiValue{ts: strconv.FormatInt(ts, 10), line: message}
+	if len(fields) > 0 {
+		value.metadata = make(map[string]string, len(fields))
+		for k, v := range fields {
+			value.metadata[lokiFieldPrefix+k] = v
+		}
+	}
+	s.Values = append(s.Values, value)
+}
+
+func newLokiLogStorage() (appTypes.AppLogStorage, error) {
+	prefix := "log:loki"
+	address, err := config.GetString(prefix + ":url")
+	if err != nil {
+		return nil, errors.Errorf("config key %s:url not found", prefix)
+	}
+	lookback, _ := config.GetDuration(prefix + ":lookback")
+	if lookback <= 0 {
+		lookback = defaultLokiLookback
+	}
+	pollInterval, _ := config.GetFloat(prefix + ":poll-interval")
+	return &lokiLogStorage{
+		url:          strings.TrimRight(address, "/"),
+		lookback:     lookback,
+		pollInterval: time.Duration(pollInterval * float64(time.Second)),
+		client:       net.Dial15Full60ClientNoKeepAlive,
+	}, nil
+}
+
+func (s *lokiLogStorage) do(req *http.Request, result interface{}) error {
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	data, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("invalid status code %d from loki: %s", rsp.StatusCode, string(data))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+func (s *lokiLogStorage) InsertApp(appName string, msgs ...*appTypes.Applog) error {
+	streams := map[[2]string]*lokiStream{}
+	var order [][2]string
+	now := time.Now().UTC()
+	for _, msg := range msgs {
+		date := msg.Date
+		if date.IsZero() {
+			date = now
+		}
+		key := [2]string{msg.Source, msg.Unit}
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: map[string]string{
+				lokiLabelAppName: appName,
+				lokiLabelSource:  msg.Source,
+				lokiLabelUnit:    msg.Unit,
+			}}
+			streams[key] = stream
+			order = append(order, key)
+		}
+		stream.add(date, msg.Message, msg.Fields)
+	}
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url+lokiPushPath, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	err = s.do(req, nil)
+	if err != nil {
+		log.Errorf("[log insert] unable to insert logs: %s", err)
+	}
+	return err
+}
+
+// lokiSelector builds a LogQL query matching the filters in args.
+func lokiSelector(args appTypes.ListLogArgs) string {
+	op := "="
+	if args.InvertFilters {
+		op = "!="
+	}
+	matchers := []string{fmt.Sprintf("%s=%s", lokiLabelAppName, strconv.Quote(args.AppName))}
+	if args.Source != "" {
+		matchers = append(matchers, fmt.Sprintf("%s%s%s", lokiLabelSource, op, strconv.Quote(args.Source)))
+	}
+	if args.Unit != "" {
+		matchers = append(matchers, fmt.Sprintf("%s%s%s", lokiLabelUnit, op, strconv.Quote(args.Unit)))
+	}
+	selector := "{" + strings.Join(matchers, ",") + "}"
+	if args.Message != "" {
+		selector += " |~ " + strconv.Quote(args.Message)
+	}
+	fields := args.FieldsFilter()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		selector += fmt.Sprintf(" | %s%s=%s", lokiFieldPrefix, k, strconv.Quote(fields[k]))
+	}
+	return selector
+}
+
+type lokiEntry struct {
+	ts  int64
+	log appTypes.Applog
+}
+
+func (s *lokiLogStorage) queryRange(args appTypes.ListLogArgs, start, end int64, direction string) ([]lokiEntry, error) {
+	limit := args.Limit
+	if limit <= 0 || limit > lokiMaxResults {
+		limit = lokiMaxResults
+	}
+	params := url.Values{}
+	params.Set("query", lokiSelector(args))
+	params.Set("start", strconv.FormatInt(start, 10))
+	params.Set("end", strconv.FormatInt(end, 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", direction)
+	req, err := http.NewRequest(http.MethodGet, s.url+lokiQueryRangePath+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data struct {
+			Result []lokiStream
+		}
+	}
+	err = s.do(req, &result)
+	if err != nil {
+		return nil, err
+	}
+	var entries []lokiEntry
+	for _, stream := range result.Data.Result {
+		for _, value := range stream.Values {
+			ts, err := strconv.ParseInt(value.ts, 10, 64)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, lokiEntry{
+				ts: ts,
+				log: appTypes.Applog{
+					Date:    time.Unix(0, ts).UTC(),
+					Message: value.line,
+					Source:  stream.Stream[lokiLabelSource],
+					AppName: stream.Stream[lokiLabelAppName],
+					Unit:    stream.Stream[lokiLabelUnit],
+					Fields:  lokiFields(stream.Stream, value.metadata),
+				},
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ts < entries[j].ts
+	})
+	if len(entries) > limit {
+		if direction == lokiDirectionBackward {
+			entries = entries[len(entries)-limit:]
+		} else {
+			entries = entries[:limit]
+		}
+	}
+	return entries, nil
+}
+
+// lokiFields extracts the structured fields of an entry. By default, Loki
+// returns the structured metadata merged with the stream labels.
+func lokiFields(labels, metadata map[string]string) map[string]string {
+	var fields map[string]string
+	for _, m := range []map[string]string{labels, metadata} {
+		for k, v := range m {
+			if !strings.HasPrefix(k, lokiFieldPrefix) {
+				continue
+			}
+			if fields == nil {
+				fields = map[string]string{}
+			}
+			fields[strings.TrimPrefix(k, lokiFieldPrefix)] = v
+		}
+	}
+	return fields
+}
+
+func (s *lokiLogStorage) List(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+	if args.AppName == "" {
+		return nil, errors.New("unable to list logs with empty app name")
+	}
+	until := time.Now().Add(time.Nanosecond)
+	if !args.Until.IsZero() {
+		until = args.Until
+	}
+	since := until.Add(-s.lookback)
+	if !args.Since.IsZero() {
+		since = args.Since
+	}
+	entries, err := s.queryRange(args, since.UnixNano(), until.UnixNano(), lokiDirectionBackward)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]appTypes.Applog, len(entries))
+	for i := range entries {
+		logs[i] = entries[i].log
+	}
+	return logs, nil
+}
+
+// Prune requests the deletion of the entries of the app older than the
+// retention max age, which requires deletion to be enabled in the Loki
+// compactor. The max size is not enforced.
+func (s *lokiLogStorage) Prune(appName string, retention appTypes.LogRetention) error {
+	cutoff := retention.Cutoff(time.Now())
+	if cutoff.IsZero() {
+		return nil
+	}
+	params := url.Values{}
+	params.Set("query", lokiSelector(appTypes.ListLogArgs{AppName: appName}))
+	params.Set("start", "0")
+	params.Set("end", strconv.FormatInt(cutoff.Unix(), 10))
+	req, err := http.NewRequest(http.MethodPost, s.url+lokiDeletePath+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil)
+}
+
+func (s *lokiLogStorage) Watch(appName, source, unit string) (appTypes.LogWatcher, error) {
+	args := appTypes.ListLogArgs{AppName: appName, Source: source, Unit: unit}
+	cursor := time.Now().UnixNano()
+	return newPollWatcher(s.pollInterval, func() ([]appTypes.Applog, error) {
+		entries, err := s.queryRange(args, cursor+1, time.Now().UnixNano()+1, lokiDirectionForward)
+		if err != nil {
+			return nil, err
+		}
+		logs := make([]appTypes.Applog, len(entries))
+		for i := range entries {
+			cursor = entries[i].ts
+			logs[i] = entries[i].log
+		}
+		return logs, nil
+	}), nil
+}
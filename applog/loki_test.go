@@ -0,0 +1,246 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/storage/storagetest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+var (
+	lokiMatcherRegexp    = regexp.MustCompile(`(\w+)(!?=)("(?:[^"\\]|\\.)*")`)
+	lokiLineFilterRegexp = regexp.MustCompile(` \|~ ("(?:[^"\\]|\\.)*")`)
+)
+
+type fakeLokiEntry struct {
+	labels   map[string]string
+	metadata map[string]string
+	ts       int64
+	line     string
+}
+
+// mergedLabels returns the labels and structured metadata of the entry, as
+// returned by Loki in query results.
+func (e *fakeLokiEntry) mergedLabels() map[string]string {
+	labels := map[string]string{}
+	for _, m := range []map[string]string{e.labels, e.metadata} {
+		for k, v := range m {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// fakeLoki implements the subset of the Loki API used by lokiLogStorage,
+// keeping entries in memory.
+type fakeLoki struct {
+	sync.Mutex
+	server  *httptest.Server
+	entries []fakeLokiEntry
+	queries []string
+}
+
+func newFakeLoki() *fakeLoki {
+	f := &fakeLoki{}
+	f.server = httptest.NewServer(f)
+	return f
+}
+
+func (f *fakeLoki) reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.entries = nil
+	f.queries = nil
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	switch r.URL.Path {
+	case lokiPushPath:
+		var payload struct{ Streams []lokiStream }
+		json.NewDecoder(r.Body).Decode(&payload)
+		for _, stream := range payload.Streams {
+			for _, value := range stream.Values {
+				ts, _ := strconv.ParseInt(value.ts, 10, 64)
+				f.entries = append(f.entries, fakeLokiEntry{labels: stream.Stream, metadata: value.metadata, ts: ts, line: value.line})
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case lokiQueryRangePath:
+		f.queryRange(w, r)
+	case lokiDeletePath:
+		f.delete(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeLoki) queryRange(w http.ResponseWriter, r *http.Request) {
+	query := r.FormValue("query")
+	f.queries = append(f.queries, query)
+	start, _ := strconv.ParseInt(r.FormValue("start"), 10, 64)
+	end, _ := strconv.ParseInt(r.FormValue("end"), 10, 64)
+	limit, _ := strconv.Atoi(r.FormValue("limit"))
+	var lineFilter *regexp.Regexp
+	if m := lokiLineFilterRegexp.FindStringSubmatch(query); m != nil {
+		query = strings.Replace(query, m[0], "", 1)
+		pattern, _ := strconv.Unquote(m[1])
+		lineFilter = regexp.MustCompile(pattern)
+	}
+	matchers := lokiMatcherRegexp.FindAllStringSubmatch(query, -1)
+	var selected []fakeLokiEntry
+entriesLoop:
+	for _, entry := range f.entries {
+		if entry.ts < start || entry.ts >= end {
+			continue
+		}
+		if lineFilter != nil && !lineFilter.MatchString(entry.line) {
+			continue
+		}
+		labels := entry.mergedLabels()
+		for _, m := range matchers {
+			value, _ := strconv.Unquote(m[3])
+			if (labels[m[1]] == value) != (m[2] == "=") {
+				continue entriesLoop
+			}
+		}
+		selected = append(selected, entry)
+	}
+	backward := r.FormValue("direction") == lokiDirectionBackward
+	sort.Slice(selected, func(i, j int) bool {
+		if backward {
+			return selected[i].ts > selected[j].ts
+		}
+		return selected[i].ts < selected[j].ts
+	})
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	streams := []lokiStream{}
+	for _, entry := range selected {
+		streams = append(streams, lokiStream{
+			Stream: entry.mergedLabels(),
+			Values: []lokiValue{{ts: strconv.FormatInt(entry.ts, 10), line: entry.line}},
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "streams", "result": streams},
+	})
+}
+
+// delete removes the entries matching the query immediately, unlike Loki,
+// which removes them asynchronously in the compactor.
+func (f *fakeLoki) delete(w http.ResponseWriter, r *http.Request) {
+	matchers := lokiMatcherRegexp.FindAllStringSubmatch(r.FormValue("query"), -1)
+	start, _ := strconv.ParseInt(r.FormValue("start"), 10, 64)
+	end, _ := strconv.ParseInt(r.FormValue("end"), 10, 64)
+	var kept []fakeLokiEntry
+entriesLoop:
+	for _, entry := range f.entries {
+		if entry.ts < start*int64(time.Second) || entry.ts > end*int64(time.Second) {
+			kept = append(kept, entry)
+			continue
+		}
+		for _, m := range matchers {
+			value, _ := strconv.Unquote(m[3])
+			if (entry.labels[m[1]] == value) != (m[2] == "=") {
+				kept = append(kept, entry)
+				continue entriesLoop
+			}
+		}
+	}
+	f.entries = kept
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var fakeLokiServer = newFakeLoki()
+
+var _ = check.Suite(&storagetest.AppLogSuite{
+	AppLogStorage: &lokiLogStorage{
+		url:          fakeLokiServer.server.URL,
+		lookback:     time.Hour,
+		pollInterval: 10 * time.Millisecond,
+		client:       http.DefaultClient,
+	},
+	SuiteHooks: &fakeServerHooks{reset: fakeLokiServer.reset},
+})
+
+type LokiSuite struct {
+	storage *lokiLogStorage
+}
+
+var _ = check.Suite(&LokiSuite{})
+
+func (s *LokiSuite) SetUpTest(c *check.C) {
+	fakeLokiServer.reset()
+	s.storage = &lokiLogStorage{
+		url:      fakeLokiServer.server.URL,
+		lookback: time.Hour,
+		client:   http.DefaultClient,
+	}
+}
+
+func (s *LokiSuite) TestNewLokiLogStorage(c *check.C) {
+	_, err := newLokiLogStorage()
+	c.Assert(err, check.ErrorMatches, "config key log:loki:url not found")
+	config.Set("log:loki:url", "http://loki.example.com:3100/")
+	config.Set("log:loki:lookback", "24h")
+	defer config.Unset("log:loki")
+	storage, err := newLokiLogStorage()
+	c.Assert(err, check.IsNil)
+	lokiStorage := storage.(*lokiLogStorage)
+	c.Assert(lokiStorage.url, check.Equals, "http://loki.example.com:3100")
+	c.Assert(lokiStorage.lookback, check.Equals, 24*time.Hour)
+}
+
+func (s *LokiSuite) TestInsertAppIncreasingTimestamps(c *check.C) {
+	date := time.Date(2019, 3, 10, 10, 0, 0, 0, time.UTC)
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Date: date, Message: "1", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Date: date, Message: "2", Source: "worker", Unit: "u1"},
+		&appTypes.Applog{Date: date, Message: "3", Source: "web", Unit: "u1"},
+	)
+	c.Assert(err, check.IsNil)
+	fakeLokiServer.Lock()
+	defer fakeLokiServer.Unlock()
+	c.Assert(fakeLokiServer.entries, check.HasLen, 3)
+	c.Assert(fakeLokiServer.entries[0].line, check.Equals, "1")
+	c.Assert(fakeLokiServer.entries[0].ts, check.Equals, date.UnixNano())
+	c.Assert(fakeLokiServer.entries[1].line, check.Equals, "3")
+	c.Assert(fakeLokiServer.entries[1].ts, check.Equals, date.UnixNano()+1)
+	c.Assert(fakeLokiServer.entries[2].labels, check.DeepEquals, map[string]string{"app": "myapp", "source": "worker", "unit": "u1"})
+	c.Assert(fakeLokiServer.entries[2].ts, check.Equals, date.UnixNano())
+}
+
+func (s *LokiSuite) TestListInvertFilters(c *check.C) {
+	err := s.storage.InsertApp("myapp",
+		&appTypes.Applog{Message: "1", Source: "web", Unit: "u1"},
+		&appTypes.Applog{Message: "2", Source: "worker", Unit: "u1"},
+		&appTypes.Applog{Message: "3", Source: "web", Unit: "u2"},
+		&appTypes.Applog{Message: "4", Source: "worker", Unit: "u2"},
+	)
+	c.Assert(err, check.IsNil)
+	logs, err := s.storage.List(appTypes.ListLogArgs{AppName: "myapp", Source: "web", Unit: "u1", InvertFilters: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "4")
+	fakeLokiServer.Lock()
+	defer fakeLokiServer.Unlock()
+	c.Assert(fakeLokiServer.queries, check.DeepEquals, []string{`{app="myapp",source!="web",unit!="u1"}`})
+}
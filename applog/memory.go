@@ -60,14 +60,18 @@ func init() {
 }
 
 type memoryLogService struct {
-	bufferMap sync.Map
+	bufferMap     sync.Map
+	parseMessages bool
 }
 
 func memoryAppLogService() (appTypes.AppLogService, error) {
-	return &memoryLogService{}, nil
+	return &memoryLogService{parseMessages: structuredParsingEnabled()}, nil
 }
 
 func (s *memoryLogService) Enqueue(entry *appTypes.Applog) error {
+	if s.parseMessages {
+		parseStructuredLog(entry)
+	}
 	buffer := s.getAppBuffer(entry.AppName)
 	buffer.add(entry)
 	return nil
@@ -107,6 +111,14 @@ func (s *memoryLogService) List(args appTypes.ListLogArgs) ([]appTypes.Applog, e
 	if args.Limit < 0 {
 		return []appTypes.Applog{}, nil
 	}
+	return listPage(args, s.list)
+}
+
+func (s *memoryLogService) list(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+	filter, err := newLogFilter(args)
+	if err != nil {
+		return nil, err
+	}
 	buffer := s.getAppBuffer(args.AppName)
 	if buffer.length == 0 {
 		return []appTypes.Applog{}, nil
@@ -117,9 +129,7 @@ func (s *memoryLogService) List(args appTypes.ListLogArgs) ([]appTypes.Applog, e
 	logs := make([]appTypes.Applog, args.Limit)
 	var count int
 	for current := buffer.end; count < args.Limit; {
-		if (args.Source == "" || (args.Source == current.log.Source) != args.InvertFilters) &&
-			(args.Unit == "" || (args.Unit == current.log.Unit) != args.InvertFilters) {
-
+		if filter.match(current.log) {
 			logs[len(logs)-count-1] = *current.log
 			count++
 		}
@@ -131,6 +141,26 @@ func (s *memoryLogService) List(args appTypes.ListLogArgs) ([]appTypes.Applog, e
 	return logs[len(logs)-count:], nil
 }
 
+// Prune removes the entries in the app buffer exceeding the retention
+// policy. Only the buffer in the current instance is affected.
+func (s *memoryLogService) Prune(appName string, retention appTypes.LogRetention) error {
+	buffer, ok := s.bufferMap.Load(appName)
+	if !ok {
+		return nil
+	}
+	var maxSize uint
+	if retention.MaxSize > 0 {
+		maxSize = uint(retention.MaxSize)
+	}
+	buffer.(*appLogBuffer).prune(retention.Cutoff(time.Now()), maxSize)
+	return nil
+}
+
+// PrunesLocalLogs returns true, as buffers are kept by each instance.
+func (s *memoryLogService) PrunesLocalLogs() bool {
+	return true
+}
+
 func (s *memoryLogService) Watch(appName, source, unit string, t auth.Token) (appTypes.LogWatcher, error) {
 	buffer := s.getAppBuffer(appName)
 	watcher := &memoryWatcher{
@@ -232,6 +262,32 @@ func (b *appLogBuffer) add(entry *appTypes.Applog) {
 	}
 }
 
+// prune removes the oldest entries while they are older than cutoff or the
+// buffer is larger than maxSize. Zero values are ignored.
+func (b *appLogBuffer) prune(cutoff time.Time, maxSize uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.length > 0 {
+		expired := !cutoff.IsZero() && b.start.log.Date.Before(cutoff)
+		oversized := maxSize > 0 && b.size > maxSize
+		if !expired && !oversized {
+			break
+		}
+		b.size -= b.start.size
+		b.length--
+		b.evictedCounter.Inc()
+		if b.length == 0 {
+			b.start, b.end = nil, nil
+			break
+		}
+		b.start = b.start.next
+		b.start.prev = b.end
+		b.end.next = b.start
+	}
+	b.sizeGauge.Set(float64(b.size))
+	b.lengthGauge.Set(float64(b.length))
+}
+
 func (b *appLogBuffer) addWatcher(watcher *memoryWatcher) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -252,7 +308,12 @@ func (b *appLogBuffer) removeWatcher(watcher *memoryWatcher) bool {
 }
 
 func entrySize(entry *appTypes.Applog) uint {
-	return uint(len(entry.AppName) +
+	var fieldsSize int
+	for k, v := range entry.Fields {
+		fieldsSize += len(k) + len(v)
+	}
+	return uint(fieldsSize +
+		len(entry.AppName) +
 		len(entry.Message) +
 		len(entry.MongoID) +
 		len(entry.Source) +
@@ -6,7 +6,9 @@ package applog
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	appTypes "github.com/tsuru/tsuru/types/app"
 	"gopkg.in/check.v1"
@@ -79,3 +81,39 @@ func (s *S) Test_MemoryLogService_MessagExactLimit(c *check.C) {
 		{Message: newMessage, AppName: "myapp", Source: "tsuru", Unit: "avranakern2"},
 	})
 }
+
+func (s *S) Test_MemoryLogService_Prune(c *check.C) {
+	svc := memoryLogService{}
+	now := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		err := svc.Enqueue(&appTypes.Applog{
+			Date:    now.Add(time.Duration(i-4) * time.Hour),
+			Message: strconv.Itoa(i),
+			AppName: "myapp",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	err := svc.Prune("myapp", appTypes.LogRetention{MaxAge: 150 * time.Minute})
+	c.Assert(err, check.IsNil)
+	msgs, err := svc.List(appTypes.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(msgs, check.HasLen, 3)
+	c.Assert(msgs[0].Message, check.Equals, "2")
+	buffer := svc.getAppBuffer("myapp")
+	err = svc.Prune("myapp", appTypes.LogRetention{MaxSize: int64(buffer.size) - 1})
+	c.Assert(err, check.IsNil)
+	msgs, err = svc.List(appTypes.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(msgs, check.HasLen, 2)
+	c.Assert(msgs[0].Message, check.Equals, "3")
+	err = svc.Prune("myapp", appTypes.LogRetention{MaxAge: time.Nanosecond})
+	c.Assert(err, check.IsNil)
+	c.Assert(buffer.length, check.Equals, 0)
+	c.Assert(buffer.size, check.Equals, uint(0))
+	err = svc.Add("myapp", "new", "tsuru", "u1")
+	c.Assert(err, check.IsNil)
+	msgs, err = svc.List(appTypes.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(msgs, check.HasLen, 1)
+	c.Assert(buffer.start.prev, check.Equals, buffer.end)
+}
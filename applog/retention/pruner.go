@@ -0,0 +1,132 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db/lease"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const leaseName = "log-retention-pruner"
+
+// Pruner periodically removes the app logs exceeding their retention
+// policies. It runs in every tsuru api instance, as some log services keep
+// logs local to each instance. Logs shared by every instance are pruned only
+// by the instance holding the pruner lease, which is renewed before pruning
+// each app.
+type Pruner struct {
+	RunInterval time.Duration
+	instance    string
+	done        chan bool
+	running     bool
+}
+
+// Initialize starts the pruner if it's enabled in the log:retention config.
+func Initialize() error {
+	enabled, _ := config.GetBool("log:retention:enabled")
+	if !enabled {
+		return nil
+	}
+	instance, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return err
+	}
+	runInterval, _ := config.GetInt("log:retention:run-interval")
+	pruner := &Pruner{
+		RunInterval: time.Duration(runInterval) * time.Second,
+		instance:    instance.Name,
+		done:        make(chan bool),
+	}
+	if pruner.RunInterval == 0 {
+		pruner.RunInterval = time.Hour
+	}
+	shutdown.Register(pruner)
+	pruner.running = true
+	go pruner.run()
+	return nil
+}
+
+func (p *Pruner) run() {
+	for {
+		err := p.runOnce()
+		if err != nil {
+			log.Errorf("[log retention] %s", err)
+		}
+		select {
+		case <-p.done:
+			return
+		case <-time.After(p.RunInterval):
+		}
+	}
+}
+
+func (p *Pruner) Shutdown(ctx context.Context) error {
+	if !p.running {
+		return nil
+	}
+	p.done <- true
+	p.running = false
+	return p.lease().Release()
+}
+
+func (p *Pruner) String() string {
+	return "log retention pruner"
+}
+
+func (p *Pruner) lease() *lease.Lease {
+	return &lease.Lease{Name: leaseName, Owner: p.instance, Duration: 3 * p.RunInterval}
+}
+
+func (p *Pruner) runOnce() (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = errors.Errorf("recovered panic, we can never stop! panic: %v", r)
+		}
+	}()
+	pruner, ok := servicemanager.AppLog.(appTypes.AppLogPruner)
+	if !ok {
+		return nil
+	}
+	localPruner, ok := pruner.(appTypes.AppLogLocalPruner)
+	local := ok && localPruner.PrunesLocalLogs()
+	policies, err := List(nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list retention policies")
+	}
+	policyMap := policyMap(policies)
+	apps, err := app.List(nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list apps")
+	}
+	for _, a := range apps {
+		policy := resolve(policyMap, a.Name, a.Pool)
+		if policy.IsZero() {
+			continue
+		}
+		if !local {
+			leader, err := p.lease().Acquire()
+			if err != nil {
+				return errors.Wrap(err, "unable to acquire leadership")
+			}
+			if !leader {
+				return nil
+			}
+		}
+		err = pruner.Prune(a.Name, policy.LogRetention)
+		if err != nil {
+			log.Errorf("[log retention] unable to prune logs for app %q: %s", a.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package retention
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	"github.com/tsuru/tsuru/types/auth"
+	check "gopkg.in/check.v1"
+)
+
+type fakePrunerLogService struct {
+	pruned map[string]appTypes.LogRetention
+}
+
+func (s *fakePrunerLogService) Enqueue(entry *appTypes.Applog) error            { return nil }
+func (s *fakePrunerLogService) Add(appName, message, source, unit string) error { return nil }
+func (s *fakePrunerLogService) List(args appTypes.ListLogArgs) ([]appTypes.Applog, error) {
+	return nil, nil
+}
+func (s *fakePrunerLogService) Watch(appName, source, unit string, t auth.Token) (appTypes.LogWatcher, error) {
+	return nil, nil
+}
+
+func (s *fakePrunerLogService) Prune(appName string, retention appTypes.LogRetention) error {
+	s.pruned[appName] = retention
+	return nil
+}
+
+func (s *S) TestPrunerRunOnce(c *check.C) {
+	for _, a := range []app.App{{Name: "app1", Pool: "pool1"}, {Name: "app2", Pool: "pool1"}, {Name: "app3", Pool: "pool2"}} {
+		err := s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	err := Set(&Policy{Target: event.Target{Type: event.TargetTypePool, Value: "pool1"}, LogRetention: appTypes.LogRetention{MaxAge: 24 * time.Hour}})
+	c.Assert(err, check.IsNil)
+	err = Set(&Policy{Target: event.Target{Type: event.TargetTypeApp, Value: "app2"}, LogRetention: appTypes.LogRetention{MaxSize: 1024}})
+	c.Assert(err, check.IsNil)
+	svc := &fakePrunerLogService{pruned: map[string]appTypes.LogRetention{}}
+	oldAppLog := servicemanager.AppLog
+	servicemanager.AppLog = svc
+	defer func() { servicemanager.AppLog = oldAppLog }()
+	p := &Pruner{RunInterval: time.Minute}
+	err = p.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(svc.pruned, check.DeepEquals, map[string]appTypes.LogRetention{
+		"app1": {MaxAge: 24 * time.Hour},
+		"app2": {MaxSize: 1024},
+	})
+}
+
+type fakeLocalPrunerLogService struct {
+	fakePrunerLogService
+}
+
+func (s *fakeLocalPrunerLogService) PrunesLocalLogs() bool {
+	return true
+}
+
+func (s *S) TestPrunerRunOnceNotLeader(c *check.C) {
+	err := s.conn.Apps().Insert(app.App{Name: "app1", Pool: "pool1"})
+	c.Assert(err, check.IsNil)
+	err = Set(&Policy{Target: event.Target{Type: event.TargetTypeApp, Value: "app1"}, LogRetention: appTypes.LogRetention{MaxAge: time.Hour}})
+	c.Assert(err, check.IsNil)
+	other := &Pruner{RunInterval: time.Minute, instance: "other"}
+	leader, err := other.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	svc := &fakePrunerLogService{pruned: map[string]appTypes.LogRetention{}}
+	localSvc := &fakeLocalPrunerLogService{fakePrunerLogService{pruned: map[string]appTypes.LogRetention{}}}
+	oldAppLog := servicemanager.AppLog
+	defer func() { servicemanager.AppLog = oldAppLog }()
+	p := &Pruner{RunInterval: time.Minute, instance: "current"}
+	servicemanager.AppLog = svc
+	err = p.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(svc.pruned, check.HasLen, 0)
+	servicemanager.AppLog = localSvc
+	err = p.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(localSvc.pruned, check.DeepEquals, map[string]appTypes.LogRetention{"app1": {MaxAge: time.Hour}})
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package retention manages the retention policies of app logs, configured
+// per app or per pool, and prunes the logs exceeding them.
+package retention
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/storage"
+	"github.com/tsuru/tsuru/event"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	SourceApp     = "app"
+	SourcePool    = "pool"
+	SourceDefault = "default"
+)
+
+var (
+	ErrPolicyNotFound = errors.New("log retention policy not found")
+	ErrInvalidPolicy  = errors.New("log retention requires a max age or a max size greater than zero")
+	ErrInvalidTarget  = errors.New("log retention target must be either an app or a pool")
+)
+
+// Policy is the log retention configured for an app or for every app in a
+// pool. Policies set for an app take precedence over the ones set for its
+// pool.
+type Policy struct {
+	Target                event.Target `json:"target" bson:"_id"`
+	appTypes.LogRetention `bson:",inline"`
+}
+
+func (p *Policy) Validate() error {
+	if (p.Target.Type != event.TargetTypeApp && p.Target.Type != event.TargetTypePool) || p.Target.Value == "" {
+		return ErrInvalidTarget
+	}
+	if p.MaxAge < 0 || p.MaxSize < 0 || p.IsZero() {
+		return ErrInvalidPolicy
+	}
+	return nil
+}
+
+// EffectivePolicy is the log retention applied to an app, along with where
+// it was configured: the app, its pool or the default retention.
+type EffectivePolicy struct {
+	appTypes.LogRetention
+	Source string `json:"source"`
+}
+
+func collection() (*storage.Collection, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Collection("log_retention"), nil
+}
+
+// Set creates or replaces the policy for the target.
+func Set(p *Policy) error {
+	err := p.Validate()
+	if err != nil {
+		return err
+	}
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	_, err = coll.UpsertId(p.Target, p)
+	return err
+}
+
+func Get(target event.Target) (*Policy, error) {
+	coll, err := collection()
+	if err != nil {
+		return nil, err
+	}
+	defer coll.Close()
+	var p Policy
+	err = coll.FindId(target).One(&p)
+	if err == mgo.ErrNotFound {
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// List returns the policies matching the targets. All policies are returned
+// if no target is informed.
+func List(targets []event.Target) ([]Policy, error) {
+	coll, err := collection()
+	if err != nil {
+		return nil, err
+	}
+	defer coll.Close()
+	query := bson.M{}
+	if targets != nil {
+		query["_id"] = bson.M{"$in": targets}
+	}
+	var policies []Policy
+	err = coll.Find(query).All(&policies)
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func Remove(target event.Target) error {
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	err = coll.RemoveId(target)
+	if err == mgo.ErrNotFound {
+		return ErrPolicyNotFound
+	}
+	return err
+}
+
+// DefaultRetention returns the retention applied to apps without policies,
+// configured in the log:retention config.
+func DefaultRetention() appTypes.LogRetention {
+	maxAge, _ := config.GetDuration("log:retention:max-age")
+	maxSize, _ := config.GetInt("log:retention:max-size")
+	return appTypes.LogRetention{MaxAge: maxAge, MaxSize: int64(maxSize)}
+}
+
+// ForApp returns the retention applied to the logs of an app in a pool.
+func ForApp(appName, poolName string) (*EffectivePolicy, error) {
+	appTarget := event.Target{Type: event.TargetTypeApp, Value: appName}
+	poolTarget := event.Target{Type: event.TargetTypePool, Value: poolName}
+	policies, err := List([]event.Target{appTarget, poolTarget})
+	if err != nil {
+		return nil, err
+	}
+	return resolve(policyMap(policies), appName, poolName), nil
+}
+
+// ForPool returns the retention applied to the logs of apps in a pool without
+// their own policies.
+func ForPool(poolName string) (*EffectivePolicy, error) {
+	policies, err := List([]event.Target{{Type: event.TargetTypePool, Value: poolName}})
+	if err != nil {
+		return nil, err
+	}
+	return resolve(policyMap(policies), "", poolName), nil
+}
+
+func policyMap(policies []Policy) map[event.Target]appTypes.LogRetention {
+	result := make(map[event.Target]appTypes.LogRetention, len(policies))
+	for _, p := range policies {
+		result[p.Target] = p.LogRetention
+	}
+	return result
+}
+
+func resolve(policies map[event.Target]appTypes.LogRetention, appName, poolName string) *EffectivePolicy {
+	if r, ok := policies[event.Target{Type: event.TargetTypeApp, Value: appName}]; ok {
+		return &EffectivePolicy{LogRetention: r, Source: SourceApp}
+	}
+	if r, ok := policies[event.Target{Type: event.TargetTypePool, Value: poolName}]; ok {
+		return &EffectivePolicy{LogRetention: r, Source: SourcePool}
+	}
+	return &EffectivePolicy{LogRetention: DefaultRetention(), Source: SourceDefault}
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package retention
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestPolicyValidate(c *check.C) {
+	tests := []struct {
+		policy Policy
+		err    error
+	}{
+		{Policy{Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}, LogRetention: appTypes.LogRetention{MaxAge: time.Hour}}, nil},
+		{Policy{Target: event.Target{Type: event.TargetTypePool, Value: "pool1"}, LogRetention: appTypes.LogRetention{MaxSize: 1024}}, nil},
+		{Policy{Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}}, ErrInvalidPolicy},
+		{Policy{Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}, LogRetention: appTypes.LogRetention{MaxAge: -time.Hour, MaxSize: 10}}, ErrInvalidPolicy},
+		{Policy{Target: event.Target{Type: event.TargetTypeNode, Value: "n1"}, LogRetention: appTypes.LogRetention{MaxAge: time.Hour}}, ErrInvalidTarget},
+		{Policy{Target: event.Target{Type: event.TargetTypeApp}, LogRetention: appTypes.LogRetention{MaxAge: time.Hour}}, ErrInvalidTarget},
+	}
+	for i, tt := range tests {
+		c.Check(tt.policy.Validate(), check.Equals, tt.err, check.Commentf("test %d", i))
+	}
+}
+
+func (s *S) TestSetGetRemove(c *check.C) {
+	target := event.Target{Type: event.TargetTypeApp, Value: "myapp"}
+	err := Set(&Policy{Target: target, LogRetention: appTypes.LogRetention{MaxAge: time.Hour}})
+	c.Assert(err, check.IsNil)
+	err = Set(&Policy{Target: target, LogRetention: appTypes.LogRetention{MaxAge: 2 * time.Hour, MaxSize: 1024}})
+	c.Assert(err, check.IsNil)
+	p, err := Get(target)
+	c.Assert(err, check.IsNil)
+	c.Assert(p, check.DeepEquals, &Policy{Target: target, LogRetention: appTypes.LogRetention{MaxAge: 2 * time.Hour, MaxSize: 1024}})
+	policies, err := List(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(policies, check.HasLen, 1)
+	err = Remove(target)
+	c.Assert(err, check.IsNil)
+	_, err = Get(target)
+	c.Assert(err, check.Equals, ErrPolicyNotFound)
+	err = Remove(target)
+	c.Assert(err, check.Equals, ErrPolicyNotFound)
+}
+
+func (s *S) TestForApp(c *check.C) {
+	config.Set("log:retention:max-age", "48h")
+	defer config.Unset("log:retention")
+	policy, err := ForApp("myapp", "pool1")
+	c.Assert(err, check.IsNil)
+	c.Assert(policy, check.DeepEquals, &EffectivePolicy{LogRetention: appTypes.LogRetention{MaxAge: 48 * time.Hour}, Source: SourceDefault})
+	err = Set(&Policy{Target: event.Target{Type: event.TargetTypePool, Value: "pool1"}, LogRetention: appTypes.LogRetention{MaxAge: 24 * time.Hour}})
+	c.Assert(err, check.IsNil)
+	policy, err = ForApp("myapp", "pool1")
+	c.Assert(err, check.IsNil)
+	c.Assert(policy, check.DeepEquals, &EffectivePolicy{LogRetention: appTypes.LogRetention{MaxAge: 24 * time.Hour}, Source: SourcePool})
+	err = Set(&Policy{Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}, LogRetention: appTypes.LogRetention{MaxAge: 90 * 24 * time.Hour}})
+	c.Assert(err, check.IsNil)
+	policy, err = ForApp("myapp", "pool1")
+	c.Assert(err, check.IsNil)
+	c.Assert(policy, check.DeepEquals, &EffectivePolicy{LogRetention: appTypes.LogRetention{MaxAge: 90 * 24 * time.Hour}, Source: SourceApp})
+	policy, err = ForApp("otherapp", "pool1")
+	c.Assert(err, check.IsNil)
+	c.Assert(policy.Source, check.Equals, SourcePool)
+}
@@ -7,6 +7,7 @@ package applog
 import (
 	"github.com/pkg/errors"
 	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/servicemanager"
 	appTypes "github.com/tsuru/tsuru/types/app"
 )
 
@@ -22,8 +23,14 @@ func AppLogService() (appTypes.AppLogService, error) {
 		svc, err = storageAppLogService()
 	case "memory":
 		svc, err = aggregatorAppLogService()
+	case "file":
+		svc, err = fileAppLogService()
+	case "elasticsearch":
+		svc, err = elasticsearchAppLogService()
+	case "loki":
+		svc, err = lokiAppLogService()
 	default:
-		return nil, errors.New(`invalid app log service, valid values are: "storage" or "memory"`)
+		return nil, errors.New(`invalid app log service, valid values are: "storage", "memory", "file", "elasticsearch" or "loki"`)
 	}
 	if err != nil {
 		return nil, err
@@ -31,3 +38,28 @@ func AppLogService() (appTypes.AppLogService, error) {
 	return svc, nil
 
 }
+
+// ValidateInstances checks whether the configured app log service can be
+// used by the live tsuru api instances. The file service keeps logs in the
+// local disk of the instance receiving them, so it only supports a single
+// instance and fails if any other instance is alive.
+func ValidateInstances() error {
+	appLogSvc, _ := config.GetString("log:app-log-service")
+	if appLogSvc != "file" {
+		return nil
+	}
+	current, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return err
+	}
+	instances, err := servicemanager.InstanceTracker.LiveInstances()
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if instance.Name != current.Name {
+			return errors.Errorf(`the "file" app log service only supports a single tsuru api instance, found live instance %q`, instance.Name)
+		}
+	}
+	return nil
+}
@@ -29,16 +29,44 @@ func storageAppLogService() (appTypes.AppLogService, error) {
 			return nil, err
 		}
 	}
+	return newStorageLogService(dbDriver.AppLogStorage), nil
+}
+
+func fileAppLogService() (appTypes.AppLogService, error) {
+	storage, err := newFileLogStorage()
+	if err != nil {
+		return nil, err
+	}
+	return newStorageLogService(storage), nil
+}
+
+func elasticsearchAppLogService() (appTypes.AppLogService, error) {
+	storage, err := newElasticsearchLogStorage()
+	if err != nil {
+		return nil, err
+	}
+	return newStorageLogService(storage), nil
+}
+
+func lokiAppLogService() (appTypes.AppLogService, error) {
+	storage, err := newLokiLogStorage()
+	if err != nil {
+		return nil, err
+	}
+	return newStorageLogService(storage), nil
+}
+
+func newStorageLogService(storage appTypes.AppLogStorage) *storageLogService {
 	queueSize, _ := config.GetInt("server:app-log-buffer-size")
 	if queueSize == 0 {
 		queueSize = 500000
 	}
 	s := &storageLogService{
-		dispatcher: newlogDispatcher(queueSize, dbDriver.AppLogStorage),
-		storage:    dbDriver.AppLogStorage,
+		dispatcher: newlogDispatcher(queueSize, storage),
+		storage:    storage,
 	}
 	shutdown.Register(s)
-	return s, nil
+	return s
 }
 
 func (s *storageLogService) Enqueue(entry *appTypes.Applog) error {
@@ -70,7 +98,23 @@ func (s *storageLogService) List(filters appTypes.ListLogArgs) ([]appTypes.Applo
 	if filters.Limit < 0 {
 		return []appTypes.Applog{}, nil
 	}
-	return s.storage.List(filters)
+	return listPage(filters, s.storage.List)
+}
+
+// Prune removes the logs exceeding the retention policy, if supported by the
+// underlying storage.
+func (s *storageLogService) Prune(appName string, retention appTypes.LogRetention) error {
+	if pruner, ok := s.storage.(appTypes.AppLogPruner); ok {
+		return pruner.Prune(appName, retention)
+	}
+	return nil
+}
+
+// PrunesLocalLogs returns whether the underlying storage keeps the logs in
+// each instance.
+func (s *storageLogService) PrunesLocalLogs() bool {
+	pruner, ok := s.storage.(appTypes.AppLogLocalPruner)
+	return ok && pruner.PrunesLocalLogs()
 }
 
 func (s *storageLogService) Watch(appName, source, unit string, t auth.Token) (appTypes.LogWatcher, error) {
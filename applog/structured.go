@@ -0,0 +1,210 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tsuru/config"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	maxStructuredFields    = 32
+	maxStructuredValueSize = 1024
+)
+
+var (
+	levelFieldAliases   = []string{"level", "lvl", "severity", "loglevel", "log_level"}
+	traceIDFieldAliases = []string{"trace_id", "traceid", "trace", "x_b3_traceid"}
+)
+
+func structuredParsingEnabled() bool {
+	enabled, _ := config.GetBool("log:parse-structured-messages")
+	return enabled
+}
+
+// parseStructuredLog fills the Fields of entries whose message is a JSON
+// object or a sequence of logfmt key=value pairs. Field names are sanitized
+// to contain only letters, digits and underscores, and the level and trace
+// id fields are stored with their canonical names.
+func parseStructuredLog(entry *appTypes.Applog) {
+	raw := parseJSONFields(entry.Message)
+	if raw == nil {
+		raw = parseLogfmtFields(entry.Message)
+	}
+	if len(raw) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make(map[string]string, len(raw))
+	for _, k := range keys {
+		key := sanitizeFieldName(k)
+		if key == "" {
+			continue
+		}
+		value := raw[k]
+		switch {
+		case hasAlias(levelFieldAliases, key):
+			key = appTypes.LogFieldLevel
+			value = appTypes.NormalizeLogLevel(value)
+		case hasAlias(traceIDFieldAliases, key):
+			key = appTypes.LogFieldTraceID
+		case len(fields) >= maxStructuredFields:
+			continue
+		}
+		fields[key] = truncateValue(value)
+	}
+	if len(fields) > 0 {
+		entry.Fields = fields
+	}
+}
+
+func hasAlias(aliases []string, key string) bool {
+	key = strings.ToLower(key)
+	for _, alias := range aliases {
+		if key == alias {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeFieldName(name string) string {
+	var buf strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			buf.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				buf.WriteRune('_')
+			}
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune('_')
+		}
+	}
+	return buf.String()
+}
+
+func truncateValue(value string) string {
+	if len(value) <= maxStructuredValueSize {
+		return value
+	}
+	value = value[:maxStructuredValueSize]
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
+
+func parseJSONFields(message string) map[string]string {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, "{") || !strings.HasSuffix(message, "}") {
+		return nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(message))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if decoder.Decode(&data) != nil {
+		return nil
+	}
+	fields := map[string]string{}
+	flattenJSON("", data, fields)
+	return fields
+}
+
+// flattenJSON stores the values of nested objects using the path to them,
+// joined by underscores, as field names.
+func flattenJSON(prefix string, data map[string]interface{}, fields map[string]string) {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "_" + k
+		}
+		switch value := v.(type) {
+		case nil:
+			fields[key] = ""
+		case string:
+			fields[key] = value
+		case json.Number:
+			fields[key] = value.String()
+		case bool:
+			fields[key] = strconv.FormatBool(value)
+		case map[string]interface{}:
+			flattenJSON(key, value, fields)
+		default:
+			data, _ := json.Marshal(value)
+			fields[key] = string(data)
+		}
+	}
+}
+
+// parseLogfmtFields parses messages in the logfmt format, e.g.
+// `level=info msg="request finished" status=200`. Messages are only
+// considered logfmt if they contain at least two pairs and nothing else.
+func parseLogfmtFields(message string) map[string]string {
+	fields := map[string]string{}
+	data := []byte(strings.TrimSpace(message))
+	for len(data) > 0 {
+		eq := bytes.IndexByte(data, '=')
+		if eq <= 0 || bytes.ContainsAny(data[:eq], " \t\"") {
+			return nil
+		}
+		key := string(data[:eq])
+		data = data[eq+1:]
+		var value string
+		if len(data) > 0 && data[0] == '"' {
+			end := closingQuote(data)
+			if end < 0 {
+				return nil
+			}
+			unquoted, err := strconv.Unquote(string(data[:end+1]))
+			if err != nil {
+				return nil
+			}
+			value = unquoted
+			data = data[end+1:]
+			if len(data) > 0 && data[0] != ' ' && data[0] != '\t' {
+				return nil
+			}
+		} else {
+			end := bytes.IndexAny(data, " \t")
+			if end < 0 {
+				end = len(data)
+			}
+			value = string(data[:end])
+			data = data[end:]
+		}
+		fields[key] = value
+		data = bytes.TrimLeft(data, " \t")
+	}
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields
+}
+
+func closingQuote(data []byte) int {
+	for i := 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"context"
+	"strings"
+
+	appTypes "github.com/tsuru/tsuru/types/app"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestParseStructuredLog(c *check.C) {
+	tests := []struct {
+		message  string
+		expected map[string]string
+	}{
+		{`plain text message`, nil},
+		{`{"level":"WARN","msg":"slow request","duration":1.5,"traceId":"abc","http":{"status":500,"path":"/"},"tags":["a"],"ok":false,"err":null}`, map[string]string{
+			"level": "warning", "msg": "slow request", "duration": "1.5", "trace_id": "abc", "http_status": "500", "http_path": "/", "tags": `["a"]`, "ok": "false", "err": "",
+		}},
+		{`{"invalid json`, nil},
+		{`level=error msg="connection refused" addr=10.0.0.1:5432 x-request-id=123`, map[string]string{
+			"level": "error", "msg": "connection refused", "addr": "10.0.0.1:5432", "x_request_id": "123",
+		}},
+		{`lvl=info`, nil},
+		{`request finished status=200 duration=3ms`, nil},
+		{`level=info msg="unterminated`, nil},
+		{`{"1st":"a","a.b":"c","$where":"d"}`, map[string]string{"_1st": "a", "a_b": "c", "_where": "d"}},
+	}
+	for i, tt := range tests {
+		entry := appTypes.Applog{Message: tt.message}
+		parseStructuredLog(&entry)
+		c.Check(entry.Fields, check.DeepEquals, tt.expected, check.Commentf("test %d", i))
+		c.Check(entry.Message, check.Equals, tt.message)
+	}
+}
+
+func (s *S) TestParseStructuredLogLimits(c *check.C) {
+	var pairs []string
+	for i := 0; i < maxStructuredFields+10; i++ {
+		pairs = append(pairs, "k"+strings.Repeat("x", i)+"=v")
+	}
+	pairs = append(pairs, "severity=error", "big="+strings.Repeat("é", maxStructuredValueSize))
+	entry := appTypes.Applog{Message: strings.Join(pairs, " ")}
+	parseStructuredLog(&entry)
+	c.Assert(entry.Fields, check.HasLen, maxStructuredFields+1)
+	c.Assert(entry.Fields["level"], check.Equals, "error")
+	c.Assert(len(entry.Fields["big"]) <= maxStructuredValueSize, check.Equals, true)
+}
+
+func (s *S) TestLogDispatcherParsesStructuredMessages(c *check.C) {
+	storage := &fileLogStorage{path: c.MkDir(), maxSize: 1024 * 1024, maxFiles: 1}
+	dispatcher := newlogDispatcher(10, storage)
+	dispatcher.parseMessages = true
+	err := dispatcher.send(&appTypes.Applog{AppName: "myapp", Message: `{"level":"error","msg":"boom"}`})
+	c.Assert(err, check.IsNil)
+	dispatcher.shutdown(context.Background())
+	logs, err := storage.List(appTypes.ListLogArgs{AppName: "myapp", Level: "error"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Fields, check.DeepEquals, map[string]string{"level": "error", "msg": "boom"})
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package applog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/log"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const defaultPollInterval = time.Second
+
+// pollFunc returns the log entries received since its previous call. It's
+// responsible for keeping track of the position of the last entry returned.
+type pollFunc func() ([]appTypes.Applog, error)
+
+// pollWatcher implements appTypes.LogWatcher for storages unable to push new
+// entries, calling a pollFunc periodically.
+type pollWatcher struct {
+	c        chan appTypes.Applog
+	quit     chan struct{}
+	interval time.Duration
+	poll     pollFunc
+	once     sync.Once
+}
+
+var _ appTypes.LogWatcher = &pollWatcher{}
+
+func newPollWatcher(interval time.Duration, poll pollFunc) *pollWatcher {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	w := &pollWatcher{
+		c:        make(chan appTypes.Applog, watchBufferSize),
+		quit:     make(chan struct{}),
+		interval: interval,
+		poll:     poll,
+	}
+	go w.run()
+	return w
+}
+
+func (w *pollWatcher) run() {
+	defer close(w.c)
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-time.After(w.interval):
+		}
+		logs, err := w.poll()
+		if err != nil {
+			log.Errorf("[log watch] unable to poll logs: %v", err)
+			continue
+		}
+		for _, l := range logs {
+			select {
+			case w.c <- l:
+			case <-w.quit:
+				return
+			}
+		}
+	}
+}
+
+func (w *pollWatcher) Chan() <-chan appTypes.Applog {
+	return w.c
+}
+
+func (w *pollWatcher) Close() {
+	w.once.Do(func() {
+		close(w.quit)
+	})
+}
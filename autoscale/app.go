@@ -0,0 +1,273 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db/lease"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+)
+
+const (
+	// scaleTolerance is the relative distance between a metric value and
+	// its target under which no scaling happens, avoiding flapping.
+	scaleTolerance = 0.1
+
+	appScalerLeaseName = "app-autoscale"
+)
+
+var appMetricsSource MetricsSource
+
+// AppScaler periodically evaluates the autoscale specs of every app, adding
+// or removing units according to the metrics of each process. Only one tsuru
+// api instance scales apps at a time, the leader is elected using a lease
+// stored in the database which is renewed on every run.
+type AppScaler struct {
+	RunInterval time.Duration
+	source      MetricsSource
+	instance    string
+	done        chan bool
+	running     bool
+}
+
+// AppScaleResult is the custom data stored in autoscale events targeting
+// apps, describing the decision taken and the metrics that triggered it.
+type AppScaleResult struct {
+	Spec    appTypes.AutoScaleSpec
+	Metrics map[string]float64
+	Current int
+	Desired int
+	Reason  string
+}
+
+// InitializeApps starts the app units autoscaler if it's enabled in the
+// autoscale:apps config.
+func InitializeApps() error {
+	enabled, _ := config.GetBool("autoscale:apps:enabled")
+	if !enabled {
+		return nil
+	}
+	scaler, err := newAppScaler()
+	if err != nil {
+		return err
+	}
+	shutdown.Register(scaler)
+	scaler.running = true
+	go scaler.run()
+	return nil
+}
+
+func newAppScaler() (*AppScaler, error) {
+	instance, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return nil, err
+	}
+	source := appMetricsSource
+	if source == nil {
+		var err error
+		source, err = newPrometheusSource()
+		if err != nil {
+			return nil, err
+		}
+	}
+	runInterval, _ := config.GetInt("autoscale:apps:run-interval")
+	scaler := &AppScaler{
+		RunInterval: time.Duration(runInterval) * time.Second,
+		source:      source,
+		instance:    instance.Name,
+		done:        make(chan bool),
+	}
+	if scaler.RunInterval == 0 {
+		scaler.RunInterval = time.Minute
+	}
+	return scaler, nil
+}
+
+func (s *AppScaler) run() {
+	for {
+		err := s.runOnce()
+		if err != nil {
+			log.Errorf("[app autoscale] %s", err)
+		}
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.RunInterval):
+		}
+	}
+}
+
+func (s *AppScaler) Shutdown(ctx context.Context) error {
+	if !s.running {
+		return nil
+	}
+	s.done <- true
+	s.running = false
+	return s.lease().Release()
+}
+
+func (s *AppScaler) String() string {
+	return "app auto scale"
+}
+
+func (s *AppScaler) lease() *lease.Lease {
+	return &lease.Lease{Name: appScalerLeaseName, Owner: s.instance, Duration: 3 * s.RunInterval}
+}
+
+func (s *AppScaler) runOnce() (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = errors.Errorf("recovered panic, we can never stop! panic: %v", r)
+		}
+	}()
+	leader, err := s.lease().Acquire()
+	if err != nil {
+		return errors.Wrap(err, "unable to acquire leadership")
+	}
+	if !leader {
+		return nil
+	}
+	apps, err := app.List(&app.Filter{AutoScaled: true})
+	if err != nil {
+		return errors.Wrap(err, "unable to list apps")
+	}
+	for i := range apps {
+		for _, spec := range apps[i].AutoScale {
+			err = s.scaleProcess(&apps[i], spec)
+			if err != nil {
+				log.Errorf("[app autoscale] error scaling app %q process %q: %s", apps[i].Name, spec.Process, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *AppScaler) scaleProcess(a *app.App, spec appTypes.AutoScaleSpec) error {
+	native, err := a.HandlesAutoScale(spec)
+	if err != nil {
+		return err
+	}
+	if native {
+		return nil
+	}
+	units, err := a.Units()
+	if err != nil {
+		return err
+	}
+	var current int
+	for _, u := range units {
+		if u.ProcessName != spec.Process {
+			continue
+		}
+		if u.Status == provision.StatusStopped || u.Status == provision.StatusAsleep {
+			log.Debugf("[app autoscale] skipping app %q process %q, units are %s", a.Name, spec.Process, u.Status)
+			return nil
+		}
+		current++
+	}
+	if current == 0 {
+		return nil
+	}
+	metrics, err := s.source.ProcessMetrics(a.Name, spec.Process)
+	if err != nil {
+		return err
+	}
+	result := desiredUnits(spec, current, metrics)
+	if result.Desired == result.Current {
+		return nil
+	}
+	return runAppScale(a, result)
+}
+
+func runAppScale(a *app.App, result *AppScaleResult) (err error) {
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		InternalKind: EventKind,
+		CustomData:   result,
+		Allowed: event.Allowed(permission.PermAppReadEvents, append(permission.Contexts(permTypes.CtxTeam, a.Teams),
+			permission.Context(permTypes.CtxApp, a.Name),
+			permission.Context(permTypes.CtxPool, a.Pool),
+		)...),
+	})
+	if err != nil {
+		if _, ok := err.(event.ErrEventLocked); ok {
+			log.Debugf("[app autoscale] skipping app %q, already locked: %s", a.Name, err)
+			return nil
+		}
+		return err
+	}
+	defer func() { evt.DoneCustomData(err, result) }()
+	evt.Logf("%s, scaling process %q from %d to %d units", result.Reason, result.Spec.Process, result.Current, result.Desired)
+	if result.Desired > result.Current {
+		return a.AddUnits(uint(result.Desired-result.Current), result.Spec.Process, evt)
+	}
+	return a.RemoveUnits(uint(result.Current-result.Desired), result.Spec.Process, evt)
+}
+
+// desiredUnits calculates the number of units needed for the metrics to
+// reach their targets, in the same fashion as kubernetes' horizontal pod
+// autoscaler: desired = ceil(current * value / target). When more than one
+// target is set the greatest number of units is used.
+func desiredUnits(spec appTypes.AutoScaleSpec, current int, metrics map[string]float64) *AppScaleResult {
+	result := &AppScaleResult{
+		Spec:    spec,
+		Metrics: metrics,
+		Current: current,
+		Desired: current,
+	}
+	targets := map[string]float64{
+		MetricCPU:      float64(spec.TargetCPU),
+		MetricMemory:   float64(spec.TargetMemory),
+		MetricRequests: spec.TargetRequests,
+	}
+	desired := -1
+	for _, metric := range []string{MetricCPU, MetricMemory, MetricRequests} {
+		target := targets[metric]
+		value, ok := metrics[metric]
+		if target <= 0 || !ok {
+			continue
+		}
+		ratio := value / target
+		metricDesired := current
+		if math.Abs(ratio-1) > scaleTolerance {
+			metricDesired = int(math.Ceil(float64(current) * ratio))
+		}
+		if metricDesired > desired {
+			desired = metricDesired
+			result.Reason = fmt.Sprintf("%s %.2f, target %.2f", metric, value, target)
+		}
+	}
+	if desired < 0 {
+		result.Reason = "no metrics available"
+		desired = current
+	}
+	if desired < int(spec.MinUnits) {
+		desired = int(spec.MinUnits)
+		result.Reason = fmt.Sprintf("%s, min units %d", result.Reason, spec.MinUnits)
+	}
+	if desired > int(spec.MaxUnits) {
+		desired = int(spec.MaxUnits)
+		result.Reason = fmt.Sprintf("%s, max units %d", result.Reason, spec.MaxUnits)
+	}
+	if desired < 1 {
+		desired = 1
+	}
+	result.Desired = desired
+	return result
+}
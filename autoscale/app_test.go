@@ -0,0 +1,178 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+type fakeMetricsSource struct {
+	metrics map[string]float64
+	calls   []string
+}
+
+func (f *fakeMetricsSource) ProcessMetrics(appName, process string) (map[string]float64, error) {
+	f.calls = append(f.calls, appName+"/"+process)
+	return f.metrics, nil
+}
+
+func (s *S) TestDesiredUnits(c *check.C) {
+	tests := []struct {
+		spec     appTypes.AutoScaleSpec
+		current  int
+		metrics  map[string]float64
+		expected int
+	}{
+		{appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 10, TargetCPU: 50}, 2, map[string]float64{"cpu": 100}, 4},
+		{appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 10, TargetCPU: 50}, 2, map[string]float64{"cpu": 53}, 2},
+		{appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 10, TargetCPU: 50}, 4, map[string]float64{"cpu": 10}, 1},
+		{appTypes.AutoScaleSpec{MinUnits: 2, MaxUnits: 10, TargetCPU: 50}, 4, map[string]float64{"cpu": 10}, 2},
+		{appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 3, TargetCPU: 50}, 2, map[string]float64{"cpu": 200}, 3},
+		{appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 10, TargetCPU: 50, TargetRequests: 10}, 2, map[string]float64{"cpu": 50, "requests": 30}, 6},
+		{appTypes.AutoScaleSpec{MinUnits: 1, MaxUnits: 10, TargetMemory: 80}, 2, map[string]float64{"cpu": 100}, 2},
+		{appTypes.AutoScaleSpec{MinUnits: 3, MaxUnits: 10, TargetMemory: 80}, 2, nil, 3},
+	}
+	for i, tt := range tests {
+		result := desiredUnits(tt.spec, tt.current, tt.metrics)
+		c.Assert(result.Current, check.Equals, tt.current, check.Commentf("test %d", i))
+		c.Assert(result.Desired, check.Equals, tt.expected, check.Commentf("test %d", i))
+	}
+}
+
+func (s *S) TestAppScalerRunOnce(c *check.C) {
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 50}
+	err := s.conn.Apps().Update(bson.M{"name": "myapp"}, bson.M{"$set": bson.M{"autoscale": []appTypes.AutoScaleSpec{spec}}})
+	c.Assert(err, check.IsNil)
+	_, err = s.p.AddUnitsToNode(s.appInstance, 2, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	source := &fakeMetricsSource{metrics: map[string]float64{MetricCPU: 100}}
+	scaler := &AppScaler{source: source}
+	err = scaler.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(source.calls, check.DeepEquals, []string{"myapp/web"})
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 4)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Kind:   "autoscale",
+		EndCustomData: map[string]interface{}{
+			"current": 2,
+			"desired": 4,
+		},
+		LogMatches: `(?s).*cpu 100.00, target 50.00, scaling process "web" from 2 to 4 units.*`,
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAppScalerRunOnceNotLeader(c *check.C) {
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 50}
+	err := s.conn.Apps().Update(bson.M{"name": "myapp"}, bson.M{"$set": bson.M{"autoscale": []appTypes.AutoScaleSpec{spec}}})
+	c.Assert(err, check.IsNil)
+	_, err = s.p.AddUnitsToNode(s.appInstance, 2, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	other := &AppScaler{instance: "other", RunInterval: time.Minute}
+	leader, err := other.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	source := &fakeMetricsSource{metrics: map[string]float64{MetricCPU: 100}}
+	scaler := &AppScaler{source: source, instance: "instance1"}
+	err = scaler.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(source.calls, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 2)
+}
+
+func (s *S) TestAppScalerRunOnceNoChanges(c *check.C) {
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 50}
+	err := s.conn.Apps().Update(bson.M{"name": "myapp"}, bson.M{"$set": bson.M{"autoscale": []appTypes.AutoScaleSpec{spec}}})
+	c.Assert(err, check.IsNil)
+	_, err = s.p.AddUnitsToNode(s.appInstance, 2, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	source := &fakeMetricsSource{metrics: map[string]float64{MetricCPU: 48}}
+	scaler := &AppScaler{source: source}
+	err = scaler.runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 2)
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+}
+
+func (s *S) TestAppScalerRunOnceStoppedUnits(c *check.C) {
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 1, MaxUnits: 5, TargetCPU: 50}
+	err := s.conn.Apps().Update(bson.M{"name": "myapp"}, bson.M{"$set": bson.M{"autoscale": []appTypes.AutoScaleSpec{spec}}})
+	c.Assert(err, check.IsNil)
+	units, err := s.p.AddUnitsToNode(s.appInstance, 2, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	for _, u := range units {
+		err = s.p.SetUnitStatus(u, provision.StatusStopped)
+		c.Assert(err, check.IsNil)
+	}
+	source := &fakeMetricsSource{metrics: map[string]float64{MetricCPU: 100}}
+	scaler := &AppScaler{source: source}
+	err = scaler.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(source.calls, check.IsNil)
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+}
+
+func (s *S) TestPrometheusSourceProcessMetrics(c *check.C) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, check.Equals, "/api/v1/query")
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+		if query == "empty" {
+			fmt.Fprint(w, `{"status":"success","data":{"result":[]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"success","data":{"result":[{"value":[1500000000,"42.5"]}]}}`)
+	}))
+	defer srv.Close()
+	config.Set("autoscale:apps:prometheus:url", srv.URL)
+	config.Set("autoscale:apps:prometheus:cpu-query", "cpu{app={{.App}},process={{.Process}}}")
+	config.Set("autoscale:apps:prometheus:memory-query", "empty")
+	defer config.Unset("autoscale:apps:prometheus")
+	source, err := newPrometheusSource()
+	c.Assert(err, check.IsNil)
+	metrics, err := source.ProcessMetrics("myapp", "web")
+	c.Assert(err, check.IsNil)
+	c.Assert(metrics, check.DeepEquals, map[string]float64{MetricCPU: 42.5})
+	c.Assert(queries, check.HasLen, 2)
+	c.Assert(queries[0] == "cpu{app=myapp,process=web}" || queries[1] == "cpu{app=myapp,process=web}", check.Equals, true)
+}
+
+func (s *S) TestPrometheusSourceQueryError(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status":"error","error":"parse error"}`)
+	}))
+	defer srv.Close()
+	config.Set("autoscale:apps:prometheus:url", srv.URL)
+	config.Set("autoscale:apps:prometheus:requests-query", "invalid(")
+	defer config.Unset("autoscale:apps:prometheus")
+	source, err := newPrometheusSource()
+	c.Assert(err, check.IsNil)
+	_, err = source.ProcessMetrics("myapp", "web")
+	c.Assert(err, check.ErrorMatches, "unable to query requests metric: prometheus query failed: parse error")
+}
+
+func (s *S) TestNewPrometheusSourceNoURL(c *check.C) {
+	_, err := newPrometheusSource()
+	c.Assert(err, check.ErrorMatches, "config key autoscale:apps:prometheus:url not found")
+}
@@ -29,6 +29,8 @@ import (
 )
 
 const (
+	// EventKind is the kind of the events created when scaling nodes,
+	// targeting a pool, and app units, targeting an app.
 	EventKind = "autoscale"
 
 	lockWaitTimeout = 30 * time.Second
@@ -60,10 +60,13 @@ func toAutoScaleEvent(evt *event.Event) (Event, error) {
 	return autoScaleEvt, nil
 }
 
+// ListAutoScaleEvents lists the node autoscale events, app autoscale events
+// share the same kind but target apps instead of pools.
 func ListAutoScaleEvents(skip, limit int) ([]Event, error) {
 	evts, err := event.List(&event.Filter{
 		Skip:      skip,
 		Limit:     limit,
+		Target:    event.Target{Type: event.TargetTypePool},
 		KindNames: []string{EventKind},
 	})
 	if err != nil {
@@ -52,8 +52,9 @@ func (a *memoryScaler) nodesMemoryData(pool string, nodes []provision.Node) (map
 			if err != nil {
 				return nil, errors.Wrapf(err, "couldn't find container app (%s)", unit.AppName)
 			}
-			data.containersMemory[unit.ID] = a.Plan.Memory
-			data.reserved += a.Plan.Memory
+			memory := a.GetProcessResources(unit.ProcessName).Memory
+			data.containersMemory[unit.ID] = memory
+			data.reserved += memory
 		}
 		data.available = data.maxMemory - data.reserved
 	}
@@ -95,6 +96,17 @@ func (a *memoryScaler) scale(pool string, nodes []provision.Node) (*ScalerResult
 			maxPlanMemory = plan.Memory
 		}
 	}
+	apps, err := app.List(&app.Filter{Pool: pool})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list apps")
+	}
+	for _, a := range apps {
+		for _, process := range a.Processes {
+			if memory := a.GetProcessResources(process.Name).Memory; memory > maxPlanMemory {
+				maxPlanMemory = memory
+			}
+		}
+	}
 	if maxPlanMemory == 0 {
 		var defaultPlan *appTypes.Plan
 		defaultPlan, err = servicemanager.Plan.DefaultPlan()
@@ -0,0 +1,124 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/net"
+)
+
+const (
+	MetricCPU      = "cpu"
+	MetricMemory   = "memory"
+	MetricRequests = "requests"
+)
+
+// MetricsSource provides the current average metric values for the units of
+// an app process. CPU and memory are returned as percentages of the unit
+// limits and requests as requests per second. Metrics not available are
+// absent from the returned map.
+type MetricsSource interface {
+	ProcessMetrics(appName, process string) (map[string]float64, error)
+}
+
+// prometheusSource fetches unit metrics from a Prometheus server, using the
+// queries in the autoscale:apps:prometheus config. Queries are templates
+// receiving the .App and .Process fields and must evaluate to a single
+// value.
+type prometheusSource struct {
+	url     string
+	queries map[string]*template.Template
+	client  *http.Client
+}
+
+func newPrometheusSource() (MetricsSource, error) {
+	prefix := "autoscale:apps:prometheus"
+	address, err := config.GetString(prefix + ":url")
+	if err != nil {
+		return nil, errors.Errorf("config key %s:url not found", prefix)
+	}
+	source := &prometheusSource{
+		url:     strings.TrimRight(address, "/"),
+		queries: map[string]*template.Template{},
+		client:  net.Dial15Full60ClientNoKeepAlive,
+	}
+	for _, metric := range []string{MetricCPU, MetricMemory, MetricRequests} {
+		query, _ := config.GetString(fmt.Sprintf("%s:%s-query", prefix, metric))
+		if query == "" {
+			continue
+		}
+		source.queries[metric], err = template.New(metric).Parse(query)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s query", metric)
+		}
+	}
+	return source, nil
+}
+
+func (s *prometheusSource) ProcessMetrics(appName, process string) (map[string]float64, error) {
+	result := map[string]float64{}
+	for metric, tpl := range s.queries {
+		var buf bytes.Buffer
+		err := tpl.Execute(&buf, struct{ App, Process string }{App: appName, Process: process})
+		if err != nil {
+			return nil, err
+		}
+		value, ok, err := s.query(buf.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to query %s metric", metric)
+		}
+		if ok {
+			result[metric] = value
+		}
+	}
+	return result, nil
+}
+
+type prometheusResponse struct {
+	Status string
+	Error  string
+	Data   struct {
+		Result []struct {
+			Value []interface{}
+		}
+	}
+}
+
+func (s *prometheusSource) query(query string) (float64, bool, error) {
+	params := url.Values{"query": []string{query}, "time": []string{strconv.FormatInt(time.Now().Unix(), 10)}}
+	rsp, err := s.client.Get(s.url + "/api/v1/query?" + params.Encode())
+	if err != nil {
+		return 0, false, err
+	}
+	defer rsp.Body.Close()
+	var data prometheusResponse
+	err = json.NewDecoder(rsp.Body).Decode(&data)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "invalid response from prometheus, status code %d", rsp.StatusCode)
+	}
+	if data.Status != "success" {
+		return 0, false, errors.Errorf("prometheus query failed: %s", data.Error)
+	}
+	if len(data.Data.Result) == 0 || len(data.Data.Result[0].Value) != 2 {
+		return 0, false, nil
+	}
+	rawValue, _ := data.Data.Result[0].Value[1].(string)
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "invalid value from prometheus")
+	}
+	return value, true, nil
+}
@@ -224,10 +224,14 @@ func tsuruYamlToCustomData(yaml *provTypes.TsuruYamlData) map[string]interface{}
 		return nil
 	}
 
-	return map[string]interface{}{
+	data := map[string]interface{}{
 		"healthcheck": yaml.Healthcheck,
 		"hooks":       yaml.Hooks,
 	}
+	if len(yaml.Processes) > 0 {
+		data["process_config"] = yaml.Processes
+	}
+	return data
 }
 
 func runBuildHooks(client provision.BuilderDockerClient, app provision.App, imageID string, evt *event.Event, tsuruYamlData *provTypes.TsuruYamlData) (string, error) {
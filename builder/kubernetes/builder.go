@@ -105,11 +105,15 @@ func tsuruYamlToCustomData(yaml *provTypes.TsuruYamlData) map[string]interface{}
 	if yaml == nil {
 		return nil
 	}
-	return map[string]interface{}{
+	data := map[string]interface{}{
 		"healthcheck": yaml.Healthcheck,
 		"hooks":       yaml.Hooks,
 		"kubernetes":  yaml.Kubernetes,
 	}
+	if len(yaml.Processes) > 0 {
+		data["process_config"] = yaml.Processes
+	}
+	return data
 }
 
 func downloadFromContainer(client provision.BuilderKubeClient, app provision.App, evt *event.Event) (io.ReadCloser, error) {
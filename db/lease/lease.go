@@ -0,0 +1,70 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lease provides leader election for the background tasks running
+// in every tsuru api instance, using leases stored in the database.
+package lease
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/tsuru/db"
+)
+
+const collectionName = "leases"
+
+// Lease is held by a single owner at a time, until it expires or is
+// released. Tasks that must run in only one tsuru api instance acquire the
+// lease before each run and renew it while running for longer than its
+// duration.
+type Lease struct {
+	Name     string
+	Owner    string
+	Duration time.Duration
+}
+
+// Acquire creates or renews the lease for its owner, expiring after
+// Duration. It returns false if the lease is held by another owner and has
+// not expired yet.
+func (l *Lease) Acquire() (bool, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	now := time.Now().UTC()
+	query := bson.M{
+		"_id": l.Name,
+		"$or": []bson.M{
+			{"owner": l.Owner},
+			{"expires": bson.M{"$lt": now}},
+		},
+	}
+	// The upsert fails with a duplicated key if the lease is held by
+	// another owner.
+	_, err = conn.Collection(collectionName).Upsert(query, bson.M{
+		"$set": bson.M{"owner": l.Owner, "expires": now.Add(l.Duration)},
+	})
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release removes the lease if it's held by its owner, allowing other
+// owners to acquire it before it expires.
+func (l *Lease) Release() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Collection(collectionName).Remove(bson.M{"_id": l.Name, "owner": l.Owner})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lease
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/dbtest"
+	check "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+var _ = check.Suite(&S{})
+
+type S struct{}
+
+func (s *S) SetUpSuite(c *check.C) {
+	config.Set("database:url", "127.0.0.1:27017?maxPoolSize=100")
+	config.Set("database:name", "lease_tests")
+}
+
+func (s *S) SetUpTest(c *check.C) {
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	dbtest.ClearAllCollections(conn.Apps().Database)
+}
+
+func (s *S) TearDownSuite(c *check.C) {
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	conn.Apps().Database.DropDatabase()
+}
+
+func (s *S) TestAcquireAndRelease(c *check.C) {
+	l1 := &Lease{Name: "task", Owner: "instance1", Duration: time.Minute}
+	l2 := &Lease{Name: "task", Owner: "instance2", Duration: time.Minute}
+	acquired, err := l1.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, true)
+	acquired, err = l2.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, false)
+	acquired, err = l1.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, true)
+	err = l1.Release()
+	c.Assert(err, check.IsNil)
+	acquired, err = l2.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, true)
+}
+
+func (s *S) TestAcquireExpired(c *check.C) {
+	l1 := &Lease{Name: "task", Owner: "instance1", Duration: -time.Minute}
+	l2 := &Lease{Name: "task", Owner: "instance2", Duration: time.Minute}
+	acquired, err := l1.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, true)
+	acquired, err = l2.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, true)
+	acquired, err = l1.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, false)
+}
+
+func (s *S) TestReleaseNotOwner(c *check.C) {
+	l1 := &Lease{Name: "task", Owner: "instance1", Duration: time.Minute}
+	l2 := &Lease{Name: "task", Owner: "instance2", Duration: time.Minute}
+	acquired, err := l1.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, true)
+	err = l2.Release()
+	c.Assert(err, check.IsNil)
+	acquired, err = l2.Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(acquired, check.Equals, false)
+}
@@ -0,0 +1,317 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package archive periodically removes events older than the retention
+// configured for their kinds, optionally archiving them to compressed files,
+// and strips the logs of old events.
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+)
+
+const (
+	EventKind = "events-archive"
+
+	batchSize = 1000
+)
+
+// Result is stored as the custom data of the events-archive internal event,
+// reporting what was done in a run.
+type Result struct {
+	Removed  []KindCount `json:"removed"`
+	Archived int         `json:"archived"`
+	Stripped int         `json:"stripped"`
+	File     string      `json:"file,omitempty"`
+}
+
+// KindCount is the number of events of a kind removed in a run. Kind names
+// can't be used as keys in the event custom data, as they contain dots.
+type KindCount struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+func (r *Result) noAction() bool {
+	return len(r.Removed) == 0 && r.Stripped == 0
+}
+
+// Archiver periodically applies the events retention. Runs in different
+// tsuru api instances are serialized by the lock of the internal event
+// reporting them.
+type Archiver struct {
+	RunInterval time.Duration
+	Config      *Config
+	done        chan bool
+	running     bool
+}
+
+// Initialize starts the archiver if it's enabled in the event:archive
+// config.
+func Initialize() error {
+	enabled, _ := config.GetBool("event:archive:enabled")
+	if !enabled {
+		return nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	runInterval, _ := config.GetInt("event:archive:run-interval")
+	archiver := &Archiver{
+		RunInterval: time.Duration(runInterval) * time.Second,
+		Config:      cfg,
+		done:        make(chan bool),
+	}
+	if archiver.RunInterval == 0 {
+		archiver.RunInterval = time.Hour
+	}
+	shutdown.Register(archiver)
+	archiver.running = true
+	go archiver.run()
+	return nil
+}
+
+func (a *Archiver) run() {
+	for {
+		err := a.runOnce()
+		if err != nil {
+			log.Errorf("[events archive] %s", err)
+		}
+		select {
+		case <-a.done:
+			return
+		case <-time.After(a.RunInterval):
+		}
+	}
+}
+
+func (a *Archiver) Shutdown(ctx context.Context) error {
+	if !a.running {
+		return nil
+	}
+	a.done <- true
+	a.running = false
+	return nil
+}
+
+func (a *Archiver) String() string {
+	return "events archiver"
+}
+
+func (a *Archiver) runOnce() (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = errors.Errorf("recovered panic, we can never stop! panic: %v", r)
+		}
+	}()
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeGlobal},
+		InternalKind: EventKind,
+		Allowed:      event.Allowed(permission.PermPoolReadEvents),
+	})
+	if err != nil {
+		if _, ok := err.(event.ErrEventLocked); ok {
+			log.Debugf("[events archive] skipping run: event locked")
+			return nil
+		}
+		return errors.Wrap(err, "unable to create event")
+	}
+	result := &Result{}
+	defer func() {
+		if result.noAction() && retErr == nil {
+			evt.Abort()
+			return
+		}
+		evt.DoneCustomData(retErr, result)
+	}()
+	return a.apply(time.Now().UTC(), result)
+}
+
+func (a *Archiver) apply(now time.Time, result *Result) error {
+	kinds, err := event.GetKinds()
+	if err != nil {
+		return errors.Wrap(err, "unable to list event kinds")
+	}
+	var archive *archiveFile
+	if a.Config.Directory != "" {
+		archive = &archiveFile{dir: a.Config.Directory, now: now}
+		defer func() {
+			if archive.file != nil {
+				result.File = archive.file.Name()
+			}
+			if closeErr := archive.Close(); closeErr != nil {
+				log.Errorf("[events archive] unable to close archive file: %v", closeErr)
+			}
+		}()
+	}
+	for _, kind := range kinds {
+		retention := a.Config.Retention(kind.Name)
+		if retention <= 0 {
+			continue
+		}
+		query := bson.M{
+			"kind.name": kind.Name,
+			"running":   false,
+			"starttime": bson.M{"$lt": now.Add(-retention)},
+		}
+		var removed int
+		if archive != nil {
+			removed, err = archiveEvents(query, archive)
+			result.Archived += removed
+		} else {
+			removed, err = removeEvents(query)
+		}
+		if removed > 0 {
+			result.Removed = append(result.Removed, KindCount{Kind: kind.Name, Count: removed})
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to remove events of kind %q", kind.Name)
+		}
+	}
+	if a.Config.StripLogsAfter > 0 {
+		result.Stripped, err = stripLogs(bson.M{
+			"running":   false,
+			"starttime": bson.M{"$lt": now.Add(-a.Config.StripLogsAfter)},
+			"log":       bson.M{"$exists": true},
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to strip event logs")
+		}
+	}
+	return nil
+}
+
+func removeEvents(query bson.M) (int, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	info, err := conn.Events().RemoveAll(query)
+	if err != nil {
+		return 0, err
+	}
+	return info.Removed, nil
+}
+
+// archiveEvents writes the events matching the query to the archive,
+// removing them in batches once written.
+func archiveEvents(query bson.M, archive *archiveFile) (int, error) {
+	var total int
+	for {
+		evts, err := event.List(&event.Filter{
+			Raw:            query,
+			IncludeRemoved: true,
+			Sort:           "starttime",
+			Limit:          batchSize,
+		})
+		if err != nil {
+			return total, err
+		}
+		if len(evts) == 0 {
+			return total, nil
+		}
+		ids := make([]bson.ObjectId, len(evts))
+		for i := range evts {
+			err = archive.Write(&evts[i])
+			if err != nil {
+				return total, err
+			}
+			ids[i] = evts[i].UniqueID
+		}
+		err = archive.Flush()
+		if err != nil {
+			return total, err
+		}
+		removed, err := removeEvents(bson.M{"uniqueid": bson.M{"$in": ids}})
+		total += removed
+		if err != nil {
+			return total, err
+		}
+		if len(evts) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+func stripLogs(query bson.M) (int, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	info, err := conn.Events().UpdateAll(query, bson.M{"$unset": bson.M{"log": ""}})
+	if err != nil {
+		return 0, err
+	}
+	return info.Updated, nil
+}
+
+// archiveFile writes events as gzipped json lines, creating the file on the
+// first write.
+type archiveFile struct {
+	dir     string
+	now     time.Time
+	file    *os.File
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+}
+
+func (f *archiveFile) Write(evt *event.Event) error {
+	if f.file == nil {
+		err := os.MkdirAll(f.dir, 0755)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("events-%s.ndjson.gz", f.now.Format("20060102T150405Z"))
+		f.file, err = os.OpenFile(filepath.Join(f.dir, name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		f.gzip = gzip.NewWriter(f.file)
+		f.encoder = json.NewEncoder(f.gzip)
+	}
+	return f.encoder.Encode(evt)
+}
+
+// Flush makes sure the written events are in the file, so they can be
+// safely removed from the database.
+func (f *archiveFile) Flush() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.gzip.Flush()
+	if err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+func (f *archiveFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.gzip.Close()
+	if err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
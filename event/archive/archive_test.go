@@ -0,0 +1,135 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) newEvent(c *check.C, kind string, age time.Duration) *event.Event {
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		InternalKind: kind,
+		DisableLock:  true,
+		Allowed:      event.Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	evt.Logf("some log for %s", kind)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Events().Update(bson.M{"uniqueid": evt.UniqueID}, bson.M{
+		"$set": bson.M{"starttime": time.Now().UTC().Add(-age)},
+	})
+	c.Assert(err, check.IsNil)
+	return evt
+}
+
+func (s *S) kindCount(c *check.C, kind string) int {
+	n, err := s.conn.Events().Find(bson.M{"kind.name": kind}).Count()
+	c.Assert(err, check.IsNil)
+	return n
+}
+
+func (s *S) TestArchiverRemovesExpiredEvents(c *check.C) {
+	s.newEvent(c, "healer", 40*24*time.Hour)
+	s.newEvent(c, "healer", 10*24*time.Hour)
+	s.newEvent(c, "autoscale", 40*24*time.Hour)
+	s.newEvent(c, "bindsyncer", 400*24*time.Hour)
+	archiver := &Archiver{Config: &Config{
+		Kinds: map[string]time.Duration{
+			"healer":    30 * 24 * time.Hour,
+			"autoscale": 60 * 24 * time.Hour,
+		},
+	}}
+	err := archiver.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.kindCount(c, "healer"), check.Equals, 1)
+	c.Assert(s.kindCount(c, "autoscale"), check.Equals, 1)
+	c.Assert(s.kindCount(c, "bindsyncer"), check.Equals, 1)
+	evts, err := event.List(&event.Filter{KindNames: []string{EventKind}})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	var result Result
+	err = evts[0].EndData(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.DeepEquals, Result{
+		Removed: []KindCount{{Kind: "healer", Count: 1}},
+	})
+}
+
+func (s *S) TestArchiverNothingToDo(c *check.C) {
+	s.newEvent(c, "healer", time.Hour)
+	archiver := &Archiver{Config: &Config{Default: 24 * time.Hour}}
+	err := archiver.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.kindCount(c, "healer"), check.Equals, 1)
+	c.Assert(s.kindCount(c, EventKind), check.Equals, 0)
+}
+
+func (s *S) TestArchiverArchivesToDirectory(c *check.C) {
+	dir, err := ioutil.TempDir("", "events-archive")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	old := s.newEvent(c, "healer", 40*24*time.Hour)
+	s.newEvent(c, "healer", time.Hour)
+	archiver := &Archiver{Config: &Config{
+		Default:   30 * 24 * time.Hour,
+		Directory: dir,
+	}}
+	err = archiver.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.kindCount(c, "healer"), check.Equals, 1)
+	files, err := filepath.Glob(filepath.Join(dir, "events-*.ndjson.gz"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+	f, err := os.Open(files[0])
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	c.Assert(err, check.IsNil)
+	decoder := json.NewDecoder(reader)
+	var archived []map[string]interface{}
+	for decoder.More() {
+		var data map[string]interface{}
+		err = decoder.Decode(&data)
+		c.Assert(err, check.IsNil)
+		archived = append(archived, data)
+	}
+	c.Assert(archived, check.HasLen, 1)
+	c.Assert(archived[0]["UniqueID"], check.Equals, old.UniqueID.Hex())
+	c.Assert(archived[0]["Log"], check.Equals, "some log for healer\n")
+	evts, err := event.List(&event.Filter{KindNames: []string{EventKind}})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	var result Result
+	err = evts[0].EndData(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.Archived, check.Equals, 1)
+	c.Assert(result.File, check.Equals, files[0])
+}
+
+func (s *S) TestArchiverStripsLogs(c *check.C) {
+	old := s.newEvent(c, "healer", 40*24*time.Hour)
+	recent := s.newEvent(c, "healer", time.Hour)
+	archiver := &Archiver{Config: &Config{StripLogsAfter: 30 * 24 * time.Hour}}
+	err := archiver.runOnce()
+	c.Assert(err, check.IsNil)
+	evt, err := event.GetByID(old.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Equals, "")
+	evt, err = event.GetByID(recent.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Equals, "some log for healer\n")
+}
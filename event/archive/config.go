@@ -0,0 +1,96 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+)
+
+// Config holds the retention of events, read from the event:archive config.
+type Config struct {
+	// Default is the retention of events whose kind has no specific
+	// retention. Zero means events are kept forever.
+	Default time.Duration
+	// Kinds maps kind names, or prefixes of kind names like "app" or
+	// "app.update", to their retention.
+	Kinds map[string]time.Duration
+	// StripLogsAfter is the age after which the logs of events are
+	// removed, keeping the rest of the event. Zero means logs are kept.
+	StripLogsAfter time.Duration
+	// Directory, if set, is where expired events are archived as gzipped
+	// json lines files before being removed.
+	Directory string
+}
+
+// parseDuration parses a duration in the format accepted by
+// time.ParseDuration, also accepting a number of days, like 365d.
+func parseDuration(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+func durationConfig(key string) (time.Duration, error) {
+	value, err := config.Get(key)
+	if err != nil {
+		return 0, nil
+	}
+	d, err := parseDuration(fmt.Sprint(value))
+	if err != nil {
+		return 0, errors.Errorf("invalid duration in %s: %v", key, value)
+	}
+	return d, nil
+}
+
+func loadConfig() (*Config, error) {
+	var cfg Config
+	var err error
+	cfg.Default, err = durationConfig("event:archive:retention:default")
+	if err != nil {
+		return nil, err
+	}
+	cfg.StripLogsAfter, err = durationConfig("event:archive:strip-logs-after")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Directory, _ = config.GetString("event:archive:directory")
+	kinds, _ := config.Get("event:archive:retention:kinds")
+	kindsMap, _ := kinds.(map[interface{}]interface{})
+	cfg.Kinds = make(map[string]time.Duration, len(kindsMap))
+	for k, v := range kindsMap {
+		d, err := parseDuration(fmt.Sprint(v))
+		if err != nil {
+			return nil, errors.Errorf("invalid duration in event:archive:retention:kinds:%v: %v", k, v)
+		}
+		cfg.Kinds[fmt.Sprint(k)] = d
+	}
+	return &cfg, nil
+}
+
+// Retention returns the retention of events of a kind, using the most
+// specific kind configured.
+func (c *Config) Retention(kindName string) time.Duration {
+	for name := kindName; name != ""; {
+		if d, ok := c.Kinds[name]; ok {
+			return d
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.Default
+}
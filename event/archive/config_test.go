@@ -0,0 +1,59 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestLoadConfig(c *check.C) {
+	config.Set("event:archive:retention:default", "90d")
+	config.Set("event:archive:retention:kinds", map[interface{}]interface{}{
+		"app.deploy": "365d",
+		"healer":     "720h",
+	})
+	config.Set("event:archive:strip-logs-after", "30d")
+	config.Set("event:archive:directory", "/var/lib/tsuru/events")
+	defer config.Unset("event:archive")
+	cfg, err := loadConfig()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg, check.DeepEquals, &Config{
+		Default: 90 * 24 * time.Hour,
+		Kinds: map[string]time.Duration{
+			"app.deploy": 365 * 24 * time.Hour,
+			"healer":     720 * time.Hour,
+		},
+		StripLogsAfter: 30 * 24 * time.Hour,
+		Directory:      "/var/lib/tsuru/events",
+	})
+}
+
+func (s *S) TestLoadConfigInvalid(c *check.C) {
+	config.Set("event:archive:retention:kinds", map[interface{}]interface{}{
+		"healer": "a month",
+	})
+	defer config.Unset("event:archive")
+	_, err := loadConfig()
+	c.Assert(err, check.ErrorMatches, `invalid duration in event:archive:retention:kinds:healer: a month`)
+}
+
+func (s *S) TestConfigRetention(c *check.C) {
+	cfg := Config{
+		Default: time.Hour,
+		Kinds: map[string]time.Duration{
+			"app":        2 * time.Hour,
+			"app.deploy": 3 * time.Hour,
+			"healer":     4 * time.Hour,
+		},
+	}
+	c.Assert(cfg.Retention("app.deploy"), check.Equals, 3*time.Hour)
+	c.Assert(cfg.Retention("app.update.env.set"), check.Equals, 2*time.Hour)
+	c.Assert(cfg.Retention("healer"), check.Equals, 4*time.Hour)
+	c.Assert(cfg.Retention("autoscale"), check.Equals, time.Hour)
+	c.Assert(cfg.Retention("application.create"), check.Equals, time.Hour)
+}
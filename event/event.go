@@ -110,6 +110,8 @@ var (
 	TargetTypeCluster         = TargetType("cluster")
 	TargetTypeVolume          = TargetType("volume")
 	TargetTypeWebhook         = TargetType("webhook")
+	TargetTypeAppJob          = TargetType("app-job")
+	TargetTypeRouter          = TargetType("router")
 )
 
 const (
@@ -271,6 +273,10 @@ func GetTargetType(t string) (TargetType, error) {
 		return TargetTypeVolume, nil
 	case "webhook":
 		return TargetTypeWebhook, nil
+	case "app-job":
+		return TargetTypeAppJob, nil
+	case "router":
+		return TargetTypeRouter, nil
 	}
 	return TargetType(""), ErrInvalidTargetType
 }
@@ -0,0 +1,326 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	tsuruRedis "github.com/tsuru/tsuru/redis"
+	authTypes "github.com/tsuru/tsuru/types/auth"
+	eventTypes "github.com/tsuru/tsuru/types/event"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+	"github.com/tsuru/tsuru/validation"
+)
+
+// notifier delivers events to a type of webhook. The event filter, the
+// retries and the delivery history are handled by the webhook service,
+// regardless of the notifier.
+type notifier interface {
+	// validate checks the type specific settings of the webhook.
+	validate(w eventTypes.Webhook) error
+	// payload returns the content delivered for the event. It's called
+	// once, before the first delivery attempt, and may also change the
+	// settings used by send, like the request headers.
+	payload(hook *eventTypes.Webhook, evt *event.Event) ([]byte, error)
+	// send makes a single delivery attempt, filling the delivery with the
+	// response. The returned bool indicates whether a failure is temporary.
+	send(req *webhookRequest, delivery *eventTypes.WebhookDelivery) (bool, error)
+}
+
+var notifiers = map[string]notifier{
+	"":                          httpNotifier{},
+	eventTypes.WebhookTypeHTTP:  httpNotifier{},
+	eventTypes.WebhookTypeSlack: slackNotifier{},
+	eventTypes.WebhookTypeEmail: emailNotifier{},
+	eventTypes.WebhookTypeQueue: &queueNotifier{},
+}
+
+func notifierFor(webhookType string) (notifier, error) {
+	n, ok := notifiers[webhookType]
+	if !ok {
+		return nil, &tsuruErrors.ValidationError{
+			Message: fmt.Sprintf("invalid webhook type %q, must be one of: %s", webhookType, strings.Join([]string{
+				eventTypes.WebhookTypeHTTP,
+				eventTypes.WebhookTypeSlack,
+				eventTypes.WebhookTypeEmail,
+				eventTypes.WebhookTypeQueue,
+			}, ", ")),
+		}
+	}
+	return n, nil
+}
+
+// renderTemplate executes text as a template with the event as data. Text
+// which isn't a valid template is returned as is.
+func renderTemplate(name, text string, evt *event.Event) (string, error) {
+	tpl, err := template.New(name).Parse(text)
+	if err != nil {
+		log.Errorf("[webhooks] unable to parse %q as template, using raw string: %v", name, err)
+		return text, nil
+	}
+	var buf bytes.Buffer
+	err = tpl.Execute(&buf, evt)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func eventStatus(evt *event.Event) string {
+	if evt.Error != "" {
+		return "failed"
+	}
+	return "succeeded"
+}
+
+// eventSummary returns a one line description of the event, used by
+// notifiers meant to be read by people.
+func eventSummary(evt *event.Event) string {
+	return fmt.Sprintf("%s on %s %q by %s %s", evt.Kind.Name, evt.Target.Type, evt.Target.Value, evt.Owner.Name, eventStatus(evt))
+}
+
+// slackNotifier sends events to Slack compatible incoming webhooks. The
+// Body of the webhook, if set, is used as the message text.
+type slackNotifier struct {
+	httpNotifier
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Fallback string       `json:"fallback"`
+	Color    string       `json:"color"`
+	Fields   []slackField `json:"fields"`
+	Ts       int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (slackNotifier) payload(hook *eventTypes.Webhook, evt *event.Event) ([]byte, error) {
+	text := eventSummary(evt)
+	if hook.Body != "" {
+		var err error
+		text, err = renderTemplate(hook.Name, hook.Body, evt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	attachment := slackAttachment{
+		Fallback: text,
+		Color:    "good",
+		Fields: []slackField{
+			{Title: "Kind", Value: evt.Kind.Name, Short: true},
+			{Title: "Target", Value: fmt.Sprintf("%s: %s", evt.Target.Type, evt.Target.Value), Short: true},
+			{Title: "Owner", Value: evt.Owner.Name, Short: true},
+			{Title: "Duration", Value: evt.EndTime.Sub(evt.StartTime).String(), Short: true},
+		},
+		Ts: evt.StartTime.Unix(),
+	}
+	if evt.Error != "" {
+		attachment.Color = "danger"
+		attachment.Fields = append(attachment.Fields, slackField{Title: "Error", Value: evt.Error})
+	}
+	hook.Method = http.MethodPost
+	hook.Headers.Set("Content-Type", "application/json")
+	return json.Marshal(slackMessage{Text: text, Attachments: []slackAttachment{attachment}})
+}
+
+// emailNotifier sends events by email, using the smtp settings also used by
+// the native auth scheme. Recipients must be members of the team owning the
+// webhook, i.e. users with a role in the context of the team, or be allowed
+// in the webhooks:email:allowed-recipients config, so that tsuru can't be
+// used to send emails to arbitrary addresses.
+type emailNotifier struct{}
+
+func (emailNotifier) validate(w eventTypes.Webhook) error {
+	if w.Email == nil || len(w.Email.To) == 0 {
+		return &tsuruErrors.ValidationError{Message: "webhook email recipients must not be empty"}
+	}
+	allowed, _ := config.GetList("webhooks:email:allowed-recipients")
+	for _, to := range w.Email.To {
+		if !validation.ValidateEmail(to) {
+			return &tsuruErrors.ValidationError{Message: fmt.Sprintf("invalid webhook email recipient %q", to)}
+		}
+		if recipientAllowed(to, allowed) {
+			continue
+		}
+		member, err := isTeamMember(to, w.TeamOwner)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return &tsuruErrors.ValidationError{
+				Message: fmt.Sprintf("webhook email recipient %q must be a member of the team %q or be allowed by the webhooks:email:allowed-recipients config", to, w.TeamOwner),
+			}
+		}
+	}
+	return nil
+}
+
+// recipientAllowed returns whether the address matches one of the allowed
+// addresses or domains, prefixed by "@".
+func recipientAllowed(to string, allowed []string) bool {
+	to = strings.ToLower(to)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if to == a || (strings.HasPrefix(a, "@") && strings.HasSuffix(to, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTeamMember(email, team string) (bool, error) {
+	if team == "" {
+		return false, nil
+	}
+	u, err := auth.GetUserByEmail(email)
+	if err == authTypes.ErrUserNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	perms, err := u.Permissions()
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p.Context.CtxType == permTypes.CtxTeam && p.Context.Value == team {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (n emailNotifier) payload(hook *eventTypes.Webhook, evt *event.Event) ([]byte, error) {
+	// Recipients are checked again as the team members may have changed.
+	err := n.validate(*hook)
+	if err != nil {
+		return nil, err
+	}
+	subject := "[tsuru] " + eventSummary(evt)
+	if hook.Email.Subject != "" {
+		subject, err = renderTemplate(hook.Name+"-subject", hook.Email.Subject, evt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	body := fmt.Sprintf("%s\n\nEvent: %s\nStart: %s\nEnd: %s\n", eventSummary(evt), evt.UniqueID.Hex(), evt.StartTime, evt.EndTime)
+	if evt.Error != "" {
+		body += fmt.Sprintf("Error: %s\n", evt.Error)
+	}
+	if hook.Body != "" {
+		body, err = renderTemplate(hook.Name, hook.Body, evt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	from, _ := config.GetString("smtp:user")
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(hook.Email.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(body)
+	return buf.Bytes(), nil
+}
+
+func (emailNotifier) send(req *webhookRequest, delivery *eventTypes.WebhookDelivery) (bool, error) {
+	server, _ := config.GetString("smtp:server")
+	if server == "" {
+		return false, errors.New(`Setting "smtp:server" is not defined`)
+	}
+	if !strings.Contains(server, ":") {
+		server += ":25"
+	}
+	user, err := config.GetString("smtp:user")
+	if err != nil {
+		return false, errors.New(`Setting "smtp:user" is not defined`)
+	}
+	var auth smtp.Auth
+	if password, _ := config.GetString("smtp:password"); password != "" {
+		host, _, _ := net.SplitHostPort(server)
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	err = smtp.SendMail(server, auth, user, req.hook.Email.To, req.body)
+	if err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// queueNotifier publishes the events, encoded as json, to a message queue.
+// Queues are redis lists, configured in the webhooks:queue config, which
+// consumers may read with BLPOP.
+type queueNotifier struct {
+	sync.Mutex
+	client tsuruRedis.Client
+}
+
+func (*queueNotifier) validate(w eventTypes.Webhook) error {
+	if w.Queue == nil || w.Queue.Name == "" {
+		return &tsuruErrors.ValidationError{Message: "webhook queue name must not be empty"}
+	}
+	return nil
+}
+
+func (*queueNotifier) payload(hook *eventTypes.Webhook, evt *event.Event) ([]byte, error) {
+	if hook.Body != "" {
+		body, err := renderTemplate(hook.Name, hook.Body, evt)
+		return []byte(body), err
+	}
+	return json.Marshal(evt)
+}
+
+func (n *queueNotifier) getClient() (tsuruRedis.Client, error) {
+	n.Lock()
+	defer n.Unlock()
+	if n.client != nil {
+		return n.client, nil
+	}
+	client, err := tsuruRedis.NewRedis("webhooks:queue")
+	if err != nil {
+		return nil, err
+	}
+	n.client = client
+	return client, nil
+}
+
+func (n *queueNotifier) send(req *webhookRequest, delivery *eventTypes.WebhookDelivery) (bool, error) {
+	client, err := n.getClient()
+	if err != nil {
+		return err != tsuruRedis.ErrNoRedisConfig, err
+	}
+	length, err := client.RPush(req.hook.Queue.Name, string(req.body)).Result()
+	if err != nil {
+		return true, err
+	}
+	delivery.Response = fmt.Sprintf("queue length: %d", length)
+	return false, nil
+}
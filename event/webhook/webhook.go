@@ -7,6 +7,9 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,7 +17,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"text/template"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,6 +37,21 @@ var (
 
 	chanBufferSize   = 1000
 	defaultUserAgent = "tsuru-webhook-client/1.0"
+
+	// retryBaseInterval is the time to wait before the first retry of a
+	// failed delivery, doubled on each following retry up to
+	// retryMaxInterval.
+	retryBaseInterval = 5 * time.Second
+	retryMaxInterval  = 5 * time.Minute
+)
+
+const (
+	signatureHeader = "X-Tsuru-Signature"
+
+	defaultMaxAttempts  = 5
+	maxAttempts         = 20
+	maxResponseSize     = 1024
+	defaultDeliveryList = 50
 )
 
 func WebhookService() (eventTypes.WebhookService, error) {
@@ -64,6 +82,7 @@ type webhookService struct {
 	evtCh   chan string
 	quitCh  chan struct{}
 	doneCh  chan struct{}
+	retries sync.WaitGroup
 
 	webhooksLatency prometheus.Histogram
 	webhooksTotal   prometheus.Counter
@@ -110,8 +129,14 @@ func (s *webhookService) Shutdown(ctx context.Context) error {
 	prometheus.Unregister(s.webhooksError)
 	prometheus.Unregister(s.webhooksQueue)
 	close(s.quitCh)
+	retriesDone := make(chan struct{})
+	go func() {
+		<-s.doneCh
+		s.retries.Wait()
+		close(retriesDone)
+	}()
 	select {
-	case <-s.doneCh:
+	case <-retriesDone:
 	case <-ctx.Done():
 		return ctx.Err()
 	}
@@ -170,17 +195,11 @@ func (s *webhookService) handleEvent(evtID string) error {
 
 func webhookBody(hook *eventTypes.Webhook, evt *event.Event) (io.Reader, error) {
 	if hook.Body != "" {
-		tpl, err := template.New(hook.Name).Parse(hook.Body)
-		if err != nil {
-			log.Errorf("[webhooks] unable to parse hook body for %q as template, using raw string: %v", hook.Name, err)
-			return strings.NewReader(hook.Body), nil
-		}
-		buf := bytes.NewBuffer(nil)
-		err = tpl.Execute(buf, evt)
+		body, err := renderTemplate(hook.Name, hook.Body, evt)
 		if err != nil {
 			return nil, err
 		}
-		return bytes.NewReader(buf.Bytes()), nil
+		return strings.NewReader(body), nil
 	}
 	if hook.Method != http.MethodPost &&
 		hook.Method != http.MethodPut &&
@@ -195,54 +214,189 @@ func webhookBody(hook *eventTypes.Webhook, evt *event.Event) (io.Reader, error)
 	return bytes.NewReader(data), nil
 }
 
-func (s *webhookService) doHook(hook eventTypes.Webhook, evt *event.Event) (err error) {
+// webhookRequest holds the data needed to deliver an event to a webhook,
+// computed once and reused by every delivery attempt.
+type webhookRequest struct {
+	hook       eventTypes.Webhook
+	notifier   notifier
+	eventID    string
+	body       []byte
+	redelivery bool
+}
+
+func newWebhookRequest(hook eventTypes.Webhook, evt *event.Event) (*webhookRequest, error) {
+	hook.Method = strings.ToUpper(hook.Method)
+	if hook.Method == "" {
+		hook.Method = http.MethodPost
+	}
+	if hook.Headers == nil {
+		hook.Headers = http.Header{}
+	}
+	n, err := notifierFor(hook.Type)
+	if err != nil {
+		return nil, err
+	}
+	body, err := n.payload(&hook, evt)
+	if err != nil {
+		return nil, err
+	}
+	req := &webhookRequest{hook: hook, notifier: n, eventID: evt.UniqueID.Hex(), body: body}
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(req.body)
+		req.hook.Headers.Set(signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return req, nil
+}
+
+func (r *webhookRequest) maxAttempts() int {
+	if r.hook.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return r.hook.MaxAttempts
+}
+
+// retryInterval returns the time to wait before the given attempt, growing
+// exponentially with the number of attempts already made.
+func retryInterval(attempt int) time.Duration {
+	interval := retryBaseInterval
+	for i := 2; i < attempt; i++ {
+		interval *= 2
+		if interval >= retryMaxInterval {
+			return retryMaxInterval
+		}
+	}
+	return interval
+}
+
+func (s *webhookService) doHook(hook eventTypes.Webhook, evt *event.Event) error {
+	req, err := newWebhookRequest(hook, evt)
+	if err != nil {
+		return err
+	}
+	return s.deliver(req, 1)
+}
+
+// deliver makes a delivery attempt, scheduling a new one in background if it
+// fails with a temporary error and the hook has attempts left. Scheduled
+// attempts are not persisted, they're dropped on shutdown.
+func (s *webhookService) deliver(req *webhookRequest, attempt int) error {
+	retry, err := s.attempt(req, attempt)
+	if err == nil || !retry || attempt >= req.maxAttempts() {
+		return err
+	}
+	s.retries.Add(1)
+	go func() {
+		defer s.retries.Done()
+		select {
+		case <-time.After(retryInterval(attempt + 1)):
+		case <-s.quitCh:
+			return
+		}
+		retryErr := s.deliver(req, attempt+1)
+		if retryErr != nil {
+			log.Errorf("[webhooks] error calling webhook %q for event %q (attempt %d): %v", req.hook.Name, req.eventID, attempt+1, retryErr)
+		}
+	}()
+	return err
+}
+
+// attempt delivers the event to the webhook once and records the delivery. The returned bool
+// indicates whether a failed attempt may succeed if retried.
+func (s *webhookService) attempt(req *webhookRequest, attempt int) (retry bool, err error) {
+	delivery := eventTypes.WebhookDelivery{
+		Webhook:    req.hook.Name,
+		EventID:    req.eventID,
+		Attempt:    attempt,
+		Redelivery: req.redelivery,
+		Timestamp:  time.Now().UTC(),
+	}
 	defer func() {
 		s.webhooksTotal.Inc()
 		if err != nil {
 			s.webhooksError.Inc()
+			delivery.Error = err.Error()
+		}
+		delivery.Success = err == nil
+		if insertErr := s.storage.InsertDelivery(delivery); insertErr != nil {
+			log.Errorf("[webhooks] unable to store delivery of event %q to webhook %q: %v", req.eventID, req.hook.Name, insertErr)
 		}
 	}()
-	hook.Method = strings.ToUpper(hook.Method)
-	if hook.Method == "" {
-		hook.Method = http.MethodPost
+	start := time.Now()
+	retry, err = req.notifier.send(req, &delivery)
+	delivery.Latency = time.Since(start)
+	s.webhooksLatency.Observe(delivery.Latency.Seconds())
+	return retry, err
+}
+
+// httpNotifier delivers events to plain HTTP webhooks.
+type httpNotifier struct{}
+
+func (httpNotifier) payload(hook *eventTypes.Webhook, evt *event.Event) ([]byte, error) {
+	body, err := webhookBody(hook, evt)
+	if err != nil || body == nil {
+		return nil, err
 	}
-	body, err := webhookBody(&hook, evt)
-	if err != nil {
-		return err
+	return ioutil.ReadAll(body)
+}
+
+func (httpNotifier) send(req *webhookRequest, delivery *eventTypes.WebhookDelivery) (retry bool, err error) {
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
 	}
-	req, err := http.NewRequest(hook.Method, hook.URL, body)
+	httpReq, err := http.NewRequest(req.hook.Method, req.hook.URL, body)
 	if err != nil {
-		return err
+		return false, err
+	}
+	httpReq.Header = make(http.Header, len(req.hook.Headers))
+	for k, v := range req.hook.Headers {
+		httpReq.Header[k] = v
 	}
-	req.Header = hook.Headers
-	if req.UserAgent() == "" {
-		req.Header.Set("User-Agent", defaultUserAgent)
+	if httpReq.UserAgent() == "" {
+		httpReq.Header.Set("User-Agent", defaultUserAgent)
 	}
 	client := tsuruNet.Dial15Full60ClientNoKeepAlive
-	if hook.Insecure {
+	if req.hook.Insecure {
 		client = &tsuruNet.Dial15Full60ClientNoKeepAliveInsecure
 	}
-	if hook.ProxyURL != "" {
-		client, err = tsuruNet.WithProxy(*client, hook.ProxyURL)
+	if req.hook.ProxyURL != "" {
+		client, err = tsuruNet.WithProxy(*client, req.hook.ProxyURL)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
-	reqStart := time.Now()
-	rsp, err := client.Do(req)
-	s.webhooksLatency.Observe(time.Since(reqStart).Seconds())
+	rsp, err := client.Do(httpReq)
 	if err != nil {
-		return err
+		return true, err
 	}
 	defer rsp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, maxResponseSize))
+	delivery.StatusCode = rsp.StatusCode
+	delivery.Response = string(data)
 	if rsp.StatusCode < 200 || rsp.StatusCode >= 400 {
-		data, _ := ioutil.ReadAll(rsp.Body)
-		return errors.Errorf("invalid status code calling hook: %d: %s", rsp.StatusCode, string(data))
+		retry = rsp.StatusCode >= 500 ||
+			rsp.StatusCode == http.StatusRequestTimeout ||
+			rsp.StatusCode == http.StatusTooManyRequests
+		return retry, errors.Errorf("invalid status code calling hook: %d: %s", rsp.StatusCode, string(data))
 	}
-	return nil
+	return false, nil
+}
+
+func validateWebhook(w eventTypes.Webhook) error {
+	n, err := notifierFor(w.Type)
+	if err != nil {
+		return err
+	}
+	if w.MaxAttempts < 0 || w.MaxAttempts > maxAttempts {
+		return &tsuruErrors.ValidationError{
+			Message: fmt.Sprintf("webhook max attempts must be between 0 and %d", maxAttempts),
+		}
+	}
+	return n.validate(w)
 }
 
-func validateURLs(w eventTypes.Webhook) error {
+func (httpNotifier) validate(w eventTypes.Webhook) error {
 	if w.URL == "" {
 		return &tsuruErrors.ValidationError{Message: "webhook url must not be empty"}
 	}
@@ -272,18 +426,26 @@ func (s *webhookService) Create(w eventTypes.Webhook) error {
 			"characters, containing only lower case letters, numbers or dashes, " +
 			"starting with a letter."}
 	}
-	err := validateURLs(w)
+	err := validateWebhook(w)
 	if err != nil {
 		return err
 	}
 	return s.storage.Insert(w)
 }
 
+// Update replaces the webhook, keeping the current secret if w has none.
 func (s *webhookService) Update(w eventTypes.Webhook) error {
-	err := validateURLs(w)
+	err := validateWebhook(w)
 	if err != nil {
 		return err
 	}
+	if w.Secret == "" {
+		current, err := s.storage.FindByName(w.Name)
+		if err != nil {
+			return err
+		}
+		w.Secret = current.Secret
+	}
 	return s.storage.Update(w)
 }
 
@@ -302,3 +464,55 @@ func (s *webhookService) Find(name string) (eventTypes.Webhook, error) {
 func (s *webhookService) List(teams []string) ([]eventTypes.Webhook, error) {
 	return s.storage.FindAllByTeams(teams)
 }
+
+// Deliveries returns the most recent delivery attempts of a webhook.
+func (s *webhookService) Deliveries(name string, limit int) ([]eventTypes.WebhookDelivery, error) {
+	_, err := s.storage.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryList
+	}
+	return s.storage.FindDeliveries(name, limit)
+}
+
+// Redeliver sends again the event of a previous delivery to the webhook,
+// using its current configuration. The delivery happens in background, with
+// the same retry policy of regular deliveries.
+func (s *webhookService) Redeliver(name, deliveryID string) error {
+	hook, err := s.storage.FindByName(name)
+	if err != nil {
+		return err
+	}
+	delivery, err := s.storage.FindDelivery(deliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery.Webhook != name {
+		return eventTypes.ErrWebhookDeliveryNotFound
+	}
+	select {
+	case <-s.quitCh:
+		return errors.New("webhook service is shutting down")
+	default:
+	}
+	evt, err := event.GetByHexID(delivery.EventID)
+	if err != nil {
+		return err
+	}
+	req, err := newWebhookRequest(*hook, evt)
+	if err != nil {
+		return err
+	}
+	req.redelivery = true
+	s.retries.Add(1)
+	go func() {
+		defer s.retries.Done()
+		deliverErr := s.deliver(req, 1)
+		if deliverErr != nil {
+			log.Errorf("[webhooks] error redelivering event %q to webhook %q: %v", req.eventID, name, deliverErr)
+		}
+	}()
+	return nil
+}
@@ -6,19 +6,29 @@ package webhook
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/auth/authtest"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/dbtest"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/redis"
 	servicemock "github.com/tsuru/tsuru/servicemanager/mock"
 	_ "github.com/tsuru/tsuru/storage/mongodb"
 	eventTypes "github.com/tsuru/tsuru/types/event"
@@ -284,6 +294,7 @@ func (s *S) TestWebhookServiceCreate(c *check.C) {
 func (s *S) TestWebhookServiceCreateInvalid(c *check.C) {
 	var tests = []struct {
 		name, url, proxyURL string
+		maxAttempts         int
 		expectedErr         error
 	}{
 		{
@@ -316,6 +327,12 @@ func (s *S) TestWebhookServiceCreateInvalid(c *check.C) {
 			url:         ":/:x",
 			expectedErr: &errors.ValidationError{Message: "webhook url is not valid: parse :/:x: missing protocol scheme"},
 		},
+		{
+			name:        "d",
+			url:         "http://valid",
+			maxAttempts: -1,
+			expectedErr: &errors.ValidationError{Message: "webhook max attempts must be between 0 and 20"},
+		},
 		{
 			name:        "d",
 			url:         "http://valid",
@@ -326,14 +343,42 @@ func (s *S) TestWebhookServiceCreateInvalid(c *check.C) {
 
 	for _, test := range tests {
 		err := s.service.Create(eventTypes.Webhook{
-			Name:     test.name,
-			URL:      test.url,
-			ProxyURL: test.proxyURL,
+			Name:        test.name,
+			URL:         test.url,
+			ProxyURL:    test.proxyURL,
+			MaxAttempts: test.maxAttempts,
 		})
 		c.Check(err, check.DeepEquals, test.expectedErr)
 	}
 }
 
+func (s *S) TestWebhookServiceUpdateKeepsSecret(c *check.C) {
+	err := s.service.Create(eventTypes.Webhook{
+		Name:   "xyz",
+		URL:    "http://a",
+		Secret: "my-secret",
+	})
+	c.Assert(err, check.IsNil)
+	err = s.service.Update(eventTypes.Webhook{
+		Name: "xyz",
+		URL:  "http://b",
+	})
+	c.Assert(err, check.IsNil)
+	w, err := s.service.Find("xyz")
+	c.Assert(err, check.IsNil)
+	c.Assert(w.URL, check.Equals, "http://b")
+	c.Assert(w.Secret, check.Equals, "my-secret")
+	err = s.service.Update(eventTypes.Webhook{
+		Name:   "xyz",
+		URL:    "http://b",
+		Secret: "other-secret",
+	})
+	c.Assert(err, check.IsNil)
+	w, err = s.service.Find("xyz")
+	c.Assert(err, check.IsNil)
+	c.Assert(w.Secret, check.Equals, "other-secret")
+}
+
 func (s *S) TestWebhookServiceUpdate(c *check.C) {
 	err := s.service.Create(eventTypes.Webhook{
 		Name: "xyz",
@@ -384,3 +429,374 @@ func (s *S) TestWebhookServiceDeleteNotFound(c *check.C) {
 	err := s.service.Delete("xyz")
 	c.Assert(err, check.Equals, eventTypes.ErrWebhookNotFound)
 }
+
+func newTestEvent(c *check.C) *event.Event {
+	evt, err := event.New(&event.Opts{
+		Target: event.Target{Type: "app", Value: "myapp"},
+		RawOwner: event.Owner{
+			Type: "user",
+			Name: "me@me.com",
+		},
+		Kind:    permission.PermAppUpdateEnvSet,
+		Allowed: event.Allowed(permission.PermAppReadEvents, permission.Context(permTypes.CtxApp, "myapp")),
+	})
+	c.Assert(err, check.IsNil)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	return evt
+}
+
+func (s *S) waitDeliveries(c *check.C, name string, count int) []eventTypes.WebhookDelivery {
+	timeout := time.After(5 * time.Second)
+	for {
+		deliveries, err := s.service.storage.FindDeliveries(name, 0)
+		c.Assert(err, check.IsNil)
+		if len(deliveries) >= count {
+			return deliveries
+		}
+		select {
+		case <-timeout:
+			c.Fatalf("timeout waiting for %d deliveries, got %d", count, len(deliveries))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (s *S) TestWebhookServiceNotifySignature(c *check.C) {
+	evt := newTestEvent(c)
+	called := make(chan struct{})
+	var receivedReq *http.Request
+	var receivedBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(called)
+		receivedBody, _ = ioutil.ReadAll(r.Body)
+		receivedReq = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	err := s.service.storage.Insert(eventTypes.Webhook{
+		Name:   "xyz",
+		URL:    srv.URL,
+		Body:   "{{.Target.Value}}",
+		Secret: "my-secret",
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	<-called
+	mac := hmac.New(sha256.New, []byte("my-secret"))
+	mac.Write([]byte("myapp"))
+	c.Assert(string(receivedBody), check.Equals, "myapp")
+	c.Assert(receivedReq.Header.Get("X-Tsuru-Signature"), check.Equals, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	deliveries := s.waitDeliveries(c, "xyz", 1)
+	c.Assert(deliveries[0].Success, check.Equals, true)
+	c.Assert(deliveries[0].StatusCode, check.Equals, http.StatusOK)
+	c.Assert(deliveries[0].EventID, check.Equals, evt.UniqueID.Hex())
+	c.Assert(deliveries[0].Attempt, check.Equals, 1)
+}
+
+func (s *S) TestWebhookServiceNotifyRetry(c *check.C) {
+	oldInterval := retryBaseInterval
+	retryBaseInterval = time.Millisecond
+	defer func() { retryBaseInterval = oldInterval }()
+	evt := newTestEvent(c)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("try again later"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	err := s.service.storage.Insert(eventTypes.Webhook{
+		Name:        "xyz",
+		URL:         srv.URL,
+		MaxAttempts: 4,
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	deliveries := s.waitDeliveries(c, "xyz", 3)
+	c.Assert(deliveries, check.HasLen, 3)
+	c.Assert(atomic.LoadInt32(&calls), check.Equals, int32(3))
+	for i, d := range deliveries {
+		c.Assert(d.Attempt, check.Equals, 3-i)
+		c.Assert(d.EventID, check.Equals, evt.UniqueID.Hex())
+	}
+	c.Assert(deliveries[0].Success, check.Equals, true)
+	c.Assert(deliveries[1].Success, check.Equals, false)
+	c.Assert(deliveries[1].StatusCode, check.Equals, http.StatusServiceUnavailable)
+	c.Assert(deliveries[1].Response, check.Equals, "try again later")
+	c.Assert(deliveries[1].Error, check.Equals, "invalid status code calling hook: 503: try again later")
+}
+
+func (s *S) TestWebhookServiceNotifyRetryMaxAttempts(c *check.C) {
+	oldInterval := retryBaseInterval
+	retryBaseInterval = time.Millisecond
+	defer func() { retryBaseInterval = oldInterval }()
+	evt := newTestEvent(c)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	err := s.service.storage.Insert(eventTypes.Webhook{
+		Name:        "xyz",
+		URL:         srv.URL,
+		MaxAttempts: 2,
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	s.waitDeliveries(c, "xyz", 2)
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(atomic.LoadInt32(&calls), check.Equals, int32(2))
+}
+
+func (s *S) TestWebhookServiceNotifyNoRetryOnClientError(c *check.C) {
+	oldInterval := retryBaseInterval
+	retryBaseInterval = time.Millisecond
+	defer func() { retryBaseInterval = oldInterval }()
+	evt := newTestEvent(c)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	err := s.service.storage.Insert(eventTypes.Webhook{
+		Name: "xyz",
+		URL:  srv.URL,
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	deliveries := s.waitDeliveries(c, "xyz", 1)
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(atomic.LoadInt32(&calls), check.Equals, int32(1))
+	c.Assert(deliveries[0].StatusCode, check.Equals, http.StatusBadRequest)
+	c.Assert(deliveries[0].Success, check.Equals, false)
+}
+
+func (s *S) TestWebhookServiceRedeliver(c *check.C) {
+	evt := newTestEvent(c)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	err := s.service.storage.Insert(eventTypes.Webhook{
+		Name: "xyz",
+		URL:  srv.URL,
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	deliveries := s.waitDeliveries(c, "xyz", 1)
+	err = s.service.Redeliver("xyz", deliveries[0].ID)
+	c.Assert(err, check.IsNil)
+	deliveries = s.waitDeliveries(c, "xyz", 2)
+	c.Assert(atomic.LoadInt32(&calls), check.Equals, int32(2))
+	c.Assert(deliveries[0].Redelivery, check.Equals, true)
+	c.Assert(deliveries[0].EventID, check.Equals, evt.UniqueID.Hex())
+	c.Assert(deliveries[1].Redelivery, check.Equals, false)
+}
+
+func (s *S) TestWebhookServiceRedeliverNotFound(c *check.C) {
+	err := s.service.storage.Insert(eventTypes.Webhook{Name: "xyz", URL: "http://a"})
+	c.Assert(err, check.IsNil)
+	err = s.service.storage.InsertDelivery(eventTypes.WebhookDelivery{ID: "d1", Webhook: "other"})
+	c.Assert(err, check.IsNil)
+	err = s.service.Redeliver("xyz", "d1")
+	c.Assert(err, check.Equals, eventTypes.ErrWebhookDeliveryNotFound)
+	err = s.service.Redeliver("xyz", "d2")
+	c.Assert(err, check.Equals, eventTypes.ErrWebhookDeliveryNotFound)
+	err = s.service.Redeliver("abc", "d1")
+	c.Assert(err, check.Equals, eventTypes.ErrWebhookNotFound)
+}
+
+func (s *S) TestWebhookServiceDeliveriesNotFound(c *check.C) {
+	_, err := s.service.Deliveries("xyz", 0)
+	c.Assert(err, check.Equals, eventTypes.ErrWebhookNotFound)
+}
+
+func (s *S) TestRetryInterval(c *check.C) {
+	c.Assert(retryInterval(2), check.Equals, 5*time.Second)
+	c.Assert(retryInterval(3), check.Equals, 10*time.Second)
+	c.Assert(retryInterval(4), check.Equals, 20*time.Second)
+	c.Assert(retryInterval(10), check.Equals, 5*time.Minute)
+}
+
+func (s *S) TestWebhookServiceNotifySlack(c *check.C) {
+	evt := newTestEvent(c)
+	called := make(chan struct{})
+	var receivedReq *http.Request
+	var message slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(called)
+		receivedReq = r
+		json.NewDecoder(r.Body).Decode(&message)
+	}))
+	defer srv.Close()
+	err := s.service.storage.Insert(eventTypes.Webhook{
+		Name:   "xyz",
+		Type:   eventTypes.WebhookTypeSlack,
+		URL:    srv.URL,
+		Method: "GET",
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	<-called
+	c.Assert(receivedReq.Method, check.Equals, "POST")
+	c.Assert(receivedReq.Header.Get("Content-Type"), check.Equals, "application/json")
+	c.Assert(message.Text, check.Equals, `app.update.env.set on app "myapp" by me@me.com succeeded`)
+	c.Assert(message.Attachments, check.HasLen, 1)
+	c.Assert(message.Attachments[0].Color, check.Equals, "good")
+	c.Assert(message.Attachments[0].Fields[:3], check.DeepEquals, []slackField{
+		{Title: "Kind", Value: "app.update.env.set", Short: true},
+		{Title: "Target", Value: "app: myapp", Short: true},
+		{Title: "Owner", Value: "me@me.com", Short: true},
+	})
+}
+
+func (s *S) TestWebhookServiceNotifyEmail(c *check.C) {
+	server, err := authtest.NewSMTPServer()
+	c.Assert(err, check.IsNil)
+	defer server.Stop()
+	config.Set("smtp:server", server.Addr())
+	config.Set("smtp:user", "tsuru@example.com")
+	defer config.Unset("smtp")
+	config.Set("webhooks:email:allowed-recipients", []interface{}{"team@example.com"})
+	defer config.Unset("webhooks")
+	evt := newTestEvent(c)
+	err = s.service.storage.Insert(eventTypes.Webhook{
+		Name: "xyz",
+		Type: eventTypes.WebhookTypeEmail,
+		Body: "{{.Kind.Name}} done",
+		Email: &eventTypes.WebhookEmail{
+			To:      []string{"team@example.com"},
+			Subject: "event on {{.Target.Value}}",
+		},
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	deliveries := s.waitDeliveries(c, "xyz", 1)
+	c.Assert(deliveries[0].Success, check.Equals, true, check.Commentf("error: %s", deliveries[0].Error))
+	server.RLock()
+	defer server.RUnlock()
+	c.Assert(server.MailBox, check.HasLen, 1)
+	mail := server.MailBox[0]
+	c.Assert(mail.From, check.Equals, "tsuru@example.com")
+	c.Assert(mail.To, check.DeepEquals, []string{"team@example.com"})
+	data := string(mail.Data)
+	c.Assert(strings.Contains(data, "Subject: event on myapp\r\n"), check.Equals, true, check.Commentf("mail: %s", data))
+	c.Assert(strings.HasSuffix(strings.TrimSpace(data), "app.update.env.set done"), check.Equals, true, check.Commentf("mail: %s", data))
+}
+
+func (s *S) TestWebhookServiceNotifyQueue(c *check.C) {
+	config.Set("webhooks:queue:redis-server", "127.0.0.1:6379")
+	config.Set("webhooks:queue:redis-db", 3)
+	defer config.Unset("webhooks")
+	notifiers[eventTypes.WebhookTypeQueue] = &queueNotifier{}
+	client, err := redis.NewRedis("webhooks:queue")
+	c.Assert(err, check.IsNil)
+	defer client.Close()
+	client.Del("tsuru-events")
+	evt := newTestEvent(c)
+	err = s.service.storage.Insert(eventTypes.Webhook{
+		Name:  "xyz",
+		Type:  eventTypes.WebhookTypeQueue,
+		Queue: &eventTypes.WebhookQueue{Name: "tsuru-events"},
+	})
+	c.Assert(err, check.IsNil)
+	s.service.Notify(evt.UniqueID.Hex())
+	deliveries := s.waitDeliveries(c, "xyz", 1)
+	c.Assert(deliveries[0].Success, check.Equals, true, check.Commentf("error: %s", deliveries[0].Error))
+	messages, err := client.LRange("tsuru-events", 0, -1).Result()
+	c.Assert(err, check.IsNil)
+	c.Assert(messages, check.HasLen, 1)
+	var received map[string]interface{}
+	err = json.Unmarshal([]byte(messages[0]), &received)
+	c.Assert(err, check.IsNil)
+	c.Assert(received["UniqueID"], check.Equals, evt.UniqueID.Hex())
+}
+
+func (s *S) TestWebhookServiceCreateEmailRecipients(c *check.C) {
+	config.Set("webhooks:email:allowed-recipients", []interface{}{"ops@example.com", "@corp.example.com"})
+	defer config.Unset("webhooks")
+	role, err := permission.NewRole("webhook-team-member", string(permTypes.CtxTeam), "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.read")
+	c.Assert(err, check.IsNil)
+	member := &auth.User{Email: "member@example.com"}
+	err = member.Create()
+	c.Assert(err, check.IsNil)
+	err = member.AddRole(role.Name, "myteam")
+	c.Assert(err, check.IsNil)
+	other := &auth.User{Email: "other@example.com"}
+	err = other.Create()
+	c.Assert(err, check.IsNil)
+	err = other.AddRole(role.Name, "otherteam")
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		to          string
+		expectedErr error
+	}{
+		{to: "ops@example.com"},
+		{to: "dev@corp.example.com"},
+		{to: "member@example.com"},
+		{
+			to:          "other@example.com",
+			expectedErr: &errors.ValidationError{Message: `webhook email recipient "other@example.com" must be a member of the team "myteam" or be allowed by the webhooks:email:allowed-recipients config`},
+		},
+		{
+			to:          "someone@elsewhere.com",
+			expectedErr: &errors.ValidationError{Message: `webhook email recipient "someone@elsewhere.com" must be a member of the team "myteam" or be allowed by the webhooks:email:allowed-recipients config`},
+		},
+		{
+			to:          "a@example.com\r\nBcc: b@example.com",
+			expectedErr: &errors.ValidationError{Message: `invalid webhook email recipient "a@example.com\r\nBcc: b@example.com"`},
+		},
+	}
+	for i, tt := range tests {
+		err := s.service.Create(eventTypes.Webhook{
+			Name:      fmt.Sprintf("hook%d", i),
+			TeamOwner: "myteam",
+			Type:      eventTypes.WebhookTypeEmail,
+			Email:     &eventTypes.WebhookEmail{To: []string{tt.to}},
+		})
+		c.Check(err, check.DeepEquals, tt.expectedErr, check.Commentf("recipient %q", tt.to))
+	}
+}
+
+func (s *S) TestWebhookServiceCreateInvalidType(c *check.C) {
+	config.Set("webhooks:email:allowed-recipients", []interface{}{"a@example.com"})
+	defer config.Unset("webhooks")
+	tests := []struct {
+		webhook     eventTypes.Webhook
+		expectedErr error
+	}{
+		{
+			webhook:     eventTypes.Webhook{Name: "a", Type: "pigeon"},
+			expectedErr: &errors.ValidationError{Message: `invalid webhook type "pigeon", must be one of: http, slack, email, queue`},
+		},
+		{
+			webhook:     eventTypes.Webhook{Name: "a", Type: eventTypes.WebhookTypeSlack},
+			expectedErr: &errors.ValidationError{Message: "webhook url must not be empty"},
+		},
+		{
+			webhook:     eventTypes.Webhook{Name: "a", Type: eventTypes.WebhookTypeEmail, Email: &eventTypes.WebhookEmail{}},
+			expectedErr: &errors.ValidationError{Message: "webhook email recipients must not be empty"},
+		},
+		{
+			webhook:     eventTypes.Webhook{Name: "a", Type: eventTypes.WebhookTypeQueue},
+			expectedErr: &errors.ValidationError{Message: "webhook queue name must not be empty"},
+		},
+		{
+			webhook: eventTypes.Webhook{Name: "a", Type: eventTypes.WebhookTypeEmail, Email: &eventTypes.WebhookEmail{To: []string{"a@example.com"}}},
+		},
+	}
+	for _, tt := range tests {
+		err := s.service.Create(tt.webhook)
+		c.Check(err, check.DeepEquals, tt.expectedErr)
+	}
+}
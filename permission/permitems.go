@@ -28,10 +28,13 @@ var (
 	PermAppReadDeploy                    = PermissionRegistry.get("app.read.deploy")                     // [global app team pool]
 	PermAppReadEnv                       = PermissionRegistry.get("app.read.env")                        // [global app team pool]
 	PermAppReadEvents                    = PermissionRegistry.get("app.read.events")                     // [global app team pool]
+	PermAppReadJob                       = PermissionRegistry.get("app.read.job")                        // [global app team pool]
 	PermAppReadLog                       = PermissionRegistry.get("app.read.log")                        // [global app team pool]
 	PermAppReadMetric                    = PermissionRegistry.get("app.read.metric")                     // [global app team pool]
 	PermAppReadRouter                    = PermissionRegistry.get("app.read.router")                     // [global app team pool]
+	PermAppReadSchedule                  = PermissionRegistry.get("app.read.schedule")                   // [global app team pool]
 	PermAppRun                           = PermissionRegistry.get("app.run")                             // [global app team pool]
+	PermAppRunJob                        = PermissionRegistry.get("app.run.job")                         // [global app team pool]
 	PermAppRunShell                      = PermissionRegistry.get("app.run.shell")                       // [global app team pool]
 	PermAppUpdate                        = PermissionRegistry.get("app.update")                          // [global app team pool]
 	PermAppUpdateBind                    = PermissionRegistry.get("app.update.bind")                     // [global app team pool]
@@ -51,8 +54,14 @@ var (
 	PermAppUpdateEvents                  = PermissionRegistry.get("app.update.events")                   // [global app team pool]
 	PermAppUpdateGrant                   = PermissionRegistry.get("app.update.grant")                    // [global app team pool]
 	PermAppUpdateImageReset              = PermissionRegistry.get("app.update.image-reset")              // [global app team pool]
+	PermAppUpdateJob                     = PermissionRegistry.get("app.update.job")                      // [global app team pool]
+	PermAppUpdateJobAdd                  = PermissionRegistry.get("app.update.job.add")                  // [global app team pool]
+	PermAppUpdateJobRemove               = PermissionRegistry.get("app.update.job.remove")               // [global app team pool]
 	PermAppUpdateLog                     = PermissionRegistry.get("app.update.log")                      // [global app team pool]
+	PermAppUpdateLogRetention            = PermissionRegistry.get("app.update.log-retention")            // [global app team pool]
+	PermAppUpdateMetadata                = PermissionRegistry.get("app.update.metadata")                 // [global app team pool]
 	PermAppUpdatePlan                    = PermissionRegistry.get("app.update.plan")                     // [global app team pool]
+	PermAppUpdatePlanProcess             = PermissionRegistry.get("app.update.plan.process")             // [global app team pool]
 	PermAppUpdatePlatform                = PermissionRegistry.get("app.update.platform")                 // [global app team pool]
 	PermAppUpdatePool                    = PermissionRegistry.get("app.update.pool")                     // [global app team pool]
 	PermAppUpdateRestart                 = PermissionRegistry.get("app.update.restart")                  // [global app team pool]
@@ -61,16 +70,23 @@ var (
 	PermAppUpdateRouterAdd               = PermissionRegistry.get("app.update.router.add")               // [global app team pool]
 	PermAppUpdateRouterRemove            = PermissionRegistry.get("app.update.router.remove")            // [global app team pool]
 	PermAppUpdateRouterUpdate            = PermissionRegistry.get("app.update.router.update")            // [global app team pool]
+	PermAppUpdateSchedule                = PermissionRegistry.get("app.update.schedule")                 // [global app team pool]
+	PermAppUpdateScheduleAdd             = PermissionRegistry.get("app.update.schedule.add")             // [global app team pool]
+	PermAppUpdateScheduleRemove          = PermissionRegistry.get("app.update.schedule.remove")          // [global app team pool]
 	PermAppUpdateSleep                   = PermissionRegistry.get("app.update.sleep")                    // [global app team pool]
 	PermAppUpdateStart                   = PermissionRegistry.get("app.update.start")                    // [global app team pool]
 	PermAppUpdateStop                    = PermissionRegistry.get("app.update.stop")                     // [global app team pool]
 	PermAppUpdateSwap                    = PermissionRegistry.get("app.update.swap")                     // [global app team pool]
 	PermAppUpdateTags                    = PermissionRegistry.get("app.update.tags")                     // [global app team pool]
 	PermAppUpdateTeamowner               = PermissionRegistry.get("app.update.teamowner")                // [global app team pool]
+	PermAppUpdateTraffic                 = PermissionRegistry.get("app.update.traffic")                  // [global app team pool]
 	PermAppUpdateUnbind                  = PermissionRegistry.get("app.update.unbind")                   // [global app team pool]
 	PermAppUpdateUnbindVolume            = PermissionRegistry.get("app.update.unbind-volume")            // [global app team pool]
 	PermAppUpdateUnit                    = PermissionRegistry.get("app.update.unit")                     // [global app team pool]
 	PermAppUpdateUnitAdd                 = PermissionRegistry.get("app.update.unit.add")                 // [global app team pool]
+	PermAppUpdateUnitAutoscale           = PermissionRegistry.get("app.update.unit.autoscale")           // [global app team pool]
+	PermAppUpdateUnitAutoscaleAdd        = PermissionRegistry.get("app.update.unit.autoscale.add")       // [global app team pool]
+	PermAppUpdateUnitAutoscaleRemove     = PermissionRegistry.get("app.update.unit.autoscale.remove")    // [global app team pool]
 	PermAppUpdateUnitRegister            = PermissionRegistry.get("app.update.unit.register")            // [global app team pool]
 	PermAppUpdateUnitRemove              = PermissionRegistry.get("app.update.unit.remove")              // [global app team pool]
 	PermAppUpdateUnitStatus              = PermissionRegistry.get("app.update.unit.status")              // [global app team pool]
@@ -138,10 +154,16 @@ var (
 	PermPoolRead                         = PermissionRegistry.get("pool.read")                           // [global pool]
 	PermPoolReadConstraints              = PermissionRegistry.get("pool.read.constraints")               // [global pool]
 	PermPoolReadEvents                   = PermissionRegistry.get("pool.read.events")                    // [global pool]
+	PermPoolReadLogRetention             = PermissionRegistry.get("pool.read.log-retention")             // [global pool]
+	PermPoolReadSchedule                 = PermissionRegistry.get("pool.read.schedule")                  // [global pool]
 	PermPoolUpdate                       = PermissionRegistry.get("pool.update")                         // [global pool]
 	PermPoolUpdateConstraints            = PermissionRegistry.get("pool.update.constraints")             // [global pool]
 	PermPoolUpdateConstraintsSet         = PermissionRegistry.get("pool.update.constraints.set")         // [global pool]
+	PermPoolUpdateLogRetention           = PermissionRegistry.get("pool.update.log-retention")           // [global pool]
 	PermPoolUpdateLogs                   = PermissionRegistry.get("pool.update.logs")                    // [global pool]
+	PermPoolUpdateSchedule               = PermissionRegistry.get("pool.update.schedule")                // [global pool]
+	PermPoolUpdateScheduleAdd            = PermissionRegistry.get("pool.update.schedule.add")            // [global pool]
+	PermPoolUpdateScheduleRemove         = PermissionRegistry.get("pool.update.schedule.remove")         // [global pool]
 	PermPoolUpdateTeam                   = PermissionRegistry.get("pool.update.team")                    // [global pool]
 	PermPoolUpdateTeamAdd                = PermissionRegistry.get("pool.update.team.add")                // [global pool]
 	PermPoolUpdateTeamRemove             = PermissionRegistry.get("pool.update.team.remove")             // [global pool]
@@ -163,6 +185,13 @@ var (
 	PermRoleUpdatePermission             = PermissionRegistry.get("role.update.permission")              // [global]
 	PermRoleUpdatePermissionAdd          = PermissionRegistry.get("role.update.permission.add")          // [global]
 	PermRoleUpdatePermissionRemove       = PermissionRegistry.get("role.update.permission.remove")       // [global]
+	PermRouter                           = PermissionRegistry.get("router")                              // [global]
+	PermRouterCreate                     = PermissionRegistry.get("router.create")                       // [global]
+	PermRouterDelete                     = PermissionRegistry.get("router.delete")                       // [global]
+	PermRouterRead                       = PermissionRegistry.get("router.read")                         // [global]
+	PermRouterReadDrift                  = PermissionRegistry.get("router.read.drift")                   // [global]
+	PermRouterReadEvents                 = PermissionRegistry.get("router.read.events")                  // [global]
+	PermRouterUpdate                     = PermissionRegistry.get("router.update")                       // [global]
 	PermService                          = PermissionRegistry.get("service")                             // [global service team]
 	PermServiceBroker                    = PermissionRegistry.get("service-broker")                      // [global]
 	PermServiceBrokerCreate              = PermissionRegistry.get("service-broker.create")               // [global]
@@ -236,4 +265,5 @@ var (
 	PermWebhookRead                      = PermissionRegistry.get("webhook.read")                        // [global team]
 	PermWebhookReadEvents                = PermissionRegistry.get("webhook.read.events")                 // [global team]
 	PermWebhookUpdate                    = PermissionRegistry.get("webhook.update")                      // [global team]
+	PermWebhookUpdateRedeliver           = PermissionRegistry.get("webhook.update.redeliver")            // [global team]
 )
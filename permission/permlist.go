@@ -17,12 +17,15 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 ).add(
 	"app.update.description",
 	"app.update.tags",
+	"app.update.metadata",
 	"app.update.log",
 	"app.update.pool",
 	"app.update.unit.add",
 	"app.update.unit.remove",
 	"app.update.unit.register",
 	"app.update.unit.status",
+	"app.update.unit.autoscale.add",
+	"app.update.unit.autoscale.remove",
 	"app.update.env.set",
 	"app.update.env.unset",
 	"app.update.restart",
@@ -30,12 +33,14 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"app.update.start",
 	"app.update.stop",
 	"app.update.swap",
+	"app.update.traffic",
 	"app.update.grant",
 	"app.update.revoke",
 	"app.update.teamowner",
 	"app.update.cname.add",
 	"app.update.cname.remove",
 	"app.update.plan",
+	"app.update.plan.process",
 	"app.update.platform",
 	"app.update.bind",
 	"app.update.bind-volume",
@@ -49,6 +54,11 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"app.update.router.add",
 	"app.update.router.update",
 	"app.update.router.remove",
+	"app.update.schedule.add",
+	"app.update.schedule.remove",
+	"app.update.job.add",
+	"app.update.job.remove",
+	"app.update.log-retention",
 	"app.deploy",
 	"app.deploy.archive-url",
 	"app.deploy.build",
@@ -64,9 +74,12 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"app.read.metric",
 	"app.read.log",
 	"app.read.certificate",
+	"app.read.schedule",
+	"app.read.job",
 	"app.delete",
 	"app.run",
 	"app.run.shell",
+	"app.run.job",
 	"app.admin.routes",
 	"app.admin.quota",
 	"app.build",
@@ -190,6 +203,11 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"pool.update.constraints.set",
 	"pool.read.constraints",
 	"pool.update.logs",
+	"pool.update.schedule.add",
+	"pool.update.schedule.remove",
+	"pool.read.schedule",
+	"pool.update.log-retention",
+	"pool.read.log-retention",
 	"pool.delete",
 ).add(
 	"debug",
@@ -221,6 +239,12 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"cluster.create",
 	"cluster.update",
 	"cluster.delete",
+).add(
+	"router.read.events",
+	"router.read.drift",
+	"router.create",
+	"router.update",
+	"router.delete",
 ).addWithCtx(
 	"volume", []permTypes.ContextType{permTypes.CtxVolume, permTypes.CtxTeam, permTypes.CtxPool},
 ).addWithCtx(
@@ -237,5 +261,6 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"webhook.read.events",
 	"webhook.create",
 	"webhook.update",
+	"webhook.update.redeliver",
 	"webhook.delete",
 )
@@ -175,6 +175,15 @@ func (s *clusterService) validate(c provTypes.Cluster, isNewCluster bool) error
 			return errors.WithStack(&tsuruErrors.ValidationError{Message: "either default or a list of pools must be set"})
 		}
 	}
+	if c.Weight < 0 {
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: "cluster weight cannot be negative"})
+	}
+	if c.Drain {
+		err := s.validateDrain(c)
+		if err != nil {
+			return err
+		}
+	}
 	prov, err := provision.Get(c.Provisioner)
 	if err != nil {
 		return errors.WithStack(&tsuruErrors.ValidationError{Message: fmt.Sprintf("provisioner error: %v", err)})
@@ -185,6 +194,35 @@ func (s *clusterService) validate(c provTypes.Cluster, isNewCluster bool) error
 	return nil
 }
 
+// validateDrain ensures every pool in a draining cluster has at least one
+// other weighted cluster able to receive its units.
+func (s *clusterService) validateDrain(c provTypes.Cluster) error {
+	if c.Weight == 0 || len(c.Pools) == 0 {
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: "only clusters with pools and weight can be drained"})
+	}
+	clusters, err := s.storage.FindByProvisioner(c.Provisioner)
+	if err != nil && err != provTypes.ErrNoCluster {
+		return err
+	}
+	available := map[string]bool{}
+	for _, other := range clusters {
+		if other.Name == c.Name || other.Drain || other.Weight == 0 {
+			continue
+		}
+		for _, pool := range other.Pools {
+			available[pool] = true
+		}
+	}
+	for _, pool := range c.Pools {
+		if !available[pool] {
+			return errors.WithStack(&tsuruErrors.ValidationError{
+				Message: fmt.Sprintf("cannot drain cluster, pool %q has no other weighted cluster", pool),
+			})
+		}
+	}
+	return nil
+}
+
 func (s *clusterService) initCluster(c provTypes.Cluster, isNewCluster bool) error {
 	prov, err := provision.Get(c.Provisioner)
 	if err != nil {
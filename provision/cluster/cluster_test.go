@@ -241,6 +241,57 @@ func (s *S) TestClusterServiceUpdateValidationError(c *check.C) {
 	}
 }
 
+func (s *S) TestClusterServiceUpdateDrain(c *check.C) {
+	existing := []provTypes.Cluster{
+		{Name: "c1", Provisioner: "fake", Pools: []string{"p1", "p2"}, Weight: 1},
+		{Name: "c2", Provisioner: "fake", Pools: []string{"p1"}, Weight: 1},
+		{Name: "c3", Provisioner: "fake", Pools: []string{"p2"}, Weight: 1, Drain: true},
+		{Name: "c4", Provisioner: "fake", Pools: []string{"p3"}},
+	}
+	var upserted []string
+	cs := &clusterService{
+		storage: &provTypes.MockClusterStorage{
+			OnFindByProvisioner: func(prov string) ([]provTypes.Cluster, error) {
+				c.Assert(prov, check.Equals, "fake")
+				return existing, nil
+			},
+			OnUpsert: func(clust provTypes.Cluster) error {
+				upserted = append(upserted, clust.Name)
+				return nil
+			},
+		},
+	}
+	tests := []struct {
+		c   provTypes.Cluster
+		err string
+	}{
+		{
+			c:   provTypes.Cluster{Name: "c1", Provisioner: "fake", Pools: []string{"p1"}, Weight: -1},
+			err: "cluster weight cannot be negative",
+		},
+		{
+			c:   provTypes.Cluster{Name: "c4", Provisioner: "fake", Pools: []string{"p3"}, Drain: true},
+			err: "only clusters with pools and weight can be drained",
+		},
+		{
+			c:   provTypes.Cluster{Name: "c1", Provisioner: "fake", Pools: []string{"p1", "p2"}, Weight: 1, Drain: true},
+			err: `cannot drain cluster, pool "p2" has no other weighted cluster`,
+		},
+		{
+			c: provTypes.Cluster{Name: "c2", Provisioner: "fake", Pools: []string{"p1"}, Weight: 1, Drain: true},
+		},
+	}
+	for _, tt := range tests {
+		err := cs.Update(tt.c)
+		if len(tt.err) == 0 {
+			c.Check(err, check.IsNil)
+		} else {
+			c.Check(err, check.ErrorMatches, tt.err)
+		}
+	}
+	c.Assert(upserted, check.DeepEquals, []string{"c2"})
+}
+
 func (s *S) TestClusterServiceList(c *check.C) {
 	clusters := []provTypes.Cluster{{Name: "cluster1"}, {Name: "cluster2"}}
 	cs := &clusterService{
@@ -437,6 +437,10 @@ func (c *Container) Stop(client provision.BuilderDockerClient, limiter provision
 	return nil
 }
 
+// cpuPeriod is the period, in microseconds, of the cpu quota enforcing the
+// cpu limit of containers.
+const cpuPeriod = 100000
+
 type StartArgs struct {
 	Client  provision.BuilderDockerClient
 	Limiter provision.ActionLimiter
@@ -449,13 +453,18 @@ func (c *Container) hostConfig(app provision.App, isDeploy bool) (*docker.HostCo
 	sharedMount, _ := config.GetString("docker:sharedfs:mountpoint")
 	sharedIsolation, _ := config.GetBool("docker:sharedfs:app-isolation")
 	sharedSalt, _ := config.GetString("docker:sharedfs:salt")
+	resources := app.GetProcessResources(c.ProcessName)
 	hostConfig := docker.HostConfig{
-		CPUShares: int64(app.GetCpuShare()),
+		CPUShares: int64(resources.CpuShare),
 	}
 
 	if !isDeploy {
-		hostConfig.Memory = app.GetMemory()
-		hostConfig.MemorySwap = app.GetMemory() + app.GetSwap()
+		hostConfig.Memory = resources.Memory
+		hostConfig.MemorySwap = resources.Memory + resources.Swap
+		if resources.CPULimit != 0 {
+			hostConfig.CPUPeriod = cpuPeriod
+			hostConfig.CPUQuota = int64(resources.CPULimit) * cpuPeriod / 1000
+		}
 		hostConfig.RestartPolicy = docker.AlwaysRestart()
 		hostConfig.PortBindings = map[docker.Port][]docker.PortBinding{
 			docker.Port(c.ExposedPort): {{HostIP: "", HostPort: ""}},
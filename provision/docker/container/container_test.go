@@ -30,6 +30,7 @@ import (
 	"github.com/tsuru/tsuru/provision/dockercommon"
 	"github.com/tsuru/tsuru/provision/provisiontest"
 	"github.com/tsuru/tsuru/router/routertest"
+	appTypes "github.com/tsuru/tsuru/types/app"
 	check "gopkg.in/check.v1"
 )
 
@@ -152,6 +153,44 @@ func (s *S) TestContainerCreate(c *check.C) {
 	c.Assert(cont.Status, check.Equals, "created")
 }
 
+func (s *S) TestContainerCreateProcessResources(c *check.C) {
+	app := provisiontest.NewFakeApp("app-name", "brainfuck", 1)
+	app.Memory = 15
+	app.Swap = 15
+	app.CpuShare = 50
+	app.Processes = map[string]appTypes.ProcessResources{
+		"worker": {Memory: 10, Swap: 5, CpuShare: 20, CPULimit: 1500},
+	}
+	routertest.FakeRouter.AddBackend(app)
+	defer routertest.FakeRouter.RemoveBackend(app.GetName())
+	img := "tsuru/brainfuck:latest"
+	s.cli.PullImage(docker.PullImageOptions{Repository: img}, docker.AuthConfiguration{})
+	cont := Container{Container: types.Container{
+		Name:        "myName",
+		AppName:     app.GetName(),
+		Type:        app.GetPlatform(),
+		Status:      "created",
+		ProcessName: "worker",
+		ExposedPort: "8888/tcp",
+	}}
+	err := cont.Create(&CreateArgs{
+		App:      app,
+		ImageID:  img,
+		Commands: []string{"docker", "run"},
+		Client:   s.cli,
+	})
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(&cont)
+	dcli, _ := docker.NewClient(s.server.URL())
+	container, err := dcli.InspectContainer(cont.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(container.HostConfig.Memory, check.Equals, int64(10))
+	c.Assert(container.HostConfig.MemorySwap, check.Equals, int64(15))
+	c.Assert(container.HostConfig.CPUShares, check.Equals, int64(20))
+	c.Assert(container.HostConfig.CPUPeriod, check.Equals, int64(100000))
+	c.Assert(container.HostConfig.CPUQuota, check.Equals, int64(150000))
+}
+
 func (s *S) TestContainerCreateCustomLog(c *check.C) {
 	client, err := docker.NewClient(s.server.URL())
 	c.Assert(err, check.IsNil)
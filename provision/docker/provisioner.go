@@ -81,6 +81,7 @@ type dockerProvisioner struct {
 var (
 	_ provision.Provisioner               = &dockerProvisioner{}
 	_ provision.RollbackableDeployer      = &dockerProvisioner{}
+	_ provision.StrategyDeployer          = &dockerProvisioner{}
 	_ provision.ExecutableProvisioner     = &dockerProvisioner{}
 	_ provision.SleepableProvisioner      = &dockerProvisioner{}
 	_ provision.MessageProvisioner        = &dockerProvisioner{}
@@ -446,12 +447,104 @@ func (p *dockerProvisioner) deploy(a provision.App, imageID string, evt *event.E
 	return err
 }
 
-func setQuota(app provision.App, toAdd map[string]*containersToAdd) error {
-	var total int
-	for _, ct := range toAdd {
-		total += ct.Quantity
+func (p *dockerProvisioner) DeployCandidate(a provision.App, buildImageID string, evt *event.Event) (string, []provision.Unit, error) {
+	if err := checkCanceled(evt); err != nil {
+		return "", nil, err
+	}
+	imageID := buildImageID
+	if strings.HasSuffix(buildImageID, "-builder") {
+		var err error
+		imageID, err = p.deployPipeline(a, buildImageID, dockercommon.DeployCmds(a), evt)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	containers, err := p.listContainersByApp(a.GetName())
+	if err != nil {
+		return "", nil, err
+	}
+	imageData, err := image.GetImageMetaData(imageID)
+	if err != nil {
+		return "", nil, err
+	}
+	toAdd := getContainersToAdd(imageData, containers)
+	// Candidate units run side by side with the current ones until one of
+	// the images is promoted, so both count against the app quota.
+	if err = setQuotaInUse(a, len(containers)+countContainersToAdd(toAdd)); err != nil {
+		return "", nil, err
+	}
+	exposedPort := ""
+	if len(imageData.ExposedPorts) > 0 {
+		exposedPort = imageData.ExposedPorts[0]
+	}
+	args := changeUnitsPipelineArgs{
+		app:         a,
+		toAdd:       toAdd,
+		writer:      evt,
+		imageID:     imageID,
+		provisioner: p,
+		event:       evt,
+		exposedPort: exposedPort,
+	}
+	pipeline := action.NewPipeline(
+		&provisionAddUnitsToHost,
+		&bindAndHealthcheck,
+	)
+	err = pipeline.Execute(args)
+	if err != nil {
+		return "", nil, provision.ErrUnitStartup{Err: err}
+	}
+	newContainers := pipeline.Result().([]container.Container)
+	units := make([]provision.Unit, len(newContainers))
+	for i := range newContainers {
+		units[i] = newContainers[i].AsUnit(a)
+	}
+	return imageID, units, nil
+}
+
+func (p *dockerProvisioner) PromoteCandidate(a provision.App, imageID string, evt *event.Event) (string, error) {
+	containers, err := p.listContainersByApp(a.GetName())
+	if err != nil {
+		return "", err
+	}
+	var toRemove []container.Container
+	for _, c := range containers {
+		if c.Image != imageID {
+			toRemove = append(toRemove, c)
+		}
 	}
-	err := app.SetQuotaInUse(total)
+	args := changeUnitsPipelineArgs{
+		app:         a,
+		toRemove:    toRemove,
+		writer:      evt,
+		imageID:     imageID,
+		provisioner: p,
+		event:       evt,
+	}
+	pipeline := action.NewPipeline(
+		&updateAppImage,
+		&provisionRemoveOldUnits,
+		&provisionUnbindOldUnits,
+	)
+	err = pipeline.Execute(args)
+	if err != nil {
+		return "", err
+	}
+	// The units of the image not promoted are gone, releasing the quota
+	// taken by DeployCandidate.
+	err = setQuotaInUse(a, len(containers)-len(toRemove))
+	if err != nil {
+		return "", err
+	}
+	return imageID, nil
+}
+
+func setQuota(app provision.App, toAdd map[string]*containersToAdd) error {
+	return setQuotaInUse(app, countContainersToAdd(toAdd))
+}
+
+func setQuotaInUse(app provision.App, inUse int) error {
+	err := app.SetQuotaInUse(inUse)
 	if err != nil {
 		return &tsuruErrors.CompositeError{
 			Base:    err,
@@ -461,6 +554,14 @@ func setQuota(app provision.App, toAdd map[string]*containersToAdd) error {
 	return nil
 }
 
+func countContainersToAdd(toAdd map[string]*containersToAdd) int {
+	var total int
+	for _, ct := range toAdd {
+		total += ct.Quantity
+	}
+	return total
+}
+
 func getContainersToAdd(data image.ImageMetadata, oldContainers []container.Container) map[string]*containersToAdd {
 	processMap := make(map[string]*containersToAdd, len(data.Processes))
 	for name := range data.Processes {
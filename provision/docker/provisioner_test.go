@@ -493,6 +493,105 @@ func (s *S) TestDeployQuotaExceeded(c *check.C) {
 	c.Assert(e.Requested, check.Equals, uint(2))
 }
 
+func (s *S) TestDeployCandidateQuotaExceeded(c *check.C) {
+	a := s.newApp("otherapp")
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	cont, err := s.newContainer(&newContainerOpts{AppName: a.Name, ProcessName: "web"}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	s.mockService.AppQuota.OnSet = func(appName string, quantity int) error {
+		c.Assert(appName, check.Equals, "otherapp")
+		c.Assert(quantity, check.Equals, 3)
+		return &quota.QuotaExceededError{Available: 1, Requested: 2}
+	}
+	customData := map[string]interface{}{
+		"processes": map[string]interface{}{
+			"web":    "python myapp.py",
+			"worker": "python myworker.py",
+		},
+	}
+	err = image.SaveImageCustomData("tsuru/app-"+a.Name+":v2", customData)
+	c.Assert(err, check.IsNil)
+	evt, err := event.New(&event.Opts{
+		Target:  event.Target{Type: "app", Value: a.Name},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	_, _, err = s.p.DeployCandidate(&a, "tsuru/app-"+a.Name+":v2", evt)
+	c.Assert(err, check.NotNil)
+	compErr, ok := err.(*errors.CompositeError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(compErr.Message, check.Equals, "Cannot start application units")
+}
+
+func (s *S) TestPromoteCandidateReleasesQuota(c *check.C) {
+	a := s.newApp("otherapp")
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	oldImage := "tsuru/app-" + a.Name + ":v1"
+	newImage := "tsuru/app-" + a.Name + ":v2"
+	for _, img := range []string{oldImage, oldImage, newImage} {
+		cont, errCont := s.newContainer(&newContainerOpts{AppName: a.Name, ProcessName: "web", Image: img}, nil)
+		c.Assert(errCont, check.IsNil)
+		defer s.removeTestContainer(cont)
+	}
+	var inUse []int
+	s.mockService.AppQuota.OnSet = func(appName string, quantity int) error {
+		c.Assert(appName, check.Equals, "otherapp")
+		inUse = append(inUse, quantity)
+		return nil
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  event.Target{Type: "app", Value: a.Name},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	_, err = s.p.PromoteCandidate(&a, newImage, evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(inUse, check.DeepEquals, []int{1})
+	containers, err := s.p.listContainersByApp(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(containers, check.HasLen, 1)
+	c.Assert(containers[0].Image, check.Equals, newImage)
+}
+
+func (s *S) TestPromoteCandidateRollbackReleasesQuota(c *check.C) {
+	a := s.newApp("otherapp")
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	oldImage := "tsuru/app-" + a.Name + ":v1"
+	newImage := "tsuru/app-" + a.Name + ":v2"
+	for _, img := range []string{oldImage, oldImage, newImage} {
+		cont, errCont := s.newContainer(&newContainerOpts{AppName: a.Name, ProcessName: "web", Image: img}, nil)
+		c.Assert(errCont, check.IsNil)
+		defer s.removeTestContainer(cont)
+	}
+	var inUse []int
+	s.mockService.AppQuota.OnSet = func(appName string, quantity int) error {
+		c.Assert(appName, check.Equals, "otherapp")
+		inUse = append(inUse, quantity)
+		return nil
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  event.Target{Type: "app", Value: a.Name},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	_, err = s.p.PromoteCandidate(&a, oldImage, evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(inUse, check.DeepEquals, []int{2})
+	containers, err := s.p.listContainersByApp(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(containers, check.HasLen, 2)
+}
+
 func (s *S) TestDeployCanceledEvent(c *check.C) {
 	err := newFakeImage(s.p, "tsuru/python:latest", nil)
 	c.Assert(err, check.IsNil)
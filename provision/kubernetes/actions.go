@@ -90,7 +90,11 @@ var updateAppCR = action.Action{
 		if err != nil {
 			return nil, err
 		}
-		return nil, updateAppNamespace(client, params.old.GetName(), client.PoolNamespace(params.new.GetPool()))
+		ns, err := client.namespaceForApp(params.new)
+		if err != nil {
+			return nil, err
+		}
+		return nil, updateAppNamespace(client, params.old.GetName(), ns)
 	},
 	Backward: func(ctx action.BWContext) {
 		params := ctx.Params[0].(updatePipelineParams)
@@ -105,7 +109,11 @@ func backwardCR(params updatePipelineParams) error {
 	if err != nil {
 		return err
 	}
-	return updateAppNamespace(client, params.old.GetName(), client.PoolNamespace(params.old.GetPool()))
+	ns, err := client.namespaceForApp(params.old)
+	if err != nil {
+		return err
+	}
+	return updateAppNamespace(client, params.old.GetName(), ns)
 }
 
 var removeOldAppResources = action.Action{
@@ -122,7 +130,11 @@ var removeOldAppResources = action.Action{
 			log.Errorf("failed to remove old resources: %v", err)
 			return nil, nil
 		}
-		oldAppCR.Spec.NamespaceName = client.PoolNamespace(params.old.GetPool())
+		oldAppCR.Spec.NamespaceName, err = client.namespaceForApp(params.old)
+		if err != nil {
+			log.Errorf("failed to remove old resources: %v", err)
+			return nil, nil
+		}
 		err = params.p.removeResources(client, oldAppCR)
 		if err != nil {
 			log.Errorf("failed to remove old resources: %v", err)
@@ -19,10 +19,12 @@ import (
 	tsuruv1clientset "github.com/tsuru/tsuru/provision/kubernetes/pkg/client/clientset/versioned"
 	"github.com/tsuru/tsuru/servicemanager"
 	provTypes "github.com/tsuru/tsuru/types/provision"
+	apiv1 "k8s.io/api/core/v1"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -37,6 +39,22 @@ const (
 	namespaceLabelsKey     = "namespace-labels"
 	externalPolicyLocalKey = "external-policy-local"
 	routerAddressLocalKey  = "router-local"
+	minAvailableKey        = "disruption-budget-min-available"
+	nativeAutoScaleKey     = "native-autoscale"
+	antiAffinityKey        = "anti-affinity"
+	spreadTopologyKey      = "spread-topology-key"
+	tolerationsKey         = "tolerations"
+	namespaceIsolationKey  = "namespace-isolation"
+	networkPoliciesKey     = "network-policies"
+	networkPolicyCIDRsKey  = "network-policy-allowed-cidrs"
+	networkPolicyRouterKey = "network-policy-router-namespace"
+
+	namespaceIsolationPool = "pool"
+	namespaceIsolationTeam = "team"
+	namespaceIsolationApp  = "app"
+
+	defaultRouterNamespace = "ingress-nginx"
+	namespaceNameLabel     = "kubernetes.io/metadata.name"
 
 	dialTimeout  = 30 * time.Second
 	tcpKeepAlive = 30 * time.Second
@@ -52,6 +70,15 @@ var (
 		namespaceLabelsKey:     "Extra labels added to dynamically created namespaces in the format <label1>=<value1>,<label2>=<value2>... This config may be prefixed with `<pool-name>:`.",
 		externalPolicyLocalKey: "Use external policy local in created services. This is not recommended as depending on the used router it can cause downtimes during restarts. This config may be prefixed with `<pool-name>:`.",
 		routerAddressLocalKey:  "Only add node addresses that contains a pod from an app to the router. This config may be prefixed with `<pool-name>:`.",
+		minAvailableKey:        "Minimum number or percentage of available units of each app process during voluntary disruptions, like node drains, in the created pod disruption budgets. Percentages are rounded up. By default, pod disruption budgets allow one unavailable unit at a time, so that processes with a single unit don't block drains. This config may be prefixed with `<pool-name>:`.",
+		nativeAutoScaleKey:     "Use kubernetes horizontal pod autoscalers for the app autoscale settings with cpu or memory targets, instead of scaling units from tsuru. This config may be prefixed with `<pool-name>:`.",
+		antiAffinityKey:        "Anti-affinity between units of the same app process on a node, either `preferred` or `required`. Required anti-affinity leaves units pending when there are more units than nodes. May be overridden by the app annotation `tsuru.io/anti-affinity`. This config may be prefixed with `<pool-name>:`.",
+		spreadTopologyKey:      "Node label, e.g. failure-domain.beta.kubernetes.io/zone, across whose values the units of each app process are preferably spread. May be overridden by the app annotation `tsuru.io/spread-topology-key`. This config may be prefixed with `<pool-name>:`.",
+		tolerationsKey:         "Tolerations added to app units in the format <key>[=<value>][:<effect>],... Tolerations in the app annotation `tsuru.io/tolerations` are added to these. This config may be prefixed with `<pool-name>:`.",
+		namespaceIsolationKey:  "Namespace where new apps are created, either `pool` (default), `team`, for a namespace per team owner, or `app`, for a namespace per app. This config may be prefixed with `<pool-name>:`.",
+		networkPoliciesKey:     "Create network policies allowing traffic to app units only from units of the same app and of the callers listed in the app annotation `tsuru.io/allowed-callers`, as app names or `<service>/<instance>` for the apps bound to a service instance. This config may be prefixed with `<pool-name>:`.",
+		networkPolicyCIDRsKey:  "Comma separated list of CIDRs, usually the nodes and routers networks, always allowed to reach app units when network policies are enabled. This config may be prefixed with `<pool-name>:`.",
+		networkPolicyRouterKey: "Namespace of the router units, always allowed to reach app units when network policies are enabled. Defaults to `ingress-nginx`. This config may be prefixed with `<pool-name>:`.",
 	}
 )
 
@@ -206,6 +233,30 @@ func (c *ClusterClient) PoolNamespace(pool string) string {
 	return prefix
 }
 
+// namespaceForApp returns the namespace where the resources of a new app are
+// created, according to the namespace isolation of its pool. Existing apps
+// keep the namespace stored in their custom resource.
+func (c *ClusterClient) namespaceForApp(a provision.App) (string, error) {
+	pool := a.GetPool()
+	var isolation string
+	if c.CustomData != nil {
+		isolation = c.configForContext(pool, namespaceIsolationKey)
+	}
+	prefix := "tsuru"
+	if c.CustomData != nil && c.CustomData[namespaceClusterKey] != "" {
+		prefix = c.CustomData[namespaceClusterKey]
+	}
+	switch isolation {
+	case "", namespaceIsolationPool:
+		return c.PoolNamespace(pool), nil
+	case namespaceIsolationTeam:
+		return fmt.Sprintf("%s-team-%s", prefix, validKubeName(a.GetTeamOwner())), nil
+	case namespaceIsolationApp:
+		return fmt.Sprintf("%s-app-%s", prefix, validKubeName(a.GetName())), nil
+	}
+	return "", errors.Errorf("invalid namespace isolation %q, must be %q, %q or %q", isolation, namespaceIsolationPool, namespaceIsolationTeam, namespaceIsolationApp)
+}
+
 // Namespace returns the namespace to be used by Custom Resources
 func (c *ClusterClient) Namespace() string {
 	if c.CustomData != nil && c.CustomData[namespaceClusterKey] != "" {
@@ -253,6 +304,95 @@ func (c *ClusterClient) OvercommitFactor(pool string) (int64, error) {
 	return int64(overcommit), err
 }
 
+// MinAvailable returns the min available units configured for the pod
+// disruption budgets of the pool, or nil if not configured.
+func (c *ClusterClient) MinAvailable(pool string) (*intstr.IntOrString, error) {
+	if c.CustomData == nil {
+		return nil, nil
+	}
+	minAvailableConf := c.configForContext(pool, minAvailableKey)
+	if minAvailableConf == "" {
+		return nil, nil
+	}
+	value := strings.TrimSuffix(minAvailableConf, "%")
+	minAvailable, err := strconv.Atoi(value)
+	if err != nil || minAvailable < 0 {
+		return nil, errors.Errorf("invalid min available value %q", minAvailableConf)
+	}
+	result := intstr.FromInt(minAvailable)
+	if value != minAvailableConf {
+		result = intstr.FromString(minAvailableConf)
+	}
+	return &result, nil
+}
+
+func (c *ClusterClient) NativeAutoScale(pool string) (bool, error) {
+	if c.CustomData == nil {
+		return false, nil
+	}
+	nativeAutoScaleConf := c.configForContext(pool, nativeAutoScaleKey)
+	if nativeAutoScaleConf == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(nativeAutoScaleConf)
+}
+
+func (c *ClusterClient) AntiAffinity(pool string) string {
+	if c.CustomData == nil {
+		return ""
+	}
+	return c.configForContext(pool, antiAffinityKey)
+}
+
+func (c *ClusterClient) SpreadTopologyKey(pool string) string {
+	if c.CustomData == nil {
+		return ""
+	}
+	return c.configForContext(pool, spreadTopologyKey)
+}
+
+func (c *ClusterClient) Tolerations(pool string) ([]apiv1.Toleration, error) {
+	if c.CustomData == nil {
+		return nil, nil
+	}
+	return parseTolerations(c.configForContext(pool, tolerationsKey))
+}
+
+func (c *ClusterClient) NetworkPolicies(pool string) (bool, error) {
+	if c.CustomData == nil {
+		return false, nil
+	}
+	networkPoliciesConf := c.configForContext(pool, networkPoliciesKey)
+	if networkPoliciesConf == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(networkPoliciesConf)
+}
+
+func (c *ClusterClient) NetworkPolicyAllowedCIDRs(pool string) []string {
+	if c.CustomData == nil {
+		return nil
+	}
+	var cidrs []string
+	for _, cidr := range strings.Split(c.configForContext(pool, networkPolicyCIDRsKey), ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
+
+func (c *ClusterClient) NetworkPolicyRouterNamespace(pool string) string {
+	if c.CustomData == nil {
+		return defaultRouterNamespace
+	}
+	if ns := strings.TrimSpace(c.configForContext(pool, networkPolicyRouterKey)); ns != "" {
+		return ns
+	}
+	return defaultRouterNamespace
+}
+
 func (c *ClusterClient) namespaceLabels(ns string) (map[string]string, error) {
 	if c.CustomData == nil {
 		return nil, nil
@@ -303,21 +443,34 @@ func clustersForApps(apps []provision.App) ([]clusterApp, error) {
 	if err != nil {
 		return nil, err
 	}
+	var provClusters []provTypes.Cluster
 	for _, a := range apps {
 		poolName := a.GetPool()
-		cluster := clusterPoolMap[poolName]
-		mapItem, inMap := clusterClientMap[cluster.Name]
-		if !inMap {
-			cli, err := NewClusterClient(&cluster)
-			if err != nil {
-				return nil, err
+		clusters := []provTypes.Cluster{clusterPoolMap[poolName]}
+		if clusters[0].Weight > 0 {
+			if provClusters == nil {
+				provClusters, err = servicemanager.Cluster.FindByProvisioner(provisionerName)
+				if err != nil {
+					return nil, err
+				}
 			}
-			mapItem = clusterApp{
-				client: cli,
+			clusters = poolClusters(clusters[0], provClusters, poolName)
+		}
+		for i := range clusters {
+			cluster := clusters[i]
+			mapItem, inMap := clusterClientMap[cluster.Name]
+			if !inMap {
+				cli, err := NewClusterClient(&cluster)
+				if err != nil {
+					return nil, err
+				}
+				mapItem = clusterApp{
+					client: cli,
+				}
 			}
+			mapItem.apps = append(mapItem.apps, a)
+			clusterClientMap[cluster.Name] = mapItem
 		}
-		mapItem.apps = append(mapItem.apps, a)
-		clusterClientMap[cluster.Name] = mapItem
 	}
 	result := make([]clusterApp, 0, len(clusterClientMap))
 	for _, v := range clusterClientMap {
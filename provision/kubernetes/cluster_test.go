@@ -14,6 +14,7 @@ import (
 	"github.com/tsuru/tsuru/provision/provisiontest"
 	provTypes "github.com/tsuru/tsuru/types/provision"
 	check "gopkg.in/check.v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/rest"
 )
 
@@ -200,6 +201,64 @@ func (s *S) TestClusterNamespacePerPoolWithInvalidCharacters(c *check.C) {
 	c.Assert(client.PoolNamespace("my_pool has *INVALID* chars"), check.Equals, "tsuru-my-pool-has--invalid--chars")
 }
 
+func (s *S) TestClusterNamespaceForApp(c *check.C) {
+	c1 := provTypes.Cluster{Addresses: []string{"addr1"}, CustomData: map[string]string{
+		"namespace-isolation":           "team",
+		"apps-pool:namespace-isolation": "app",
+		"bad-pool:namespace-isolation":  "other",
+	}}
+	client, err := NewClusterClient(&c1)
+	c.Assert(err, check.IsNil)
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.TeamOwner = "my_team"
+	a.Pool = "mypool"
+	ns, err := client.namespaceForApp(a)
+	c.Assert(err, check.IsNil)
+	c.Assert(ns, check.Equals, "tsuru-team-my-team")
+	a.Pool = "apps-pool"
+	ns, err = client.namespaceForApp(a)
+	c.Assert(err, check.IsNil)
+	c.Assert(ns, check.Equals, "tsuru-app-myapp")
+	a.Pool = "bad-pool"
+	_, err = client.namespaceForApp(a)
+	c.Assert(err, check.ErrorMatches, `invalid namespace isolation "other", must be "pool", "team" or "app"`)
+	c1.CustomData = map[string]string{"namespace": "x", "namespace-isolation": "app"}
+	client, err = NewClusterClient(&c1)
+	c.Assert(err, check.IsNil)
+	a.Pool = "mypool"
+	ns, err = client.namespaceForApp(a)
+	c.Assert(err, check.IsNil)
+	c.Assert(ns, check.Equals, "x-app-myapp")
+	c1.CustomData = nil
+	client, err = NewClusterClient(&c1)
+	c.Assert(err, check.IsNil)
+	ns, err = client.namespaceForApp(a)
+	c.Assert(err, check.IsNil)
+	c.Assert(ns, check.Equals, client.PoolNamespace("mypool"))
+}
+
+func (s *S) TestClusterNetworkPolicies(c *check.C) {
+	c1 := provTypes.Cluster{Addresses: []string{"addr1"}, CustomData: map[string]string{
+		"network-policies":                   "true",
+		"other:network-policies":             "false",
+		"invalid:network-policies":           "xyz",
+		"network-policy-allowed-cidrs":       "10.0.0.0/8, 192.168.0.0/16,",
+		"other:network-policy-allowed-cidrs": "172.16.0.0/12",
+	}}
+	client, err := NewClusterClient(&c1)
+	c.Assert(err, check.IsNil)
+	enabled, err := client.NetworkPolicies("mypool")
+	c.Assert(err, check.IsNil)
+	c.Assert(enabled, check.Equals, true)
+	enabled, err = client.NetworkPolicies("other")
+	c.Assert(err, check.IsNil)
+	c.Assert(enabled, check.Equals, false)
+	_, err = client.NetworkPolicies("invalid")
+	c.Assert(err, check.NotNil)
+	c.Assert(client.NetworkPolicyAllowedCIDRs("mypool"), check.DeepEquals, []string{"10.0.0.0/8", "192.168.0.0/16"})
+	c.Assert(client.NetworkPolicyAllowedCIDRs("other"), check.DeepEquals, []string{"172.16.0.0/12"})
+}
+
 func (s *S) TestClusterOvercommitFactor(c *check.C) {
 	c1 := provTypes.Cluster{Addresses: []string{"addr1"}, CustomData: map[string]string{
 		"overcommit-factor":         "2",
@@ -219,6 +278,43 @@ func (s *S) TestClusterOvercommitFactor(c *check.C) {
 	c.Assert(ovf, check.Equals, int64(0))
 }
 
+func (s *S) TestClusterMinAvailable(c *check.C) {
+	c1 := provTypes.Cluster{Addresses: []string{"addr1"}, CustomData: map[string]string{
+		"disruption-budget-min-available":         "2",
+		"my-pool:disruption-budget-min-available": "75%",
+		"invalid:disruption-budget-min-available": "a%",
+	}}
+	client, err := NewClusterClient(&c1)
+	c.Assert(err, check.IsNil)
+	minAvailable, err := client.MinAvailable("my-pool")
+	c.Assert(err, check.IsNil)
+	c.Assert(*minAvailable, check.Equals, intstr.FromString("75%"))
+	minAvailable, err = client.MinAvailable("global")
+	c.Assert(err, check.IsNil)
+	c.Assert(*minAvailable, check.Equals, intstr.FromInt(2))
+	_, err = client.MinAvailable("invalid")
+	c.Assert(err, check.ErrorMatches, `invalid min available value "a%"`)
+	client, err = NewClusterClient(&provTypes.Cluster{Addresses: []string{"addr1"}})
+	c.Assert(err, check.IsNil)
+	minAvailable, err = client.MinAvailable("my-pool")
+	c.Assert(err, check.IsNil)
+	c.Assert(minAvailable, check.IsNil)
+}
+
+func (s *S) TestClusterNativeAutoScale(c *check.C) {
+	c1 := provTypes.Cluster{Addresses: []string{"addr1"}, CustomData: map[string]string{
+		"my-pool:native-autoscale": "true",
+	}}
+	client, err := NewClusterClient(&c1)
+	c.Assert(err, check.IsNil)
+	native, err := client.NativeAutoScale("my-pool")
+	c.Assert(err, check.IsNil)
+	c.Assert(native, check.Equals, true)
+	native, err = client.NativeAutoScale("other")
+	c.Assert(err, check.IsNil)
+	c.Assert(native, check.Equals, false)
+}
+
 func (s *S) TestClustersForApps(c *check.C) {
 	c1 := provTypes.Cluster{
 		Name:        "c1",
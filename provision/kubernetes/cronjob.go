@@ -0,0 +1,356 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// cronJobNameMaxLen is the max length of cron job names, the jobs
+	// created from them are named after the cron job with an 11 characters
+	// suffix.
+	cronJobNameMaxLen = 52
+
+	jobRunRecordedAnnotation = tsuruLabelPrefix + "job-run-recorded"
+)
+
+var jobPodLogs = func(client *ClusterClient, ns, pod, container string) ([]byte, error) {
+	return client.CoreV1().Pods(ns).GetLogs(pod, &apiv1.PodLogOptions{Container: container}).DoRaw()
+}
+
+var cronJobConcurrencyPolicies = map[string]batchv1beta1.ConcurrencyPolicy{
+	appTypes.JobConcurrencyAllow:   batchv1beta1.AllowConcurrent,
+	appTypes.JobConcurrencyForbid:  batchv1beta1.ForbidConcurrent,
+	appTypes.JobConcurrencyReplace: batchv1beta1.ReplaceConcurrent,
+}
+
+func cronJobNameForApp(a provision.App, job string) string {
+	name := validKubeName(a.GetName())
+	job = validKubeName(job)
+	cronJobName := fmt.Sprintf("%s-%s", name, job)
+	if len(cronJobName) > cronJobNameMaxLen {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(job)))
+		maxLen := cronJobNameMaxLen - len(name) - 1
+		if len(hash) > maxLen {
+			hash = hash[:maxLen]
+		}
+		cronJobName = fmt.Sprintf("%s-%s", name, hash)
+	}
+	return cronJobName
+}
+
+func jobLabels(a provision.App, job string) (*provision.LabelSet, error) {
+	ls, err := provision.ServiceLabels(provision.ServiceLabelsOpts{
+		App: a,
+		ServiceLabelExtendedOpts: provision.ServiceLabelExtendedOpts{
+			Prefix:        tsuruLabelPrefix,
+			Provisioner:   provisionerName,
+			IsIsolatedRun: true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ls = ls.WithoutAppReplicas()
+	ls.SetAppJob(job)
+	return ls, nil
+}
+
+func jobCmds(command string) []string {
+	return []string{"/bin/sh", "-lc", "[ -d /home/application/current ] && cd /home/application/current; " + command}
+}
+
+func newCronJob(client *ClusterClient, a provision.App, job appTypes.Job, imageName string) (*batchv1beta1.CronJob, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := jobLabels(a, job.Name)
+	if err != nil {
+		return nil, err
+	}
+	pullSecrets, err := getImagePullSecrets(client, ns, imageName)
+	if err != nil {
+		return nil, err
+	}
+	nodeSelector := provision.NodeLabels(provision.NodeLabelsOpts{
+		Pool:   a.GetPool(),
+		Prefix: tsuruLabelPrefix,
+	}).ToNodeByPoolSelector()
+	var envs []apiv1.EnvVar
+	for _, envData := range provision.EnvsForApp(a, "", false) {
+		envs = append(envs, apiv1.EnvVar{Name: envData.Name, Value: envData.Value})
+	}
+	name := cronJobNameForApp(a, job.Name)
+	historyLimit := int32(job.HistoryLimit)
+	backoffLimit := int32(0)
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels:    ls.ToLabels(),
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   job.Schedule,
+			ConcurrencyPolicy:          cronJobConcurrencyPolicies[job.ConcurrencyPolicy],
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: ls.ToLabels(),
+				},
+				Spec: batchv1.JobSpec{
+					// Failed runs are not retried, the next run happens on
+					// schedule.
+					BackoffLimit: &backoffLimit,
+					Template: apiv1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: ls.ToLabels(),
+						},
+						Spec: apiv1.PodSpec{
+							ImagePullSecrets:   pullSecrets,
+							ServiceAccountName: serviceAccountNameForApp(a),
+							NodeSelector:       nodeSelector,
+							RestartPolicy:      apiv1.RestartPolicyNever,
+							Containers: []apiv1.Container{
+								{
+									Name:    name,
+									Image:   imageName,
+									Command: jobCmds(job.Command),
+									Env:     envs,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// ensureCronJobs makes the cron jobs of the app match its jobs, running them
+// with the given image or the app current image if empty. Jobs of apps never
+// deployed are created on the first deploy.
+func ensureCronJobs(client *ClusterClient, a provision.App, imageName string) error {
+	if imageName == "" {
+		var err error
+		imageName, err = image.AppCurrentImageName(a.GetName())
+		if err != nil {
+			if errors.Cause(err) == image.ErrNoImagesAvailable {
+				return nil
+			}
+			return err
+		}
+	}
+	err := ensureNamespaceForApp(client, a)
+	if err != nil {
+		return err
+	}
+	err = ensureServiceAccountForApp(client, a)
+	if err != nil {
+		return err
+	}
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	ls, err := jobLabels(a, "")
+	if err != nil {
+		return err
+	}
+	existing, err := client.BatchV1beta1().CronJobs(ns).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set(ls.ToAppSelector())).String(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	current := map[string]*batchv1beta1.CronJob{}
+	for i := range existing.Items {
+		current[existing.Items[i].Name] = &existing.Items[i]
+	}
+	for _, job := range a.GetJobs() {
+		cronJob, err := newCronJob(client, a, job, imageName)
+		if err != nil {
+			return err
+		}
+		old, ok := current[cronJob.Name]
+		delete(current, cronJob.Name)
+		if !ok {
+			_, err = client.BatchV1beta1().CronJobs(ns).Create(cronJob)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+		if reflect.DeepEqual(old.Spec, cronJob.Spec) && reflect.DeepEqual(old.Labels, cronJob.Labels) {
+			continue
+		}
+		old.Labels = cronJob.Labels
+		old.Spec = cronJob.Spec
+		_, err = client.BatchV1beta1().CronJobs(ns).Update(old)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for name := range current {
+		err = client.BatchV1beta1().CronJobs(ns).Delete(name, &metav1.DeleteOptions{
+			PropagationPolicy: propagationPtr(metav1.DeletePropagationForeground),
+		})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// removeCronJobs removes every cron job of the app, along with their jobs
+// and pods.
+func removeCronJobs(client *ClusterClient, ns, appName string) error {
+	ls := provision.LabelSet{Prefix: tsuruLabelPrefix}
+	ls.SetAppName(appName)
+	err := client.BatchV1beta1().CronJobs(ns).DeleteCollection(&metav1.DeleteOptions{
+		PropagationPolicy: propagationPtr(metav1.DeletePropagationForeground),
+	}, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set(ls.ToAppSelector())).String(),
+	})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// jobFinished returns whether the job has finished and the reason of its
+// failure, if it failed.
+func jobFinished(job *batchv1.Job) (bool, string) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != apiv1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return true, ""
+		case batchv1.JobFailed:
+			if cond.Message != "" {
+				return true, fmt.Sprintf("%s: %s", cond.Reason, cond.Message)
+			}
+			return true, cond.Reason
+		}
+	}
+	return false, ""
+}
+
+// recordJobRun records a finished run of an app job in an event, with its
+// exit status, and writes its output to the app logs. Jobs are annotated
+// before being recorded so each run is recorded only once.
+func recordJobRun(client *ClusterClient, job *batchv1.Job) error {
+	labelSet := labelSetFromMeta(&job.ObjectMeta)
+	appName, jobName := labelSet.AppName(), labelSet.AppJob()
+	if appName == "" || jobName == "" || job.Annotations[jobRunRecordedAnnotation] == "true" {
+		return nil
+	}
+	finished, failure := jobFinished(job)
+	if !finished {
+		return nil
+	}
+	job = job.DeepCopy()
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[jobRunRecordedAnnotation] = "true"
+	_, err := client.BatchV1().Jobs(job.Namespace).Update(job)
+	if err != nil {
+		if k8sErrors.IsConflict(err) || k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	a, err := app.GetByName(appName)
+	if err != nil {
+		if err == appTypes.ErrAppNotFound {
+			return nil
+		}
+		return err
+	}
+	appJob, err := a.GetJob(jobName)
+	removedJob := err == appTypes.ErrJobNotFound
+	if removedJob {
+		appJob = &appTypes.Job{Name: jobName}
+	}
+	run := &appTypes.JobRun{Job: jobName, EndTime: time.Now().UTC()}
+	if job.Status.StartTime != nil {
+		run.StartTime = job.Status.StartTime.Time.UTC()
+	}
+	if job.Status.CompletionTime != nil {
+		run.EndTime = job.Status.CompletionTime.Time.UTC()
+	}
+	var output []byte
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	pods, err := client.CoreV1().Pods(job.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(pods.Items) > 0 {
+		pod := pods.Items[len(pods.Items)-1]
+		run.Unit = pod.Name
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				exitCode := int(status.State.Terminated.ExitCode)
+				run.ExitCode = &exitCode
+			}
+		}
+		if len(pod.Spec.Containers) > 0 {
+			output, err = jobPodLogs(client, job.Namespace, pod.Name, pod.Spec.Containers[0].Name)
+			if err != nil {
+				log.Errorf("[job-controller] unable to get logs of job %q of app %q: %v", jobName, appName, err)
+			}
+		}
+	}
+	evt, err := a.NewJobRunEvent(*appJob, run, false)
+	if err != nil {
+		return err
+	}
+	logWriter := app.LogWriter{AppName: appName, Source: appTypes.JobLogSource(jobName)}
+	for _, line := range bytes.Split(bytes.TrimSpace(output), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		evt.Write(append(line, '\n'))
+		logWriter.Write(line)
+	}
+	var runErr error
+	if failure != "" {
+		runErr = errors.Errorf("job run failed: %s", failure)
+	}
+	if !removedJob && !run.StartTime.IsZero() {
+		err = a.UpdateJobLastRun(jobName, run.StartTime)
+		if err != nil {
+			log.Errorf("[job-controller] unable to update last run of job %q of app %q: %v", jobName, appName, err)
+		}
+	}
+	return evt.DoneCustomData(runErr, run)
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (s *S) TestCronJobNameForApp(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	c.Assert(cronJobNameForApp(a, "cleanup"), check.Equals, "myapp-cleanup")
+	a = provisiontest.NewFakeApp(strings.Repeat("a", 40), "python", 0)
+	name := cronJobNameForApp(a, "cleanup-old-sessions")
+	c.Assert(name, check.HasLen, cronJobNameMaxLen)
+	c.Assert(strings.HasPrefix(name, strings.Repeat("a", 40)+"-"), check.Equals, true)
+	c.Assert(cronJobNameForApp(a, "cleanup-old-sessions"), check.Equals, name)
+	c.Assert(cronJobNameForApp(a, "cleanup-old-tokens"), check.Not(check.Equals), name)
+}
+
+func (s *S) TestEnsureCronJobs(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.SetEnv(bind.EnvVar{Name: "MY_VAR", Value: "value"})
+	a.Jobs = []appTypes.Job{
+		{Name: "cleanup", Schedule: "*/5 * * * *", Command: "./cleanup.sh", ConcurrencyPolicy: appTypes.JobConcurrencyForbid, HistoryLimit: 2},
+		{Name: "report", Schedule: "@daily", Command: "./report.sh", ConcurrencyPolicy: appTypes.JobConcurrencyAllow, HistoryLimit: 3},
+	}
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	err = ensureCronJobs(s.clusterClient, a, "")
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	cronJobs, err := s.client.BatchV1beta1().CronJobs(ns).List(metav1.ListOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(cronJobs.Items, check.HasLen, 0)
+	err = image.AppendAppImageName(a.GetName(), "tsuru/app-myapp:v1")
+	c.Assert(err, check.IsNil)
+	err = ensureCronJobs(s.clusterClient, a, "")
+	c.Assert(err, check.IsNil)
+	cronJob, err := s.client.BatchV1beta1().CronJobs(ns).Get("myapp-cleanup", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(cronJob.Labels["tsuru.io/app-name"], check.Equals, "myapp")
+	c.Assert(cronJob.Labels["tsuru.io/app-job"], check.Equals, "cleanup")
+	c.Assert(cronJob.Spec.Schedule, check.Equals, "*/5 * * * *")
+	c.Assert(cronJob.Spec.ConcurrencyPolicy, check.Equals, batchv1beta1.ForbidConcurrent)
+	c.Assert(*cronJob.Spec.SuccessfulJobsHistoryLimit, check.Equals, int32(2))
+	c.Assert(*cronJob.Spec.FailedJobsHistoryLimit, check.Equals, int32(2))
+	c.Assert(*cronJob.Spec.JobTemplate.Spec.BackoffLimit, check.Equals, int32(0))
+	podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+	c.Assert(podSpec.RestartPolicy, check.Equals, apiv1.RestartPolicyNever)
+	c.Assert(podSpec.ServiceAccountName, check.Equals, "app-myapp")
+	c.Assert(podSpec.Containers, check.HasLen, 1)
+	c.Assert(podSpec.Containers[0].Image, check.Equals, "tsuru/app-myapp:v1")
+	c.Assert(podSpec.Containers[0].Command, check.DeepEquals, []string{
+		"/bin/sh", "-lc", "[ -d /home/application/current ] && cd /home/application/current; ./cleanup.sh",
+	})
+	c.Assert(podSpec.Containers[0].Env[0], check.DeepEquals, apiv1.EnvVar{Name: "MY_VAR", Value: "value"})
+	a.Jobs = []appTypes.Job{
+		{Name: "cleanup", Schedule: "0 * * * *", Command: "./cleanup.sh", ConcurrencyPolicy: appTypes.JobConcurrencyReplace, HistoryLimit: 2},
+	}
+	err = ensureCronJobs(s.clusterClient, a, "tsuru/app-myapp:v2")
+	c.Assert(err, check.IsNil)
+	cronJobs, err = s.client.BatchV1beta1().CronJobs(ns).List(metav1.ListOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(cronJobs.Items, check.HasLen, 1)
+	cronJob = &cronJobs.Items[0]
+	c.Assert(cronJob.Name, check.Equals, "myapp-cleanup")
+	c.Assert(cronJob.Spec.Schedule, check.Equals, "0 * * * *")
+	c.Assert(cronJob.Spec.ConcurrencyPolicy, check.Equals, batchv1beta1.ReplaceConcurrent)
+	c.Assert(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image, check.Equals, "tsuru/app-myapp:v2")
+	err = removeCronJobs(s.clusterClient, ns, a.GetName())
+	c.Assert(err, check.IsNil)
+	cronJobs, err = s.client.BatchV1beta1().CronJobs(ns).List(metav1.ListOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(cronJobs.Items, check.HasLen, 0)
+}
+
+func (s *S) TestRecordJobRun(c *check.C) {
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetJob(appTypes.Job{Name: "cleanup", Schedule: "@hourly", Command: "./cleanup.sh"})
+	c.Assert(err, check.IsNil)
+	oldPodLogs := jobPodLogs
+	defer func() { jobPodLogs = oldPodLogs }()
+	jobPodLogs = func(client *ClusterClient, ns, pod, container string) ([]byte, error) {
+		c.Check(pod, check.Equals, "myapp-cleanup-1552644000-x1z2")
+		c.Check(container, check.Equals, "myapp-cleanup")
+		return []byte("removed 3 sessions\ndone\n"), nil
+	}
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	ls, err := jobLabels(a, "cleanup")
+	c.Assert(err, check.IsNil)
+	startTime := metav1.NewTime(time.Date(2019, 3, 15, 10, 0, 0, 0, time.UTC))
+	completionTime := metav1.NewTime(startTime.Add(10 * time.Second))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp-cleanup-1552644000",
+			Namespace: ns,
+			Labels:    ls.ToLabels(),
+		},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "myapp-cleanup-1552644000"}},
+		},
+		Status: batchv1.JobStatus{
+			StartTime:      &startTime,
+			CompletionTime: &completionTime,
+			Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: apiv1.ConditionTrue},
+			},
+		},
+	}
+	_, err = s.client.BatchV1().Jobs(ns).Create(job)
+	c.Assert(err, check.IsNil)
+	_, err = s.client.CoreV1().Pods(ns).Create(&apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp-cleanup-1552644000-x1z2",
+			Namespace: ns,
+			Labels:    map[string]string{"job-name": "myapp-cleanup-1552644000"},
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: "myapp-cleanup"}},
+		},
+		Status: apiv1.PodStatus{
+			ContainerStatuses: []apiv1.ContainerStatus{{
+				State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 0}},
+			}},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	err = recordJobRun(s.clusterClient, job)
+	c.Assert(err, check.IsNil)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeAppJob, Value: "myapp/cleanup"},
+		ExtraTargets: []event.ExtraTarget{
+			{Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}},
+		},
+		Kind:       "app.run.job",
+		Owner:      "job",
+		LogMatches: `(?s)removed 3 sessions.*done.*`,
+	}, eventtest.HasEvent)
+	dbJob, err := s.client.BatchV1().Jobs(ns).Get(job.Name, metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(dbJob.Annotations[jobRunRecordedAnnotation], check.Equals, "true")
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs[0].LastRun.Equal(startTime.Time), check.Equals, true)
+	err = recordJobRun(s.clusterClient, dbJob)
+	c.Assert(err, check.IsNil)
+	evts, err := event.List(&event.Filter{KindNames: []string{"app.run.job"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+}
+
+func (s *S) TestRecordJobRunNotFinished(c *check.C) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp-cleanup-1552644000",
+			Namespace: "default",
+			Labels:    map[string]string{"tsuru.io/app-name": "myapp", "tsuru.io/app-job": "cleanup"},
+		},
+	}
+	err := recordJobRun(s.clusterClient, job)
+	c.Assert(err, check.IsNil)
+	evts, err := event.List(&event.Filter{KindNames: []string{"app.run.job"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 0)
+}
@@ -443,6 +443,88 @@ func probesFromHC(hc *provTypes.TsuruYamlHealthcheck, port int) (hcResult, error
 	return result, nil
 }
 
+// probeFromConfig returns the kubernetes probe for a probe configured in the
+// tsuru.yaml, using port for http probes without a port.
+func probeFromConfig(p *provTypes.TsuruYamlProbe, port int) (*apiv1.Probe, error) {
+	if p.Port != 0 {
+		port = p.Port
+	}
+	probe := &apiv1.Probe{
+		InitialDelaySeconds: int32(p.InitialDelaySeconds),
+		PeriodSeconds:       int32(p.IntervalSeconds),
+		TimeoutSeconds:      int32(p.TimeoutSeconds),
+		FailureThreshold:    int32(p.AllowedFailures),
+	}
+	switch {
+	case len(p.Command) > 0:
+		probe.Exec = &apiv1.ExecAction{Command: p.Command}
+	case p.Path != "":
+		if port == 0 {
+			return nil, errors.New("probe: port is required for http probes in processes without ports")
+		}
+		scheme := p.Scheme
+		if scheme == "" {
+			scheme = provision.DefaultHealthcheckScheme
+		}
+		headers := []apiv1.HTTPHeader{}
+		for header, value := range p.Headers {
+			headers = append(headers, apiv1.HTTPHeader{Name: header, Value: value})
+		}
+		sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+		probe.HTTPGet = &apiv1.HTTPGetAction{
+			Path:        p.Path,
+			Port:        intstr.FromInt(port),
+			Scheme:      apiv1.URIScheme(strings.ToUpper(scheme)),
+			HTTPHeaders: headers,
+		}
+	case p.Port != 0:
+		probe.TCPSocket = &apiv1.TCPSocketAction{Port: intstr.FromInt(port)}
+	default:
+		return nil, errors.New("probe: one of command, path or port is required")
+	}
+	return probe, nil
+}
+
+// probesForProcess returns the probes of a process, from its healthcheck
+// and from the liveness and startup settings in the tsuru.yaml. The startup
+// time is applied as the initial delay of the liveness probe, as startup
+// probes aren't available in the kubernetes API version in use, so a startup
+// setting without a liveness probe is rejected.
+func probesForProcess(yamlData provTypes.TsuruYamlData, process, webProcessName string, processPorts []provTypes.TsuruYamlKubernetesProcessPortConfig) (hcResult, error) {
+	var result hcResult
+	var err error
+	port := 0
+	if len(processPorts) > 0 {
+		port = processPorts[0].TargetPort
+		//TODO: add support to multiple HCs
+		result, err = probesFromHC(yamlData.ProcessHealthcheck(process, webProcessName), port)
+		if err != nil {
+			return result, err
+		}
+	}
+	processConfig := yamlData.ProcessConfig(process)
+	if processConfig.Liveness != nil {
+		result.liveness, err = probeFromConfig(processConfig.Liveness, port)
+		if err != nil {
+			return result, errors.Wrapf(err, "liveness of process %q", process)
+		}
+	}
+	if processConfig.Startup != nil {
+		if result.liveness == nil {
+			return result, &tsuruErrors.ValidationError{
+				Message: fmt.Sprintf("startup of process %q requires a liveness probe", process),
+			}
+		}
+		liveness := *result.liveness
+		startupSeconds := int32(processConfig.Startup.StartupSeconds())
+		if liveness.InitialDelaySeconds < startupSeconds {
+			liveness.InitialDelaySeconds = startupSeconds
+		}
+		result.liveness = &liveness
+	}
+	return result, nil
+}
+
 func ensureNamespaceForApp(client *ClusterClient, app provision.App) error {
 	ns, err := client.AppNamespace(app)
 	if err != nil {
@@ -525,13 +607,9 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 	if err != nil {
 		return nil, nil, nil, errors.WithStack(err)
 	}
-	var hcData hcResult
-	if process == webProcessName && len(processPorts) > 0 {
-		//TODO: add support to multiple HCs
-		hcData, err = probesFromHC(yamlData.Healthcheck, processPorts[0].TargetPort)
-		if err != nil {
-			return nil, nil, nil, err
-		}
+	hcData, err := probesForProcess(yamlData, process, webProcessName, processPorts)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 	var lifecycle *apiv1.Lifecycle
 	if yamlData.Hooks != nil && len(yamlData.Hooks.Restart.After) > 0 {
@@ -547,6 +625,23 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 			},
 		}
 	}
+	var terminationGracePeriod *int64
+	if processLifecycle := yamlData.ProcessConfig(process).Lifecycle; processLifecycle != nil {
+		if len(processLifecycle.PreStop) > 0 {
+			if lifecycle == nil {
+				lifecycle = &apiv1.Lifecycle{}
+			}
+			lifecycle.PreStop = &apiv1.Handler{
+				Exec: &apiv1.ExecAction{
+					Command: []string{"sh", "-c", strings.Join(processLifecycle.PreStop, " && ")},
+				},
+			}
+		}
+		if processLifecycle.TerminationGracePeriodSeconds > 0 {
+			gracePeriod := int64(processLifecycle.TerminationGracePeriodSeconds)
+			terminationGracePeriod = &gracePeriod
+		}
+	}
 	maxSurge := intstr.FromString("100%")
 	maxUnavailable := intstr.FromInt(0)
 	nodeSelector := provision.NodeLabels(provision.NodeLabelsOpts{
@@ -560,20 +655,38 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 		return nil, nil, nil, errors.WithMessage(err, "misconfigured cluster overcommit factor")
 	}
 	resourceRequests := apiv1.ResourceList{}
-	memory := a.GetMemory()
-	if memory != 0 {
-		resourceLimits[apiv1.ResourceMemory] = *resource.NewQuantity(memory, resource.BinarySI)
-		resourceRequests[apiv1.ResourceMemory] = *resource.NewQuantity(memory/overcommit, resource.BinarySI)
+	resources := a.GetProcessResources(process)
+	if resources.Memory != 0 {
+		resourceLimits[apiv1.ResourceMemory] = *resource.NewQuantity(resources.Memory, resource.BinarySI)
+		resourceRequests[apiv1.ResourceMemory] = *resource.NewQuantity(resources.Memory/overcommit, resource.BinarySI)
+	}
+	if resources.MemoryRequest != 0 {
+		resourceRequests[apiv1.ResourceMemory] = *resource.NewQuantity(resources.MemoryRequest, resource.BinarySI)
+	}
+	if resources.CPULimit != 0 {
+		resourceLimits[apiv1.ResourceCPU] = *resource.NewMilliQuantity(int64(resources.CPULimit), resource.DecimalSI)
+		resourceRequests[apiv1.ResourceCPU] = *resource.NewMilliQuantity(int64(resources.CPULimit)/overcommit, resource.DecimalSI)
+	}
+	if resources.CPURequest != 0 {
+		resourceRequests[apiv1.ResourceCPU] = *resource.NewMilliQuantity(int64(resources.CPURequest), resource.DecimalSI)
 	}
+	processConfig := yamlData.ProcessConfig(process)
+	err = validateProcessContainers(a, depName, processConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	extraContainers := processContainers(processConfig)
 	volumes, mounts, err := createVolumesForApp(client, a)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	volumes = append(volumes, extraContainers.volumes...)
+	mounts = append(mounts, extraContainers.mounts...)
 	ns, err := client.AppNamespace(a)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	pullSecrets, err := getImagePullSecrets(client, ns, imageName)
+	pullSecrets, err := getImagePullSecrets(client, ns, append([]string{imageName}, extraContainers.images...)...)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -586,6 +699,22 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 	_, tag := image.SplitImageName(imageName)
 	expandedLabels["version"] = tag
 	expandedLabelsNoReplicas["version"] = tag
+	podAnnotations := annotations.ToLabels()
+	metadata := a.GetMetadata()
+	for _, item := range metadata.Labels {
+		if _, ok := expandedLabelsNoReplicas[item.Name]; !ok {
+			expandedLabelsNoReplicas[item.Name] = item.Value
+		}
+	}
+	for _, item := range metadata.Annotations {
+		if _, ok := podAnnotations[item.Name]; !ok {
+			podAnnotations[item.Name] = item.Value
+		}
+	}
+	affinity, tolerations, err := schedulingForApp(client, a, labels.ToSelector())
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	containerPorts := make([]apiv1.ContainerPort, len(processPorts))
 	for i, port := range processPorts {
 		portInt := port.TargetPort
@@ -618,7 +747,7 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 			Template: apiv1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      expandedLabelsNoReplicas,
-					Annotations: annotations.ToLabels(),
+					Annotations: podAnnotations,
 				},
 				Spec: apiv1.PodSpec{
 					ImagePullSecrets:   pullSecrets,
@@ -626,11 +755,14 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 					SecurityContext: &apiv1.PodSecurityContext{
 						RunAsUser: uid,
 					},
-					RestartPolicy: apiv1.RestartPolicyAlways,
-					NodeSelector:  nodeSelector,
-					Volumes:       volumes,
-					Subdomain:     headlessServiceNameForApp(a, process),
-					Containers: []apiv1.Container{
+					RestartPolicy:                 apiv1.RestartPolicyAlways,
+					NodeSelector:                  nodeSelector,
+					Affinity:                      affinity,
+					Tolerations:                   tolerations,
+					Volumes:                       volumes,
+					Subdomain:                     headlessServiceNameForApp(a, process),
+					TerminationGracePeriodSeconds: terminationGracePeriod,
+					Containers: append([]apiv1.Container{
 						{
 							Name:           depName,
 							Image:          imageName,
@@ -646,7 +778,8 @@ func createAppDeployment(client *ClusterClient, oldDeployment *appsv1.Deployment
 							Ports:        containerPorts,
 							Lifecycle:    lifecycle,
 						},
-					},
+					}, extraContainers.sidecars...),
+					InitContainers: extraContainers.initContainers,
 				},
 			},
 		},
@@ -700,6 +833,14 @@ func (m *serviceManager) RemoveService(a provision.App, process string) error {
 	if err != nil && !k8sErrors.IsNotFound(err) {
 		multiErrors.Add(errors.WithStack(err))
 	}
+	err = removePDB(m.client, a, process)
+	if err != nil {
+		multiErrors.Add(err)
+	}
+	err = removeHPA(m.client, a, process)
+	if err != nil {
+		multiErrors.Add(err)
+	}
 	return multiErrors.ToError()
 }
 
@@ -918,6 +1059,10 @@ func (m *serviceManager) DeployService(ctx context.Context, a provision.App, pro
 	if err != nil {
 		return err
 	}
+	err = ensureNetworkPolicy(m.client, a)
+	if err != nil {
+		return err
+	}
 	depName := deploymentNameForApp(a, process)
 	ns, err := m.client.AppNamespace(a)
 	if err != nil {
@@ -1069,7 +1214,11 @@ func (m *serviceManager) DeployService(ctx context.Context, a provision.App, pro
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	return nil
+	err = ensurePDB(m.client, a, process)
+	if err != nil {
+		return err
+	}
+	return ensureHPA(m.client, a, process)
 }
 
 func loadServicePorts(imgName, processName string) ([]apiv1.ServicePort, error) {
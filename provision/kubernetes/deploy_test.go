@@ -22,11 +22,13 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/image"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/kubernetes/testing"
 	"github.com/tsuru/tsuru/provision/nodecontainer"
+	"github.com/tsuru/tsuru/provision/pool"
 	"github.com/tsuru/tsuru/provision/servicecommon"
 	"github.com/tsuru/tsuru/safe"
 	appTypes "github.com/tsuru/tsuru/types/app"
@@ -941,6 +943,134 @@ func (s *S) TestServiceManagerDeployServiceWithRestartHooks(c *check.C) {
 	c.Assert(cmd[2], check.Matches, `.*before cmd1 && before cmd2 && exec proc2$`)
 }
 
+func (s *S) TestProbesForProcessStartupWithoutLiveness(c *check.C) {
+	yamlData := provTypes.TsuruYamlData{
+		Processes: map[string]provTypes.TsuruYamlProcessConfig{
+			"worker": {
+				Startup: &provTypes.TsuruYamlProbe{IntervalSeconds: 10, AllowedFailures: 12},
+			},
+		},
+	}
+	_, err := probesForProcess(yamlData, "worker", "web", nil)
+	c.Assert(err, check.DeepEquals, &tsuruErrors.ValidationError{Message: `startup of process "worker" requires a liveness probe`})
+}
+
+func (s *S) TestServiceManagerDeployServiceWithProcessConfig(c *check.C) {
+	waitDep := s.mock.DeploymentReactions(c)
+	defer waitDep()
+	m := serviceManager{client: s.clusterClient}
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.SaveImageCustomData("myimg", map[string]interface{}{
+		"processes": map[string]interface{}{
+			"web":    "proc1",
+			"worker": "proc2",
+		},
+		"healthcheck": provTypes.TsuruYamlHealthcheck{
+			Path: "/hc",
+		},
+		"process_config": map[string]provTypes.TsuruYamlProcessConfig{
+			"web": {
+				Liveness: &provTypes.TsuruYamlProbe{
+					Path:            "/alive",
+					IntervalSeconds: 5,
+				},
+				Startup: &provTypes.TsuruYamlProbe{
+					IntervalSeconds: 10,
+					AllowedFailures: 12,
+				},
+			},
+			"worker": {
+				Liveness: &provTypes.TsuruYamlProbe{
+					Command:         []string{"./check-worker.sh"},
+					AllowedFailures: 2,
+				},
+				Lifecycle: &provTypes.TsuruYamlLifecycle{
+					PreStop:                       []string{"./drain.sh", "sleep 5"},
+					TerminationGracePeriodSeconds: 120,
+				},
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	err = servicecommon.RunServicePipeline(&m, a, "myimg", servicecommon.ProcessSpec{
+		"web":    servicecommon.ProcessState{Start: true},
+		"worker": servicecommon.ProcessState{Start: true},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	waitDep()
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	dep, err := s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	container := dep.Spec.Template.Spec.Containers[0]
+	c.Assert(container.ReadinessProbe, check.NotNil)
+	c.Assert(container.ReadinessProbe.HTTPGet.Path, check.Equals, "/hc")
+	c.Assert(container.LivenessProbe, check.DeepEquals, &apiv1.Probe{
+		Handler: apiv1.Handler{
+			HTTPGet: &apiv1.HTTPGetAction{
+				Path:        "/alive",
+				Port:        intstr.FromInt(8888),
+				Scheme:      "HTTP",
+				HTTPHeaders: []apiv1.HTTPHeader{},
+			},
+		},
+		InitialDelaySeconds: 120,
+		PeriodSeconds:       5,
+	})
+	c.Assert(dep.Spec.Template.Spec.TerminationGracePeriodSeconds, check.IsNil)
+	dep, err = s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-worker", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	container = dep.Spec.Template.Spec.Containers[0]
+	c.Assert(container.ReadinessProbe, check.IsNil)
+	c.Assert(container.LivenessProbe, check.DeepEquals, &apiv1.Probe{
+		Handler: apiv1.Handler{
+			Exec: &apiv1.ExecAction{Command: []string{"./check-worker.sh"}},
+		},
+		FailureThreshold: 2,
+	})
+	c.Assert(container.Lifecycle, check.DeepEquals, &apiv1.Lifecycle{
+		PreStop: &apiv1.Handler{
+			Exec: &apiv1.ExecAction{
+				Command: []string{"sh", "-c", "./drain.sh && sleep 5"},
+			},
+		},
+	})
+	gracePeriod := int64(120)
+	c.Assert(dep.Spec.Template.Spec.TerminationGracePeriodSeconds, check.DeepEquals, &gracePeriod)
+}
+
+func (s *S) TestProbeFromConfig(c *check.C) {
+	probe, err := probeFromConfig(&provTypes.TsuruYamlProbe{Port: 9000, TimeoutSeconds: 3}, 8888)
+	c.Assert(err, check.IsNil)
+	c.Assert(probe, check.DeepEquals, &apiv1.Probe{
+		Handler: apiv1.Handler{
+			TCPSocket: &apiv1.TCPSocketAction{Port: intstr.FromInt(9000)},
+		},
+		TimeoutSeconds: 3,
+	})
+	probe, err = probeFromConfig(&provTypes.TsuruYamlProbe{
+		Path:    "/alive",
+		Scheme:  "https",
+		Headers: map[string]string{"X-B": "b", "X-A": "a"},
+	}, 8888)
+	c.Assert(err, check.IsNil)
+	c.Assert(probe.HTTPGet, check.DeepEquals, &apiv1.HTTPGetAction{
+		Path:   "/alive",
+		Port:   intstr.FromInt(8888),
+		Scheme: "HTTPS",
+		HTTPHeaders: []apiv1.HTTPHeader{
+			{Name: "X-A", Value: "a"},
+			{Name: "X-B", Value: "b"},
+		},
+	})
+	_, err = probeFromConfig(&provTypes.TsuruYamlProbe{Path: "/alive"}, 0)
+	c.Assert(err, check.ErrorMatches, "probe: port is required for http probes in processes without ports")
+	_, err = probeFromConfig(&provTypes.TsuruYamlProbe{InitialDelaySeconds: 10}, 8888)
+	c.Assert(err, check.ErrorMatches, "probe: one of command, path or port is required")
+}
+
 func (s *S) TestServiceManagerDeployServiceWithKubernetesPorts(c *check.C) {
 	waitDep := s.mock.DeploymentReactions(c)
 	defer waitDep()
@@ -1460,6 +1590,60 @@ func (s *S) TestServiceManagerDeployServiceWithResourceRequirements(c *check.C)
 	})
 }
 
+func (s *S) TestServiceManagerDeployServiceWithProcessResources(c *check.C) {
+	waitDep := s.mock.DeploymentReactions(c)
+	defer waitDep()
+	m := serviceManager{client: s.clusterClient}
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Plan = appTypes.Plan{Memory: 1024}
+	a.Processes = []appTypes.Process{
+		{Name: "p2", Plan: appTypes.Plan{Name: "small", Memory: 512}, Resources: appTypes.ProcessResources{
+			MemoryRequest: 256,
+			CPURequest:    100,
+			CPULimit:      500,
+		}},
+	}
+	err = image.SaveImageCustomData("myimg", map[string]interface{}{
+		"processes": map[string]interface{}{
+			"p1": "cm1",
+			"p2": "cm2",
+		},
+	})
+	c.Assert(err, check.IsNil)
+	err = servicecommon.RunServicePipeline(&m, a, "myimg", servicecommon.ProcessSpec{
+		"p1": servicecommon.ProcessState{Start: true},
+		"p2": servicecommon.ProcessState{Start: true},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	waitDep()
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	dep, err := s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-p1", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(dep.Spec.Template.Spec.Containers[0].Resources, check.DeepEquals, apiv1.ResourceRequirements{
+		Limits: apiv1.ResourceList{
+			apiv1.ResourceMemory: *resource.NewQuantity(1024, resource.BinarySI),
+		},
+		Requests: apiv1.ResourceList{
+			apiv1.ResourceMemory: *resource.NewQuantity(1024, resource.BinarySI),
+		},
+	})
+	dep, err = s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-p2", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(dep.Spec.Template.Spec.Containers[0].Resources, check.DeepEquals, apiv1.ResourceRequirements{
+		Limits: apiv1.ResourceList{
+			apiv1.ResourceMemory: *resource.NewQuantity(512, resource.BinarySI),
+			apiv1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+		},
+		Requests: apiv1.ResourceList{
+			apiv1.ResourceMemory: *resource.NewQuantity(256, resource.BinarySI),
+			apiv1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+		},
+	})
+}
+
 func (s *S) TestServiceManagerDeployServiceWithClusterWideOvercommitFactor(c *check.C) {
 	waitDep := s.mock.DeploymentReactions(c)
 	defer waitDep()
@@ -1496,6 +1680,154 @@ func (s *S) TestServiceManagerDeployServiceWithClusterWideOvercommitFactor(c *ch
 	})
 }
 
+func (s *S) TestServiceManagerDeployServiceWithCPUAndOvercommitFactor(c *check.C) {
+	waitDep := s.mock.DeploymentReactions(c)
+	defer waitDep()
+	s.clusterClient.CustomData[overcommitClusterKey] = "2"
+	m := serviceManager{client: s.clusterClient}
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Plan = appTypes.Plan{Memory: 1024, CPULimit: 1000}
+	err = image.SaveImageCustomData("myimg", map[string]interface{}{
+		"processes": map[string]interface{}{
+			"p1": "cm1",
+		},
+	})
+	c.Assert(err, check.IsNil)
+	err = servicecommon.RunServicePipeline(&m, a, "myimg", servicecommon.ProcessSpec{
+		"p1": servicecommon.ProcessState{Start: true},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	waitDep()
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	dep, err := s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-p1", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(dep.Spec.Template.Spec.Containers[0].Resources, check.DeepEquals, apiv1.ResourceRequirements{
+		Limits: apiv1.ResourceList{
+			apiv1.ResourceMemory: *resource.NewQuantity(1024, resource.BinarySI),
+			apiv1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+		},
+		Requests: apiv1.ResourceList{
+			apiv1.ResourceMemory: *resource.NewQuantity(512, resource.BinarySI),
+			apiv1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+		},
+	})
+}
+
+func (s *S) TestServiceManagerDeployServiceWithSchedulingAndMetadata(c *check.C) {
+	waitDep := s.mock.DeploymentReactions(c)
+	defer waitDep()
+	s.clusterClient.CustomData[antiAffinityKey] = "required"
+	s.clusterClient.CustomData[tolerationsKey] = "dedicated=apps:NoSchedule"
+	m := serviceManager{client: s.clusterClient}
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Metadata = appTypes.Metadata{
+		Labels:      []appTypes.MetadataItem{{Name: "tier", Value: "backend"}, {Name: "tsuru.io/app-name", Value: "other"}},
+		Annotations: []appTypes.MetadataItem{{Name: "example.com/owner", Value: "team-a"}},
+	}
+	err = image.SaveImageCustomData("myimg", map[string]interface{}{
+		"processes": map[string]interface{}{
+			"p1": "cm1",
+		},
+	})
+	c.Assert(err, check.IsNil)
+	err = servicecommon.RunServicePipeline(&m, a, "myimg", servicecommon.ProcessSpec{
+		"p1": servicecommon.ProcessState{Start: true},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	waitDep()
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	dep, err := s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-p1", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	podSpec := dep.Spec.Template.Spec
+	c.Assert(podSpec.Affinity, check.DeepEquals, &apiv1.Affinity{
+		PodAntiAffinity: &apiv1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []apiv1.PodAffinityTerm{{
+				LabelSelector: dep.Spec.Selector,
+				TopologyKey:   "kubernetes.io/hostname",
+			}},
+		},
+	})
+	c.Assert(podSpec.Tolerations, check.DeepEquals, []apiv1.Toleration{
+		{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "apps", Effect: apiv1.TaintEffectNoSchedule},
+	})
+	c.Assert(dep.Spec.Template.Labels["tier"], check.Equals, "backend")
+	c.Assert(dep.Spec.Template.Labels["tsuru.io/app-name"], check.Equals, "myapp")
+	c.Assert(dep.Spec.Template.Annotations["example.com/owner"], check.Equals, "team-a")
+}
+
+func (s *S) TestServiceManagerDeployServiceWithSidecars(c *check.C) {
+	waitDep := s.mock.DeploymentReactions(c)
+	defer waitDep()
+	m := serviceManager{client: s.clusterClient}
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.SaveImageCustomData("myimg", map[string]interface{}{
+		"processes": map[string]interface{}{
+			"web": "proc1",
+		},
+		"process_config": map[string]provTypes.TsuruYamlProcessConfig{
+			"web": {
+				SharedVolumes: []provTypes.TsuruYamlSharedVolume{{Name: "logs", Path: "/var/log/app"}},
+				Sidecars: []provTypes.TsuruYamlContainer{{
+					Name:   "shipper",
+					Image:  "fluent/fluent-bit",
+					Mounts: []provTypes.TsuruYamlContainerMount{{Volume: "logs", Path: "/logs", ReadOnly: true}},
+				}},
+				InitContainers: []provTypes.TsuruYamlContainer{{
+					Name:    "setup",
+					Image:   "fluent/setup",
+					Command: []string{"/setup.sh"},
+				}},
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	err = servicecommon.RunServicePipeline(&m, a, "myimg", servicecommon.ProcessSpec{
+		"web": servicecommon.ProcessState{Start: true},
+	}, nil)
+	c.Assert(err, check.ErrorMatches, `(?s).*sidecar image "fluent/fluent-bit" is not allowed on pool "test-default".*`)
+	err = pool.SetPoolConstraint(&pool.PoolConstraint{PoolExpr: "test-default", Field: pool.ConstraintTypeSidecarImage, Values: []string{"fluent/*"}})
+	c.Assert(err, check.IsNil)
+	err = servicecommon.RunServicePipeline(&m, a, "myimg", servicecommon.ProcessSpec{
+		"web": servicecommon.ProcessState{Start: true},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	waitDep()
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	dep, err := s.client.Clientset.AppsV1().Deployments(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	podSpec := dep.Spec.Template.Spec
+	c.Assert(podSpec.Containers, check.HasLen, 2)
+	c.Assert(podSpec.Containers[0].Name, check.Equals, "myapp-web")
+	c.Assert(podSpec.Containers[0].VolumeMounts, check.DeepEquals, []apiv1.VolumeMount{
+		{Name: "shared-logs", MountPath: "/var/log/app"},
+	})
+	c.Assert(podSpec.Containers[1], check.DeepEquals, apiv1.Container{
+		Name:  "shipper",
+		Image: "fluent/fluent-bit",
+		VolumeMounts: []apiv1.VolumeMount{
+			{Name: "shared-logs", MountPath: "/logs", ReadOnly: true},
+		},
+	})
+	c.Assert(podSpec.InitContainers, check.DeepEquals, []apiv1.Container{{
+		Name:    "setup",
+		Image:   "fluent/setup",
+		Command: []string{"/setup.sh"},
+	}})
+	c.Assert(podSpec.Volumes, check.DeepEquals, []apiv1.Volume{{
+		Name:         "shared-logs",
+		VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}},
+	}})
+}
+
 func (s *S) TestServiceManagerDeployServiceWithClusterPoolOvercommitFactor(c *check.C) {
 	waitDep := s.mock.DeploymentReactions(c)
 	defer waitDep()
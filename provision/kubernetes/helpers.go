@@ -560,6 +560,7 @@ type execOpts struct {
 	stdin    io.Reader
 	termSize *remotecommand.TerminalSize
 	tty      bool
+	ctx      context.Context
 }
 
 func execCommand(opts execOpts) error {
@@ -622,6 +623,7 @@ func execCommand(opts execOpts) error {
 }
 
 type runSinglePodArgs struct {
+	ctx      context.Context
 	client   *ClusterClient
 	stdout   io.Writer
 	stderr   io.Writer
@@ -704,16 +706,40 @@ func runPod(args runSinglePodArgs) error {
 	if args.stdin == nil {
 		args.stdin = bytes.NewBufferString(".")
 	}
-	err = doAttach(context.TODO(), args.client, args.stdin, args.stdout, args.stderr, pod.Name, args.name, tty, args.termSize, ns)
+	attachCtx := args.ctx
+	if attachCtx == nil {
+		attachCtx = context.Background()
+	}
+	err = doAttach(attachCtx, args.client, args.stdin, args.stdout, args.stderr, pod.Name, args.name, tty, args.termSize, ns)
 	if err != nil {
 		multiErr.Add(errors.WithStack(err))
 	}
 	if multiErr.Len() > 0 {
-		return multiErr
+		return podExitError(args.client, pod.Name, args.name, ns, multiErr)
 	}
 	ctx, cancel = context.WithTimeout(context.Background(), kubeConf.PodReadyTimeout)
 	defer cancel()
-	return waitForPod(ctx, args.client, pod, ns, false)
+	err = waitForPod(ctx, args.client, pod, ns, false)
+	if err != nil {
+		return podExitError(args.client, pod.Name, args.name, ns, err)
+	}
+	return nil
+}
+
+// podExitError wraps err in a provision.ExecExitError if the container of
+// the pod terminated with a non-zero exit code.
+func podExitError(client *ClusterClient, podName, container, namespace string, err error) error {
+	pod, getErr := client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+	if getErr != nil {
+		return err
+	}
+	for _, contStatus := range pod.Status.ContainerStatuses {
+		termData := contStatus.State.Terminated
+		if contStatus.Name == container && termData != nil && termData.ExitCode != 0 {
+			return &provision.ExecExitError{Code: int(termData.ExitCode), Err: err}
+		}
+	}
+	return err
 }
 
 func (p *kubernetesProvisioner) getNodeByAddr(client *ClusterClient, address string) (*apiv1.Node, error) {
@@ -591,3 +591,27 @@ func (s *S) TestGetServicePort(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(port, check.Equals, int32(123))
 }
+
+func (s *S) TestPodExitError(c *check.C) {
+	ns := "default"
+	_, err := s.client.CoreV1().Pods(ns).Create(&apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp-isolated-run", Namespace: ns},
+		Status: apiv1.PodStatus{
+			ContainerStatuses: []apiv1.ContainerStatus{{
+				Name:  "myapp-isolated-run",
+				State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 3}},
+			}},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	runErr := errors.New("pod failed")
+	err = podExitError(s.clusterClient, "myapp-isolated-run", "myapp-isolated-run", ns, runErr)
+	exitErr, ok := err.(*provision.ExecExitError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(exitErr.Code, check.Equals, 3)
+	c.Assert(exitErr.Error(), check.Equals, "pod failed")
+	err = podExitError(s.clusterClient, "myapp-isolated-run", "other", ns, runErr)
+	c.Assert(err, check.Equals, runErr)
+	err = podExitError(s.clusterClient, "not-found", "myapp-isolated-run", ns, runErr)
+	c.Assert(err, check.Equals, runErr)
+}
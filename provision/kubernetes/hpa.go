@@ -0,0 +1,279 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	provTypes "github.com/tsuru/tsuru/types/provision"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func hpaNameForApp(a provision.App, process string) string {
+	return appProcessName(a, process)
+}
+
+func autoScaleSpecForProcess(a provision.App, process string) *appTypes.AutoScaleSpec {
+	for _, spec := range a.AutoScaleInfo() {
+		if spec.Process == process {
+			return &spec
+		}
+	}
+	return nil
+}
+
+// handlesAutoScale returns whether the units of the process in spec are
+// scaled by kubernetes horizontal pod autoscalers, which requires native
+// autoscale in every cluster of the app. Requests targets depend on metrics
+// not available to kubernetes and are always handled by tsuru.
+func handlesAutoScale(clients []*ClusterClient, a provision.App, spec appTypes.AutoScaleSpec) (bool, error) {
+	if spec.TargetRequests != 0 || (spec.TargetCPU == 0 && spec.TargetMemory == 0) {
+		return false, nil
+	}
+	for _, client := range clients {
+		native, err := client.NativeAutoScale(a.GetPool())
+		if err != nil {
+			return false, errors.WithMessage(err, "misconfigured cluster native autoscale")
+		}
+		if !native {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// clusterAutoScaleSpec returns the share of spec handled by the autoscaler
+// of the cluster at idx, splitting the min and max units among the clusters
+// according to their weights.
+func clusterAutoScaleSpec(spec appTypes.AutoScaleSpec, clusters []provTypes.Cluster, idx int) appTypes.AutoScaleSpec {
+	if len(clusters) < 2 {
+		return spec
+	}
+	spec.MinUnits = uint(splitReplicas(int(spec.MinUnits), clusters)[idx])
+	spec.MaxUnits = uint(splitReplicas(int(spec.MaxUnits), clusters)[idx])
+	if spec.MinUnits > spec.MaxUnits {
+		spec.MinUnits = spec.MaxUnits
+	}
+	return spec
+}
+
+// hpaMetrics converts the autoscale targets, which are percentages of the
+// unit limits, to kubernetes resource metrics. Kubernetes utilizations are
+// relative to the resource requests, so absolute values are used whenever
+// the process has limits.
+func hpaMetrics(spec appTypes.AutoScaleSpec, resources appTypes.ProcessResources) []autoscalingv2.MetricSpec {
+	var metrics []autoscalingv2.MetricSpec
+	if spec.TargetCPU > 0 {
+		source := &autoscalingv2.ResourceMetricSource{Name: apiv1.ResourceCPU}
+		if resources.CPULimit > 0 {
+			source.TargetAverageValue = resource.NewMilliQuantity(int64(resources.CPULimit*spec.TargetCPU/100), resource.DecimalSI)
+		} else {
+			target := int32(spec.TargetCPU)
+			source.TargetAverageUtilization = &target
+		}
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type:     autoscalingv2.ResourceMetricSourceType,
+			Resource: source,
+		})
+	}
+	if spec.TargetMemory > 0 {
+		source := &autoscalingv2.ResourceMetricSource{Name: apiv1.ResourceMemory}
+		if resources.Memory > 0 {
+			source.TargetAverageValue = resource.NewQuantity(resources.Memory*int64(spec.TargetMemory)/100, resource.BinarySI)
+		} else {
+			target := int32(spec.TargetMemory)
+			source.TargetAverageUtilization = &target
+		}
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type:     autoscalingv2.ResourceMetricSourceType,
+			Resource: source,
+		})
+	}
+	return metrics
+}
+
+func newHPA(client *ClusterClient, a provision.App, process string, spec appTypes.AutoScaleSpec) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := processObjectLabels(a, process)
+	if err != nil {
+		return nil, err
+	}
+	minReplicas := int32(spec.MinUnits)
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hpaNameForApp(a, process),
+			Namespace: ns,
+			Labels:    ls.ToLabels(),
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deploymentNameForApp(a, process),
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: int32(spec.MaxUnits),
+			Metrics:     hpaMetrics(spec, a.GetProcessResources(process)),
+		},
+	}, nil
+}
+
+func hpaSpecEqual(a, b autoscalingv2.HorizontalPodAutoscalerSpec) bool {
+	if a.ScaleTargetRef != b.ScaleTargetRef || a.MaxReplicas != b.MaxReplicas ||
+		!reflect.DeepEqual(a.MinReplicas, b.MinReplicas) || len(a.Metrics) != len(b.Metrics) {
+		return false
+	}
+	for i := range a.Metrics {
+		ma, mb := a.Metrics[i], b.Metrics[i]
+		if ma.Type != mb.Type || ma.Resource == nil || mb.Resource == nil {
+			return false
+		}
+		ra, rb := ma.Resource, mb.Resource
+		if ra.Name != rb.Name || !reflect.DeepEqual(ra.TargetAverageUtilization, rb.TargetAverageUtilization) {
+			return false
+		}
+		if (ra.TargetAverageValue == nil) != (rb.TargetAverageValue == nil) {
+			return false
+		}
+		if ra.TargetAverageValue != nil && ra.TargetAverageValue.Cmp(*rb.TargetAverageValue) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureHPA creates, updates or removes the horizontal pod autoscaler of the
+// app process in the cluster of client according to the autoscale spec of
+// the process. Autoscalers are removed from clusters no longer hosting the
+// app.
+func ensureHPA(client *ClusterClient, a provision.App, process string) error {
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return err
+	}
+	for i := range clients {
+		if clients[i].Name == client.Name {
+			return ensureClusterHPA(clients, i, a, process)
+		}
+	}
+	return removeHPA(client, a, process)
+}
+
+// ensureClusterHPA works like ensureHPA for the cluster at idx of clients,
+// which must hold every cluster of the app, using the share of the autoscale
+// spec handled by that cluster.
+func ensureClusterHPA(clients []*ClusterClient, idx int, a provision.App, process string) error {
+	client := clients[idx]
+	spec := autoScaleSpecForProcess(a, process)
+	if spec == nil {
+		return removeHPA(client, a, process)
+	}
+	native, err := handlesAutoScale(clients, a, *spec)
+	if err != nil {
+		return err
+	}
+	clusterSpec := clusterAutoScaleSpec(*spec, clustersFromClients(clients), idx)
+	if !native || clusterSpec.MaxUnits == 0 {
+		return removeHPA(client, a, process)
+	}
+	hpa, err := newHPA(client, a, process, clusterSpec)
+	if err != nil {
+		return err
+	}
+	existing, err := client.AutoscalingV2beta1().HorizontalPodAutoscalers(hpa.Namespace).Get(hpa.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return errors.WithStack(err)
+		}
+		_, err = client.AutoscalingV2beta1().HorizontalPodAutoscalers(hpa.Namespace).Create(hpa)
+		return errors.WithStack(err)
+	}
+	if hpaSpecEqual(existing.Spec, hpa.Spec) && reflect.DeepEqual(existing.Labels, hpa.Labels) {
+		return nil
+	}
+	existing.Labels = hpa.Labels
+	existing.Spec = hpa.Spec
+	_, err = client.AutoscalingV2beta1().HorizontalPodAutoscalers(hpa.Namespace).Update(existing)
+	return errors.WithStack(err)
+}
+
+// ensureHPAs makes the horizontal pod autoscalers of every process of the app
+// match its autoscale specs in each cluster of the app.
+func ensureHPAs(clients []*ClusterClient, a provision.App) error {
+	for i := range clients {
+		err := ensureClusterHPAs(clients, i, a)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureClusterHPAs(clients []*ClusterClient, idx int, a provision.App) error {
+	client := clients[idx]
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	ls, err := provision.ServiceLabels(provision.ServiceLabelsOpts{
+		App: a,
+		ServiceLabelExtendedOpts: provision.ServiceLabelExtendedOpts{
+			Prefix:      tsuruLabelPrefix,
+			Provisioner: provisionerName,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	existing, err := client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set(ls.ToAppSelector())).String(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	processes := map[string]struct{}{}
+	for _, hpa := range existing.Items {
+		processes[labelSetFromMeta(&hpa.ObjectMeta).AppProcess()] = struct{}{}
+	}
+	for _, spec := range a.AutoScaleInfo() {
+		processes[spec.Process] = struct{}{}
+	}
+	for process := range processes {
+		if process == "" {
+			continue
+		}
+		err = ensureClusterHPA(clients, idx, a, process)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeHPA(client *ClusterClient, a provision.App, process string) error {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	err = client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Delete(hpaNameForApp(a, process), &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	provTypes "github.com/tsuru/tsuru/types/provision"
+	check "gopkg.in/check.v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (s *S) TestHPAMetrics(c *check.C) {
+	spec := appTypes.AutoScaleSpec{Process: "web", MaxUnits: 3, TargetCPU: 50, TargetMemory: 80}
+	metrics := hpaMetrics(spec, appTypes.ProcessResources{Memory: 1024, CPULimit: 2000})
+	c.Assert(metrics, check.HasLen, 2)
+	c.Assert(metrics[0].Resource.Name, check.Equals, apiv1.ResourceCPU)
+	c.Assert(metrics[0].Resource.TargetAverageUtilization, check.IsNil)
+	c.Assert(metrics[0].Resource.TargetAverageValue.MilliValue(), check.Equals, int64(1000))
+	c.Assert(metrics[1].Resource.Name, check.Equals, apiv1.ResourceMemory)
+	c.Assert(metrics[1].Resource.TargetAverageUtilization, check.IsNil)
+	c.Assert(metrics[1].Resource.TargetAverageValue.Value(), check.Equals, int64(819))
+	metrics = hpaMetrics(spec, appTypes.ProcessResources{})
+	c.Assert(metrics, check.HasLen, 2)
+	c.Assert(*metrics[0].Resource.TargetAverageUtilization, check.Equals, int32(50))
+	c.Assert(metrics[0].Resource.TargetAverageValue, check.IsNil)
+	c.Assert(*metrics[1].Resource.TargetAverageUtilization, check.Equals, int32(80))
+	c.Assert(metrics[1].Resource.TargetAverageValue, check.IsNil)
+}
+
+func (s *S) TestEnsureHPA(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.AutoScale = []appTypes.AutoScaleSpec{
+		{Process: "web", MaxUnits: 5, TargetCPU: 50},
+		{Process: "worker", MinUnits: 1, MaxUnits: 2, TargetRequests: 10},
+	}
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	err = ensureHPA(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	_, err = s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(k8sErrors.IsNotFound(err), check.Equals, true)
+	s.clusterClient.CustomData[nativeAutoScaleKey] = "true"
+	err = ensureHPA(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	err = ensureHPA(s.clusterClient, a, "worker")
+	c.Assert(err, check.IsNil)
+	hpa, err := s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	minReplicas := int32(1)
+	target := int32(50)
+	c.Assert(hpa.Spec, check.DeepEquals, autoscalingv2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "myapp-web",
+		},
+		MinReplicas: &minReplicas,
+		MaxReplicas: 5,
+		Metrics: []autoscalingv2.MetricSpec{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:                     apiv1.ResourceCPU,
+				TargetAverageUtilization: &target,
+			},
+		}},
+	})
+	_, err = s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Get("myapp-worker", metav1.GetOptions{})
+	c.Assert(k8sErrors.IsNotFound(err), check.Equals, true)
+	a.AutoScale[0].MaxUnits = 10
+	a.Processes = map[string]appTypes.ProcessResources{"web": {Memory: 1024, CPULimit: 500}}
+	err = ensureHPA(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	hpa, err = s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(hpa.Spec.MaxReplicas, check.Equals, int32(10))
+	c.Assert(hpa.Spec.Metrics[0].Resource.TargetAverageValue.Cmp(*resource.NewMilliQuantity(250, resource.DecimalSI)), check.Equals, 0)
+	a.AutoScale = nil
+	err = ensureHPA(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	_, err = s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(k8sErrors.IsNotFound(err), check.Equals, true)
+}
+
+func (s *S) TestClusterAutoScaleSpec(c *check.C) {
+	spec := appTypes.AutoScaleSpec{Process: "web", MinUnits: 3, MaxUnits: 10, TargetCPU: 50}
+	c.Assert(clusterAutoScaleSpec(spec, []provTypes.Cluster{{}}, 0), check.DeepEquals, spec)
+	clusters := []provTypes.Cluster{{Weight: 3}, {Weight: 2}, {Weight: 1, Drain: true}}
+	shares := []appTypes.AutoScaleSpec{
+		clusterAutoScaleSpec(spec, clusters, 0),
+		clusterAutoScaleSpec(spec, clusters, 1),
+		clusterAutoScaleSpec(spec, clusters, 2),
+	}
+	c.Assert(shares[0].MinUnits, check.Equals, uint(2))
+	c.Assert(shares[0].MaxUnits, check.Equals, uint(6))
+	c.Assert(shares[1].MinUnits, check.Equals, uint(1))
+	c.Assert(shares[1].MaxUnits, check.Equals, uint(4))
+	c.Assert(shares[2].MinUnits, check.Equals, uint(0))
+	c.Assert(shares[2].MaxUnits, check.Equals, uint(0))
+	c.Assert(shares[0].TargetCPU, check.Equals, 50)
+}
+
+func (s *S) TestEnsureHPAs(c *check.C) {
+	s.clusterClient.CustomData[nativeAutoScaleKey] = "true"
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.AutoScale = []appTypes.AutoScaleSpec{
+		{Process: "web", MaxUnits: 5, TargetCPU: 50},
+		{Process: "worker", MaxUnits: 2, TargetMemory: 70},
+	}
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	err = ensureHPAs([]*ClusterClient{s.clusterClient}, a)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	hpas, err := s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).List(metav1.ListOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(hpas.Items, check.HasLen, 2)
+	a.AutoScale = a.AutoScale[1:]
+	err = ensureHPAs([]*ClusterClient{s.clusterClient}, a)
+	c.Assert(err, check.IsNil)
+	hpas, err = s.client.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).List(metav1.ListOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(hpas.Items, check.HasLen, 1)
+	c.Assert(hpas.Items[0].Name, check.Equals, "myapp-worker")
+}
+
+func (s *S) TestHandlesAutoScale(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	spec := appTypes.AutoScaleSpec{Process: "web", MaxUnits: 5, TargetCPU: 50}
+	native, err := s.p.HandlesAutoScale(a, spec)
+	c.Assert(err, check.IsNil)
+	c.Assert(native, check.Equals, false)
+	s.clusterClient.CustomData[nativeAutoScaleKey] = "true"
+	native, err = s.p.HandlesAutoScale(a, spec)
+	c.Assert(err, check.IsNil)
+	c.Assert(native, check.Equals, true)
+	spec.TargetRequests = 10
+	native, err = s.p.HandlesAutoScale(a, spec)
+	c.Assert(err, check.IsNil)
+	c.Assert(native, check.Equals, false)
+}
@@ -7,21 +7,31 @@ package kubernetes
 import (
 	"context"
 	"os"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/router/rebuild"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
+	autoscalinginformers "k8s.io/client-go/informers/autoscaling/v2beta1"
+	batchinformers "k8s.io/client-go/informers/batch/v1"
 	v1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/informers/internalinterfaces"
+	policyinformers "k8s.io/client-go/informers/policy/v1beta1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/leaderelection"
@@ -43,6 +53,9 @@ type clusterController struct {
 	podInformer        v1informers.PodInformer
 	serviceInformer    v1informers.ServiceInformer
 	nodeInformer       v1informers.NodeInformer
+	pdbInformer        policyinformers.PodDisruptionBudgetInformer
+	hpaInformer        autoscalinginformers.HorizontalPodAutoscalerInformer
+	jobInformer        batchinformers.JobInformer
 	stopCh             chan struct{}
 	cancel             context.CancelFunc
 	resourceVers       map[types.NamespacedName]string
@@ -150,9 +163,147 @@ func (c *clusterController) start() error {
 			}
 		},
 	})
+	err = c.startAvailabilityController()
+	if err != nil {
+		return err
+	}
+	return c.startJobController()
+}
+
+// startJobController watches the jobs created by the cron jobs of apps,
+// recording each finished run in an event and in the app logs.
+func (c *clusterController) startJobController() error {
+	jobInformer, err := c.getJobInformerWait(false)
+	if err != nil {
+		return err
+	}
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if !c.isLeader() {
+				return
+			}
+			err := recordJobRun(c.cluster, obj.(*batchv1.Job))
+			if err != nil {
+				log.Errorf("[job-controller] error on add job event: %v", err)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if !c.isLeader() {
+				return
+			}
+			err := recordJobRun(c.cluster, newObj.(*batchv1.Job))
+			if err != nil {
+				log.Errorf("[job-controller] error on update job event: %v", err)
+			}
+		},
+	})
 	return nil
 }
 
+// startAvailabilityController watches the pod disruption budgets and
+// horizontal pod autoscalers managed by tsuru, restoring them whenever they
+// are changed or removed outside tsuru.
+func (c *clusterController) startAvailabilityController() error {
+	pdbInformer, err := c.getPDBInformerWait(false)
+	if err != nil {
+		return err
+	}
+	pdbInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPDB, newPDB := oldObj.(*policy.PodDisruptionBudget), newObj.(*policy.PodDisruptionBudget)
+			if !c.isLeader() || (reflect.DeepEqual(oldPDB.Spec, newPDB.Spec) && reflect.DeepEqual(oldPDB.Labels, newPDB.Labels)) {
+				return
+			}
+			err := c.reconcileAppProcess(&newPDB.ObjectMeta, ensurePDB)
+			if err != nil {
+				log.Errorf("[availability-controller] error on update pod disruption budget event: %v", err)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if !c.isLeader() {
+				return
+			}
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			pdb, ok := obj.(*policy.PodDisruptionBudget)
+			if !ok {
+				log.Errorf("[availability-controller] deleted object is not a pod disruption budget: %#v", obj)
+				return
+			}
+			err := c.reconcileAppProcess(&pdb.ObjectMeta, ensurePDB)
+			if err != nil {
+				log.Errorf("[availability-controller] error on delete pod disruption budget event: %v", err)
+			}
+		},
+	})
+	hpaInformer, err := c.getHPAInformerWait(false)
+	if err != nil {
+		return err
+	}
+	hpaInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Autoscalers are updated on every status change, only
+			// changes to their specs are relevant.
+			oldHPA, newHPA := oldObj.(*autoscalingv2.HorizontalPodAutoscaler), newObj.(*autoscalingv2.HorizontalPodAutoscaler)
+			if !c.isLeader() || (reflect.DeepEqual(oldHPA.Spec, newHPA.Spec) && reflect.DeepEqual(oldHPA.Labels, newHPA.Labels)) {
+				return
+			}
+			err := c.reconcileAppProcess(&newHPA.ObjectMeta, ensureHPA)
+			if err != nil {
+				log.Errorf("[availability-controller] error on update horizontal pod autoscaler event: %v", err)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if !c.isLeader() {
+				return
+			}
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			hpa, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler)
+			if !ok {
+				log.Errorf("[availability-controller] deleted object is not a horizontal pod autoscaler: %#v", obj)
+				return
+			}
+			err := c.reconcileAppProcess(&hpa.ObjectMeta, ensureHPA)
+			if err != nil {
+				log.Errorf("[availability-controller] error on delete horizontal pod autoscaler event: %v", err)
+			}
+		},
+	})
+	return nil
+}
+
+// reconcileAppProcess calls ensure for the app process owning the object,
+// unless the process deployment is gone or being removed, in which case the
+// object is being removed along with it.
+func (c *clusterController) reconcileAppProcess(objMeta *metav1.ObjectMeta, ensure func(*ClusterClient, provision.App, string) error) error {
+	labelSet := labelSetFromMeta(objMeta)
+	appName, process := labelSet.AppName(), labelSet.AppProcess()
+	if appName == "" || process == "" {
+		return nil
+	}
+	a, err := app.GetByName(appName)
+	if err != nil {
+		if err == appTypes.ErrAppNotFound {
+			return nil
+		}
+		return err
+	}
+	dep, err := c.cluster.AppsV1().Deployments(objMeta.Namespace).Get(deploymentNameForApp(a, process), metav1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	if dep.DeletionTimestamp != nil {
+		return nil
+	}
+	return ensure(c.cluster, a, process)
+}
+
 func (c *clusterController) onAdd(obj interface{}) error {
 	// Pods are never ready on add, ignore and do nothing
 	return nil
@@ -248,6 +399,63 @@ func (c *clusterController) getNodeInformer() (v1informers.NodeInformer, error)
 	return c.nodeInformer, err
 }
 
+func (c *clusterController) getPDBInformerWait(wait bool) (policyinformers.PodDisruptionBudgetInformer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pdbInformer == nil {
+		err := c.withInformerFactory(func(factory informers.SharedInformerFactory) {
+			c.pdbInformer = factory.Policy().V1beta1().PodDisruptionBudgets()
+			c.pdbInformer.Informer()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if wait {
+		err = c.waitForSync(c.pdbInformer.Informer())
+	}
+	return c.pdbInformer, err
+}
+
+func (c *clusterController) getHPAInformerWait(wait bool) (autoscalinginformers.HorizontalPodAutoscalerInformer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hpaInformer == nil {
+		err := c.withInformerFactory(func(factory informers.SharedInformerFactory) {
+			c.hpaInformer = factory.Autoscaling().V2beta1().HorizontalPodAutoscalers()
+			c.hpaInformer.Informer()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if wait {
+		err = c.waitForSync(c.hpaInformer.Informer())
+	}
+	return c.hpaInformer, err
+}
+
+func (c *clusterController) getJobInformerWait(wait bool) (batchinformers.JobInformer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.jobInformer == nil {
+		err := c.withInformerFactory(func(factory informers.SharedInformerFactory) {
+			c.jobInformer = factory.Batch().V1().Jobs()
+			c.jobInformer.Informer()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if wait {
+		err = c.waitForSync(c.jobInformer.Informer())
+	}
+	return c.jobInformer, err
+}
+
 func (c *clusterController) getPodInformerWait(wait bool) (v1informers.PodInformer, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -0,0 +1,464 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/image"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/servicecommon"
+	"github.com/tsuru/tsuru/router/rebuild"
+	"github.com/tsuru/tsuru/servicemanager"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+	provTypes "github.com/tsuru/tsuru/types/provision"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const rebalanceEventKind = "cluster-rebalance"
+
+var rebalanceLockTimeout = 10 * time.Minute
+
+// poolClusters returns the clusters hosting units of apps in pool, starting
+// with primary. A pool only spans multiple clusters when its primary cluster
+// has a weight, in which case every other weighted cluster listing the pool
+// is included. Draining clusters are always placed last.
+func poolClusters(primary provTypes.Cluster, clusters []provTypes.Cluster, pool string) []provTypes.Cluster {
+	result := []provTypes.Cluster{primary}
+	if primary.Weight == 0 || !containsPool(primary.Pools, pool) {
+		return result
+	}
+	for _, c := range clusters {
+		if c.Name == primary.Name || c.Weight == 0 || !containsPool(c.Pools, pool) {
+			continue
+		}
+		result = append(result, c)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return !result[i].Drain && result[j].Drain
+	})
+	return result
+}
+
+func containsPool(pools []string, pool string) bool {
+	for _, p := range pools {
+		if p == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// clustersForApp returns clients for every cluster hosting units of the app,
+// the primary cluster, used for builds and single cluster operations, first.
+func clustersForApp(a provision.App) ([]*ClusterClient, error) {
+	primary, err := servicemanager.Cluster.FindByPool(provisionerName, a.GetPool())
+	if err != nil {
+		return nil, err
+	}
+	clusters := []provTypes.Cluster{*primary}
+	if primary.Weight > 0 {
+		var provClusters []provTypes.Cluster
+		provClusters, err = servicemanager.Cluster.FindByProvisioner(provisionerName)
+		if err != nil {
+			return nil, err
+		}
+		clusters = poolClusters(*primary, provClusters, a.GetPool())
+	}
+	return clusterClients(clusters)
+}
+
+// clustersForAppExcept works like clustersForApp ignoring the cluster named
+// excluded, which may still be in the storage while it's being removed.
+func clustersForAppExcept(a provision.App, excluded string) ([]*ClusterClient, error) {
+	provClusters, err := servicemanager.Cluster.FindByProvisioner(provisionerName)
+	if err != nil {
+		return nil, err
+	}
+	var candidates, others []provTypes.Cluster
+	var defaultCluster *provTypes.Cluster
+	for i, c := range provClusters {
+		if c.Name == excluded {
+			continue
+		}
+		others = append(others, c)
+		if containsPool(c.Pools, a.GetPool()) {
+			candidates = append(candidates, c)
+		} else if c.Default {
+			defaultCluster = &provClusters[i]
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Drain != candidates[j].Drain {
+			return !candidates[i].Drain
+		}
+		if candidates[i].Weight != candidates[j].Weight {
+			return candidates[i].Weight > candidates[j].Weight
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	var primary provTypes.Cluster
+	switch {
+	case len(candidates) > 0:
+		primary = candidates[0]
+	case defaultCluster != nil:
+		primary = *defaultCluster
+	default:
+		return nil, provTypes.ErrNoCluster
+	}
+	return clusterClients(poolClusters(primary, others, a.GetPool()))
+}
+
+func clusterClients(clusters []provTypes.Cluster) ([]*ClusterClient, error) {
+	clients := make([]*ClusterClient, len(clusters))
+	for i := range clusters {
+		var err error
+		clients[i], err = NewClusterClient(&clusters[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return clients, nil
+}
+
+func clustersFromClients(clients []*ClusterClient) []provTypes.Cluster {
+	clusters := make([]provTypes.Cluster, len(clients))
+	for i, client := range clients {
+		clusters[i] = *client.Cluster
+	}
+	return clusters
+}
+
+// splitReplicas distributes replicas among clusters proportionally to their
+// weights using the largest remainder method, ties favoring the clusters
+// listed first. Draining clusters receive no replicas.
+func splitReplicas(replicas int, clusters []provTypes.Cluster) []int {
+	result := make([]int, len(clusters))
+	if len(clusters) == 0 {
+		return result
+	}
+	totalWeight := 0
+	for _, c := range clusters {
+		if !c.Drain {
+			totalWeight += c.Weight
+		}
+	}
+	if totalWeight == 0 {
+		result[0] = replicas
+		return result
+	}
+	type remainder struct {
+		idx   int
+		value int
+	}
+	var remainders []remainder
+	assigned := 0
+	for i, c := range clusters {
+		if c.Drain || c.Weight == 0 {
+			continue
+		}
+		result[i] = replicas * c.Weight / totalWeight
+		assigned += result[i]
+		remainders = append(remainders, remainder{idx: i, value: replicas * c.Weight % totalWeight})
+	}
+	sort.SliceStable(remainders, func(i, j int) bool {
+		return remainders[i].value > remainders[j].value
+	})
+	for i := 0; assigned < replicas; i++ {
+		result[remainders[i].idx]++
+		assigned++
+	}
+	return result
+}
+
+func newServiceManager(clients []*ClusterClient, w io.Writer) servicecommon.ServiceManager {
+	if len(clients) == 1 {
+		return &serviceManager{client: clients[0], writer: w}
+	}
+	return &multiClusterManager{clients: clients, writer: w}
+}
+
+func ensureAppCustomResourcesSynced(clients []*ClusterClient, a provision.App) error {
+	for _, client := range clients {
+		err := ensureAppCustomResourceSynced(client, a)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiClusterManager deploys the services of an app to every cluster of its
+// pool, splitting the units according to the cluster weights. The app
+// replicas label always holds the total number of units.
+type multiClusterManager struct {
+	clients []*ClusterClient
+	writer  io.Writer
+}
+
+var _ servicecommon.ServiceManager = &multiClusterManager{}
+
+func (m *multiClusterManager) RemoveService(a provision.App, process string) error {
+	multiErrors := tsuruErrors.NewMultiError()
+	for _, client := range m.clients {
+		err := m.managerFor(client).RemoveService(a, process)
+		if err != nil {
+			multiErrors.Add(err)
+		}
+	}
+	return multiErrors.ToError()
+}
+
+func (m *multiClusterManager) CurrentLabels(a provision.App, process string) (*provision.LabelSet, error) {
+	for _, client := range m.clients {
+		labels, err := m.managerFor(client).CurrentLabels(a, process)
+		if err != nil || labels != nil {
+			return labels, err
+		}
+	}
+	return nil, nil
+}
+
+func (m *multiClusterManager) DeployService(ctx context.Context, a provision.App, process string, labels *provision.LabelSet, replicas int, img string) error {
+	shares := splitReplicas(replicas, clustersFromClients(m.clients))
+	if m.writer == nil {
+		m.writer = ioutil.Discard
+	}
+	for i, client := range m.clients {
+		err := ensureNamespace(client, client.Namespace())
+		if err != nil {
+			return err
+		}
+		err = ensureAppCustomResource(client, a)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(m.writer, "\n---- Deploying %d of %d units [%s] to cluster %q ----\n", shares[i], replicas, process, client.Name)
+		err = m.managerFor(client).DeployService(ctx, a, process, copyLabelSet(labels), shares[i], img)
+		if err != nil {
+			return errors.Wrapf(err, "error deploying to cluster %q", client.Name)
+		}
+	}
+	return nil
+}
+
+func (m *multiClusterManager) managerFor(client *ClusterClient) *serviceManager {
+	return &serviceManager{client: client, writer: m.writer}
+}
+
+func copyLabelSet(ls *provision.LabelSet) *provision.LabelSet {
+	labels := make(map[string]string, len(ls.Labels))
+	for k, v := range ls.Labels {
+		labels[k] = v
+	}
+	return &provision.LabelSet{Labels: labels, Prefix: ls.Prefix}
+}
+
+// podForUnit looks for the pod of unitID in each of the clients, returning
+// the client of the cluster where it was found.
+func podForUnit(clients []*ClusterClient, a provision.App, unitID string) (*ClusterClient, *apiv1.Pod, error) {
+	for _, client := range clients {
+		ns, err := client.AppNamespace(a)
+		if err != nil {
+			if len(clients) == 1 {
+				return nil, nil, err
+			}
+			continue
+		}
+		pod, err := client.CoreV1().Pods(ns).Get(unitID, metav1.GetOptions{})
+		if err == nil {
+			return client, pod, nil
+		}
+		if !k8sErrors.IsNotFound(err) {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+	return nil, nil, &provision.UnitNotFoundError{ID: unitID}
+}
+
+// rebalanceClusterApps starts, in background, moving the units of the apps in
+// the pools of c to match the current cluster weights and draining flags.
+// When removed is set, c is being deleted and every unit is moved away from
+// it. Updating a cluster without weight only removes its apps from the
+// clusters that no longer host their pools. Each app is locked and
+// rebalanced in its own event.
+func (p *kubernetesProvisioner) rebalanceClusterApps(c *provTypes.Cluster, removed bool) error {
+	if len(c.Pools) == 0 {
+		return nil
+	}
+	apps, err := app.List(&app.Filter{Pools: c.Pools})
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		return nil
+	}
+	if c.Writer != nil {
+		fmt.Fprintf(c.Writer, " ---> Rebalancing units of %d apps in background, progress is available in the %q events of each app\n", len(apps), rebalanceEventKind)
+	}
+	cluster := *c
+	cluster.Writer = nil
+	go func() {
+		for i := range apps {
+			err := p.rebalanceApp(&apps[i], &cluster, removed)
+			if err != nil {
+				log.Errorf("[kubernetes] unable to rebalance app %q: %v", apps[i].Name, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *kubernetesProvisioner) rebalanceApp(a *app.App, c *provTypes.Cluster, removed bool) (err error) {
+	if a.GetDeploys() == 0 {
+		return nil
+	}
+	_, err = image.AppCurrentImageName(a.GetName())
+	if err == image.ErrNoImagesAvailable {
+		return nil
+	}
+	var clients []*ClusterClient
+	if removed {
+		clients, err = clustersForAppExcept(a, c.Name)
+	} else {
+		clients, err = clustersForApp(a)
+	}
+	if err != nil {
+		return err
+	}
+	stale, err := staleClustersForApp(a, clients, c, removed)
+	if err != nil {
+		return err
+	}
+	if c.Weight == 0 && len(stale) == 0 {
+		return nil
+	}
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		InternalKind: rebalanceEventKind,
+		CustomData:   map[string]interface{}{"cluster": c.Name, "removed": removed},
+		Allowed:      event.Allowed(permission.PermAppReadEvents, permission.Context(permTypes.CtxApp, a.Name)),
+		RetryTimeout: rebalanceLockTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	draining := false
+	for _, client := range clients {
+		draining = draining || client.Drain
+	}
+	if draining {
+		// Routes are updated first so no traffic reaches the draining
+		// cluster while its units are removed.
+		rebuild.RoutesRebuildOrEnqueue(a.Name)
+	}
+	fmt.Fprintf(evt, " ---> Rebalancing units for app %q\n", a.Name)
+	err = ensureAppCustomResourcesSynced(clients, a)
+	if err != nil {
+		return err
+	}
+	err = servicecommon.ChangeAppState(newServiceManager(clients, evt), a, "", servicecommon.ProcessState{})
+	if err != nil {
+		return err
+	}
+	err = ensureHPAs(clients, a)
+	if err != nil {
+		return err
+	}
+	err = ensureClustersCronJobs(clients, a, "")
+	if err != nil {
+		return err
+	}
+	if !draining {
+		rebuild.RoutesRebuildOrEnqueue(a.Name)
+	}
+	multiErrors := tsuruErrors.NewMultiError()
+	for _, client := range stale {
+		fmt.Fprintf(evt, " ---> Removing app %q from cluster %q\n", a.Name, client.Name)
+		err = p.destroyInCluster(client, a)
+		if err != nil {
+			multiErrors.Add(errors.Wrapf(err, "unable to remove app from cluster %q", client.Name))
+		}
+	}
+	return multiErrors.ToError()
+}
+
+// staleClustersForApp returns clients for the clusters, not listed in
+// clients, which still have resources of the app. The cluster c is always
+// checked, even if it's no longer stored, when removed is set.
+func staleClustersForApp(a provision.App, clients []*ClusterClient, c *provTypes.Cluster, removed bool) ([]*ClusterClient, error) {
+	provClusters, err := servicemanager.Cluster.FindByProvisioner(provisionerName)
+	if err != nil && err != provTypes.ErrNoCluster {
+		return nil, err
+	}
+	current := map[string]struct{}{}
+	for _, client := range clients {
+		current[client.Name] = struct{}{}
+	}
+	var candidates []provTypes.Cluster
+	if removed {
+		candidates = append(candidates, *c)
+		current[c.Name] = struct{}{}
+	}
+	for _, pc := range provClusters {
+		if _, ok := current[pc.Name]; !ok {
+			candidates = append(candidates, pc)
+		}
+	}
+	var stale []*ClusterClient
+	for i := range candidates {
+		client, err := NewClusterClient(&candidates[i])
+		if err != nil {
+			return nil, err
+		}
+		tclient, err := TsuruClientForConfig(client.restConfig)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tclient.TsuruV1().Apps(client.Namespace()).Get(a.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if k8sErrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.WithStack(err)
+		}
+		stale = append(stale, client)
+	}
+	return stale, nil
+}
+
+// ensureClustersCronJobs keeps the cron jobs of the app in its primary
+// cluster, the first client, removing them from the others.
+func ensureClustersCronJobs(clients []*ClusterClient, a provision.App, imageName string) error {
+	err := ensureCronJobs(clients[0], a, imageName)
+	if err != nil {
+		return err
+	}
+	for _, client := range clients[1:] {
+		ns, err := client.AppNamespace(a)
+		if err != nil {
+			return err
+		}
+		err = removeCronJobs(client, ns, a.GetName())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"sort"
+
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	provTypes "github.com/tsuru/tsuru/types/provision"
+	check "gopkg.in/check.v1"
+)
+
+func clusterNames(clusters []provTypes.Cluster) []string {
+	names := make([]string, len(clusters))
+	for i, c := range clusters {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (s *S) TestPoolClusters(c *check.C) {
+	clusters := []provTypes.Cluster{
+		{Name: "c1", Pools: []string{"p1"}, Weight: 2},
+		{Name: "c2", Pools: []string{"p1", "p2"}, Weight: 1, Drain: true},
+		{Name: "c3", Pools: []string{"p1"}, Weight: 1},
+		{Name: "c4", Pools: []string{"p1"}},
+		{Name: "c5", Pools: []string{"p2"}, Weight: 1},
+		{Name: "c6", Default: true},
+	}
+	result := poolClusters(clusters[0], clusters, "p1")
+	c.Assert(clusterNames(result), check.DeepEquals, []string{"c1", "c3", "c2"})
+	result = poolClusters(clusters[4], clusters, "p2")
+	c.Assert(clusterNames(result), check.DeepEquals, []string{"c5", "c2"})
+	result = poolClusters(clusters[3], clusters, "p1")
+	c.Assert(clusterNames(result), check.DeepEquals, []string{"c4"})
+	result = poolClusters(clusters[5], clusters, "other")
+	c.Assert(clusterNames(result), check.DeepEquals, []string{"c6"})
+}
+
+func (s *S) TestSplitReplicas(c *check.C) {
+	tests := []struct {
+		replicas int
+		clusters []provTypes.Cluster
+		expected []int
+	}{
+		{replicas: 3, clusters: []provTypes.Cluster{{}}, expected: []int{3}},
+		{replicas: 4, clusters: []provTypes.Cluster{{Weight: 1}, {Weight: 1}}, expected: []int{2, 2}},
+		{replicas: 3, clusters: []provTypes.Cluster{{Weight: 1}, {Weight: 1}}, expected: []int{2, 1}},
+		{replicas: 1, clusters: []provTypes.Cluster{{Weight: 1}, {Weight: 3}}, expected: []int{0, 1}},
+		{replicas: 10, clusters: []provTypes.Cluster{{Weight: 3}, {Weight: 2}, {Weight: 1}}, expected: []int{5, 3, 2}},
+		{replicas: 5, clusters: []provTypes.Cluster{{Weight: 1}, {Weight: 1, Drain: true}}, expected: []int{5, 0}},
+		{replicas: 0, clusters: []provTypes.Cluster{{Weight: 1}, {Weight: 2}}, expected: []int{0, 0}},
+	}
+	for i, tt := range tests {
+		c.Check(splitReplicas(tt.replicas, tt.clusters), check.DeepEquals, tt.expected, check.Commentf("test %d", i))
+	}
+}
+
+func (s *S) TestClustersForApp(c *check.C) {
+	c1 := provTypes.Cluster{Name: "c1", Addresses: []string{"addr1"}, Pools: []string{"p1"}, Weight: 1, Provisioner: provisionerName}
+	c2 := provTypes.Cluster{Name: "c2", Addresses: []string{"addr2"}, Pools: []string{"p1"}, Weight: 1, Drain: true, Provisioner: provisionerName}
+	c3 := provTypes.Cluster{Name: "c3", Addresses: []string{"addr3"}, Pools: []string{"p1"}, Weight: 2, Provisioner: provisionerName}
+	s.mockService.Cluster.OnFindByPool = func(prov, pool string) (*provTypes.Cluster, error) {
+		c.Assert(pool, check.Equals, "p1")
+		return &c3, nil
+	}
+	s.mockService.Cluster.OnFindByProvisioner = func(prov string) ([]provTypes.Cluster, error) {
+		return []provTypes.Cluster{c1, c2, c3}, nil
+	}
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.Pool = "p1"
+	clients, err := clustersForApp(a)
+	c.Assert(err, check.IsNil)
+	c.Assert(clients, check.HasLen, 3)
+	c.Assert(clients[0].Name, check.Equals, "c3")
+	c.Assert(clients[1].Name, check.Equals, "c1")
+	c.Assert(clients[2].Name, check.Equals, "c2")
+}
+
+func (s *S) TestClustersForAppExcept(c *check.C) {
+	c1 := provTypes.Cluster{Name: "c1", Addresses: []string{"addr1"}, Pools: []string{"p1"}, Weight: 1, Provisioner: provisionerName}
+	c2 := provTypes.Cluster{Name: "c2", Addresses: []string{"addr2"}, Pools: []string{"p1"}, Weight: 1, Drain: true, Provisioner: provisionerName}
+	c3 := provTypes.Cluster{Name: "c3", Addresses: []string{"addr3"}, Pools: []string{"p1"}, Weight: 2, Provisioner: provisionerName}
+	c4 := provTypes.Cluster{Name: "c4", Addresses: []string{"addr4"}, Default: true, Provisioner: provisionerName}
+	s.mockService.Cluster.OnFindByProvisioner = func(prov string) ([]provTypes.Cluster, error) {
+		return []provTypes.Cluster{c1, c2, c3, c4}, nil
+	}
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.Pool = "p1"
+	clients, err := clustersForAppExcept(a, "c3")
+	c.Assert(err, check.IsNil)
+	c.Assert(clients, check.HasLen, 2)
+	c.Assert(clients[0].Name, check.Equals, "c1")
+	c.Assert(clients[1].Name, check.Equals, "c2")
+	a.Pool = "p2"
+	clients, err = clustersForAppExcept(a, "c3")
+	c.Assert(err, check.IsNil)
+	c.Assert(clients, check.HasLen, 1)
+	c.Assert(clients[0].Name, check.Equals, "c4")
+	_, err = clustersForAppExcept(a, "c4")
+	c.Assert(err, check.Equals, provTypes.ErrNoCluster)
+}
+
+func (s *S) TestClustersForAppsWeighted(c *check.C) {
+	c1 := provTypes.Cluster{Name: "c1", Addresses: []string{"addr1"}, Pools: []string{"p1", "p2"}, Weight: 1, Provisioner: provisionerName}
+	c2 := provTypes.Cluster{Name: "c2", Addresses: []string{"addr2"}, Pools: []string{"p1"}, Weight: 1, Provisioner: provisionerName}
+	s.mockService.Cluster.OnFindByPools = func(prov string, pools []string) (map[string]provTypes.Cluster, error) {
+		return map[string]provTypes.Cluster{"p1": c1, "p2": c1}, nil
+	}
+	s.mockService.Cluster.OnFindByProvisioner = func(prov string) ([]provTypes.Cluster, error) {
+		return []provTypes.Cluster{c1, c2}, nil
+	}
+	a1 := provisiontest.NewFakeApp("myapp1", "python", 0)
+	a1.Pool = "p1"
+	a2 := provisiontest.NewFakeApp("myapp2", "python", 0)
+	a2.Pool = "p2"
+	cApps, err := clustersForApps([]provision.App{a1, a2})
+	c.Assert(err, check.IsNil)
+	c.Assert(cApps, check.HasLen, 2)
+	sort.Slice(cApps, func(i, j int) bool {
+		return cApps[i].client.Name < cApps[j].client.Name
+	})
+	c.Assert(cApps[0].client.Name, check.Equals, "c1")
+	c.Assert(cApps[0].apps, check.DeepEquals, []provision.App{a1, a2})
+	c.Assert(cApps[1].client.Name, check.Equals, "c2")
+	c.Assert(cApps[1].apps, check.DeepEquals, []provision.App{a1})
+}
@@ -0,0 +1,255 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/pool"
+	"github.com/tsuru/tsuru/service"
+	networking "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// allowedCallersAnnotation is the app annotation, prefixed with
+// tsuruLabelPrefix, listing the apps allowed to call it using its internal
+// addresses. Entries in the <service>/<instance> format allow every app bound
+// to the service instance.
+const allowedCallersAnnotation = "allowed-callers"
+
+// appAllowedCallers returns the entries listed in the allowed callers
+// annotation of the app.
+func appAllowedCallers(a provision.App) []string {
+	metadata := a.GetMetadata()
+	raw, _ := metadata.Annotation(tsuruLabelPrefix + allowedCallersAnnotation)
+	var callers []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		callers = append(callers, name)
+	}
+	sort.Strings(callers)
+	return callers
+}
+
+// allowedCallerApps returns the names of the apps allowed to call a, either
+// listed directly in its allowed callers annotation or bound to the service
+// instances listed there.
+func allowedCallerApps(a provision.App) ([]string, error) {
+	names := map[string]struct{}{}
+	for _, caller := range appAllowedCallers(a) {
+		parts := strings.SplitN(caller, "/", 2)
+		if len(parts) == 1 {
+			names[caller] = struct{}{}
+			continue
+		}
+		si, err := service.GetServiceInstance(parts[0], parts[1])
+		if err != nil {
+			if err == service.ErrServiceInstanceNotFound {
+				continue
+			}
+			return nil, err
+		}
+		for _, appName := range si.Apps {
+			names[appName] = struct{}{}
+		}
+	}
+	delete(names, a.GetName())
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func appSelector(a provision.App) (map[string]string, error) {
+	ls, err := provision.ServiceLabels(provision.ServiceLabelsOpts{
+		App: a,
+		ServiceLabelExtendedOpts: provision.ServiceLabelExtendedOpts{
+			Prefix:      tsuruLabelPrefix,
+			Provisioner: provisionerName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ls.ToAppSelector(), nil
+}
+
+func networkPolicyNameForApp(a provision.App) string {
+	return validKubeName(a.GetName())
+}
+
+// newNetworkPolicy returns a network policy isolating the units of the app,
+// allowing ingress traffic only from units of the app itself, from units of
+// the allowed callers, in any namespace, from the router namespace and from
+// the CIDRs allowed in the cluster. Egress traffic isn't restricted, so bound
+// service instances and other external addresses are still reachable.
+func newNetworkPolicy(client *ClusterClient, a provision.App, callers []string) (*networking.NetworkPolicy, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := processObjectLabels(a, "")
+	if err != nil {
+		return nil, err
+	}
+	selector, err := appSelector(a)
+	if err != nil {
+		return nil, err
+	}
+	peers := []networking.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: selector}},
+	}
+	for _, caller := range callers {
+		callerLabels := provision.LabelSet{Prefix: tsuruLabelPrefix}
+		callerLabels.SetAppName(caller)
+		peers = append(peers, networking.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: callerLabels.ToAppSelector()},
+		})
+	}
+	if routerNamespace := client.NetworkPolicyRouterNamespace(a.GetPool()); routerNamespace != "" {
+		peers = append(peers, networking.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				namespaceNameLabel: routerNamespace,
+			}},
+		})
+	}
+	for _, cidr := range client.NetworkPolicyAllowedCIDRs(a.GetPool()) {
+		peers = append(peers, networking.NetworkPolicyPeer{
+			IPBlock: &networking.IPBlock{CIDR: cidr},
+		})
+	}
+	return &networking.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      networkPolicyNameForApp(a),
+			Namespace: ns,
+			Labels:    ls.ToLabels(),
+		},
+		Spec: networking.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: selector},
+			PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress},
+			Ingress: []networking.NetworkPolicyIngressRule{
+				{From: peers},
+			},
+		},
+	}, nil
+}
+
+// ensureNetworkPolicy creates or updates the network policy of the app when
+// network policies are enabled for its pool, removing it otherwise.
+func ensureNetworkPolicy(client *ClusterClient, a provision.App) error {
+	enabled, err := client.NetworkPolicies(a.GetPool())
+	if err != nil {
+		return errors.WithMessage(err, "misconfigured cluster network policies")
+	}
+	if !enabled {
+		return removeNetworkPolicy(client, a)
+	}
+	callers, err := allowedCallerApps(a)
+	if err != nil {
+		return err
+	}
+	np, err := newNetworkPolicy(client, a, callers)
+	if err != nil {
+		return err
+	}
+	existing, err := client.NetworkingV1().NetworkPolicies(np.Namespace).Get(np.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return errors.WithStack(err)
+		}
+		_, err = client.NetworkingV1().NetworkPolicies(np.Namespace).Create(np)
+		return errors.WithStack(err)
+	}
+	if reflect.DeepEqual(existing.Spec, np.Spec) && reflect.DeepEqual(existing.Labels, np.Labels) {
+		return nil
+	}
+	existing.Spec = np.Spec
+	existing.Labels = np.Labels
+	_, err = client.NetworkingV1().NetworkPolicies(np.Namespace).Update(existing)
+	return errors.WithStack(err)
+}
+
+func removeNetworkPolicy(client *ClusterClient, a provision.App) error {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	err = client.NetworkingV1().NetworkPolicies(ns).Delete(networkPolicyNameForApp(a), &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// syncAllowedCallersNetworkPolicy updates the network policies of the app
+// when its allowed callers change.
+func syncAllowedCallersNetworkPolicy(old, new provision.App) error {
+	if reflect.DeepEqual(appAllowedCallers(old), appAllowedCallers(new)) {
+		return nil
+	}
+	return updateNetworkPolicies(new)
+}
+
+// updateNetworkPolicies ensures the network policy of the app in each of its
+// clusters, if the app has been deployed.
+func updateNetworkPolicies(a provision.App) error {
+	if a.GetDeploys() == 0 {
+		return nil
+	}
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		err = ensureNetworkPolicy(client, a)
+		if err != nil {
+			return errors.Wrapf(err, "unable to update network policy of app %q", a.GetName())
+		}
+	}
+	return nil
+}
+
+// UpdateServiceBinds updates the network policies of the apps allowing calls
+// from apps bound to the service instance.
+func (p *kubernetesProvisioner) UpdateServiceBinds(serviceName, instanceName string) error {
+	filter := &app.Filter{}
+	filter.ExtraIn("metadata.annotations.name", tsuruLabelPrefix+allowedCallersAnnotation)
+	apps, err := app.List(filter)
+	if err != nil {
+		return err
+	}
+	entry := serviceName + "/" + instanceName
+	for i := range apps {
+		if !containsString(appAllowedCallers(&apps[i]), entry) {
+			continue
+		}
+		prov, err := pool.GetProvisionerForPool(apps[i].GetPool())
+		if err != nil {
+			return err
+		}
+		if prov.GetName() != provisionerName {
+			continue
+		}
+		err = updateNetworkPolicies(&apps[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
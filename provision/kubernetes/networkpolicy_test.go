@@ -0,0 +1,152 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	"github.com/tsuru/tsuru/service"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+	networking "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (s *S) TestAppAllowedCallers(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	c.Assert(appAllowedCallers(a), check.HasLen, 0)
+	a.Metadata.Annotations = []appTypes.MetadataItem{
+		{Name: "tsuru.io/allowed-callers", Value: "users, mysql/db,,users"},
+	}
+	c.Assert(appAllowedCallers(a), check.DeepEquals, []string{"mysql/db", "users"})
+}
+
+func (s *S) TestAllowedCallerApps(c *check.C) {
+	err := s.conn.ServiceInstances().Insert(service.ServiceInstance{
+		Name:        "db",
+		ServiceName: "mysql",
+		Apps:        []string{"checkout", "myapp", "reports"},
+	})
+	c.Assert(err, check.IsNil)
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	a.Metadata.Annotations = []appTypes.MetadataItem{
+		{Name: "tsuru.io/allowed-callers", Value: "users,mysql/db,mysql/missing"},
+	}
+	callers, err := allowedCallerApps(a)
+	c.Assert(err, check.IsNil)
+	c.Assert(callers, check.DeepEquals, []string{"checkout", "reports", "users"})
+}
+
+func (s *S) TestEnsureNetworkPolicy(c *check.C) {
+	a := &app.App{Name: "billing", TeamOwner: s.team.Name, Metadata: appTypes.Metadata{
+		Annotations: []appTypes.MetadataItem{{Name: "tsuru.io/allowed-callers", Value: "checkout"}},
+	}}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	other := &app.App{Name: "reports", TeamOwner: s.team.Name, Metadata: appTypes.Metadata{
+		Annotations: []appTypes.MetadataItem{{Name: "tsuru.io/allowed-callers", Value: "billing"}},
+	}}
+	err = app.CreateApp(other, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	err = ensureNetworkPolicy(s.clusterClient, a)
+	c.Assert(err, check.IsNil)
+	_, err = s.client.NetworkingV1().NetworkPolicies(ns).Get("billing", metav1.GetOptions{})
+	c.Assert(k8sErrors.IsNotFound(err), check.Equals, true)
+	s.clusterClient.CustomData[networkPoliciesKey] = "true"
+	s.clusterClient.CustomData[networkPolicyCIDRsKey] = "10.0.0.0/8"
+	err = ensureNetworkPolicy(s.clusterClient, a)
+	c.Assert(err, check.IsNil)
+	np, err := s.client.NetworkingV1().NetworkPolicies(ns).Get("billing", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(np.Labels["tsuru.io/app-name"], check.Equals, "billing")
+	c.Assert(np.Spec, check.DeepEquals, networking.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tsuru.io/app-name": "billing"}},
+		PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress},
+		Ingress: []networking.NetworkPolicyIngressRule{{
+			From: []networking.NetworkPolicyPeer{
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tsuru.io/app-name": "billing"}}},
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"tsuru.io/app-name": "checkout"}},
+				},
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "ingress-nginx"}}},
+				{IPBlock: &networking.IPBlock{CIDR: "10.0.0.0/8"}},
+			},
+		}},
+	})
+	delete(s.clusterClient.CustomData, networkPolicyCIDRsKey)
+	s.clusterClient.CustomData[networkPolicyRouterKey] = "routers"
+	err = ensureNetworkPolicy(s.clusterClient, a)
+	c.Assert(err, check.IsNil)
+	np, err = s.client.NetworkingV1().NetworkPolicies(ns).Get("billing", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(np.Spec.Ingress[0].From, check.HasLen, 3)
+	c.Assert(np.Spec.Ingress[0].From[2].NamespaceSelector.MatchLabels, check.DeepEquals, map[string]string{"kubernetes.io/metadata.name": "routers"})
+	s.clusterClient.CustomData[networkPoliciesKey] = "false"
+	err = ensureNetworkPolicy(s.clusterClient, a)
+	c.Assert(err, check.IsNil)
+	_, err = s.client.NetworkingV1().NetworkPolicies(ns).Get("billing", metav1.GetOptions{})
+	c.Assert(k8sErrors.IsNotFound(err), check.Equals, true)
+}
+
+func (s *S) TestEnsureNetworkPolicyInvalidConfig(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	s.clusterClient.CustomData[networkPoliciesKey] = "xyz"
+	err = ensureNetworkPolicy(s.clusterClient, a)
+	c.Assert(err, check.ErrorMatches, `misconfigured cluster network policies: .*`)
+}
+
+func (s *S) TestUpdateAppAllowedCallersSyncsNetworkPolicy(c *check.C) {
+	s.clusterClient.CustomData[networkPoliciesKey] = "true"
+	a := &app.App{Name: "billing", TeamOwner: s.team.Name, Deploys: 1}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	err = ensureNetworkPolicy(s.clusterClient, a)
+	c.Assert(err, check.IsNil)
+	err = a.Update(app.App{Metadata: appTypes.Metadata{
+		Annotations: []appTypes.MetadataItem{{Name: "tsuru.io/allowed-callers", Value: "checkout"}},
+	}}, nil)
+	c.Assert(err, check.IsNil)
+	np, err := s.client.NetworkingV1().NetworkPolicies(ns).Get("billing", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(np.Spec.Ingress[0].From, check.HasLen, 3)
+	c.Assert(np.Spec.Ingress[0].From[1].PodSelector.MatchLabels, check.DeepEquals, map[string]string{"tsuru.io/app-name": "checkout"})
+}
+
+func (s *S) TestUpdateServiceBindsSyncsNetworkPolicy(c *check.C) {
+	s.clusterClient.CustomData[networkPoliciesKey] = "true"
+	a := &app.App{Name: "billing", TeamOwner: s.team.Name, Deploys: 1, Metadata: appTypes.Metadata{
+		Annotations: []appTypes.MetadataItem{{Name: "tsuru.io/allowed-callers", Value: "mysql/db"}},
+	}}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	err = s.conn.ServiceInstances().Insert(service.ServiceInstance{
+		Name:        "db",
+		ServiceName: "mysql",
+		Apps:        []string{"checkout"},
+	})
+	c.Assert(err, check.IsNil)
+	err = s.p.UpdateServiceBinds("mysql", "db")
+	c.Assert(err, check.IsNil)
+	np, err := s.client.NetworkingV1().NetworkPolicies(ns).Get("billing", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(np.Spec.Ingress[0].From, check.HasLen, 3)
+	c.Assert(np.Spec.Ingress[0].From[1].PodSelector.MatchLabels, check.DeepEquals, map[string]string{"tsuru.io/app-name": "checkout"})
+}
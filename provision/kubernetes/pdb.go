@@ -0,0 +1,114 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/provision"
+	policy "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func pdbNameForApp(a provision.App, process string) string {
+	return appProcessName(a, process)
+}
+
+func processObjectLabels(a provision.App, process string) (*provision.LabelSet, error) {
+	ls, err := provision.ServiceLabels(provision.ServiceLabelsOpts{
+		App:     a,
+		Process: process,
+		ServiceLabelExtendedOpts: provision.ServiceLabelExtendedOpts{
+			Prefix:      tsuruLabelPrefix,
+			Provisioner: provisionerName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ls.WithoutAppReplicas(), nil
+}
+
+func newPDB(client *ClusterClient, a provision.App, process string) (*policy.PodDisruptionBudget, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	minAvailable, err := client.MinAvailable(a.GetPool())
+	if err != nil {
+		return nil, errors.WithMessage(err, "misconfigured cluster disruption budget")
+	}
+	ls, err := processObjectLabels(a, process)
+	if err != nil {
+		return nil, err
+	}
+	pdb := &policy.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pdbNameForApp(a, process),
+			Namespace: ns,
+			Labels:    ls.ToLabels(),
+		},
+		Spec: policy.PodDisruptionBudgetSpec{
+			MinAvailable: minAvailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ls.ToSelector(),
+			},
+		},
+	}
+	if minAvailable == nil {
+		// A min available percentage would block drains of nodes running
+		// processes with a single unit.
+		maxUnavailable := intstr.FromInt(1)
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	}
+	return pdb, nil
+}
+
+// ensurePDB creates or updates the pod disruption budget of the app process,
+// preventing voluntary disruptions from taking down too many of its units at
+// once.
+func ensurePDB(client *ClusterClient, a provision.App, process string) error {
+	pdb, err := newPDB(client, a, process)
+	if err != nil {
+		return err
+	}
+	existing, err := client.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Get(pdb.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return errors.WithStack(err)
+		}
+		_, err = client.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Create(pdb)
+		return errors.WithStack(err)
+	}
+	if reflect.DeepEqual(existing.Spec.MinAvailable, pdb.Spec.MinAvailable) &&
+		reflect.DeepEqual(existing.Spec.MaxUnavailable, pdb.Spec.MaxUnavailable) &&
+		reflect.DeepEqual(existing.Spec.Selector, pdb.Spec.Selector) &&
+		reflect.DeepEqual(existing.Labels, pdb.Labels) {
+		return nil
+	}
+	// The spec of pod disruption budgets is immutable in older kubernetes
+	// versions, so they're replaced instead of updated.
+	err = client.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Delete(pdb.Name, &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	_, err = client.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Create(pdb)
+	return errors.WithStack(err)
+}
+
+func removePDB(client *ClusterClient, a provision.App, process string) error {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	err = client.PolicyV1beta1().PodDisruptionBudgets(ns).Delete(pdbNameForApp(a, process), &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
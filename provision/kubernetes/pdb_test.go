@@ -0,0 +1,72 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	check "gopkg.in/check.v1"
+	policy "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func (s *S) TestEnsurePDB(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	err = ensurePDB(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	pdb, err := s.client.PolicyV1beta1().PodDisruptionBudgets(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	maxUnavailable := intstr.FromInt(1)
+	c.Assert(pdb.Spec, check.DeepEquals, policy.PodDisruptionBudgetSpec{
+		MaxUnavailable: &maxUnavailable,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"tsuru.io/app-name":        "myapp",
+				"tsuru.io/app-process":     "web",
+				"tsuru.io/is-build":        "false",
+				"tsuru.io/is-isolated-run": "false",
+			},
+		},
+	})
+	c.Assert(pdb.Labels["tsuru.io/app-name"], check.Equals, "myapp")
+	c.Assert(pdb.Labels["tsuru.io/app-process"], check.Equals, "web")
+	s.clusterClient.CustomData[minAvailableKey] = "1"
+	err = ensurePDB(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	pdb, err = s.client.PolicyV1beta1().PodDisruptionBudgets(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(*pdb.Spec.MinAvailable, check.Equals, intstr.FromInt(1))
+	c.Assert(pdb.Spec.MaxUnavailable, check.IsNil)
+}
+
+func (s *S) TestEnsurePDBInvalidConfig(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	s.clusterClient.CustomData[minAvailableKey] = "-1"
+	err = ensurePDB(s.clusterClient, a, "web")
+	c.Assert(err, check.ErrorMatches, `misconfigured cluster disruption budget: invalid min available value "-1"`)
+}
+
+func (s *S) TestRemovePDB(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	err := s.p.Provision(a)
+	c.Assert(err, check.IsNil)
+	err = ensurePDB(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	err = removePDB(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	_, err = s.client.PolicyV1beta1().PodDisruptionBudgets(ns).Get("myapp-web", metav1.GetOptions{})
+	c.Assert(k8sErrors.IsNotFound(err), check.Equals, true)
+	err = removePDB(s.clusterClient, a, "web")
+	c.Assert(err, check.IsNil)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/tsuru/tsuru/provision/node"
 	"github.com/tsuru/tsuru/provision/servicecommon"
 	"github.com/tsuru/tsuru/set"
+	appTypes "github.com/tsuru/tsuru/types/app"
 	provTypes "github.com/tsuru/tsuru/types/provision"
 	"github.com/tsuru/tsuru/volume"
 	apiv1 "k8s.io/api/core/v1"
@@ -74,6 +75,10 @@ var (
 	_ provision.RollbackableDeployer     = &kubernetesProvisioner{}
 	_ provision.InterAppProvisioner      = &kubernetesProvisioner{}
 	_ provision.HCProvisioner            = &kubernetesProvisioner{}
+	_ provision.ExecCancelProvisioner    = &kubernetesProvisioner{}
+	_ provision.ServiceBindProvisioner   = &kubernetesProvisioner{}
+	_ provision.AutoScaleProvisioner     = &kubernetesProvisioner{}
+	_ provision.JobProvisioner           = &kubernetesProvisioner{}
 	_ cluster.ClusteredProvisioner       = &kubernetesProvisioner{}
 	_ cluster.ClusterProvider            = &kubernetesProvisioner{}
 	// _ provision.OptionalLogsProvisioner  = &kubernetesProvisioner{}
@@ -191,7 +196,10 @@ func (p *kubernetesProvisioner) InitializeCluster(c *provTypes.Cluster) error {
 	}
 	stopClusterController(p, clusterClient)
 	_, err = getClusterController(p, clusterClient)
-	return err
+	if err != nil {
+		return err
+	}
+	return p.rebalanceClusterApps(c, false)
 }
 
 func (p *kubernetesProvisioner) ValidateCluster(c *provTypes.Cluster) error {
@@ -228,6 +236,12 @@ func (p *kubernetesProvisioner) UpdateCluster(ctx context.Context, c *provTypes.
 
 func (p *kubernetesProvisioner) DeleteCluster(ctx context.Context, c *provTypes.Cluster) error {
 	stopClusterControllerByName(p, c.Name)
+	if c.Weight > 0 {
+		err := p.rebalanceClusterApps(c, true)
+		if err != nil {
+			return err
+		}
+	}
 	if len(c.CreateData) > 0 {
 		return provider.DeleteCluster(ctx, c.Name, c.CreateData, c.Writer)
 	}
@@ -239,18 +253,28 @@ func (p *kubernetesProvisioner) GetName() string {
 }
 
 func (p *kubernetesProvisioner) Provision(a provision.App) error {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return err
 	}
-	return ensureAppCustomResourceSynced(client, a)
+	return ensureAppCustomResourcesSynced(clients, a)
 }
 
 func (p *kubernetesProvisioner) Destroy(a provision.App) error {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return err
 	}
+	for i, client := range clients {
+		err = p.destroyInCluster(client, a)
+		if err != nil && (i == 0 || !k8sErrors.IsNotFound(err)) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *kubernetesProvisioner) destroyInCluster(client *ClusterClient, a provision.App) error {
 	tclient, err := TsuruClientForConfig(client.restConfig)
 	if err != nil {
 		return err
@@ -275,8 +299,24 @@ func (p *kubernetesProvisioner) removeResources(client *ClusterClient, app *tsur
 			if err != nil && !k8sErrors.IsNotFound(err) {
 				multiErrors.Add(err)
 			}
+			err = client.PolicyV1beta1().PodDisruptionBudgets(app.Spec.NamespaceName).Delete(dd, &metav1.DeleteOptions{})
+			if err != nil && !k8sErrors.IsNotFound(err) {
+				multiErrors.Add(errors.WithStack(err))
+			}
+			err = client.AutoscalingV2beta1().HorizontalPodAutoscalers(app.Spec.NamespaceName).Delete(dd, &metav1.DeleteOptions{})
+			if err != nil && !k8sErrors.IsNotFound(err) {
+				multiErrors.Add(errors.WithStack(err))
+			}
 		}
 	}
+	err := removeCronJobs(client, app.Spec.NamespaceName, app.Name)
+	if err != nil {
+		multiErrors.Add(err)
+	}
+	err = client.NetworkingV1().NetworkPolicies(app.Spec.NamespaceName).Delete(validKubeName(app.Name), &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		multiErrors.Add(errors.WithStack(err))
+	}
 	for _, s := range app.Spec.Services {
 		for _, ss := range s {
 			err := client.CoreV1().Services(app.Spec.NamespaceName).Delete(ss, &metav1.DeleteOptions{
@@ -320,31 +360,25 @@ func (p *kubernetesProvisioner) removeResources(client *ClusterClient, app *tsur
 }
 
 func changeState(a provision.App, process string, state servicecommon.ProcessState, w io.Writer) error {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return err
 	}
-	if err := ensureAppCustomResourceSynced(client, a); err != nil {
+	if err := ensureAppCustomResourcesSynced(clients, a); err != nil {
 		return err
 	}
-	return servicecommon.ChangeAppState(&serviceManager{
-		client: client,
-		writer: w,
-	}, a, process, state)
+	return servicecommon.ChangeAppState(newServiceManager(clients, w), a, process, state)
 }
 
 func changeUnits(a provision.App, units int, processName string, w io.Writer) error {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return err
 	}
-	if err := ensureAppCustomResourceSynced(client, a); err != nil {
+	if err := ensureAppCustomResourcesSynced(clients, a); err != nil {
 		return err
 	}
-	return servicecommon.ChangeUnits(&serviceManager{
-		client: client,
-		writer: w,
-	}, a, units, processName)
+	return servicecommon.ChangeUnits(newServiceManager(clients, w), a, units, processName)
 }
 
 func (p *kubernetesProvisioner) AddUnits(a provision.App, units uint, processName string, w io.Writer) error {
@@ -582,7 +616,7 @@ func (p *kubernetesProvisioner) podsForApps(client *ClusterClient, apps []provis
 }
 
 func (p *kubernetesProvisioner) RoutableAddresses(a provision.App) ([]url.URL, error) {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return nil, err
 	}
@@ -600,6 +634,24 @@ func (p *kubernetesProvisioner) RoutableAddresses(a provision.App) ([]url.URL, e
 	if webProcessName == "" {
 		return nil, nil
 	}
+	if len(clients) == 1 {
+		return p.routableAddressesForCluster(clients[0], a, webProcessName)
+	}
+	var addrs []url.URL
+	for _, client := range clients {
+		if client.Drain {
+			continue
+		}
+		clusterAddrs, err := p.routableAddressesForCluster(client, a, webProcessName)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, clusterAddrs...)
+	}
+	return addrs, nil
+}
+
+func (p *kubernetesProvisioner) routableAddressesForCluster(client *ClusterClient, a provision.App, webProcessName string) ([]url.URL, error) {
 	srvName := deploymentNameForApp(a, webProcessName)
 	ns, err := client.AppNamespace(a)
 	if err != nil {
@@ -693,21 +745,14 @@ func (p *kubernetesProvisioner) addressesForPool(client *ClusterClient, poolName
 }
 
 func (p *kubernetesProvisioner) RegisterUnit(a provision.App, unitID string, customData map[string]interface{}) error {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return err
 	}
-	ns, err := client.AppNamespace(a)
+	client, pod, err := podForUnit(clients, a, unitID)
 	if err != nil {
 		return err
 	}
-	pod, err := client.CoreV1().Pods(ns).Get(unitID, metav1.GetOptions{})
-	if err != nil {
-		if k8sErrors.IsNotFound(err) {
-			return &provision.UnitNotFoundError{ID: unitID}
-		}
-		return errors.WithStack(err)
-	}
 	units, err := p.podsToUnits(client, []apiv1.Pod{*pod}, a, nil)
 	if err != nil {
 		return err
@@ -746,10 +791,13 @@ func (p *kubernetesProvisioner) ListNodes(addressFilter []string) ([]provision.N
 }
 
 func (p *kubernetesProvisioner) InternalAddresses(ctx context.Context, a provision.App) ([]provision.AppInternalAddress, error) {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return nil, err
 	}
+	// Services have the same names in every cluster of the app, the primary
+	// cluster is used as it's never draining while others are available.
+	client := clients[0]
 	ns, err := client.AppNamespace(a)
 	if err != nil {
 		return nil, err
@@ -1061,13 +1109,14 @@ func (p *kubernetesProvisioner) internalNodeUpdate(opts provision.UpdateNodeOpti
 }
 
 func (p *kubernetesProvisioner) Deploy(a provision.App, buildImageID string, evt *event.Event) (string, error) {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return "", err
 	}
-	if err = ensureAppCustomResourceSynced(client, a); err != nil {
+	if err = ensureAppCustomResourcesSynced(clients, a); err != nil {
 		return "", err
 	}
+	client := clients[0]
 	newImage := buildImageID
 	if strings.HasSuffix(buildImageID, "-builder") {
 		newImage, err = image.AppNewImageName(a.GetName())
@@ -1101,19 +1150,20 @@ func (p *kubernetesProvisioner) Deploy(a provision.App, buildImageID string, evt
 			return "", err
 		}
 	}
-	manager := &serviceManager{
-		client: client,
-		writer: evt,
-	}
+	manager := newServiceManager(clients, evt)
 	err = servicecommon.RunServicePipeline(manager, a, newImage, nil, evt)
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
-	return newImage, ensureAppCustomResourceSynced(client, a)
+	err = ensureClustersCronJobs(clients, a, newImage)
+	if err != nil {
+		return "", err
+	}
+	return newImage, ensureAppCustomResourcesSynced(clients, a)
 }
 
 func (p *kubernetesProvisioner) Rollback(a provision.App, imageID string, evt *event.Event) (string, error) {
-	client, err := clusterForPool(a.GetPool())
+	clients, err := clustersForApp(a)
 	if err != nil {
 		return "", err
 	}
@@ -1128,14 +1178,15 @@ func (p *kubernetesProvisioner) Rollback(a provision.App, imageID string, evt *e
 	if imgMetaData.DisableRollback {
 		return "", fmt.Errorf("Can't Rollback image %s, reason: %s", foundImageID, imgMetaData.Reason)
 	}
-	manager := &serviceManager{
-		client: client,
-		writer: evt,
-	}
+	manager := newServiceManager(clients, evt)
 	err = servicecommon.RunServicePipeline(manager, a, foundImageID, nil, evt)
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
+	err = ensureClustersCronJobs(clients, a, foundImageID)
+	if err != nil {
+		return "", err
+	}
 	return imageID, nil
 }
 
@@ -1155,10 +1206,11 @@ func (p *kubernetesProvisioner) RemoveNodeContainer(name string, pool string, wr
 }
 
 func (p *kubernetesProvisioner) ExecuteCommand(opts provision.ExecOptions) error {
-	client, err := clusterForPool(opts.App.GetPool())
+	clients, err := clustersForApp(opts.App)
 	if err != nil {
 		return err
 	}
+	client := clients[0]
 	var size *remotecommand.TerminalSize
 	if opts.Width != 0 && opts.Height != 0 {
 		size = &remotecommand.TerminalSize{
@@ -1180,10 +1232,19 @@ func (p *kubernetesProvisioner) ExecuteCommand(opts provision.ExecOptions) error
 		tty:      opts.Stdin != nil,
 	}
 	if len(opts.Units) == 0 {
+		ctx, cancel := opts.Event.CancelableContext(context.Background())
+		defer cancel()
+		eOpts.ctx = ctx
 		return runIsolatedCmdPod(client, eOpts)
 	}
 	for _, u := range opts.Units {
 		eOpts.unit = u
+		if len(clients) > 1 {
+			eOpts.client, _, err = podForUnit(clients, opts.App, u)
+			if err != nil {
+				return err
+			}
+		}
 		err := execCommand(eOpts)
 		if err != nil {
 			return err
@@ -1217,6 +1278,7 @@ func runIsolatedCmdPod(client *ClusterClient, opts execOpts) error {
 		envs = append(envs, apiv1.EnvVar{Name: envData.Name, Value: envData.Value})
 	}
 	return runPod(runSinglePodArgs{
+		ctx:      opts.ctx,
 		client:   client,
 		stdout:   opts.stdout,
 		stderr:   opts.stderr,
@@ -1280,7 +1342,11 @@ func (p *kubernetesProvisioner) IsVolumeProvisioned(volumeName, pool string) (bo
 }
 
 func (p *kubernetesProvisioner) UpdateApp(old, new provision.App, w io.Writer) error {
-	if old.GetPool() == new.GetPool() {
+	err := syncAllowedCallersNetworkPolicy(old, new)
+	if err != nil {
+		return err
+	}
+	if old.GetPool() == new.GetPool() && old.GetTeamOwner() == new.GetTeamOwner() {
 		return nil
 	}
 	client, err := clusterForPool(old.GetPool())
@@ -1291,8 +1357,16 @@ func (p *kubernetesProvisioner) UpdateApp(old, new provision.App, w io.Writer) e
 	if err != nil {
 		return err
 	}
+	oldNamespace, err := client.namespaceForApp(old)
+	if err != nil {
+		return err
+	}
+	newNamespace, err := newclient.namespaceForApp(new)
+	if err != nil {
+		return err
+	}
 	sameCluster := client.GetCluster().Name == newclient.GetCluster().Name
-	sameNamespace := client.PoolNamespace(old.GetPool()) == client.PoolNamespace(new.GetPool())
+	sameNamespace := oldNamespace == newNamespace
 	if sameCluster && !sameNamespace {
 		volumes, err := volume.ListByApp(old.GetName())
 		if err != nil {
@@ -1400,9 +1474,13 @@ func ensureAppCustomResource(client *ClusterClient, a provision.App) error {
 	if !k8sErrors.IsNotFound(err) {
 		return err
 	}
+	ns, err := client.namespaceForApp(a)
+	if err != nil {
+		return err
+	}
 	_, err = tclient.TsuruV1().Apps(client.Namespace()).Create(&tsuruv1.App{
 		ObjectMeta: metav1.ObjectMeta{Name: a.GetName()},
-		Spec:       tsuruv1.AppSpec{NamespaceName: client.PoolNamespace(a.GetPool())},
+		Spec:       tsuruv1.AppSpec{NamespaceName: ns},
 	})
 	return err
 }
@@ -1537,3 +1615,31 @@ func isDefaultPort(portsConfig []provTypes.TsuruYamlKubernetesProcessPortConfig)
 func (p *kubernetesProvisioner) HandlesHC() bool {
 	return true
 }
+
+func (p *kubernetesProvisioner) HandlesExecCancel() bool {
+	return true
+}
+
+func (p *kubernetesProvisioner) HandlesAutoScale(a provision.App, spec appTypes.AutoScaleSpec) (bool, error) {
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return false, err
+	}
+	return handlesAutoScale(clients, a, spec)
+}
+
+func (p *kubernetesProvisioner) UpdateAutoScale(a provision.App) error {
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return err
+	}
+	return ensureHPAs(clients, a)
+}
+
+func (p *kubernetesProvisioner) UpdateJobs(a provision.App) error {
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return err
+	}
+	return ensureClustersCronJobs(clients, a, "")
+}
@@ -0,0 +1,927 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/image"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/router"
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	ingressRouterType      = "kubernetes-ingress"
+	loadBalancerRouterType = "kubernetes-loadbalancer"
+
+	routerAppLabel  = tsuruLabelPrefix + "router-app"
+	routerNameLabel = tsuruLabelPrefix + "router"
+
+	routerRoutesAnnotation = tsuruLabelPrefix + "router-routes"
+	routerCNamesAnnotation = tsuruLabelPrefix + "router-cnames"
+	routerCertsAnnotation  = tsuruLabelPrefix + "router-certificates"
+	routerTargetAnnotation = tsuruLabelPrefix + "router-target-app"
+	routerOptsAnnotation   = tsuruLabelPrefix + "router-opts"
+
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+	ingressClassOpt        = "class"
+
+	loadBalancerPort = 80
+)
+
+func init() {
+	router.Register(ingressRouterType, createIngressRouter)
+	router.Register(loadBalancerRouterType, createLoadBalancerRouter)
+}
+
+// kubeRouter routes traffic to apps using objects created directly in the
+// clusters of the app: an Ingress or a Service of type LoadBalancer for each
+// backend, pointing to the web process of the app. The state of each backend
+// is kept in annotations of the object serving it.
+type kubeRouter struct {
+	routerName   string
+	routerType   string
+	domain       string
+	ingressClass string
+	annotations  map[string]string
+	allowedOpts  []string
+	loadBalancer bool
+}
+
+// ingressRouter is a kubeRouter backed by Ingresses, which are also able to
+// terminate TLS for the cnames of the app.
+type ingressRouter struct {
+	*kubeRouter
+}
+
+var (
+	_ router.Router       = &kubeRouter{}
+	_ router.CNameRouter  = &kubeRouter{}
+	_ router.OptsRouter   = &kubeRouter{}
+	_ router.StatusRouter = &kubeRouter{}
+	_ router.TLSRouter    = &ingressRouter{}
+)
+
+func createIngressRouter(routerName string, config router.ConfigGetter) (router.Router, error) {
+	r, err := newKubeRouter(routerName, ingressRouterType, config)
+	if err != nil {
+		return nil, err
+	}
+	r.ingressClass, _ = config.GetString("ingress-class")
+	return &ingressRouter{kubeRouter: r}, nil
+}
+
+func createLoadBalancerRouter(routerName string, config router.ConfigGetter) (router.Router, error) {
+	r, err := newKubeRouter(routerName, loadBalancerRouterType, config)
+	if err != nil {
+		return nil, err
+	}
+	r.loadBalancer = true
+	return r, nil
+}
+
+func newKubeRouter(routerName, routerType string, config router.ConfigGetter) (*kubeRouter, error) {
+	domain, _ := config.GetString("domain")
+	annotations := map[string]string{}
+	rawAnnotations, _ := config.Get("annotations")
+	if rawAnnotations != nil {
+		m, ok := rawAnnotations.(map[interface{}]interface{})
+		if !ok {
+			return nil, errors.Errorf("invalid annotations configuration: %v", rawAnnotations)
+		}
+		for k := range m {
+			key := fmt.Sprint(k)
+			annotations[key], _ = config.GetString("annotations:" + key)
+		}
+	}
+	var allowedOpts []string
+	rawAllowedOpts, _ := config.Get("allowed-opts")
+	switch v := rawAllowedOpts.(type) {
+	case nil:
+	case []string:
+		allowedOpts = v
+	case []interface{}:
+		for _, opt := range v {
+			allowedOpts = append(allowedOpts, fmt.Sprint(opt))
+		}
+	default:
+		return nil, errors.Errorf("invalid allowed-opts configuration: %v", rawAllowedOpts)
+	}
+	return &kubeRouter{
+		routerName:  routerName,
+		routerType:  routerType,
+		domain:      domain,
+		annotations: annotations,
+		allowedOpts: allowedOpts,
+	}, nil
+}
+
+// optAllowed returns whether the opt is in the allowed-opts of the router.
+// Entries ending with * allow every opt starting with the rest of the entry.
+func (r *kubeRouter) optAllowed(opt string) bool {
+	for _, allowed := range r.allowedOpts {
+		if allowed == opt || (strings.HasSuffix(allowed, "*") && strings.HasPrefix(opt, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOpts ensures every backend opt is allowed by the router, as opts
+// are set as annotations of the objects created by the router.
+func (r *kubeRouter) validateOpts(opts map[string]string) error {
+	for opt := range opts {
+		if !r.optAllowed(opt) {
+			return &tsuruErrors.ValidationError{Message: fmt.Sprintf("opt %q is not allowed by router %q", opt, r.routerName)}
+		}
+	}
+	return nil
+}
+
+// routerBackend is the state of a backend, stored in the annotations of the
+// object serving it.
+type routerBackend struct {
+	app    *app.App
+	target string
+	routes []string
+	cnames []string
+	certs  []string
+	opts   map[string]string
+}
+
+func (r *kubeRouter) GetName() string {
+	return r.routerName
+}
+
+func (r *kubeRouter) StartupMessage() (string, error) {
+	return fmt.Sprintf("%s router %q with domain %q", r.routerType, r.routerName, r.domain), nil
+}
+
+func (r *kubeRouter) objectName(backend string) string {
+	return validKubeName(fmt.Sprintf("%s-router-%s", backend, r.routerName))
+}
+
+func (r *kubeRouter) secretName(backend, cname string) string {
+	return validKubeName(fmt.Sprintf("%s-%s", r.objectName(backend), cname))
+}
+
+func (r *kubeRouter) objectLabels(backend string) map[string]string {
+	return map[string]string{
+		routerAppLabel:  backend,
+		routerNameLabel: r.routerName,
+	}
+}
+
+func (r *kubeRouter) AddBackend(a router.App) error {
+	return r.AddBackendOpts(a, nil)
+}
+
+func (r *kubeRouter) AddBackendOpts(routerApp router.App, opts map[string]string) error {
+	err := r.validateOpts(opts)
+	if err != nil {
+		return err
+	}
+	a, err := app.GetByName(routerApp.GetName())
+	if err != nil {
+		return err
+	}
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return err
+	}
+	_, err = r.getBackend(clients[0], a)
+	if err == nil {
+		return router.ErrBackendExists
+	}
+	if err != router.ErrBackendNotFound {
+		return err
+	}
+	b := &routerBackend{app: a, target: a.Name, opts: opts}
+	for _, client := range clients {
+		err = r.syncBackend(client, b, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return router.Store(a.Name, a.Name, r.routerType)
+}
+
+func (r *kubeRouter) UpdateBackendOpts(routerApp router.App, opts map[string]string) error {
+	err := r.validateOpts(opts)
+	if err != nil {
+		return err
+	}
+	return r.updateBackend(routerApp.GetName(), func(b *routerBackend) error {
+		b.opts = opts
+		return nil
+	})
+}
+
+func (r *kubeRouter) RemoveBackend(name string) error {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return err
+	}
+	if backendName != name {
+		return router.ErrBackendSwapped
+	}
+	a, err := app.GetByName(backendName)
+	if err != nil {
+		return err
+	}
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return err
+	}
+	for i, client := range clients {
+		err = r.removeBackend(client, a)
+		if k8sErrors.IsNotFound(err) {
+			if i == 0 {
+				return router.ErrBackendNotFound
+			}
+			err = nil
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return router.Remove(backendName)
+}
+
+func (r *kubeRouter) removeBackend(client *ClusterClient, a *app.App) error {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	err = client.CoreV1().Secrets(ns).DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set(r.objectLabels(a.Name))).String(),
+	})
+	if err != nil {
+		return err
+	}
+	if r.loadBalancer {
+		return client.CoreV1().Services(ns).Delete(r.objectName(a.Name), &metav1.DeleteOptions{})
+	}
+	return client.ExtensionsV1beta1().Ingresses(ns).Delete(r.objectName(a.Name), &metav1.DeleteOptions{})
+}
+
+func (r *kubeRouter) AddRoutes(name string, addresses []*url.URL) error {
+	return r.updateBackend(name, func(b *routerBackend) error {
+		for _, addr := range addresses {
+			if !containsString(b.routes, addr.String()) {
+				b.routes = append(b.routes, addr.String())
+			}
+		}
+		return nil
+	})
+}
+
+func (r *kubeRouter) RemoveRoutes(name string, addresses []*url.URL) error {
+	return r.updateBackend(name, func(b *routerBackend) error {
+		toRemove := make([]string, len(addresses))
+		for i, addr := range addresses {
+			toRemove[i] = addr.String()
+		}
+		b.routes = removeStrings(b.routes, toRemove...)
+		return nil
+	})
+}
+
+func (r *kubeRouter) Routes(name string) ([]*url.URL, error) {
+	b, _, err := r.loadBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*url.URL, 0, len(b.routes))
+	for _, route := range b.routes {
+		u, err := url.Parse(route)
+		if err != nil {
+			return nil, errors.Errorf("failed to parse url %s: %s", route, err)
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func (r *kubeRouter) Addr(name string) (string, error) {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return "", err
+	}
+	if !r.loadBalancer && r.domain != "" {
+		return fmt.Sprintf("%s.%s", backendName, r.domain), nil
+	}
+	lbStatuses, err := r.loadBalancerStatuses(name)
+	if err != nil {
+		return "", err
+	}
+	for _, lbStatus := range lbStatuses {
+		for _, ingress := range lbStatus.status.Ingress {
+			if ingress.Hostname != "" {
+				return ingress.Hostname, nil
+			}
+			if ingress.IP != "" {
+				return ingress.IP, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (r *kubeRouter) GetBackendStatus(name string) (router.BackendStatus, string, error) {
+	lbStatuses, err := r.loadBalancerStatuses(name)
+	if err != nil {
+		return "", "", err
+	}
+	for _, lbStatus := range lbStatuses {
+		if len(lbStatus.status.Ingress) == 0 {
+			msg := "waiting for load balancer address"
+			if len(lbStatuses) > 1 {
+				msg = fmt.Sprintf("%s in cluster %q", msg, lbStatus.cluster)
+			}
+			return router.BackendStatusNotReady, msg, nil
+		}
+	}
+	return router.BackendStatusReady, "", nil
+}
+
+type clusterLoadBalancerStatus struct {
+	cluster string
+	status  *apiv1.LoadBalancerStatus
+}
+
+// loadBalancerStatuses returns the load balancer status of the backend in
+// each cluster of its app receiving traffic, the primary cluster first.
+func (r *kubeRouter) loadBalancerStatuses(name string) ([]clusterLoadBalancerStatus, error) {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return nil, err
+	}
+	a, err := app.GetByName(backendName)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return nil, err
+	}
+	var result []clusterLoadBalancerStatus
+	for i, client := range clients {
+		if i > 0 && client.Drain {
+			continue
+		}
+		status, err := r.loadBalancerStatus(client, a)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, clusterLoadBalancerStatus{cluster: client.Name, status: status})
+	}
+	return result, nil
+}
+
+func (r *kubeRouter) loadBalancerStatus(client *ClusterClient, a *app.App) (*apiv1.LoadBalancerStatus, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	if r.loadBalancer {
+		svc, err := client.CoreV1().Services(ns).Get(r.objectName(a.Name), metav1.GetOptions{})
+		if err != nil {
+			return nil, backendError(err)
+		}
+		return &svc.Status.LoadBalancer, nil
+	}
+	ingress, err := client.ExtensionsV1beta1().Ingresses(ns).Get(r.objectName(a.Name), metav1.GetOptions{})
+	if err != nil {
+		return nil, backendError(err)
+	}
+	return &ingress.Status.LoadBalancer, nil
+}
+
+func (r *kubeRouter) SetCName(cname, name string) error {
+	if r.domain != "" && !router.ValidCName(cname, r.domain) {
+		return router.ErrCNameNotAllowed
+	}
+	return r.updateBackend(name, func(b *routerBackend) error {
+		if containsString(b.cnames, cname) {
+			return router.ErrCNameExists
+		}
+		b.cnames = append(b.cnames, cname)
+		return nil
+	})
+}
+
+func (r *kubeRouter) UnsetCName(cname, name string) error {
+	return r.updateBackend(name, func(b *routerBackend) error {
+		if !containsString(b.cnames, cname) {
+			return router.ErrCNameNotFound
+		}
+		b.cnames = removeStrings(b.cnames, cname)
+		return nil
+	})
+}
+
+func (r *kubeRouter) CNames(name string) ([]*url.URL, error) {
+	b, _, err := r.loadBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*url.URL, len(b.cnames))
+	for i, cname := range b.cnames {
+		result[i] = &url.URL{Host: cname}
+	}
+	return result, nil
+}
+
+// Swap exchanges the apps served by the backends before swapping their
+// routes, as the traffic of each backend is sent to the web process service
+// of its target app instead of to the routes themselves.
+func (r *kubeRouter) Swap(backend1, backend2 string, cnameOnly bool) error {
+	if !cnameOnly {
+		b1, clients1, err := r.loadBackend(backend1)
+		if err != nil {
+			return err
+		}
+		b2, clients2, err := r.loadBackend(backend2)
+		if err != nil {
+			return err
+		}
+		ns1, err := clients1[0].AppNamespace(b1.app)
+		if err != nil {
+			return err
+		}
+		ns2, err := clients2[0].AppNamespace(b2.app)
+		if err != nil {
+			return err
+		}
+		if ns1 != ns2 {
+			return errors.Errorf("swap is only allowed between apps in the same namespace. %q uses %q, %q uses %q",
+				backend1, ns1, backend2, ns2)
+		}
+		b1.target, b2.target = b2.target, b1.target
+		for _, client := range clients1 {
+			err = r.syncBackend(client, b1, b1.opts)
+			if err != nil {
+				return err
+			}
+		}
+		for _, client := range clients2 {
+			err = r.syncBackend(client, b2, b2.opts)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return router.Swap(r, backend1, backend2, cnameOnly)
+}
+
+func (r *ingressRouter) AddCertificate(routerApp router.App, cname, certificate, key string) error {
+	return r.updateBackend(routerApp.GetName(), func(b *routerBackend) error {
+		clients, err := clustersForApp(b.app)
+		if err != nil {
+			return err
+		}
+		for _, client := range clients {
+			err = r.saveCertificate(client, b.app, cname, certificate, key)
+			if err != nil {
+				return err
+			}
+		}
+		if !containsString(b.certs, cname) {
+			b.certs = append(b.certs, cname)
+		}
+		return nil
+	})
+}
+
+func (r *ingressRouter) saveCertificate(client *ClusterClient, a *app.App, cname, certificate, key string) error {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return err
+	}
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.secretName(a.Name, cname),
+			Namespace: ns,
+			Labels:    r.objectLabels(a.Name),
+		},
+		Type: apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       []byte(certificate),
+			apiv1.TLSPrivateKeyKey: []byte(key),
+		},
+	}
+	_, err = client.CoreV1().Secrets(ns).Update(secret)
+	if k8sErrors.IsNotFound(err) {
+		_, err = client.CoreV1().Secrets(ns).Create(secret)
+	}
+	return errors.WithStack(err)
+}
+
+func (r *ingressRouter) RemoveCertificate(routerApp router.App, cname string) error {
+	return r.updateBackend(routerApp.GetName(), func(b *routerBackend) error {
+		if !containsString(b.certs, cname) {
+			return router.ErrCertificateNotFound
+		}
+		clients, err := clustersForApp(b.app)
+		if err != nil {
+			return err
+		}
+		for _, client := range clients {
+			ns, err := client.AppNamespace(b.app)
+			if err != nil {
+				return err
+			}
+			err = client.CoreV1().Secrets(ns).Delete(r.secretName(b.app.Name, cname), &metav1.DeleteOptions{})
+			if err != nil && !k8sErrors.IsNotFound(err) {
+				return errors.WithStack(err)
+			}
+		}
+		b.certs = removeStrings(b.certs, cname)
+		return nil
+	})
+}
+
+func (r *ingressRouter) GetCertificate(routerApp router.App, cname string) (string, error) {
+	b, clients, err := r.loadBackend(routerApp.GetName())
+	if err != nil {
+		return "", err
+	}
+	if !containsString(b.certs, cname) {
+		return "", router.ErrCertificateNotFound
+	}
+	ns, err := clients[0].AppNamespace(b.app)
+	if err != nil {
+		return "", err
+	}
+	secret, err := clients[0].CoreV1().Secrets(ns).Get(r.secretName(b.app.Name, cname), metav1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", router.ErrCertificateNotFound
+		}
+		return "", errors.WithStack(err)
+	}
+	return string(secret.Data[apiv1.TLSCertKey]), nil
+}
+
+// loadBackend returns the state of the backend currently serving name, read
+// from the primary cluster of its app, and the clients for every cluster
+// where the backend must exist.
+func (r *kubeRouter) loadBackend(name string) (*routerBackend, []*ClusterClient, error) {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := app.GetByName(backendName)
+	if err != nil {
+		return nil, nil, err
+	}
+	clients, err := clustersForApp(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := r.getBackend(clients[0], a)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, clients, nil
+}
+
+func (r *kubeRouter) updateBackend(name string, fn func(b *routerBackend) error) error {
+	b, clients, err := r.loadBackend(name)
+	if err != nil {
+		return err
+	}
+	oldOpts := b.opts
+	err = fn(b)
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		err = r.syncBackend(client, b, oldOpts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *kubeRouter) getBackend(client *ClusterClient, a *app.App) (*routerBackend, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	var meta metav1.ObjectMeta
+	if r.loadBalancer {
+		svc, err := client.CoreV1().Services(ns).Get(r.objectName(a.Name), metav1.GetOptions{})
+		if err != nil {
+			return nil, backendError(err)
+		}
+		meta = svc.ObjectMeta
+	} else {
+		ingress, err := client.ExtensionsV1beta1().Ingresses(ns).Get(r.objectName(a.Name), metav1.GetOptions{})
+		if err != nil {
+			return nil, backendError(err)
+		}
+		meta = ingress.ObjectMeta
+	}
+	b := &routerBackend{
+		app:    a,
+		target: meta.Annotations[routerTargetAnnotation],
+		routes: splitAnnotation(meta.Annotations[routerRoutesAnnotation]),
+		cnames: splitAnnotation(meta.Annotations[routerCNamesAnnotation]),
+		certs:  splitAnnotation(meta.Annotations[routerCertsAnnotation]),
+	}
+	if b.target == "" {
+		b.target = a.Name
+	}
+	if rawOpts := meta.Annotations[routerOptsAnnotation]; rawOpts != "" {
+		err = json.Unmarshal([]byte(rawOpts), &b.opts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return b, nil
+}
+
+// syncBackend creates or updates the object serving the backend in the
+// cluster, removing the annotations set by oldOpts.
+func (r *kubeRouter) syncBackend(client *ClusterClient, b *routerBackend, oldOpts map[string]string) error {
+	ns, err := client.AppNamespace(b.app)
+	if err != nil {
+		return err
+	}
+	target := b.app
+	if b.target != b.app.Name {
+		target, err = app.GetByName(b.target)
+		if err != nil {
+			return err
+		}
+	}
+	svc, err := routerTargetService(client, target)
+	if err != nil {
+		return err
+	}
+	if r.loadBalancer {
+		return r.syncLoadBalancer(client, ns, b, svc, oldOpts)
+	}
+	return r.syncIngress(client, ns, b, svc, oldOpts)
+}
+
+func (r *kubeRouter) syncIngress(client *ClusterClient, ns string, b *routerBackend, svc *apiv1.Service, oldOpts map[string]string) error {
+	backend := extensions.IngressBackend{
+		ServiceName: svc.Name,
+		ServicePort: intstr.FromInt(int(svc.Spec.Ports[0].Port)),
+	}
+	var hosts []string
+	if r.domain != "" {
+		hosts = append(hosts, fmt.Sprintf("%s.%s", b.app.Name, r.domain))
+	}
+	hosts = append(hosts, b.cnames...)
+	var spec extensions.IngressSpec
+	if len(hosts) == 0 {
+		spec.Backend = &backend
+	}
+	for _, host := range hosts {
+		spec.Rules = append(spec.Rules, extensions.IngressRule{
+			Host: host,
+			IngressRuleValue: extensions.IngressRuleValue{
+				HTTP: &extensions.HTTPIngressRuleValue{
+					Paths: []extensions.HTTPIngressPath{{Backend: backend}},
+				},
+			},
+		})
+	}
+	for _, cname := range b.certs {
+		spec.TLS = append(spec.TLS, extensions.IngressTLS{
+			Hosts:      []string{cname},
+			SecretName: r.secretName(b.app.Name, cname),
+		})
+	}
+	ingresses := client.ExtensionsV1beta1().Ingresses(ns)
+	ingress, err := ingresses.Get(r.objectName(b.app.Name), metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	isNew := err != nil
+	if isNew {
+		ingress = &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      r.objectName(b.app.Name),
+				Namespace: ns,
+			},
+		}
+	}
+	ingress.Labels = r.objectLabels(b.app.Name)
+	ingress.Annotations, err = r.backendAnnotations(ingress.Annotations, b, oldOpts)
+	if err != nil {
+		return err
+	}
+	ingress.Spec = spec
+	if isNew {
+		_, err = ingresses.Create(ingress)
+	} else {
+		_, err = ingresses.Update(ingress)
+	}
+	return errors.WithStack(err)
+}
+
+// syncLoadBalancer exposes the ports of the web process service of the
+// target app, with the first one published as port 80.
+func (r *kubeRouter) syncLoadBalancer(client *ClusterClient, ns string, b *routerBackend, svc *apiv1.Service, oldOpts map[string]string) error {
+	services := client.CoreV1().Services(ns)
+	lbSvc, err := services.Get(r.objectName(b.app.Name), metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+	isNew := err != nil
+	if isNew {
+		lbSvc = &apiv1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      r.objectName(b.app.Name),
+				Namespace: ns,
+			},
+		}
+	}
+	nodePorts := map[string]int32{}
+	for _, port := range lbSvc.Spec.Ports {
+		nodePorts[port.Name] = port.NodePort
+	}
+	ports := make([]apiv1.ServicePort, len(svc.Spec.Ports))
+	for i, port := range svc.Spec.Ports {
+		ports[i] = apiv1.ServicePort{
+			Name:       port.Name,
+			Protocol:   port.Protocol,
+			Port:       port.Port,
+			TargetPort: port.TargetPort,
+			NodePort:   nodePorts[port.Name],
+		}
+	}
+	ports[0].Port = loadBalancerPort
+	lbSvc.Labels = r.objectLabels(b.app.Name)
+	lbSvc.Annotations, err = r.backendAnnotations(lbSvc.Annotations, b, oldOpts)
+	if err != nil {
+		return err
+	}
+	lbSvc.Spec.Type = apiv1.ServiceTypeLoadBalancer
+	lbSvc.Spec.Selector = svc.Spec.Selector
+	lbSvc.Spec.Ports = ports
+	if isNew {
+		_, err = services.Create(lbSvc)
+	} else {
+		_, err = services.Update(lbSvc)
+	}
+	return errors.WithStack(err)
+}
+
+// backendAnnotations returns the annotations for the object serving the
+// backend, keeping the ones added by others to current. The annotations in
+// the router config are applied after the backend opts, so that opts can't
+// override them.
+func (r *kubeRouter) backendAnnotations(current map[string]string, b *routerBackend, oldOpts map[string]string) (map[string]string, error) {
+	result := map[string]string{}
+	for k, v := range current {
+		result[k] = v
+	}
+	for k := range oldOpts {
+		delete(result, r.optAnnotation(k))
+	}
+	if !r.loadBalancer && r.ingressClass != "" {
+		result[ingressClassAnnotation] = r.ingressClass
+	}
+	for k, v := range b.opts {
+		if r.optAllowed(k) {
+			result[r.optAnnotation(k)] = v
+		}
+	}
+	for k, v := range r.annotations {
+		result[k] = v
+	}
+	delete(result, routerOptsAnnotation)
+	if len(b.opts) > 0 {
+		rawOpts, err := json.Marshal(b.opts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		result[routerOptsAnnotation] = string(rawOpts)
+	}
+	result[routerTargetAnnotation] = b.target
+	result[routerRoutesAnnotation] = strings.Join(b.routes, ",")
+	result[routerCNamesAnnotation] = strings.Join(b.cnames, ",")
+	result[routerCertsAnnotation] = strings.Join(b.certs, ",")
+	return result, nil
+}
+
+// optAnnotation returns the annotation set by a backend opt. The class opt
+// selects the ingress class, any other opt is set as is.
+func (r *kubeRouter) optAnnotation(opt string) string {
+	if !r.loadBalancer && opt == ingressClassOpt {
+		return ingressClassAnnotation
+	}
+	return opt
+}
+
+// routerTargetService returns the service of the web process of the app.
+// When the app has not been deployed yet, the service it will have once
+// deployed is returned instead.
+func routerTargetService(client *ClusterClient, a *app.App) (*apiv1.Service, error) {
+	ns, err := client.AppNamespace(a)
+	if err != nil {
+		return nil, err
+	}
+	process := "web"
+	imageName, err := image.AppCurrentImageName(a.Name)
+	if err != nil && err != image.ErrNoImagesAvailable {
+		return nil, err
+	}
+	if imageName != "" {
+		webProcessName, err := image.GetImageWebProcessName(imageName)
+		if err != nil {
+			return nil, err
+		}
+		if webProcessName != "" {
+			process = webProcessName
+		}
+	}
+	svc, err := client.CoreV1().Services(ns).Get(deploymentNameForApp(a, process), metav1.GetOptions{})
+	if err == nil && len(svc.Spec.Ports) > 0 {
+		return svc, nil
+	}
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, errors.WithStack(err)
+	}
+	ls, err := provision.ServiceLabels(provision.ServiceLabelsOpts{
+		App:     a,
+		Process: process,
+		ServiceLabelExtendedOpts: provision.ServiceLabelExtendedOpts{
+			Prefix:      tsuruLabelPrefix,
+			Provisioner: provisionerName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defaultPort := defaultKubernetesPodPortConfig()
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentNameForApp(a, process),
+			Namespace: ns,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: ls.ToSelector(),
+			Ports: []apiv1.ServicePort{{
+				Name:       defaultPort.Name,
+				Protocol:   apiv1.Protocol(defaultPort.Protocol),
+				Port:       int32(defaultPort.Port),
+				TargetPort: intstr.FromInt(defaultPort.TargetPort),
+			}},
+		},
+	}, nil
+}
+
+func backendError(err error) error {
+	if k8sErrors.IsNotFound(err) {
+		return router.ErrBackendNotFound
+	}
+	return errors.WithStack(err)
+}
+
+func splitAnnotation(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeStrings(values []string, toRemove ...string) []string {
+	var result []string
+	for _, v := range values {
+		if !containsString(toRemove, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,258 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"net/url"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/router"
+	check "gopkg.in/check.v1"
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func (s *S) newTestKubeRouter(c *check.C, routerType string) router.Router {
+	config.Set("routers:kube:type", routerType)
+	config.Set("routers:kube:domain", "kube.io")
+	config.Set("routers:kube:ingress-class", "nginx")
+	config.Set("routers:kube:annotations:team", "admin")
+	config.Set("routers:kube:allowed-opts", []interface{}{"class", "x/*", "y/retries", "team"})
+	r, err := router.Get("kube")
+	c.Assert(err, check.IsNil)
+	return r
+}
+
+func (s *S) TestIngressRouterAddBackend(c *check.C) {
+	defer config.Unset("routers:kube")
+	r := s.newTestKubeRouter(c, ingressRouterType)
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = r.(router.OptsRouter).AddBackendOpts(a, map[string]string{"class": "internal", "x/timeout": "10"})
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	ingress, err := s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Labels, check.DeepEquals, map[string]string{
+		"tsuru.io/router-app": "myapp",
+		"tsuru.io/router":     "kube",
+	})
+	c.Assert(ingress.Annotations["kubernetes.io/ingress.class"], check.Equals, "internal")
+	c.Assert(ingress.Annotations["x/timeout"], check.Equals, "10")
+	c.Assert(ingress.Annotations["team"], check.Equals, "admin")
+	c.Assert(ingress.Annotations["tsuru.io/router-target-app"], check.Equals, "myapp")
+	c.Assert(ingress.Spec, check.DeepEquals, extensions.IngressSpec{
+		Rules: []extensions.IngressRule{{
+			Host: "myapp.kube.io",
+			IngressRuleValue: extensions.IngressRuleValue{
+				HTTP: &extensions.HTTPIngressRuleValue{
+					Paths: []extensions.HTTPIngressPath{{
+						Backend: extensions.IngressBackend{
+							ServiceName: "myapp-web",
+							ServicePort: intstr.FromInt(8888),
+						},
+					}},
+				},
+			},
+		}},
+	})
+	addr, err := r.Addr("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(addr, check.Equals, "myapp.kube.io")
+	err = r.AddBackend(a)
+	c.Assert(err, check.Equals, router.ErrBackendExists)
+	err = r.(router.OptsRouter).UpdateBackendOpts(a, map[string]string{"y/retries": "2"})
+	c.Assert(err, check.IsNil)
+	ingress, err = s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Annotations["kubernetes.io/ingress.class"], check.Equals, "nginx")
+	c.Assert(ingress.Annotations["y/retries"], check.Equals, "2")
+	_, ok := ingress.Annotations["x/timeout"]
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *S) TestIngressRouterOptsNotAllowed(c *check.C) {
+	defer config.Unset("routers:kube")
+	r := s.newTestKubeRouter(c, ingressRouterType)
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = r.(router.OptsRouter).AddBackendOpts(a, map[string]string{"z/snippet": "evil"})
+	c.Assert(err, check.ErrorMatches, `opt "z/snippet" is not allowed by router "kube"`)
+	err = r.(router.OptsRouter).AddBackendOpts(a, map[string]string{"team": "mine"})
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	ingress, err := s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Annotations["team"], check.Equals, "admin")
+	err = r.(router.OptsRouter).UpdateBackendOpts(a, map[string]string{"y/other": "1"})
+	c.Assert(err, check.ErrorMatches, `opt "y/other" is not allowed by router "kube"`)
+}
+
+func (s *S) TestIngressRouterRoutes(c *check.C) {
+	defer config.Unset("routers:kube")
+	r := s.newTestKubeRouter(c, ingressRouterType)
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = r.AddBackend(a)
+	c.Assert(err, check.IsNil)
+	addr1, _ := url.Parse("http://10.0.0.1:30000")
+	addr2, _ := url.Parse("http://10.0.0.2:30000")
+	err = r.AddRoutes("myapp", []*url.URL{addr1, addr2})
+	c.Assert(err, check.IsNil)
+	routes, err := r.Routes("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(routes, check.DeepEquals, []*url.URL{addr1, addr2})
+	err = r.RemoveRoutes("myapp", []*url.URL{addr1})
+	c.Assert(err, check.IsNil)
+	routes, err = r.Routes("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(routes, check.DeepEquals, []*url.URL{addr2})
+}
+
+func (s *S) TestIngressRouterCNamesAndCertificates(c *check.C) {
+	defer config.Unset("routers:kube")
+	r := s.newTestKubeRouter(c, ingressRouterType)
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = r.AddBackend(a)
+	c.Assert(err, check.IsNil)
+	cnameRouter := r.(router.CNameRouter)
+	err = cnameRouter.SetCName("www.myapp.com", "myapp")
+	c.Assert(err, check.IsNil)
+	err = cnameRouter.SetCName("www.myapp.com", "myapp")
+	c.Assert(err, check.Equals, router.ErrCNameExists)
+	err = cnameRouter.SetCName("other.kube.io", "myapp")
+	c.Assert(err, check.Equals, router.ErrCNameNotAllowed)
+	cnames, err := cnameRouter.CNames("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(cnames, check.DeepEquals, []*url.URL{{Host: "www.myapp.com"}})
+	tlsRouter := r.(router.TLSRouter)
+	err = tlsRouter.AddCertificate(a, "www.myapp.com", "mycert", "mykey")
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	secret, err := s.client.CoreV1().Secrets(ns).Get("myapp-router-kube-www.myapp.com", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(secret.Type, check.Equals, apiv1.SecretTypeTLS)
+	c.Assert(string(secret.Data["tls.key"]), check.Equals, "mykey")
+	ingress, err := s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Spec.Rules, check.HasLen, 2)
+	c.Assert(ingress.Spec.Rules[1].Host, check.Equals, "www.myapp.com")
+	c.Assert(ingress.Spec.TLS, check.DeepEquals, []extensions.IngressTLS{
+		{Hosts: []string{"www.myapp.com"}, SecretName: "myapp-router-kube-www.myapp.com"},
+	})
+	cert, err := tlsRouter.GetCertificate(a, "www.myapp.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(cert, check.Equals, "mycert")
+	err = tlsRouter.RemoveCertificate(a, "www.myapp.com")
+	c.Assert(err, check.IsNil)
+	_, err = tlsRouter.GetCertificate(a, "www.myapp.com")
+	c.Assert(err, check.Equals, router.ErrCertificateNotFound)
+	err = cnameRouter.UnsetCName("www.myapp.com", "myapp")
+	c.Assert(err, check.IsNil)
+	err = cnameRouter.UnsetCName("www.myapp.com", "myapp")
+	c.Assert(err, check.Equals, router.ErrCNameNotFound)
+	ingress, err = s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Spec.Rules, check.HasLen, 1)
+	c.Assert(ingress.Spec.TLS, check.HasLen, 0)
+}
+
+func (s *S) TestIngressRouterSwap(c *check.C) {
+	defer config.Unset("routers:kube")
+	r := s.newTestKubeRouter(c, ingressRouterType)
+	a1 := &app.App{Name: "myapp1", TeamOwner: s.team.Name}
+	err := app.CreateApp(a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := &app.App{Name: "myapp2", TeamOwner: s.team.Name}
+	err = app.CreateApp(a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = r.AddBackend(a1)
+	c.Assert(err, check.IsNil)
+	err = r.AddBackend(a2)
+	c.Assert(err, check.IsNil)
+	err = r.Swap("myapp1", "myapp2", false)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a1)
+	c.Assert(err, check.IsNil)
+	ingress, err := s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp1-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Spec.Rules[0].Host, check.Equals, "myapp1.kube.io")
+	c.Assert(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName, check.Equals, "myapp2-web")
+	addr, err := r.Addr("myapp1")
+	c.Assert(err, check.IsNil)
+	c.Assert(addr, check.Equals, "myapp2.kube.io")
+	err = r.RemoveBackend("myapp1")
+	c.Assert(err, check.Equals, router.ErrBackendSwapped)
+	err = r.Swap("myapp1", "myapp2", false)
+	c.Assert(err, check.IsNil)
+	ingress, err = s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp1-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName, check.Equals, "myapp1-web")
+	err = r.RemoveBackend("myapp1")
+	c.Assert(err, check.IsNil)
+	_, err = s.client.ExtensionsV1beta1().Ingresses(ns).Get("myapp1-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestLoadBalancerRouter(c *check.C) {
+	defer config.Unset("routers:kube")
+	r := s.newTestKubeRouter(c, loadBalancerRouterType)
+	_, isTLS := r.(router.TLSRouter)
+	c.Assert(isTLS, check.Equals, false)
+	a := &app.App{Name: "myapp", TeamOwner: s.team.Name}
+	err := app.CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	err = r.AddBackend(a)
+	c.Assert(err, check.IsNil)
+	ns, err := s.client.AppNamespace(a)
+	c.Assert(err, check.IsNil)
+	svc, err := s.client.CoreV1().Services(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(svc.Spec.Type, check.Equals, apiv1.ServiceTypeLoadBalancer)
+	c.Assert(svc.Spec.Selector["tsuru.io/app-name"], check.Equals, "myapp")
+	c.Assert(svc.Spec.Selector["tsuru.io/app-process"], check.Equals, "web")
+	c.Assert(svc.Spec.Ports, check.DeepEquals, []apiv1.ServicePort{{
+		Name:       "http-default",
+		Protocol:   apiv1.ProtocolTCP,
+		Port:       80,
+		TargetPort: intstr.FromInt(8888),
+	}})
+	c.Assert(svc.Annotations["team"], check.Equals, "admin")
+	_, ok := svc.Annotations["kubernetes.io/ingress.class"]
+	c.Assert(ok, check.Equals, false)
+	status, detail, err := r.(router.StatusRouter).GetBackendStatus("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, router.BackendStatusNotReady)
+	c.Assert(detail, check.Equals, "waiting for load balancer address")
+	svc.Status.LoadBalancer.Ingress = []apiv1.LoadBalancerIngress{{IP: "192.168.10.1"}}
+	_, err = s.client.CoreV1().Services(ns).UpdateStatus(svc)
+	c.Assert(err, check.IsNil)
+	status, _, err = r.(router.StatusRouter).GetBackendStatus("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, router.BackendStatusReady)
+	addr, err := r.Addr("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(addr, check.Equals, "192.168.10.1")
+	err = r.(router.CNameRouter).SetCName("www.myapp.com", "myapp")
+	c.Assert(err, check.IsNil)
+	svc, err = s.client.CoreV1().Services(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(svc.Annotations["tsuru.io/router-cnames"], check.Equals, "www.myapp.com")
+	err = r.RemoveBackend("myapp")
+	c.Assert(err, check.IsNil)
+	_, err = s.client.CoreV1().Services(ns).Get("myapp-router-kube", metav1.GetOptions{})
+	c.Assert(err, check.NotNil)
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/provision"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	antiAffinityPreferred = "preferred"
+	antiAffinityRequired  = "required"
+
+	hostnameTopologyKey = "kubernetes.io/hostname"
+	spreadWeight        = 100
+)
+
+// parseTolerations parses a comma separated list of tolerations in the
+// format <key>[=<value>][:<effect>]. Tolerations without a value match any
+// value of the taint key.
+func parseTolerations(raw string) ([]apiv1.Toleration, error) {
+	var tolerations []apiv1.Toleration
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var toleration apiv1.Toleration
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			toleration.Effect = apiv1.TaintEffect(part[i+1:])
+			part = part[:i]
+			switch toleration.Effect {
+			case apiv1.TaintEffectNoSchedule, apiv1.TaintEffectPreferNoSchedule, apiv1.TaintEffectNoExecute:
+			default:
+				return nil, errors.Errorf("invalid toleration effect %q", toleration.Effect)
+			}
+		}
+		toleration.Key = part
+		toleration.Operator = apiv1.TolerationOpExists
+		if i := strings.Index(part, "="); i >= 0 {
+			toleration.Key, toleration.Value = part[:i], part[i+1:]
+			toleration.Operator = apiv1.TolerationOpEqual
+		}
+		if toleration.Key == "" {
+			return nil, errors.Errorf("invalid toleration %q, key is required", part)
+		}
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations, nil
+}
+
+// schedulingForApp returns the affinity and tolerations of the units of an
+// app process, from the app annotations or the pool config in the cluster.
+// Spreading across a topology is done with preferred anti-affinity, as
+// topology spread constraints aren't available in the supported kubernetes
+// versions.
+func schedulingForApp(client *ClusterClient, a provision.App, selector map[string]string) (*apiv1.Affinity, []apiv1.Toleration, error) {
+	pool := a.GetPool()
+	metadata := a.GetMetadata()
+	antiAffinity := client.AntiAffinity(pool)
+	if v, ok := metadata.Annotation(tsuruLabelPrefix + antiAffinityKey); ok {
+		antiAffinity = v
+	}
+	topologyKey := client.SpreadTopologyKey(pool)
+	if v, ok := metadata.Annotation(tsuruLabelPrefix + spreadTopologyKey); ok {
+		topologyKey = v
+	}
+	tolerations, err := client.Tolerations(pool)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "misconfigured cluster tolerations")
+	}
+	if v, ok := metadata.Annotation(tsuruLabelPrefix + tolerationsKey); ok {
+		appTolerations, err := parseTolerations(v)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "invalid app tolerations")
+		}
+		tolerations = append(tolerations, appTolerations...)
+	}
+	labelSelector := &metav1.LabelSelector{MatchLabels: selector}
+	podAntiAffinity := &apiv1.PodAntiAffinity{}
+	switch antiAffinity {
+	case "":
+	case antiAffinityPreferred:
+		podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, apiv1.WeightedPodAffinityTerm{
+			Weight: spreadWeight,
+			PodAffinityTerm: apiv1.PodAffinityTerm{
+				LabelSelector: labelSelector,
+				TopologyKey:   hostnameTopologyKey,
+			},
+		})
+	case antiAffinityRequired:
+		podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, apiv1.PodAffinityTerm{
+			LabelSelector: labelSelector,
+			TopologyKey:   hostnameTopologyKey,
+		})
+	default:
+		return nil, nil, errors.Errorf("invalid anti-affinity %q, must be %q or %q", antiAffinity, antiAffinityPreferred, antiAffinityRequired)
+	}
+	if topologyKey != "" {
+		podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, apiv1.WeightedPodAffinityTerm{
+			Weight: spreadWeight,
+			PodAffinityTerm: apiv1.PodAffinityTerm{
+				LabelSelector: labelSelector,
+				TopologyKey:   topologyKey,
+			},
+		})
+	}
+	if len(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) == 0 && len(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) == 0 {
+		return nil, tolerations, nil
+	}
+	return &apiv1.Affinity{PodAntiAffinity: podAntiAffinity}, tolerations, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (s *S) TestParseTolerations(c *check.C) {
+	tolerations, err := parseTolerations("dedicated=apps:NoSchedule, gpu , spot:PreferNoSchedule,zone=a")
+	c.Assert(err, check.IsNil)
+	c.Assert(tolerations, check.DeepEquals, []apiv1.Toleration{
+		{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "apps", Effect: apiv1.TaintEffectNoSchedule},
+		{Key: "gpu", Operator: apiv1.TolerationOpExists},
+		{Key: "spot", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectPreferNoSchedule},
+		{Key: "zone", Operator: apiv1.TolerationOpEqual, Value: "a"},
+	})
+	tolerations, err = parseTolerations("")
+	c.Assert(err, check.IsNil)
+	c.Assert(tolerations, check.IsNil)
+	_, err = parseTolerations("gpu:Never")
+	c.Assert(err, check.ErrorMatches, `invalid toleration effect "Never"`)
+	_, err = parseTolerations("=a:NoSchedule")
+	c.Assert(err, check.ErrorMatches, `invalid toleration "=a", key is required`)
+}
+
+func (s *S) TestSchedulingForApp(c *check.C) {
+	selector := map[string]string{"tsuru.io/app-name": "myapp", "tsuru.io/app-process": "web"}
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	affinity, tolerations, err := schedulingForApp(s.clusterClient, a, selector)
+	c.Assert(err, check.IsNil)
+	c.Assert(affinity, check.IsNil)
+	c.Assert(tolerations, check.IsNil)
+	s.clusterClient.CustomData[antiAffinityKey] = "preferred"
+	s.clusterClient.CustomData[a.GetPool()+":"+spreadTopologyKey] = "failure-domain.beta.kubernetes.io/zone"
+	s.clusterClient.CustomData[tolerationsKey] = "dedicated=apps:NoSchedule"
+	affinity, tolerations, err = schedulingForApp(s.clusterClient, a, selector)
+	c.Assert(err, check.IsNil)
+	labelSelector := &metav1.LabelSelector{MatchLabels: selector}
+	c.Assert(affinity, check.DeepEquals, &apiv1.Affinity{
+		PodAntiAffinity: &apiv1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []apiv1.WeightedPodAffinityTerm{
+				{Weight: 100, PodAffinityTerm: apiv1.PodAffinityTerm{LabelSelector: labelSelector, TopologyKey: "kubernetes.io/hostname"}},
+				{Weight: 100, PodAffinityTerm: apiv1.PodAffinityTerm{LabelSelector: labelSelector, TopologyKey: "failure-domain.beta.kubernetes.io/zone"}},
+			},
+		},
+	})
+	c.Assert(tolerations, check.DeepEquals, []apiv1.Toleration{
+		{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "apps", Effect: apiv1.TaintEffectNoSchedule},
+	})
+	a.Metadata.Annotations = []appTypes.MetadataItem{
+		{Name: "tsuru.io/anti-affinity", Value: "required"},
+		{Name: "tsuru.io/spread-topology-key", Value: ""},
+		{Name: "tsuru.io/tolerations", Value: "gpu:NoExecute"},
+	}
+	affinity, tolerations, err = schedulingForApp(s.clusterClient, a, selector)
+	c.Assert(err, check.IsNil)
+	c.Assert(affinity, check.DeepEquals, &apiv1.Affinity{
+		PodAntiAffinity: &apiv1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []apiv1.PodAffinityTerm{
+				{LabelSelector: labelSelector, TopologyKey: "kubernetes.io/hostname"},
+			},
+		},
+	})
+	c.Assert(tolerations, check.DeepEquals, []apiv1.Toleration{
+		{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "apps", Effect: apiv1.TaintEffectNoSchedule},
+		{Key: "gpu", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoExecute},
+	})
+	a.Metadata.Annotations = []appTypes.MetadataItem{{Name: "tsuru.io/anti-affinity", Value: "always"}}
+	_, _, err = schedulingForApp(s.clusterClient, a, selector)
+	c.Assert(err, check.ErrorMatches, `invalid anti-affinity "always", must be "preferred" or "required"`)
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/pool"
+	provTypes "github.com/tsuru/tsuru/types/provision"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func sharedVolumeName(name string) string {
+	return "shared-" + name
+}
+
+// validateProcessContainers checks the shared volumes, sidecars and init
+// containers of a process, including whether their images are allowed in
+// the app pool.
+func validateProcessContainers(a provision.App, depName string, conf provTypes.TsuruYamlProcessConfig) error {
+	volumes := map[string]struct{}{}
+	for _, v := range conf.SharedVolumes {
+		if errs := validation.IsDNS1123Label(v.Name); len(errs) > 0 {
+			return errors.Errorf("invalid shared volume name %q: %s", v.Name, strings.Join(errs, ", "))
+		}
+		if _, ok := volumes[v.Name]; ok {
+			return errors.Errorf("duplicated shared volume %q", v.Name)
+		}
+		volumes[v.Name] = struct{}{}
+	}
+	names := map[string]struct{}{depName: {}}
+	var images []string
+	for _, cont := range append(append([]provTypes.TsuruYamlContainer{}, conf.Sidecars...), conf.InitContainers...) {
+		if errs := validation.IsDNS1123Label(cont.Name); len(errs) > 0 {
+			return errors.Errorf("invalid container name %q: %s", cont.Name, strings.Join(errs, ", "))
+		}
+		if _, ok := names[cont.Name]; ok {
+			return errors.Errorf("duplicated container name %q", cont.Name)
+		}
+		names[cont.Name] = struct{}{}
+		if cont.Image == "" {
+			return errors.Errorf("image is required for container %q", cont.Name)
+		}
+		for _, m := range cont.Mounts {
+			if _, ok := volumes[m.Volume]; !ok {
+				return errors.Errorf("container %q mounts unknown shared volume %q", cont.Name, m.Volume)
+			}
+		}
+		images = append(images, cont.Image)
+	}
+	if len(images) == 0 {
+		return nil
+	}
+	p, err := pool.GetPoolByName(a.GetPool())
+	if err != nil {
+		return err
+	}
+	return p.ValidateSidecarImages(images...)
+}
+
+func containerFromYaml(cont provTypes.TsuruYamlContainer) apiv1.Container {
+	container := apiv1.Container{
+		Name:    cont.Name,
+		Image:   cont.Image,
+		Command: cont.Command,
+	}
+	for name, value := range cont.Env {
+		container.Env = append(container.Env, apiv1.EnvVar{Name: name, Value: value})
+	}
+	sort.Slice(container.Env, func(i, j int) bool {
+		return container.Env[i].Name < container.Env[j].Name
+	})
+	for _, port := range cont.Ports {
+		container.Ports = append(container.Ports, apiv1.ContainerPort{ContainerPort: int32(port)})
+	}
+	for _, m := range cont.Mounts {
+		container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+			Name:      sharedVolumeName(m.Volume),
+			MountPath: m.Path,
+			ReadOnly:  m.ReadOnly,
+		})
+	}
+	return container
+}
+
+type processContainersData struct {
+	sidecars       []apiv1.Container
+	initContainers []apiv1.Container
+	volumes        []apiv1.Volume
+	mounts         []apiv1.VolumeMount
+	images         []string
+}
+
+// processContainers returns the additional containers of the units of a
+// process, along with the shared volumes and their mounts in the process
+// container.
+func processContainers(conf provTypes.TsuruYamlProcessConfig) processContainersData {
+	var data processContainersData
+	for _, v := range conf.SharedVolumes {
+		data.volumes = append(data.volumes, apiv1.Volume{
+			Name: sharedVolumeName(v.Name),
+			VolumeSource: apiv1.VolumeSource{
+				EmptyDir: &apiv1.EmptyDirVolumeSource{},
+			},
+		})
+		if v.Path != "" {
+			data.mounts = append(data.mounts, apiv1.VolumeMount{
+				Name:      sharedVolumeName(v.Name),
+				MountPath: v.Path,
+			})
+		}
+	}
+	for _, cont := range conf.Sidecars {
+		data.sidecars = append(data.sidecars, containerFromYaml(cont))
+		data.images = append(data.images, cont.Image)
+	}
+	for _, cont := range conf.InitContainers {
+		data.initContainers = append(data.initContainers, containerFromYaml(cont))
+		data.images = append(data.images, cont.Image)
+	}
+	return data
+}
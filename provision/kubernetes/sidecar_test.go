@@ -0,0 +1,120 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"github.com/tsuru/tsuru/provision/pool"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	provTypes "github.com/tsuru/tsuru/types/provision"
+	check "gopkg.in/check.v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func (s *S) TestProcessContainers(c *check.C) {
+	data := processContainers(provTypes.TsuruYamlProcessConfig{
+		SharedVolumes: []provTypes.TsuruYamlSharedVolume{
+			{Name: "logs", Path: "/var/log/app"},
+			{Name: "scratch"},
+		},
+		Sidecars: []provTypes.TsuruYamlContainer{{
+			Name:    "shipper",
+			Image:   "fluent/fluent-bit:1.0",
+			Command: []string{"fluent-bit", "-c", "/etc/fluent.conf"},
+			Env:     map[string]string{"B": "2", "A": "1"},
+			Ports:   []int{2020},
+			Mounts:  []provTypes.TsuruYamlContainerMount{{Volume: "logs", Path: "/logs", ReadOnly: true}},
+		}},
+		InitContainers: []provTypes.TsuruYamlContainer{{
+			Name:   "setup",
+			Image:  "busybox",
+			Mounts: []provTypes.TsuruYamlContainerMount{{Volume: "scratch", Path: "/scratch"}},
+		}},
+	})
+	c.Assert(data.sidecars, check.DeepEquals, []apiv1.Container{{
+		Name:    "shipper",
+		Image:   "fluent/fluent-bit:1.0",
+		Command: []string{"fluent-bit", "-c", "/etc/fluent.conf"},
+		Env:     []apiv1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+		Ports:   []apiv1.ContainerPort{{ContainerPort: 2020}},
+		VolumeMounts: []apiv1.VolumeMount{
+			{Name: "shared-logs", MountPath: "/logs", ReadOnly: true},
+		},
+	}})
+	c.Assert(data.initContainers, check.DeepEquals, []apiv1.Container{{
+		Name:  "setup",
+		Image: "busybox",
+		VolumeMounts: []apiv1.VolumeMount{
+			{Name: "shared-scratch", MountPath: "/scratch"},
+		},
+	}})
+	c.Assert(data.volumes, check.DeepEquals, []apiv1.Volume{
+		{Name: "shared-logs", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}},
+		{Name: "shared-scratch", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}},
+	})
+	c.Assert(data.mounts, check.DeepEquals, []apiv1.VolumeMount{
+		{Name: "shared-logs", MountPath: "/var/log/app"},
+	})
+	c.Assert(data.images, check.DeepEquals, []string{"fluent/fluent-bit:1.0", "busybox"})
+}
+
+func (s *S) TestValidateProcessContainers(c *check.C) {
+	a := provisiontest.NewFakeApp("myapp", "python", 0)
+	err := validateProcessContainers(a, "myapp-web", provTypes.TsuruYamlProcessConfig{})
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		conf provTypes.TsuruYamlProcessConfig
+		err  string
+	}{
+		{
+			conf: provTypes.TsuruYamlProcessConfig{SharedVolumes: []provTypes.TsuruYamlSharedVolume{{Name: "Logs"}}},
+			err:  `invalid shared volume name "Logs": .*`,
+		},
+		{
+			conf: provTypes.TsuruYamlProcessConfig{SharedVolumes: []provTypes.TsuruYamlSharedVolume{{Name: "logs"}, {Name: "logs"}}},
+			err:  `duplicated shared volume "logs"`,
+		},
+		{
+			conf: provTypes.TsuruYamlProcessConfig{Sidecars: []provTypes.TsuruYamlContainer{{Name: "my_sidecar", Image: "busybox"}}},
+			err:  `invalid container name "my_sidecar": .*`,
+		},
+		{
+			conf: provTypes.TsuruYamlProcessConfig{Sidecars: []provTypes.TsuruYamlContainer{{Name: "myapp-web", Image: "busybox"}}},
+			err:  `duplicated container name "myapp-web"`,
+		},
+		{
+			conf: provTypes.TsuruYamlProcessConfig{
+				Sidecars:       []provTypes.TsuruYamlContainer{{Name: "setup", Image: "busybox"}},
+				InitContainers: []provTypes.TsuruYamlContainer{{Name: "setup", Image: "busybox"}},
+			},
+			err: `duplicated container name "setup"`,
+		},
+		{
+			conf: provTypes.TsuruYamlProcessConfig{InitContainers: []provTypes.TsuruYamlContainer{{Name: "setup"}}},
+			err:  `image is required for container "setup"`,
+		},
+		{
+			conf: provTypes.TsuruYamlProcessConfig{Sidecars: []provTypes.TsuruYamlContainer{{
+				Name:   "shipper",
+				Image:  "busybox",
+				Mounts: []provTypes.TsuruYamlContainerMount{{Volume: "logs", Path: "/logs"}},
+			}}},
+			err: `container "shipper" mounts unknown shared volume "logs"`,
+		},
+	}
+	for _, tt := range tests {
+		err = validateProcessContainers(a, "myapp-web", tt.conf)
+		c.Check(err, check.ErrorMatches, tt.err)
+	}
+	err = pool.SetPoolConstraint(&pool.PoolConstraint{PoolExpr: "test-default", Field: pool.ConstraintTypeSidecarImage, Values: []string{"fluent/*"}})
+	c.Assert(err, check.IsNil)
+	err = validateProcessContainers(a, "myapp-web", provTypes.TsuruYamlProcessConfig{
+		Sidecars: []provTypes.TsuruYamlContainer{{Name: "shipper", Image: "fluent/fluent-bit"}},
+	})
+	c.Assert(err, check.IsNil)
+	err = validateProcessContainers(a, "myapp-web", provTypes.TsuruYamlProcessConfig{
+		InitContainers: []provTypes.TsuruYamlContainer{{Name: "setup", Image: "busybox"}},
+	})
+	c.Assert(err, check.ErrorMatches, `sidecar image "busybox" is not allowed on pool "test-default"`)
+}
@@ -29,6 +29,7 @@ var (
 	labelAppProcessReplicas = "app-process-replicas"
 	LabelAppPool            = "app-pool"
 	labelAppPlatform        = "app-platform"
+	labelAppJob             = "app-job"
 
 	labelNodeContainerName = "node-container-name"
 	labelNodeContainerPool = "node-container-pool"
@@ -105,6 +106,10 @@ func (s *LabelSet) AppPlatform() string {
 	return s.getLabel(labelAppPlatform)
 }
 
+func (s *LabelSet) AppJob() string {
+	return s.getLabel(labelAppJob)
+}
+
 func (s *LabelSet) AppPool() string {
 	return s.getLabel(LabelAppPool)
 }
@@ -232,6 +237,14 @@ func (s *LabelSet) SetIsHeadlessService() {
 	s.addLabel(labelIsHeadlessService, strconv.FormatBool(true))
 }
 
+func (s *LabelSet) SetAppName(name string) {
+	s.addLabel(labelAppName, name)
+}
+
+func (s *LabelSet) SetAppJob(name string) {
+	s.addLabel(labelAppJob, name)
+}
+
 func (s *LabelSet) SetBuildImage(image string) {
 	s.addLabel(labelBuildImage, image)
 }
@@ -18,7 +18,7 @@ import (
 
 var (
 	ErrInvalidConstraintType = errors.Errorf("invalid constraint type. Valid types are: %s", validConstraintTypes)
-	validConstraintTypes     = []poolConstraintType{ConstraintTypeTeam, ConstraintTypeService, ConstraintTypeRouter, ConstraintTypePlan}
+	validConstraintTypes     = []poolConstraintType{ConstraintTypeTeam, ConstraintTypeService, ConstraintTypeRouter, ConstraintTypePlan, ConstraintTypeSidecarImage}
 )
 
 type poolConstraintType string
@@ -28,6 +28,11 @@ const (
 	ConstraintTypeRouter  = poolConstraintType("router")
 	ConstraintTypeService = poolConstraintType("service")
 	ConstraintTypePlan    = poolConstraintType("plan")
+
+	// ConstraintTypeSidecarImage lists the images allowed in sidecars and
+	// init containers of apps. No image is allowed unless the constraint
+	// is set.
+	ConstraintTypeSidecarImage = poolConstraintType("sidecar-image")
 )
 
 type regexpCache struct {
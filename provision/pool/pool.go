@@ -157,6 +157,23 @@ func (p *Pool) ValidateRouters(routers []appTypes.AppRouter) error {
 	return nil
 }
 
+// ValidateSidecarImages checks whether the images may be used in the
+// sidecars and init containers of apps in the pool.
+func (p *Pool) ValidateSidecarImages(images ...string) error {
+	constraints, err := getConstraintsForPool(p.Name, ConstraintTypeSidecarImage)
+	if err != nil {
+		return err
+	}
+	constraint := constraints[ConstraintTypeSidecarImage]
+	for _, img := range images {
+		if !constraint.check(img) {
+			msg := fmt.Sprintf("sidecar image %q is not allowed on pool %q", img, p.Name)
+			return &tsuruErrors.ValidationError{Message: msg}
+		}
+	}
+	return nil
+}
+
 func (p *Pool) allowedValues() (map[poolConstraintType][]string, error) {
 	teams, err := teamsNames()
 	if err != nil {
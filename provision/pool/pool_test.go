@@ -20,6 +20,7 @@ import (
 	_ "github.com/tsuru/tsuru/storage/mongodb"
 	appTypes "github.com/tsuru/tsuru/types/app"
 	authTypes "github.com/tsuru/tsuru/types/auth"
+	routerTypes "github.com/tsuru/tsuru/types/router"
 	check "gopkg.in/check.v1"
 )
 
@@ -98,6 +99,20 @@ func (s *S) TestValidateRouters(c *check.C) {
 	c.Assert(err, check.NotNil)
 }
 
+func (s *S) TestValidateSidecarImages(c *check.C) {
+	pool := Pool{Name: "pool1"}
+	err := pool.ValidateSidecarImages("busybox")
+	c.Assert(err, check.ErrorMatches, `sidecar image "busybox" is not allowed on pool "pool1"`)
+	err = SetPoolConstraint(&PoolConstraint{PoolExpr: "pool*", Field: ConstraintTypeSidecarImage, Values: []string{"fluent/*", "busybox"}})
+	c.Assert(err, check.IsNil)
+	err = pool.ValidateSidecarImages("busybox", "fluent/fluent-bit:1.0")
+	c.Assert(err, check.IsNil)
+	err = pool.ValidateSidecarImages("fluent/fluentd", "nginx")
+	c.Assert(err, check.ErrorMatches, `sidecar image "nginx" is not allowed on pool "pool1"`)
+	_, isValidation := err.(*tsuruErrors.ValidationError)
+	c.Assert(isValidation, check.Equals, true)
+}
+
 func (s *S) TestAddPool(c *check.C) {
 	msg := "Invalid pool name, pool name should have at most 40 " +
 		"characters, containing only lower case letters, numbers or dashes, " +
@@ -527,6 +542,30 @@ func (s *S) TestGetRouters(c *check.C) {
 	c.Assert(routers, check.DeepEquals, []string{"router1", "router2"})
 }
 
+func (s *S) TestGetRoutersWithDynamicRouters(c *check.C) {
+	config.Set("routers:router1:type", "hipache")
+	defer config.Unset("routers")
+	servicemanager.DynamicRouter = &routerTypes.MockDynamicRouterService{
+		OnList: func() ([]routerTypes.DynamicRouter, error) {
+			return []routerTypes.DynamicRouter{{Name: "dyn1", Type: "api"}, {Name: "dyn2", Type: "api"}}, nil
+		},
+	}
+	defer func() { servicemanager.DynamicRouter = nil }()
+	err := AddPool(AddPoolOptions{Name: "pool1"})
+	c.Assert(err, check.IsNil)
+	err = SetPoolConstraint(&PoolConstraint{PoolExpr: "pool*", Field: ConstraintTypeRouter, Values: []string{"dyn*"}})
+	c.Assert(err, check.IsNil)
+	pool, err := GetPoolByName("pool1")
+	c.Assert(err, check.IsNil)
+	routers, err := pool.GetRouters()
+	c.Assert(err, check.IsNil)
+	c.Assert(routers, check.DeepEquals, []string{"dyn1", "dyn2"})
+	err = pool.ValidateRouters([]appTypes.AppRouter{{Name: "dyn2"}})
+	c.Assert(err, check.IsNil)
+	err = pool.ValidateRouters([]appTypes.AppRouter{{Name: "router1"}})
+	c.Assert(err, check.ErrorMatches, `router "router1" is not available for pool "pool1".*`)
+}
+
 func (s *S) TestGetPlans(c *check.C) {
 	err := AddPool(AddPoolOptions{Name: "pool1"})
 	c.Assert(err, check.IsNil)
@@ -65,6 +65,17 @@ func (e ProvisionerNotSupported) Error() string {
 	return fmt.Sprintf("provisioner %q does not support %s", e.Prov.GetName(), e.Action)
 }
 
+// ExecExitError is returned by ExecuteCommand when the command finished with
+// a non-zero exit code known by the provisioner.
+type ExecExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExecExitError) Error() string {
+	return e.Err.Error()
+}
+
 // Status represents the status of a unit in tsuru.
 type Status string
 
@@ -228,6 +239,19 @@ type App interface {
 	GetSwap() int64
 	GetCpuShare() int
 
+	// GetProcessResources returns the resources of each unit of a process,
+	// which may differ from the app plan.
+	GetProcessResources(process string) appTypes.ProcessResources
+
+	// AutoScaleInfo returns the autoscale specs of the processes of the app.
+	AutoScaleInfo() []appTypes.AutoScaleSpec
+
+	// GetJobs returns the scheduled jobs of the app.
+	GetJobs() []appTypes.Job
+
+	// GetMetadata returns the labels and annotations of the app.
+	GetMetadata() appTypes.Metadata
+
 	GetUpdatePlatform() bool
 
 	GetRouters() []appTypes.AppRouter
@@ -245,6 +269,21 @@ type RollbackableDeployer interface {
 	Rollback(App, string, *event.Event) (string, error)
 }
 
+// StrategyDeployer is a provisioner that is able to start units for a new
+// image side by side with the units already running, allowing the traffic to
+// be shifted gradually between them.
+type StrategyDeployer interface {
+	// DeployCandidate starts units running the given image without removing
+	// the current units or routing to the new ones. It returns the name of
+	// the image that will be deployed and the units that were started.
+	DeployCandidate(App, string, *event.Event) (string, []Unit, error)
+
+	// PromoteCandidate makes the given image the one deployed in the app,
+	// removing every unit running a different image. It does not change
+	// the app routes.
+	PromoteCandidate(App, string, *event.Event) (string, error)
+}
+
 type BuilderDockerClient interface {
 	PullAndCreateContainer(opts docker.CreateContainerOptions, w io.Writer) (*docker.Container, string, error)
 	RemoveContainer(opts docker.RemoveContainerOptions) error
@@ -356,6 +395,9 @@ type ExecOptions struct {
 	Term   string
 	Cmds   []string
 	Units  []string
+	// Event, when cancelable, allows the command to be stopped by canceling
+	// the event. Only supported on isolated runs by some provisioners.
+	Event *event.Event
 }
 
 type ExecutableProvisioner interface {
@@ -422,6 +464,42 @@ type HCProvisioner interface {
 	HandlesHC() bool
 }
 
+// ServiceBindProvisioner is a provisioner that must be notified when apps are
+// bound to or unbound from service instances.
+type ServiceBindProvisioner interface {
+	// UpdateServiceBinds is called after the set of apps bound to the
+	// service instance changes.
+	UpdateServiceBinds(serviceName, instanceName string) error
+}
+
+// ExecCancelProvisioner is a provisioner that may stop isolated commands when
+// the event in their ExecOptions is canceled.
+type ExecCancelProvisioner interface {
+	// HandlesExecCancel returns true if the provisioner will stop isolated
+	// commands when their event is canceled.
+	HandlesExecCancel() bool
+}
+
+// AutoScaleProvisioner is a provisioner that may natively scale the units of
+// apps according to their autoscale specs.
+type AutoScaleProvisioner interface {
+	// HandlesAutoScale returns true if the provisioner will scale the units
+	// of the process in the spec instead of tsuru.
+	HandlesAutoScale(App, appTypes.AutoScaleSpec) (bool, error)
+
+	// UpdateAutoScale makes the provisioner autoscalers match the current
+	// autoscale specs of the app.
+	UpdateAutoScale(App) error
+}
+
+// JobProvisioner is a provisioner that natively runs the scheduled jobs of
+// apps. Jobs of apps in other provisioners are run by the tsuru scheduler.
+type JobProvisioner interface {
+	// UpdateJobs makes the provisioner scheduled jobs match the current jobs
+	// of the app.
+	UpdateJobs(App) error
+}
+
 type AddNodeOptions struct {
 	IaaSID     string
 	Address    string
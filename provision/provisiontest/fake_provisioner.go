@@ -39,6 +39,7 @@ var (
 	_ provision.NodeProvisioner      = &FakeProvisioner{}
 	_ provision.InterAppProvisioner  = &FakeProvisioner{}
 	_ provision.UpdatableProvisioner = &FakeProvisioner{}
+	_ provision.StrategyDeployer     = &FakeProvisioner{}
 	_ provision.Provisioner          = &FakeProvisioner{}
 	_ provision.App                  = &FakeApp{}
 	_ bind.App                       = &FakeApp{}
@@ -68,6 +69,10 @@ type FakeApp struct {
 	Memory          int64
 	Swap            int64
 	CpuShare        int
+	Processes       map[string]appTypes.ProcessResources
+	AutoScale       []appTypes.AutoScaleSpec
+	Jobs            []appTypes.Job
+	Metadata        appTypes.Metadata
 	commMut         sync.Mutex
 	Deploys         uint
 	env             map[string]bind.EnvVar
@@ -125,6 +130,25 @@ func (a *FakeApp) GetCpuShare() int {
 	return a.CpuShare
 }
 
+func (a *FakeApp) GetProcessResources(process string) appTypes.ProcessResources {
+	if r, ok := a.Processes[process]; ok {
+		return r
+	}
+	return appTypes.ProcessResources{Memory: a.Memory, Swap: a.Swap, CpuShare: a.CpuShare}
+}
+
+func (a *FakeApp) AutoScaleInfo() []appTypes.AutoScaleSpec {
+	return a.AutoScale
+}
+
+func (a *FakeApp) GetJobs() []appTypes.Job {
+	return a.Jobs
+}
+
+func (a *FakeApp) GetMetadata() appTypes.Metadata {
+	return a.Metadata
+}
+
 func (a *FakeApp) GetTeamsName() []string {
 	return a.Teams
 }
@@ -872,6 +896,78 @@ func (p *FakeProvisioner) Deploy(app provision.App, img string, evt *event.Event
 	return fakeAppImage, nil
 }
 
+func (p *FakeProvisioner) DeployCandidate(app provision.App, img string, evt *event.Event) (string, []provision.Unit, error) {
+	if err := p.getError("DeployCandidate"); err != nil {
+		return "", nil, err
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	pApp, ok := p.apps[app.GetName()]
+	if !ok {
+		return "", nil, errNotProvisioned
+	}
+	var candidates []provision.Unit
+	for _, u := range pApp.units {
+		val := atomic.AddInt32(&uniqueIpCounter, 1)
+		hostAddr := fmt.Sprintf("10.10.10.%d", val)
+		candidates = append(candidates, provision.Unit{
+			ID:          fmt.Sprintf("%s-%d", app.GetName(), pApp.unitLen),
+			AppName:     app.GetName(),
+			Type:        app.GetPlatform(),
+			Status:      provision.StatusStarted,
+			IP:          hostAddr,
+			ProcessName: u.ProcessName,
+			Address: &url.URL{
+				Scheme: "http",
+				Host:   fmt.Sprintf("%s:%d", hostAddr, val),
+			},
+		})
+		pApp.unitLen++
+	}
+	pApp.units = append(pApp.units, candidates...)
+	pApp.candidateImage = img
+	pApp.candidateUnits = make([]string, len(candidates))
+	for i := range candidates {
+		pApp.candidateUnits[i] = candidates[i].ID
+	}
+	evt.Write([]byte("Candidate deploy called"))
+	p.apps[app.GetName()] = pApp
+	return img, candidates, nil
+}
+
+func (p *FakeProvisioner) PromoteCandidate(app provision.App, img string, evt *event.Event) (string, error) {
+	if err := p.getError("PromoteCandidate"); err != nil {
+		return "", err
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	pApp, ok := p.apps[app.GetName()]
+	if !ok {
+		return "", errNotProvisioned
+	}
+	keepCandidates := img == pApp.candidateImage
+	var units []provision.Unit
+	for _, u := range pApp.units {
+		if stringInArray(u.ID, pApp.candidateUnits) == keepCandidates {
+			units = append(units, u)
+		}
+	}
+	pApp.units = units
+	pApp.image = img
+	pApp.candidateImage = ""
+	pApp.candidateUnits = nil
+	evt.Write([]byte("Promote candidate called"))
+	p.apps[app.GetName()] = pApp
+	return img, nil
+}
+
+// Image returns the image last deployed to the given app.
+func (p *FakeProvisioner) Image(app provision.App) string {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.apps[app.GetName()].image
+}
+
 func (p *FakeProvisioner) GetClient(app provision.App) (provision.BuilderDockerClient, error) {
 	for _, node := range p.nodes {
 		client, err := docker.NewClient(node.Addr)
@@ -1353,6 +1449,10 @@ func (p *FakeProvisioner) ExecuteCommand(opts provision.ExecOptions) error {
 	return err
 }
 
+func (p *FakeProvisioner) HandlesExecCancel() bool {
+	return true
+}
+
 func (p *FakeProvisioner) FilterAppsByUnitStatus(apps []provision.App, status []string) ([]provision.App, error) {
 	filteredApps := []provision.App{}
 	for i := range apps {
@@ -1480,4 +1580,7 @@ type provisionedApp struct {
 	unitLen     int
 	lastData    map[string]interface{}
 	image       string
+
+	candidateImage string
+	candidateUnits []string
 }
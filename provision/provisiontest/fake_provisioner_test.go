@@ -563,6 +563,58 @@ func (s *S) TestImageDeployWithPrepareFailure(c *check.C) {
 	c.Assert(err, check.ErrorMatches, "not really")
 }
 
+func (s *S) TestDeployCandidateAndPromote(c *check.C) {
+	app := NewFakeApp("otherapp", "test", 1)
+	evt, err := event.New(&event.Opts{
+		Target:   event.Target{Type: "app", Value: app.name},
+		Kind:     permission.PermAppDeploy,
+		RawOwner: event.Owner{Type: event.OwnerTypeUser, Name: "me@me.com"},
+		Allowed:  event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	p := NewFakeProvisioner()
+	err = p.Provision(app)
+	c.Assert(err, check.IsNil)
+	err = p.AddUnits(app, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	img, candidates, err := p.DeployCandidate(app, "image/v2", evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(img, check.Equals, "image/v2")
+	c.Assert(candidates, check.HasLen, 2)
+	c.Assert(p.GetUnits(app), check.HasLen, 4)
+	img, err = p.PromoteCandidate(app, "image/v2", evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(img, check.Equals, "image/v2")
+	c.Assert(p.GetUnits(app), check.DeepEquals, candidates)
+	c.Assert(p.Image(app), check.Equals, "image/v2")
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Log, check.Equals, "Candidate deploy calledPromote candidate called")
+}
+
+func (s *S) TestPromoteCandidatePreviousImage(c *check.C) {
+	app := NewFakeApp("otherapp", "test", 1)
+	evt, err := event.New(&event.Opts{
+		Target:   event.Target{Type: "app", Value: app.name},
+		Kind:     permission.PermAppDeploy,
+		RawOwner: event.Owner{Type: event.OwnerTypeUser, Name: "me@me.com"},
+		Allowed:  event.Allowed(permission.PermApp),
+	})
+	c.Assert(err, check.IsNil)
+	p := NewFakeProvisioner()
+	err = p.Provision(app)
+	c.Assert(err, check.IsNil)
+	err = p.AddUnits(app, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	oldUnits := p.GetUnits(app)
+	_, _, err = p.DeployCandidate(app, "image/v2", evt)
+	c.Assert(err, check.IsNil)
+	_, err = p.PromoteCandidate(app, "image/v1", evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(p.GetUnits(app), check.DeepEquals, oldUnits)
+	c.Assert(p.Image(app), check.Equals, "image/v1")
+}
+
 func (s *S) TestProvision(c *check.C) {
 	app := NewFakeApp("kid-gloves", "rush", 1)
 	p := NewFakeProvisioner()
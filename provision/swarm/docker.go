@@ -28,6 +28,7 @@ import (
 	"github.com/tsuru/tsuru/provision/dockercommon"
 	"github.com/tsuru/tsuru/provision/nodecontainer"
 	"github.com/tsuru/tsuru/provision/servicecommon"
+	appTypes "github.com/tsuru/tsuru/types/app"
 	provTypes "github.com/tsuru/tsuru/types/provision"
 )
 
@@ -255,6 +256,8 @@ func serviceSpecForApp(opts tsuruServiceOpts) (*swarm.ServiceSpec, error) {
 	var endpointSpec *swarm.EndpointSpec
 	var networks []swarm.NetworkAttachmentConfig
 	var healthConfig *container.HealthConfig
+	var stopGracePeriod *time.Duration
+	var resources *swarm.ResourceRequirements
 	port := provision.WebProcessDefaultPort()
 	portInt, _ := strconv.Atoi(port)
 	mounts, err := mountsForApp(opts.app)
@@ -281,7 +284,12 @@ func serviceSpecForApp(opts tsuruServiceOpts) (*swarm.ServiceSpec, error) {
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		healthConfig = toHealthConfig(yamlData, portInt)
+		healthConfig = toHealthConfig(yamlData, opts.process, portInt)
+		resources = resourcesForProcess(opts.app.GetProcessResources(opts.process))
+		if processLifecycle := yamlData.ProcessConfig(opts.process).Lifecycle; processLifecycle != nil && processLifecycle.TerminationGracePeriodSeconds > 0 {
+			gracePeriod := time.Duration(processLifecycle.TerminationGracePeriodSeconds) * time.Second
+			stopGracePeriod = &gracePeriod
+		}
 	}
 	if opts.labels == nil {
 		opts.labels, err = provision.ServiceLabels(provision.ServiceLabelsOpts{
@@ -316,14 +324,16 @@ func serviceSpecForApp(opts tsuruServiceOpts) (*swarm.ServiceSpec, error) {
 	spec := swarm.ServiceSpec{
 		TaskTemplate: swarm.TaskSpec{
 			ContainerSpec: &swarm.ContainerSpec{
-				Image:       opts.image,
-				Env:         envs,
-				Labels:      opts.labels.ToLabels(),
-				Command:     cmds,
-				Healthcheck: healthConfig,
-				Mounts:      mounts,
+				Image:           opts.image,
+				Env:             envs,
+				Labels:          opts.labels.ToLabels(),
+				Command:         cmds,
+				Healthcheck:     healthConfig,
+				Mounts:          mounts,
+				StopGracePeriod: stopGracePeriod,
 			},
-			Networks: networks,
+			Networks:  networks,
+			Resources: resources,
 			RestartPolicy: &swarm.RestartPolicy{
 				Condition: swarm.RestartPolicyConditionAny,
 			},
@@ -348,6 +358,22 @@ func serviceSpecForApp(opts tsuruServiceOpts) (*swarm.ServiceSpec, error) {
 	return &spec, nil
 }
 
+// resourcesForProcess returns the cpu limit and reservation of the units of a
+// process, converting millicores to nano cpus.
+func resourcesForProcess(r appTypes.ProcessResources) *swarm.ResourceRequirements {
+	if r.CPULimit == 0 && r.CPURequest == 0 {
+		return nil
+	}
+	resources := &swarm.ResourceRequirements{}
+	if r.CPULimit != 0 {
+		resources.Limits = &swarm.Resources{NanoCPUs: int64(r.CPULimit) * 1e6}
+	}
+	if r.CPURequest != 0 {
+		resources.Reservations = &swarm.Resources{NanoCPUs: int64(r.CPURequest) * 1e6}
+	}
+	return resources
+}
+
 func removeServiceAndLog(client *clusterClient, id string) {
 	err := client.RemoveService(docker.RemoveServiceOptions{
 		ID: id,
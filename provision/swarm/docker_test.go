@@ -24,6 +24,7 @@ import (
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/nodecontainer"
 	"github.com/tsuru/tsuru/provision/servicecommon"
+	appTypes "github.com/tsuru/tsuru/types/app"
 	check "gopkg.in/check.v1"
 )
 
@@ -379,6 +380,17 @@ func (s *S) TestServiceSpecForApp(c *check.C) {
 	}
 }
 
+func (s *S) TestResourcesForProcess(c *check.C) {
+	c.Assert(resourcesForProcess(appTypes.ProcessResources{Memory: 1024}), check.IsNil)
+	c.Assert(resourcesForProcess(appTypes.ProcessResources{CPULimit: 1500}), check.DeepEquals, &swarm.ResourceRequirements{
+		Limits: &swarm.Resources{NanoCPUs: 1500000000},
+	})
+	c.Assert(resourcesForProcess(appTypes.ProcessResources{CPURequest: 250, CPULimit: 500}), check.DeepEquals, &swarm.ResourceRequirements{
+		Limits:       &swarm.Resources{NanoCPUs: 500000000},
+		Reservations: &swarm.Resources{NanoCPUs: 250000000},
+	})
+}
+
 func (s *S) TestServiceSpecForNodeContainer(c *check.C) {
 	c1 := nodecontainer.NodeContainerConfig{
 		Name: "swarmbs",
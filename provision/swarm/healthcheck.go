@@ -14,8 +14,12 @@ import (
 	provTypes "github.com/tsuru/tsuru/types/provision"
 )
 
-func toHealthConfig(meta provTypes.TsuruYamlData, port int) *container.HealthConfig {
+func toHealthConfig(meta provTypes.TsuruYamlData, process string, port int) *container.HealthConfig {
 	hc := meta.Healthcheck
+	processConfig := meta.ProcessConfig(process)
+	if processConfig.Healthcheck != nil {
+		hc = processConfig.Healthcheck
+	}
 	var (
 		path            string
 		method          string
@@ -71,7 +75,7 @@ func toHealthConfig(meta provTypes.TsuruYamlData, port int) *container.HealthCon
 	if cmdLine == "" {
 		return nil
 	}
-	return &container.HealthConfig{
+	healthConfig := &container.HealthConfig{
 		Interval: 3 * time.Second,
 		Retries:  allowedFailures + 1,
 		Timeout:  time.Duration(timeoutSeconds) * time.Second,
@@ -80,4 +84,8 @@ func toHealthConfig(meta provTypes.TsuruYamlData, port int) *container.HealthCon
 			cmdLine,
 		},
 	}
+	if processConfig.Startup != nil {
+		healthConfig.StartPeriod = time.Duration(processConfig.Startup.StartupSeconds()) * time.Second
+	}
+	return healthConfig
 }
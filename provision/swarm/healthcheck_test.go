@@ -118,9 +118,35 @@ func (s *S) TestToHealthConfig(c *check.C) {
 			Interval: 3 * time.Second,
 			Retries:  1,
 		}},
+		{input: provision.TsuruYamlData{
+			Healthcheck: &provision.TsuruYamlHealthcheck{
+				Path: "/",
+			},
+			Processes: map[string]provision.TsuruYamlProcessConfig{
+				"web": {
+					Healthcheck: &provision.TsuruYamlHealthcheck{
+						Path: "/ready",
+					},
+					Startup: &provision.TsuruYamlProbe{
+						InitialDelaySeconds: 5,
+						IntervalSeconds:     5,
+						AllowedFailures:     6,
+					},
+				},
+			},
+		}, expected: &container.HealthConfig{
+			Test: []string{
+				"CMD-SHELL",
+				"curl -k -XGET -fsSL http://localhost:9000/ready -o/dev/null -w '%{http_code}' | grep 200",
+			},
+			Timeout:     60 * time.Second,
+			Interval:    3 * time.Second,
+			Retries:     1,
+			StartPeriod: 35 * time.Second,
+		}},
 	}
 	for i, test := range tests {
-		result := toHealthConfig(test.input, 9000)
+		result := toHealthConfig(test.input, "web", 9000)
 		c.Assert(result, check.DeepEquals, test.expected, check.Commentf("failed test %d", i))
 	}
 }
@@ -21,6 +21,8 @@ var capMap = map[string][]string{
 	"healthcheck": {"router.CustomHealthcheckRouter", "apiRouterWithHealthcheckSupport"},
 	"info":        {"router.InfoRouter", "apiRouterWithInfo"},
 	"status":      {"router.StatusRouter", "apiRouterWithStatus"},
+	"acme":        {"router.ACMERouter", "apiRouterWithACMESupport"},
+	"weights":     {"router.TrafficSplitRouter", "apiRouterWithWeightsSupport"},
 }
 
 var fileTpl = `// AUTOMATICALLY GENERATED FILE - DO NOT EDIT!
@@ -15,7 +15,6 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
-	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/router"
@@ -36,6 +35,8 @@ var (
 	_ router.CustomHealthcheckRouter = &apiRouterWithHealthcheckSupport{}
 	_ router.InfoRouter              = &apiRouterWithInfo{}
 	_ router.StatusRouter            = &apiRouterWithStatus{}
+	_ router.ACMERouter              = &apiRouterWithACMESupport{}
+	_ router.TrafficSplitRouter      = &apiRouterWithWeightsSupport{}
 )
 
 type apiRouter struct {
@@ -57,6 +58,10 @@ type apiRouterWithInfo struct{ *apiRouter }
 
 type apiRouterWithStatus struct{ *apiRouter }
 
+type apiRouterWithACMESupport struct{ *apiRouter }
+
+type apiRouterWithWeightsSupport struct{ *apiRouter }
+
 type routesReq struct {
 	Addresses []string `json:"addresses"`
 }
@@ -75,6 +80,14 @@ type certData struct {
 	Key         string `json:"key"`
 }
 
+type acmeChallengeData struct {
+	KeyAuthorization string `json:"keyAuthorization"`
+}
+
+type weightsData struct {
+	Weights []router.BackendWeight `json:"weights"`
+}
+
 type backendResp struct {
 	Address string `json:"address"`
 }
@@ -92,21 +105,23 @@ var (
 	capHealthcheck = capability("healthcheck")
 	capInfo        = capability("info")
 	capStatus      = capability("status")
+	capACME        = capability("acme")
+	capWeights     = capability("weights")
 
-	allCaps = []capability{capCName, capTLS, capHealthcheck, capInfo, capStatus}
+	allCaps = []capability{capCName, capTLS, capHealthcheck, capInfo, capStatus, capACME, capWeights}
 )
 
 func init() {
 	router.Register(routerType, createRouter)
 }
 
-func createRouter(routerName, configPrefix string) (router.Router, error) {
-	endpoint, err := config.GetString(configPrefix + ":api-url")
+func createRouter(routerName string, config router.ConfigGetter) (router.Router, error) {
+	endpoint, err := config.GetString("api-url")
 	if err != nil {
 		return nil, err
 	}
-	debug, _ := config.GetBool(configPrefix + ":debug")
-	headers, _ := config.Get(configPrefix + ":headers")
+	debug, _ := config.GetBool("debug")
+	headers, _ := config.Get("headers")
 	headerMap := make(map[string]string)
 	if headers != nil {
 		h, ok := headers.(map[interface{}]interface{})
@@ -119,7 +134,7 @@ func createRouter(routerName, configPrefix string) (router.Router, error) {
 			if !okK || !okV {
 				return nil, errors.Errorf("invalid header configuration: %v. Expected string got %s and %s", headers, k, v)
 			}
-			value, _ := config.GetString(fmt.Sprintf("%s:headers:%s", configPrefix, k))
+			value, _ := config.GetString("headers:" + k)
 			headerMap[k] = value
 		}
 	}
@@ -490,6 +505,70 @@ func (r *apiRouterWithStatus) GetBackendStatus(name string) (router.BackendStatu
 	return status.Status, status.Detail, nil
 }
 
+func (r *apiRouterWithACMESupport) AddACMEChallenge(app router.App, cname, token, keyAuthorization string) error {
+	b, err := json.Marshal(acmeChallengeData{KeyAuthorization: keyAuthorization})
+	if err != nil {
+		return err
+	}
+	_, code, err := r.do(http.MethodPut, fmt.Sprintf("backend/%s/acme-challenge/%s/%s", app.GetName(), cname, token), bytes.NewReader(b))
+	if code == http.StatusNotFound {
+		return router.ErrBackendNotFound
+	}
+	return err
+}
+
+func (r *apiRouterWithACMESupport) RemoveACMEChallenge(app router.App, cname, token string) error {
+	_, code, err := r.do(http.MethodDelete, fmt.Sprintf("backend/%s/acme-challenge/%s/%s", app.GetName(), cname, token), nil)
+	if code == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (r *apiRouterWithWeightsSupport) SetBackendWeights(name string, weights []router.BackendWeight) error {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return err
+	}
+	data := weightsData{Weights: make([]router.BackendWeight, len(weights))}
+	for i, w := range weights {
+		data.Weights[i] = w
+		data.Weights[i].Backend, err = router.Retrieve(w.Backend)
+		if err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, code, err := r.do(http.MethodPut, fmt.Sprintf("backend/%s/weights", backendName), bytes.NewReader(b))
+	if code == http.StatusNotFound {
+		return router.ErrBackendNotFound
+	}
+	return err
+}
+
+func (r *apiRouterWithWeightsSupport) GetBackendWeights(name string) ([]router.BackendWeight, error) {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return nil, err
+	}
+	data, code, err := r.do(http.MethodGet, fmt.Sprintf("backend/%s/weights", backendName), nil)
+	if code == http.StatusNotFound {
+		return nil, router.ErrBackendNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var weights weightsData
+	err = json.Unmarshal(data, &weights)
+	if err != nil {
+		return nil, err
+	}
+	return weights.Weights, nil
+}
+
 func addDefaultOpts(app router.App, opts map[string]string) map[string]interface{} {
 	mergedOpts := make(map[string]interface{})
 	for k, v := range opts {
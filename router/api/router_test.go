@@ -47,7 +47,7 @@ func init() {
 		config.Set("routers:apirouter:api-url", r.endpoint)
 		config.Set("database:name", "router_api_tests")
 		r.backends = make(map[string]*backend)
-		apiRouter, err := createRouter("api", "routers:apirouter")
+		apiRouter, err := createRouter("api", &router.StaticConfigGetter{Prefix: "routers:apirouter"})
 		c.Assert(err, check.IsNil)
 		suite.Router = apiRouter
 	}
@@ -65,6 +65,7 @@ func init() {
 func (s *S) SetUpTest(c *check.C) {
 	s.apiRouter = newFakeRouter(c)
 	s.apiRouter.certificates = make(map[string]certData)
+	s.apiRouter.challenges = make(map[string]string)
 	s.testRouter = &apiRouter{
 		endpoint:   s.apiRouter.endpoint,
 		client:     tsuruNet.Dial15Full60ClientNoKeepAlive,
@@ -351,14 +352,79 @@ func (s *S) TestHealcheckBackendNotFound(c *check.C) {
 	c.Assert(err, check.DeepEquals, router.ErrBackendNotFound)
 }
 
+func (s *S) TestAddACMEChallenge(c *check.C) {
+	acmeRouter := &apiRouterWithACMESupport{s.testRouter}
+	err := acmeRouter.AddACMEChallenge(routertest.FakeApp{Name: "mybackend"}, "cname.com", "tok", "tok.thumb")
+	c.Assert(err, check.IsNil)
+	c.Assert(s.apiRouter.challenges, check.DeepEquals, map[string]string{"cname.com/tok": "tok.thumb"})
+}
+
+func (s *S) TestAddACMEChallengeBackendNotFound(c *check.C) {
+	acmeRouter := &apiRouterWithACMESupport{s.testRouter}
+	err := acmeRouter.AddACMEChallenge(routertest.FakeApp{Name: "invalid"}, "cname.com", "tok", "tok.thumb")
+	c.Assert(err, check.Equals, router.ErrBackendNotFound)
+}
+
+func (s *S) TestRemoveACMEChallenge(c *check.C) {
+	acmeRouter := &apiRouterWithACMESupport{s.testRouter}
+	err := acmeRouter.AddACMEChallenge(routertest.FakeApp{Name: "mybackend"}, "cname.com", "tok", "tok.thumb")
+	c.Assert(err, check.IsNil)
+	err = acmeRouter.RemoveACMEChallenge(routertest.FakeApp{Name: "mybackend"}, "cname.com", "tok")
+	c.Assert(err, check.IsNil)
+	c.Assert(s.apiRouter.challenges, check.HasLen, 0)
+	err = acmeRouter.RemoveACMEChallenge(routertest.FakeApp{Name: "mybackend"}, "cname.com", "tok")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestSetBackendWeights(c *check.C) {
+	err := s.testRouter.AddBackend(routertest.FakeApp{Name: "otherbackend"})
+	c.Assert(err, check.IsNil)
+	weightsRouter := &apiRouterWithWeightsSupport{s.testRouter}
+	err = weightsRouter.SetBackendWeights("mybackend", []router.BackendWeight{
+		{Backend: "mybackend", Weight: 90},
+		{Backend: "otherbackend", Weight: 10},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(s.apiRouter.backends["mybackend"].weights, check.DeepEquals, []router.BackendWeight{
+		{Backend: "mybackend", Weight: 90},
+		{Backend: "otherbackend", Weight: 10},
+	})
+	weights, err := weightsRouter.GetBackendWeights("mybackend")
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.DeepEquals, []router.BackendWeight{
+		{Backend: "mybackend", Weight: 90},
+		{Backend: "otherbackend", Weight: 10},
+	})
+	err = weightsRouter.SetBackendWeights("mybackend", nil)
+	c.Assert(err, check.IsNil)
+	weights, err = weightsRouter.GetBackendWeights("mybackend")
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.HasLen, 0)
+}
+
+func (s *S) TestSetBackendWeightsBackendNotFound(c *check.C) {
+	err := s.testRouter.AddBackend(routertest.FakeApp{Name: "otherbackend"})
+	c.Assert(err, check.IsNil)
+	delete(s.apiRouter.backends, "otherbackend")
+	weightsRouter := &apiRouterWithWeightsSupport{s.testRouter}
+	err = weightsRouter.SetBackendWeights("otherbackend", []router.BackendWeight{{Backend: "mybackend", Weight: 100}})
+	c.Assert(err, check.Equals, router.ErrBackendNotFound)
+	_, err = weightsRouter.GetBackendWeights("otherbackend")
+	c.Assert(err, check.Equals, router.ErrBackendNotFound)
+	err = weightsRouter.SetBackendWeights("mybackend", []router.BackendWeight{{Backend: "unknown", Weight: 100}})
+	c.Assert(err, check.Equals, router.ErrBackendNotFound)
+}
+
 func (s *S) TestCreateRouterSupport(c *check.C) {
 	tt := []struct {
 		features    map[string]bool
 		expectCname bool
 		expectTLS   bool
 		expectHC    bool
+		expectACME  bool
+		expectSplit bool
 	}{
-		{nil, false, false, false},
+		{nil, false, false, false, false, false},
 		{features: map[string]bool{"cname": true}, expectCname: true},
 		{features: map[string]bool{"tls": true}, expectTLS: true},
 		{features: map[string]bool{"healthcheck": true}, expectHC: true},
@@ -366,6 +432,8 @@ func (s *S) TestCreateRouterSupport(c *check.C) {
 		{features: map[string]bool{"cname": true, "tls": true, "healthcheck": true}, expectCname: true, expectTLS: true, expectHC: true},
 		{features: map[string]bool{"cname": true, "healthcheck": true}, expectCname: true, expectHC: true},
 		{features: map[string]bool{"tls": true, "healthcheck": true}, expectTLS: true, expectHC: true},
+		{features: map[string]bool{"tls": true, "acme": true}, expectTLS: true, expectACME: true},
+		{features: map[string]bool{"cname": true, "weights": true}, expectCname: true, expectSplit: true},
 	}
 	var i int
 	s.apiRouter.router.HandleFunc("/support/{name}", func(w http.ResponseWriter, r *http.Request) {
@@ -383,7 +451,7 @@ func (s *S) TestCreateRouterSupport(c *check.C) {
 	})
 	for i = range tt {
 		comment := check.Commentf("case %d: %v", i, tt[i])
-		r, err := createRouter("myrouter", "routers:apirouter")
+		r, err := createRouter("myrouter", &router.StaticConfigGetter{Prefix: "routers:apirouter"})
 		c.Assert(err, check.IsNil, comment)
 		_, ok := r.(router.CNameRouter)
 		c.Assert(ok, check.Equals, tt[i].expectCname, comment)
@@ -391,6 +459,10 @@ func (s *S) TestCreateRouterSupport(c *check.C) {
 		c.Assert(ok, check.Equals, tt[i].expectTLS, comment)
 		_, ok = r.(router.CustomHealthcheckRouter)
 		c.Assert(ok, check.Equals, tt[i].expectHC, comment)
+		_, ok = r.(router.ACMERouter)
+		c.Assert(ok, check.Equals, tt[i].expectACME, comment)
+		_, ok = r.(router.TrafficSplitRouter)
+		c.Assert(ok, check.Equals, tt[i].expectSplit, comment)
 	}
 }
 
@@ -405,7 +477,7 @@ func (s *S) TestCreateCustomHeaders(c *check.C) {
 	config.Set("routers:apirouter:headers", map[interface{}]interface{}{"X-CUSTOM": "HI", "X-CUSTOM-ENV": "$ROUTER_ENV_HEADER_OPT"})
 	defer config.Unset("router:apirouter:headers")
 	defer os.Unsetenv("ROUTER_ENV_HEADER_OPT")
-	r, err := createRouter("apirouter", "routers:apirouter")
+	r, err := createRouter("apirouter", &router.StaticConfigGetter{Prefix: "routers:apirouter"})
 	c.Assert(err, check.IsNil)
 	_, code, err := r.(*struct {
 		router.Router
@@ -434,6 +506,10 @@ func newFakeRouter(c *check.C) *fakeRouterAPI {
 	r.HandleFunc("/backend/{name}/certificate/{cname}", api.addCertificate).Methods(http.MethodPut)
 	r.HandleFunc("/backend/{name}/certificate/{cname}", api.removeCertificate).Methods(http.MethodDelete)
 	r.HandleFunc("/backend/{name}/status", api.getStatusBackend).Methods(http.MethodGet)
+	r.HandleFunc("/backend/{name}/acme-challenge/{cname}/{token}", api.addACMEChallenge).Methods(http.MethodPut)
+	r.HandleFunc("/backend/{name}/acme-challenge/{cname}/{token}", api.removeACMEChallenge).Methods(http.MethodDelete)
+	r.HandleFunc("/backend/{name}/weights", api.getWeights).Methods(http.MethodGet)
+	r.HandleFunc("/backend/{name}/weights", api.setWeights).Methods(http.MethodPut)
 	r.HandleFunc("/info", api.getInfo).Methods(http.MethodGet)
 
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -453,12 +529,14 @@ type backend struct {
 	cnameOnly   bool
 	healthcheck routerTypes.HealthcheckData
 	opts        map[string]interface{}
+	weights     []router.BackendWeight
 }
 
 type fakeRouterAPI struct {
 	listener     net.Listener
 	backends     map[string]*backend
 	certificates map[string]certData
+	challenges   map[string]string
 	endpoint     string
 	router       *mux.Router
 }
@@ -733,6 +811,47 @@ func (f *fakeRouterAPI) removeCertificate(w http.ResponseWriter, r *http.Request
 	delete(f.certificates, cname)
 }
 
+func (f *fakeRouterAPI) addACMEChallenge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, ok := f.backends[vars["name"]]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var data acmeChallengeData
+	json.NewDecoder(r.Body).Decode(&data)
+	f.challenges[vars["cname"]+"/"+vars["token"]] = data.KeyAuthorization
+}
+
+func (f *fakeRouterAPI) removeACMEChallenge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	key := vars["cname"] + "/" + vars["token"]
+	if _, ok := f.challenges[key]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	delete(f.challenges, key)
+}
+
+func (f *fakeRouterAPI) getWeights(w http.ResponseWriter, r *http.Request) {
+	b, ok := f.backends[mux.Vars(r)["name"]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(weightsData{Weights: b.weights})
+}
+
+func (f *fakeRouterAPI) setWeights(w http.ResponseWriter, r *http.Request) {
+	b, ok := f.backends[mux.Vars(r)["name"]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var data weightsData
+	json.NewDecoder(r.Body).Decode(&data)
+	b.weights = data.Weights
+}
+
 func (f *fakeRouterAPI) setHealthcheck(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
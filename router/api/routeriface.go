@@ -12,13 +12,15 @@ import (
 )
 
 func toSupportedInterface(base *apiRouter, supports map[capability]bool) router.Router {
+	apiRouterWithACMESupportInst := &apiRouterWithACMESupport{base}
 	apiRouterWithCnameSupportInst := &apiRouterWithCnameSupport{base}
 	apiRouterWithHealthcheckSupportInst := &apiRouterWithHealthcheckSupport{base}
 	apiRouterWithInfoInst := &apiRouterWithInfo{base}
 	apiRouterWithStatusInst := &apiRouterWithStatus{base}
 	apiRouterWithTLSSupportInst := &apiRouterWithTLSSupport{base}
+	apiRouterWithWeightsSupportInst := &apiRouterWithWeightsSupport{base}
 
-	if !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
@@ -27,192 +29,431 @@ func toSupportedInterface(base *apiRouter, supports map[capability]bool) router.
 			base,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] {
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.CNameRouter
 			router.CustomHealthcheckRouter
 		}{
 			base,
 			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.CustomHealthcheckRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.CNameRouter
 			router.InfoRouter
 		}{
 			base,
 			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithInfoInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.InfoRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithInfoInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.InfoRouter
 		}{
 			base,
 			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.InfoRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithStatusInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.CNameRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.CNameRouter
 			router.InfoRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.InfoRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CustomHealthcheckRouter
 			router.InfoRouter
 			router.StatusRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.InfoRouter
@@ -220,13 +461,14 @@ func toSupportedInterface(base *apiRouter, supports map[capability]bool) router.
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithStatusInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
@@ -237,7 +479,20 @@ func toSupportedInterface(base *apiRouter, supports map[capability]bool) router.
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] {
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
@@ -250,220 +505,1567 @@ func toSupportedInterface(base *apiRouter, supports map[capability]bool) router.
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] {
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.CustomHealthcheckRouter
+			router.ACMERouter
+			router.CNameRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] {
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.InfoRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithInfoInst,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] {
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
 			router.CNameRouter
-			router.InfoRouter
+			router.CustomHealthcheckRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
 			apiRouterWithCnameSupportInst,
-			apiRouterWithInfoInst,
+			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
 			router.CustomHealthcheckRouter
-			router.InfoRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
-			apiRouterWithInfoInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.CNameRouter
-			router.CustomHealthcheckRouter
 			router.InfoRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithCnameSupportInst,
-			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] {
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.StatusRouter
+			router.ACMERouter
+			router.InfoRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithStatusInst,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] {
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
 			router.CNameRouter
-			router.StatusRouter
+			router.InfoRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
 			apiRouterWithCnameSupportInst,
-			apiRouterWithStatusInst,
+			apiRouterWithInfoInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] {
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.CustomHealthcheckRouter
-			router.StatusRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.InfoRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithHealthcheckSupportInst,
-			apiRouterWithStatusInst,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] {
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
-			router.CNameRouter
 			router.CustomHealthcheckRouter
-			router.StatusRouter
+			router.InfoRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
-			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
-			apiRouterWithStatusInst,
+			apiRouterWithInfoInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] {
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
 			router.InfoRouter
-			router.StatusRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
-			apiRouterWithStatusInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] {
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
 			router.CNameRouter
+			router.CustomHealthcheckRouter
 			router.InfoRouter
-			router.StatusRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
 			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
-			apiRouterWithStatusInst,
 			apiRouterWithTLSSupportInst,
 		}
 	}
-	if !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && !supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.InfoRouter
-			router.StatusRouter
 			router.TLSRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && !supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && !supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && !supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && !supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && supports["cname"] && !supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CNameRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithCnameSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if supports["acme"] && !supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.ACMERouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithACMESupportInst,
+			apiRouterWithHealthcheckSupportInst,
+			apiRouterWithInfoInst,
+			apiRouterWithStatusInst,
+			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
+		}
+	}
+	if !supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
+		return &struct {
+			router.Router
+			router.OptsRouter
+			router.CNameRouter
+			router.CustomHealthcheckRouter
+			router.InfoRouter
+			router.StatusRouter
+			router.TLSRouter
+			router.TrafficSplitRouter
+		}{
+			base,
+			base,
+			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithStatusInst,
 			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
 		}
 	}
-	if supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] {
+	if supports["acme"] && supports["cname"] && supports["healthcheck"] && supports["info"] && supports["status"] && supports["tls"] && supports["weights"] {
 		return &struct {
 			router.Router
 			router.OptsRouter
+			router.ACMERouter
 			router.CNameRouter
 			router.CustomHealthcheckRouter
 			router.InfoRouter
 			router.StatusRouter
 			router.TLSRouter
+			router.TrafficSplitRouter
 		}{
 			base,
 			base,
+			apiRouterWithACMESupportInst,
 			apiRouterWithCnameSupportInst,
 			apiRouterWithHealthcheckSupportInst,
 			apiRouterWithInfoInst,
 			apiRouterWithStatusInst,
 			apiRouterWithTLSSupportInst,
+			apiRouterWithWeightsSupportInst,
 		}
 	}
 	return nil
@@ -0,0 +1,194 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	routerTypes "github.com/tsuru/tsuru/types/router"
+)
+
+// ConfigGetter gives router factories access to the settings of a router,
+// regardless of whether it's defined in the config file or registered
+// through the API. Keys are relative to the router, e.g. "api-url" or
+// "headers:X-Key".
+type ConfigGetter interface {
+	Get(key string) (interface{}, error)
+	GetString(key string) (string, error)
+	GetInt(key string) (int, error)
+	GetFloat(key string) (float64, error)
+	GetBool(key string) (bool, error)
+}
+
+// StaticConfigGetter reads the settings of a router from the config file,
+// under Prefix.
+type StaticConfigGetter struct {
+	Prefix string
+}
+
+var _ ConfigGetter = &StaticConfigGetter{}
+
+func (g *StaticConfigGetter) key(key string) string {
+	return g.Prefix + ":" + key
+}
+
+func (g *StaticConfigGetter) Get(key string) (interface{}, error) {
+	return config.Get(g.key(key))
+}
+
+func (g *StaticConfigGetter) GetString(key string) (string, error) {
+	return config.GetString(g.key(key))
+}
+
+func (g *StaticConfigGetter) GetInt(key string) (int, error) {
+	return config.GetInt(g.key(key))
+}
+
+func (g *StaticConfigGetter) GetFloat(key string) (float64, error) {
+	return config.GetFloat(g.key(key))
+}
+
+func (g *StaticConfigGetter) GetBool(key string) (bool, error) {
+	return config.GetBool(g.key(key))
+}
+
+// dynamicConfigGetter reads the settings of a router registered through the
+// API. As these come from JSON, forms or the database, values are converted
+// more leniently than the ones in the config file.
+type dynamicConfigGetter struct {
+	router routerTypes.DynamicRouter
+}
+
+var _ ConfigGetter = &dynamicConfigGetter{}
+
+func (g *dynamicConfigGetter) Get(key string) (interface{}, error) {
+	var value interface{} = g.router.Config
+	for _, part := range strings.Split(key, ":") {
+		m, ok := toStringMap(value)
+		if !ok {
+			return nil, config.ErrKeyNotFound{Key: key}
+		}
+		value, ok = m[part]
+		if !ok || value == nil {
+			return nil, config.ErrKeyNotFound{Key: key}
+		}
+	}
+	return toConfigValue(value), nil
+}
+
+func (g *dynamicConfigGetter) GetString(key string) (string, error) {
+	value, err := g.Get(key)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int, int32, int64, bool:
+		return fmt.Sprint(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", invalidValue(key, "string")
+}
+
+func (g *dynamicConfigGetter) GetInt(key string) (int, error) {
+	value, err := g.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if float64(int(v)) == v {
+			return int(v), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+	}
+	return 0, invalidValue(key, "int")
+}
+
+func (g *dynamicConfigGetter) GetFloat(key string) (float64, error) {
+	value, err := g.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, invalidValue(key, "float")
+}
+
+func (g *dynamicConfigGetter) GetBool(key string) (bool, error) {
+	value, err := g.Get(key)
+	if err != nil {
+		return false, err
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, invalidValue(key, "boolean")
+}
+
+func invalidValue(key, kind string) error {
+	return errors.Errorf("value for the key %q is not a %s", key, kind)
+}
+
+// toStringMap returns the map in value with its keys converted to strings,
+// handling the different map types used by the json, bson and yaml decoders.
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map {
+		return nil, false
+	}
+	result := make(map[string]interface{}, v.Len())
+	for _, k := range v.MapKeys() {
+		result[fmt.Sprint(k.Interface())] = v.MapIndex(k).Interface()
+	}
+	return result, true
+}
+
+// toConfigValue converts nested maps to map[interface{}]interface{}, the
+// type returned by config.Get, so factories handle both sources alike.
+func toConfigValue(value interface{}) interface{} {
+	m, ok := toStringMap(value)
+	if !ok {
+		return value
+	}
+	result := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		result[k] = toConfigValue(v)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package router
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/config"
+	routerTypes "github.com/tsuru/tsuru/types/router"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestStaticConfigGetter(c *check.C) {
+	config.Set("routers:mine:api-url", "http://router.io")
+	config.Set("routers:mine:wait-timeout", 10)
+	config.Set("routers:mine:debug", true)
+	defer config.Unset("routers:mine")
+	getter := &StaticConfigGetter{Prefix: "routers:mine"}
+	url, err := getter.GetString("api-url")
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "http://router.io")
+	timeout, err := getter.GetInt("wait-timeout")
+	c.Assert(err, check.IsNil)
+	c.Assert(timeout, check.Equals, 10)
+	debug, err := getter.GetBool("debug")
+	c.Assert(err, check.IsNil)
+	c.Assert(debug, check.Equals, true)
+	_, err = getter.GetString("domain")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestDynamicConfigGetter(c *check.C) {
+	getter := &dynamicConfigGetter{router: routerTypes.DynamicRouter{
+		Name: "mine",
+		Type: "api",
+		Config: map[string]interface{}{
+			"api-url":      "http://router.io",
+			"wait-timeout": float64(10),
+			"max-requests": "20",
+			"weight":       "0.5",
+			"debug":        "true",
+			"use-token":    true,
+			"headers":      bson.M{"X-Key": "value"},
+		},
+	}}
+	url, err := getter.GetString("api-url")
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "http://router.io")
+	timeout, err := getter.GetInt("wait-timeout")
+	c.Assert(err, check.IsNil)
+	c.Assert(timeout, check.Equals, 10)
+	maxRequests, err := getter.GetInt("max-requests")
+	c.Assert(err, check.IsNil)
+	c.Assert(maxRequests, check.Equals, 20)
+	weight, err := getter.GetFloat("weight")
+	c.Assert(err, check.IsNil)
+	c.Assert(weight, check.Equals, 0.5)
+	debug, err := getter.GetBool("debug")
+	c.Assert(err, check.IsNil)
+	c.Assert(debug, check.Equals, true)
+	useToken, err := getter.GetBool("use-token")
+	c.Assert(err, check.IsNil)
+	c.Assert(useToken, check.Equals, true)
+	headers, err := getter.Get("headers")
+	c.Assert(err, check.IsNil)
+	c.Assert(headers, check.DeepEquals, map[interface{}]interface{}{"X-Key": "value"})
+	header, err := getter.GetString("headers:X-Key")
+	c.Assert(err, check.IsNil)
+	c.Assert(header, check.Equals, "value")
+	_, err = getter.GetString("domain")
+	c.Assert(err, check.DeepEquals, config.ErrKeyNotFound{Key: "domain"})
+	_, err = getter.GetString("api-url:other")
+	c.Assert(err, check.DeepEquals, config.ErrKeyNotFound{Key: "api-url:other"})
+	_, err = getter.GetInt("api-url")
+	c.Assert(err, check.ErrorMatches, `value for the key "api-url" is not a int`)
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/servicemanager"
+	"github.com/tsuru/tsuru/storage"
+	routerTypes "github.com/tsuru/tsuru/types/router"
+	"github.com/tsuru/tsuru/validation"
+)
+
+type dynamicRouterService struct {
+	storage routerTypes.DynamicRouterStorage
+}
+
+var _ routerTypes.DynamicRouterService = &dynamicRouterService{}
+
+func DynamicRouterStorage() (routerTypes.DynamicRouterStorage, error) {
+	dbDriver, err := storage.GetCurrentDbDriver()
+	if err != nil {
+		dbDriver, err = storage.GetDefaultDbDriver()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dbDriver.DynamicRouterStorage, nil
+}
+
+func DynamicRouterService() (routerTypes.DynamicRouterService, error) {
+	storage, err := DynamicRouterStorage()
+	if err != nil {
+		return nil, err
+	}
+	return &dynamicRouterService{
+		storage: storage,
+	}, nil
+}
+
+func (s *dynamicRouterService) Create(r routerTypes.DynamicRouter) error {
+	err := s.validate(r, true)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.Get(r.Name)
+	if err == nil {
+		return routerTypes.ErrDynamicRouterAlreadyExists
+	}
+	if err != routerTypes.ErrDynamicRouterNotFound {
+		return err
+	}
+	return s.storage.Save(r)
+}
+
+func (s *dynamicRouterService) Update(r routerTypes.DynamicRouter) error {
+	err := s.validate(r, false)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.Get(r.Name)
+	if err != nil {
+		return err
+	}
+	return s.storage.Save(r)
+}
+
+func (s *dynamicRouterService) Get(name string) (*routerTypes.DynamicRouter, error) {
+	return s.storage.Get(name)
+}
+
+func (s *dynamicRouterService) List() ([]routerTypes.DynamicRouter, error) {
+	return s.storage.List()
+}
+
+func (s *dynamicRouterService) Remove(name string) error {
+	return s.storage.Remove(name)
+}
+
+// validate checks the router definition and tries to create the router from
+// it, so invalid settings are reported on registration instead of on first
+// use.
+func (s *dynamicRouterService) validate(r routerTypes.DynamicRouter, isNew bool) error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: "router name is mandatory"})
+	}
+	if isNew && !validation.ValidateName(r.Name) {
+		msg := "Invalid router name, router name should have at most 40 " +
+			"characters, containing only lower case letters, numbers or dashes, " +
+			"starting with a letter."
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: msg})
+	}
+	if _, err := config.Get("routers:" + r.Name); err == nil {
+		return errors.WithStack(&tsuruErrors.ValidationError{
+			Message: fmt.Sprintf("router %q is already defined in the config file", r.Name),
+		})
+	}
+	if r.Type == "" {
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: "router type is mandatory"})
+	}
+	factory, ok := routers[r.Type]
+	if !ok {
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: fmt.Sprintf("unknown router type: %q", r.Type)})
+	}
+	_, err := factory(r.Name, &dynamicConfigGetter{router: r})
+	if err != nil {
+		return errors.WithStack(&tsuruErrors.ValidationError{Message: fmt.Sprintf("invalid router config: %v", err)})
+	}
+	return nil
+}
+
+func getDynamicRouter(name string) (*routerTypes.DynamicRouter, error) {
+	if servicemanager.DynamicRouter == nil {
+		return nil, routerTypes.ErrDynamicRouterNotFound
+	}
+	return servicemanager.DynamicRouter.Get(name)
+}
+
+func listDynamicRouters() ([]routerTypes.DynamicRouter, error) {
+	if servicemanager.DynamicRouter == nil {
+		return nil, nil
+	}
+	return servicemanager.DynamicRouter.List()
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package router
+
+import (
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	routerTypes "github.com/tsuru/tsuru/types/router"
+	check "gopkg.in/check.v1"
+)
+
+type fakeDynamicRouterStorage struct {
+	routers map[string]routerTypes.DynamicRouter
+}
+
+func (f *fakeDynamicRouterStorage) Save(r routerTypes.DynamicRouter) error {
+	f.routers[r.Name] = r
+	return nil
+}
+
+func (f *fakeDynamicRouterStorage) Get(name string) (*routerTypes.DynamicRouter, error) {
+	r, ok := f.routers[name]
+	if !ok {
+		return nil, routerTypes.ErrDynamicRouterNotFound
+	}
+	return &r, nil
+}
+
+func (f *fakeDynamicRouterStorage) List() ([]routerTypes.DynamicRouter, error) {
+	var result []routerTypes.DynamicRouter
+	for _, r := range f.routers {
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (f *fakeDynamicRouterStorage) Remove(name string) error {
+	if _, ok := f.routers[name]; !ok {
+		return routerTypes.ErrDynamicRouterNotFound
+	}
+	delete(f.routers, name)
+	return nil
+}
+
+func (s *S) TestGetDynamicRouter(c *check.C) {
+	var urls []string
+	Register("myrouter", func(name string, cfg ConfigGetter) (Router, error) {
+		url, err := cfg.GetString("api-url")
+		urls = append(urls, url)
+		return &testInfoRouter{}, err
+	})
+	s.mockService.DynamicRouter.OnGet = func(name string) (*routerTypes.DynamicRouter, error) {
+		if name != "dyn" {
+			return nil, routerTypes.ErrDynamicRouterNotFound
+		}
+		return &routerTypes.DynamicRouter{
+			Name:   "dyn",
+			Type:   "myrouter",
+			Config: map[string]interface{}{"api-url": "http://router.io"},
+		}, nil
+	}
+	r, err := Get("dyn")
+	c.Assert(err, check.IsNil)
+	c.Assert(r, check.FitsTypeOf, &testInfoRouter{})
+	c.Assert(urls, check.DeepEquals, []string{"http://router.io"})
+	rType, prefix, err := Type("dyn")
+	c.Assert(err, check.IsNil)
+	c.Assert(rType, check.Equals, "myrouter")
+	c.Assert(prefix, check.Equals, "")
+	_, err = Get("other")
+	c.Assert(err, check.DeepEquals, &ErrRouterNotFound{Name: "other"})
+}
+
+func (s *S) TestGetConfigRouterOverDynamicRouter(c *check.C) {
+	var prefixes []string
+	Register("myrouter", func(name string, cfg ConfigGetter) (Router, error) {
+		prefixes = append(prefixes, cfg.(*StaticConfigGetter).Prefix)
+		return &testInfoRouter{}, nil
+	})
+	config.Set("routers:dyn:type", "myrouter")
+	defer config.Unset("routers:dyn")
+	s.mockService.DynamicRouter.OnGet = func(name string) (*routerTypes.DynamicRouter, error) {
+		c.Fatalf("unexpected call to dynamic router service")
+		return nil, nil
+	}
+	_, err := Get("dyn")
+	c.Assert(err, check.IsNil)
+	c.Assert(prefixes, check.DeepEquals, []string{"routers:dyn"})
+}
+
+func (s *S) TestListWithDynamicRouters(c *check.C) {
+	config.Set("routers:router1:type", "foo")
+	config.Set("routers:router2:type", "bar")
+	config.Set("routers:router2:default", true)
+	defer config.Unset("routers")
+	s.mockService.DynamicRouter.OnList = func() ([]routerTypes.DynamicRouter, error) {
+		return []routerTypes.DynamicRouter{
+			{Name: "dyn1", Type: "foo", Default: true},
+			{Name: "router1", Type: "bar"},
+		}, nil
+	}
+	routers, err := List()
+	c.Assert(err, check.IsNil)
+	c.Assert(routers, check.DeepEquals, []PlanRouter{
+		{Name: "router1", Type: "foo"},
+		{Name: "router2", Type: "bar", Default: true},
+		{Name: "dyn1", Type: "foo", Default: true, Dynamic: true},
+	})
+	d, err := Default()
+	c.Assert(err, check.IsNil)
+	c.Assert(d, check.Equals, "router2")
+}
+
+func (s *S) TestDefaultDynamicRouter(c *check.C) {
+	config.Set("routers:router1:type", "foo")
+	defer config.Unset("routers")
+	s.mockService.DynamicRouter.OnList = func() ([]routerTypes.DynamicRouter, error) {
+		return []routerTypes.DynamicRouter{{Name: "dyn1", Type: "foo", Default: true}}, nil
+	}
+	d, err := Default()
+	c.Assert(err, check.IsNil)
+	c.Assert(d, check.Equals, "dyn1")
+}
+
+func (s *S) TestDynamicRouterServiceCreate(c *check.C) {
+	Register("myrouter", func(name string, cfg ConfigGetter) (Router, error) {
+		_, err := cfg.GetString("api-url")
+		return &testInfoRouter{}, err
+	})
+	storage := &fakeDynamicRouterStorage{routers: map[string]routerTypes.DynamicRouter{}}
+	svc := &dynamicRouterService{storage: storage}
+	dr := routerTypes.DynamicRouter{
+		Name:   "dyn",
+		Type:   "myrouter",
+		Config: map[string]interface{}{"api-url": "http://router.io"},
+	}
+	err := svc.Create(dr)
+	c.Assert(err, check.IsNil)
+	c.Assert(storage.routers, check.DeepEquals, map[string]routerTypes.DynamicRouter{"dyn": dr})
+	err = svc.Create(dr)
+	c.Assert(err, check.Equals, routerTypes.ErrDynamicRouterAlreadyExists)
+}
+
+func (s *S) TestDynamicRouterServiceCreateValidation(c *check.C) {
+	Register("myrouter", func(name string, cfg ConfigGetter) (Router, error) {
+		_, err := cfg.GetString("api-url")
+		return &testInfoRouter{}, err
+	})
+	config.Set("routers:static:type", "myrouter")
+	defer config.Unset("routers")
+	svc := &dynamicRouterService{storage: &fakeDynamicRouterStorage{routers: map[string]routerTypes.DynamicRouter{}}}
+	tests := []struct {
+		router routerTypes.DynamicRouter
+		msg    string
+	}{
+		{routerTypes.DynamicRouter{Type: "myrouter"}, "router name is mandatory"},
+		{routerTypes.DynamicRouter{Name: "My_Router", Type: "myrouter"}, "Invalid router name.*"},
+		{routerTypes.DynamicRouter{Name: "static", Type: "myrouter"}, `router "static" is already defined in the config file`},
+		{routerTypes.DynamicRouter{Name: "dyn"}, "router type is mandatory"},
+		{routerTypes.DynamicRouter{Name: "dyn", Type: "unknown"}, `unknown router type: "unknown"`},
+		{routerTypes.DynamicRouter{Name: "dyn", Type: "myrouter"}, `invalid router config: key "api-url" not found`},
+	}
+	for _, tt := range tests {
+		err := svc.Create(tt.router)
+		c.Assert(err, check.ErrorMatches, tt.msg)
+		_, isValidation := errors.Cause(err).(*tsuruErrors.ValidationError)
+		c.Assert(isValidation, check.Equals, true)
+	}
+}
+
+func (s *S) TestDynamicRouterServiceUpdateNotFound(c *check.C) {
+	Register("myrouter", func(name string, cfg ConfigGetter) (Router, error) {
+		return &testInfoRouter{}, nil
+	})
+	svc := &dynamicRouterService{storage: &fakeDynamicRouterStorage{routers: map[string]routerTypes.DynamicRouter{}}}
+	err := svc.Update(routerTypes.DynamicRouter{Name: "dyn", Type: "myrouter"})
+	c.Assert(err, check.Equals, routerTypes.ErrDynamicRouterNotFound)
+}
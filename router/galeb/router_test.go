@@ -22,6 +22,7 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/dbtest"
+	"github.com/tsuru/tsuru/router"
 	galebClient "github.com/tsuru/tsuru/router/galeb/client"
 	"github.com/tsuru/tsuru/router/routertest"
 	check "gopkg.in/check.v1"
@@ -378,7 +379,7 @@ func init() {
 		c.Assert(err, check.IsNil)
 		server = httptest.NewServer(fakeServer)
 		config.Set("routers:galeb:api-url", server.URL+"/api")
-		gRouter, err := createRouter("galeb", "routers:galeb")
+		gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 		c.Assert(err, check.IsNil)
 		suite.Router = gRouter
 		conn, err := db.Conn()
@@ -434,7 +435,7 @@ func (s *S) TestCreateRouterConcurrent(c *check.C) {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			r, err := createRouter("rx", prefixes[i%len(prefixes)])
+			r, err := createRouter("rx", &router.StaticConfigGetter{Prefix: prefixes[i%len(prefixes)]})
 			c.Assert(err, check.IsNil)
 			mu.Lock()
 			routers = append(routers, r.(*galebRouter))
@@ -459,7 +460,7 @@ func (s *S) TestAddBackendPartialFailure(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	fakeServer.prepareError("PATCH", "/api/rule/3/parents", "error on SetRuleVirtualHostIDs")
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
@@ -477,7 +478,7 @@ func (s *S) TestAddBackendPartialFailureExisting(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -496,7 +497,7 @@ func (s *S) TestAddBackendPartialFailureInFirstResource(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	fakeServer.prepareError("POST", "/api/pool", "error in pool create")
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
@@ -514,7 +515,7 @@ func (s *S) TestAddBackendPartialFailureInFirstResourceExisting(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -533,7 +534,7 @@ func (s *S) TestRouteAddRoutesPartialFailure(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/tsuru/config"
 	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/hc"
 	"github.com/tsuru/tsuru/log"
@@ -24,60 +23,78 @@ const routerType = "galebv2"
 
 var _ router.AsyncRouter = &galebRouter{}
 
+// clientOpts holds the settings of a galeb client, clients are shared by
+// routers with the same settings.
+type clientOpts struct {
+	apiURL        string
+	username      string
+	password      string
+	tokenHeader   string
+	useToken      bool
+	environment   string
+	project       string
+	balancePolicy string
+	ruleType      string
+	debug         bool
+	waitTimeout   time.Duration
+	maxRequests   int
+}
+
 var clientCache struct {
 	sync.Mutex
-	cache map[string]*galebClient.GalebClient
+	cache map[clientOpts]*galebClient.GalebClient
 }
 
-func getClient(configPrefix string) (*galebClient.GalebClient, error) {
-	clientCache.Lock()
-	defer clientCache.Unlock()
-	if clientCache.cache == nil {
-		clientCache.cache = map[string]*galebClient.GalebClient{}
-	}
-	if clientCache.cache[configPrefix] != nil {
-		return clientCache.cache[configPrefix], nil
-	}
-	apiURL, err := config.GetString(configPrefix + ":api-url")
+func getClient(config router.ConfigGetter) (*galebClient.GalebClient, error) {
+	apiURL, err := config.GetString("api-url")
 	if err != nil {
 		return nil, err
 	}
-	username, _ := config.GetString(configPrefix + ":username")
-	password, _ := config.GetString(configPrefix + ":password")
-	tokenHeader, _ := config.GetString(configPrefix + ":token-header")
-	useToken, _ := config.GetBool(configPrefix + ":use-token")
-	environment, _ := config.GetString(configPrefix + ":environment")
-	project, _ := config.GetString(configPrefix + ":project")
-	balancePolicy, _ := config.GetString(configPrefix + ":balance-policy")
-	ruleType, _ := config.GetString(configPrefix + ":rule-type")
-	debug, _ := config.GetBool(configPrefix + ":debug")
-	waitTimeoutSec, err := config.GetInt(configPrefix + ":wait-timeout")
+	opts := clientOpts{apiURL: apiURL}
+	opts.username, _ = config.GetString("username")
+	opts.password, _ = config.GetString("password")
+	opts.tokenHeader, _ = config.GetString("token-header")
+	opts.useToken, _ = config.GetBool("use-token")
+	opts.environment, _ = config.GetString("environment")
+	opts.project, _ = config.GetString("project")
+	opts.balancePolicy, _ = config.GetString("balance-policy")
+	opts.ruleType, _ = config.GetString("rule-type")
+	opts.debug, _ = config.GetBool("debug")
+	waitTimeoutSec, err := config.GetInt("wait-timeout")
 	if err != nil {
 		waitTimeoutSec = 10 * 60
 	}
-	maxRequests, _ := config.GetInt(configPrefix + ":max-requests")
+	opts.waitTimeout = time.Duration(waitTimeoutSec) * time.Second
+	opts.maxRequests, _ = config.GetInt("max-requests")
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	if clientCache.cache == nil {
+		clientCache.cache = map[clientOpts]*galebClient.GalebClient{}
+	}
+	if clientCache.cache[opts] != nil {
+		return clientCache.cache[opts], nil
+	}
 	client := &galebClient.GalebClient{
-		ApiURL:        apiURL,
-		Username:      username,
-		Password:      password,
-		UseToken:      useToken,
-		TokenHeader:   tokenHeader,
-		Environment:   environment,
-		Project:       project,
-		BalancePolicy: balancePolicy,
-		RuleType:      ruleType,
-		WaitTimeout:   time.Duration(waitTimeoutSec) * time.Second,
-		Debug:         debug,
-		MaxRequests:   maxRequests,
-	}
-	clientCache.cache[configPrefix] = client
+		ApiURL:        opts.apiURL,
+		Username:      opts.username,
+		Password:      opts.password,
+		UseToken:      opts.useToken,
+		TokenHeader:   opts.tokenHeader,
+		Environment:   opts.environment,
+		Project:       opts.project,
+		BalancePolicy: opts.balancePolicy,
+		RuleType:      opts.ruleType,
+		WaitTimeout:   opts.waitTimeout,
+		Debug:         opts.debug,
+		MaxRequests:   opts.maxRequests,
+	}
+	clientCache.cache[opts] = client
 	return client, nil
 }
 
 type galebRouter struct {
 	client     *galebClient.GalebClient
 	domain     string
-	prefix     string
 	routerName string
 }
 
@@ -86,19 +103,18 @@ func init() {
 	hc.AddChecker("Router galeb", router.BuildHealthCheck(routerType))
 }
 
-func createRouter(routerName, configPrefix string) (router.Router, error) {
-	domain, err := config.GetString(configPrefix + ":domain")
+func createRouter(routerName string, config router.ConfigGetter) (router.Router, error) {
+	domain, err := config.GetString("domain")
 	if err != nil {
 		return nil, err
 	}
-	client, err := getClient(configPrefix)
+	client, err := getClient(config)
 	if err != nil {
 		return nil, err
 	}
 	r := galebRouter{
 		client:     client,
 		domain:     domain,
-		prefix:     configPrefix,
 		routerName: routerName,
 	}
 	return &r, nil
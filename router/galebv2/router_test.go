@@ -368,7 +368,7 @@ func init() {
 		c.Assert(err, check.IsNil)
 		server = httptest.NewServer(fakeServer)
 		config.Set("routers:galeb:api-url", server.URL+"/api")
-		gRouter, err := createRouter("galeb", "routers:galeb")
+		gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 		c.Assert(err, check.IsNil)
 		suite.Router = gRouter
 		conn, err := db.Conn()
@@ -424,7 +424,7 @@ func (s *S) TestCreateRouterConcurrent(c *check.C) {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			r, err := createRouter("rx", prefixes[i%len(prefixes)])
+			r, err := createRouter("rx", &router.StaticConfigGetter{Prefix: prefixes[i%len(prefixes)]})
 			c.Assert(err, check.IsNil)
 			mu.Lock()
 			routers = append(routers, r.(*galebRouter))
@@ -449,7 +449,7 @@ func (s *S) TestAddBackendPartialFailure(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	fakeServer.prepareError("POST", "/api/rule", "error on AddRuleToPool")
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
@@ -467,7 +467,7 @@ func (s *S) TestAddBackendPartialFailureExisting(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -486,7 +486,7 @@ func (s *S) TestAddBackendPartialFailureInFirstResource(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	fakeServer.prepareError("POST", "/api/pool", "error in pool create")
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
@@ -504,7 +504,7 @@ func (s *S) TestAddBackendPartialFailureInFirstResourceExisting(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -523,7 +523,7 @@ func (s *S) TestRouteAddRoutesPartialFailure(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -547,7 +547,7 @@ func (s *S) TestSetHealthcheck(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
@@ -566,7 +566,7 @@ func (s *S) TestSetHealthcheckTCPOnly(c *check.C) {
 	server := httptest.NewServer(fakeServer)
 	defer server.Close()
 	config.Set("routers:galeb:api-url", server.URL+"/api")
-	gRouter, err := createRouter("galeb", "routers:galeb")
+	gRouter, err := createRouter("galeb", &router.StaticConfigGetter{Prefix: "routers:galeb"})
 	c.Assert(err, check.IsNil)
 	err = gRouter.AddBackend(routertest.FakeApp{Name: "backend1"})
 	c.Assert(err, check.IsNil)
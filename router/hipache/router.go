@@ -44,12 +44,31 @@ func init() {
 	hc.AddChecker("Router Planb", router.BuildHealthCheck("planb"))
 }
 
-func createHipacheRouter(routerName, configPrefix string) (router.Router, error) {
-	return &hipacheRouter{prefix: configPrefix, routerName: routerName}, nil
+func createHipacheRouter(routerName string, config router.ConfigGetter) (router.Router, error) {
+	prefix, err := configPrefix(config)
+	if err != nil {
+		return nil, err
+	}
+	return &hipacheRouter{prefix: prefix, routerName: routerName}, nil
 }
 
-func createPlanbRouter(routerName, configPrefix string) (router.Router, error) {
-	return &planbRouter{hipacheRouter{prefix: configPrefix, routerName: routerName}}, nil
+func createPlanbRouter(routerName string, config router.ConfigGetter) (router.Router, error) {
+	prefix, err := configPrefix(config)
+	if err != nil {
+		return nil, err
+	}
+	return &planbRouter{hipacheRouter{prefix: prefix, routerName: routerName}}, nil
+}
+
+// configPrefix returns the config file prefix of the router, the redis
+// clients are shared by prefix so these routers can't be registered through
+// the API.
+func configPrefix(config router.ConfigGetter) (string, error) {
+	static, ok := config.(*router.StaticConfigGetter)
+	if !ok {
+		return "", errors.New("hipache and planb routers must be defined in the config file")
+	}
+	return static.Prefix, nil
 }
 
 func (r *hipacheRouter) connect() (tsuruRedis.Client, error) {
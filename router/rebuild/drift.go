@@ -0,0 +1,392 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rebuild
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/lease"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/router"
+	"github.com/tsuru/tsuru/servicemanager"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+const (
+	driftReportID         = "latest"
+	defaultDriftInterval  = 10 * time.Minute
+	driftTypeMissing      = "missing"
+	driftTypeStale        = "stale"
+	driftReportCollection = "router_drift"
+	driftLeaseName        = "router-drift"
+)
+
+var (
+	driftBackends = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tsuru_router_drift_backends",
+		Help: "The number of app backends differing from the expected state in the last drift check.",
+	}, []string{"router"})
+
+	driftRoutes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tsuru_router_drift_routes",
+		Help: "The number of missing and stale routes found in the last drift check.",
+	}, []string{"router", "type"})
+
+	driftCNames = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tsuru_router_drift_cnames",
+		Help: "The number of missing and stale cnames found in the last drift check.",
+	}, []string{"router", "type"})
+
+	driftRepairs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tsuru_router_drift_repairs_total",
+		Help: "The total number of app backends repaired by the drift checker.",
+	}, []string{"router"})
+
+	driftErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tsuru_router_drift_errors_total",
+		Help: "The total number of errors checking app backends for drift.",
+	}, []string{"router"})
+
+	driftDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tsuru_router_drift_last_duration",
+		Help: "The duration of the last drift check.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(driftBackends, driftRoutes, driftCNames, driftRepairs, driftErrors, driftDuration)
+}
+
+// Drift describes how the backend of an app in a router differs from what
+// tsuru expects it to be.
+type Drift struct {
+	App            string   `json:"app"`
+	Router         string   `json:"router"`
+	MissingBackend bool     `json:"missingBackend,omitempty"`
+	MissingRoutes  []string `json:"missingRoutes,omitempty"`
+	StaleRoutes    []string `json:"staleRoutes,omitempty"`
+	MissingCNames  []string `json:"missingCNames,omitempty"`
+	StaleCNames    []string `json:"staleCNames,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	Repaired       bool     `json:"repaired,omitempty"`
+	RepairError    string   `json:"repairError,omitempty"`
+}
+
+func (d *Drift) hasChanges() bool {
+	return d.MissingBackend || len(d.MissingRoutes) > 0 || len(d.StaleRoutes) > 0 ||
+		len(d.MissingCNames) > 0 || len(d.StaleCNames) > 0
+}
+
+// DriftReport is the result of checking every app for drift. Only backends
+// with differences or errors are listed in Drifts.
+type DriftReport struct {
+	ID          string    `json:"-" bson:"_id"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+	CheckedApps int       `json:"checkedApps"`
+	AutoRepair  bool      `json:"autoRepair"`
+	Drifts      []Drift   `json:"drifts"`
+}
+
+// CheckDrift compares the routes and cnames of the app in each of its
+// routers with the expected ones, without changing anything. Only routers
+// where differences or errors are found are returned.
+func CheckDrift(app RebuildApp) []Drift {
+	var drifts []Drift
+	addresses, addrErr := app.RoutableAddresses()
+	for _, appRouter := range app.GetRouters() {
+		drift := Drift{App: app.GetName(), Router: appRouter.Name}
+		if addrErr != nil {
+			drift.Error = addrErr.Error()
+		} else if err := checkDriftInRouter(app, appRouter, addresses, &drift); err != nil {
+			drift.Error = err.Error()
+		}
+		if drift.Error != "" || drift.hasChanges() {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
+
+func checkDriftInRouter(app RebuildApp, appRouter appTypes.AppRouter, addresses []url.URL, drift *Drift) error {
+	r, err := router.Get(appRouter.Name)
+	if err != nil {
+		return err
+	}
+	routes, err := r.Routes(app.GetName())
+	if err == router.ErrBackendNotFound {
+		drift.MissingBackend = true
+		for _, addr := range addresses {
+			drift.MissingRoutes = append(drift.MissingRoutes, addr.String())
+		}
+		drift.MissingCNames = append(drift.MissingCNames, app.GetCname()...)
+		sort.Strings(drift.MissingRoutes)
+		sort.Strings(drift.MissingCNames)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	toAdd, toRemove := diffRoutes(routes, addresses)
+	drift.MissingRoutes = urlStrings(toAdd, false)
+	drift.StaleRoutes = urlStrings(toRemove, false)
+	cnameRouter, ok := r.(router.CNameRouter)
+	if !ok {
+		return nil
+	}
+	cnames, err := cnameRouter.CNames(app.GetName())
+	if err != nil {
+		return err
+	}
+	appCnames := app.GetCname()
+	cnameAddrs := make([]url.URL, len(appCnames))
+	for i, cname := range appCnames {
+		cnameAddrs[i] = url.URL{Host: cname}
+	}
+	toAdd, toRemove = diffRoutes(cnames, cnameAddrs)
+	drift.MissingCNames = urlStrings(toAdd, true)
+	drift.StaleCNames = urlStrings(toRemove, true)
+	return nil
+}
+
+func urlStrings(urls []*url.URL, hostOnly bool) []string {
+	var result []string
+	for _, u := range urls {
+		if hostOnly {
+			result = append(result, u.Host)
+		} else {
+			result = append(result, u.String())
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// GetDriftReport returns the report of the last drift check, or nil if no
+// check has finished yet.
+func GetDriftReport() (*DriftReport, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var report DriftReport
+	err = conn.Collection(driftReportCollection).FindId(driftReportID).One(&report)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func saveDriftReport(report *DriftReport) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	report.ID = driftReportID
+	_, err = conn.Collection(driftReportCollection).UpsertId(report.ID, report)
+	return err
+}
+
+// InitializeDriftChecker starts checking the routers of every app for drift
+// periodically, if enabled in the router-drift config. Only one tsuru api
+// instance runs the checks at a time, the leader is elected using a lease
+// stored in the database.
+func InitializeDriftChecker(appLister func() ([]RebuildApp, error)) error {
+	enabled, _ := config.GetBool("router-drift:enabled")
+	if !enabled {
+		return nil
+	}
+	instance, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return err
+	}
+	interval, _ := config.GetDuration("router-drift:interval")
+	autoRepair, _ := config.GetBool("router-drift:auto-repair")
+	checker := &driftChecker{
+		interval:   interval,
+		autoRepair: autoRepair,
+		appLister:  appLister,
+		instance:   instance.Name,
+	}
+	err = checker.start()
+	if err != nil {
+		return err
+	}
+	shutdown.Register(checker)
+	return nil
+}
+
+type driftChecker struct {
+	interval   time.Duration
+	autoRepair bool
+	appLister  func() ([]RebuildApp, error)
+	instance   string
+
+	started  bool
+	shutdown chan struct{}
+	done     chan struct{}
+}
+
+// start starts the drift checks on a different goroutine
+func (d *driftChecker) start() error {
+	if d.started {
+		return errors.New("drift checker already started")
+	}
+	if d.appLister == nil {
+		return errors.New("must set app lister function")
+	}
+	if d.interval <= 0 {
+		d.interval = defaultDriftInterval
+	}
+	d.shutdown = make(chan struct{}, 1)
+	d.done = make(chan struct{})
+	d.started = true
+	log.Debugf("[router-drift] starting. Running every %s.", d.interval)
+	go func(wait time.Duration) {
+		for {
+			select {
+			case <-time.After(wait):
+				err := d.run()
+				if err != nil {
+					log.Errorf("[router-drift] %v", err)
+				}
+				wait = d.interval
+			case <-d.shutdown:
+				d.done <- struct{}{}
+				return
+			}
+		}
+	}(time.Millisecond * 100)
+	return nil
+}
+
+// Shutdown stops the drift checker, waiting for the current check to
+// complete
+func (d *driftChecker) Shutdown(ctx context.Context) error {
+	if !d.started {
+		return nil
+	}
+	d.shutdown <- struct{}{}
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+	}
+	d.started = false
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.lease().Release()
+}
+
+func (d *driftChecker) String() string {
+	return "router drift checker"
+}
+
+func (d *driftChecker) lease() *lease.Lease {
+	return &lease.Lease{Name: driftLeaseName, Owner: d.instance, Duration: 3 * d.interval}
+}
+
+func (d *driftChecker) run() error {
+	leader, err := d.lease().Acquire()
+	if err != nil {
+		return errors.Wrap(err, "unable to acquire leadership")
+	}
+	if !leader {
+		return nil
+	}
+	report := &DriftReport{StartedAt: time.Now().UTC(), AutoRepair: d.autoRepair}
+	defer func() {
+		driftDuration.Set(time.Since(report.StartedAt).Seconds())
+	}()
+	apps, err := d.appLister()
+	if err != nil {
+		return errors.Wrap(err, "error listing apps, aborting drift check")
+	}
+	renewedAt := time.Now()
+	for _, a := range apps {
+		// Checks with auto repair may take longer than the lease, it's
+		// renewed once per interval.
+		if time.Since(renewedAt) > d.interval {
+			leader, err = d.lease().Acquire()
+			if err != nil {
+				return errors.Wrap(err, "unable to renew leadership")
+			}
+			if !leader {
+				return errors.New("leadership lost, aborting drift check")
+			}
+			renewedAt = time.Now()
+		}
+		drifts := CheckDrift(a)
+		if d.autoRepair {
+			repairDrifts(a.GetName(), drifts)
+		}
+		report.Drifts = append(report.Drifts, drifts...)
+		report.CheckedApps++
+		if len(d.shutdown) > 0 {
+			break
+		}
+	}
+	report.FinishedAt = time.Now().UTC()
+	updateDriftMetrics(report)
+	log.Debugf("[router-drift] finished running. Checked %d apps, found %d drifts.", report.CheckedApps, len(report.Drifts))
+	return saveDriftReport(report)
+}
+
+// repairDrifts rebuilds the routes of the app when any of its routers has
+// drifted, holding the app lock like the rebuild task.
+func repairDrifts(appName string, drifts []Drift) {
+	var toRepair []int
+	for i := range drifts {
+		if drifts[i].Error == "" && drifts[i].hasChanges() {
+			toRepair = append(toRepair, i)
+		}
+	}
+	if len(toRepair) == 0 {
+		return
+	}
+	err := runRoutesRebuildOnce(appName, true)
+	for _, i := range toRepair {
+		if err != nil {
+			drifts[i].RepairError = err.Error()
+			continue
+		}
+		drifts[i].Repaired = true
+		driftRepairs.WithLabelValues(drifts[i].Router).Inc()
+	}
+	if err != nil {
+		log.Errorf("[router-drift] error repairing app %q: %v", appName, err)
+	}
+}
+
+func updateDriftMetrics(report *DriftReport) {
+	driftBackends.Reset()
+	driftRoutes.Reset()
+	driftCNames.Reset()
+	for _, drift := range report.Drifts {
+		if drift.Error != "" {
+			driftErrors.WithLabelValues(drift.Router).Inc()
+			continue
+		}
+		driftBackends.WithLabelValues(drift.Router).Inc()
+		driftRoutes.WithLabelValues(drift.Router, driftTypeMissing).Add(float64(len(drift.MissingRoutes)))
+		driftRoutes.WithLabelValues(drift.Router, driftTypeStale).Add(float64(len(drift.StaleRoutes)))
+		driftCNames.WithLabelValues(drift.Router, driftTypeMissing).Add(float64(len(drift.MissingCNames)))
+		driftCNames.WithLabelValues(drift.Router, driftTypeStale).Add(float64(len(drift.StaleCNames)))
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rebuild_test
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	"github.com/tsuru/tsuru/router"
+	"github.com/tsuru/tsuru/router/rebuild"
+	"github.com/tsuru/tsuru/router/routertest"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestCheckDrift(c *check.C) {
+	a := app.App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = provisiontest.ProvisionerInstance.AddUnits(&a, 3, "web", nil)
+	c.Assert(err, check.IsNil)
+	units, err := a.Units()
+	c.Assert(err, check.IsNil)
+	err = a.AddCName("my.cname.com", "other.cname.com")
+	c.Assert(err, check.IsNil)
+	routertest.FakeRouter.RemoveRoutes(a.Name, []*url.URL{units[2].Address})
+	routertest.FakeRouter.AddRoutes(a.Name, []*url.URL{{Scheme: "http", Host: "invalid:1234"}})
+	routertest.FakeRouter.UnsetCName("other.cname.com", a.Name)
+	routertest.FakeRouter.SetCName("stale.cname.com", a.Name)
+	drifts := rebuild.CheckDrift(&a)
+	c.Assert(drifts, check.DeepEquals, []rebuild.Drift{{
+		App:           a.Name,
+		Router:        "fake",
+		MissingRoutes: []string{units[2].Address.String()},
+		StaleRoutes:   []string{"http://invalid:1234"},
+		MissingCNames: []string{"other.cname.com"},
+		StaleCNames:   []string{"stale.cname.com"},
+	}})
+	c.Assert(routertest.FakeRouter.HasRoute(a.Name, units[2].Address.String()), check.Equals, false)
+	c.Assert(routertest.FakeRouter.HasRoute(a.Name, "http://invalid:1234"), check.Equals, true)
+	c.Assert(routertest.FakeRouter.HasCName("stale.cname.com"), check.Equals, true)
+}
+
+func (s *S) TestCheckDriftNoChanges(c *check.C) {
+	a := app.App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = provisiontest.ProvisionerInstance.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	err = a.AddCName("my.cname.com")
+	c.Assert(err, check.IsNil)
+	drifts := rebuild.CheckDrift(&a)
+	c.Assert(drifts, check.HasLen, 0)
+}
+
+func (s *S) TestCheckDriftMissingBackend(c *check.C) {
+	a := app.App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = provisiontest.ProvisionerInstance.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	units, err := a.Units()
+	c.Assert(err, check.IsNil)
+	err = routertest.FakeRouter.RemoveBackend(a.Name)
+	c.Assert(err, check.IsNil)
+	err = router.Remove(a.Name)
+	c.Assert(err, check.IsNil)
+	drifts := rebuild.CheckDrift(&a)
+	expectedRoutes := []string{units[0].Address.String(), units[1].Address.String()}
+	sort.Strings(expectedRoutes)
+	c.Assert(drifts, check.DeepEquals, []rebuild.Drift{{
+		App:            a.Name,
+		Router:         "fake",
+		MissingBackend: true,
+		MissingRoutes:  expectedRoutes,
+	}})
+}
+
+func (s *S) TestCheckDriftRouterError(c *check.C) {
+	a := app.App{Name: "my-test-app", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Routers = append(a.Routers, appTypes.AppRouter{Name: "unknown"})
+	drifts := rebuild.CheckDrift(&a)
+	c.Assert(drifts, check.DeepEquals, []rebuild.Drift{{
+		App:    a.Name,
+		Router: "unknown",
+		Error:  `router "unknown" not found`,
+	}})
+}
@@ -26,6 +26,7 @@ type RebuildApp interface {
 	GetRouters() []appTypes.AppRouter
 	GetHealthcheckData() (routerTypes.HealthcheckData, error)
 	RoutableAddresses() ([]url.URL, error)
+	GetTrafficWeights() []router.BackendWeight
 }
 
 func RebuildRoutes(app RebuildApp, dry bool) (map[string]RebuildRoutesResult, error) {
@@ -175,6 +176,14 @@ func rebuildRoutesInRouter(app RebuildApp, dry bool, appRouter appTypes.AppRoute
 	if err != nil {
 		return nil, err
 	}
+	if splitRouter, ok := r.(router.TrafficSplitRouter); ok {
+		if weights := app.GetTrafficWeights(); len(weights) > 0 {
+			err = splitRouter.SetBackendWeights(app.GetName(), weights)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	log.Debugf("[rebuild-routes] routes added for app %q: %s", app.GetName(), strings.Join(result.Added, ", "))
 	log.Debugf("[rebuild-routes] routes removed for app %q: %s", app.GetName(), strings.Join(result.Removed, ", "))
 	return &result, nil
@@ -12,8 +12,10 @@ import (
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/provision/provisiontest"
+	"github.com/tsuru/tsuru/router"
 	"github.com/tsuru/tsuru/router/rebuild"
 	"github.com/tsuru/tsuru/router/routertest"
+	appTypes "github.com/tsuru/tsuru/types/app"
 	routerTypes "github.com/tsuru/tsuru/types/router"
 	check "gopkg.in/check.v1"
 )
@@ -257,3 +259,25 @@ func (s *S) TestRebuildRoutesSetsHealthcheck(c *check.C) {
 	}
 	c.Assert(routertest.FakeRouter.GetHealthcheck("my-test-app"), check.DeepEquals, expected)
 }
+
+func (s *S) TestRebuildRoutesSetsTrafficWeights(c *check.C) {
+	routers := []appTypes.AppRouter{{Name: "fake-weights"}}
+	app1 := app.App{Name: "app1", TeamOwner: s.team.Name, Routers: routers}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := app.App{Name: "app2", TeamOwner: s.team.Name, Routers: routers}
+	err = app.CreateApp(&app2, s.user)
+	c.Assert(err, check.IsNil)
+	err = app1.SetTrafficWeights([]app.TrafficWeight{{App: "app1", Weight: 80}, {App: "app2", Weight: 20}})
+	c.Assert(err, check.IsNil)
+	err = routertest.WeightsRouter.SetBackendWeights(app1.Name, nil)
+	c.Assert(err, check.IsNil)
+	_, err = rebuild.RebuildRoutes(&app1, false)
+	c.Assert(err, check.IsNil)
+	weights, err := routertest.WeightsRouter.GetBackendWeights(app1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(weights, check.DeepEquals, []router.BackendWeight{
+		{Backend: "app1", Weight: 80},
+		{Backend: "app2", Weight: 20},
+	})
+}
@@ -45,6 +45,7 @@ func (s *S) SetUpSuite(c *check.C) {
 	config.Set("routers:fake:type", "fake")
 	config.Set("routers:fake:default", true)
 	config.Set("routers:fake-hc:type", "fake-hc")
+	config.Set("routers:fake-weights:type", "fake-weights")
 	config.Set("docker:router", "fake")
 	config.Set("auth:hash-cost", bcrypt.MinCost)
 	provision.DefaultProvisioner = "fake"
@@ -69,6 +70,7 @@ func (s *S) SetUpTest(c *check.C) {
 	})
 	c.Assert(err, check.IsNil)
 	routertest.FakeRouter.Reset()
+	routertest.WeightsRouter.Reset()
 	provisiontest.ProvisionerInstance.Reset()
 	err = dbtest.ClearAllCollections(s.conn.Apps().Database)
 	c.Assert(err, check.IsNil)
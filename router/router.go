@@ -19,10 +19,10 @@ import (
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/storage"
 	"github.com/tsuru/tsuru/log"
-	"github.com/tsuru/tsuru/types/router"
+	routerTypes "github.com/tsuru/tsuru/types/router"
 )
 
-type routerFactory func(routerName, configPrefix string) (Router, error)
+type routerFactory func(routerName string, config ConfigGetter) (Router, error)
 
 var (
 	ErrBackendExists         = errors.New("Backend already exists")
@@ -57,23 +57,45 @@ func Unregister(name string) {
 	delete(routers, name)
 }
 
+// Type returns the type of the named router and, for routers defined in the
+// config file, the prefix of its settings. Routers registered through the API
+// have an empty prefix.
 func Type(name string) (string, string, error) {
-	prefix := "routers:" + name
-	routerType, err := config.GetString(prefix + ":type")
+	routerType, cfg, err := configFor(name)
 	if err != nil {
-		msg := fmt.Sprintf("config key '%s:type' not found", prefix)
-		if name != "hipache" {
-			return "", "", errors.New(msg)
-		}
-		log.Errorf("WARNING: %s, fallback to top level '%s:*' router config", msg, name)
-		return name, name, nil
+		return "", "", err
+	}
+	var prefix string
+	if static, ok := cfg.(*StaticConfigGetter); ok {
+		prefix = static.Prefix
 	}
 	return routerType, prefix, nil
 }
 
+func configFor(name string) (string, ConfigGetter, error) {
+	prefix := "routers:" + name
+	routerType, err := config.GetString(prefix + ":type")
+	if err == nil {
+		return routerType, &StaticConfigGetter{Prefix: prefix}, nil
+	}
+	msg := fmt.Sprintf("config key '%s:type' not found", prefix)
+	dynamicRouter, dynErr := getDynamicRouter(name)
+	if dynErr == nil {
+		return dynamicRouter.Type, &dynamicConfigGetter{router: *dynamicRouter}, nil
+	}
+	if dynErr != routerTypes.ErrDynamicRouterNotFound {
+		return "", nil, dynErr
+	}
+	if name != "hipache" {
+		return "", nil, errors.New(msg)
+	}
+	log.Errorf("WARNING: %s, fallback to top level '%s:*' router config", msg, name)
+	return name, &StaticConfigGetter{Prefix: name}, nil
+}
+
 // Get gets the named router from the registry.
 func Get(name string) (Router, error) {
-	routerType, prefix, err := Type(name)
+	routerType, cfg, err := configFor(name)
 	if err != nil {
 		return nil, &ErrRouterNotFound{Name: name}
 	}
@@ -81,14 +103,15 @@ func Get(name string) (Router, error) {
 	if !ok {
 		return nil, errors.Errorf("unknown router: %q.", routerType)
 	}
-	r, err := factory(name, prefix)
+	r, err := factory(name, cfg)
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
-// Default returns the default router
+// Default returns the default router. Routers defined in the config file take
+// precedence over the ones registered through the API.
 func Default() (string, error) {
 	plans, err := List()
 	if err != nil {
@@ -149,7 +172,7 @@ type MessageRouter interface {
 }
 
 type CustomHealthcheckRouter interface {
-	SetHealthcheck(name string, data router.HealthcheckData) error
+	SetHealthcheck(name string, data routerTypes.HealthcheckData) error
 }
 
 type HealthChecker interface {
@@ -169,6 +192,29 @@ type TLSRouter interface {
 	GetCertificate(app App, cname string) (string, error)
 }
 
+// ACMERouter is a router able to answer ACME HTTP-01 challenges, serving the
+// key authorization in the /.well-known/acme-challenge/{token} path of a
+// cname of the app.
+type ACMERouter interface {
+	AddACMEChallenge(app App, cname, token, keyAuthorization string) error
+	RemoveACMEChallenge(app App, cname, token string) error
+}
+
+// BackendWeight is the share of the traffic of a backend, in percent, sent
+// to the backend of an app.
+type BackendWeight struct {
+	Backend string `json:"backend"`
+	Weight  int    `json:"weight"`
+}
+
+// TrafficSplitRouter is a router able to split the traffic of a backend,
+// including its cnames, between the backends of different apps. Setting no
+// weights sends all the traffic back to the backend itself.
+type TrafficSplitRouter interface {
+	SetBackendWeights(name string, weights []BackendWeight) error
+	GetBackendWeights(name string) ([]BackendWeight, error)
+}
+
 type InfoRouter interface {
 	GetInfo() (map[string]string, error)
 }
@@ -394,6 +440,8 @@ type PlanRouter struct {
 	Type    string            `json:"type"`
 	Info    map[string]string `json:"info"`
 	Default bool              `json:"default"`
+	// Dynamic is true for routers registered through the API.
+	Dynamic bool `json:"dynamic"`
 }
 
 func ListWithInfo() ([]PlanRouter, error) {
@@ -453,6 +501,21 @@ func List() ([]PlanRouter, error) {
 			Default: defaultFlag,
 		})
 	}
+	dynamicRouters, err := listDynamicRouters()
+	if err != nil {
+		return nil, err
+	}
+	for _, dr := range dynamicRouters {
+		if _, inConfig := routers[dr.Name]; inConfig {
+			continue
+		}
+		routersList = append(routersList, PlanRouter{
+			Name:    dr.Name,
+			Type:    dr.Type,
+			Default: dr.Default,
+			Dynamic: true,
+		})
+	}
 	return routersList, nil
 }
 
@@ -15,9 +15,9 @@ func (s *S) TestRegisterAndGet(c *check.C) {
 	var r Router
 	var prefixes []string
 	var names []string
-	routerCreator := func(name, prefix string) (Router, error) {
+	routerCreator := func(name string, cfg ConfigGetter) (Router, error) {
 		names = append(names, name)
-		prefixes = append(prefixes, prefix)
+		prefixes = append(prefixes, cfg.(*StaticConfigGetter).Prefix)
 		return r, nil
 	}
 	Register("router", routerCreator)
@@ -64,9 +64,9 @@ func (s *S) TestRegisterAndTypeSpecialCase(c *check.C) {
 func (s *S) TestRegisterAndGetCustomNamedRouter(c *check.C) {
 	var names []string
 	var prefixes []string
-	routerCreator := func(name, prefix string) (Router, error) {
+	routerCreator := func(name string, cfg ConfigGetter) (Router, error) {
 		names = append(names, name)
-		prefixes = append(prefixes, prefix)
+		prefixes = append(prefixes, cfg.(*StaticConfigGetter).Prefix)
 		var r Router
 		return r, nil
 	}
@@ -274,7 +274,7 @@ func (s *S) TestListWithInfo(c *check.C) {
 	config.Set("routers:router2:default", true)
 	defer config.Unset("routers:router1")
 	defer config.Unset("routers:router2")
-	fooCreator := func(name, prefix string) (Router, error) {
+	fooCreator := func(name string, cfg ConfigGetter) (Router, error) {
 		return &testInfoRouter{}, nil
 	}
 	Register("foo", fooCreator)
@@ -294,10 +294,10 @@ func (s *S) TestListWithInfoError(c *check.C) {
 	config.Set("routers:router2:default", true)
 	defer config.Unset("routers:router1")
 	defer config.Unset("routers:router2")
-	fooCreator := func(name, prefix string) (Router, error) {
+	fooCreator := func(name string, cfg ConfigGetter) (Router, error) {
 		return &testInfoRouter{}, nil
 	}
-	barCreator := func(name, prefix string) (Router, error) {
+	barCreator := func(name string, cfg ConfigGetter) (Router, error) {
 		return &testInfoErrRouter{}, nil
 	}
 	Register("foo", fooCreator)
@@ -38,6 +38,12 @@ var TLSRouter = tlsRouter{
 	fakeRouter: newFakeRouter(),
 	Certs:      make(map[string]string),
 	Keys:       make(map[string]string),
+	Challenges: make(map[string]string),
+}
+
+var WeightsRouter = weightsRouter{
+	fakeRouter: newFakeRouter(),
+	Weights:    make(map[string][]router.BackendWeight),
 }
 
 var ErrForcedFailure = errors.New("Forced failure")
@@ -49,32 +55,37 @@ func init() {
 	router.Register("fake-opts", createOptsRouter)
 	router.Register("fake-info", createInfoRouter)
 	router.Register("fake-status", createStatusRouter)
+	router.Register("fake-weights", createWeightsRouter)
 }
 
-func createRouter(name, prefix string) (router.Router, error) {
+func createRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
 	return &FakeRouter, nil
 }
 
-func createHCRouter(name, prefix string) (router.Router, error) {
+func createHCRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
 	return &HCRouter, nil
 }
 
-func createTLSRouter(name, prefix string) (router.Router, error) {
+func createTLSRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
 	return &TLSRouter, nil
 }
 
-func createOptsRouter(name, prefix string) (router.Router, error) {
+func createOptsRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
 	return &OptsRouter, nil
 }
 
-func createInfoRouter(name, prefix string) (router.Router, error) {
+func createInfoRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
 	return &InfoRouter, nil
 }
 
-func createStatusRouter(name, prefix string) (router.Router, error) {
+func createStatusRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
 	return &StatusRouter, nil
 }
 
+func createWeightsRouter(name string, cfg router.ConfigGetter) (router.Router, error) {
+	return &WeightsRouter, nil
+}
+
 func newFakeRouter() fakeRouter {
 	return fakeRouter{cnames: make(map[string]string), backends: make(map[string][]string), failuresByIp: make(map[string]bool), healthcheck: make(map[string]routerTypes.HealthcheckData), mutex: &sync.Mutex{}}
 }
@@ -424,11 +435,22 @@ func (r *fakeRouter) RemoveHealthcheck(name string) error {
 
 type tlsRouter struct {
 	fakeRouter
-	Certs map[string]string
-	Keys  map[string]string
+	Certs      map[string]string
+	Keys       map[string]string
+	Challenges map[string]string
 }
 
-var _ router.TLSRouter = &tlsRouter{}
+var (
+	_ router.TLSRouter  = &tlsRouter{}
+	_ router.ACMERouter = &tlsRouter{}
+)
+
+func (r *tlsRouter) Reset() {
+	r.fakeRouter.Reset()
+	r.Certs = make(map[string]string)
+	r.Keys = make(map[string]string)
+	r.Challenges = make(map[string]string)
+}
 
 func (r *tlsRouter) AddCertificate(app router.App, cname, certificate, key string) error {
 	r.Certs[cname] = certificate
@@ -450,6 +472,16 @@ func (r *tlsRouter) GetCertificate(app router.App, cname string) (string, error)
 	return data, nil
 }
 
+func (r *tlsRouter) AddACMEChallenge(app router.App, cname, token, keyAuthorization string) error {
+	r.Challenges[cname+"/"+token] = keyAuthorization
+	return nil
+}
+
+func (r *tlsRouter) RemoveACMEChallenge(app router.App, cname, token string) error {
+	delete(r.Challenges, cname+"/"+token)
+	return nil
+}
+
 func (r *tlsRouter) Addr(name string) (string, error) {
 	addr, err := r.fakeRouter.Addr(name)
 	if err != nil {
@@ -508,3 +540,48 @@ func (r *statusRouter) Reset() {
 	r.Status = router.BackendStatusReady
 	r.StatusDetail = ""
 }
+
+type weightsRouter struct {
+	fakeRouter
+	Weights map[string][]router.BackendWeight
+}
+
+var _ router.TrafficSplitRouter = &weightsRouter{}
+
+func (r *weightsRouter) SetBackendWeights(name string, weights []router.BackendWeight) error {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return err
+	}
+	if !r.HasBackend(backendName) {
+		return router.ErrBackendNotFound
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(weights) == 0 {
+		delete(r.Weights, backendName)
+		return nil
+	}
+	r.Weights[backendName] = weights
+	return nil
+}
+
+func (r *weightsRouter) GetBackendWeights(name string) ([]router.BackendWeight, error) {
+	backendName, err := router.Retrieve(name)
+	if err != nil {
+		return nil, err
+	}
+	if !r.HasBackend(backendName) {
+		return nil, router.ErrBackendNotFound
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.Weights[backendName], nil
+}
+
+func (r *weightsRouter) Reset() {
+	r.fakeRouter.Reset()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Weights = make(map[string][]router.BackendWeight)
+}
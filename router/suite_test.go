@@ -10,14 +10,16 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/dbtest"
+	servicemock "github.com/tsuru/tsuru/servicemanager/mock"
 	check "gopkg.in/check.v1"
 )
 
 func Test(t *testing.T) { check.TestingT(t) }
 
 type S struct {
-	conn    *db.Storage
-	routers map[string]routerFactory
+	conn        *db.Storage
+	routers     map[string]routerFactory
+	mockService servicemock.MockService
 }
 
 var _ = check.Suite(&S{})
@@ -37,6 +39,7 @@ func (s *S) SetUpTest(c *check.C) {
 		s.routers[k] = v
 	}
 	dbtest.ClearAllCollections(s.conn.Apps().Database)
+	servicemock.SetMockService(&s.mockService)
 }
 
 func (s *S) TearDownTest(c *check.C) {
@@ -120,12 +120,10 @@ func (s *S) TestShouldBeRegisteredAllowingPrefixes(c *check.C) {
 	c.Assert(ok, check.Equals, true)
 	c.Assert(r1.client.Addr, check.Equals, "http://localhost:1")
 	c.Assert(r1.domain, check.Equals, "inst1.example.com")
-	c.Assert(r1.prefix, check.Equals, "routers:inst1")
 	r2, ok := got2.(*vulcandRouter)
 	c.Assert(ok, check.Equals, true)
 	c.Assert(r2.client.Addr, check.Equals, "http://localhost:2")
 	c.Assert(r2.domain, check.Equals, "inst2.example.com")
-	c.Assert(r2.prefix, check.Equals, "routers:inst2")
 }
 
 func (s *S) TestAddBackend(c *check.C) {
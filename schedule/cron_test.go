@@ -0,0 +1,64 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestCronNext(c *check.C) {
+	base := time.Date(2019, 3, 15, 10, 30, 20, 0, time.UTC) // friday
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2019, 3, 15, 10, 31, 0, 0, time.UTC)},
+		{"0 20 * * *", time.Date(2019, 3, 15, 20, 0, 0, 0, time.UTC)},
+		{"0 7 * * *", time.Date(2019, 3, 16, 7, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"5,35 10 * * *", time.Date(2019, 3, 15, 10, 35, 0, 0, time.UTC)},
+		{"0 0 * * sat,sun", time.Date(2019, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2019, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2019, 3, 17, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2019, 3, 22, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2019, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2019, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for i, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		c.Assert(err, check.IsNil, check.Commentf("test %d", i))
+		c.Assert(spec.Next(base).Equal(tt.expected), check.Equals, true, check.Commentf("test %d: %s", i, spec.Next(base)))
+	}
+}
+
+func (s *S) TestCronNextLocation(c *check.C) {
+	loc := time.FixedZone("UTC-3", -3*60*60)
+	spec, err := parseCron("0 20 * * *")
+	c.Assert(err, check.IsNil)
+	next := spec.Next(time.Date(2019, 3, 15, 22, 0, 0, 0, time.UTC).In(loc))
+	c.Assert(next.Equal(time.Date(2019, 3, 15, 23, 0, 0, 0, time.UTC)), check.Equals, true)
+}
+
+func (s *S) TestParseCronInvalid(c *check.C) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"* * * *", `invalid cron expression "\* \* \* \*": expected 5 fields, got 4`},
+		{"60 * * * *", `invalid cron expression .*: value 60 out of range \[0, 59\]`},
+		{"* 5-2 * * *", `invalid cron expression .*: invalid range "5-2"`},
+		{"*/0 * * * *", `invalid cron expression .*: invalid step in "\*/0"`},
+		{"* * * * xyz", `invalid cron expression .*: invalid value "xyz"`},
+	}
+	for i, tt := range tests {
+		_, err := parseCron(tt.expr)
+		c.Assert(err, check.ErrorMatches, tt.err, check.Commentf("test %d", i))
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+)
+
+func jobNextRun(job appTypes.Job) (time.Time, error) {
+	spec, err := parseCron(job.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	last := job.LastRun
+	if last.IsZero() {
+		last = job.CreatedAt
+	}
+	return spec.Next(last.UTC()), nil
+}
+
+// runJobs starts the app jobs whose time has come, for apps whose
+// provisioner doesn't run jobs natively. Runs of jobs with the forbid
+// concurrency policy are skipped while the previous run is still running,
+// while the replace policy cancels the previous run before starting.
+func (s *Scheduler) runJobs(now time.Time) error {
+	apps, err := app.List(&app.Filter{WithJobs: true})
+	if err != nil {
+		return errors.Wrap(err, "unable to list apps with jobs")
+	}
+	for i := range apps {
+		a := &apps[i]
+		native, err := a.HandlesJobs()
+		if err != nil {
+			log.Errorf("[scheduler] unable to check jobs support for app %q: %s", a.Name, err)
+			continue
+		}
+		if native {
+			continue
+		}
+		for _, job := range a.Jobs {
+			next, err := jobNextRun(job)
+			if err != nil {
+				log.Errorf("[scheduler] invalid job %q of app %q: %s", job.Name, a.Name, err)
+				continue
+			}
+			if next.IsZero() || next.After(now) {
+				continue
+			}
+			err = s.renewLease()
+			if err != nil {
+				return err
+			}
+			err = a.UpdateJobLastRun(job.Name, now)
+			if err != nil {
+				log.Errorf("[scheduler] unable to update job %q of app %q: %s", job.Name, a.Name, err)
+				continue
+			}
+			if now.Sub(next) > s.MaxDelay {
+				log.Errorf("[scheduler] skipping job %q of app %q, run at %s missed by more than %s", job.Name, a.Name, next, s.MaxDelay)
+				continue
+			}
+			s.jobs.Add(1)
+			go func(a *app.App, job appTypes.Job) {
+				defer s.jobs.Done()
+				runErr := runJob(a, job)
+				if runErr != nil {
+					log.Errorf("[scheduler] error running job %q of app %q: %s", job.Name, a.Name, runErr)
+				}
+			}(a, job)
+		}
+	}
+	return nil
+}
+
+var (
+	// jobReplaceTimeout is how long a run of a job with the replace
+	// concurrency policy waits for the canceled previous run to finish.
+	jobReplaceTimeout  = time.Minute
+	jobReplaceInterval = time.Second
+)
+
+func runJob(a *app.App, job appTypes.Job) (err error) {
+	run := &appTypes.JobRun{Job: job.Name, StartTime: time.Now().UTC()}
+	evt, err := a.NewJobRunEvent(job, run, job.ConcurrencyPolicy != appTypes.JobConcurrencyAllow)
+	if _, ok := err.(event.ErrEventLocked); ok && job.ConcurrencyPolicy == appTypes.JobConcurrencyReplace {
+		evt, err = replaceJobRun(a, job, run)
+	}
+	if err != nil {
+		if _, ok := err.(event.ErrEventLocked); ok {
+			log.Debugf("[scheduler] skipping job %q of app %q, previous run still running", job.Name, a.Name)
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		run.EndTime = time.Now().UTC()
+		if err == nil {
+			exitCode := 0
+			run.ExitCode = &exitCode
+		} else if exitErr, ok := err.(*provision.ExecExitError); ok {
+			run.ExitCode = &exitErr.Code
+		}
+		evt.DoneCustomData(err, run)
+	}()
+	evt.Logf("Running job %q: %s", job.Name, job.Command)
+	return a.RunJob(job, evt)
+}
+
+// replaceJobRun cancels the running run of the job, waiting for it to finish
+// before creating the event of the new run.
+func replaceJobRun(a *app.App, job appTypes.Job, run *appTypes.JobRun) (*event.Event, error) {
+	err := a.CancelJobRuns(job, "replaced by a new run")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to cancel the previous run")
+	}
+	timeout := time.After(jobReplaceTimeout)
+	for {
+		select {
+		case <-timeout:
+			return nil, errors.Errorf("previous run still running after %s", jobReplaceTimeout)
+		case <-time.After(jobReplaceInterval):
+		}
+		run.StartTime = time.Now().UTC()
+		evt, err := a.NewJobRunEvent(job, run, true)
+		if _, ok := err.(event.ErrEventLocked); !ok {
+			return evt, err
+		}
+	}
+}
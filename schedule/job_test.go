@@ -0,0 +1,142 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"errors"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/provision"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestJobNextRun(c *check.C) {
+	created := time.Date(2019, 3, 15, 10, 30, 20, 0, time.UTC)
+	next, err := jobNextRun(appTypes.Job{Schedule: "0 * * * *", CreatedAt: created})
+	c.Assert(err, check.IsNil)
+	c.Assert(next.Equal(time.Date(2019, 3, 15, 11, 0, 0, 0, time.UTC)), check.Equals, true)
+	next, err = jobNextRun(appTypes.Job{
+		Schedule:  "0 * * * *",
+		CreatedAt: created,
+		LastRun:   time.Date(2019, 3, 15, 14, 0, 5, 0, time.UTC),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(next.Equal(time.Date(2019, 3, 15, 15, 0, 0, 0, time.UTC)), check.Equals, true)
+	_, err = jobNextRun(appTypes.Job{Schedule: "every hour", CreatedAt: created})
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestValidateCron(c *check.C) {
+	c.Assert(ValidateCron("*/5 * * * *"), check.IsNil)
+	c.Assert(ValidateCron("@daily"), check.IsNil)
+	c.Assert(ValidateCron("61 * * * *"), check.NotNil)
+	c.Assert(ValidateCron("* * *"), check.NotNil)
+}
+
+func (s *S) setAppJobs(c *check.C, jobs ...appTypes.Job) {
+	err := s.conn.Apps().Update(bson.M{"name": "myapp"}, bson.M{"$set": bson.M{"jobs": jobs}})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestSchedulerRunOnceJobs(c *check.C) {
+	s.setAppJobs(c, appTypes.Job{
+		Name:              "cleanup",
+		Schedule:          "* * * * *",
+		Command:           "./cleanup.sh",
+		ConcurrencyPolicy: appTypes.JobConcurrencyForbid,
+		CreatedAt:         time.Now().Add(-2 * time.Minute),
+	})
+	s.p.PrepareOutput([]byte("cleaned"))
+	scheduler := s.newScheduler()
+	err := scheduler.runOnce()
+	c.Assert(err, check.IsNil)
+	scheduler.jobs.Wait()
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeAppJob, Value: "myapp/cleanup"},
+		ExtraTargets: []event.ExtraTarget{
+			{Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}},
+		},
+		Kind:       "app.run.job",
+		Owner:      "job",
+		LogMatches: `(?s).*Running job "cleanup": ./cleanup.sh.*cleaned.*`,
+	}, eventtest.HasEvent)
+	execs := s.p.Execs("isolated")
+	c.Assert(execs, check.HasLen, 1)
+	c.Assert(execs[0].Cmds, check.DeepEquals, []string{"/bin/sh", "-c", "[ -f /home/application/apprc ] && source /home/application/apprc; [ -d /home/application/current ] && cd /home/application/current; ./cleanup.sh"})
+	var dbApp struct{ Jobs []appTypes.Job }
+	err = s.conn.Apps().Find(bson.M{"name": "myapp"}).One(&dbApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Jobs, check.HasLen, 1)
+	c.Assert(time.Since(dbApp.Jobs[0].LastRun) < time.Minute, check.Equals, true)
+}
+
+func (s *S) TestSchedulerRunOnceJobsNotDue(c *check.C) {
+	s.setAppJobs(c, appTypes.Job{
+		Name:      "cleanup",
+		Schedule:  "@yearly",
+		Command:   "./cleanup.sh",
+		CreatedAt: time.Now(),
+	})
+	err := s.newScheduler().runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.p.Execs("isolated"), check.HasLen, 0)
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+}
+
+func (s *S) TestRunJobReplaceCancelsPreviousRun(c *check.C) {
+	oldInterval := jobReplaceInterval
+	jobReplaceInterval = 10 * time.Millisecond
+	defer func() { jobReplaceInterval = oldInterval }()
+	a, err := app.GetByName("myapp")
+	c.Assert(err, check.IsNil)
+	job := appTypes.Job{Name: "cleanup", Command: "./cleanup.sh", ConcurrencyPolicy: appTypes.JobConcurrencyReplace}
+	previous, err := a.NewJobRunEvent(job, &appTypes.JobRun{Job: job.Name}, true)
+	c.Assert(err, check.IsNil)
+	done := make(chan error)
+	go func() {
+		done <- runJob(a, job)
+	}()
+	timeout := time.After(5 * time.Second)
+	for {
+		evt, err := event.GetByID(previous.UniqueID)
+		c.Assert(err, check.IsNil)
+		if evt.CancelInfo.Asked {
+			c.Assert(evt.CancelInfo.Reason, check.Equals, "replaced by a new run")
+			break
+		}
+		select {
+		case <-timeout:
+			c.Fatal("timeout waiting for the previous run to be canceled")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	c.Assert(s.p.Execs("isolated"), check.HasLen, 0)
+	err = previous.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(s.p.Execs("isolated"), check.HasLen, 1)
+}
+
+func (s *S) TestRunJobFailureExitCode(c *check.C) {
+	a, err := app.GetByName("myapp")
+	c.Assert(err, check.IsNil)
+	s.p.PrepareFailure("ExecuteCommand", &provision.ExecExitError{Code: 2, Err: errors.New("command failed")})
+	job := appTypes.Job{Name: "cleanup", Command: "./cleanup.sh", ConcurrencyPolicy: appTypes.JobConcurrencyForbid}
+	err = runJob(a, job)
+	c.Assert(err, check.ErrorMatches, "command failed")
+	evts, err := event.List(&event.Filter{Target: event.Target{Type: event.TargetTypeAppJob, Value: "myapp/cleanup"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	var run appTypes.JobRun
+	err = evts[0].EndData(&run)
+	c.Assert(err, check.IsNil)
+	c.Assert(run.ExitCode, check.NotNil)
+	c.Assert(*run.ExitCode, check.Equals, 2)
+}
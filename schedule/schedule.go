@@ -0,0 +1,229 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package schedule provides cron-like schedules to scale, sleep, start and
+// stop apps, either individually or every app in a pool. The scheduler also
+// runs the jobs of apps whose provisioner doesn't run them natively.
+package schedule
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/storage"
+	"github.com/tsuru/tsuru/event"
+)
+
+type Action string
+
+const (
+	ActionScale = Action("scale")
+	ActionSleep = Action("sleep")
+	ActionStart = Action("start")
+	ActionStop  = Action("stop")
+)
+
+var (
+	ErrScheduleNotFound      = errors.New("schedule not found")
+	ErrScheduleAlreadyExists = errors.New("schedule already exists with the same name")
+	ErrInvalidAction         = errors.New("invalid schedule action, valid actions are: scale, sleep, start and stop")
+	ErrInvalidTarget         = errors.New("schedule target must be either an app or a pool")
+
+	errScaleProcessRequired = errors.New("scale action requires a process name")
+)
+
+// Schedule is a recurring action executed on an app or on every app in a
+// pool. Cron is a standard five fields cron expression evaluated in the
+// Timezone location, UTC if empty.
+type Schedule struct {
+	Name        string       `json:"name" bson:"_id"`
+	Description string       `json:"description"`
+	Target      event.Target `json:"target"`
+	Cron        string       `json:"cron"`
+	Timezone    string       `json:"timezone,omitempty"`
+	Action      Action       `json:"action"`
+	Process     string       `json:"process,omitempty"`
+	Units       uint         `json:"units,omitempty"`
+	ProxyURL    string       `json:"proxyURL,omitempty"`
+	Disabled    bool         `json:"disabled"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	LastRun     time.Time    `json:"lastRun"`
+	LastError   string       `json:"lastError,omitempty"`
+}
+
+func (s *Schedule) Validate() error {
+	if s.Name == "" {
+		return errors.New("schedule name is required")
+	}
+	if (s.Target.Type != event.TargetTypeApp && s.Target.Type != event.TargetTypePool) || s.Target.Value == "" {
+		return ErrInvalidTarget
+	}
+	switch s.Action {
+	case ActionScale:
+		if s.Units == 0 {
+			return errors.New("scale action requires a number of units greater than zero")
+		}
+		if s.Process == "" {
+			return errScaleProcessRequired
+		}
+	case ActionSleep:
+		proxyURL, err := url.Parse(s.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return errors.New("sleep action requires a valid proxy url")
+		}
+	case ActionStart, ActionStop:
+	default:
+		return ErrInvalidAction
+	}
+	_, err := s.cron()
+	if err != nil {
+		return err
+	}
+	_, err = s.location()
+	return err
+}
+
+func (s *Schedule) cron() (*cronSpec, error) {
+	return parseCron(s.Cron)
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid timezone")
+	}
+	return loc, nil
+}
+
+// NextRuns returns the next n times the schedule will run after t.
+func (s *Schedule) NextRuns(t time.Time, n int) ([]time.Time, error) {
+	spec, err := s.cron()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.location()
+	if err != nil {
+		return nil, err
+	}
+	var result []time.Time
+	t = t.In(loc)
+	for i := 0; i < n; i++ {
+		t = spec.Next(t)
+		if t.IsZero() {
+			break
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (s *Schedule) nextRun() (time.Time, error) {
+	last := s.LastRun
+	if last.IsZero() {
+		last = s.CreatedAt
+	}
+	runs, err := s.NextRuns(last, 1)
+	if err != nil || len(runs) == 0 {
+		return time.Time{}, err
+	}
+	return runs[0], nil
+}
+
+func collection() (*storage.Collection, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Collection("schedules"), nil
+}
+
+func Create(s *Schedule) error {
+	err := s.Validate()
+	if err != nil {
+		return err
+	}
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	s.CreatedAt = time.Now().UTC()
+	s.LastRun = time.Time{}
+	s.LastError = ""
+	err = coll.Insert(s)
+	if mgo.IsDup(err) {
+		return ErrScheduleAlreadyExists
+	}
+	return err
+}
+
+func Get(name string) (*Schedule, error) {
+	coll, err := collection()
+	if err != nil {
+		return nil, err
+	}
+	defer coll.Close()
+	var s Schedule
+	err = coll.FindId(name).One(&s)
+	if err == mgo.ErrNotFound {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// List returns the schedules matching the targets. All schedules are
+// returned if no target is informed.
+func List(targets []event.Target) ([]Schedule, error) {
+	coll, err := collection()
+	if err != nil {
+		return nil, err
+	}
+	defer coll.Close()
+	query := bson.M{}
+	if targets != nil {
+		query["target"] = bson.M{"$in": targets}
+	}
+	var schedules []Schedule
+	err = coll.Find(query).Sort("_id").All(&schedules)
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func Remove(name string) error {
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	err = coll.RemoveId(name)
+	if err == mgo.ErrNotFound {
+		return ErrScheduleNotFound
+	}
+	return err
+}
+
+func updateLastRun(name string, lastRun time.Time, runErr error) error {
+	coll, err := collection()
+	if err != nil {
+		return err
+	}
+	defer coll.Close()
+	var lastError string
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+	return coll.UpdateId(name, bson.M{"$set": bson.M{"lastrun": lastRun, "lasterror": lastError}})
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/event"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) TestScheduleValidate(c *check.C) {
+	appTarget := event.Target{Type: event.TargetTypeApp, Value: "myapp"}
+	tests := []struct {
+		sched Schedule
+		err   string
+	}{
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: ActionScale, Process: "web", Units: 1}, ""},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: ActionStop, Timezone: "America/Sao_Paulo"}, ""},
+		{Schedule{Name: "s1", Target: event.Target{Type: event.TargetTypePool, Value: "dev"}, Cron: "0 0 * * sat", Action: ActionSleep, ProxyURL: "http://proxy:8080"}, ""},
+		{Schedule{Target: appTarget, Cron: "0 20 * * *", Action: ActionStop}, "schedule name is required"},
+		{Schedule{Name: "s1", Target: event.Target{Type: event.TargetTypeNode, Value: "n1"}, Cron: "0 20 * * *", Action: ActionStop}, ErrInvalidTarget.Error()},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: "restart"}, "invalid schedule action.*"},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: ActionScale}, "scale action requires a number of units greater than zero"},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: ActionScale, Units: 1}, "scale action requires a process name"},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: ActionSleep}, "sleep action requires a valid proxy url"},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * *", Action: ActionStop}, "invalid cron expression.*"},
+		{Schedule{Name: "s1", Target: appTarget, Cron: "0 20 * * *", Action: ActionStop, Timezone: "Mars/Olympus"}, "invalid timezone.*"},
+	}
+	for i, tt := range tests {
+		err := tt.sched.Validate()
+		if tt.err == "" {
+			c.Assert(err, check.IsNil, check.Commentf("test %d", i))
+		} else {
+			c.Assert(err, check.ErrorMatches, tt.err, check.Commentf("test %d", i))
+		}
+	}
+}
+
+func (s *S) TestScheduleNextRuns(c *check.C) {
+	sched := Schedule{Cron: "0 20 * * *", Timezone: "America/Sao_Paulo"}
+	runs, err := sched.NextRuns(time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC), 2)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 2)
+	c.Assert(runs[0].UTC(), check.DeepEquals, time.Date(2019, 6, 1, 23, 0, 0, 0, time.UTC))
+	c.Assert(runs[1].UTC(), check.DeepEquals, time.Date(2019, 6, 2, 23, 0, 0, 0, time.UTC))
+}
+
+func (s *S) TestScheduleCreateGetListRemove(c *check.C) {
+	sched1 := Schedule{Name: "scale-down", Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}, Cron: "0 20 * * *", Action: ActionScale, Process: "web", Units: 1}
+	err := Create(&sched1)
+	c.Assert(err, check.IsNil)
+	c.Assert(sched1.CreatedAt.IsZero(), check.Equals, false)
+	sched2 := Schedule{Name: "stop-weekend", Target: event.Target{Type: event.TargetTypePool, Value: "pool1"}, Cron: "0 0 * * sat", Action: ActionStop}
+	err = Create(&sched2)
+	c.Assert(err, check.IsNil)
+	err = Create(&sched2)
+	c.Assert(err, check.Equals, ErrScheduleAlreadyExists)
+	dbSched, err := Get("scale-down")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbSched.Units, check.Equals, uint(1))
+	c.Assert(dbSched.Target, check.DeepEquals, sched1.Target)
+	schedules, err := List(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(schedules, check.HasLen, 2)
+	schedules, err = List([]event.Target{{Type: event.TargetTypePool, Value: "pool1"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(schedules, check.HasLen, 1)
+	c.Assert(schedules[0].Name, check.Equals, "stop-weekend")
+	err = Remove("scale-down")
+	c.Assert(err, check.IsNil)
+	err = Remove("scale-down")
+	c.Assert(err, check.Equals, ErrScheduleNotFound)
+	_, err = Get("scale-down")
+	c.Assert(err, check.Equals, ErrScheduleNotFound)
+}
+
+func (s *S) TestScheduleCreateInvalid(c *check.C) {
+	err := Create(&Schedule{Name: "s1", Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"}, Cron: "0 20 * * *", Action: "restart"})
+	c.Assert(err, check.Equals, ErrInvalidAction)
+	schedules, err := List(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(schedules, check.HasLen, 0)
+}
@@ -0,0 +1,364 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db/lease"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/servicemanager"
+	permTypes "github.com/tsuru/tsuru/types/permission"
+)
+
+const (
+	leaseName = "scheduler"
+	ownerName = "scheduler"
+)
+
+// Scheduler periodically runs the schedules whose time has come. Only one
+// tsuru api instance runs schedules at a time, the leader is elected using a
+// lease stored in the database which is renewed before running each schedule.
+type Scheduler struct {
+	RunInterval time.Duration
+	MaxDelay    time.Duration
+	instance    string
+	done        chan bool
+	running     bool
+	jobs        sync.WaitGroup
+}
+
+// ScheduledAction describes the action a schedule will take on an app.
+type ScheduledAction struct {
+	App          string `json:"app"`
+	Action       Action `json:"action"`
+	Process      string `json:"process,omitempty"`
+	CurrentUnits int    `json:"currentUnits"`
+	Units        int    `json:"units"`
+	Description  string `json:"description"`
+	Error        string `json:"error,omitempty"`
+}
+
+// DryRunResult is the result of a schedule dry run, with the next times the
+// schedule will run and what would be done if it ran now.
+type DryRunResult struct {
+	Schedule Schedule          `json:"schedule"`
+	NextRuns []time.Time       `json:"nextRuns"`
+	Actions  []ScheduledAction `json:"actions"`
+}
+
+// Initialize starts the scheduler if it's enabled in the schedules config.
+func Initialize() error {
+	enabled, _ := config.GetBool("schedules:enabled")
+	if !enabled {
+		return nil
+	}
+	scheduler, err := newScheduler()
+	if err != nil {
+		return err
+	}
+	shutdown.Register(scheduler)
+	scheduler.running = true
+	go scheduler.run()
+	return nil
+}
+
+func newScheduler() (*Scheduler, error) {
+	instance, err := servicemanager.InstanceTracker.CurrentInstance()
+	if err != nil {
+		return nil, err
+	}
+	runInterval, _ := config.GetInt("schedules:run-interval")
+	maxDelay, _ := config.GetInt("schedules:max-delay")
+	scheduler := &Scheduler{
+		RunInterval: time.Duration(runInterval) * time.Second,
+		MaxDelay:    time.Duration(maxDelay) * time.Second,
+		instance:    instance.Name,
+		done:        make(chan bool),
+	}
+	if scheduler.RunInterval == 0 {
+		scheduler.RunInterval = 30 * time.Second
+	}
+	if scheduler.MaxDelay == 0 {
+		scheduler.MaxDelay = 5 * time.Minute
+	}
+	return scheduler, nil
+}
+
+func (s *Scheduler) run() {
+	for {
+		err := s.runOnce()
+		if err != nil {
+			log.Errorf("[scheduler] %s", err)
+		}
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.RunInterval):
+		}
+	}
+}
+
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	if !s.running {
+		return nil
+	}
+	s.done <- true
+	s.running = false
+	jobsDone := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(jobsDone)
+	}()
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+		log.Errorf("[scheduler] shutting down with jobs still running: %s", ctx.Err())
+	}
+	return s.lease().Release()
+}
+
+func (s *Scheduler) String() string {
+	return "scheduler"
+}
+
+func (s *Scheduler) lease() *lease.Lease {
+	return &lease.Lease{Name: leaseName, Owner: s.instance, Duration: 3 * s.RunInterval}
+}
+
+func (s *Scheduler) runOnce() (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = errors.Errorf("recovered panic, we can never stop! panic: %v", r)
+		}
+	}()
+	leader, err := s.lease().Acquire()
+	if err != nil {
+		return errors.Wrap(err, "unable to acquire leadership")
+	}
+	if !leader {
+		return nil
+	}
+	return s.runSchedules(time.Now().UTC())
+}
+
+// renewLease renews the scheduler lease, returning an error if the current
+// instance is no longer the leader and must stop running schedules.
+func (s *Scheduler) renewLease() error {
+	leader, err := s.lease().Acquire()
+	if err != nil {
+		return errors.Wrap(err, "unable to renew leadership")
+	}
+	if !leader {
+		return errors.New("leadership lost, stopping run")
+	}
+	return nil
+}
+
+// runSchedules runs the schedules and jobs due at now, renewing the lease
+// before each one so a slow run can't outlive the leadership.
+func (s *Scheduler) runSchedules(now time.Time) error {
+	schedules, err := List(nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list schedules")
+	}
+	for i := range schedules {
+		sched := &schedules[i]
+		if sched.Disabled {
+			continue
+		}
+		next, err := sched.nextRun()
+		if err != nil {
+			log.Errorf("[scheduler] invalid schedule %q: %s", sched.Name, err)
+			continue
+		}
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+		err = s.renewLease()
+		if err != nil {
+			return err
+		}
+		var runErr error
+		if now.Sub(next) > s.MaxDelay {
+			log.Errorf("[scheduler] skipping schedule %q, run at %s missed by more than %s", sched.Name, next, s.MaxDelay)
+		} else {
+			runErr = runSchedule(sched)
+			if runErr != nil {
+				log.Errorf("[scheduler] error running schedule %q: %s", sched.Name, runErr)
+			}
+		}
+		err = updateLastRun(sched.Name, now, runErr)
+		if err != nil {
+			log.Errorf("[scheduler] unable to update schedule %q: %s", sched.Name, err)
+		}
+	}
+	return s.runJobs(now)
+}
+
+func targetApps(sched *Schedule) ([]app.App, error) {
+	switch sched.Target.Type {
+	case event.TargetTypeApp:
+		a, err := app.GetByName(sched.Target.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []app.App{*a}, nil
+	case event.TargetTypePool:
+		return app.List(&app.Filter{Pool: sched.Target.Value})
+	}
+	return nil, ErrInvalidTarget
+}
+
+func runSchedule(sched *Schedule) error {
+	apps, err := targetApps(sched)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for i := range apps {
+		err = runAppSchedule(sched, &apps[i])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", apps[i].Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("errors running schedule: %v", errs)
+	}
+	return nil
+}
+
+func planAppSchedule(sched *Schedule, a *app.App) (ScheduledAction, error) {
+	action := ScheduledAction{
+		App:     a.Name,
+		Action:  sched.Action,
+		Process: sched.Process,
+	}
+	units, err := a.Units()
+	if err != nil {
+		return action, err
+	}
+	for _, u := range units {
+		if sched.Process == "" || u.ProcessName == sched.Process {
+			action.CurrentUnits++
+		}
+	}
+	action.Units = action.CurrentUnits
+	target := fmt.Sprintf("process %q", sched.Process)
+	if sched.Process == "" {
+		target = "all processes"
+	}
+	switch sched.Action {
+	case ActionScale:
+		// Scaling every process to the same number of units is never what
+		// is intended in apps with more than one process.
+		if sched.Process == "" {
+			return action, errScaleProcessRequired
+		}
+		action.Units = int(sched.Units)
+		switch {
+		case action.Units > action.CurrentUnits:
+			action.Description = fmt.Sprintf("add %d units to %s", action.Units-action.CurrentUnits, target)
+		case action.Units < action.CurrentUnits:
+			action.Description = fmt.Sprintf("remove %d units from %s", action.CurrentUnits-action.Units, target)
+		default:
+			action.Description = fmt.Sprintf("nothing to do, %s already has %d units", target, action.Units)
+		}
+	default:
+		action.Description = fmt.Sprintf("%s %s", sched.Action, target)
+	}
+	return action, nil
+}
+
+func actionKind(sched *Schedule, action ScheduledAction) *permission.PermissionScheme {
+	switch sched.Action {
+	case ActionScale:
+		if action.Units < action.CurrentUnits {
+			return permission.PermAppUpdateUnitRemove
+		}
+		return permission.PermAppUpdateUnitAdd
+	case ActionSleep:
+		return permission.PermAppUpdateSleep
+	case ActionStart:
+		return permission.PermAppUpdateStart
+	}
+	return permission.PermAppUpdateStop
+}
+
+func runAppSchedule(sched *Schedule, a *app.App) (err error) {
+	action, err := planAppSchedule(sched, a)
+	if err != nil {
+		return err
+	}
+	if sched.Action == ActionScale && action.Units == action.CurrentUnits {
+		return nil
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:       actionKind(sched, action),
+		RawOwner:   event.Owner{Type: event.OwnerTypeInternal, Name: ownerName},
+		CustomData: sched,
+		Allowed: event.Allowed(permission.PermAppReadEvents, append(permission.Contexts(permTypes.CtxTeam, a.Teams),
+			permission.Context(permTypes.CtxApp, a.Name),
+			permission.Context(permTypes.CtxPool, a.Pool),
+		)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	evt.Logf("Running schedule %q: %s", sched.Name, action.Description)
+	switch sched.Action {
+	case ActionScale:
+		if action.Units > action.CurrentUnits {
+			return a.AddUnits(uint(action.Units-action.CurrentUnits), sched.Process, evt)
+		}
+		return a.RemoveUnits(uint(action.CurrentUnits-action.Units), sched.Process, evt)
+	case ActionSleep:
+		var proxyURL *url.URL
+		proxyURL, err = url.Parse(sched.ProxyURL)
+		if err != nil {
+			return err
+		}
+		return a.Sleep(evt, sched.Process, proxyURL)
+	case ActionStart:
+		return a.Start(evt, sched.Process)
+	case ActionStop:
+		return a.Stop(evt, sched.Process)
+	}
+	return ErrInvalidAction
+}
+
+// DryRun returns the next n times the schedule will run and the actions it
+// would take on each app if it ran now, without changing anything.
+func DryRun(sched *Schedule, n int) (*DryRunResult, error) {
+	nextRuns, err := sched.NextRuns(time.Now(), n)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := targetApps(sched)
+	if err != nil {
+		return nil, err
+	}
+	result := &DryRunResult{Schedule: *sched, NextRuns: nextRuns}
+	for i := range apps {
+		action, err := planAppSchedule(sched, &apps[i])
+		if err != nil {
+			action.Error = err.Error()
+		}
+		result.Actions = append(result.Actions, action)
+	}
+	return result, nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	check "gopkg.in/check.v1"
+)
+
+func (s *S) insertDueSchedule(c *check.C, sched Schedule, lastRun time.Time) {
+	sched.CreatedAt = lastRun
+	sched.LastRun = lastRun
+	err := s.conn.Collection("schedules").Insert(sched)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestSchedulerRunOnceScale(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Cron:    "* * * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}, time.Now().Add(-2*time.Minute))
+	scheduler := s.newScheduler()
+	err = scheduler.runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 1)
+	c.Assert(eventtest.EventDesc{
+		Target:     event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Kind:       "app.update.unit.remove",
+		Owner:      ownerName,
+		LogMatches: `(?s).*Running schedule "scale-down": remove 2 units from process "web".*`,
+	}, eventtest.HasEvent)
+	sched, err := Get("scale-down")
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(sched.LastRun) < time.Minute, check.Equals, true)
+	c.Assert(sched.LastError, check.Equals, "")
+}
+
+func (s *S) TestSchedulerRunOncePoolTarget(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 1, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-up",
+		Target:  event.Target{Type: event.TargetTypePool, Value: "pool1"},
+		Cron:    "* * * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   3,
+	}, time.Now().Add(-2*time.Minute))
+	err = s.newScheduler().runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+}
+
+func (s *S) TestSchedulerRunOnceNotDue(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Cron:    "@yearly",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}, time.Now())
+	err = s.newScheduler().runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+}
+
+func (s *S) TestSchedulerRunOnceSkipsDelayedRuns(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Cron:    "* * * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}, time.Now().Add(-time.Hour))
+	err = s.newScheduler().runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+	sched, err := Get("scale-down")
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(sched.LastRun) < time.Minute, check.Equals, true)
+}
+
+func (s *S) TestSchedulerRunOnceBlocked(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	err = event.AddBlock(&event.Block{KindName: "app.update.unit", Reason: "maintenance"})
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Cron:    "* * * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}, time.Now().Add(-2*time.Minute))
+	err = s.newScheduler().runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+	sched, err := Get("scale-down")
+	c.Assert(err, check.IsNil)
+	c.Assert(sched.LastError, check.Matches, `.*myapp: error running "app.update.unit.remove" on app\(myapp\): block app.update.unit by all users on all targets: maintenance.*`)
+}
+
+func (s *S) TestSchedulerLeadership(c *check.C) {
+	s1 := s.newScheduler()
+	s2 := s.newScheduler()
+	s2.instance = "instance2"
+	leader, err := s1.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	leader, err = s2.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, false)
+	leader, err = s1.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	err = s1.lease().Release()
+	c.Assert(err, check.IsNil)
+	leader, err = s2.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+}
+
+func (s *S) TestSchedulerRunOnceNotLeader(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Cron:    "* * * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}, time.Now().Add(-2*time.Minute))
+	other := s.newScheduler()
+	other.instance = "other"
+	leader, err := other.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	err = s.newScheduler().runOnce()
+	c.Assert(err, check.IsNil)
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+}
+
+func (s *S) TestSchedulerRunSchedulesStopsWithoutLeadership(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	s.insertDueSchedule(c, Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Cron:    "* * * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}, time.Now().Add(-2*time.Minute))
+	other := s.newScheduler()
+	other.instance = "other"
+	leader, err := other.lease().Acquire()
+	c.Assert(err, check.IsNil)
+	c.Assert(leader, check.Equals, true)
+	err = s.newScheduler().runSchedules(time.Now().UTC())
+	c.Assert(err, check.ErrorMatches, "leadership lost, stopping run")
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+	sched, err := Get("scale-down")
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(sched.LastRun) > time.Minute, check.Equals, true)
+}
+
+func (s *S) TestDryRun(c *check.C) {
+	_, err := s.p.AddUnitsToNode(s.appInstance, 3, "web", nil, "n1:1")
+	c.Assert(err, check.IsNil)
+	sched := &Schedule{
+		Name:    "scale-down",
+		Target:  event.Target{Type: event.TargetTypePool, Value: "pool1"},
+		Cron:    "0 20 * * *",
+		Action:  ActionScale,
+		Process: "web",
+		Units:   1,
+	}
+	result, err := DryRun(sched, 3)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.NextRuns, check.HasLen, 3)
+	c.Assert(result.Actions, check.DeepEquals, []ScheduledAction{
+		{App: "myapp", Action: ActionScale, Process: "web", CurrentUnits: 3, Units: 1, Description: `remove 2 units from process "web"`},
+	})
+	units, err := s.p.Units(s.appInstance)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/dbtest"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/pool"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	"github.com/tsuru/tsuru/router/routertest"
+	servicemock "github.com/tsuru/tsuru/servicemanager/mock"
+	_ "github.com/tsuru/tsuru/storage/mongodb"
+	appTypes "github.com/tsuru/tsuru/types/app"
+	check "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+var _ = check.Suite(&S{})
+
+type S struct {
+	conn        *db.Storage
+	p           *provisiontest.FakeProvisioner
+	appInstance *provisiontest.FakeApp
+	mockService servicemock.MockService
+}
+
+func (s *S) SetUpSuite(c *check.C) {
+	config.Set("log:disable-syslog", true)
+	config.Set("database:url", "127.0.0.1:27017?maxPoolSize=100")
+	config.Set("database:name", "schedule_tests_s")
+}
+
+func (s *S) SetUpTest(c *check.C) {
+	routertest.FakeRouter.Reset()
+	var err error
+	s.conn, err = db.Conn()
+	c.Assert(err, check.IsNil)
+	dbtest.ClearAllCollections(s.conn.Apps().Database)
+	provisiontest.ProvisionerInstance.Reset()
+	s.p = provisiontest.ProvisionerInstance
+	err = pool.AddPool(pool.AddPoolOptions{Name: "pool1", Provisioner: "fake"})
+	c.Assert(err, check.IsNil)
+	s.appInstance = provisiontest.NewFakeApp("myapp", "python", 0)
+	s.appInstance.Pool = "pool1"
+	s.p.Provision(s.appInstance)
+	plan := appTypes.Plan{Memory: 4194304, Name: "default", CpuShare: 10}
+	servicemock.SetMockService(&s.mockService)
+	s.mockService.Plan.OnList = func() ([]appTypes.Plan, error) {
+		return []appTypes.Plan{plan}, nil
+	}
+	s.mockService.Plan.OnDefaultPlan = func() (*appTypes.Plan, error) {
+		return &plan, nil
+	}
+	err = s.conn.Apps().Insert(&app.App{Name: "myapp", Pool: "pool1", Plan: plan})
+	c.Assert(err, check.IsNil)
+	err = s.p.AddNode(provision.AddNodeOptions{Address: "http://n1:1", Pool: "pool1"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TearDownTest(c *check.C) {
+	app.GetAppRouterUpdater().Shutdown(context.Background())
+	s.conn.Close()
+}
+
+func (s *S) TearDownSuite(c *check.C) {
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	dbtest.ClearAllCollections(conn.Apps().Database)
+}
+
+func (s *S) newScheduler() *Scheduler {
+	return &Scheduler{
+		RunInterval: time.Minute,
+		MaxDelay:    5 * time.Minute,
+		instance:    "instance1",
+	}
+}
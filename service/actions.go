@@ -317,6 +317,8 @@ var setBoundEnvsAction = &action.Action{
 			return envs[i].Name < envs[j].Name
 		})
 		addArgs := bind.AddInstanceArgs{
+			ServiceName:   args.serviceInstance.ServiceName,
+			InstanceName:  args.serviceInstance.Name,
 			Envs:          envs,
 			ShouldRestart: args.shouldRestart,
 			Writer:        args.writer,
@@ -420,6 +420,8 @@ func (s *S) TestSetBoundEnvsActionForward(c *check.C) {
 	result, err := setBoundEnvsAction.Forward(ctx)
 	c.Assert(err, check.IsNil)
 	args := bind.AddInstanceArgs{
+		ServiceName:  "mysql",
+		InstanceName: "my-mysql",
 		Envs: []bind.ServiceEnvVar{
 			{EnvVar: bind.EnvVar{Name: "DATABASE_NAME", Value: "mydb"}, ServiceName: "mysql", InstanceName: "my-mysql"},
 			{EnvVar: bind.EnvVar{Name: "DATABASE_USER", Value: "root"}, ServiceName: "mysql", InstanceName: "my-mysql"},
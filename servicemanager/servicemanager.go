@@ -12,6 +12,7 @@ import (
 	"github.com/tsuru/tsuru/types/event"
 	"github.com/tsuru/tsuru/types/provision"
 	"github.com/tsuru/tsuru/types/quota"
+	"github.com/tsuru/tsuru/types/router"
 	"github.com/tsuru/tsuru/types/service"
 	"github.com/tsuru/tsuru/types/tracker"
 )
@@ -31,4 +32,5 @@ var (
 	ServiceBrokerCatalogCache service.ServiceBrokerCatalogCacheService
 	AppLog                    app.AppLogService
 	InstanceTracker           tracker.InstanceService
+	DynamicRouter             router.DynamicRouterService
 )
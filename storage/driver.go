@@ -16,6 +16,7 @@ import (
 	"github.com/tsuru/tsuru/types/event"
 	"github.com/tsuru/tsuru/types/provision"
 	"github.com/tsuru/tsuru/types/quota"
+	"github.com/tsuru/tsuru/types/router"
 	"github.com/tsuru/tsuru/types/service"
 	"github.com/tsuru/tsuru/types/tracker"
 )
@@ -35,6 +36,7 @@ type DbDriver struct {
 	PlatformImageStorage             image.PlatformImageStorage
 	AppLogStorage                    app.AppLogStorage
 	InstanceTrackerStorage           tracker.InstanceStorage
+	DynamicRouterStorage             router.DynamicRouterStorage
 }
 
 var (
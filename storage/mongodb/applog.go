@@ -6,10 +6,15 @@ package mongodb
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/storage"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/types/app"
 	appTypes "github.com/tsuru/tsuru/types/app"
@@ -69,7 +74,34 @@ func (s *applogStorage) List(args app.ListLogArgs) ([]app.Applog, error) {
 			q[k] = bson.M{"$ne": v}
 		}
 	}
-	err = conn.AppLogCollection(args.AppName).Find(q).Sort("-$natural").Limit(args.Limit).All(&logs)
+	if !args.Since.IsZero() || !args.Until.IsZero() {
+		dateQuery := bson.M{}
+		if !args.Since.IsZero() {
+			dateQuery["$gte"] = args.Since
+		}
+		if !args.Until.IsZero() {
+			dateQuery["$lt"] = args.Until
+		}
+		q["date"] = dateQuery
+	}
+	if args.Message != "" {
+		q["message"] = bson.RegEx{Pattern: args.Message}
+	}
+	for k, v := range args.FieldsFilter() {
+		q["fields."+k] = v
+	}
+	if args.Cursor != nil {
+		// Dates are stored with millisecond precision, the id breaks the
+		// ties between entries sharing the cursor date.
+		if !bson.IsObjectIdHex(args.Cursor.ID) {
+			return nil, fmt.Errorf("invalid log cursor %q", args.Cursor.String())
+		}
+		q["$or"] = []bson.M{
+			{"date": bson.M{"$lt": args.Cursor.Date}},
+			{"date": args.Cursor.Date, "_id": bson.M{"$lt": bson.ObjectIdHex(args.Cursor.ID)}},
+		}
+	}
+	err = conn.AppLogCollection(args.AppName).Find(q).Sort("-date", "-_id").Limit(args.Limit).All(&logs)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +112,194 @@ func (s *applogStorage) List(args app.ListLogArgs) ([]app.Applog, error) {
 	return logs, nil
 }
 
+const (
+	logTTLIndexName = "date_ttl"
+	pruneBatchSize  = 1000
+)
+
+// Prune removes the entries exceeding the retention policy. Entries can't be
+// removed from capped collections, so the capped collection of the app is
+// converted once into a regular collection, with a TTL index on the date of
+// the entries enforcing the max age. Entries exceeding the max size are
+// removed from the oldest ones.
+func (s *applogStorage) Prune(appName string, retention app.LogRetention) error {
+	if appName == "" {
+		return errors.New("unable to prune logs with empty app name")
+	}
+	conn, err := db.LogConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	coll := conn.AppLogCollection(appName)
+	count, err := coll.Count()
+	if err != nil || count == 0 {
+		return err
+	}
+	capped, err := isCapped(coll)
+	if err != nil {
+		return err
+	}
+	if capped {
+		err = convertToRegular(conn, coll)
+		if err != nil {
+			return err
+		}
+	}
+	err = ensureTTLIndex(coll, retention.MaxAge)
+	if err != nil {
+		return err
+	}
+	if cutoff := retention.Cutoff(time.Now()); !cutoff.IsZero() {
+		// The TTL monitor removes expired entries only periodically.
+		_, err = coll.RemoveAll(bson.M{"date": bson.M{"$lt": cutoff}})
+		if err != nil {
+			return err
+		}
+	}
+	if retention.MaxSize <= 0 {
+		return nil
+	}
+	return pruneSize(coll, retention.MaxSize)
+}
+
+func isCapped(coll *storage.Collection) (bool, error) {
+	var stats struct {
+		Capped bool `bson:"capped"`
+	}
+	err := coll.Database.Run(bson.D{{Name: "collStats", Value: coll.Name}}, &stats)
+	return stats.Capped, err
+}
+
+// convertToRegular replaces a capped logs collection with a regular
+// collection holding the same entries. The capped collection is renamed
+// before copying the entries inserted during the first copy, so only entries
+// inserted between the renames may be lost.
+func convertToRegular(conn *db.LogStorage, coll *storage.Collection) error {
+	tmp := conn.Collection(coll.Name + "_regular")
+	tmp.DropCollection()
+	err := tmp.Create(&mgo.CollectionInfo{})
+	if err != nil {
+		return err
+	}
+	last, err := copyLogs(coll, tmp, "")
+	if err != nil {
+		return err
+	}
+	old := conn.Collection(coll.Name + "_capped")
+	err = renameCollection(coll.Database, coll.Name, old.Name)
+	if err != nil {
+		return err
+	}
+	defer old.DropCollection()
+	_, err = copyLogs(old, tmp, last)
+	if err != nil {
+		return err
+	}
+	return renameCollection(coll.Database, tmp.Name, coll.Name)
+}
+
+// copyLogs copies the entries with ids greater than after, if set, returning
+// the id of the last entry copied.
+func copyLogs(src, dst *storage.Collection, after bson.ObjectId) (bson.ObjectId, error) {
+	query := bson.M{}
+	if after != "" {
+		query["_id"] = bson.M{"$gt": after}
+	}
+	iter := src.Find(query).Sort("_id").Iter()
+	batch := make([]interface{}, 0, pruneBatchSize)
+	var doc bson.Raw
+	for iter.Next(&doc) {
+		var entry struct {
+			ID bson.ObjectId `bson:"_id"`
+		}
+		if doc.Unmarshal(&entry) == nil {
+			after = entry.ID
+		}
+		batch = append(batch, doc)
+		if len(batch) == pruneBatchSize {
+			err := dst.Insert(batch...)
+			if err != nil {
+				iter.Close()
+				return "", err
+			}
+			batch = batch[:0]
+		}
+	}
+	err := iter.Close()
+	if err != nil {
+		return "", err
+	}
+	if len(batch) > 0 {
+		err = dst.Insert(batch...)
+	}
+	return after, err
+}
+
+func renameCollection(database *mgo.Database, from, to string) error {
+	return database.Session.Run(bson.D{
+		{Name: "renameCollection", Value: database.Name + "." + from},
+		{Name: "to", Value: database.Name + "." + to},
+		{Name: "dropTarget", Value: true},
+	}, nil)
+}
+
+// ensureTTLIndex creates or updates the TTL index removing the entries older
+// than maxAge, or drops it if maxAge is not set.
+func ensureTTLIndex(coll *storage.Collection, maxAge time.Duration) error {
+	if maxAge <= 0 {
+		err := coll.DropIndexName(logTTLIndexName)
+		if err != nil && strings.Contains(err.Error(), "index not found") {
+			return nil
+		}
+		return err
+	}
+	expire := maxAge.Round(time.Second)
+	if expire < time.Second {
+		expire = time.Second
+	}
+	err := coll.EnsureIndex(mgo.Index{Name: logTTLIndexName, Key: []string{"date"}, ExpireAfter: expire})
+	if err != nil {
+		return err
+	}
+	// EnsureIndex doesn't change the expiration of existing indexes.
+	return coll.Database.Run(bson.D{
+		{Name: "collMod", Value: coll.Name},
+		{Name: "index", Value: bson.M{"name": logTTLIndexName, "expireAfterSeconds": int(expire / time.Second)}},
+	}, nil)
+}
+
+// pruneSize removes the oldest entries exceeding maxSize, estimated by the
+// average size of the entries.
+func pruneSize(coll *storage.Collection, maxSize int64) error {
+	var stats struct {
+		Size  int64 `bson:"size"`
+		Count int64 `bson:"count"`
+	}
+	err := coll.Database.Run(bson.D{{Name: "collStats", Value: coll.Name}}, &stats)
+	if err != nil || stats.Size <= maxSize || stats.Count == 0 {
+		return err
+	}
+	avgSize := stats.Size / stats.Count
+	if avgSize == 0 {
+		avgSize = 1
+	}
+	excess := int((stats.Size - maxSize + avgSize - 1) / avgSize)
+	var last struct {
+		ID bson.ObjectId `bson:"_id"`
+	}
+	err = coll.Find(nil).Sort("_id").Select(bson.M{"_id": 1}).Skip(excess - 1).One(&last)
+	if err == mgo.ErrNotFound {
+		_, err = coll.RemoveAll(nil)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	_, err = coll.RemoveAll(bson.M{"_id": bson.M{"$lte": last.ID}})
+	return err
+}
+
 func (s *applogStorage) Watch(appName, source, unit string) (app.LogWatcher, error) {
 	listener, err := newLogListener(s, appName, appTypes.Applog{Source: source, Unit: unit})
 	if err != nil {
@@ -16,6 +16,10 @@ import (
 	appTypes "github.com/tsuru/tsuru/types/app"
 )
 
+// logPollInterval is the interval between queries for new entries in regular
+// logs collections.
+var logPollInterval = time.Second
+
 type logListener struct {
 	c       <-chan appTypes.Applog
 	logConn *db.LogStorage
@@ -77,9 +81,22 @@ func newLogListener(svc appTypes.AppLogStorage, appName string, filterLog appTyp
 		}
 		return m
 	}
-	query := coll.Find(mkQuery())
+	// Capped collections are tailed, regular collections, used for apps
+	// with retention policies, are polled.
+	capped, err := isCapped(coll)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
 	tailTimeout := 10 * time.Second
-	iter := query.Sort("$natural").Tail(tailTimeout)
+	newIter := func() *mgo.Iter {
+		query := coll.Find(mkQuery())
+		if capped {
+			return query.Sort("$natural").Tail(tailTimeout)
+		}
+		return query.Sort("_id").Iter()
+	}
+	iter := newIter()
 	go func() {
 		defer close(c)
 		defer func() {
@@ -101,19 +118,27 @@ func newLogListener(svc appTypes.AppLogStorage, appName string, filterLog appTyp
 					return
 				}
 			}
-			if iter.Timeout() {
+			if capped && iter.Timeout() {
 				continue
 			}
-			if err := iter.Err(); err != nil {
-				if !isCappedPositionLost(err) {
+			if err := iter.Close(); err != nil && !isCappedPositionLost(err) {
+				// The capped collection is replaced with a regular one the
+				// first time the logs of the app are pruned.
+				var checkErr error
+				capped, checkErr = isCapped(coll)
+				if capped || checkErr != nil {
 					log.Errorf("error tailing logs: %v", err)
-					iter.Close()
 					return
 				}
 			}
-			iter.Close()
-			query = coll.Find(mkQuery())
-			iter = query.Sort("$natural").Tail(tailTimeout)
+			if !capped {
+				select {
+				case <-quit:
+					return
+				case <-time.After(logPollInterval):
+				}
+			}
+			iter = newIter()
 		}
 	}()
 	l := logListener{c: c, logConn: conn, quit: quit}
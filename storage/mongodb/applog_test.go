@@ -5,7 +5,13 @@
 package mongodb
 
 import (
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/storage/storagetest"
+	"github.com/tsuru/tsuru/types/app"
 	check "gopkg.in/check.v1"
 )
 
@@ -13,3 +19,50 @@ var _ = check.Suite(&storagetest.AppLogSuite{
 	AppLogStorage: &applogStorage{},
 	SuiteHooks:    &mongodbBaseTest{name: "applog"},
 })
+
+type applogPruneSuite struct {
+	mongodbBaseTest
+}
+
+var _ = check.Suite(&applogPruneSuite{mongodbBaseTest{name: "applog_prune"}})
+
+func (s *applogPruneSuite) TestPruneConvertsToRegularCollection(c *check.C) {
+	storage := &applogStorage{}
+	now := time.Now().UTC()
+	for i := 0; i < 10; i++ {
+		err := storage.InsertApp("myapp", &app.Applog{Date: now, Message: strings.Repeat("x", 100), AppName: "myapp"})
+		c.Assert(err, check.IsNil)
+	}
+	err := storage.Prune("myapp", app.LogRetention{MaxAge: time.Hour})
+	c.Assert(err, check.IsNil)
+	conn, err := db.LogConn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	coll := conn.AppLogCollection("myapp")
+	capped, err := isCapped(coll)
+	c.Assert(err, check.IsNil)
+	c.Assert(capped, check.Equals, false)
+	indexes, err := coll.Indexes()
+	c.Assert(err, check.IsNil)
+	var expireAfter time.Duration
+	for _, idx := range indexes {
+		if idx.Name == logTTLIndexName {
+			expireAfter = idx.ExpireAfter
+		}
+	}
+	c.Assert(expireAfter, check.Equals, time.Hour)
+	count, err := coll.Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 10)
+	err = storage.Prune("myapp", app.LogRetention{MaxAge: 2 * time.Hour, MaxSize: 500})
+	c.Assert(err, check.IsNil)
+	var stats struct {
+		Size int64 `bson:"size"`
+	}
+	err = coll.Database.Run(bson.D{{Name: "collStats", Value: coll.Name}}, &stats)
+	c.Assert(err, check.IsNil)
+	c.Assert(stats.Size <= 500, check.Equals, true)
+	logs, err := storage.List(app.ListLogArgs{AppName: "myapp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.Not(check.HasLen), 0)
+}
@@ -30,6 +30,8 @@ type cluster struct {
 	CustomData  map[string]string `bson:",omitempty"`
 	CreateData  map[string]string `bson:",omitempty"`
 	Default     bool
+	Weight      int       `bson:",omitempty"`
+	Drain       bool      `bson:",omitempty"`
 	Writer      io.Writer `bson:"-"`
 }
 
@@ -44,15 +46,20 @@ func (s *clusterStorage) Upsert(c provision.Cluster) error {
 	}
 	defer conn.Close()
 	coll := clustersCollection(conn)
-	updates := bson.M{}
 	if len(c.Pools) > 0 {
-		updates["$pullAll"] = bson.M{"pools": c.Pools}
+		query := bson.M{"provisioner": c.Provisioner, "_id": bson.M{"$ne": c.Name}}
+		if c.Weight > 0 {
+			// Weighted clusters may share pools with each other, only
+			// clusters without weight lose the pools.
+			query["weight"] = bson.M{"$in": []interface{}{0, nil}}
+		}
+		_, err = coll.UpdateAll(query, bson.M{"$pullAll": bson.M{"pools": c.Pools}})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 	}
 	if c.Default {
-		updates["$set"] = bson.M{"default": false}
-	}
-	if len(updates) > 0 {
-		_, err = coll.UpdateAll(bson.M{"provisioner": c.Provisioner}, updates)
+		_, err = coll.UpdateAll(bson.M{"provisioner": c.Provisioner}, bson.M{"$set": bson.M{"default": false}})
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -96,7 +103,7 @@ func (s *clusterStorage) FindByPool(provisioner, pool string) (*provision.Cluste
 	coll := clustersCollection(conn)
 	var c cluster
 	if pool != "" {
-		err = coll.Find(bson.M{"provisioner": provisioner, "pools": pool}).One(&c)
+		err = coll.Find(bson.M{"provisioner": provisioner, "pools": pool}).Sort("drain", "-weight", "_id").One(&c)
 	}
 	if pool == "" || err == mgo.ErrNotFound {
 		err = coll.Find(bson.M{"provisioner": provisioner, "default": true}).One(&c)
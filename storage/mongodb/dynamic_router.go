@@ -0,0 +1,95 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	dbStorage "github.com/tsuru/tsuru/db/storage"
+	"github.com/tsuru/tsuru/types/router"
+)
+
+type dynamicRouterStorage struct{}
+
+var _ router.DynamicRouterStorage = &dynamicRouterStorage{}
+
+type dynamicRouter struct {
+	Name    string `bson:"_id"`
+	Type    string
+	Config  map[string]interface{} `bson:",omitempty"`
+	Default bool
+}
+
+func dynamicRoutersCollection(conn *db.Storage) *dbStorage.Collection {
+	return conn.Collection("dynamic_routers")
+}
+
+func (s *dynamicRouterStorage) Save(r router.DynamicRouter) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	coll := dynamicRoutersCollection(conn)
+	if r.Default {
+		_, err = coll.UpdateAll(bson.M{"_id": bson.M{"$ne": r.Name}}, bson.M{"$set": bson.M{"default": false}})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	_, err = coll.UpsertId(r.Name, dynamicRouter(r))
+	return errors.WithStack(err)
+}
+
+func (s *dynamicRouterStorage) Get(name string) (*router.DynamicRouter, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var r dynamicRouter
+	err = dynamicRoutersCollection(conn).FindId(name).One(&r)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			err = router.ErrDynamicRouterNotFound
+		}
+		return nil, err
+	}
+	dr := router.DynamicRouter(r)
+	return &dr, nil
+}
+
+func (s *dynamicRouterStorage) List() ([]router.DynamicRouter, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var routers []dynamicRouter
+	err = dynamicRoutersCollection(conn).Find(nil).Sort("_id").All(&routers)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]router.DynamicRouter, len(routers))
+	for i, r := range routers {
+		result[i] = router.DynamicRouter(r)
+	}
+	return result, nil
+}
+
+func (s *dynamicRouterStorage) Remove(name string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = dynamicRoutersCollection(conn).RemoveId(name)
+	if err == mgo.ErrNotFound {
+		return router.ErrDynamicRouterNotFound
+	}
+	return err
+}
@@ -24,6 +24,7 @@ func init() {
 		ServiceBrokerCatalogCacheStorage: serviceBrokerCatalogCacheStorage(),
 		AppLogStorage:                    &applogStorage{},
 		InstanceTrackerStorage:           &instanceTrackerStorage{},
+		DynamicRouterStorage:             &dynamicRouterStorage{},
 	}
 	storage.RegisterDbDriver("mongodb", mongodbDriver)
 }
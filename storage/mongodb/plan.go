@@ -17,11 +17,13 @@ var _ app.PlanStorage = &PlanStorage{}
 type PlanStorage struct{}
 
 type plan struct {
-	Name     string `bson:"_id"`
-	Memory   int64
-	Swap     int64
-	CpuShare int
-	Default  bool
+	Name       string `bson:"_id"`
+	Memory     int64
+	Swap       int64
+	CpuShare   int
+	CPURequest int `bson:",omitempty"`
+	CPULimit   int `bson:",omitempty"`
+	Default    bool
 }
 
 func plansCollection(conn *db.Storage) *dbStorage.Collection {
@@ -6,6 +6,7 @@ package mongodb
 
 import (
 	"strings"
+	"time"
 
 	mgo "github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
@@ -25,6 +26,16 @@ func webhookCollection(conn *db.Storage) *dbStorage.Collection {
 	return coll
 }
 
+// webhookDeliveryTTL is how long the history of webhook deliveries is kept.
+const webhookDeliveryTTL = 30 * 24 * time.Hour
+
+func webhookDeliveryCollection(conn *db.Storage) *dbStorage.Collection {
+	coll := conn.Collection("webhook_deliveries")
+	coll.EnsureIndex(mgo.Index{Key: []string{"webhook", "-timestamp"}})
+	coll.EnsureIndex(mgo.Index{Key: []string{"timestamp"}, ExpireAfter: webhookDeliveryTTL})
+	return coll
+}
+
 var _ event.WebhookStorage = &webhookStorage{}
 
 func (s *webhookStorage) Insert(w event.Webhook) error {
@@ -124,3 +135,47 @@ func (s *webhookStorage) Delete(name string) error {
 	}
 	return err
 }
+
+func (s *webhookStorage) InsertDelivery(d event.WebhookDelivery) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if d.ID == "" {
+		d.ID = bson.NewObjectId().Hex()
+	}
+	return webhookDeliveryCollection(conn).Insert(d)
+}
+
+func (s *webhookStorage) FindDeliveries(webhookName string, limit int) ([]event.WebhookDelivery, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	query := webhookDeliveryCollection(conn).Find(bson.M{"webhook": webhookName}).Sort("-timestamp", "-_id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var deliveries []event.WebhookDelivery
+	err = query.All(&deliveries)
+	return deliveries, err
+}
+
+func (s *webhookStorage) FindDelivery(id string) (*event.WebhookDelivery, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var result event.WebhookDelivery
+	err = webhookDeliveryCollection(conn).FindId(id).One(&result)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			err = event.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return &result, nil
+}
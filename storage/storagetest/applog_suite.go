@@ -258,6 +258,170 @@ func (s *AppLogSuite) TestLogStorageListEmpty(c *check.C) {
 	c.Assert(logs, check.DeepEquals, []app.Applog{})
 }
 
+func (s *AppLogSuite) TestLogStorageListTimeRange(c *check.C) {
+	base := time.Date(2019, 5, 10, 14, 0, 0, 0, time.UTC)
+	var logs []*app.Applog
+	for i := 0; i < 10; i++ {
+		logs = append(logs, &app.Applog{
+			Date: base.Add(time.Duration(i) * time.Minute), Message: strconv.Itoa(i), Source: "web", AppName: "myapp", Unit: "u1",
+		})
+	}
+	err := s.AppLogStorage.InsertApp("myapp", logs...)
+	c.Assert(err, check.IsNil)
+	result, err := s.AppLogStorage.List(app.ListLogArgs{
+		AppName: "myapp",
+		Since:   base.Add(2 * time.Minute),
+		Until:   base.Add(5 * time.Minute),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 3)
+	c.Assert(result[0].Message, check.Equals, "2")
+	c.Assert(result[2].Message, check.Equals, "4")
+	result, err = s.AppLogStorage.List(app.ListLogArgs{
+		AppName: "myapp",
+		Since:   base.Add(8 * time.Minute),
+		Limit:   10,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 2)
+	c.Assert(result[0].Message, check.Equals, "8")
+	c.Assert(result[1].Message, check.Equals, "9")
+	result, err = s.AppLogStorage.List(app.ListLogArgs{
+		AppName: "myapp",
+		Until:   base.Add(5 * time.Minute),
+		Limit:   2,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 2)
+	c.Assert(result[0].Message, check.Equals, "3")
+	c.Assert(result[1].Message, check.Equals, "4")
+}
+
+func (s *AppLogSuite) TestLogStorageListMessageFilter(c *check.C) {
+	addLog(c, s.AppLogStorage, "myapp", "starting server", "web", "u1")
+	addLog(c, s.AppLogStorage, "myapp", "process killed: OOM", "web", "u1")
+	addLog(c, s.AppLogStorage, "myapp", "request finished in 12ms", "web", "u1")
+	addLog(c, s.AppLogStorage, "myapp", "process killed: OOM", "worker", "u2")
+	logs, err := s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Message: "OOM"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+	c.Assert(logs[0].Source, check.Equals, "web")
+	c.Assert(logs[1].Source, check.Equals, "worker")
+	logs, err = s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Message: "in [0-9]+ms", Source: "web"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "request finished in 12ms")
+	logs, err = s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Message: "OOM", Source: "web", InvertFilters: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Source, check.Equals, "worker")
+}
+
+func (s *AppLogSuite) TestLogStorageListFieldsFilter(c *check.C) {
+	err := s.AppLogStorage.InsertApp("myapp", []*app.Applog{
+		{Message: "1", Source: "web", AppName: "myapp", Fields: map[string]string{"level": "info", "trace_id": "abc"}},
+		{Message: "2", Source: "web", AppName: "myapp", Fields: map[string]string{"level": "error", "trace_id": "abc"}},
+		{Message: "3", Source: "web", AppName: "myapp", Fields: map[string]string{"level": "error", "trace_id": "def"}},
+		{Message: "4", Source: "web", AppName: "myapp"},
+	}...)
+	c.Assert(err, check.IsNil)
+	logs, err := s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Level: "error"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+	c.Assert(logs[0].Message, check.Equals, "2")
+	c.Assert(logs[0].Fields, check.DeepEquals, map[string]string{"level": "error", "trace_id": "abc"})
+	c.Assert(logs[1].Message, check.Equals, "3")
+	logs, err = s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Level: "warn", Fields: map[string]string{"trace_id": "abc"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 0)
+	logs, err = s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Level: "ERROR", Fields: map[string]string{"trace_id": "abc"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "2")
+}
+
+func (s *AppLogSuite) TestLogStoragePruneByAge(c *check.C) {
+	pruner, ok := s.AppLogStorage.(app.AppLogPruner)
+	if !ok {
+		c.Skip("storage does not support pruning")
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	err := s.AppLogStorage.InsertApp("myapp",
+		&app.Applog{Date: now.Add(-48 * time.Hour), Message: "old1", Source: "web", AppName: "myapp", Unit: "u1"},
+		&app.Applog{Date: now.Add(-25 * time.Hour), Message: "old2", Source: "web", AppName: "myapp", Unit: "u1"},
+		&app.Applog{Date: now.Add(-time.Hour), Message: "new1", Source: "web", AppName: "myapp", Unit: "u1"},
+		&app.Applog{Date: now.Add(-time.Minute), Message: "new2", Source: "web", AppName: "myapp", Unit: "u1"},
+	)
+	c.Assert(err, check.IsNil)
+	err = s.AppLogStorage.InsertApp("otherapp",
+		&app.Applog{Date: now.Add(-48 * time.Hour), Message: "other", Source: "web", AppName: "otherapp", Unit: "u1"},
+	)
+	c.Assert(err, check.IsNil)
+	err = pruner.Prune("myapp", app.LogRetention{MaxAge: 24 * time.Hour})
+	c.Assert(err, check.IsNil)
+	logs, err := s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Since: now.Add(-72 * time.Hour)})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+	c.Assert(logs[0].Message, check.Equals, "new1")
+	c.Assert(logs[1].Message, check.Equals, "new2")
+	logs, err = s.AppLogStorage.List(app.ListLogArgs{AppName: "otherapp", Since: now.Add(-72 * time.Hour)})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+}
+
+func (s *AppLogSuite) TestLogStorageWatchAfterPrune(c *check.C) {
+	pruner, ok := s.AppLogStorage.(app.AppLogPruner)
+	if !ok {
+		c.Skip("storage does not support pruning")
+	}
+	addLog(c, s.AppLogStorage, "myapp", "old", "web", "u1")
+	err := pruner.Prune("myapp", app.LogRetention{MaxAge: time.Hour})
+	c.Assert(err, check.IsNil)
+	l, err := s.AppLogStorage.Watch("myapp", "", "")
+	c.Assert(err, check.IsNil)
+	defer l.Close()
+	addLog(c, s.AppLogStorage, "myapp", "new", "web", "u1")
+	select {
+	case logMsg := <-l.Chan():
+		c.Assert(logMsg.Message, check.Equals, "new")
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for log entry")
+	}
+}
+
+func (s *AppLogSuite) TestLogStorageListCursorSameDate(c *check.C) {
+	date := time.Now().UTC().Truncate(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		err := s.AppLogStorage.InsertApp("myapp", &app.Applog{Date: date, Message: strconv.Itoa(i), Source: "web", AppName: "myapp", Unit: "u1"})
+		c.Assert(err, check.IsNil)
+	}
+	logs, err := s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Limit: 2})
+	c.Assert(err, check.IsNil)
+	cursor := app.NextLogCursor(logs, nil)
+	if cursor == nil || cursor.ID == "" {
+		c.Skip("storage does not support cursors")
+	}
+	pages := [][]string{messages(logs)}
+	for {
+		logs, err = s.AppLogStorage.List(app.ListLogArgs{AppName: "myapp", Limit: 2, Cursor: cursor})
+		c.Assert(err, check.IsNil)
+		if len(logs) == 0 {
+			break
+		}
+		pages = append(pages, messages(logs))
+		cursor = app.NextLogCursor(logs, cursor)
+	}
+	c.Assert(pages, check.DeepEquals, [][]string{{"3", "4"}, {"1", "2"}, {"0"}})
+}
+
+func messages(logs []app.Applog) []string {
+	var msgs []string
+	for _, l := range logs {
+		msgs = append(msgs, l.Message)
+	}
+	return msgs
+}
+
 func compareLogsNoDate(c *check.C, logs1 []app.Applog, logs2 []app.Applog) {
 	for i := range logs1 {
 		logs1[i].MongoID = ""
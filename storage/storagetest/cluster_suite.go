@@ -138,6 +138,38 @@ func (s *ClusterSuite) TestFindClusterByPool(c *check.C) {
 	c.Assert(cluster, check.IsNil)
 }
 
+func (s *ClusterSuite) TestUpsertWeightedClustersSharePools(c *check.C) {
+	err := s.ClusterStorage.Upsert(provision.Cluster{Name: "c1", Provisioner: "kubernetes", Pools: []string{"pool-a", "pool-b"}})
+	c.Assert(err, check.IsNil)
+	err = s.ClusterStorage.Upsert(provision.Cluster{Name: "c2", Provisioner: "kubernetes", Pools: []string{"pool-a"}, Weight: 1})
+	c.Assert(err, check.IsNil)
+	err = s.ClusterStorage.Upsert(provision.Cluster{Name: "c3", Provisioner: "kubernetes", Pools: []string{"pool-a"}, Weight: 2})
+	c.Assert(err, check.IsNil)
+	c1, err := s.ClusterStorage.FindByName("c1")
+	c.Assert(err, check.IsNil)
+	c.Assert(c1.Pools, check.DeepEquals, []string{"pool-b"})
+	c2, err := s.ClusterStorage.FindByName("c2")
+	c.Assert(err, check.IsNil)
+	c.Assert(c2.Pools, check.DeepEquals, []string{"pool-a"})
+	c.Assert(c2.Weight, check.Equals, 1)
+	cluster, err := s.ClusterStorage.FindByPool("kubernetes", "pool-a")
+	c.Assert(err, check.IsNil)
+	c.Assert(cluster.Name, check.Equals, "c3")
+	err = s.ClusterStorage.Upsert(provision.Cluster{Name: "c3", Provisioner: "kubernetes", Pools: []string{"pool-a"}, Weight: 2, Drain: true})
+	c.Assert(err, check.IsNil)
+	cluster, err = s.ClusterStorage.FindByPool("kubernetes", "pool-a")
+	c.Assert(err, check.IsNil)
+	c.Assert(cluster.Name, check.Equals, "c2")
+	err = s.ClusterStorage.Upsert(provision.Cluster{Name: "c4", Provisioner: "kubernetes", Pools: []string{"pool-a"}})
+	c.Assert(err, check.IsNil)
+	c2, err = s.ClusterStorage.FindByName("c2")
+	c.Assert(err, check.IsNil)
+	c.Assert(c2.Pools, check.HasLen, 0)
+	cluster, err = s.ClusterStorage.FindByPool("kubernetes", "pool-a")
+	c.Assert(err, check.IsNil)
+	c.Assert(cluster.Name, check.Equals, "c4")
+}
+
 func (s *ClusterSuite) TestFindClusterByPoolNoCluster(c *check.C) {
 	_, err := s.ClusterStorage.FindByPool("swarm", "pool-a")
 	c.Assert(err, check.Equals, provision.ErrNoCluster)
@@ -0,0 +1,101 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storagetest
+
+import (
+	"github.com/tsuru/tsuru/types/router"
+	check "gopkg.in/check.v1"
+)
+
+type DynamicRouterSuite struct {
+	SuiteHooks
+	DynamicRouterStorage router.DynamicRouterStorage
+}
+
+func (s *DynamicRouterSuite) TestSaveNewRouter(c *check.C) {
+	r := router.DynamicRouter{
+		Name: "myrouter",
+		Type: "api",
+		Config: map[string]interface{}{
+			"api-url": "http://router.io",
+			"headers": map[string]interface{}{"x-key": "value"},
+		},
+	}
+	err := s.DynamicRouterStorage.Save(r)
+	c.Assert(err, check.IsNil)
+	dr, err := s.DynamicRouterStorage.Get("myrouter")
+	c.Assert(err, check.IsNil)
+	c.Assert(dr.Name, check.Equals, "myrouter")
+	c.Assert(dr.Type, check.Equals, "api")
+	c.Assert(dr.Config["api-url"], check.Equals, "http://router.io")
+	c.Assert(dr.Config["headers"], check.NotNil)
+	c.Assert(dr.Default, check.Equals, false)
+}
+
+func (s *DynamicRouterSuite) TestSaveExistingRouter(c *check.C) {
+	err := s.DynamicRouterStorage.Save(router.DynamicRouter{Name: "myrouter", Type: "api"})
+	c.Assert(err, check.IsNil)
+	err = s.DynamicRouterStorage.Save(router.DynamicRouter{
+		Name:   "myrouter",
+		Type:   "galeb",
+		Config: map[string]interface{}{"domain": "galeb.io"},
+	})
+	c.Assert(err, check.IsNil)
+	dr, err := s.DynamicRouterStorage.Get("myrouter")
+	c.Assert(err, check.IsNil)
+	c.Assert(dr.Type, check.Equals, "galeb")
+	c.Assert(dr.Config, check.DeepEquals, map[string]interface{}{"domain": "galeb.io"})
+}
+
+func (s *DynamicRouterSuite) TestSaveDefaultRouter(c *check.C) {
+	err := s.DynamicRouterStorage.Save(router.DynamicRouter{Name: "r1", Type: "api", Default: true})
+	c.Assert(err, check.IsNil)
+	err = s.DynamicRouterStorage.Save(router.DynamicRouter{Name: "r2", Type: "api", Default: true})
+	c.Assert(err, check.IsNil)
+	dr, err := s.DynamicRouterStorage.Get("r1")
+	c.Assert(err, check.IsNil)
+	c.Assert(dr.Default, check.Equals, false)
+	dr, err = s.DynamicRouterStorage.Get("r2")
+	c.Assert(err, check.IsNil)
+	c.Assert(dr.Default, check.Equals, true)
+}
+
+func (s *DynamicRouterSuite) TestGetRouterNotFound(c *check.C) {
+	_, err := s.DynamicRouterStorage.Get("myrouter")
+	c.Assert(err, check.Equals, router.ErrDynamicRouterNotFound)
+}
+
+func (s *DynamicRouterSuite) TestListRouters(c *check.C) {
+	err := s.DynamicRouterStorage.Save(router.DynamicRouter{Name: "r2", Type: "api"})
+	c.Assert(err, check.IsNil)
+	err = s.DynamicRouterStorage.Save(router.DynamicRouter{Name: "r1", Type: "galeb"})
+	c.Assert(err, check.IsNil)
+	routers, err := s.DynamicRouterStorage.List()
+	c.Assert(err, check.IsNil)
+	c.Assert(routers, check.DeepEquals, []router.DynamicRouter{
+		{Name: "r1", Type: "galeb"},
+		{Name: "r2", Type: "api"},
+	})
+}
+
+func (s *DynamicRouterSuite) TestListRoutersEmpty(c *check.C) {
+	routers, err := s.DynamicRouterStorage.List()
+	c.Assert(err, check.IsNil)
+	c.Assert(routers, check.HasLen, 0)
+}
+
+func (s *DynamicRouterSuite) TestRemoveRouter(c *check.C) {
+	err := s.DynamicRouterStorage.Save(router.DynamicRouter{Name: "myrouter", Type: "api"})
+	c.Assert(err, check.IsNil)
+	err = s.DynamicRouterStorage.Remove("myrouter")
+	c.Assert(err, check.IsNil)
+	_, err = s.DynamicRouterStorage.Get("myrouter")
+	c.Assert(err, check.Equals, router.ErrDynamicRouterNotFound)
+}
+
+func (s *DynamicRouterSuite) TestRemoveRouterNotFound(c *check.C) {
+	err := s.DynamicRouterStorage.Remove("myrouter")
+	c.Assert(err, check.Equals, router.ErrDynamicRouterNotFound)
+}
@@ -41,6 +41,15 @@ func (s *PlanSuite) TestInsertPlan(c *check.C) {
 	c.Assert(plan.Default, check.Equals, p.Default)
 }
 
+func (s *PlanSuite) TestInsertPlanWithCPU(c *check.C) {
+	p := app.Plan{Name: "myplan", Memory: 4194304, CpuShare: 512, CPURequest: 500, CPULimit: 1000}
+	err := s.PlanStorage.Insert(p)
+	c.Assert(err, check.IsNil)
+	plan, err := s.PlanStorage.FindByName(p.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(*plan, check.Equals, p)
+}
+
 func (s *PlanSuite) TestInsertDuplicatePlan(c *check.C) {
 	p := app.Plan{Name: "myplan", Default: true}
 	err := s.PlanStorage.Insert(p)
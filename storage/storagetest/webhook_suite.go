@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"time"
 
 	eventTypes "github.com/tsuru/tsuru/types/event"
 	check "gopkg.in/check.v1"
@@ -239,3 +240,62 @@ func (s *WebhookSuite) TestFindByNameNotFound(c *check.C) {
 	_, err := s.WebhookStorage.FindByName("wh1")
 	c.Assert(err, check.Equals, eventTypes.ErrWebhookNotFound)
 }
+
+func (s *WebhookSuite) TestInsertAndFindDeliveries(c *check.C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 1; i <= 3; i++ {
+		err := s.WebhookStorage.InsertDelivery(eventTypes.WebhookDelivery{
+			ID:         fmt.Sprintf("d%d", i),
+			Webhook:    "wh1",
+			EventID:    "evt1",
+			Attempt:    i,
+			Timestamp:  now.Add(time.Duration(i) * time.Second),
+			StatusCode: http.StatusInternalServerError,
+		})
+		c.Assert(err, check.IsNil)
+	}
+	err := s.WebhookStorage.InsertDelivery(eventTypes.WebhookDelivery{Webhook: "wh2", Timestamp: now})
+	c.Assert(err, check.IsNil)
+	deliveries, err := s.WebhookStorage.FindDeliveries("wh1", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(deliveries, check.HasLen, 3)
+	var ids []string
+	for _, d := range deliveries {
+		ids = append(ids, d.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{"d3", "d2", "d1"})
+	deliveries, err = s.WebhookStorage.FindDeliveries("wh1", 2)
+	c.Assert(err, check.IsNil)
+	c.Assert(deliveries, check.HasLen, 2)
+	deliveries, err = s.WebhookStorage.FindDeliveries("wh2", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(deliveries, check.HasLen, 1)
+	c.Assert(deliveries[0].ID, check.Not(check.Equals), "")
+	delivery, err := s.WebhookStorage.FindDelivery("d2")
+	c.Assert(err, check.IsNil)
+	c.Assert(delivery.Webhook, check.Equals, "wh1")
+	c.Assert(delivery.EventID, check.Equals, "evt1")
+	c.Assert(delivery.Attempt, check.Equals, 2)
+	c.Assert(delivery.StatusCode, check.Equals, http.StatusInternalServerError)
+	c.Assert(delivery.Timestamp.Equal(now.Add(2*time.Second)), check.Equals, true)
+}
+
+func (s *WebhookSuite) TestFindDeliveryNotFound(c *check.C) {
+	_, err := s.WebhookStorage.FindDelivery("d1")
+	c.Assert(err, check.Equals, eventTypes.ErrWebhookDeliveryNotFound)
+}
+
+func (s *WebhookSuite) TestInsertWebhookWithType(c *check.C) {
+	w := eventTypes.Webhook{
+		Name:  "wh1",
+		Type:  eventTypes.WebhookTypeEmail,
+		Email: &eventTypes.WebhookEmail{To: []string{"a@example.com"}, Subject: "{{.Kind.Name}}"},
+	}
+	err := s.WebhookStorage.Insert(w)
+	c.Assert(err, check.IsNil)
+	webhook, err := s.WebhookStorage.FindByName(w.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(webhook.Type, check.Equals, eventTypes.WebhookTypeEmail)
+	c.Assert(webhook.Email, check.DeepEquals, &eventTypes.WebhookEmail{To: []string{"a@example.com"}, Subject: "{{.Kind.Name}}"})
+	c.Assert(webhook.Queue, check.IsNil)
+}
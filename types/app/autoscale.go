@@ -0,0 +1,44 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import "errors"
+
+var (
+	ErrAutoScaleNoTarget     = errors.New("at least one of cpu, memory or requests target must be set")
+	ErrAutoScaleInvalidUnits = errors.New("max units must be greater than zero and greater than or equal to min units")
+	ErrAutoScaleInvalidPct   = errors.New("cpu and memory targets must be percentages between 1 and 100")
+	ErrAutoScaleNegativeReqs = errors.New("requests target must not be negative")
+	ErrAutoScaleNotFound     = errors.New("autoscale not found for process")
+)
+
+// AutoScaleSpec describes how the units of an app process must be scaled
+// according to the metrics collected from them. Targets are the desired
+// average values per unit: CPU and memory as percentages of the unit limits
+// and requests as requests per second.
+type AutoScaleSpec struct {
+	Process        string  `json:"process"`
+	MinUnits       uint    `json:"minUnits"`
+	MaxUnits       uint    `json:"maxUnits"`
+	TargetCPU      int     `json:"targetCPU,omitempty"`
+	TargetMemory   int     `json:"targetMemory,omitempty"`
+	TargetRequests float64 `json:"targetRequests,omitempty"`
+}
+
+func (s *AutoScaleSpec) Validate() error {
+	if s.MaxUnits == 0 || s.MinUnits > s.MaxUnits {
+		return ErrAutoScaleInvalidUnits
+	}
+	if s.TargetCPU == 0 && s.TargetMemory == 0 && s.TargetRequests == 0 {
+		return ErrAutoScaleNoTarget
+	}
+	if s.TargetCPU < 0 || s.TargetCPU > 100 || s.TargetMemory < 0 || s.TargetMemory > 100 {
+		return ErrAutoScaleInvalidPct
+	}
+	if s.TargetRequests < 0 {
+		return ErrAutoScaleNegativeReqs
+	}
+	return nil
+}
@@ -19,6 +19,8 @@ var (
 	ErrPlanDefaultNotFound    = errors.New("default plan not found")
 	ErrLimitOfCpuShare        = errors.New("The minimum allowed cpu-shares is 2")
 	ErrLimitOfMemory          = errors.New("The minimum allowed memory is 4MB")
+	ErrLimitOfCPU             = errors.New("The minimum allowed cpu request and limit is 10 millicores")
+	ErrPlanCPURequest         = errors.New("The cpu request must not be greater than the cpu limit")
 	ErrPlatformNameMissing    = errors.New("Platform name is required.")
 	ErrPlatformImageMissing   = errors.New("Platform image is required.")
 	ErrPlatformNotFound       = errors.New("Platform doesn't exist.")
@@ -0,0 +1,92 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+const (
+	JobConcurrencyAllow   = "allow"
+	JobConcurrencyForbid  = "forbid"
+	JobConcurrencyReplace = "replace"
+
+	DefaultJobHistoryLimit = 3
+	maxJobHistoryLimit     = 100
+)
+
+var (
+	ErrJobNotFound           = errors.New("job not found")
+	ErrJobInvalidName        = errors.New("invalid job name, job name should have at most 30 characters, containing only lower case letters, numbers or dashes, starting with a letter")
+	ErrJobNoSchedule         = errors.New("job schedule is required")
+	ErrJobNoCommand          = errors.New("job command is required")
+	ErrJobInvalidConcurrency = errors.New("invalid job concurrency policy, valid policies are: allow, forbid and replace")
+	ErrJobInvalidHistory     = errors.New("job history limit must be between 1 and 100")
+
+	jobNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,29}$`)
+)
+
+// Job is a command run periodically in a new unit of an app, using the app
+// current image. Schedule is a standard five fields cron expression
+// evaluated in UTC. ConcurrencyPolicy defines what happens when a run is
+// due while the previous one is still running: both run (allow), the new
+// run is skipped (forbid) or the previous run is stopped (replace).
+// HistoryLimit is the number of finished runs kept by the provisioner,
+// DefaultJobHistoryLimit if not set.
+type Job struct {
+	Name              string    `json:"name"`
+	Schedule          string    `json:"schedule"`
+	Command           string    `json:"command"`
+	ConcurrencyPolicy string    `json:"concurrencyPolicy"`
+	HistoryLimit      int       `json:"historyLimit"`
+	CreatedAt         time.Time `json:"createdAt"`
+	LastRun           time.Time `json:"lastRun"`
+}
+
+// JobRun is the result of a single run of a job, stored in the event
+// created for the run. ExitCode is only set when known.
+type JobRun struct {
+	Job       string    `json:"job"`
+	Unit      string    `json:"unit,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	ExitCode  *int      `json:"exitCode,omitempty"`
+}
+
+// Validate checks the job fields, except for the schedule expression which
+// is validated by the scheduler, and sets default values for the optional
+// ones.
+func (j *Job) Validate() error {
+	if !jobNameRegexp.MatchString(j.Name) {
+		return ErrJobInvalidName
+	}
+	if j.Schedule == "" {
+		return ErrJobNoSchedule
+	}
+	if j.Command == "" {
+		return ErrJobNoCommand
+	}
+	switch j.ConcurrencyPolicy {
+	case "":
+		j.ConcurrencyPolicy = JobConcurrencyAllow
+	case JobConcurrencyAllow, JobConcurrencyForbid, JobConcurrencyReplace:
+	default:
+		return ErrJobInvalidConcurrency
+	}
+	if j.HistoryLimit == 0 {
+		j.HistoryLimit = DefaultJobHistoryLimit
+	}
+	if j.HistoryLimit < 0 || j.HistoryLimit > maxJobHistoryLimit {
+		return ErrJobInvalidHistory
+	}
+	return nil
+}
+
+// JobLogSource returns the source of the log entries of the runs of a job.
+func JobLogSource(name string) string {
+	return "job-" + name
+}
@@ -1,6 +1,9 @@
 package app
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/globalsign/mgo/bson"
@@ -29,6 +32,41 @@ type AppLogStorage interface {
 	Watch(appName, source, unit string) (LogWatcher, error)
 }
 
+// AppLogPruner is implemented by log services and storages able to remove
+// the entries of an app exceeding a retention policy.
+type AppLogPruner interface {
+	Prune(appName string, retention LogRetention) error
+}
+
+// AppLogLocalPruner is implemented by pruners of logs kept by each tsuru api
+// instance, which must be pruned in every instance instead of only in the
+// one elected to prune the logs.
+type AppLogLocalPruner interface {
+	AppLogPruner
+	PrunesLocalLogs() bool
+}
+
+// LogRetention limits the logs kept for an app by the age of the entries and
+// by the total size of the entries, in bytes. Zero values mean no limit.
+type LogRetention struct {
+	MaxAge  time.Duration `json:"maxAge"`
+	MaxSize int64         `json:"maxSize"`
+}
+
+// IsZero returns whether the retention has no limits.
+func (r LogRetention) IsZero() bool {
+	return r.MaxAge <= 0 && r.MaxSize <= 0
+}
+
+// Cutoff returns the date before which entries must be removed, or the zero
+// time if there's no age limit.
+func (r LogRetention) Cutoff(now time.Time) time.Time {
+	if r.MaxAge <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-r.MaxAge)
+}
+
 type ListLogArgs struct {
 	AppName       string
 	Source        string
@@ -36,6 +74,126 @@ type ListLogArgs struct {
 	Limit         int
 	InvertFilters bool
 	Token         auth.Token
+	// Since and Until restrict the listing to entries with Since <= Date <
+	// Until. Zero values are ignored.
+	Since time.Time
+	Until time.Time
+	// Message is a regular expression matched against the message of each
+	// entry. Unlike Source and Unit, it's not affected by InvertFilters.
+	Message string
+	// Cursor, when set, restricts the listing to the entries preceding the
+	// ones returned by a previous call.
+	Cursor *LogCursor
+	// Level and Fields restrict the listing to entries whose structured
+	// fields have the given values. Level is a shortcut for the "level"
+	// field.
+	Level  string
+	Fields map[string]string
+}
+
+// FieldsFilter returns the fields filter, including the level, if any.
+func (a *ListLogArgs) FieldsFilter() map[string]string {
+	if a.Level == "" {
+		return a.Fields
+	}
+	fields := map[string]string{LogFieldLevel: NormalizeLogLevel(a.Level)}
+	for k, v := range a.Fields {
+		if k != LogFieldLevel {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// LogCursor identifies the oldest entry returned by a log listing. Entries
+// stored with an unique id, as in MongoDB, are identified by their date and
+// ID, as the dates are stored with millisecond precision and may be shared
+// by many entries. Otherwise, Skip holds how many entries with Date were
+// already returned.
+type LogCursor struct {
+	Date time.Time
+	ID   string
+	Skip int
+}
+
+func (c LogCursor) String() string {
+	if c.ID != "" {
+		return fmt.Sprintf("%d.%s", c.Date.UnixNano(), c.ID)
+	}
+	return fmt.Sprintf("%d-%d", c.Date.UnixNano(), c.Skip)
+}
+
+// ParseLogCursor parses a cursor in the format returned by LogCursor.String.
+func ParseLogCursor(s string) (*LogCursor, error) {
+	if parts := strings.SplitN(s, ".", 2); len(parts) == 2 {
+		ts, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || parts[1] == "" {
+			return nil, fmt.Errorf("invalid log cursor %q", s)
+		}
+		return &LogCursor{Date: time.Unix(0, ts).UTC(), ID: parts[1]}, nil
+	}
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid log cursor %q", s)
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log cursor %q", s)
+	}
+	skip, err := strconv.Atoi(parts[1])
+	if err != nil || skip < 0 {
+		return nil, fmt.Errorf("invalid log cursor %q", s)
+	}
+	return &LogCursor{Date: time.Unix(0, ts).UTC(), Skip: skip}, nil
+}
+
+// NextLogCursor returns the cursor pointing to the oldest entry in logs,
+// which must be sorted by date, or nil if logs is empty. previous is the
+// cursor used to list logs, if any.
+func NextLogCursor(logs []Applog, previous *LogCursor) *LogCursor {
+	if len(logs) == 0 {
+		return nil
+	}
+	cursor := &LogCursor{Date: logs[0].Date.UTC()}
+	if logs[0].MongoID.Valid() {
+		cursor.ID = logs[0].MongoID.Hex()
+		return cursor
+	}
+	for i := range logs {
+		if !logs[i].Date.Equal(cursor.Date) {
+			break
+		}
+		cursor.Skip++
+	}
+	if previous != nil && previous.Date.Equal(cursor.Date) {
+		cursor.Skip += previous.Skip
+	}
+	return cursor
+}
+
+const (
+	LogFieldLevel   = "level"
+	LogFieldTraceID = "trace_id"
+)
+
+var logLevelAliases = map[string]string{
+	"warn":        "warning",
+	"err":         "error",
+	"eror":        "error",
+	"crit":        "critical",
+	"information": "info",
+	"dbug":        "debug",
+	"trce":        "trace",
+}
+
+// NormalizeLogLevel returns the canonical lowercase name of a log level, so
+// that entries using different conventions may be filtered together.
+func NormalizeLogLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if alias, ok := logLevelAliases[level]; ok {
+		return alias
+	}
+	return level
 }
 
 // Applog represents a log entry.
@@ -46,4 +204,7 @@ type Applog struct {
 	Source  string
 	AppName string
 	Unit    string
+	// Fields holds the fields extracted from structured (JSON or logfmt)
+	// messages, such as the level and trace_id.
+	Fields map[string]string `bson:",omitempty" json:",omitempty"`
 }
@@ -0,0 +1,90 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+const maxLabelValueLength = 63
+
+var (
+	ErrMetadataNameEmpty = errors.New("metadata name must not be empty")
+
+	metadataNameRegexp       = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	metadataLabelValueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// MetadataItem is a single label or annotation of an app. Delete is only
+// used in updates, removing the item with the same name.
+type MetadataItem struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Delete bool   `json:"delete,omitempty" bson:"-"`
+}
+
+// Metadata holds the labels and annotations of an app. Provisioners may
+// apply them to the units of the app and use them as app specific settings.
+type Metadata struct {
+	Labels      []MetadataItem `json:"labels,omitempty" bson:",omitempty"`
+	Annotations []MetadataItem `json:"annotations,omitempty" bson:",omitempty"`
+}
+
+func (m *Metadata) Empty() bool {
+	return len(m.Labels) == 0 && len(m.Annotations) == 0
+}
+
+func (m *Metadata) Validate() error {
+	for _, items := range [][]MetadataItem{m.Labels, m.Annotations} {
+		for _, item := range items {
+			if item.Name == "" {
+				return ErrMetadataNameEmpty
+			}
+			if !metadataNameRegexp.MatchString(item.Name) {
+				return fmt.Errorf("invalid metadata name %q", item.Name)
+			}
+		}
+	}
+	for _, item := range m.Labels {
+		if len(item.Value) > maxLabelValueLength || !metadataLabelValueRegexp.MatchString(item.Value) {
+			return fmt.Errorf("invalid value %q for label %q", item.Value, item.Name)
+		}
+	}
+	return nil
+}
+
+// Update adds, replaces or removes the items in other.
+func (m *Metadata) Update(other Metadata) {
+	m.Labels = updateMetadataItems(m.Labels, other.Labels)
+	m.Annotations = updateMetadataItems(m.Annotations, other.Annotations)
+}
+
+// Annotation returns the value of the annotation with the given name.
+func (m *Metadata) Annotation(name string) (string, bool) {
+	for _, item := range m.Annotations {
+		if item.Name == name {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+func updateMetadataItems(items, changes []MetadataItem) []MetadataItem {
+	for _, change := range changes {
+		var result []MetadataItem
+		for _, item := range items {
+			if item.Name != change.Name {
+				result = append(result, item)
+			}
+		}
+		if !change.Delete {
+			result = append(result, MetadataItem{Name: change.Name, Value: change.Value})
+		}
+		items = result
+	}
+	return items
+}
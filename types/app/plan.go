@@ -4,12 +4,16 @@
 
 package app
 
+// Plan defines the resources of each unit of apps. CPURequest and CPULimit
+// are in millicores, with zero meaning unset.
 type Plan struct {
-	Name     string `json:"name"`
-	Memory   int64  `json:"memory"`
-	Swap     int64  `json:"swap"`
-	CpuShare int    `json:"cpushare"`
-	Default  bool   `json:"default,omitempty"`
+	Name       string `json:"name"`
+	Memory     int64  `json:"memory"`
+	Swap       int64  `json:"swap"`
+	CpuShare   int    `json:"cpushare"`
+	CPURequest int    `json:"cpurequest,omitempty"`
+	CPULimit   int    `json:"cpulimit,omitempty"`
+	Default    bool   `json:"default,omitempty"`
 }
 
 type PlanService interface {
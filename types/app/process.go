@@ -0,0 +1,83 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import "errors"
+
+var (
+	ErrProcessNegativeResources = errors.New("process resources must not be negative")
+	ErrProcessMemoryRequest     = errors.New("memory request must not be greater than the memory limit")
+	ErrProcessCPURequest        = errors.New("cpu request must not be greater than the cpu limit")
+)
+
+// Process holds the settings of a single process of an app. The units of the
+// process use Plan, when set, instead of the app plan, and the resources set
+// in Resources instead of the ones from the plan.
+type Process struct {
+	Name      string           `json:"name"`
+	Plan      Plan             `json:"plan"`
+	Resources ProcessResources `json:"resources"`
+}
+
+// ProcessResources are the resources of each unit of a process. Memory and
+// Swap are in bytes and CPURequest and CPULimit in millicores. Zero values
+// are unset. MemoryRequest, CPURequest and CPULimit are only enforced by
+// provisioners supporting them.
+type ProcessResources struct {
+	Memory        int64 `json:"memory,omitempty"`
+	MemoryRequest int64 `json:"memoryRequest,omitempty" bson:"memoryrequest,omitempty"`
+	Swap          int64 `json:"swap,omitempty"`
+	CpuShare      int   `json:"cpushare,omitempty"`
+	CPURequest    int   `json:"cpuRequest,omitempty" bson:"cpurequest,omitempty"`
+	CPULimit      int   `json:"cpuLimit,omitempty" bson:"cpulimit,omitempty"`
+}
+
+func (r *ProcessResources) Validate() error {
+	if r.Memory < 0 || r.MemoryRequest < 0 || r.Swap < 0 || r.CpuShare < 0 || r.CPURequest < 0 || r.CPULimit < 0 {
+		return ErrProcessNegativeResources
+	}
+	if r.Memory > 0 && r.MemoryRequest > r.Memory {
+		return ErrProcessMemoryRequest
+	}
+	if r.CPULimit > 0 && r.CPURequest > r.CPULimit {
+		return ErrProcessCPURequest
+	}
+	return nil
+}
+
+// Override returns the resources with the values set in other replacing the
+// ones in r.
+func (r ProcessResources) Override(other ProcessResources) ProcessResources {
+	if other.Memory != 0 {
+		r.Memory = other.Memory
+	}
+	if other.MemoryRequest != 0 {
+		r.MemoryRequest = other.MemoryRequest
+	}
+	if other.Swap != 0 {
+		r.Swap = other.Swap
+	}
+	if other.CpuShare != 0 {
+		r.CpuShare = other.CpuShare
+	}
+	if other.CPURequest != 0 {
+		r.CPURequest = other.CPURequest
+	}
+	if other.CPULimit != 0 {
+		r.CPULimit = other.CPULimit
+	}
+	return r
+}
+
+// Resources returns the resources of the units of apps using the plan.
+func (p Plan) Resources() ProcessResources {
+	return ProcessResources{
+		Memory:     p.Memory,
+		Swap:       p.Swap,
+		CpuShare:   p.CpuShare,
+		CPURequest: p.CPURequest,
+		CPULimit:   p.CPULimit,
+	}
+}
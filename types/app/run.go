@@ -0,0 +1,37 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrRunNotFound           = errors.New("run not found")
+	ErrRunCancelNotSupported = errors.New("canceling runs is not supported by the app provisioner")
+)
+
+// AppRun is a command run in a new unit of an app detached from the client
+// connection. ExitCode is only set when known. Output holds the last lines of
+// the output of finished runs, the output of running commands is available in
+// the app log, with the source returned by RunLogSource.
+type AppRun struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	Owner     string    `json:"owner"`
+	Running   bool      `json:"running"`
+	Canceled  bool      `json:"canceled"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	ExitCode  *int      `json:"exitCode,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Output    string    `json:"output,omitempty"`
+}
+
+// RunLogSource returns the source of the log entries of a detached run.
+func RunLogSource(id string) string {
+	return "run-" + id
+}
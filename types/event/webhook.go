@@ -7,11 +7,22 @@ package event
 import (
 	"errors"
 	"net/http"
+	"time"
+)
+
+// Webhook types, defining how events are delivered. Webhooks without a type
+// are plain HTTP webhooks.
+const (
+	WebhookTypeHTTP  = "http"
+	WebhookTypeSlack = "slack"
+	WebhookTypeEmail = "email"
+	WebhookTypeQueue = "queue"
 )
 
 var (
-	ErrWebhookAlreadyExists = errors.New("webhook already exists with the same name")
-	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrWebhookAlreadyExists    = errors.New("webhook already exists with the same name")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
 )
 
 type WebhookEventFilter struct {
@@ -34,6 +45,50 @@ type Webhook struct {
 	Method      string             `json:"method" form:"method"`
 	Body        string             `json:"body" form:"body"`
 	Insecure    bool               `json:"insecure" form:"insecure"`
+	// Secret, if set, is used to sign the request body with HMAC-SHA256,
+	// the signature is sent in the X-Tsuru-Signature header. It's never
+	// returned by the API, and updates without a secret keep the current
+	// one.
+	Secret string `json:"secret,omitempty" form:"secret"`
+	// MaxAttempts is the number of times a delivery is attempted before
+	// giving up, including the first attempt. Zero means the default.
+	// Pending retries are kept in memory by the tsuru api instance handling
+	// the event, and are lost if the instance stops, the failed attempts
+	// may still be redelivered manually.
+	MaxAttempts int `json:"max_attempts" form:"max_attempts"`
+	// Type is one of the WebhookType constants, an empty type is handled as
+	// WebhookTypeHTTP.
+	Type  string        `json:"type,omitempty" form:"type"`
+	Email *WebhookEmail `json:"email,omitempty" form:"email" bson:",omitempty"`
+	Queue *WebhookQueue `json:"queue,omitempty" form:"queue" bson:",omitempty"`
+}
+
+// WebhookEmail holds the settings of webhooks of type WebhookTypeEmail. The
+// subject and the Body of the webhook may be templates.
+type WebhookEmail struct {
+	To      []string `json:"to" form:"to"`
+	Subject string   `json:"subject" form:"subject"`
+}
+
+// WebhookQueue holds the settings of webhooks of type WebhookTypeQueue.
+type WebhookQueue struct {
+	Name string `json:"name" form:"name"`
+}
+
+// WebhookDelivery is the record of an attempt to deliver an event to a
+// webhook.
+type WebhookDelivery struct {
+	ID         string        `json:"id" bson:"_id"`
+	Webhook    string        `json:"webhook"`
+	EventID    string        `json:"event_id"`
+	Attempt    int           `json:"attempt"`
+	Redelivery bool          `json:"redelivery"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Latency    time.Duration `json:"latency"`
+	StatusCode int           `json:"status_code"`
+	Response   string        `json:"response"`
+	Error      string        `json:"error"`
+	Success    bool          `json:"success"`
 }
 
 type WebhookService interface {
@@ -43,6 +98,8 @@ type WebhookService interface {
 	Delete(string) error
 	Find(string) (Webhook, error)
 	List([]string) ([]Webhook, error)
+	Deliveries(name string, limit int) ([]WebhookDelivery, error)
+	Redeliver(name, deliveryID string) error
 }
 
 type WebhookStorage interface {
@@ -52,4 +109,7 @@ type WebhookStorage interface {
 	FindByName(string) (*Webhook, error)
 	FindByEvent(f WebhookEventFilter, isSuccess bool) ([]Webhook, error)
 	Delete(string) error
+	InsertDelivery(WebhookDelivery) error
+	FindDeliveries(webhookName string, limit int) ([]WebhookDelivery, error)
+	FindDelivery(id string) (*WebhookDelivery, error)
 }
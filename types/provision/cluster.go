@@ -21,7 +21,14 @@ type Cluster struct {
 	CustomData  map[string]string `json:"custom_data"`
 	CreateData  map[string]string `json:"create_data"`
 	Default     bool              `json:"default"`
-	Writer      io.Writer         `json:"-"`
+	// Weight is the relative share of units this cluster receives for the
+	// apps in its pools. A pool may only span multiple clusters when all of
+	// them have a weight set.
+	Weight int `json:"weight"`
+	// Drain marks the cluster as being emptied: units are moved to the other
+	// clusters in its pools and it's no longer used as a router backend.
+	Drain  bool      `json:"drain"`
+	Writer io.Writer `json:"-"`
 }
 
 type ClusterHelpInfo struct {
@@ -7,9 +7,10 @@ package provision
 import "github.com/tsuru/tsuru/types/router"
 
 type TsuruYamlData struct {
-	Hooks       *TsuruYamlHooks            `json:"hooks,omitempty" bson:",omitempty"`
-	Healthcheck *TsuruYamlHealthcheck      `json:"healthcheck,omitempty" bson:",omitempty"`
-	Kubernetes  *TsuruYamlKubernetesConfig `json:"kubernetes,omitempty" bson:",omitempty"`
+	Hooks       *TsuruYamlHooks                   `json:"hooks,omitempty" bson:",omitempty"`
+	Healthcheck *TsuruYamlHealthcheck             `json:"healthcheck,omitempty" bson:",omitempty"`
+	Kubernetes  *TsuruYamlKubernetesConfig        `json:"kubernetes,omitempty" bson:",omitempty"`
+	Processes   map[string]TsuruYamlProcessConfig `json:"process_config,omitempty" yaml:"process_config" bson:"process_config,omitempty"`
 }
 
 type TsuruYamlHooks struct {
@@ -37,6 +38,94 @@ type TsuruYamlHealthcheck struct {
 	TimeoutSeconds  int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds" bson:"timeout_seconds,omitempty"`
 }
 
+// TsuruYamlProcessConfig holds the settings of a single process in the
+// Procfile.
+type TsuruYamlProcessConfig struct {
+	// Healthcheck is used to decide whether units of the process are ready
+	// to receive requests. For the web process, it takes precedence over
+	// the top level healthcheck.
+	Healthcheck *TsuruYamlHealthcheck `json:"healthcheck,omitempty" bson:",omitempty"`
+	// Liveness is used to decide whether units of the process must be
+	// restarted.
+	Liveness *TsuruYamlProbe `json:"liveness,omitempty" bson:",omitempty"`
+	// Startup is the time units of the process are given to start, before
+	// being checked by the liveness probe.
+	Startup   *TsuruYamlProbe     `json:"startup,omitempty" bson:",omitempty"`
+	Lifecycle *TsuruYamlLifecycle `json:"lifecycle,omitempty" bson:",omitempty"`
+	// SharedVolumes are empty volumes created for each unit of the process,
+	// which may be mounted by the process and its additional containers.
+	SharedVolumes []TsuruYamlSharedVolume `json:"shared_volumes,omitempty" yaml:"shared_volumes" bson:"shared_volumes,omitempty"`
+	// Sidecars are containers run alongside the process in each unit.
+	Sidecars []TsuruYamlContainer `json:"sidecars,omitempty" bson:",omitempty"`
+	// InitContainers are containers run to completion, in order, before
+	// the process starts in each unit.
+	InitContainers []TsuruYamlContainer `json:"init_containers,omitempty" yaml:"init_containers" bson:"init_containers,omitempty"`
+}
+
+// TsuruYamlSharedVolume is an empty volume shared by the containers of a
+// unit. It's mounted in Path in the process container, unless Path is
+// empty.
+type TsuruYamlSharedVolume struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty" bson:",omitempty"`
+}
+
+// TsuruYamlContainer is an additional container run in the units of a
+// process.
+type TsuruYamlContainer struct {
+	Name    string                    `json:"name"`
+	Image   string                    `json:"image"`
+	Command []string                  `json:"command,omitempty" bson:",omitempty"`
+	Env     map[string]string         `json:"env,omitempty" bson:",omitempty"`
+	Ports   []int                     `json:"ports,omitempty" bson:",omitempty"`
+	Mounts  []TsuruYamlContainerMount `json:"mounts,omitempty" bson:",omitempty"`
+}
+
+// TsuruYamlContainerMount mounts a shared volume of the process in Path.
+type TsuruYamlContainerMount struct {
+	Volume   string `json:"volume"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"read_only,omitempty" yaml:"read_only" bson:"read_only,omitempty"`
+}
+
+// TsuruYamlProbe is a check run in the units of a process. It runs Command,
+// when set, or makes a http request to Path, or opens a tcp connection to
+// Port.
+type TsuruYamlProbe struct {
+	Path                string            `json:"path,omitempty" bson:",omitempty"`
+	Port                int               `json:"port,omitempty" bson:",omitempty"`
+	Scheme              string            `json:"scheme,omitempty" bson:",omitempty"`
+	Headers             map[string]string `json:"headers,omitempty" bson:",omitempty"`
+	Command             []string          `json:"command,omitempty" bson:",omitempty"`
+	InitialDelaySeconds int               `json:"initial_delay_seconds,omitempty" yaml:"initial_delay_seconds" bson:"initial_delay_seconds,omitempty"`
+	IntervalSeconds     int               `json:"interval_seconds,omitempty" yaml:"interval_seconds" bson:"interval_seconds,omitempty"`
+	TimeoutSeconds      int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds" bson:"timeout_seconds,omitempty"`
+	AllowedFailures     int               `json:"allowed_failures,omitempty" yaml:"allowed_failures" bson:"allowed_failures,omitempty"`
+}
+
+// StartupSeconds returns the time, in seconds, the probe allows for a unit
+// to start.
+func (p *TsuruYamlProbe) StartupSeconds() int {
+	interval := p.IntervalSeconds
+	if interval == 0 {
+		interval = 10
+	}
+	failures := p.AllowedFailures
+	if failures == 0 {
+		failures = 3
+	}
+	return p.InitialDelaySeconds + interval*failures
+}
+
+type TsuruYamlLifecycle struct {
+	// PreStop commands run in units of the process before they are
+	// stopped.
+	PreStop []string `json:"pre_stop,omitempty" yaml:"pre_stop" bson:"pre_stop,omitempty"`
+	// TerminationGracePeriodSeconds is the time units are given to stop
+	// after being signaled, before being killed.
+	TerminationGracePeriodSeconds int `json:"termination_grace_period_seconds,omitempty" yaml:"termination_grace_period_seconds" bson:"termination_grace_period_seconds,omitempty"`
+}
+
 type TsuruYamlKubernetesConfig struct {
 	Groups map[string]TsuruYamlKubernetesGroup `json:"groups,omitempty"`
 }
@@ -68,6 +157,24 @@ func (y TsuruYamlData) ToRouterHC() router.HealthcheckData {
 	}
 }
 
+// ProcessConfig returns the settings of a process, which are empty if not
+// set in the tsuru.yaml.
+func (y TsuruYamlData) ProcessConfig(process string) TsuruYamlProcessConfig {
+	return y.Processes[process]
+}
+
+// ProcessHealthcheck returns the healthcheck of a process. The top level
+// healthcheck is used by the web process, unless it has its own healthcheck.
+func (y TsuruYamlData) ProcessHealthcheck(process, webProcess string) *TsuruYamlHealthcheck {
+	if hc := y.ProcessConfig(process).Healthcheck; hc != nil {
+		return hc
+	}
+	if process == webProcess {
+		return y.Healthcheck
+	}
+	return nil
+}
+
 func (y *TsuruYamlKubernetesConfig) GetProcessConfigs(procName string) *TsuruYamlKubernetesProcessConfig {
 	for _, group := range y.Groups {
 		for p, proc := range group {
@@ -0,0 +1,37 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package router
+
+import "errors"
+
+// DynamicRouter is a router registered through the API instead of the config
+// file. Config holds the same settings that would be set under the
+// routers:<name> key in the config file, nested settings are nested maps.
+type DynamicRouter struct {
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Config  map[string]interface{} `json:"config"`
+	Default bool                   `json:"default"`
+}
+
+type DynamicRouterService interface {
+	Create(DynamicRouter) error
+	Update(DynamicRouter) error
+	Get(name string) (*DynamicRouter, error)
+	List() ([]DynamicRouter, error)
+	Remove(name string) error
+}
+
+type DynamicRouterStorage interface {
+	Save(DynamicRouter) error
+	Get(name string) (*DynamicRouter, error)
+	List() ([]DynamicRouter, error)
+	Remove(name string) error
+}
+
+var (
+	ErrDynamicRouterNotFound      = errors.New("dynamic router not found")
+	ErrDynamicRouterAlreadyExists = errors.New("router already exists")
+)
@@ -0,0 +1,51 @@
+// Copyright 2019 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package router
+
+var _ DynamicRouterService = &MockDynamicRouterService{}
+
+// MockDynamicRouterService implements DynamicRouterService interface
+type MockDynamicRouterService struct {
+	OnCreate func(DynamicRouter) error
+	OnUpdate func(DynamicRouter) error
+	OnGet    func(string) (*DynamicRouter, error)
+	OnList   func() ([]DynamicRouter, error)
+	OnRemove func(string) error
+}
+
+func (m *MockDynamicRouterService) Create(r DynamicRouter) error {
+	if m.OnCreate == nil {
+		return nil
+	}
+	return m.OnCreate(r)
+}
+
+func (m *MockDynamicRouterService) Update(r DynamicRouter) error {
+	if m.OnUpdate == nil {
+		return nil
+	}
+	return m.OnUpdate(r)
+}
+
+func (m *MockDynamicRouterService) Get(name string) (*DynamicRouter, error) {
+	if m.OnGet == nil {
+		return nil, ErrDynamicRouterNotFound
+	}
+	return m.OnGet(name)
+}
+
+func (m *MockDynamicRouterService) List() ([]DynamicRouter, error) {
+	if m.OnList == nil {
+		return nil, nil
+	}
+	return m.OnList()
+}
+
+func (m *MockDynamicRouterService) Remove(name string) error {
+	if m.OnRemove == nil {
+		return nil
+	}
+	return m.OnRemove(name)
+}